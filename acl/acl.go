@@ -0,0 +1,686 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package acl
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+	"bytes"
+	"strings"
+	"encoding/binary"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// errno
+//
+const (
+	AclMgrEnoNone	= iota
+	AclMgrEnoParameter
+	AclMgrEnoScheduler
+	AclMgrEnoConfig
+	AclMgrEnoNotFound
+	AclMgrEnoDatabase
+	AclMgrEnoUnknown
+)
+
+type AclMgrErrno int
+
+//
+// Deny and allow lists. An entry targets a node identity or an address range
+// in CIDR notation, and might expire at some time. A node is rejected if it
+// matches any deny entry, even it matches some allow entry too, so a deny
+// always wins. When configured as allow only, nodes matching no allow entries
+// are rejected as well. Those checked without identity, say, an inbound
+// connection just accepted, are rejected only if their addresses denied,
+// since it's not known yet whom they are, and they would be checked again
+// with identity later. Entries are saved into a database, so they survive
+// restarts, and expired ones are purged periodically.
+//
+const (
+	AclDeny		= iota		// deny the target, over any allow entries
+	AclAllow				// allow the target, if it's not denied
+)
+
+const aclPurgeCycle = time.Minute	// cycle to purge expired entries
+
+//
+// Entry of lists
+//
+type AclEntry struct {
+	Kind		int				// AclDeny or AclAllow
+	Node		*ycfg.NodeID	// node identity, nil for an address range
+	Net			*net.IPNet		// address range, nil for a node identity
+	Expire		time.Time		// time the entry expired, zero for never
+}
+
+//
+// Target of entry in string
+//
+func (e AclEntry) Target() string {
+	if e.Node != nil {
+		return ycfg.P2pNodeId2HexString(*e.Node)
+	}
+	if e.Net != nil {
+		return e.Net.String()
+	}
+	return ""
+}
+
+//
+// String of entry, for debug
+//
+func (e AclEntry) String() string {
+
+	kind := "deny"
+	if e.Kind == AclAllow {
+		kind = "allow"
+	}
+
+	if e.Expire.IsZero() {
+		return fmt.Sprintf("%s %s", kind, e.Target())
+	}
+
+	return fmt.Sprintf("%s %s till %s", kind, e.Target(), e.Expire.Format(time.RFC3339))
+}
+
+//
+// Check if entry expired
+//
+func (e AclEntry) expired(now time.Time) bool {
+	return !e.Expire.IsZero() && !now.Before(e.Expire)
+}
+
+//
+// Parse target of entry, which is a node identity in hex string, an address
+// range in CIDR notation, or a single ip address taken as a range of itself.
+//
+func AclParseTarget(target string) (*ycfg.NodeID, *net.IPNet, AclMgrErrno) {
+
+	if strings.Contains(target, "/") {
+
+		_, ipnet, err := net.ParseCIDR(target)
+		if err != nil {
+			yclog.LogCallerFileLine("AclParseTarget: invalid cidr: %s", target)
+			return nil, nil, AclMgrEnoParameter
+		}
+
+		return nil, ipnet, AclMgrEnoNone
+	}
+
+	if ip := net.ParseIP(target); ip != nil {
+
+		if ip4 := ip.To4(); ip4 != nil {
+			return nil, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, AclMgrEnoNone
+		}
+
+		return nil, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, AclMgrEnoNone
+	}
+
+	if id := ycfg.P2pHexString2NodeId(target); id != nil {
+		return id, nil, AclMgrEnoNone
+	}
+
+	yclog.LogCallerFileLine("AclParseTarget: invalid target: %s", target)
+
+	return nil, nil, AclMgrEnoParameter
+}
+
+//
+// Entries are keyed with prefixes in the database, node identities with
+// "n:", address ranges with "c:", and valued with the kind and time the
+// entry expired.
+//
+var (
+	aclPrefixNode	= []byte("n:")
+	aclPrefixNet	= []byte("c:")
+)
+
+const aclValueSize = 9
+
+func aclKey(e *AclEntry) []byte {
+	if e.Node != nil {
+		return append(append([]byte{}, aclPrefixNode...), e.Node[:]...)
+	}
+	return append(append([]byte{}, aclPrefixNet...), e.Net.String()...)
+}
+
+func aclValue(e *AclEntry) []byte {
+
+	var exp int64
+
+	if !e.Expire.IsZero() {
+		exp = e.Expire.UnixNano()
+	}
+
+	val := make([]byte, aclValueSize)
+	val[0] = byte(e.Kind)
+	binary.BigEndian.PutUint64(val[1:], uint64(exp))
+
+	return val
+}
+
+//
+// Manager of lists
+//
+const AclMgrName = sch.AclMgrName
+
+type aclManager struct {
+	name		string						// name
+	tep			sch.SchUserTaskEp			// entry
+	sdl			*sch.Scheduler				// pointer to scheduler
+	ptnMe		interface{}					// pointer to myself task node
+	tidPurge	int							// purge timer identity
+	lock		sync.RWMutex				// lock, since lists are checked by other tasks
+	opened		bool						// opened or not
+	db			*leveldb.DB					// database, nil if failed to open
+	allowOnly	bool						// accept only nodes allowed
+	nodes		map[ycfg.NodeID]*AclEntry	// entries of node identities
+	nets		map[string]*AclEntry		// entries of address ranges, keyed by CIDR
+}
+
+//
+// Create manager, one for each p2p instance
+//
+func NewAclMgr() interface{} {
+	return &aclManager{
+		name:		AclMgrName,
+		tep:		AclMgrProc,
+		ptnMe:		nil,
+		tidPurge:	sch.SchInvalidTid,
+		nodes:		map[ycfg.NodeID]*AclEntry{},
+		nets:		map[string]*AclEntry{},
+	}
+}
+
+//
+// Get manager of a p2p instance by its' scheduler, for functions exported
+// to other tasks.
+//
+func aclGetManager(sdl *sch.Scheduler) *aclManager {
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(sdl, AclMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+		return nil
+	}
+
+	aclMgr, _ := sch.SchinfGetUserDataArea(ptn).(*aclManager)
+
+	return aclMgr
+}
+
+//
+// Manager entry
+//
+func AclMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
+	yclog.LogCallerFileLine("AclMgrProc: scheduled, msg: %d", msg.Id)
+
+	aclMgr := sch.SchinfGetUserDataArea(ptn).(*aclManager)
+
+	var eno AclMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = aclMgr.aclMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = aclMgr.aclMgrPoweroff(ptn)
+
+	case sch.EvAclPurgeTimer:
+		eno = aclMgr.aclPurge()
+
+	default:
+		yclog.LogCallerFileLine("AclMgrProc: invalid message: %d", msg.Id)
+		eno = AclMgrEnoParameter
+	}
+
+	if eno != AclMgrEnoNone {
+		yclog.LogCallerFileLine("AclMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
+	return sch.SchEnoNone
+}
+
+//
+// Poweron handler
+//
+func (aclMgr *aclManager) aclMgrPoweron(ptn interface{}) AclMgrErrno {
+
+	aclMgr.sdl = sch.SchinfGetScheduler(ptn)
+	aclMgr.ptnMe = ptn
+
+	cfg := ycfg.P2pConfig4Acl(sch.SchinfGetP2pConfig(aclMgr.sdl))
+	if cfg == nil {
+		yclog.LogCallerFileLine("aclMgrPoweron: invalid configuration")
+		return AclMgrEnoConfig
+	}
+
+	aclMgr.aclOpen(cfg)
+
+	var td = sch.TimerDescription {
+		Name:	AclMgrName + "_purge",
+		Utid:	sch.AclPurgeTimerId,
+		Tmt:	sch.SchTmTypePeriod,
+		Dur:	aclPurgeCycle,
+		Extra:	nil,
+	}
+
+	eno, tid := sch.SchInfSetTimer(ptn, &td)
+	if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+		yclog.LogCallerFileLine("aclMgrPoweron: " +
+			"SchInfSetTimer failed, eno: %d, timer: %s",
+			eno, td.Name)
+
+		return AclMgrEnoScheduler
+	}
+
+	aclMgr.tidPurge = tid
+
+	return AclMgrEnoNone
+}
+
+//
+// Poweroff handler
+//
+func (aclMgr *aclManager) aclMgrPoweroff(ptn interface{}) AclMgrErrno {
+
+	if aclMgr.tidPurge != sch.SchInvalidTid {
+		sch.SchinfKillTimer(ptn, aclMgr.tidPurge)
+		aclMgr.tidPurge = sch.SchInvalidTid
+	}
+
+	aclMgr.lock.Lock()
+
+	if aclMgr.db != nil {
+		aclMgr.db.Close()
+		aclMgr.db = nil
+	}
+
+	aclMgr.nodes = map[ycfg.NodeID]*AclEntry{}
+	aclMgr.nets = map[string]*AclEntry{}
+	aclMgr.opened = false
+
+	aclMgr.lock.Unlock()
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return AclMgrEnoUnknown
+	}
+
+	return AclMgrEnoNone
+}
+
+//
+// Open lists, entries are loaded from database configured. Errors about the
+// database are logged and ignored, entries are just not persisted then.
+//
+func (aclMgr *aclManager) aclOpen(cfg *ycfg.Cfg4Acl) {
+
+	aclMgr.lock.Lock()
+	defer aclMgr.lock.Unlock()
+
+	aclMgr.allowOnly = cfg.AllowOnly
+	aclMgr.nodes = map[ycfg.NodeID]*AclEntry{}
+	aclMgr.nets = map[string]*AclEntry{}
+	aclMgr.opened = true
+
+	if len(cfg.Db) == 0 {
+		return
+	}
+
+	db, err := leveldb.OpenFile(cfg.Db, &opt.Options{OpenFilesCacheCapacity: 4})
+	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+		db, err = leveldb.RecoverFile(cfg.Db, nil)
+	}
+
+	if err != nil {
+
+		yclog.LogCallerFileLine("aclOpen: " +
+			"OpenFile failed, path: %s, err: %s",
+			cfg.Db, err.Error())
+
+		return
+	}
+
+	aclMgr.db = db
+
+	now := time.Now()
+	it := db.NewIterator(nil, nil)
+
+	for it.Next() {
+
+		key, val := it.Key(), it.Value()
+		if len(val) != aclValueSize || (val[0] != AclDeny && val[0] != AclAllow) {
+			continue
+		}
+
+		e := &AclEntry{Kind: int(val[0])}
+
+		if exp := int64(binary.BigEndian.Uint64(val[1:])); exp != 0 {
+			e.Expire = time.Unix(0, exp)
+		}
+
+		if e.expired(now) {
+			db.Delete(key, nil)
+			continue
+		}
+
+		if bytes.HasPrefix(key, aclPrefixNode) && len(key) == len(aclPrefixNode) + ycfg.NodeIDBytes {
+
+			var id ycfg.NodeID
+			copy(id[:], key[len(aclPrefixNode):])
+			e.Node = &id
+			aclMgr.nodes[id] = e
+
+		} else if bytes.HasPrefix(key, aclPrefixNet) {
+
+			_, ipnet, err := net.ParseCIDR(string(key[len(aclPrefixNet):]))
+			if err != nil {
+				continue
+			}
+
+			e.Net = ipnet
+			aclMgr.nets[ipnet.String()] = e
+		}
+	}
+
+	it.Release()
+
+	yclog.LogCallerFileLine("aclOpen: " +
+		"entries loaded, nodes: %d, nets: %d, path: %s",
+		len(aclMgr.nodes), len(aclMgr.nets), cfg.Db)
+}
+
+//
+// Save entry to database, lock should be held by caller
+//
+func (aclMgr *aclManager) aclSave(e *AclEntry) AclMgrErrno {
+
+	if aclMgr.db == nil {
+		return AclMgrEnoNone
+	}
+
+	if err := aclMgr.db.Put(aclKey(e), aclValue(e), nil); err != nil {
+
+		yclog.LogCallerFileLine("aclSave: " +
+			"Put failed, err: %s, target: %s",
+			err.Error(), e.Target())
+
+		return AclMgrEnoDatabase
+	}
+
+	return AclMgrEnoNone
+}
+
+//
+// Remove entry from database, lock should be held by caller
+//
+func (aclMgr *aclManager) aclDelete(e *AclEntry) AclMgrErrno {
+
+	if aclMgr.db == nil {
+		return AclMgrEnoNone
+	}
+
+	if err := aclMgr.db.Delete(aclKey(e), nil); err != nil {
+
+		yclog.LogCallerFileLine("aclDelete: " +
+			"Delete failed, err: %s, target: %s",
+			err.Error(), e.Target())
+
+		return AclMgrEnoDatabase
+	}
+
+	return AclMgrEnoNone
+}
+
+//
+// Purge timer handler, expired entries are removed
+//
+func (aclMgr *aclManager) aclPurge() AclMgrErrno {
+
+	aclMgr.lock.Lock()
+	defer aclMgr.lock.Unlock()
+
+	now := time.Now()
+	purged := 0
+
+	for id, e := range aclMgr.nodes {
+		if e.expired(now) {
+			aclMgr.aclDelete(e)
+			delete(aclMgr.nodes, id)
+			purged++
+		}
+	}
+
+	for cidr, e := range aclMgr.nets {
+		if e.expired(now) {
+			aclMgr.aclDelete(e)
+			delete(aclMgr.nets, cidr)
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		yclog.LogCallerFileLine("aclPurge: expired entries purged: %d", purged)
+	}
+
+	return AclMgrEnoNone
+}
+
+//
+// Check node against lists, see comments about AclDeny for details
+//
+func (aclMgr *aclManager) aclCheck(id *ycfg.NodeID, ip net.IP) bool {
+
+	aclMgr.lock.RLock()
+	defer aclMgr.lock.RUnlock()
+
+	if !aclMgr.opened {
+		return true
+	}
+
+	now := time.Now()
+	allowed, denied := false, false
+
+	match := func(e *AclEntry) {
+		if e.expired(now) {
+			return
+		}
+		if e.Kind == AclAllow {
+			allowed = true
+		} else {
+			denied = true
+		}
+	}
+
+	if id != nil {
+		if e, ok := aclMgr.nodes[*id]; ok {
+			match(e)
+		}
+	}
+
+	if ip != nil {
+		for _, e := range aclMgr.nets {
+			if e.Net.Contains(ip) {
+				match(e)
+			}
+		}
+	}
+
+	if denied {
+		return false
+	}
+
+	if allowed || id == nil {
+		return true
+	}
+
+	return !aclMgr.allowOnly
+}
+
+//
+// Check if a node is accepted, id or ip can be nil if it's not known. Notice:
+// functions exported here are called by other tasks directly, the lists are
+// protected by lock. Nodes are accepted if the manager is not found.
+//
+func AclCheck(sdl *sch.Scheduler, id *ycfg.NodeID, ip net.IP) bool {
+
+	aclMgr := aclGetManager(sdl)
+	if aclMgr == nil {
+		return true
+	}
+
+	return aclMgr.aclCheck(id, ip)
+}
+
+//
+// Add an entry to lists, the one with the same target is replaced. If it's
+// failed to be saved, AclMgrEnoDatabase is returned, but the entry is still
+// applied till the instance stopped.
+//
+func AclAdd(sdl *sch.Scheduler, e *AclEntry) AclMgrErrno {
+
+	if e == nil || (e.Node == nil) == (e.Net == nil) || (e.Kind != AclDeny && e.Kind != AclAllow) {
+		yclog.LogCallerFileLine("AclAdd: invalid parameter")
+		return AclMgrEnoParameter
+	}
+
+	aclMgr := aclGetManager(sdl)
+	if aclMgr == nil {
+		return AclMgrEnoScheduler
+	}
+
+	return aclMgr.aclAdd(e)
+}
+
+//
+// Add an entry to lists and save it, see AclAdd
+//
+func (aclMgr *aclManager) aclAdd(e *AclEntry) AclMgrErrno {
+
+	aclMgr.lock.Lock()
+	defer aclMgr.lock.Unlock()
+
+	if !aclMgr.opened {
+		return AclMgrEnoScheduler
+	}
+
+	ne := *e
+
+	if ne.Node != nil {
+		id := *ne.Node
+		ne.Node = &id
+		aclMgr.nodes[id] = &ne
+	} else {
+		ipnet := net.IPNet{IP: ne.Net.IP.Mask(ne.Net.Mask), Mask: ne.Net.Mask}
+		ne.Net = &ipnet
+		aclMgr.nets[ipnet.String()] = &ne
+	}
+
+	yclog.LogCallerFileLine("AclAdd: " +
+		"kind: %d, expire: %s, target: %s",
+		ne.Kind, ne.Expire.String(), ne.Target())
+
+	return aclMgr.aclSave(&ne)
+}
+
+//
+// Remove the entry of a target from lists
+//
+func AclRemove(sdl *sch.Scheduler, id *ycfg.NodeID, ipnet *net.IPNet) AclMgrErrno {
+
+	if (id == nil) == (ipnet == nil) {
+		yclog.LogCallerFileLine("AclRemove: invalid parameter")
+		return AclMgrEnoParameter
+	}
+
+	aclMgr := aclGetManager(sdl)
+	if aclMgr == nil {
+		return AclMgrEnoScheduler
+	}
+
+	aclMgr.lock.Lock()
+	defer aclMgr.lock.Unlock()
+
+	var e *AclEntry
+	var ok bool
+
+	if id != nil {
+		if e, ok = aclMgr.nodes[*id]; ok {
+			delete(aclMgr.nodes, *id)
+		}
+	} else {
+		cidr := (&net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}).String()
+		if e, ok = aclMgr.nets[cidr]; ok {
+			delete(aclMgr.nets, cidr)
+		}
+	}
+
+	if !ok {
+		return AclMgrEnoNotFound
+	}
+
+	yclog.LogCallerFileLine("AclRemove: target: %s", e.Target())
+
+	return aclMgr.aclDelete(e)
+}
+
+//
+// Get entries not expired in lists
+//
+func AclList(sdl *sch.Scheduler) []AclEntry {
+
+	aclMgr := aclGetManager(sdl)
+	if aclMgr == nil {
+		return nil
+	}
+
+	aclMgr.lock.RLock()
+	defer aclMgr.lock.RUnlock()
+
+	now := time.Now()
+	entries := make([]AclEntry, 0, len(aclMgr.nodes) + len(aclMgr.nets))
+
+	for _, e := range aclMgr.nodes {
+		if !e.expired(now) {
+			entries = append(entries, *e)
+		}
+	}
+
+	for _, e := range aclMgr.nets {
+		if !e.expired(now) {
+			entries = append(entries, *e)
+		}
+	}
+
+	return entries
+}
+
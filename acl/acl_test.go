@@ -0,0 +1,209 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package acl
+
+import (
+	"net"
+	"time"
+	"testing"
+	"strings"
+	"path/filepath"
+	ycfg	"github.com/yeeco/p2p/config"
+)
+
+func aclTestManager(t *testing.T, cfg *ycfg.Cfg4Acl, entries []string) *aclManager {
+
+	aclMgr := NewAclMgr().(*aclManager)
+	aclMgr.aclOpen(cfg)
+
+	for _, entry := range entries {
+		if eno := aclMgr.aclAdd(aclTestEntry(t, entry)); eno != AclMgrEnoNone {
+			t.Fatalf("aclAdd failed, entry: %s, eno: %d", entry, eno)
+		}
+	}
+
+	return aclMgr
+}
+
+//
+// Entry in form of "deny|allow target [ttl]"
+//
+func aclTestEntry(t *testing.T, entry string) *AclEntry {
+
+	var kind, target, ttl string
+	var e AclEntry
+
+	for i, f := range strings.Fields(entry) {
+		switch i {
+		case 0: kind = f
+		case 1: target = f
+		case 2: ttl = f
+		}
+	}
+
+	if kind == "allow" {
+		e.Kind = AclAllow
+	}
+
+	id, ipnet, eno := AclParseTarget(target)
+	if eno != AclMgrEnoNone {
+		t.Fatalf("AclParseTarget failed, target: %s", target)
+	}
+	e.Node, e.Net = id, ipnet
+
+	if len(ttl) > 0 {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			t.Fatalf("invalid ttl: %s", ttl)
+		}
+		e.Expire = time.Now().Add(d)
+	}
+
+	return &e
+}
+
+var (
+	aclTestNode1 = ycfg.NodeID{1}
+	aclTestNode2 = ycfg.NodeID{2}
+	aclTestHex1 = ycfg.P2pNodeId2HexString(aclTestNode1)
+	aclTestHex2 = ycfg.P2pNodeId2HexString(aclTestNode2)
+)
+
+func TestAclCheck(t *testing.T) {
+
+	cases := []struct {
+		name		string
+		allowOnly	bool
+		entries		[]string
+		id			*ycfg.NodeID
+		ip			string
+		want		bool
+	}{
+		{"empty", false, nil, &aclTestNode1, "10.0.0.1", true},
+
+		{"ipv4 in range", false, []string{"deny 10.0.0.0/8"}, &aclTestNode1, "10.1.2.3", false},
+		{"ipv4 out of range", false, []string{"deny 10.0.0.0/8"}, &aclTestNode1, "11.0.0.1", true},
+		{"ipv4 single", false, []string{"deny 10.0.0.1"}, &aclTestNode1, "10.0.0.1", false},
+		{"ipv4 single other", false, []string{"deny 10.0.0.1"}, &aclTestNode1, "10.0.0.2", true},
+		{"ipv4 in ipv6 form", false, []string{"deny 10.0.0.0/8"}, &aclTestNode1, "::ffff:10.0.0.1", false},
+		{"ipv6 in range", false, []string{"deny 2001:db8::/32"}, &aclTestNode1, "2001:db8::1", false},
+		{"ipv6 out of range", false, []string{"deny 2001:db8::/32"}, &aclTestNode1, "2001:db9::1", true},
+		{"ipv6 single", false, []string{"deny ::1"}, &aclTestNode1, "::1", false},
+		{"ipv6 against ipv4", false, []string{"deny 2001:db8::/32"}, &aclTestNode1, "10.0.0.1", true},
+		{"ipv4 against ipv6", false, []string{"deny 0.0.0.0/0"}, &aclTestNode1, "2001:db8::1", true},
+
+		{"node denied", false, []string{"deny " + aclTestHex1}, &aclTestNode1, "10.0.0.1", false},
+		{"other node", false, []string{"deny " + aclTestHex1}, &aclTestNode2, "10.0.0.1", true},
+		{"node denied without id", false, []string{"deny " + aclTestHex1}, nil, "10.0.0.1", true},
+
+		{"deny node over allow range", false, []string{"deny " + aclTestHex1, "allow 10.0.0.0/8"}, &aclTestNode1, "10.0.0.1", false},
+		{"deny range over allow node", false, []string{"allow " + aclTestHex1, "deny 10.0.0.0/8"}, &aclTestNode1, "10.0.0.1", false},
+		{"deny range over allow range", false, []string{"allow 10.0.0.1", "deny 10.0.0.0/8"}, &aclTestNode1, "10.0.0.1", false},
+		{"deny range without id", false, []string{"allow " + aclTestHex1, "deny 10.0.0.0/8"}, nil, "10.0.0.1", false},
+
+		{"allow only, allowed", true, []string{"allow " + aclTestHex1}, &aclTestNode1, "10.0.0.1", true},
+		{"allow only, not allowed", true, []string{"allow " + aclTestHex1}, &aclTestNode2, "10.0.0.1", false},
+		{"allow only, range allowed", true, []string{"allow 10.0.0.0/8"}, &aclTestNode2, "10.0.0.1", true},
+		{"allow only, without id", true, []string{"allow " + aclTestHex1}, nil, "11.0.0.1", true},
+		{"allow only, denied", true, []string{"allow " + aclTestHex1, "deny 10.0.0.1"}, &aclTestNode1, "10.0.0.1", false},
+
+		{"deny expired", false, []string{"deny 10.0.0.0/8 -1s"}, &aclTestNode1, "10.0.0.1", true},
+		{"deny not expired", false, []string{"deny 10.0.0.0/8 1h"}, &aclTestNode1, "10.0.0.1", false},
+		{"node deny expired", false, []string{"deny " + aclTestHex1 + " -1s"}, &aclTestNode1, "10.0.0.1", true},
+		{"allow expired", true, []string{"allow " + aclTestHex1 + " -1s"}, &aclTestNode1, "10.0.0.1", false},
+		{"expired deny over allow", false, []string{"allow " + aclTestHex1, "deny 10.0.0.0/8 -1s"}, &aclTestNode1, "10.0.0.1", true},
+	}
+
+	for _, c := range cases {
+
+		aclMgr := aclTestManager(t, &ycfg.Cfg4Acl{AllowOnly: c.allowOnly}, c.entries)
+
+		if ok := aclMgr.aclCheck(c.id, net.ParseIP(c.ip)); ok != c.want {
+			t.Fatalf("%s: aclCheck returned %t, want %t", c.name, ok, c.want)
+		}
+	}
+}
+
+func TestAclPersist(t *testing.T) {
+
+	cfg := &ycfg.Cfg4Acl{Db: filepath.Join(t.TempDir(), "acl")}
+	entries := []string{
+		"deny " + aclTestHex1,
+		"allow " + aclTestHex2 + " 1h",
+		"deny 10.0.0.0/8",
+		"allow 2001:db8::/32 1h",
+		"deny 192.168.0.1 1ms",
+	}
+
+	aclMgr := aclTestManager(t, cfg, entries)
+	if aclMgr.db == nil {
+		t.Fatalf("database not opened, path: %s", cfg.Db)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	want := map[string]AclEntry{}
+	for _, e := range aclMgr.nodes {
+		want[e.Target()] = *e
+	}
+	for _, e := range aclMgr.nets {
+		if !e.expired(time.Now()) {
+			want[e.Target()] = *e
+		}
+	}
+
+	aclMgr.db.Close()
+
+	loaded := aclTestManager(t, cfg, nil)
+	defer loaded.db.Close()
+
+	got := map[string]AclEntry{}
+	for _, e := range loaded.nodes {
+		got[e.Target()] = *e
+	}
+	for _, e := range loaded.nets {
+		got[e.Target()] = *e
+	}
+
+	if len(got) != len(want) || len(want) != len(entries) - 1 {
+		t.Fatalf("loaded %d entries, want %d", len(got), len(want))
+	}
+
+	for target, w := range want {
+
+		g, ok := got[target]
+		if !ok {
+			t.Fatalf("%s: not loaded", target)
+		}
+
+		if g.Kind != w.Kind || !g.Expire.Equal(w.Expire) {
+			t.Fatalf("%s: loaded %s, want %s", target, g.String(), w.String())
+		}
+	}
+
+	if loaded.aclCheck(&aclTestNode2, net.ParseIP("10.0.0.1")) {
+		t.Fatalf("denied range not enforced after load")
+	}
+
+	if !loaded.aclCheck(&aclTestNode2, net.ParseIP("192.168.0.1")) {
+		t.Fatalf("expired entry enforced after load")
+	}
+}
@@ -34,11 +34,14 @@ import (
 	"fmt"
 
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"io"
 	"io/ioutil"
 	"math/big"
 	"errors"
+	"time"
 
 	//ethereum "github.com/ethereum/go-ethereum/crypto"
 
@@ -68,6 +71,9 @@ const (
 const (
 	PcfgEnoIpAddrivateKey	= "nodekey"	// Path within the datadir to the node's private key
 	datadirNodeDatabase		= "nodes"	// Path within the datadir to store the node infos
+	datadirChunkStore		= "chunks"	// Path within the datadir to store the dht chunks
+	datadirPeerScores		= "scores"	// Path within the datadir to store the scores of peers
+	datadirAcl				= "acl"		// Path within the datadir to store the deny and allow lists
 )
 
 //
@@ -75,6 +81,12 @@ const (
 //
 //	node-identity-hex-string@ip:udp-port:tcp-port
 //
+// where an IPv6 address must be enclosed in brackets, like:
+//
+//	node-identity-hex-string@[fe80::1]:udp-port:tcp-port
+//
+// see P2pParseNodeUrl for details please.
+//
 const P2pMaxBootstrapNodes = 32
 
 var BootstrapNodeUrl = []string {
@@ -153,6 +165,18 @@ type Config struct {
 	Local			Node				// myself
 	ProtoNum		uint32				// local protocol number
 	Protocols		[]Protocol			// local protocol table
+	DhtChunkStore	string				// dht chunk store backend: "memory" or "leveldb"
+	DhtReplicas		int					// dht replication factor
+	PeerTxQueueSize	int					// max packages queued to be sent for a peer
+	PeerCompress	string				// payload compression advertised to peers: "none" or "snappy"
+	PeerCompressMin	int					// min size of payloads to be compressed
+	PeerDropScore	int					// peers scored below are disconnected
+	PeerBanScore	int					// peers scored below are disconnected and banned
+	PeerBanTime		time.Duration		// time peers banned for
+	PeerScoreDecay	time.Duration		// time for scores of peers to decay to half
+	AclAllowOnly	bool				// accept only nodes in the allow list
+	NetworkId		uint32				// identity of network, nodes of other networks are rejected
+	NetworkKey		[]byte				// pre-shared key of private network, nil for none
 }
 
 //
@@ -163,6 +187,8 @@ type Cfg4UdpListener struct {
 	UDP		uint16		// udp port numbers
 	TCP		uint16		// tcp port numbers
 	ID		NodeID		// the node's public key
+	PrivateKey	*ecdsa.PrivateKey	// the node's private key, for signing messages
+	Network		[]byte		// tag of network, mixed into digests signed, see P2pNetworkTag
 }
 
 //
@@ -184,6 +210,7 @@ type Cfg4PeerManager struct {
 	Port			uint16		// tcp port number
 	UDP				uint16		// udp port number, used with handshake procedure
 	ID				NodeID		// the node's public key
+	PrivateKey		*ecdsa.PrivateKey	// the node's private key, for handshake signing
 	MaxPeers		int			// max peers would be
 	MaxOutbounds	int			// max concurrency outbounds
 	MaxInBounds		int			// max concurrency inbounds
@@ -192,6 +219,15 @@ type Cfg4PeerManager struct {
 	BootstrapNode	bool		// local is a bootstrap node
 	ProtoNum		uint32		// local protocol number
 	Protocols		[]Protocol	// local protocol table
+	TxQueueSize		int			// max packages queued to be sent for a peer
+	Compress		string		// payload compression advertised to peers
+	CompressMin		int			// min size of payloads to be compressed
+	ScoreDb			string		// path to database of peer scores
+	DropScore		int			// peers scored below are disconnected
+	BanScore		int			// peers scored below are disconnected and banned
+	BanTime			time.Duration	// time peers banned for
+	ScoreDecay		time.Duration	// time for scores of peers to decay to half
+	Network			[]byte		// tag of network, mixed into digests signed, see P2pNetworkTag
 }
 
 //
@@ -205,6 +241,51 @@ type Cfg4TabManager struct {
 	BootstrapNode	bool	// bootstrap node flag
 }
 
+//
+// Configuration about dht storer
+//
+type Cfg4DhtStorer struct {
+	Backend		string	// chunk store backend
+	Path		string	// chunk store path for persistent backend
+}
+
+//
+// Configuration about dht syncer
+//
+type Cfg4DhtSyncer struct {
+	Local		NodeID	// local node identity
+	Replicas	int		// replication factor
+}
+
+//
+// Configuration about gossip
+//
+type Cfg4Gossip struct {
+	Local		NodeID	// local node identity, as the publisher of messages
+}
+
+//
+// Configuration about stream
+//
+type Cfg4Stream struct {
+	Local		NodeID	// local node identity, for allocating stream identities
+}
+
+//
+// Configuration about deny and allow lists
+//
+type Cfg4Acl struct {
+	Db			string	// path to database of the lists
+	AllowOnly	bool	// accept only nodes in the allow list
+}
+
+//
+// Configuration about dht provider
+//
+type Cfg4DhtProvider struct {
+	Local		Node	// local node, announced as provider
+}
+
 //
 // Configuration about protocols supported
 //
@@ -233,8 +314,55 @@ const (
 	dftTcpPort = 30303
 )
 
+//
+// Default dht chunk store backend
+//
+const dftDhtChunkStore = "leveldb"
+
+//
+// Default dht replication factor
+//
+const dftDhtReplicas = 3
+
+//
+// Default max packages queued to be sent for a peer
+//
+const dftPeerTxQueueSize = 64
+
+//
+// Payload compression, payloads smaller than dftPeerCompressMin are sent
+// raw by default, since they hardly get smaller.
+//
+const (
+	P2pCompressNone		= "none"
+	P2pCompressSnappy	= "snappy"
+	dftPeerCompress		= P2pCompressSnappy
+	dftPeerCompressMin	= 1024
+)
+
+//
+// Default network, the public one without key
+//
+const dftNetworkId = 0
+
+//
+// Default thresholds and times about scores of peers, scores are limited in
+// [-100, 100], see peer.PeScoreXXX.
+//
+const (
+	dftPeerDropScore		= -40
+	dftPeerBanScore			= -80
+	dftPeerBanTime			= time.Hour
+	dftPeerScoreDecay		= 30 * time.Minute
+)
+
+//
+// The local node listens on all addresses of both IPv4 and IPv6 by default, and
+// advertises the unspecified address, then the remote takes the address it
+// sees us from, see udpmsg.CheckUdpMsgFromPeer and P2pPreferredIP.
+//
 var dftLocal = Node {
-	IP:		net.IPv4(192,168,2,102),
+	IP:		net.IPv6unspecified,
 	UDP:	dftUdpPort,
 	TCP:	dftTcpPort,
 	ID:		NodeID{0},
@@ -255,17 +383,39 @@ var config = Config {
 	NoDial:				false,
 	BootstrapNode:		false,
 	Local:				dftLocal,
-	ProtoNum:			1,
-	Protocols:			[]Protocol {{Pid:0,Ver:[4]byte{0,1,0,0},}},
+	ProtoNum:			5,
+	Protocols:			[]Protocol {
+							{Pid:0,Ver:[4]byte{0,1,0,0},},	// PID_P2P
+							{Pid:1,Ver:[4]byte{0,1,0,0},},	// PID_DHT
+							{Pid:2,Ver:[4]byte{0,1,0,0},},	// PID_GOSSIP
+							{Pid:3,Ver:[4]byte{0,1,0,0},},	// PID_RPC
+							{Pid:4,Ver:[4]byte{0,1,0,0},},	// PID_STREAM
+						},
+	DhtChunkStore:		dftDhtChunkStore,
+	DhtReplicas:		dftDhtReplicas,
+	PeerTxQueueSize:	dftPeerTxQueueSize,
+	PeerCompress:		dftPeerCompress,
+	PeerCompressMin:	dftPeerCompressMin,
+	PeerDropScore:		dftPeerDropScore,
+	PeerBanScore:		dftPeerBanScore,
+	PeerBanTime:		dftPeerBanTime,
+	PeerScoreDecay:		dftPeerScoreDecay,
+	AclAllowOnly:		false,
+	NetworkId:			dftNetworkId,
+	NetworkKey:			nil,
 }
 
-var PtrConfig = &config
-
 //
-// Get default config
+// Get default config. A copy is returned each time, so that several p2p
+// instances in a process can each own its' configuration.
 //
 func P2pDefaultConfig() *Config {
-	return &config
+	var cfg = config
+	cfg.BootstrapNodes = append([]*Node{}, config.BootstrapNodes...)
+	cfg.Protocols = append([]Protocol{}, config.Protocols...)
+	cfg.Local.IP = append(net.IP{}, config.Local.IP...)
+	cfg.NetworkKey = append([]byte(nil), config.NetworkKey...)
+	return &cfg
 }
 
 //
@@ -274,21 +424,21 @@ func P2pDefaultConfig() *Config {
 func P2pSetConfig(cfg *Config) P2pCfgErrno {
 
 	//
-	// Update, one SHOULD first call P2pDefaultConfig to get default value, modify
-	// some fields if necessary, and then call this function, since the configuration
-	// is overlapped directly here in this function.
+	// Check and update, one SHOULD first call P2pDefaultConfig to get default value,
+	// modify some fields if necessary, and then call this function, since the key
+	// pair and local node identity are setup directly in "cfg" here in this function.
 	//
 
 	if cfg == nil {
 		yclog.LogCallerFileLine("P2pSetConfig: invalid configuration")
 		return PcfgEnoParameter
 	}
-	config = *cfg
+	config := cfg
 
 	//
-	// Check configuration. Notice that we do not need a private key in current
-	// implement, only public key needed to build the local node identity, so one
-	// can leave private to be nil while give a not nil public key. If both are
+	// Check configuration. Notice that the private key is needed to sign the
+	// handshake with peers, one can leave private to be nil while give a not nil
+	// public key, but then no peer connection could be established. If both are
 	// nils, key pair will be built, see bellow pls.
 	//
 
@@ -341,11 +491,53 @@ func P2pSetConfig(cfg *Config) P2pCfgErrno {
 		return PcfgEnoIpAddr
 	}
 
+	if config.DhtReplicas <= 0 {
+		yclog.LogCallerFileLine("P2pSetConfig: " +
+			"invalid dht replication factor: %d",
+			config.DhtReplicas)
+		return PcfgEnoParameter
+	}
+
+	if config.PeerTxQueueSize <= 0 {
+		yclog.LogCallerFileLine("P2pSetConfig: " +
+			"invalid peer tx queue size: %d",
+			config.PeerTxQueueSize)
+		return PcfgEnoParameter
+	}
+
+	if config.PeerCompress != P2pCompressNone && config.PeerCompress != P2pCompressSnappy {
+		yclog.LogCallerFileLine("P2pSetConfig: " +
+			"invalid peer compression: %s",
+			config.PeerCompress)
+		return PcfgEnoParameter
+	}
+
+	if config.PeerCompressMin < 0 {
+		yclog.LogCallerFileLine("P2pSetConfig: " +
+			"invalid min size to compress: %d",
+			config.PeerCompressMin)
+		return PcfgEnoParameter
+	}
+
+	if config.PeerDropScore >= 0 || config.PeerBanScore > config.PeerDropScore {
+		yclog.LogCallerFileLine("P2pSetConfig: " +
+			"invalid score thresholds, drop: %d, ban: %d",
+			config.PeerDropScore, config.PeerBanScore)
+		return PcfgEnoParameter
+	}
+
+	if config.PeerBanTime <= 0 || config.PeerScoreDecay <= 0 {
+		yclog.LogCallerFileLine("P2pSetConfig: " +
+			"invalid ban time: %s, or score decay: %s",
+			config.PeerBanTime, config.PeerScoreDecay)
+		return PcfgEnoParameter
+	}
+
 	//
 	// setup local node identity from key
 	//
 
-	if p2pSetupLocalNodeId() != PcfgEnoNone {
+	if p2pSetupLocalNodeId(config) != PcfgEnoNone {
 		yclog.LogCallerFileLine("P2pSetConfig: invalid ip address")
 		return PcfgEnoNodeId
 	}
@@ -353,13 +545,6 @@ func P2pSetConfig(cfg *Config) P2pCfgErrno {
 	return PcfgEnoNone
 }
 
-//
-// Get global configuration pointer
-//
-func P2pGetConfig() *Config {
-	return &config
-}
-
 //
 // Node identity to hex string
 //
@@ -461,7 +646,7 @@ func P2pGetUserHomeDir() string {
 	return ""
 }
 
-func p2pBuildPrivateKey() *ecdsa.PrivateKey {
+func p2pBuildPrivateKey(config *Config) *ecdsa.PrivateKey {
 
 	//
 	// Here we apply the Ethereum crypto package to build node private key:
@@ -546,10 +731,82 @@ func p2pPubkey2NodeId(pub *ecdsa.PublicKey) *NodeID {
 	return &id
 }
 
+//
+// Trans node identity to public key
+//
+func P2pNodeId2Pubkey(id NodeID) *ecdsa.PublicKey {
+
+	pbytes := make([]byte, 0, len(id) + 1)
+	pbytes = append(pbytes, 0x04)
+	pbytes = append(pbytes, id[:]...)
+
+	x, y := elliptic.Unmarshal(S256(), pbytes)
+
+	if x == nil || y == nil {
+		yclog.LogCallerFileLine("P2pNodeId2Pubkey: " +
+			"invalid node identity for public key")
+		return nil
+	}
+
+	return &ecdsa.PublicKey{Curve: S256(), X: x, Y: y}
+}
+
+//
+// Sign a digest with private key, the signature returned is formated as
+// "r|s", each part padded to the curve size.
+//
+const P2pSignatureBytes = 64
+
+func P2pSign(priv *ecdsa.PrivateKey, digest []byte) []byte {
+
+	if priv == nil || len(digest) == 0 {
+		yclog.LogCallerFileLine("P2pSign: invalid parameters")
+		return nil
+	}
+
+	r, s, err := ecdsa.Sign(rand.Reader, priv, digest)
+	if err != nil {
+		yclog.LogCallerFileLine("P2pSign: " +
+			"Sign failed, err: %s",
+			err.Error())
+		return nil
+	}
+
+	sig := make([]byte, P2pSignatureBytes)
+	rb := r.Bytes()
+	sb := s.Bytes()
+	copy(sig[P2pSignatureBytes/2-len(rb):P2pSignatureBytes/2], rb)
+	copy(sig[P2pSignatureBytes-len(sb):], sb)
+
+	return sig
+}
+
+//
+// Verify a signature against the node identity(the public key) of signer
+//
+func P2pVerify(id NodeID, digest []byte, sig []byte) bool {
+
+	if len(digest) == 0 || len(sig) != P2pSignatureBytes {
+		yclog.LogCallerFileLine("P2pVerify: invalid parameters")
+		return false
+	}
+
+	pub := P2pNodeId2Pubkey(id)
+	if pub == nil {
+		yclog.LogCallerFileLine("P2pVerify: P2pNodeId2Pubkey failed")
+		return false
+	}
+
+	r := new(big.Int).SetBytes(sig[:P2pSignatureBytes/2])
+	s := new(big.Int).SetBytes(sig[P2pSignatureBytes/2:])
+
+	return ecdsa.Verify(pub, digest, r, s)
+}
+
 //
 // Setup local node identity
 //
-func p2pSetupLocalNodeId() P2pCfgErrno {
+func p2pSetupLocalNodeId(config *Config) P2pCfgErrno {
 
 	if config.PrivateKey != nil {
 
@@ -557,7 +814,7 @@ func p2pSetupLocalNodeId() P2pCfgErrno {
 
 	} else if config.PublicKey == nil {
 
-		config.PrivateKey = p2pBuildPrivateKey()
+		config.PrivateKey = p2pBuildPrivateKey(config)
 
 		if config.PrivateKey == nil {
 			yclog.LogCallerFileLine("p2pSetupLocalNodeId: " +
@@ -592,79 +849,199 @@ func P2pSetupDefaultBootstrapNodes() []*Node {
 
 	var bsn = make([]*Node, 0, P2pMaxBootstrapNodes)
 
-	for idx, url := range BootstrapNodeUrl {
+	for _, url := range BootstrapNodeUrl {
 
-		strs := strings.Split(url,"@")
-		if len(strs) != 2 {
+		n := P2pParseNodeUrl(url)
+		if n == nil {
 			yclog.LogCallerFileLine("P2pSetupDefaultBootstrapNodes: " +
 				"invalid bootstrap url: %s",
 				url)
 			return nil
 		}
 
-		strNodeId := strs[0]
-		strs = strings.Split(strs[1],":")
-		if len(strs) != 3 {
-			yclog.LogCallerFileLine("P2pSetupDefaultBootstrapNodes: " +
-				"invalid bootstrap url: %s",
-				url)
-			return nil
-		}
+		bsn = append(bsn, n)
+	}
 
-		strIp := strs[0]
-		strUdpPort := strs[1]
-		strTcpPort := strs[2]
+	return  bsn
+}
 
-		pid := P2pHexString2NodeId(strNodeId)
-		if pid == nil {
-			yclog.LogCallerFileLine("P2pSetupDefaultBootstrapNodes: " +
-				"P2pHexString2NodeId failed, strNodeId: %s",
-				strNodeId)
-			return nil
-		}
+//
+// Parse node from url, see BootstrapNodeUrl for the format. The ip address
+// is normalized by P2pNormalizeIP. nil returned for invalid url.
+//
+func P2pParseNodeUrl(url string) *Node {
 
-		bsn = append(bsn, new(Node))
-		copy(bsn[idx].ID[:], (*pid)[:])
-		bsn[idx].IP = net.ParseIP(strIp)
+	strs := strings.Split(url,"@")
+	if len(strs) != 2 {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"invalid url: %s",
+			url)
+		return nil
+	}
 
-		if port, err := strconv.Atoi(strUdpPort); err != nil {
-			yclog.LogCallerFileLine("P2pSetupDefaultBootstrapNodes: " +
-				"Atoi for UDP port failed, err: %s",
-				err.Error())
-			return nil
-		} else {
-			bsn[idx].UDP = uint16(port)
-		}
+	strNodeId := strs[0]
+	strAddr := strs[1]
 
-		if port, err := strconv.Atoi(strTcpPort); err != nil {
-			yclog.LogCallerFileLine("P2pSetupDefaultBootstrapNodes: " +
-				"Atoi for TCP port failed, err: %s",
-				err.Error())
-			return nil
-		} else {
-			bsn[idx].TCP = uint16(port)
-		}
+	//
+	// split the ip and ports from the tail, since an IPv6 address contains
+	// colons itself, and then strip the brackets.
+	//
+
+	idx := strings.LastIndex(strAddr, ":")
+	if idx < 0 {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"invalid url: %s",
+			url)
+		return nil
 	}
 
-	return  bsn
+	strTcpPort := strAddr[idx+1:]
+	strAddr = strAddr[:idx]
+
+	strIp, strUdpPort, err := net.SplitHostPort(strAddr)
+	if err != nil {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"SplitHostPort failed, url: %s, err: %s",
+			url, err.Error())
+		return nil
+	}
+
+	ip := net.ParseIP(strIp)
+	if ip == nil {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"invalid ip: %s",
+			strIp)
+		return nil
+	}
+
+	if ip.To4() == nil && strings.HasPrefix(strAddr, "[") != true {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"IPv6 address not bracketed, url: %s",
+			url)
+		return nil
+	}
+
+	pid := P2pHexString2NodeId(strNodeId)
+	if pid == nil {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"P2pHexString2NodeId failed, strNodeId: %s",
+			strNodeId)
+		return nil
+	}
+
+	udp, err := strconv.ParseUint(strUdpPort, 10, 16)
+	if err != nil {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"ParseUint for UDP port failed, err: %s",
+			err.Error())
+		return nil
+	}
+
+	tcp, err := strconv.ParseUint(strTcpPort, 10, 16)
+	if err != nil {
+		yclog.LogCallerFileLine("P2pParseNodeUrl: " +
+			"ParseUint for TCP port failed, err: %s",
+			err.Error())
+		return nil
+	}
+
+	return &Node {
+		IP:		P2pNormalizeIP(ip),
+		UDP:	uint16(udp),
+		TCP:	uint16(tcp),
+		ID:		*pid,
+	}
+}
+
+//
+// Url of node, see BootstrapNodeUrl for the format
+//
+func P2pNodeUrl(n *Node) string {
+	return fmt.Sprintf("%s@%s:%d",
+		P2pNodeId2HexString(n.ID),
+		net.JoinHostPort(n.IP.String(), strconv.Itoa(int(n.UDP))),
+		n.TCP)
+}
+
+//
+// Normalize ip address: an IPv4 one, including the IPv4-mapped IPv6 address
+// got from a dual-stack socket, is in 4 bytes form, while others are in 16
+// bytes, so addresses of the same node compare equal in bytes.
+//
+func P2pNormalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip.To16()
+}
+
+//
+// Choose the address of a remote node between the one it advertised and the
+// one it contacted us from. The advertised one is taken, unless it's missed,
+// unspecified, or of the other family, for a node might have addresses of
+// both IPv4 and IPv6 while only the one it contacted us from is known to
+// work.
+//
+func P2pPreferredIP(advertised net.IP, observed net.IP) net.IP {
+
+	if len(observed) == 0 {
+		return P2pNormalizeIP(advertised)
+	}
+
+	if len(advertised) == 0 || advertised.IsUnspecified() ||
+		(advertised.To4() == nil) != (observed.To4() == nil) {
+		return P2pNormalizeIP(observed)
+	}
+
+	return P2pNormalizeIP(advertised)
+}
+
+//
+// Tag of network. Nodes of a network share the network identity and the
+// pre-shared key, from which the tag is derived, and it's mixed into the
+// digests signed for udp messages and handshakes, so messages from nodes
+// of other networks, or those without the key, fail to be verified, and
+// such nodes would never be put into the table, nor take a peer slot. The
+// tag itself is never sent. It's nil for the default network, so nothing
+// changed for nodes in it.
+//
+const p2pLabelNetwork = "ycp2p-network"
+
+func P2pNetworkTag(config *Config) []byte {
+
+	if config.NetworkId == dftNetworkId && len(config.NetworkKey) == 0 {
+		return nil
+	}
+
+	var u32 = make([]byte, 4)
+	binary.BigEndian.PutUint32(u32, config.NetworkId)
+
+	h := sha256.New()
+	h.Write([]byte(p2pLabelNetwork))
+	h.Write(u32)
+	h.Write(config.NetworkKey)
+
+	return h.Sum(nil)
 }
 
 //
 // Get configuration of neighbor discovering listener
 //
-func P2pConfig4UdpListener() *Cfg4UdpListener {
+func P2pConfig4UdpListener(config *Config) *Cfg4UdpListener {
 	return &Cfg4UdpListener {
 		IP:		config.Local.IP,
 		UDP:	config.Local.UDP,
 		TCP:	config.Local.TCP,
 		ID:		config.Local.ID,
+		PrivateKey:	config.PrivateKey,
+		Network:	P2pNetworkTag(config),
 	}
 }
 
 //
 // Get configuration of peer listener
 //
-func P2pConfig4PeerListener() *Cfg4PeerListener {
+func P2pConfig4PeerListener(config *Config) *Cfg4PeerListener {
 	return &Cfg4PeerListener {
 		IP:			config.Local.IP,
 		Port:		config.Local.TCP,
@@ -676,12 +1053,13 @@ func P2pConfig4PeerListener() *Cfg4PeerListener {
 //
 // Get configuration of peer manager
 //
-func P2pConfig4PeerManager() *Cfg4PeerManager {
+func P2pConfig4PeerManager(config *Config) *Cfg4PeerManager {
 	return &Cfg4PeerManager {
 		IP:				config.Local.IP,
 		Port:			config.Local.TCP,
 		UDP:			config.Local.UDP,
 		ID:				config.Local.ID,
+		PrivateKey:		config.PrivateKey,
 		MaxPeers:		config.MaxPeers,
 		MaxOutbounds:	config.MaxOutbounds,
 		MaxInBounds:	config.MaxInbounds,
@@ -689,13 +1067,22 @@ func P2pConfig4PeerManager() *Cfg4PeerManager {
 		NoDial:			config.NoDial,
 		ProtoNum:		config.ProtoNum,
 		Protocols:		config.Protocols,
+		TxQueueSize:	config.PeerTxQueueSize,
+		Compress:		config.PeerCompress,
+		CompressMin:	config.PeerCompressMin,
+		ScoreDb:		filepath.Join(config.NodeDataDir, datadirPeerScores),
+		DropScore:		config.PeerDropScore,
+		BanScore:		config.PeerBanScore,
+		BanTime:		config.PeerBanTime,
+		ScoreDecay:		config.PeerScoreDecay,
+		Network:		P2pNetworkTag(config),
 	}
 }
 
 //
 // Get configuration op table manager
 //
-func P2pConfig4TabManager() *Cfg4TabManager {
+func P2pConfig4TabManager(config *Config) *Cfg4TabManager {
 	return &Cfg4TabManager {
 		Local:			config.Local,
 		BootstrapNodes:	config.BootstrapNodes,
@@ -705,10 +1092,67 @@ func P2pConfig4TabManager() *Cfg4TabManager {
 	}
 }
 
+//
+// Get configuration of dht storer
+//
+func P2pConfig4DhtStorer(config *Config) *Cfg4DhtStorer {
+	return &Cfg4DhtStorer {
+		Backend:	config.DhtChunkStore,
+		Path:		filepath.Join(config.NodeDataDir, datadirChunkStore),
+	}
+}
+
+//
+// Get configuration of dht syncer
+//
+func P2pConfig4DhtSyncer(config *Config) *Cfg4DhtSyncer {
+	return &Cfg4DhtSyncer {
+		Local:		config.Local.ID,
+		Replicas:	config.DhtReplicas,
+	}
+}
+
+//
+// Get configuration of gossip
+//
+func P2pConfig4Gossip(config *Config) *Cfg4Gossip {
+	return &Cfg4Gossip {
+		Local:	config.Local.ID,
+	}
+}
+
+//
+// Get configuration of stream
+//
+func P2pConfig4Stream(config *Config) *Cfg4Stream {
+	return &Cfg4Stream {
+		Local:	config.Local.ID,
+	}
+}
+
+//
+// Get configuration of deny and allow lists
+//
+func P2pConfig4Acl(config *Config) *Cfg4Acl {
+	return &Cfg4Acl {
+		Db:			filepath.Join(config.NodeDataDir, datadirAcl),
+		AllowOnly:	config.AclAllowOnly,
+	}
+}
+
+//
+// Get configuration of dht provider
+//
+func P2pConfig4DhtProvider(config *Config) *Cfg4DhtProvider {
+	return &Cfg4DhtProvider {
+		Local:	config.Local,
+	}
+}
+
 //
 // Get protocols
 //
-func P2pConfig4Protocols() *Cfg4Protocols {
+func P2pConfig4Protocols(config *Config) *Cfg4Protocols {
 	return &Cfg4Protocols {
 		ProtoNum: config.ProtoNum,
 		Protocols: config.Protocols,
@@ -22,30 +22,507 @@
 package chunker
 
 import (
+	"fmt"
 	sch 	"github.com/yeeco/p2p/scheduler"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/dht"
 )
 
 //
-// Provider manager
+// errno
 //
-const DhtchMgrName = "DhtchMgr"
+const (
+	DhtchMgrEnoNone	= iota
+	DhtchMgrEnoParameter
+	DhtchMgrEnoScheduler
+	DhtchMgrEnoStore
+	DhtchMgrEnoRetrive
+	DhtchMgrEnoIntegrity
+	DhtchMgrEnoUnknown
+)
+
+type DhtchMgrErrno int
+
+//
+// Max chunk requests in flight to the storer and retriver. Since they confirm
+// to us by events, this should be well under the size of mailbox of tasks to
+// avoid being blocked each other.
+//
+const chMaxInflight = 64
+
+//
+// Segment of object while retriving, it's a block in the DAG
+//
+type chSegment struct {
+	key			[]byte			// key of block
+	size		uint64			// size of data under block, from the parent link
+	root		bool			// is root block
+	node		*Node			// block decoded
+	children	[]*chSegment	// children of link block
+}
+
+//
+// Object operation: store or retrive an object
+//
+type chObject struct {
+	store		bool			// store or retrive
+	id			uint64			// identity of the user request
+	ptn			interface{}		// task to confirm to, nil for the user of dht
+	key			[]byte			// key of the root block
+	size		uint64			// size of object
+	root		*chSegment		// root segment, for retriving
+	pending		int				// chunk requests not confirmed
+	done		bool			// had been confirmed to the user
+}
+
+//
+// Chunk request
+//
+type chRequest struct {
+	qid			uint64			// identity of request
+	obj			*chObject		// object the request belongs to
+	block		*Block			// block to store, for storing
+	seg			*chSegment		// segment to retrive, for retriving
+}
+
+//
+// Chunker manager
+//
+const DhtchMgrName = sch.DhtchMgrName
 
 type dhtChunkerManager struct {
-	name	string				// name
-	tep		sch.SchUserTaskEp	// entry
+	name		string					// name
+	tep			sch.SchUserTaskEp		// entry
+	sdl			*sch.Scheduler			// pointer to scheduler
+	ptnMe		interface{}				// pointer to myself task node
+	ptnSt		interface{}				// pointer to storer task node
+	ptnRe		interface{}				// pointer to retriver task node
+	qidSeq		uint64					// sequence for request identities
+	queue		[]*chRequest			// chunk requests waiting to be sent
+	inflight	map[uint64]*chRequest	// chunk requests sent
 }
 
-var dhtchMgr = dhtChunkerManager{
-	name:	DhtchMgrName,
-	tep:	DhtchMgrProc,
+//
+// Create chunker manager, one for each p2p instance
+//
+func NewDhtchMgr() interface{} {
+	return &dhtChunkerManager{
+		name:		DhtchMgrName,
+		tep:		DhtchMgrProc,
+		ptnMe:		nil,
+		ptnSt:		nil,
+		ptnRe:		nil,
+		qidSeq:		0,
+		queue:		nil,
+		inflight:	map[uint64]*chRequest{},
+	}
 }
 
 //
-// Table manager entry
+// Chunker manager entry
 //
 func DhtchMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtchMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtchMgr := sch.SchinfGetUserDataArea(ptn).(*dhtChunkerManager)
+
+	var eno DhtchMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = dhtchMgr.dhtchMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = dhtchMgr.dhtchMgrPoweroff(ptn)
+
+	case sch.EvDhtObjStoreReq:
+		eno = dhtchMgr.dhtchMgrObjStoreReq(msg.Body.(*sch.MsgDhtObjStoreReq))
+
+	case sch.EvDhtObjRetriveReq:
+		eno = dhtchMgr.dhtchMgrObjRetriveReq(msg.Body.(*sch.MsgDhtObjRetriveReq))
+
+	case sch.EvDhtStoreCfm:
+		eno = dhtchMgr.dhtchMgrStoreCfm(msg.Body.(*sch.MsgDhtStoreCfm))
+
+	case sch.EvDhtRetriveCfm:
+		eno = dhtchMgr.dhtchMgrRetriveCfm(msg.Body.(*sch.MsgDhtRetriveCfm))
+
+	default:
+		yclog.LogCallerFileLine("DhtchMgrProc: invalid message: %d", msg.Id)
+		eno = DhtchMgrEnoParameter
+	}
+
+	if eno != DhtchMgrEnoNone {
+		yclog.LogCallerFileLine("DhtchMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweron handler
+//
+func (dhtchMgr *dhtChunkerManager) dhtchMgrPoweron(ptn interface{}) DhtchMgrErrno {
+
+	var eno sch.SchErrno
+
+	dhtchMgr.sdl = sch.SchinfGetScheduler(ptn)
+	dhtchMgr.ptnMe = ptn
+
+	if eno, dhtchMgr.ptnSt = sch.SchinfGetTaskNodeByName(dhtchMgr.sdl, sch.DhtstMgrName);
+	eno != sch.SchEnoNone || dhtchMgr.ptnSt == nil {
+
+		yclog.LogCallerFileLine("dhtchMgrPoweron: " +
+			"SchinfGetTaskNodeByName failed, eno: %d, target: %s",
+			eno, sch.DhtstMgrName)
+
+		return DhtchMgrEnoScheduler
+	}
+
+	if eno, dhtchMgr.ptnRe = sch.SchinfGetTaskNodeByName(dhtchMgr.sdl, sch.DhtreMgrName);
+	eno != sch.SchEnoNone || dhtchMgr.ptnRe == nil {
+
+		yclog.LogCallerFileLine("dhtchMgrPoweron: " +
+			"SchinfGetTaskNodeByName failed, eno: %d, target: %s",
+			eno, sch.DhtreMgrName)
+
+		return DhtchMgrEnoScheduler
+	}
+
+	return DhtchMgrEnoNone
+}
+
+//
+// Poweroff handler
+//
+func (dhtchMgr *dhtChunkerManager) dhtchMgrPoweroff(ptn interface{}) DhtchMgrErrno {
+
+	dhtchMgr.queue = nil
+	dhtchMgr.inflight = map[uint64]*chRequest{}
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtchMgrEnoUnknown
+	}
+
+	return DhtchMgrEnoNone
+}
+
+//
+// Store object request handler: build the DAG and store all blocks
+//
+func (dhtchMgr *dhtChunkerManager) dhtchMgrObjStoreReq(req *sch.MsgDhtObjStoreReq) DhtchMgrErrno {
+
+	if req.Mode != ChunkModeFixed && req.Mode != ChunkModeRolling {
+
+		yclog.LogCallerFileLine("dhtchMgrObjStoreReq: " +
+			"invalid mode: %d",
+			req.Mode)
+
+		dhtchMgr.dhtchStoreConfirm(&chObject{id: req.Id, ptn: req.Ptn}, DhtchMgrEnoParameter)
+		return DhtchMgrEnoNone
+	}
+
+	key, blocks := BuildDag(req.Data, req.Mode)
+
+	obj := &chObject {
+		store:		true,
+		id:			req.Id,
+		ptn:		req.Ptn,
+		key:		key,
+		size:		uint64(len(req.Data)),
+		pending:	len(blocks),
+	}
+
+	for _, b := range blocks {
+		dhtchMgr.dhtchEnqueue(&chRequest{obj: obj, block: b})
+	}
+
+	dhtchMgr.dhtchPump()
+
+	return DhtchMgrEnoNone
+}
+
+//
+// Retrive object request handler: retrive the root block and then go down
+//
+func (dhtchMgr *dhtChunkerManager) dhtchMgrObjRetriveReq(req *sch.MsgDhtObjRetriveReq) DhtchMgrErrno {
+
+	if len(req.Key) != ChunkKeySize {
+
+		yclog.LogCallerFileLine("dhtchMgrObjRetriveReq: " +
+			"invalid key: %s",
+			fmt.Sprintf("%X", req.Key))
+
+		dhtchMgr.dhtchRetriveConfirm(&chObject{id: req.Id, ptn: req.Ptn, key: req.Key}, nil, DhtchMgrEnoParameter)
+		return DhtchMgrEnoNone
+	}
+
+	obj := &chObject {
+		store:		false,
+		id:			req.Id,
+		ptn:		req.Ptn,
+		key:		req.Key,
+		root:		&chSegment{key: req.Key, root: true},
+		pending:	1,
+	}
+
+	dhtchMgr.dhtchEnqueue(&chRequest{obj: obj, seg: obj.root})
+	dhtchMgr.dhtchPump()
+
+	return DhtchMgrEnoNone
+}
+
+//
+// Store chunk confirm handler
+//
+func (dhtchMgr *dhtChunkerManager) dhtchMgrStoreCfm(cfm *sch.MsgDhtStoreCfm) DhtchMgrErrno {
+
+	req, ok := dhtchMgr.inflight[cfm.Id]
+	if !ok {
+		yclog.LogCallerFileLine("dhtchMgrStoreCfm: request not found, id: %d", cfm.Id)
+		return DhtchMgrEnoNone
+	}
+
+	delete(dhtchMgr.inflight, cfm.Id)
+	obj := req.obj
+
+	if !obj.done {
+
+		if cfm.Eno != 0 {
+
+			yclog.LogCallerFileLine("dhtchMgrStoreCfm: " +
+				"store failed, eno: %d, key: %s",
+				cfm.Eno, fmt.Sprintf("%X", cfm.Key))
+
+			dhtchMgr.dhtchStoreConfirm(obj, DhtchMgrEnoStore)
+
+		} else if obj.pending--; obj.pending == 0 {
+
+			dhtchMgr.dhtchStoreConfirm(obj, DhtchMgrEnoNone)
+		}
+	}
+
+	dhtchMgr.dhtchPump()
+
+	return DhtchMgrEnoNone
+}
+
+//
+// Retrive chunk confirm handler: check the block, and go down if it's a link
+// block, or reassemble the object if all blocks are retrived.
+//
+func (dhtchMgr *dhtChunkerManager) dhtchMgrRetriveCfm(cfm *sch.MsgDhtRetriveCfm) DhtchMgrErrno {
+
+	req, ok := dhtchMgr.inflight[cfm.Id]
+	if !ok {
+		yclog.LogCallerFileLine("dhtchMgrRetriveCfm: request not found, id: %d", cfm.Id)
+		return DhtchMgrEnoNone
+	}
+
+	delete(dhtchMgr.inflight, cfm.Id)
+	obj, seg := req.obj, req.seg
+
+	if obj.done {
+		dhtchMgr.dhtchPump()
+		return DhtchMgrEnoNone
+	}
+
+	if cfm.Eno != 0 {
+
+		yclog.LogCallerFileLine("dhtchMgrRetriveCfm: " +
+			"retrive failed, eno: %d, key: %s",
+			cfm.Eno, fmt.Sprintf("%X", seg.key))
+
+		dhtchMgr.dhtchRetriveConfirm(obj, nil, DhtchMgrEnoRetrive)
+		dhtchMgr.dhtchPump()
+
+		return DhtchMgrEnoNone
+	}
+
+	node, err := DecodeBlock(seg.key, cfm.Chunk)
+	if err == nil && !seg.root && node.Size != seg.size {
+		err = ErrBlockFormat
+	}
+
+	if err != nil {
+
+		yclog.LogCallerFileLine("dhtchMgrRetriveCfm: " +
+			"invalid block, err: %s, key: %s",
+			err.Error(), fmt.Sprintf("%X", seg.key))
+
+		dhtchMgr.dhtchRetriveConfirm(obj, nil, DhtchMgrEnoIntegrity)
+		dhtchMgr.dhtchPump()
+
+		return DhtchMgrEnoNone
+	}
+
+	seg.node = node
+	obj.pending--
+
+	for _, l := range node.Links {
+		child := &chSegment{key: l.Key, size: l.Size}
+		seg.children = append(seg.children, child)
+		dhtchMgr.dhtchEnqueue(&chRequest{obj: obj, seg: child})
+		obj.pending++
+	}
+
+	if obj.pending == 0 {
+
+		data := make([]byte, 0, obj.root.node.Size)
+		data = dhtchAssemble(obj.root, data)
+
+		if uint64(len(data)) != obj.root.node.Size {
+
+			yclog.LogCallerFileLine("dhtchMgrRetriveCfm: " +
+				"size mismatched, expected: %d, real: %d",
+				obj.root.node.Size, len(data))
+
+			dhtchMgr.dhtchRetriveConfirm(obj, nil, DhtchMgrEnoIntegrity)
+
+		} else {
+
+			dhtchMgr.dhtchRetriveConfirm(obj, data, DhtchMgrEnoNone)
+		}
+	}
+
+	dhtchMgr.dhtchPump()
+
+	return DhtchMgrEnoNone
+}
+
+//
+// Collect data of segments in order
+//
+func dhtchAssemble(seg *chSegment, data []byte) []byte {
+
+	if len(seg.children) == 0 {
+		return append(data, seg.node.Data...)
+	}
+
+	for _, child := range seg.children {
+		data = dhtchAssemble(child, data)
+	}
+
+	return data
+}
+
+//
+// Queue a chunk request
+//
+func (dhtchMgr *dhtChunkerManager) dhtchEnqueue(req *chRequest) {
+	dhtchMgr.queue = append(dhtchMgr.queue, req)
+}
+
+//
+// Send chunk requests queued, with requests in flight limited
+//
+func (dhtchMgr *dhtChunkerManager) dhtchPump() {
+
+	for len(dhtchMgr.queue) > 0 && len(dhtchMgr.inflight) < chMaxInflight {
+
+		req := dhtchMgr.queue[0]
+		dhtchMgr.queue[0] = nil
+		dhtchMgr.queue = dhtchMgr.queue[1:]
+
+		//
+		// requests of objects failed are discarded
+		//
+
+		if req.obj.done {
+			continue
+		}
+
+		dhtchMgr.qidSeq++
+		req.qid = dhtchMgr.qidSeq
+
+		var ptnTo interface{}
+		var evId int
+		var body interface{}
+
+		if req.obj.store {
+
+			ptnTo, evId = dhtchMgr.ptnSt, sch.EvDhtStoreReq
+			body = &sch.MsgDhtStoreReq {
+				Key:	req.block.Key,
+				Chunk:	req.block.Data,
+				Id:		req.qid,
+				Ptn:	dhtchMgr.ptnMe,
+			}
+
+		} else {
+
+			ptnTo, evId = dhtchMgr.ptnRe, sch.EvDhtRetriveReq
+			body = &sch.MsgDhtRetriveReq {
+				Key:	req.seg.key,
+				Id:		req.qid,
+				Ptn:	dhtchMgr.ptnMe,
+			}
+		}
+
+		var schMsg = sch.SchMessage{}
+		var eno sch.SchErrno
+
+		if eno = sch.SchinfMakeMessage(&schMsg, dhtchMgr.ptnMe, ptnTo, evId, body);
+		eno == sch.SchEnoNone {
+			eno = sch.SchinfSendMessage(&schMsg)
+		}
+
+		if eno != sch.SchEnoNone {
+
+			yclog.LogCallerFileLine("dhtchPump: " +
+				"send request failed, eno: %d, target: %s",
+				eno, sch.SchinfGetTaskName(ptnTo))
+
+			if req.obj.store {
+				dhtchMgr.dhtchStoreConfirm(req.obj, DhtchMgrEnoScheduler)
+			} else {
+				dhtchMgr.dhtchRetriveConfirm(req.obj, nil, DhtchMgrEnoScheduler)
+			}
+
+			continue
+		}
+
+		dhtchMgr.inflight[req.qid] = req
+	}
+}
+
+//
+// Confirm store object request to the requester
+//
+func (dhtchMgr *dhtChunkerManager) dhtchStoreConfirm(obj *chObject, eno DhtchMgrErrno) {
+
+	obj.done = true
+
+	var cfm = sch.MsgDhtObjStoreCfm {
+		Eno:	int(eno),
+		Key:	nil,
+		Size:	obj.size,
+		Id:		obj.id,
+	}
+
+	if eno == DhtchMgrEnoNone {
+		cfm.Key = obj.key
+	}
+
+	dht.DhtConfirmTo(dhtchMgr.ptnMe, obj.ptn, sch.EvDhtObjStoreCfm, &cfm)
+}
+
+//
+// Confirm retrive object request to the requester
+//
+func (dhtchMgr *dhtChunkerManager) dhtchRetriveConfirm(obj *chObject, data []byte, eno DhtchMgrErrno) {
+
+	obj.done = true
+
+	var cfm = sch.MsgDhtObjRetriveCfm {
+		Eno:	int(eno),
+		Key:	obj.key,
+		Data:	data,
+		Id:		obj.id,
+	}
+
+	dht.DhtConfirmTo(dhtchMgr.ptnMe, obj.ptn, sch.EvDhtObjRetriveCfm, &cfm)
+}
@@ -0,0 +1,306 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+
+package chunker
+
+import (
+	"io"
+	"bytes"
+	"errors"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+//
+// Objects are split into chunks, fixed-size or content-defined, and then a
+// Merkle DAG is built on them: the leaves are data blocks holding the chunks,
+// and the inner nodes are link blocks holding keys and sizes of their children.
+// Every block is stored under the sha256 of itself, so the key of the root
+// block identifies the whole object, and any block retrieved can be checked
+// against the key it's asked by.
+//
+//	data block:	chBlockData | chunk bytes
+//	link block:	chBlockLink | uvarint(object size under) | uvarint(n) | n * (key | uvarint(size))
+//
+const (
+	ChunkModeFixed		= 0						// fixed-size chunks
+	ChunkModeRolling	= 1						// content-defined chunks by rolling hash
+)
+
+const (
+	chFixedSize			= 256 * 1024			// size of fixed-size chunk
+	chRollingMin		= 64 * 1024				// min size of content-defined chunk
+	chRollingMax		= 1024 * 1024			// max size of content-defined chunk
+	chRollingMask		= (1 << 18) - 1			// mask for boundary, average about 256K
+	chMaxLinks			= 1024					// max links in a link block
+	ChunkKeySize		= sha256.Size			// size of block key
+)
+
+const (
+	chBlockData			= 0x00					// data block
+	chBlockLink			= 0x01					// link block
+)
+
+var (
+	ErrBlockKey			= errors.New("chunker: block mismatched with key")
+	ErrBlockFormat		= errors.New("chunker: invalid block format")
+)
+
+//
+// Block of DAG
+//
+type Block struct {
+	Key		[]byte		// sha256 of Data
+	Data	[]byte		// block bytes
+}
+
+//
+// Link to a child block
+//
+type Link struct {
+	Key		[]byte		// key of child
+	Size	uint64		// size of object data under child
+}
+
+//
+// Block decoded
+//
+type Node struct {
+	Data	[]byte		// chunk data, for data block
+	Links	[]Link		// links, for link block
+	Size	uint64		// size of object data under this node
+}
+
+//
+// Gear table for the rolling hash. It's generated deterministically so that
+// all nodes cut the same data at the same boundaries.
+//
+var chGear [256]uint64
+
+func init() {
+	for i := 0; i < len(chGear); i++ {
+		h := sha256.Sum256([]byte{byte(i)})
+		chGear[i] = binary.BigEndian.Uint64(h[:8])
+	}
+}
+
+//
+// Split data into chunks
+//
+func Split(data []byte, mode int) [][]byte {
+
+	var chunks [][]byte
+
+	if len(data) == 0 {
+		return [][]byte{data}
+	}
+
+	for len(data) > 0 {
+
+		var cut int
+
+		if mode == ChunkModeRolling {
+			cut = chRollingCut(data)
+		} else if cut = chFixedSize; cut > len(data) {
+			cut = len(data)
+		}
+
+		chunks = append(chunks, data[:cut])
+		data = data[cut:]
+	}
+
+	return chunks
+}
+
+//
+// Find the boundary of the next content-defined chunk by gear hash
+//
+func chRollingCut(data []byte) int {
+
+	if len(data) <= chRollingMin {
+		return len(data)
+	}
+
+	end := len(data)
+	if end > chRollingMax {
+		end = chRollingMax
+	}
+
+	var h uint64 = 0
+
+	for i := chRollingMin; i < end; i++ {
+		h = (h << 1) + chGear[data[i]]
+		if h & chRollingMask == 0 {
+			return i + 1
+		}
+	}
+
+	return end
+}
+
+//
+// Make a block with key
+//
+func chMakeBlock(data []byte) *Block {
+	k := sha256.Sum256(data)
+	return &Block{Key: k[:], Data: data}
+}
+
+//
+// Encode a link block
+//
+func chEncodeLinks(links []Link) []byte {
+
+	var size uint64 = 0
+	for _, l := range links {
+		size += l.Size
+	}
+
+	u := make([]byte, binary.MaxVarintLen64)
+	buf := new(bytes.Buffer)
+	buf.WriteByte(chBlockLink)
+	buf.Write(u[:binary.PutUvarint(u, size)])
+	buf.Write(u[:binary.PutUvarint(u, uint64(len(links)))])
+
+	for _, l := range links {
+		buf.Write(l.Key)
+		buf.Write(u[:binary.PutUvarint(u, l.Size)])
+	}
+
+	return buf.Bytes()
+}
+
+//
+// Build the DAG for data, the root key and all blocks are returned, the root
+// block is the last one.
+//
+func BuildDag(data []byte, mode int) ([]byte, []*Block) {
+
+	var blocks []*Block
+	var links []Link
+
+	for _, c := range Split(data, mode) {
+		b := chMakeBlock(append([]byte{chBlockData}, c...))
+		blocks = append(blocks, b)
+		links = append(links, Link{Key: b.Key, Size: uint64(len(c))})
+	}
+
+	//
+	// a single chunk object is identified by its' data block directly
+	//
+
+	for len(links) > 1 {
+
+		var upper []Link
+
+		for len(links) > 0 {
+
+			n := len(links)
+			if n > chMaxLinks {
+				n = chMaxLinks
+			}
+
+			b := chMakeBlock(chEncodeLinks(links[:n]))
+			blocks = append(blocks, b)
+
+			var size uint64 = 0
+			for _, l := range links[:n] {
+				size += l.Size
+			}
+
+			upper = append(upper, Link{Key: b.Key, Size: size})
+			links = links[n:]
+		}
+
+		links = upper
+	}
+
+	return links[0].Key, blocks
+}
+
+//
+// Check block against the key and decode it
+//
+func DecodeBlock(key []byte, block []byte) (*Node, error) {
+
+	if k := sha256.Sum256(block); bytes.Equal(k[:], key) != true {
+		return nil, ErrBlockKey
+	}
+
+	if len(block) == 0 {
+		return nil, ErrBlockFormat
+	}
+
+	switch block[0] {
+
+	case chBlockData:
+		return &Node{Data: block[1:], Size: uint64(len(block) - 1)}, nil
+
+	case chBlockLink:
+
+		r := bytes.NewReader(block[1:])
+
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, ErrBlockFormat
+		}
+
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n == 0 || n > chMaxLinks {
+			return nil, ErrBlockFormat
+		}
+
+		node := &Node{Links: make([]Link, 0, n), Size: size}
+		var sum uint64 = 0
+
+		for i := uint64(0); i < n; i++ {
+
+			l := Link{Key: make([]byte, ChunkKeySize)}
+
+			if _, err := io.ReadFull(r, l.Key); err != nil {
+				return nil, ErrBlockFormat
+			}
+
+			if l.Size, err = binary.ReadUvarint(r); err != nil {
+				return nil, ErrBlockFormat
+			}
+
+			//
+			// the sum must not overflow, it's never more than the size
+			//
+
+			if l.Size > size - sum {
+				return nil, ErrBlockFormat
+			}
+
+			sum += l.Size
+			node.Links = append(node.Links, l)
+		}
+
+		if sum != size || r.Len() != 0 {
+			return nil, ErrBlockFormat
+		}
+
+		return node, nil
+	}
+
+	return nil, ErrBlockFormat
+}
@@ -0,0 +1,263 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package chunker
+
+import (
+	"bytes"
+	"testing"
+	"math/rand"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+//
+// Test data of size specified, random or zeros
+//
+func dagTestData(size int, zero bool) []byte {
+	data := make([]byte, size)
+	if !zero {
+		rand.New(rand.NewSource(int64(size))).Read(data)
+	}
+	return data
+}
+
+//
+// Encode a link block with the object size claimed, which might not be the
+// sum of the link sizes, and extra bytes appended.
+//
+func dagTestLinks(size uint64, n uint64, links []Link, extra []byte) []byte {
+	u := make([]byte, binary.MaxVarintLen64)
+	buf := new(bytes.Buffer)
+	buf.WriteByte(chBlockLink)
+	buf.Write(u[:binary.PutUvarint(u, size)])
+	buf.Write(u[:binary.PutUvarint(u, n)])
+	for _, l := range links {
+		buf.Write(l.Key)
+		buf.Write(u[:binary.PutUvarint(u, l.Size)])
+	}
+	buf.Write(extra)
+	return buf.Bytes()
+}
+
+//
+// Retrive the object from blocks as the chunker manager does, see function
+// dhtchMgrRetriveCfm.
+//
+func dagTestAssemble(t *testing.T, root []byte, blocks map[string][]byte) []byte {
+
+	var fetch func(seg *chSegment)
+
+	fetch = func(seg *chSegment) {
+
+		node, err := DecodeBlock(seg.key, blocks[string(seg.key)])
+		if err == nil && !seg.root && node.Size != seg.size {
+			err = ErrBlockFormat
+		}
+		if err != nil {
+			t.Fatalf("DecodeBlock failed, err: %s", err.Error())
+		}
+
+		seg.node = node
+
+		for _, l := range node.Links {
+			child := &chSegment{key: l.Key, size: l.Size}
+			seg.children = append(seg.children, child)
+			fetch(child)
+		}
+	}
+
+	seg := &chSegment{key: root, root: true}
+	fetch(seg)
+
+	data := dhtchAssemble(seg, make([]byte, 0, seg.node.Size))
+	if uint64(len(data)) != seg.node.Size {
+		t.Fatalf("size mismatched, expected: %d, real: %d", seg.node.Size, len(data))
+	}
+
+	return data
+}
+
+func TestDecodeBlockKey(t *testing.T) {
+
+	data := chMakeBlock(append([]byte{chBlockData}, "hello"...))
+	other := chMakeBlock(append([]byte{chBlockData}, "world"...))
+	tampered := append([]byte{}, data.Data...)
+	tampered[1] ^= 0x01
+
+	cases := []struct {
+		name	string
+		key		[]byte
+		block	[]byte
+	}{
+		{"other key", other.Key, data.Data},
+		{"nil key", nil, data.Data},
+		{"short key", data.Key[:ChunkKeySize-1], data.Data},
+		{"tampered block", data.Key, tampered},
+		{"nil block", data.Key, nil},
+	}
+
+	for _, c := range cases {
+		if _, err := DecodeBlock(c.key, c.block); err != ErrBlockKey {
+			t.Fatalf("%s: expected ErrBlockKey, got: %v", c.name, err)
+		}
+	}
+
+	node, err := DecodeBlock(data.Key, data.Data)
+	if err != nil || !bytes.Equal(node.Data, []byte("hello")) || node.Size != 5 {
+		t.Fatalf("valid block: %v, %+v", err, node)
+	}
+}
+
+func TestDecodeBlockFormat(t *testing.T) {
+
+	k1 := sha256.Sum256([]byte{1})
+	k2 := sha256.Sum256([]byte{2})
+	links := []Link{{Key: k1[:], Size: 100}, {Key: k2[:], Size: 200}}
+
+	many := make([]Link, chMaxLinks + 1)
+	for i := range many {
+		many[i] = Link{Key: k1[:], Size: 1}
+	}
+
+	cases := []struct {
+		name	string
+		block	[]byte
+	}{
+		{"empty", []byte{}},
+		{"unknown type", []byte{0x02, 1, 2, 3}},
+		{"sizes under", dagTestLinks(299, 2, links, nil)},
+		{"sizes over", dagTestLinks(301, 2, links, nil)},
+		{"sizes overflow", dagTestLinks(300, 2, []Link{{Key: k1[:], Size: 1<<64 - 1}, {Key: k2[:], Size: 301}}, nil)},
+		{"no links", dagTestLinks(0, 0, nil, nil)},
+		{"too many links", dagTestLinks(uint64(len(many)), uint64(len(many)), many, nil)},
+		{"links missed", dagTestLinks(300, 3, links, nil)},
+		{"trailing bytes", dagTestLinks(300, 2, links, []byte{0})},
+		{"truncated key", dagTestLinks(300, 2, links, nil)[:40]},
+		{"truncated header", []byte{chBlockLink, 0x80}},
+	}
+
+	for _, c := range cases {
+		k := sha256.Sum256(c.block)
+		if _, err := DecodeBlock(k[:], c.block); err != ErrBlockFormat {
+			t.Fatalf("%s: expected ErrBlockFormat, got: %v", c.name, err)
+		}
+	}
+
+	block := dagTestLinks(300, 2, links, nil)
+	k := sha256.Sum256(block)
+	node, err := DecodeBlock(k[:], block)
+	if err != nil || len(node.Links) != 2 || node.Size != 300 {
+		t.Fatalf("valid link block: %v, %+v", err, node)
+	}
+}
+
+func TestSplitRoundTrip(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		mode	int
+		size	int
+		zero	bool
+	}{
+		{"fixed empty", ChunkModeFixed, 0, false},
+		{"fixed one byte", ChunkModeFixed, 1, false},
+		{"fixed under boundary", ChunkModeFixed, chFixedSize - 1, false},
+		{"fixed on boundary", ChunkModeFixed, chFixedSize, false},
+		{"fixed over boundary", ChunkModeFixed, chFixedSize + 1, false},
+		{"fixed multiple", ChunkModeFixed, 3 * chFixedSize + 7, false},
+		{"rolling empty", ChunkModeRolling, 0, false},
+		{"rolling under min", ChunkModeRolling, chRollingMin - 1, false},
+		{"rolling on min", ChunkModeRolling, chRollingMin, false},
+		{"rolling over min", ChunkModeRolling, chRollingMin + 1, false},
+		{"rolling on max", ChunkModeRolling, chRollingMax, false},
+		{"rolling over max", ChunkModeRolling, chRollingMax + 1, false},
+		{"rolling multiple", ChunkModeRolling, 4 * chRollingMax + 3, false},
+		{"rolling zeros", ChunkModeRolling, 2 * chRollingMax + 1, true},
+	}
+
+	for _, c := range cases {
+
+		data := dagTestData(c.size, c.zero)
+		chunks := Split(data, c.mode)
+
+		if !bytes.Equal(bytes.Join(chunks, nil), data) {
+			t.Fatalf("%s: chunks not joined to data", c.name)
+		}
+
+		for i, ch := range chunks {
+
+			last := i == len(chunks) - 1
+
+			if c.mode == ChunkModeFixed {
+				if len(ch) > chFixedSize || (!last && len(ch) != chFixedSize) {
+					t.Fatalf("%s: chunk %d of size %d", c.name, i, len(ch))
+				}
+			} else {
+				if len(ch) > chRollingMax || (!last && len(ch) <= chRollingMin) {
+					t.Fatalf("%s: chunk %d of size %d", c.name, i, len(ch))
+				}
+			}
+		}
+
+		root, blocks := BuildDag(data, c.mode)
+
+		if !bytes.Equal(blocks[len(blocks)-1].Key, root) {
+			t.Fatalf("%s: root block is not the last one", c.name)
+		}
+
+		store := map[string][]byte{}
+		for _, b := range blocks {
+			store[string(b.Key)] = b.Data
+		}
+
+		if got := dagTestAssemble(t, root, store); !bytes.Equal(got, data) {
+			t.Fatalf("%s: data reassembled mismatched", c.name)
+		}
+	}
+}
+
+func TestSplitRollingShift(t *testing.T) {
+
+	//
+	// content-defined boundaries come back after some bytes inserted in
+	// front, so the chunks after the first few are shared.
+	//
+
+	data := dagTestData(8 * chRollingMax, false)
+	shifted := append([]byte("inserted"), data...)
+
+	seen := map[[sha256.Size]byte]bool{}
+	for _, ch := range Split(data, ChunkModeRolling) {
+		seen[sha256.Sum256(ch)] = true
+	}
+
+	chunks := Split(shifted, ChunkModeRolling)
+	shared := 0
+	for _, ch := range chunks {
+		if seen[sha256.Sum256(ch)] {
+			shared++
+		}
+	}
+
+	if shared < len(chunks) - 2 {
+		t.Fatalf("shared chunks: %d of %d", shared, len(chunks))
+	}
+}
@@ -22,30 +22,415 @@
 package dht
 
 import (
+	"sync"
+	"fmt"
 	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
+	"github.com/yeeco/p2p/peer"
+	dm		"github.com/yeeco/p2p/dht/dhtmsg"
 )
 
+//
+// errno
+//
+const (
+	DhtMgrEnoNone	= iota
+	DhtMgrEnoParameter
+	DhtMgrEnoScheduler
+	DhtMgrEnoMessage
+	DhtMgrEnoPeer
+	DhtMgrEnoUnknown
+)
+
+type DhtMgrErrno int
+
 //
 // DHT manager
 //
-const DhtMgrName = "DhtMgr"
+const DhtMgrName = sch.DhtMgrName
 
 type dhtManager struct {
-	name	string				// name
-	tep		sch.SchUserTaskEp	// entry
+	name		string					// name
+	tep			sch.SchUserTaskEp		// entry
+	sdl			*sch.Scheduler			// pointer to scheduler
+	ptnMe		interface{}				// pointer to myself task node
+	ptnTasks	map[string]interface{}	// map task name to task node
+	cfmCb		DhtConfirmCallback		// confirm callback
+	cfmLock		sync.Mutex				// lock for confirm callback
 }
 
-var dhtMgr = dhtManager{
-	name:	DhtMgrName,
-	tep:	DhtMgrProc,
+//
+// Create dht manager, one for each p2p instance
+//
+func NewDhtMgr() interface{} {
+	return &dhtManager{
+		name:		DhtMgrName,
+		tep:		DhtMgrProc,
+		ptnMe:		nil,
+		ptnTasks:	map[string]interface{}{},
+	}
 }
 
 //
-// Table manager entry
+// Get dht manager of a p2p instance by its' scheduler
+//
+func dhtGetManager(sdl *sch.Scheduler) *dhtManager {
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(sdl, DhtMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+		return nil
+	}
+
+	dhtMgr, _ := sch.SchinfGetUserDataArea(ptn).(*dhtManager)
+
+	return dhtMgr
+}
+
+//
+// DHT manager entry
 //
 func DhtMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtMgr := sch.SchinfGetUserDataArea(ptn).(*dhtManager)
+
+	var eno DhtMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = dhtMgr.dhtMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = dhtMgr.dhtMgrPoweroff(ptn)
+
+	case sch.EvDhtMgrPkgInd:
+		eno = dhtMgr.dhtMgrPkgInd(msg.Body.(*sch.MsgDhtPkgInd))
+
+	case sch.EvDhtMgrPeerInd:
+		eno = dhtMgr.dhtMgrPeerInd(msg.Body.(*sch.MsgDhtPeerInd))
+
+	default:
+		yclog.LogCallerFileLine("DhtMgrProc: invalid message: %d", msg.Id)
+		eno = DhtMgrEnoParameter
+	}
+
+	if eno != DhtMgrEnoNone {
+		yclog.LogCallerFileLine("DhtMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweron handler
+//
+func (dhtMgr *dhtManager) dhtMgrPoweron(ptn interface{}) DhtMgrErrno {
+
+	dhtMgr.sdl = sch.SchinfGetScheduler(ptn)
+	dhtMgr.ptnMe = ptn
+	dhtMgr.ptnTasks = map[string]interface{}{}
+
+	//
+	// the dht tasks messages dispatched to are looked up while the first
+	// message for them comes, see dhtMgrTaskNode.
+	//
+
+	return DhtMgrEnoNone
+}
+
+//
+// Poweroff handler
+//
+func (dhtMgr *dhtManager) dhtMgrPoweroff(ptn interface{}) DhtMgrErrno {
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtMgrEnoUnknown
+	}
+
+	return DhtMgrEnoNone
+}
+
+//
+// Package from peer handler: decode the message and dispatch it to the task
+// it belongs to.
+//
+func (dhtMgr *dhtManager) dhtMgrPkgInd(ind *sch.MsgDhtPkgInd) DhtMgrErrno {
+
+	msg, eno := dm.Decode(ind.Payload)
+	if eno != dm.DhtMsgEnoNone {
+
+		yclog.LogCallerFileLine("dhtMgrPkgInd: " +
+			"Decode failed, eno: %d, from: %s",
+			eno, fmt.Sprintf("%X", ind.From))
+
+		return DhtMgrEnoMessage
+	}
+
+	var evId int
+	var body interface{}
+	var task string
+
+	switch msg.Mid {
+
+	case dm.MID_DHT_FINDVALUE:
+		task, evId = sch.DhtreMgrName, sch.EvDhtPeerLkFindValueReq
+		body = &sch.MsgDhtPeerLkFindValueReq{From: ind.From, FindValue: msg.FindValue}
+
+	case dm.MID_DHT_VALUE:
+		task, evId = sch.DhtreMgrName, sch.EvDhtPeerLkValueRsp
+		body = &sch.MsgDhtPeerLkValueRsp{From: ind.From, Value: msg.Value}
+
+	case dm.MID_DHT_FINDNODE:
+		task, evId = sch.DhtroMgrName, sch.EvDhtPeerLkFindNodeReq
+		body = &sch.MsgDhtPeerLkFindNodeReq{From: ind.From, FindNode: msg.FindNode}
+
+	case dm.MID_DHT_NEIGHBORS:
+		task, evId = sch.DhtroMgrName, sch.EvDhtPeerLkNeighborsRsp
+		body = &sch.MsgDhtPeerLkNeighborsRsp{From: ind.From, Neighbors: msg.Neighbors}
+
+	case dm.MID_DHT_STORE:
+		task, evId = sch.DhtstMgrName, sch.EvDhtPeerLkStoreReq
+		body = &sch.MsgDhtPeerLkStoreReq{From: ind.From, Store: msg.Store}
+
+	case dm.MID_DHT_STORERSP:
+		task, evId = sch.DhtsyMgrName, sch.EvDhtPeerLkStoreRsp
+		body = &sch.MsgDhtPeerLkStoreRsp{From: ind.From, StoreRsp: msg.StoreRsp}
+
+	case dm.MID_DHT_GETPROVIDERS:
+		task, evId = sch.DhtpMgrName, sch.EvDhtPrdGetProvidersReq
+		body = &sch.MsgDhtPrdGetProvidersReq{From: ind.From, GetProviders: msg.GetProviders}
+
+	case dm.MID_DHT_PROVIDERS:
+		task, evId = sch.DhtpMgrName, sch.EvDhtPrdProvidersRsp
+		body = &sch.MsgDhtPrdProvidersRsp{From: ind.From, Providers: msg.Providers}
+
+	case dm.MID_DHT_ADDPROVIDER:
+		task, evId = sch.DhtpMgrName, sch.EvDhtPrdAddProviderReq
+		body = &sch.MsgDhtPrdAddProviderReq{From: ind.From, AddProvider: msg.AddProvider}
+
+	default:
+		yclog.LogCallerFileLine("dhtMgrPkgInd: invalid mid: %d", msg.Mid)
+		return DhtMgrEnoMessage
+	}
+
+	ptn := dhtMgr.dhtMgrTaskNode(task)
+	if ptn == nil {
+
+		yclog.LogCallerFileLine("dhtMgrPkgInd: " +
+			"task not found, discarded, mid: %d, task: %s",
+			msg.Mid, task)
+
+		return DhtMgrEnoScheduler
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno := sch.SchinfMakeMessage(&schMsg, dhtMgr.ptnMe, ptn, evId, body);
+	eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("dhtMgrPkgInd: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return DhtMgrEnoScheduler
+	}
+
+	if eno := sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("dhtMgrPkgInd: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, task)
+
+		return DhtMgrEnoScheduler
+	}
+
+	return DhtMgrEnoNone
+}
+
+//
+// Peer activated or closed indication handler: dispatch it to those tasks
+// interested in.
+//
+func (dhtMgr *dhtManager) dhtMgrPeerInd(ind *sch.MsgDhtPeerInd) DhtMgrErrno {
+
+	for _, task := range []string{sch.DhtrMgrName, sch.DhtsyMgrName} {
+
+		ptn := dhtMgr.dhtMgrTaskNode(task)
+		if ptn == nil {
+			yclog.LogCallerFileLine("dhtMgrPeerInd: task not found: %s", task)
+			continue
+		}
+
+		var schMsg = sch.SchMessage{}
+
+		if eno := sch.SchinfMakeMessage(&schMsg, dhtMgr.ptnMe, ptn, sch.EvDhtMgrPeerInd, ind);
+		eno != sch.SchEnoNone {
+
+			yclog.LogCallerFileLine("dhtMgrPeerInd: " +
+				"SchinfMakeMessage failed, eno: %d",
+				eno)
+
+			return DhtMgrEnoScheduler
+		}
+
+		if eno := sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+			yclog.LogCallerFileLine("dhtMgrPeerInd: " +
+				"SchinfSendMessage failed, eno: %d, target: %s",
+				eno, task)
+
+			return DhtMgrEnoScheduler
+		}
+	}
+
+	return DhtMgrEnoNone
+}
+
+//
+// Get task node of a dht task by name, those found are cached
+//
+func (dhtMgr *dhtManager) dhtMgrTaskNode(name string) interface{} {
+
+	if ptn, ok := dhtMgr.ptnTasks[name]; ok {
+		return ptn
+	}
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(dhtMgr.sdl, name)
+	if eno != sch.SchEnoNone || ptn == nil {
+		return nil
+	}
+
+	dhtMgr.ptnTasks[name] = ptn
+
+	return ptn
+}
+
+//
+// Send dht message to peer
+//
+func DhtSendMessage(sdl *sch.Scheduler, to ycfg.NodeID, msg *dm.DhtMessage) DhtMgrErrno {
+
+	payload, eno := msg.Encode()
+	if eno != dm.DhtMsgEnoNone {
+
+		yclog.LogCallerFileLine("DhtSendMessage: " +
+			"Encode failed, eno: %d",
+			eno)
+
+		return DhtMgrEnoMessage
+	}
+
+	var pkg = peer.P2pPackage2Peer {
+		IdList:			[]peer.PeerId{peer.PeerId(to)},
+		ProtoId:		int(peer.PID_DHT),
+		PayloadLength:	len(payload),
+		Payload:		payload,
+	}
+
+	if pe, _ := peer.SendPackage(sdl, &pkg); pe != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("DhtSendMessage: " +
+			"SendPackage failed, eno: %d, to: %s",
+			pe, fmt.Sprintf("%X", to))
+
+		return DhtMgrEnoPeer
+	}
+
+	return DhtMgrEnoNone
+}
+
+//
+// Confirm callback: dht tasks hand over their confirms to the user of dht
+// by this callback, the confirm is one of those sch.MsgDhtXxxCfm.
+//
+type DhtConfirmCallback func(msg interface{})
+
+//
+// Set confirm callback
+//
+func SetConfirmCallback(sdl *sch.Scheduler, cb DhtConfirmCallback) DhtMgrErrno {
+
+	dhtMgr := dhtGetManager(sdl)
+	if dhtMgr == nil {
+		yclog.LogCallerFileLine("SetConfirmCallback: dht manager not found")
+		return DhtMgrEnoScheduler
+	}
+
+	dhtMgr.cfmLock.Lock()
+	defer dhtMgr.cfmLock.Unlock()
+
+	if dhtMgr.cfmCb != nil {
+		yclog.LogCallerFileLine("SetConfirmCallback: old one will be overlapped")
+	}
+
+	dhtMgr.cfmCb = cb
+
+	return DhtMgrEnoNone
+}
+
+//
+// Confirm to the user of dht
+//
+func DhtConfirm(sdl *sch.Scheduler, msg interface{}) {
+
+	dhtMgr := dhtGetManager(sdl)
+	if dhtMgr == nil {
+		yclog.LogCallerFileLine("DhtConfirm: dht manager not found")
+		return
+	}
+
+	//
+	// the callback is called without the lock held, so it might call into
+	// dht again, say, set another callback, without deadlock, and a slow one
+	// would not block other tasks confirming
+	//
+
+	dhtMgr.cfmLock.Lock()
+	cb := dhtMgr.cfmCb
+	dhtMgr.cfmLock.Unlock()
+
+	if cb == nil {
+		yclog.LogCallerFileLine("DhtConfirm: confirm callback not installed yet")
+		return
+	}
+
+	cb(msg)
+}
+
+//
+// Confirm to the requester: the user of dht if ptnTo is nil, else the task
+// ptnTo, by event evId.
+//
+func DhtConfirmTo(ptnMe interface{}, ptnTo interface{}, evId int, msg interface{}) DhtMgrErrno {
+
+	if ptnTo == nil {
+		DhtConfirm(sch.SchinfGetScheduler(ptnMe), msg)
+		return DhtMgrEnoNone
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno := sch.SchinfMakeMessage(&schMsg, ptnMe, ptnTo, evId, msg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("DhtConfirmTo: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return DhtMgrEnoScheduler
+	}
+
+	if eno := sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("DhtConfirmTo: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, sch.SchinfGetTaskName(ptnTo))
+
+		return DhtMgrEnoScheduler
+	}
+
+	return DhtMgrEnoNone
+}
@@ -0,0 +1,455 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dhtmsg
+
+import (
+	"net"
+	ycfg	"github.com/yeeco/p2p/config"
+	pb		"github.com/yeeco/p2p/peer/pb"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// DHT messages carried by packages with protocol identity PID_DHT over the
+// peer connections, see tcpmsg.proto for the protobuf specification.
+//
+const (
+	MID_DHT_FINDVALUE		= pb.MessageId_MID_DHT_FINDVALUE
+	MID_DHT_VALUE			= pb.MessageId_MID_DHT_VALUE
+	MID_DHT_FINDNODE		= pb.MessageId_MID_DHT_FINDNODE
+	MID_DHT_NEIGHBORS		= pb.MessageId_MID_DHT_NEIGHBORS
+	MID_DHT_STORE			= pb.MessageId_MID_DHT_STORE
+	MID_DHT_STORERSP		= pb.MessageId_MID_DHT_STORERSP
+	MID_DHT_GETPROVIDERS	= pb.MessageId_MID_DHT_GETPROVIDERS
+	MID_DHT_PROVIDERS		= pb.MessageId_MID_DHT_PROVIDERS
+	MID_DHT_ADDPROVIDER		= pb.MessageId_MID_DHT_ADDPROVIDER
+)
+
+//
+// errno
+//
+const (
+	DhtMsgEnoNone	= iota
+	DhtMsgEnoParameter
+	DhtMsgEnoEncode
+	DhtMsgEnoDecode
+	DhtMsgEnoMessage
+)
+
+type DhtMsgErrno int
+
+type (
+
+	// FindValue: query a value by key
+	FindValue struct {
+		Id			uint64			// identity of query
+		Key			[]byte			// key wanted
+	}
+
+	// Value: response to FindValue
+	Value struct {
+		Id			uint64			// identity of query
+		Key			[]byte			// key wanted
+		Value		[]byte			// value, nil if not found
+		Closer		[]*ycfg.Node	// closer nodes if value not found
+	}
+
+	// FindNode: query nodes closest to target
+	FindNode struct {
+		Id			uint64			// identity of query
+		Target		ycfg.NodeID		// target node identity
+	}
+
+	// Neighbors: response to FindNode
+	Neighbors struct {
+		Id			uint64			// identity of query
+		Target		ycfg.NodeID		// target node identity
+		Nodes		[]*ycfg.Node	// nodes closest to target
+	}
+
+	// Store: request to store a value
+	Store struct {
+		Id			uint64			// identity of request
+		Key			[]byte			// key of value
+		Value		[]byte			// value to be stored
+	}
+
+	// StoreRsp: response to Store
+	StoreRsp struct {
+		Id			uint64			// identity of request
+		Key			[]byte			// key of value
+		Eno			int				// result, 0: ok, others: errno
+	}
+
+	// GetProviders: query providers of key
+	GetProviders struct {
+		Id			uint64			// identity of query
+		Key			[]byte			// key wanted
+	}
+
+	// Providers: response to GetProviders
+	Providers struct {
+		Id			uint64			// identity of query
+		Key			[]byte			// key wanted
+		Providers	[]*ycfg.Node	// providers of key
+		Closer		[]*ycfg.Node	// closer nodes to key
+	}
+
+	// AddProvider: announce a provider of key
+	AddProvider struct {
+		Id			uint64			// identity of request
+		Key			[]byte			// key provided
+		Provider	*ycfg.Node		// the provider
+		Ttl			uint32			// time to live in seconds, 0 for default
+	}
+
+	// DhtMessage: one of those above
+	DhtMessage struct {
+		Mid				pb.MessageId	// message identity
+		FindValue		*FindValue		// find value message
+		Value			*Value			// value message
+		FindNode		*FindNode		// find node message
+		Neighbors		*Neighbors		// neighbors message
+		Store			*Store			// store message
+		StoreRsp		*StoreRsp		// store response message
+		GetProviders	*GetProviders	// get providers message
+		Providers		*Providers		// providers message
+		AddProvider		*AddProvider	// add provider message
+	}
+)
+
+//
+// Encode dht message to payload of package
+//
+func (dm *DhtMessage) Encode() ([]byte, DhtMsgErrno) {
+
+	pbMsg := new(pb.DhtMessage)
+	pbMsg.Mid = new(pb.MessageId)
+	*pbMsg.Mid = dm.Mid
+
+	var absent = false
+
+	switch dm.Mid {
+
+	case MID_DHT_FINDVALUE:
+
+		if absent = dm.FindValue == nil; !absent {
+			pbMsg.FindValue = &pb.DhtMessage_FindValue {
+				Id:		pbUint64(dm.FindValue.Id),
+				Key:	append([]byte{}, dm.FindValue.Key...),
+			}
+		}
+
+	case MID_DHT_VALUE:
+
+		if absent = dm.Value == nil; !absent {
+			pbMsg.Value = &pb.DhtMessage_Value {
+				Id:		pbUint64(dm.Value.Id),
+				Key:	append([]byte{}, dm.Value.Key...),
+				Value:	append([]byte{}, dm.Value.Value...),
+				Closer:	encodeNodes(dm.Value.Closer),
+			}
+		}
+
+	case MID_DHT_FINDNODE:
+
+		if absent = dm.FindNode == nil; !absent {
+			pbMsg.FindNode = &pb.DhtMessage_FindNode {
+				Id:		pbUint64(dm.FindNode.Id),
+				Target:	append([]byte{}, dm.FindNode.Target[:]...),
+			}
+		}
+
+	case MID_DHT_NEIGHBORS:
+
+		if absent = dm.Neighbors == nil; !absent {
+			pbMsg.Neighbors = &pb.DhtMessage_Neighbors {
+				Id:		pbUint64(dm.Neighbors.Id),
+				Target:	append([]byte{}, dm.Neighbors.Target[:]...),
+				Nodes:	encodeNodes(dm.Neighbors.Nodes),
+			}
+		}
+
+	case MID_DHT_STORE:
+
+		if absent = dm.Store == nil; !absent {
+			pbMsg.Store = &pb.DhtMessage_Store {
+				Id:		pbUint64(dm.Store.Id),
+				Key:	append([]byte{}, dm.Store.Key...),
+				Value:	append([]byte{}, dm.Store.Value...),
+			}
+		}
+
+	case MID_DHT_STORERSP:
+
+		if absent = dm.StoreRsp == nil; !absent {
+			pbMsg.StoreRsp = &pb.DhtMessage_StoreRsp {
+				Id:		pbUint64(dm.StoreRsp.Id),
+				Key:	append([]byte{}, dm.StoreRsp.Key...),
+				Eno:	pbUint32(uint32(dm.StoreRsp.Eno)),
+			}
+		}
+
+	case MID_DHT_GETPROVIDERS:
+
+		if absent = dm.GetProviders == nil; !absent {
+			pbMsg.GetProviders = &pb.DhtMessage_GetProviders {
+				Id:		pbUint64(dm.GetProviders.Id),
+				Key:	append([]byte{}, dm.GetProviders.Key...),
+			}
+		}
+
+	case MID_DHT_PROVIDERS:
+
+		if absent = dm.Providers == nil; !absent {
+			pbMsg.ProvidersRsp = &pb.DhtMessage_Providers {
+				Id:			pbUint64(dm.Providers.Id),
+				Key:		append([]byte{}, dm.Providers.Key...),
+				Providers:	encodeNodes(dm.Providers.Providers),
+				Closer:		encodeNodes(dm.Providers.Closer),
+			}
+		}
+
+	case MID_DHT_ADDPROVIDER:
+
+		if absent = dm.AddProvider == nil || dm.AddProvider.Provider == nil; !absent {
+			pbMsg.AddProvider = &pb.DhtMessage_AddProvider {
+				Id:			pbUint64(dm.AddProvider.Id),
+				Key:		append([]byte{}, dm.AddProvider.Key...),
+				Provider:	encodeNodes([]*ycfg.Node{dm.AddProvider.Provider})[0],
+				Ttl:		pbUint32(dm.AddProvider.Ttl),
+			}
+		}
+
+	default:
+		yclog.LogCallerFileLine("Encode: invalid mid: %d", dm.Mid)
+		return nil, DhtMsgEnoParameter
+	}
+
+	if absent {
+		yclog.LogCallerFileLine("Encode: message absent, mid: %d", dm.Mid)
+		return nil, DhtMsgEnoParameter
+	}
+
+	buf, err := pbMsg.Marshal()
+	if err != nil {
+
+		yclog.LogCallerFileLine("Encode: " +
+			"Marshal failed, err: %s",
+			err.Error())
+
+		return nil, DhtMsgEnoEncode
+	}
+
+	return buf, DhtMsgEnoNone
+}
+
+//
+// Decode dht message from payload of package
+//
+func Decode(payload []byte) (*DhtMessage, DhtMsgErrno) {
+
+	pbMsg := new(pb.DhtMessage)
+
+	if err := pbMsg.Unmarshal(payload); err != nil {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"Unmarshal failed, err: %s",
+			err.Error())
+
+		return nil, DhtMsgEnoDecode
+	}
+
+	dm := &DhtMessage{Mid: pbMsg.GetMid()}
+	var absent = false
+
+	switch dm.Mid {
+
+	case MID_DHT_FINDVALUE:
+
+		if pbFv := pbMsg.GetFindValue(); pbFv != nil {
+			dm.FindValue = &FindValue {
+				Id:		pbFv.GetId(),
+				Key:	append([]byte{}, pbFv.Key...),
+			}
+		}
+		absent = dm.FindValue == nil
+
+	case MID_DHT_VALUE:
+
+		if pbVal := pbMsg.GetValue(); pbVal != nil {
+			dm.Value = &Value {
+				Id:		pbVal.GetId(),
+				Key:	append([]byte{}, pbVal.Key...),
+				Value:	nil,
+				Closer:	decodeNodes(pbVal.Closer),
+			}
+			if len(pbVal.Value) > 0 {
+				dm.Value.Value = append(dm.Value.Value, pbVal.Value...)
+			}
+		}
+		absent = dm.Value == nil
+
+	case MID_DHT_FINDNODE:
+
+		if pbFn := pbMsg.GetFindNode(); pbFn != nil && len(pbFn.Target) == ycfg.NodeIDBytes {
+			dm.FindNode = &FindNode{Id: pbFn.GetId()}
+			copy(dm.FindNode.Target[:], pbFn.Target)
+		}
+		absent = dm.FindNode == nil
+
+	case MID_DHT_NEIGHBORS:
+
+		if pbNb := pbMsg.GetNeighbors(); pbNb != nil && len(pbNb.Target) == ycfg.NodeIDBytes {
+			dm.Neighbors = &Neighbors {
+				Id:		pbNb.GetId(),
+				Nodes:	decodeNodes(pbNb.Nodes),
+			}
+			copy(dm.Neighbors.Target[:], pbNb.Target)
+		}
+		absent = dm.Neighbors == nil
+
+	case MID_DHT_STORE:
+
+		if pbSt := pbMsg.GetStore(); pbSt != nil {
+			dm.Store = &Store {
+				Id:		pbSt.GetId(),
+				Key:	append([]byte{}, pbSt.Key...),
+				Value:	append([]byte{}, pbSt.Value...),
+			}
+		}
+		absent = dm.Store == nil
+
+	case MID_DHT_STORERSP:
+
+		if pbSr := pbMsg.GetStoreRsp(); pbSr != nil {
+			dm.StoreRsp = &StoreRsp {
+				Id:		pbSr.GetId(),
+				Key:	append([]byte{}, pbSr.Key...),
+				Eno:	int(pbSr.GetEno()),
+			}
+		}
+		absent = dm.StoreRsp == nil
+
+	case MID_DHT_GETPROVIDERS:
+
+		if pbGp := pbMsg.GetGetProviders(); pbGp != nil {
+			dm.GetProviders = &GetProviders {
+				Id:		pbGp.GetId(),
+				Key:	append([]byte{}, pbGp.Key...),
+			}
+		}
+		absent = dm.GetProviders == nil
+
+	case MID_DHT_PROVIDERS:
+
+		if pbPr := pbMsg.GetProvidersRsp(); pbPr != nil {
+			dm.Providers = &Providers {
+				Id:			pbPr.GetId(),
+				Key:		append([]byte{}, pbPr.Key...),
+				Providers:	decodeNodes(pbPr.Providers),
+				Closer:		decodeNodes(pbPr.Closer),
+			}
+		}
+		absent = dm.Providers == nil
+
+	case MID_DHT_ADDPROVIDER:
+
+		if pbAp := pbMsg.GetAddProvider(); pbAp != nil {
+			if nodes := decodeNodes([]*pb.DhtMessage_Node{pbAp.Provider}); len(nodes) == 1 {
+				dm.AddProvider = &AddProvider {
+					Id:			pbAp.GetId(),
+					Key:		append([]byte{}, pbAp.Key...),
+					Provider:	nodes[0],
+					Ttl:		pbAp.GetTtl(),
+				}
+			}
+		}
+		absent = dm.AddProvider == nil
+
+	default:
+		yclog.LogCallerFileLine("Decode: invalid mid: %d", dm.Mid)
+		return nil, DhtMsgEnoMessage
+	}
+
+	if absent {
+		yclog.LogCallerFileLine("Decode: message absent or invalid, mid: %d", dm.Mid)
+		return nil, DhtMsgEnoMessage
+	}
+
+	return dm, DhtMsgEnoNone
+}
+
+//
+// Encode nodes
+//
+func encodeNodes(nodes []*ycfg.Node) []*pb.DhtMessage_Node {
+
+	pbNodes := make([]*pb.DhtMessage_Node, 0, len(nodes))
+
+	for _, n := range nodes {
+
+		pn := &pb.DhtMessage_Node {
+			NodeId:	append([]byte{}, n.ID[:]...),
+			IP:		append([]byte{}, n.IP...),
+			UDP:	pbUint32(uint32(n.UDP)),
+			TCP:	pbUint32(uint32(n.TCP)),
+		}
+
+		pbNodes = append(pbNodes, pn)
+	}
+
+	return pbNodes
+}
+
+//
+// Decode nodes, those with invalid identity are discarded
+//
+func decodeNodes(pbNodes []*pb.DhtMessage_Node) []*ycfg.Node {
+
+	nodes := make([]*ycfg.Node, 0, len(pbNodes))
+
+	for _, pn := range pbNodes {
+
+		if pn == nil || len(pn.NodeId) != ycfg.NodeIDBytes {
+			yclog.LogCallerFileLine("decodeNodes: invalid node identity")
+			continue
+		}
+
+		n := new(ycfg.Node)
+		copy(n.ID[:], pn.NodeId)
+		n.IP = append(net.IP{}, pn.IP...)
+		n.UDP = uint16(pn.GetUDP())
+		n.TCP = uint16(pn.GetTCP())
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
+
+func pbUint64(v uint64) *uint64 {
+	return &v
+}
+
+func pbUint32(v uint32) *uint32 {
+	return &v
+}
@@ -22,30 +22,461 @@
 package dispatcher
 
 import (
+	"time"
 	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/dht"
+	dhtre	"github.com/yeeco/p2p/dht/retriver"
 )
 
+//
+// errno
+//
+const (
+	DhtdiMgrEnoNone	= iota
+	DhtdiMgrEnoParameter
+	DhtdiMgrEnoScheduler
+	DhtdiMgrEnoTimeout
+	DhtdiMgrEnoUnknown
+)
+
+type DhtdiMgrErrno int
+
+//
+// The dispatcher tracks the requests from the user of dht. Each request is
+// given a dispatcher identity, with which it's handed over to the dht task
+// serving it, and the confirm from that task is correlated back by this
+// identity. Each request has a deadline, checked by a periodic timer, and
+// it's confirmed to the user exactly once: by the confirm from the task, or
+// by a timeout error when the deadline expired, whichever comes first; the
+// other one is discarded.
+//
+// A retrive chunk request not found is retried against alternate peers: the
+// peers queried are excluded, and the request is handed over again, until
+// the chunk found, no more peers to query, or attempts exhausted.
+//
+const (
+	diChunkTimeout		= time.Second * 30		// default deadline for chunk requests
+	diObjectTimeout		= time.Minute * 5		// default deadline for object requests
+	diProviderTimeout	= time.Second * 30		// default deadline for provider requests
+	diMaxAttempts		= 3						// max attempts for a retrive chunk request
+	diDeadlineTickCycle	= time.Second			// cycle for deadline checking
+)
+
+//
+// Request tracked
+//
+type diRequest struct {
+	seq			uint64					// dispatcher identity
+	id			uint64					// identity of the user request
+	req			interface{}				// the user request
+	deadline	time.Time				// time to wait confirm till
+	attempts	int						// attempts handed over
+	exclude		[]ycfg.NodeID			// peers queried by former attempts
+}
+
 //
 // dispatch manager
 //
-const DhtdiMgrName = "DhtdiMgr"
+const DhtdiMgrName = sch.DhtdiMgrName
 
 type dhtDispatcherManager struct {
-	name	string				// name
-	tep		sch.SchUserTaskEp	// entry
+	name		string					// name
+	tep			sch.SchUserTaskEp		// entry
+	sdl			*sch.Scheduler			// pointer to scheduler
+	ptnMe		interface{}				// pointer to myself task node
+	tidTick		int						// deadline timer identity
+	seq			uint64					// sequence for dispatcher identities
+	requests	map[uint64]*diRequest	// requests by dispatcher identity
 }
 
-var dhtdiMgr = dhtDispatcherManager{
-	name:	DhtdiMgrName,
-	tep:	DhtdiMgrProc,
+//
+// Create dispatcher manager, one for each p2p instance
+//
+func NewDhtdiMgr() interface{} {
+	return &dhtDispatcherManager{
+		name:		DhtdiMgrName,
+		tep:		DhtdiMgrProc,
+		ptnMe:		nil,
+		tidTick:	sch.SchInvalidTid,
+		seq:		0,
+		requests:	map[uint64]*diRequest{},
+	}
 }
 
 //
 // dispatch manager entry
 //
 func DhtdiMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtdiMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtdiMgr := sch.SchinfGetUserDataArea(ptn).(*dhtDispatcherManager)
+
+	var eno DhtdiMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = dhtdiMgr.dhtdiMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = dhtdiMgr.dhtdiMgrPoweroff(ptn)
+
+	case sch.EvDhtdiReq:
+		eno = dhtdiMgr.dhtdiMgrReq(msg.Body.(*sch.MsgDhtdiReq))
+
+	case sch.EvDhtdiDeadlineTimer:
+		eno = dhtdiMgr.dhtdiMgrDeadlineTimerHandler()
+
+	case sch.EvDhtStoreCfm:
+		eno = dhtdiMgr.dhtdiMgrCfm(msg.Body.(*sch.MsgDhtStoreCfm).Id, msg.Body)
+
+	case sch.EvDhtRetriveCfm:
+		eno = dhtdiMgr.dhtdiMgrRetriveCfm(msg.Body.(*sch.MsgDhtRetriveCfm))
+
+	case sch.EvDhtObjStoreCfm:
+		eno = dhtdiMgr.dhtdiMgrCfm(msg.Body.(*sch.MsgDhtObjStoreCfm).Id, msg.Body)
+
+	case sch.EvDhtObjRetriveCfm:
+		eno = dhtdiMgr.dhtdiMgrCfm(msg.Body.(*sch.MsgDhtObjRetriveCfm).Id, msg.Body)
+
+	case sch.EvDhtPrdProvideCfm:
+		eno = dhtdiMgr.dhtdiMgrCfm(msg.Body.(*sch.MsgDhtPrdProvideCfm).Id, msg.Body)
+
+	case sch.EvDhtPrdFindCfm:
+		eno = dhtdiMgr.dhtdiMgrCfm(msg.Body.(*sch.MsgDhtPrdFindCfm).Id, msg.Body)
+
+	default:
+		yclog.LogCallerFileLine("DhtdiMgrProc: invalid message: %d", msg.Id)
+		eno = DhtdiMgrEnoParameter
+	}
+
+	if eno != DhtdiMgrEnoNone {
+		yclog.LogCallerFileLine("DhtdiMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweron handler
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiMgrPoweron(ptn interface{}) DhtdiMgrErrno {
+
+	dhtdiMgr.sdl = sch.SchinfGetScheduler(ptn)
+	dhtdiMgr.ptnMe = ptn
+
+	var td = sch.TimerDescription {
+		Name:	DhtdiMgrName + "_deadline",
+		Utid:	sch.DhtdiDeadlineTimerId,
+		Tmt:	sch.SchTmTypePeriod,
+		Dur:	diDeadlineTickCycle,
+		Extra:	nil,
+	}
+
+	eno, tid := sch.SchInfSetTimer(ptn, &td)
+	if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+		yclog.LogCallerFileLine("dhtdiMgrPoweron: " +
+			"SchInfSetTimer failed, eno: %d, timer: %s",
+			eno, td.Name)
+
+		return DhtdiMgrEnoScheduler
+	}
+
+	dhtdiMgr.tidTick = tid
+
+	return DhtdiMgrEnoNone
+}
+
+//
+// Poweroff handler: those requests pending are dropped
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiMgrPoweroff(ptn interface{}) DhtdiMgrErrno {
+
+	if dhtdiMgr.tidTick != sch.SchInvalidTid {
+		sch.SchinfKillTimer(ptn, dhtdiMgr.tidTick)
+		dhtdiMgr.tidTick = sch.SchInvalidTid
+	}
+
+	dhtdiMgr.requests = map[uint64]*diRequest{}
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtdiMgrEnoUnknown
+	}
+
+	return DhtdiMgrEnoNone
+}
+
+//
+// Request from the user of dht handler
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiMgrReq(msg *sch.MsgDhtdiReq) DhtdiMgrErrno {
+
+	id, dft, ok := dhtdiReqInfo(msg.Req)
+	if !ok {
+		yclog.LogCallerFileLine("dhtdiMgrReq: invalid request: %T", msg.Req)
+		return DhtdiMgrEnoParameter
+	}
+
+	timeout := msg.Timeout
+	if timeout <= 0 {
+		timeout = dft
+	}
+
+	dhtdiMgr.seq++
+
+	r := &diRequest {
+		seq:		dhtdiMgr.seq,
+		id:			id,
+		req:		msg.Req,
+		deadline:	time.Now().Add(timeout),
+		attempts:	0,
+		exclude:	nil,
+	}
+
+	dhtdiMgr.requests[r.seq] = r
+
+	if eno := dhtdiMgr.dhtdiDispatch(r); eno != DhtdiMgrEnoNone {
+		dhtdiMgr.dhtdiDone(r, nil, eno)
+		return eno
+	}
+
+	return DhtdiMgrEnoNone
+}
+
+//
+// Deadline timer handler: check those requests timeout
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiMgrDeadlineTimerHandler() DhtdiMgrErrno {
+
+	now := time.Now()
+
+	for seq, r := range dhtdiMgr.requests {
+
+		if now.Before(r.deadline) {
+			continue
+		}
+
+		yclog.LogCallerFileLine("dhtdiMgrDeadlineTimerHandler: " +
+			"request timeout, seq: %d, id: %d, attempts: %d",
+			seq, r.id, r.attempts)
+
+		dhtdiMgr.dhtdiDone(r, nil, DhtdiMgrEnoTimeout)
+	}
+
+	return DhtdiMgrEnoNone
+}
+
+//
+// Confirm from dht task handler
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiMgrCfm(seq uint64, cfm interface{}) DhtdiMgrErrno {
+
+	r, ok := dhtdiMgr.requests[seq]
+	if !ok {
+
+		yclog.LogCallerFileLine("dhtdiMgrCfm: " +
+			"request not found, might be timeout, seq: %d",
+			seq)
+
+		return DhtdiMgrEnoNone
+	}
+
+	dhtdiMgr.dhtdiDone(r, cfm, DhtdiMgrEnoNone)
+
+	return DhtdiMgrEnoNone
+}
+
+//
+// Confirm from retriver handler: retry against alternate peers if the chunk
+// not found.
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiMgrRetriveCfm(cfm *sch.MsgDhtRetriveCfm) DhtdiMgrErrno {
+
+	r, ok := dhtdiMgr.requests[cfm.Id]
+	if !ok {
+
+		yclog.LogCallerFileLine("dhtdiMgrRetriveCfm: " +
+			"request not found, might be timeout, seq: %d",
+			cfm.Id)
+
+		return DhtdiMgrEnoNone
+	}
+
+	//
+	// no peers left for a retry means not found
+	//
+
+	if cfm.Eno == dhtre.DhtreMgrEnoNoPeer && r.attempts > 1 {
+		cfm.Eno = dhtre.DhtreMgrEnoNotFound
+	}
+
+	if cfm.Eno != dhtre.DhtreMgrEnoNotFound ||
+		len(cfm.Queried) == 0 ||
+		r.attempts >= diMaxAttempts {
+		dhtdiMgr.dhtdiDone(r, cfm, DhtdiMgrEnoNone)
+		return DhtdiMgrEnoNone
+	}
+
+	yclog.LogCallerFileLine("dhtdiMgrRetriveCfm: " +
+		"not found, retry, seq: %d, id: %d, attempts: %d, queried: %d",
+		r.seq, r.id, r.attempts, len(cfm.Queried))
+
+	r.exclude = append(r.exclude, cfm.Queried...)
+
+	if eno := dhtdiMgr.dhtdiDispatch(r); eno != DhtdiMgrEnoNone {
+		dhtdiMgr.dhtdiDone(r, cfm, DhtdiMgrEnoNone)
+	}
+
+	return DhtdiMgrEnoNone
+}
+
+//
+// Get identity and default deadline of a request
+//
+func dhtdiReqInfo(req interface{}) (uint64, time.Duration, bool) {
+
+	switch m := req.(type) {
+	case *sch.MsgDhtStoreReq:
+		return m.Id, diChunkTimeout, true
+	case *sch.MsgDhtRetriveReq:
+		return m.Id, diChunkTimeout, true
+	case *sch.MsgDhtObjStoreReq:
+		return m.Id, diObjectTimeout, true
+	case *sch.MsgDhtObjRetriveReq:
+		return m.Id, diObjectTimeout, true
+	case *sch.MsgDhtPrdProvideReq:
+		return m.Id, diProviderTimeout, true
+	case *sch.MsgDhtPrdFindReq:
+		return m.Id, diProviderTimeout, true
+	}
+
+	return 0, 0, false
+}
+
+//
+// Hand a request over to the dht task serving it. A copy of the request is
+// sent, with the identity replaced by the dispatcher one and the dispatcher
+// as the task to confirm to.
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiDispatch(r *diRequest) DhtdiMgrErrno {
+
+	var task string
+	var evId int
+	var body interface{}
+
+	switch m := r.req.(type) {
+
+	case *sch.MsgDhtStoreReq:
+		req := *m
+		req.Id, req.Ptn = r.seq, dhtdiMgr.ptnMe
+		task, evId, body = sch.DhtstMgrName, sch.EvDhtStoreReq, &req
+
+	case *sch.MsgDhtRetriveReq:
+		req := *m
+		req.Id, req.Ptn = r.seq, dhtdiMgr.ptnMe
+		req.Exclude = append(append([]ycfg.NodeID{}, m.Exclude...), r.exclude...)
+		task, evId, body = sch.DhtreMgrName, sch.EvDhtRetriveReq, &req
+
+	case *sch.MsgDhtObjStoreReq:
+		req := *m
+		req.Id, req.Ptn = r.seq, dhtdiMgr.ptnMe
+		task, evId, body = sch.DhtchMgrName, sch.EvDhtObjStoreReq, &req
+
+	case *sch.MsgDhtObjRetriveReq:
+		req := *m
+		req.Id, req.Ptn = r.seq, dhtdiMgr.ptnMe
+		task, evId, body = sch.DhtchMgrName, sch.EvDhtObjRetriveReq, &req
+
+	case *sch.MsgDhtPrdProvideReq:
+		req := *m
+		req.Id, req.Ptn = r.seq, dhtdiMgr.ptnMe
+		task, evId, body = sch.DhtpMgrName, sch.EvDhtPrdProvideReq, &req
+
+	case *sch.MsgDhtPrdFindReq:
+		req := *m
+		req.Id, req.Ptn = r.seq, dhtdiMgr.ptnMe
+		task, evId, body = sch.DhtpMgrName, sch.EvDhtPrdFindReq, &req
+
+	default:
+		yclog.LogCallerFileLine("dhtdiDispatch: invalid request: %T", r.req)
+		return DhtdiMgrEnoParameter
+	}
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(dhtdiMgr.sdl, task)
+	if eno != sch.SchEnoNone || ptn == nil {
+
+		yclog.LogCallerFileLine("dhtdiDispatch: " +
+			"SchinfGetTaskNodeByName failed, eno: %d, name: %s",
+			eno, task)
+
+		return DhtdiMgrEnoScheduler
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno = sch.SchinfMakeMessage(&schMsg, dhtdiMgr.ptnMe, ptn, evId, body); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("dhtdiDispatch: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return DhtdiMgrEnoScheduler
+	}
+
+	if eno = sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("dhtdiDispatch: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, task)
+
+		return DhtdiMgrEnoScheduler
+	}
+
+	r.attempts++
+
+	return DhtdiMgrEnoNone
+}
+
+//
+// End up a request: remove it and confirm
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiDone(r *diRequest, cfm interface{}, eno DhtdiMgrErrno) {
+	delete(dhtdiMgr.requests, r.seq)
+	dhtdiMgr.dhtdiConfirm(r, cfm, eno)
+}
+
+//
+// Confirm to the user of dht, the identity of the confirm from the task is
+// restored to that of the user request.
+//
+func (dhtdiMgr *dhtDispatcherManager) dhtdiConfirm(r *diRequest, cfm interface{}, eno DhtdiMgrErrno) {
+
+	switch m := cfm.(type) {
+	case *sch.MsgDhtStoreCfm:
+		m.Id = r.id
+	case *sch.MsgDhtRetriveCfm:
+		m.Id = r.id
+	case *sch.MsgDhtObjStoreCfm:
+		m.Id = r.id
+	case *sch.MsgDhtObjRetriveCfm:
+		m.Id = r.id
+	case *sch.MsgDhtPrdProvideCfm:
+		m.Id = r.id
+	case *sch.MsgDhtPrdFindCfm:
+		m.Id = r.id
+	}
+
+	var dc = sch.MsgDhtdiCfm {
+		Eno:	int(eno),
+		Id:		r.id,
+		Req:	r.req,
+		Cfm:	cfm,
+	}
+
+	dht.DhtConfirm(dhtdiMgr.sdl, &dc)
+}
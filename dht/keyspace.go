@@ -0,0 +1,75 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	ycfg	"github.com/yeeco/p2p/config"
+)
+
+//
+// Key space. Nodes are placed in the space by sha256 of their identities, as
+// what the table does. To place a key in the same space, the key is mapped to
+// a node identity by sha512 first, so a key can be taken as a "target node"
+// while looking up in the route table.
+//
+const DhtHashLength = sha256.Size
+
+type DhtHash [DhtHashLength]byte
+
+//
+// Map key to node identity
+//
+func DhtKey2NodeId(key []byte) ycfg.NodeID {
+	return ycfg.NodeID(sha512.Sum512(key))
+}
+
+//
+// Hash of node identity, which is its' position in the key space
+//
+func DhtNodeId2Hash(id ycfg.NodeID) DhtHash {
+	return DhtHash(sha256.Sum256(id[:]))
+}
+
+//
+// Position of key in the key space
+//
+func DhtKey2Hash(key []byte) DhtHash {
+	return DhtNodeId2Hash(DhtKey2NodeId(key))
+}
+
+//
+// Compare the distances from a and b to target: -1 if a is closer, +1 if b
+// is closer, 0 if they are equal.
+//
+func DhtDistCmp(target, a, b DhtHash) int {
+
+	var da, db DhtHash
+
+	for i := 0; i < DhtHashLength; i++ {
+		da[i] = target[i] ^ a[i]
+		db[i] = target[i] ^ b[i]
+	}
+
+	return bytes.Compare(da[:], db[:])
+}
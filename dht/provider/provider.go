@@ -8,30 +8,614 @@
 package provider
 
 import (
+	"fmt"
+	"time"
 	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/dht"
+	dm		"github.com/yeeco/p2p/dht/dhtmsg"
+	dhtr	"github.com/yeeco/p2p/dht/route"
 )
 
+//
+// errno
+//
+const (
+	DhtpMgrEnoNone	= iota
+	DhtpMgrEnoParameter
+	DhtpMgrEnoScheduler
+	DhtpMgrEnoConfig
+	DhtpMgrEnoMessage
+	DhtpMgrEnoNoPeer
+	DhtpMgrEnoUnknown
+)
+
+type DhtpMgrErrno int
+
+//
+// A provider record tells that node X can serve key K. Records are published
+// to the prdK peers closest to the key, which keep them till they expired. The
+// local node republishes records of those keys it provides periodically, so
+// they would not expire in the network.
+//
+const (
+	prdK				= 16					// number of peers to publish to
+	prdRecordTtl		= time.Hour * 24		// default time to live of records
+	prdMaxTtl			= time.Hour * 48		// max time to live accepted
+	prdRepublishCycle	= time.Hour * 12		// cycle to republish local provides
+	prdCleanupCycle		= time.Minute			// cycle to remove expired records
+	prdQueryTimeout		= time.Second * 5		// timeout for a query
+	prdQueryTickCycle	= time.Second			// cycle for query timeout checking
+	prdMaxQueried		= prdK * 2				// max peers queried for a find request
+)
+
+//
+// Provider record
+//
+type prdRecord struct {
+	node		ycfg.Node		// the provider
+	expire		time.Time		// time the record expired at
+}
+
+//
+// Find providers request
+//
+type prdQuery struct {
+	key			[]byte						// key wanted
+	id			uint64						// identity of the user request
+	ptn			interface{}					// task to confirm to, nil for the user of dht
+	found		map[ycfg.NodeID]*ycfg.Node	// providers found
+	queried		map[ycfg.NodeID]bool		// peers queried
+	pending		int							// queries not responsed
+}
+
+//
+// Query to a peer
+//
+type prdPending struct {
+	query		*prdQuery		// the find request
+	peer		ycfg.NodeID		// peer queried
+	sent		time.Time		// time the query sent
+	deadline	time.Time		// time to wait response till
+}
+
 //
 // Provider manager
 //
-const DhtpMgrName = "DhtpMgr"
+const DhtpMgrName = sch.DhtpMgrName
 
 type dhtProviderManager struct {
-	name	string				// name
-	tep		sch.SchUserTaskEp	// entry
+	name		string										// name
+	tep			sch.SchUserTaskEp							// entry
+	sdl			*sch.Scheduler								// pointer to scheduler
+	ptnMe		interface{}									// pointer to myself task node
+	local		ycfg.Node									// local node
+	tidRepub	int											// republish timer identity
+	tidClean	int											// cleanup timer identity
+	tidQuery	int											// query timer identity
+	seq			uint64										// sequence for message identities
+	records		map[string]map[ycfg.NodeID]*prdRecord		// provider records by key
+	provides	map[string][]byte							// keys provided by local node
+	pendings	map[uint64]*prdPending						// queries to peers
 }
 
-var dhtpMgr = dhtProviderManager{
-	name:	DhtpMgrName,
-	tep:	DhtpMgrProc,
+//
+// Create provider manager, one for each p2p instance
+//
+func NewDhtpMgr() interface{} {
+	return &dhtProviderManager{
+		name:		DhtpMgrName,
+		tep:		DhtpMgrProc,
+		ptnMe:		nil,
+		tidRepub:	sch.SchInvalidTid,
+		tidClean:	sch.SchInvalidTid,
+		tidQuery:	sch.SchInvalidTid,
+		seq:		0,
+		records:	map[string]map[ycfg.NodeID]*prdRecord{},
+		provides:	map[string][]byte{},
+		pendings:	map[uint64]*prdPending{},
+	}
 }
 
 //
-// Table manager entry
+// Provider manager entry
 //
 func DhtpMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtpMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtpMgr := sch.SchinfGetUserDataArea(ptn).(*dhtProviderManager)
+
+	var eno DhtpMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = dhtpMgr.dhtpMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = dhtpMgr.dhtpMgrPoweroff(ptn)
+
+	case sch.EvDhtPrdRepublishTimer:
+		eno = dhtpMgr.dhtpMgrRepublishTimerHandler()
+
+	case sch.EvDhtPrdCleanupTimer:
+		eno = dhtpMgr.dhtpMgrCleanupTimerHandler()
+
+	case sch.EvDhtPrdQueryTimer:
+		eno = dhtpMgr.dhtpMgrQueryTimerHandler()
+
+	case sch.EvDhtPrdProvideReq:
+		eno = dhtpMgr.dhtpMgrProvideReq(msg.Body.(*sch.MsgDhtPrdProvideReq))
+
+	case sch.EvDhtPrdFindReq:
+		eno = dhtpMgr.dhtpMgrFindReq(msg.Body.(*sch.MsgDhtPrdFindReq))
+
+	case sch.EvDhtPrdAddProviderReq:
+		eno = dhtpMgr.dhtpMgrAddProviderReq(msg.Body.(*sch.MsgDhtPrdAddProviderReq))
+
+	case sch.EvDhtPrdGetProvidersReq:
+		eno = dhtpMgr.dhtpMgrGetProvidersReq(msg.Body.(*sch.MsgDhtPrdGetProvidersReq))
+
+	case sch.EvDhtPrdProvidersRsp:
+		eno = dhtpMgr.dhtpMgrProvidersRsp(msg.Body.(*sch.MsgDhtPrdProvidersRsp))
+
+	default:
+		yclog.LogCallerFileLine("DhtpMgrProc: invalid message: %d", msg.Id)
+		eno = DhtpMgrEnoParameter
+	}
+
+	if eno != DhtpMgrEnoNone {
+		yclog.LogCallerFileLine("DhtpMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweron handler
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrPoweron(ptn interface{}) DhtpMgrErrno {
+
+	dhtpMgr.sdl = sch.SchinfGetScheduler(ptn)
+	dhtpMgr.ptnMe = ptn
+
+	cfg := ycfg.P2pConfig4DhtProvider(sch.SchinfGetP2pConfig(dhtpMgr.sdl))
+	if cfg == nil {
+		yclog.LogCallerFileLine("dhtpMgrPoweron: P2pConfig4DhtProvider failed")
+		return DhtpMgrEnoConfig
+	}
+
+	dhtpMgr.local = cfg.Local
+
+	var tmd = []struct {
+		tid		*int
+		utid	int
+		name	string
+		dur		time.Duration
+	}{
+		{&dhtpMgr.tidRepub,	sch.DhtPrdRepublishTimerId,	"_republish",	prdRepublishCycle},
+		{&dhtpMgr.tidClean,	sch.DhtPrdCleanupTimerId,	"_cleanup",		prdCleanupCycle},
+		{&dhtpMgr.tidQuery,	sch.DhtPrdQueryTimerId,		"_query",		prdQueryTickCycle},
+	}
+
+	for _, t := range tmd {
+
+		var td = sch.TimerDescription {
+			Name:	DhtpMgrName + t.name,
+			Utid:	t.utid,
+			Tmt:	sch.SchTmTypePeriod,
+			Dur:	t.dur,
+			Extra:	nil,
+		}
+
+		eno, tid := sch.SchInfSetTimer(ptn, &td)
+		if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+			yclog.LogCallerFileLine("dhtpMgrPoweron: " +
+				"SchInfSetTimer failed, eno: %d, timer: %s",
+				eno, td.Name)
+
+			return DhtpMgrEnoScheduler
+		}
+
+		*t.tid = tid
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Poweroff handler
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrPoweroff(ptn interface{}) DhtpMgrErrno {
+
+	for _, tid := range []*int{&dhtpMgr.tidRepub, &dhtpMgr.tidClean, &dhtpMgr.tidQuery} {
+		if *tid != sch.SchInvalidTid {
+			sch.SchinfKillTimer(ptn, *tid)
+			*tid = sch.SchInvalidTid
+		}
+	}
+
+	dhtpMgr.records = map[string]map[ycfg.NodeID]*prdRecord{}
+	dhtpMgr.provides = map[string][]byte{}
+	dhtpMgr.pendings = map[uint64]*prdPending{}
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtpMgrEnoUnknown
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Provide request handler: announce local node as a provider of key
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrProvideReq(req *sch.MsgDhtPrdProvideReq) DhtpMgrErrno {
+
+	dhtpMgr.provides[string(req.Key)] = req.Key
+
+	var cfm = sch.MsgDhtPrdProvideCfm {
+		Eno:	DhtpMgrEnoNone,
+		Key:	req.Key,
+		Peers:	dhtpMgr.dhtpPublish(req.Key),
+		Id:		req.Id,
+	}
+
+	if cfm.Peers == 0 {
+		cfm.Eno = DhtpMgrEnoNoPeer
+	}
+
+	dht.DhtConfirmTo(dhtpMgr.ptnMe, req.Ptn, sch.EvDhtPrdProvideCfm, &cfm)
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Find providers request handler: take those known locally, and then ask the
+// peers closest to the key.
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrFindReq(req *sch.MsgDhtPrdFindReq) DhtpMgrErrno {
+
+	q := &prdQuery {
+		key:		req.Key,
+		id:			req.Id,
+		ptn:		req.Ptn,
+		found:		map[ycfg.NodeID]*ycfg.Node{},
+		queried:	map[ycfg.NodeID]bool{},
+		pending:	0,
+	}
+
+	for _, n := range dhtpMgr.dhtpLocalProviders(req.Key) {
+		q.found[n.ID] = n
+	}
+
+	for _, n := range dhtr.DhtrClosestPeers(dhtpMgr.sdl, dht.DhtKey2Hash(req.Key), nil, prdK) {
+		dhtpMgr.dhtpQuery(q, n.ID)
+	}
+
+	if q.pending == 0 {
+		dhtpMgr.dhtpFindConfirm(q)
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// AddProvider request from peer handler
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrAddProviderReq(req *sch.MsgDhtPrdAddProviderReq) DhtpMgrErrno {
+
+	ap := req.AddProvider
+
+	if ap == nil || len(ap.Key) == 0 || ap.Provider == nil {
+		yclog.LogCallerFileLine("dhtpMgrAddProviderReq: invalid request")
+		return DhtpMgrEnoMessage
+	}
+
+	//
+	// a peer can announce itself only
+	//
+
+	if ap.Provider.ID != req.From {
+
+		yclog.LogCallerFileLine("dhtpMgrAddProviderReq: " +
+			"provider mismatched, from: %s, provider: %s",
+			fmt.Sprintf("%X", req.From),
+			fmt.Sprintf("%X", ap.Provider.ID))
+
+		return DhtpMgrEnoMessage
+	}
+
+	ttl := time.Duration(ap.Ttl) * time.Second
+	if ttl == 0 {
+		ttl = prdRecordTtl
+	} else if ttl > prdMaxTtl {
+		ttl = prdMaxTtl
+	}
+
+	dhtpMgr.dhtpAddRecord(ap.Key, ap.Provider, ttl)
+
+	return DhtpMgrEnoNone
+}
+
+//
+// GetProviders request from peer handler
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrGetProvidersReq(req *sch.MsgDhtPrdGetProvidersReq) DhtpMgrErrno {
+
+	gp := req.GetProviders
+
+	if gp == nil || len(gp.Key) == 0 {
+		yclog.LogCallerFileLine("dhtpMgrGetProvidersReq: invalid request")
+		return DhtpMgrEnoMessage
+	}
+
+	var msg = dm.DhtMessage {
+		Mid:		dm.MID_DHT_PROVIDERS,
+		Providers:	&dm.Providers {
+			Id:			gp.Id,
+			Key:		gp.Key,
+			Providers:	dhtpMgr.dhtpLocalProviders(gp.Key),
+			Closer:		dhtr.DhtrClosestPeers(dhtpMgr.sdl, dht.DhtKey2Hash(gp.Key), &req.From, prdK),
+		},
+	}
+
+	if eno := dht.DhtSendMessage(dhtpMgr.sdl, req.From, &msg); eno != dht.DhtMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtpMgrGetProvidersReq: " +
+			"DhtSendMessage failed, eno: %d, to: %s",
+			eno, fmt.Sprintf("%X", req.From))
+
+		return DhtpMgrEnoMessage
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Providers response from peer handler
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrProvidersRsp(rsp *sch.MsgDhtPrdProvidersRsp) DhtpMgrErrno {
+
+	pr := rsp.Providers
+
+	if pr == nil {
+		yclog.LogCallerFileLine("dhtpMgrProvidersRsp: invalid response")
+		return DhtpMgrEnoMessage
+	}
+
+	pd, ok := dhtpMgr.pendings[pr.Id]
+	if !ok || pd.peer != rsp.From {
+
+		yclog.LogCallerFileLine("dhtpMgrProvidersRsp: " +
+			"query not found, might be timeout, qid: %d, from: %s",
+			pr.Id, fmt.Sprintf("%X", rsp.From))
+
+		return DhtpMgrEnoNone
+	}
+
+	delete(dhtpMgr.pendings, pr.Id)
+	q := pd.query
+	q.pending--
+
+	dhtr.DhtrReportLatency(dhtpMgr.sdl, pd.peer, time.Since(pd.sent))
+
+	for _, n := range pr.Providers {
+		q.found[n.ID] = n
+	}
+
+	//
+	// go on asking those closer peers in route table but not queried
+	//
+
+	for _, n := range pr.Closer {
+		if q.queried[n.ID] || len(q.queried) >= prdMaxQueried {
+			continue
+		}
+		if _, ok := dhtr.DhtrLookup(dhtpMgr.sdl, n.ID); ok {
+			dhtpMgr.dhtpQuery(q, n.ID)
+		}
+	}
+
+	if q.pending == 0 {
+		dhtpMgr.dhtpFindConfirm(q)
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Republish timer handler
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrRepublishTimerHandler() DhtpMgrErrno {
+
+	for _, key := range dhtpMgr.provides {
+		dhtpMgr.dhtpPublish(key)
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Cleanup timer handler: remove those records expired
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrCleanupTimerHandler() DhtpMgrErrno {
+
+	now := time.Now()
+
+	for k, recs := range dhtpMgr.records {
+
+		for id, rec := range recs {
+			if now.After(rec.expire) {
+				delete(recs, id)
+			}
+		}
+
+		if len(recs) == 0 {
+			delete(dhtpMgr.records, k)
+		}
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Query timer handler: check those queries timeout
+//
+func (dhtpMgr *dhtProviderManager) dhtpMgrQueryTimerHandler() DhtpMgrErrno {
+
+	now := time.Now()
+
+	for qid, pd := range dhtpMgr.pendings {
+
+		if now.Before(pd.deadline) {
+			continue
+		}
+
+		yclog.LogCallerFileLine("dhtpMgrQueryTimerHandler: " +
+			"query timeout, qid: %d, peer: %s",
+			qid, fmt.Sprintf("%X", pd.peer))
+
+		delete(dhtpMgr.pendings, qid)
+		dhtr.DhtrReportFailure(dhtpMgr.sdl, pd.peer)
+
+		if pd.query.pending--; pd.query.pending == 0 {
+			dhtpMgr.dhtpFindConfirm(pd.query)
+		}
+	}
+
+	return DhtpMgrEnoNone
+}
+
+//
+// Publish local node as a provider of key to the closest peers, the number
+// of peers published to is returned.
+//
+func (dhtpMgr *dhtProviderManager) dhtpPublish(key []byte) int {
+
+	dhtpMgr.dhtpAddRecord(key, &dhtpMgr.local, prdRecordTtl)
+
+	var count = 0
+
+	for _, n := range dhtr.DhtrClosestPeers(dhtpMgr.sdl, dht.DhtKey2Hash(key), nil, prdK) {
+
+		dhtpMgr.seq++
+
+		var msg = dm.DhtMessage {
+			Mid:			dm.MID_DHT_ADDPROVIDER,
+			AddProvider:	&dm.AddProvider {
+				Id:			dhtpMgr.seq,
+				Key:		key,
+				Provider:	&dhtpMgr.local,
+				Ttl:		uint32(prdRecordTtl / time.Second),
+			},
+		}
+
+		if eno := dht.DhtSendMessage(dhtpMgr.sdl, n.ID, &msg); eno != dht.DhtMgrEnoNone {
+
+			yclog.LogCallerFileLine("dhtpPublish: " +
+				"DhtSendMessage failed, eno: %d, to: %s",
+				eno, fmt.Sprintf("%X", n.ID))
+
+			continue
+		}
+
+		count++
+	}
+
+	return count
+}
+
+//
+// Send GetProviders to a peer for a find request
+//
+func (dhtpMgr *dhtProviderManager) dhtpQuery(q *prdQuery, to ycfg.NodeID) {
+
+	q.queried[to] = true
+	dhtpMgr.seq++
+
+	var msg = dm.DhtMessage {
+		Mid:			dm.MID_DHT_GETPROVIDERS,
+		GetProviders:	&dm.GetProviders {
+			Id:		dhtpMgr.seq,
+			Key:	q.key,
+		},
+	}
+
+	if eno := dht.DhtSendMessage(dhtpMgr.sdl, to, &msg); eno != dht.DhtMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtpQuery: " +
+			"DhtSendMessage failed, eno: %d, to: %s",
+			eno, fmt.Sprintf("%X", to))
+
+		return
+	}
+
+	now := time.Now()
+
+	dhtpMgr.pendings[dhtpMgr.seq] = &prdPending {
+		query:		q,
+		peer:		to,
+		sent:		now,
+		deadline:	now.Add(prdQueryTimeout),
+	}
+
+	q.pending++
+}
+
+//
+// Add or refresh a provider record
+//
+func (dhtpMgr *dhtProviderManager) dhtpAddRecord(key []byte, node *ycfg.Node, ttl time.Duration) {
+
+	recs, ok := dhtpMgr.records[string(key)]
+	if !ok {
+		recs = make(map[ycfg.NodeID]*prdRecord)
+		dhtpMgr.records[string(key)] = recs
+	}
+
+	recs[node.ID] = &prdRecord {
+		node:	*node,
+		expire:	time.Now().Add(ttl),
+	}
+}
+
+//
+// Get providers of key not expired
+//
+func (dhtpMgr *dhtProviderManager) dhtpLocalProviders(key []byte) []*ycfg.Node {
+
+	now := time.Now()
+	nodes := make([]*ycfg.Node, 0)
+
+	for _, rec := range dhtpMgr.records[string(key)] {
+		if now.Before(rec.expire) {
+			n := rec.node
+			nodes = append(nodes, &n)
+		}
+	}
+
+	return nodes
+}
+
+//
+// Confirm find providers request to the requester
+//
+func (dhtpMgr *dhtProviderManager) dhtpFindConfirm(q *prdQuery) {
+
+	var cfm = sch.MsgDhtPrdFindCfm {
+		Eno:		DhtpMgrEnoNone,
+		Key:		q.key,
+		Providers:	make([]*ycfg.Node, 0, len(q.found)),
+		Id:			q.id,
+	}
+
+	for _, n := range q.found {
+		cfm.Providers = append(cfm.Providers, n)
+	}
+
+	dht.DhtConfirmTo(dhtpMgr.ptnMe, q.ptn, sch.EvDhtPrdFindCfm, &cfm)
+}
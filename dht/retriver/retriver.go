@@ -18,34 +18,529 @@
  *
  */
 
-
 package retriver
 
 import (
+	"fmt"
+	"sort"
+	"time"
 	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/dht"
+	dm		"github.com/yeeco/p2p/dht/dhtmsg"
+	dhtst	"github.com/yeeco/p2p/dht/storer"
+	dhtr	"github.com/yeeco/p2p/dht/route"
+)
+
+//
+// errno
+//
+const (
+	DhtreMgrEnoNone	= iota
+	DhtreMgrEnoParameter
+	DhtreMgrEnoScheduler
+	DhtreMgrEnoNotFound
+	DhtreMgrEnoNoPeer
+	DhtreMgrEnoMessage
+	DhtreMgrEnoUnknown
+)
+
+type DhtreMgrErrno int
+
+//
+// Lookup parameters. A value is looked up iteratively as what Kademlia does:
+// at most reAlpha queries are in flight for a lookup, and the lookup ends up
+// when the value found, or all of the reK closest candidates known have been
+// queried without the value found. Notice that only those peers in the route
+// table are queried, nodes learned from responses but not in it are skipped.
+//
+const (
+	reAlpha				= 3					// max queries in flight for a lookup
+	reK					= 16				// size of the shortlist, not more than table's bonding
+	reQueryTimeout		= time.Second * 5	// timeout for a query
+	reQueryTickCycle	= time.Second		// cycle for query timeout checking
 )
 
+//
+// Candidate state
+//
+const (
+	reCandNew		= iota	// not queried yet
+	reCandQueried			// query sent, waiting response
+	reCandReplied			// response received
+	reCandFailed			// query timeout
+)
+
+type reCandidate struct {
+	node		ycfg.Node		// the peer node
+	hash		dht.DhtHash		// position of the node
+	state		int				// state
+	qid			uint64			// identity of query to this candidate
+	sent		time.Time		// time the query sent
+	deadline	time.Time		// time to wait response till
+}
+
+type reLookup struct {
+	key			[]byte					// key wanted
+	id			uint64					// identity of the retrive request
+	ptn			interface{}				// task to confirm to, nil for the user of dht
+	exclude		map[ycfg.NodeID]bool	// peers not to be queried
+	target		dht.DhtHash				// position of the key
+	short		[]*reCandidate			// shortlist, sorted by distance to target
+	inflight	int						// number of queries in flight
+}
+
 //
 // retrive manager
 //
-const DhtreMgrName = "DhtreMgr"
+const DhtreMgrName = sch.DhtreMgrName
 
 type dhtRetriverManager struct {
-	name	string				// name
-	tep		sch.SchUserTaskEp	// entry
+	name		string					// name
+	tep			sch.SchUserTaskEp		// entry
+	sdl			*sch.Scheduler			// pointer to scheduler
+	ptnMe		interface{}				// pointer to myself task node
+	tidQuery	int						// query timer identity
+	qidSeq		uint64					// sequence for query identities
+	queries		map[uint64]*reLookup	// map query identity to lookup
 }
 
-var dhtreMgr = dhtRetriverManager{
-	name:	DhtreMgrName,
-	tep:	DhtreMgrProc,
+//
+// Create retriver manager, one for each p2p instance
+//
+func NewDhtreMgr() interface{} {
+	return &dhtRetriverManager{
+		name:		DhtreMgrName,
+		tep:		DhtreMgrProc,
+		ptnMe:		nil,
+		tidQuery:	sch.SchInvalidTid,
+		qidSeq:		0,
+		queries:	map[uint64]*reLookup{},
+	}
 }
 
 //
 // retrive manager entry
 //
 func DhtreMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtreMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtreMgr := sch.SchinfGetUserDataArea(ptn).(*dhtRetriverManager)
+
+	var eno DhtreMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = dhtreMgr.dhtreMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = dhtreMgr.dhtreMgrPoweroff(ptn)
+
+	case sch.EvDhtreQueryTimer:
+		eno = dhtreMgr.dhtreMgrQueryTimerHandler()
+
+	case sch.EvDhtRetriveReq:
+		eno = dhtreMgr.dhtreMgrRetriveReq(msg.Body.(*sch.MsgDhtRetriveReq))
+
+	case sch.EvDhtPeerLkFindValueReq:
+		eno = dhtreMgr.dhtreMgrFindValueReq(msg.Body.(*sch.MsgDhtPeerLkFindValueReq))
+
+	case sch.EvDhtPeerLkValueRsp:
+		eno = dhtreMgr.dhtreMgrValueRsp(msg.Body.(*sch.MsgDhtPeerLkValueRsp))
+
+	default:
+		yclog.LogCallerFileLine("DhtreMgrProc: invalid message: %d", msg.Id)
+		eno = DhtreMgrEnoParameter
+	}
+
+	if eno != DhtreMgrEnoNone {
+		yclog.LogCallerFileLine("DhtreMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweron handler
+//
+func (dhtreMgr *dhtRetriverManager) dhtreMgrPoweron(ptn interface{}) DhtreMgrErrno {
+
+	dhtreMgr.sdl = sch.SchinfGetScheduler(ptn)
+	dhtreMgr.ptnMe = ptn
+
+	var td = sch.TimerDescription {
+		Name:	DhtreMgrName + "_query",
+		Utid:	sch.DhtreQueryTimerId,
+		Tmt:	sch.SchTmTypePeriod,
+		Dur:	reQueryTickCycle,
+		Extra:	nil,
+	}
+
+	eno, tid := sch.SchInfSetTimer(ptn, &td)
+	if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+		yclog.LogCallerFileLine("dhtreMgrPoweron: " +
+			"SchInfSetTimer failed, eno: %d",
+			eno)
+
+		return DhtreMgrEnoScheduler
+	}
+
+	dhtreMgr.tidQuery = tid
+
+	return DhtreMgrEnoNone
+}
+
+//
+// Poweroff handler
+//
+func (dhtreMgr *dhtRetriverManager) dhtreMgrPoweroff(ptn interface{}) DhtreMgrErrno {
+
+	if dhtreMgr.tidQuery != sch.SchInvalidTid {
+		sch.SchinfKillTimer(ptn, dhtreMgr.tidQuery)
+		dhtreMgr.tidQuery = sch.SchInvalidTid
+	}
+
+	dhtreMgr.queries = map[uint64]*reLookup{}
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtreMgrEnoUnknown
+	}
+
+	return DhtreMgrEnoNone
+}
+
+//
+// Retrive request handler
+//
+func (dhtreMgr *dhtRetriverManager) dhtreMgrRetriveReq(req *sch.MsgDhtRetriveReq) DhtreMgrErrno {
+
+	//
+	// try local store at first
+	//
+
+	if chunk, eno := dhtst.DhtstGetChunk(dhtreMgr.sdl, req.Key); eno == dhtst.DhtstMgrEnoNone {
+		dhtreMgr.dhtreConfirm(req.Ptn, req.Key, req.Id, chunk, nil, DhtreMgrEnoNone)
+		return DhtreMgrEnoNone
+	}
+
+	lk := &reLookup {
+		key:		req.Key,
+		id:			req.Id,
+		ptn:		req.Ptn,
+		exclude:	make(map[ycfg.NodeID]bool, len(req.Exclude)),
+		target:		dht.DhtKey2Hash(req.Key),
+		short:		make([]*reCandidate, 0, reK),
+		inflight:	0,
+	}
+
+	for _, id := range req.Exclude {
+		lk.exclude[id] = true
+	}
+
+	//
+	// the starting set: peers closest to the key in the route table
+	//
+
+	for _, n := range dhtr.DhtrClosestPeers(dhtreMgr.sdl, lk.target, nil, reK) {
+		dhtreAddCandidate(lk, n)
+	}
+
+	if len(lk.short) == 0 {
+
+		yclog.LogCallerFileLine("dhtreMgrRetriveReq: " +
+			"no peers to query, key: %s",
+			fmt.Sprintf("%X", req.Key))
+
+		dhtreMgr.dhtreConfirm(req.Ptn, req.Key, req.Id, nil, nil, DhtreMgrEnoNoPeer)
+		return DhtreMgrEnoNone
+	}
+
+	dhtreMgr.dhtreLookupNext(lk)
+
+	return DhtreMgrEnoNone
+}
+
+//
+// FindValue request from peer handler
+//
+func (dhtreMgr *dhtRetriverManager) dhtreMgrFindValueReq(req *sch.MsgDhtPeerLkFindValueReq) DhtreMgrErrno {
+
+	fv := req.FindValue
+
+	if fv == nil || len(fv.Key) == 0 {
+		yclog.LogCallerFileLine("dhtreMgrFindValueReq: invalid request")
+		return DhtreMgrEnoMessage
+	}
+
+	var val = dm.Value {
+		Id:		fv.Id,
+		Key:	fv.Key,
+		Value:	nil,
+		Closer:	nil,
+	}
+
+	if chunk, eno := dhtst.DhtstGetChunk(dhtreMgr.sdl, fv.Key); eno == dhtst.DhtstMgrEnoNone {
+
+		val.Value = chunk
+
+	} else {
+
+		//
+		// tell the requester the peers closer to the key we known, except
+		// itself.
+		//
+
+		val.Closer = dhtr.DhtrClosestPeers(dhtreMgr.sdl, dht.DhtKey2Hash(fv.Key), &req.From, reK)
+	}
+
+	var msg = dm.DhtMessage {
+		Mid:	dm.MID_DHT_VALUE,
+		Value:	&val,
+	}
+
+	if eno := dht.DhtSendMessage(dhtreMgr.sdl, req.From, &msg); eno != dht.DhtMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtreMgrFindValueReq: " +
+			"DhtSendMessage failed, eno: %d, to: %s",
+			eno, fmt.Sprintf("%X", req.From))
+
+		return DhtreMgrEnoMessage
+	}
+
+	return DhtreMgrEnoNone
+}
+
+//
+// Value response from peer handler
+//
+func (dhtreMgr *dhtRetriverManager) dhtreMgrValueRsp(rsp *sch.MsgDhtPeerLkValueRsp) DhtreMgrErrno {
+
+	val := rsp.Value
+
+	if val == nil {
+		yclog.LogCallerFileLine("dhtreMgrValueRsp: invalid response")
+		return DhtreMgrEnoMessage
+	}
+
+	lk, ok := dhtreMgr.queries[val.Id]
+	if !ok {
+
+		yclog.LogCallerFileLine("dhtreMgrValueRsp: " +
+			"query not found, might be timeout, qid: %d",
+			val.Id)
+
+		return DhtreMgrEnoNone
+	}
+
+	var cand *reCandidate = nil
+
+	for _, c := range lk.short {
+		if c.qid == val.Id && c.state == reCandQueried {
+			cand = c
+			break
+		}
+	}
+
+	if cand == nil || cand.node.ID != rsp.From {
+
+		yclog.LogCallerFileLine("dhtreMgrValueRsp: " +
+			"response mismatched, qid: %d, from: %s",
+			val.Id, fmt.Sprintf("%X", rsp.From))
+
+		return DhtreMgrEnoMessage
+	}
+
+	delete(dhtreMgr.queries, val.Id)
+	cand.state = reCandReplied
+	lk.inflight--
+
+	dhtr.DhtrReportLatency(dhtreMgr.sdl, cand.node.ID, time.Since(cand.sent))
+
+	if len(val.Value) > 0 {
+		dhtreMgr.dhtreLookupDone(lk, val.Value, DhtreMgrEnoNone)
+		return DhtreMgrEnoNone
+	}
+
+	//
+	// merge closer nodes into the shortlist, those not in route table are
+	// skipped
+	//
+
+	for _, n := range val.Closer {
+		if rn, ok := dhtr.DhtrLookup(dhtreMgr.sdl, n.ID); ok {
+			dhtreAddCandidate(lk, rn)
+		}
+	}
+
+	dhtreMgr.dhtreLookupNext(lk)
+
+	return DhtreMgrEnoNone
+}
+
+//
+// Query timer handler: check those queries timeout
+//
+func (dhtreMgr *dhtRetriverManager) dhtreMgrQueryTimerHandler() DhtreMgrErrno {
+
+	now := time.Now()
+	lookups := make(map[*reLookup]bool)
+
+	for qid, lk := range dhtreMgr.queries {
+
+		for _, c := range lk.short {
+
+			if c.qid != qid || c.state != reCandQueried || now.Before(c.deadline) {
+				continue
+			}
+
+			yclog.LogCallerFileLine("dhtreMgrQueryTimerHandler: " +
+				"query timeout, qid: %d, peer: %s",
+				qid, fmt.Sprintf("%X", c.node.ID))
+
+			c.state = reCandFailed
+			lk.inflight--
+			delete(dhtreMgr.queries, qid)
+			lookups[lk] = true
+
+			dhtr.DhtrReportFailure(dhtreMgr.sdl, c.node.ID)
+		}
+	}
+
+	for lk := range lookups {
+		dhtreMgr.dhtreLookupNext(lk)
+	}
+
+	return DhtreMgrEnoNone
+}
+
+//
+// Send queries for a lookup, or end it up if nothing more to do
+//
+func (dhtreMgr *dhtRetriverManager) dhtreLookupNext(lk *reLookup) {
+
+	var considered = 0
+
+	for _, c := range lk.short {
+
+		if lk.inflight >= reAlpha || considered >= reK {
+			break
+		}
+
+		if c.state == reCandFailed {
+			continue
+		}
+
+		considered++
+
+		if c.state != reCandNew {
+			continue
+		}
+
+		dhtreMgr.qidSeq++
+		c.qid = dhtreMgr.qidSeq
+
+		var msg = dm.DhtMessage {
+			Mid:		dm.MID_DHT_FINDVALUE,
+			FindValue:	&dm.FindValue {
+				Id:		c.qid,
+				Key:	lk.key,
+			},
+		}
+
+		if eno := dht.DhtSendMessage(dhtreMgr.sdl, c.node.ID, &msg); eno != dht.DhtMgrEnoNone {
+
+			yclog.LogCallerFileLine("dhtreLookupNext: " +
+				"DhtSendMessage failed, eno: %d, to: %s",
+				eno, fmt.Sprintf("%X", c.node.ID))
+
+			c.state = reCandFailed
+			continue
+		}
+
+		c.state = reCandQueried
+		c.sent = time.Now()
+		c.deadline = c.sent.Add(reQueryTimeout)
+		dhtreMgr.queries[c.qid] = lk
+		lk.inflight++
+	}
+
+	if lk.inflight == 0 {
+		dhtreMgr.dhtreLookupDone(lk, nil, DhtreMgrEnoNotFound)
+	}
+}
+
+//
+// End up a lookup and confirm the result
+//
+func (dhtreMgr *dhtRetriverManager) dhtreLookupDone(lk *reLookup, chunk []byte, eno DhtreMgrErrno) {
+
+	for _, c := range lk.short {
+		if c.state == reCandQueried {
+			delete(dhtreMgr.queries, c.qid)
+		}
+	}
+
+	lk.inflight = 0
+
+	queried := make([]ycfg.NodeID, 0, len(lk.short))
+
+	for _, c := range lk.short {
+		if c.state == reCandReplied || c.state == reCandFailed {
+			queried = append(queried, c.node.ID)
+		}
+	}
+
+	dhtreMgr.dhtreConfirm(lk.ptn, lk.key, lk.id, chunk, queried, eno)
+}
+
+//
+// Add a candidate to the shortlist of a lookup, duplicated and excluded ones
+// are ignored
+//
+func dhtreAddCandidate(lk *reLookup, node *ycfg.Node) {
+
+	if lk.exclude[node.ID] {
+		return
+	}
+
+	for _, c := range lk.short {
+		if c.node.ID == node.ID {
+			return
+		}
+	}
+
+	cand := &reCandidate {
+		node:	*node,
+		hash:	dht.DhtNodeId2Hash(node.ID),
+		state:	reCandNew,
+	}
+
+	idx := sort.Search(len(lk.short), func(i int) bool {
+		return dht.DhtDistCmp(lk.target, cand.hash, lk.short[i].hash) < 0
+	})
+
+	lk.short = append(lk.short, nil)
+	copy(lk.short[idx+1:], lk.short[idx:])
+	lk.short[idx] = cand
+}
+
+//
+// Confirm to the requester
+//
+func (dhtreMgr *dhtRetriverManager) dhtreConfirm(ptn interface{}, key []byte, id uint64, chunk []byte, queried []ycfg.NodeID, eno DhtreMgrErrno) {
+
+	var cfm = sch.MsgDhtRetriveCfm {
+		Eno:		int(eno),
+		Key:		key,
+		Chunk:		chunk,
+		Id:			id,
+		Queried:	queried,
+	}
+
+	dht.DhtConfirmTo(dhtreMgr.ptnMe, ptn, sch.EvDhtRetriveCfm, &cfm)
+}
@@ -8,30 +8,368 @@
 package route
 
 import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
 	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/peer"
+			"github.com/yeeco/p2p/dht"
 )
 
+//
+// errno
+//
+const (
+	DhtrMgrEnoNone	= iota
+	DhtrMgrEnoParameter
+	DhtrMgrEnoNotFound
+	DhtrMgrEnoScheduler
+	DhtrMgrEnoUnknown
+)
+
+type DhtrMgrErrno int
+
+//
+// The route table of dht. Different from the table of discovery, which holds
+// nodes learned from udp neighbors, only those peers completed the handshake
+// and advertised the dht protocol are put into this table, so the dht never
+// routes through nodes it's not connected to. Peers are placed by sha256 of
+// their identities, see dht.DhtNodeId2Hash, and the latency and failures
+// reported by other dht tasks are counted for each of them. Peers failed
+// continuously too many times are skipped when looking up for a while, till
+// they response again or the hold time passed.
+//
+const (
+	rtMaxFails		= 3					// max continuous failures for a peer to be routed
+	rtFailHold		= time.Minute		// time to skip a peer failed too many times
+	rtRttWeight		= 8					// weight of history in smoothed latency
+)
+
+//
+// Statistics of a peer
+//
+type DhtrPeerStat struct {
+	Added		time.Time		// time the peer added
+	LastSeen	time.Time		// time of last response from peer
+	Latency		time.Duration	// smoothed latency
+	Samples		int				// latency samples
+	Fails		int				// continuous failures
+	TotalFails	int				// total failures
+	LastFail	time.Time		// time of last failure
+}
+
+//
+// Route table entry
+//
+type rtEntry struct {
+	node		ycfg.Node		// the peer node
+	hash		dht.DhtHash		// position of the peer
+	stat		DhtrPeerStat	// statistics
+}
+
 //
 // Route manager
 //
-const DhtrMgrName = "DhtpMgr"
+const DhtrMgrName = sch.DhtrMgrName
 
 type dhtRouteManager struct {
-	name	string				// name
-	tep		sch.SchUserTaskEp	// entry
+	name		string						// name
+	tep			sch.SchUserTaskEp			// entry
+	sdl			*sch.Scheduler				// pointer to scheduler
+	ptnMe		interface{}					// pointer to myself task node
+	lock		sync.RWMutex				// lock for the table, accessed by other tasks
+	table		map[dht.DhtHash]*rtEntry	// route table keyed by position of peers
 }
 
-var dhtpMgr = dhtRouteManager{
-	name:	DhtrMgrName,
-	tep:	DhtrMgrProc,
+//
+// Create route manager, one for each p2p instance
+//
+func NewDhtrMgr() interface{} {
+	return &dhtRouteManager{
+		name:	DhtrMgrName,
+		tep:	DhtrMgrProc,
+		ptnMe:	nil,
+		table:	map[dht.DhtHash]*rtEntry{},
+	}
+}
+
+//
+// Get route manager of a p2p instance by its' scheduler, for functions exported
+// to other dht tasks.
+//
+func dhtrGetManager(sdl *sch.Scheduler) *dhtRouteManager {
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(sdl, DhtrMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+		return nil
+	}
+
+	dhtrMgr, _ := sch.SchinfGetUserDataArea(ptn).(*dhtRouteManager)
+
+	return dhtrMgr
 }
 
 //
-// Table manager entry
+// Route manager entry
 //
 func DhtrMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtrMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtrMgr := sch.SchinfGetUserDataArea(ptn).(*dhtRouteManager)
+
+	var eno DhtrMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		dhtrMgr.sdl = sch.SchinfGetScheduler(ptn)
+		dhtrMgr.ptnMe = ptn
+		eno = DhtrMgrEnoNone
+
+	case sch.EvSchPoweroff:
+		eno = dhtrMgr.dhtrMgrPoweroff(ptn)
+
+	case sch.EvDhtMgrPeerInd:
+		eno = dhtrMgr.dhtrMgrPeerInd(msg.Body.(*sch.MsgDhtPeerInd))
+
+	default:
+		yclog.LogCallerFileLine("DhtrMgrProc: invalid message: %d", msg.Id)
+		eno = DhtrMgrEnoParameter
+	}
+
+	if eno != DhtrMgrEnoNone {
+		yclog.LogCallerFileLine("DhtrMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweroff handler
+//
+func (dhtrMgr *dhtRouteManager) dhtrMgrPoweroff(ptn interface{}) DhtrMgrErrno {
+
+	dhtrMgr.lock.Lock()
+	dhtrMgr.table = map[dht.DhtHash]*rtEntry{}
+	dhtrMgr.lock.Unlock()
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtrMgrEnoUnknown
+	}
+
+	return DhtrMgrEnoNone
+}
+
+//
+// Peer activated or closed indication handler
+//
+func (dhtrMgr *dhtRouteManager) dhtrMgrPeerInd(ind *sch.MsgDhtPeerInd) DhtrMgrErrno {
+
+	hash := dht.DhtNodeId2Hash(ind.Node.ID)
+
+	dhtrMgr.lock.Lock()
+	defer dhtrMgr.lock.Unlock()
+
+	switch ind.Ind {
+
+	case peer.P2pIndPeerActivated:
+
+		dhtrMgr.table[hash] = &rtEntry {
+			node:	ind.Node,
+			hash:	hash,
+			stat:	DhtrPeerStat {
+				Added:		time.Now(),
+				LastSeen:	time.Now(),
+			},
+		}
+
+	case peer.P2pIndPeerClosed:
+
+		delete(dhtrMgr.table, hash)
+
+	default:
+
+		yclog.LogCallerFileLine("dhtrMgrPeerInd: " +
+			"invalid indication: %d, peer: %s",
+			ind.Ind, fmt.Sprintf("%X", ind.Node.ID))
+
+		return DhtrMgrEnoParameter
+	}
+
+	return DhtrMgrEnoNone
+}
+
+//
+// Get peers in table closest to target, the one specified by except would
+// be excluded if it's not nil. Notice: functions exported here are called
+// by other dht tasks directly, the table is protected by lock.
+//
+func DhtrClosestPeers(sdl *sch.Scheduler, target dht.DhtHash, except *ycfg.NodeID, size int) []*ycfg.Node {
+
+	dhtrMgr := dhtrGetManager(sdl)
+	if dhtrMgr == nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	dhtrMgr.lock.RLock()
+
+	entries := make([]*rtEntry, 0, len(dhtrMgr.table))
+
+	for _, e := range dhtrMgr.table {
+		if e.stat.Fails >= rtMaxFails && now.Sub(e.stat.LastFail) < rtFailHold {
+			continue
+		}
+		if except == nil || e.node.ID != *except {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return dht.DhtDistCmp(target, entries[i].hash, entries[j].hash) < 0
+	})
+
+	if len(entries) > size {
+		entries = entries[:size]
+	}
+
+	closest := make([]*ycfg.Node, 0, len(entries))
+
+	for _, e := range entries {
+		n := e.node
+		closest = append(closest, &n)
+	}
+
+	dhtrMgr.lock.RUnlock()
+
+	return closest
+}
+
+//
+// Get peer node in table by identity
+//
+func DhtrLookup(sdl *sch.Scheduler, id ycfg.NodeID) (*ycfg.Node, bool) {
+
+	dhtrMgr := dhtrGetManager(sdl)
+	if dhtrMgr == nil {
+		return nil, false
+	}
+
+	dhtrMgr.lock.RLock()
+	defer dhtrMgr.lock.RUnlock()
+
+	e, ok := dhtrMgr.table[dht.DhtNodeId2Hash(id)]
+	if !ok {
+		return nil, false
+	}
+
+	n := e.node
+
+	return &n, true
+}
+
+//
+// Get number of peers in table
+//
+func DhtrSize(sdl *sch.Scheduler) int {
+	dhtrMgr := dhtrGetManager(sdl)
+	if dhtrMgr == nil {
+		return 0
+	}
+
+	dhtrMgr.lock.RLock()
+	defer dhtrMgr.lock.RUnlock()
+	return len(dhtrMgr.table)
+}
+
+//
+// Get statistics of a peer
+//
+func DhtrGetStat(sdl *sch.Scheduler, id ycfg.NodeID) (DhtrPeerStat, DhtrMgrErrno) {
+
+	dhtrMgr := dhtrGetManager(sdl)
+	if dhtrMgr == nil {
+		return DhtrPeerStat{}, DhtrMgrEnoScheduler
+	}
+
+	dhtrMgr.lock.RLock()
+	defer dhtrMgr.lock.RUnlock()
+
+	e, ok := dhtrMgr.table[dht.DhtNodeId2Hash(id)]
+	if !ok {
+		return DhtrPeerStat{}, DhtrMgrEnoNotFound
+	}
+
+	return e.stat, DhtrMgrEnoNone
+}
+
+//
+// Report a response from peer with the round trip time of the request
+//
+func DhtrReportLatency(sdl *sch.Scheduler, id ycfg.NodeID, rtt time.Duration) DhtrMgrErrno {
+
+	dhtrMgr := dhtrGetManager(sdl)
+	if dhtrMgr == nil {
+		return DhtrMgrEnoScheduler
+	}
+
+	dhtrMgr.lock.Lock()
+	defer dhtrMgr.lock.Unlock()
+
+	e, ok := dhtrMgr.table[dht.DhtNodeId2Hash(id)]
+	if !ok {
+		return DhtrMgrEnoNotFound
+	}
+
+	st := &e.stat
+
+	if st.Samples == 0 {
+		st.Latency = rtt
+	} else {
+		st.Latency = (st.Latency * (rtRttWeight - 1) + rtt) / rtRttWeight
+	}
+
+	st.Samples++
+	st.Fails = 0
+	st.LastSeen = time.Now()
+
+	return DhtrMgrEnoNone
+}
+
+//
+// Report a request to peer failed, timeout mostly
+//
+func DhtrReportFailure(sdl *sch.Scheduler, id ycfg.NodeID) DhtrMgrErrno {
+
+	dhtrMgr := dhtrGetManager(sdl)
+	if dhtrMgr == nil {
+		return DhtrMgrEnoScheduler
+	}
+
+	dhtrMgr.lock.Lock()
+	defer dhtrMgr.lock.Unlock()
+
+	e, ok := dhtrMgr.table[dht.DhtNodeId2Hash(id)]
+	if !ok {
+		return DhtrMgrEnoNotFound
+	}
+
+	e.stat.Fails++
+	e.stat.TotalFails++
+	e.stat.LastFail = time.Now()
+
+	if e.stat.Fails == rtMaxFails {
+
+		yclog.LogCallerFileLine("DhtrReportFailure: " +
+			"peer not routed till it responses, fails: %d, peer: %s",
+			e.stat.Fails, fmt.Sprintf("%X", id))
+	}
+
+	return DhtrMgrEnoNone
+}
@@ -22,30 +22,159 @@
 package route
 
 import (
+	"fmt"
 	sch 	"github.com/yeeco/p2p/scheduler"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/dht"
+	dm		"github.com/yeeco/p2p/dht/dhtmsg"
+	dhtr	"github.com/yeeco/p2p/dht/route"
 )
 
+//
+// errno
+//
+const (
+	DhtroMgrEnoNone	= iota
+	DhtroMgrEnoParameter
+	DhtroMgrEnoMessage
+	DhtroMgrEnoUnknown
+)
+
+type DhtroMgrErrno int
+
+//
+// Max nodes in a neighbors response
+//
+const roMaxNeighbors = 16
+
 //
 // Route manager
 //
-const DhtroMgrName = "DhtroMgr"
+const DhtroMgrName = sch.DhtroMgrName
 
 type dhtRouteManager struct {
 	name	string				// name
 	tep		sch.SchUserTaskEp	// entry
+	sdl		*sch.Scheduler		// pointer to scheduler
+	ptnMe	interface{}			// pointer to myself task node
 }
 
-var dhtrMgr = dhtRouteManager{
-	name:	DhtroMgrName,
-	tep:	DhtroMgrProc,
+//
+// Create router manager, one for each p2p instance
+//
+func NewDhtroMgr() interface{} {
+	return &dhtRouteManager{
+		name:	DhtroMgrName,
+		tep:	DhtroMgrProc,
+		ptnMe:	nil,
+	}
 }
 
 //
-// Table manager entry
+// Route manager entry
 //
 func DhtroMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtroMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtrMgr := sch.SchinfGetUserDataArea(ptn).(*dhtRouteManager)
+
+	var eno DhtroMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		dhtrMgr.sdl = sch.SchinfGetScheduler(ptn)
+		dhtrMgr.ptnMe = ptn
+		eno = DhtroMgrEnoNone
+
+	case sch.EvSchPoweroff:
+		eno = dhtroMgrPoweroff(ptn)
+
+	case sch.EvDhtPeerLkFindNodeReq:
+		eno = dhtrMgr.dhtroMgrFindNodeReq(msg.Body.(*sch.MsgDhtPeerLkFindNodeReq))
+
+	case sch.EvDhtPeerLkNeighborsRsp:
+		eno = dhtroMgrNeighborsRsp(msg.Body.(*sch.MsgDhtPeerLkNeighborsRsp))
+
+	default:
+		yclog.LogCallerFileLine("DhtroMgrProc: invalid message: %d", msg.Id)
+		eno = DhtroMgrEnoParameter
+	}
+
+	if eno != DhtroMgrEnoNone {
+		yclog.LogCallerFileLine("DhtroMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweroff handler
+//
+func dhtroMgrPoweroff(ptn interface{}) DhtroMgrErrno {
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtroMgrEnoUnknown
+	}
+
+	return DhtroMgrEnoNone
+}
+
+//
+// FindNode request from peer handler: response with peers closest to the
+// target except the requester.
+//
+func (dhtrMgr *dhtRouteManager) dhtroMgrFindNodeReq(req *sch.MsgDhtPeerLkFindNodeReq) DhtroMgrErrno {
+
+	fn := req.FindNode
+
+	if fn == nil {
+		yclog.LogCallerFileLine("dhtroMgrFindNodeReq: invalid request")
+		return DhtroMgrEnoMessage
+	}
+
+	var msg = dm.DhtMessage {
+		Mid:		dm.MID_DHT_NEIGHBORS,
+		Neighbors:	&dm.Neighbors {
+			Id:		fn.Id,
+			Target:	fn.Target,
+			Nodes:	dhtr.DhtrClosestPeers(dhtrMgr.sdl, dht.DhtNodeId2Hash(fn.Target), &req.From, roMaxNeighbors),
+		},
+	}
+
+	if eno := dht.DhtSendMessage(dhtrMgr.sdl, req.From, &msg); eno != dht.DhtMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtroMgrFindNodeReq: " +
+			"DhtSendMessage failed, eno: %d, to: %s",
+			eno, fmt.Sprintf("%X", req.From))
+
+		return DhtroMgrEnoMessage
+	}
+
+	return DhtroMgrEnoNone
+}
+
+//
+// Neighbors response from peer handler
+//
+func dhtroMgrNeighborsRsp(rsp *sch.MsgDhtPeerLkNeighborsRsp) DhtroMgrErrno {
+
+	if rsp.Neighbors == nil {
+		yclog.LogCallerFileLine("dhtroMgrNeighborsRsp: invalid response")
+		return DhtroMgrEnoMessage
+	}
+
+	//
+	// no node lookup issued by this task currently, just discard it
+	//
+
+	yclog.LogCallerFileLine("dhtroMgrNeighborsRsp: " +
+		"discarded, qid: %d, from: %s, nodes: %d",
+		rsp.Neighbors.Id,
+		fmt.Sprintf("%X", rsp.From),
+		len(rsp.Neighbors.Nodes))
+
+	return DhtroMgrEnoNone
+}
@@ -0,0 +1,140 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package storer
+
+import (
+	"sync"
+	"errors"
+)
+
+//
+// Chunk store interface. A backend should be safe for concurrent accessing,
+// since it might be read by other tasks than the storer, see DhtstGetChunk.
+//
+type ChunkStore interface {
+	Put(key []byte, chunk []byte) error		// put a chunk, overlapped if exist
+	Get(key []byte) ([]byte, error)			// get a chunk, ErrChunkNotFound if none
+	Has(key []byte) bool					// check if chunk exist
+	Delete(key []byte) error				// delete a chunk
+	ForEach(fn func(key []byte) bool) error	// walk all keys until fn returns false
+	Close() error							// close the store
+}
+
+//
+// Errors for chunk store
+//
+var ErrChunkNotFound = errors.New("chunk not found")
+var ErrStoreClosed = errors.New("chunk store closed")
+
+//
+// Chunk store backends
+//
+const (
+	ChunkStoreMemory	= "memory"		// in memory, lost when power off
+	ChunkStoreLevelDb	= "leveldb"		// leveldb in data directory
+)
+
+//
+// In-memory backend
+//
+type memChunkStore struct {
+	lock	sync.RWMutex		// lock for chunks
+	chunks	map[string][]byte	// map key to chunk
+}
+
+func NewMemChunkStore() ChunkStore {
+	return &memChunkStore{
+		chunks: make(map[string][]byte),
+	}
+}
+
+func (ms *memChunkStore) Put(key []byte, chunk []byte) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	if ms.chunks == nil {
+		return ErrStoreClosed
+	}
+	ms.chunks[string(key)] = append([]byte{}, chunk...)
+	return nil
+}
+
+func (ms *memChunkStore) Get(key []byte) ([]byte, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	if ms.chunks == nil {
+		return nil, ErrStoreClosed
+	}
+	chunk, ok := ms.chunks[string(key)]
+	if !ok {
+		return nil, ErrChunkNotFound
+	}
+	return append([]byte{}, chunk...), nil
+}
+
+func (ms *memChunkStore) Has(key []byte) bool {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	_, ok := ms.chunks[string(key)]
+	return ok
+}
+
+func (ms *memChunkStore) Delete(key []byte) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	if ms.chunks == nil {
+		return ErrStoreClosed
+	}
+	delete(ms.chunks, string(key))
+	return nil
+}
+
+func (ms *memChunkStore) ForEach(fn func(key []byte) bool) error {
+
+	//
+	// keys are copied out firstly, so fn can access the store
+	//
+
+	ms.lock.RLock()
+	if ms.chunks == nil {
+		ms.lock.RUnlock()
+		return ErrStoreClosed
+	}
+	keys := make([][]byte, 0, len(ms.chunks))
+	for k := range ms.chunks {
+		keys = append(keys, []byte(k))
+	}
+	ms.lock.RUnlock()
+
+	for _, k := range keys {
+		if !fn(k) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (ms *memChunkStore) Close() error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.chunks = nil
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package storer
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+//
+// Leveldb backend, chunks are keyed with a prefix, so the database can be
+// extended with other items later.
+//
+var ldbChunkPrefix = []byte("c:")
+
+type ldbChunkStore struct {
+	lvl		*leveldb.DB		// the database
+}
+
+func NewLdbChunkStore(path string) (ChunkStore, error) {
+	opts := &opt.Options{OpenFilesCacheCapacity: 16}
+	db, err := leveldb.OpenFile(path, opts)
+	if _, iscorrupted := err.(*errors.ErrCorrupted); iscorrupted {
+		db, err = leveldb.RecoverFile(path, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ldbChunkStore{lvl: db}, nil
+}
+
+func ldbChunkKey(key []byte) []byte {
+	return append(append(make([]byte, 0, len(ldbChunkPrefix) + len(key)), ldbChunkPrefix...), key...)
+}
+
+func (ls *ldbChunkStore) Put(key []byte, chunk []byte) error {
+	return ls.lvl.Put(ldbChunkKey(key), chunk, nil)
+}
+
+func (ls *ldbChunkStore) Get(key []byte) ([]byte, error) {
+	chunk, err := ls.lvl.Get(ldbChunkKey(key), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrChunkNotFound
+	}
+	return chunk, err
+}
+
+func (ls *ldbChunkStore) Has(key []byte) bool {
+	ok, err := ls.lvl.Has(ldbChunkKey(key), nil)
+	return err == nil && ok
+}
+
+func (ls *ldbChunkStore) Delete(key []byte) error {
+	return ls.lvl.Delete(ldbChunkKey(key), nil)
+}
+
+func (ls *ldbChunkStore) ForEach(fn func(key []byte) bool) error {
+
+	//
+	// iterate on a snapshot, so fn can modify the store
+	//
+
+	snap, err := ls.lvl.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	it := snap.NewIterator(util.BytesPrefix(ldbChunkPrefix), nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := append([]byte{}, it.Key()[len(ldbChunkPrefix):]...)
+		if !fn(key) {
+			break
+		}
+	}
+
+	return it.Error()
+}
+
+func (ls *ldbChunkStore) Close() error {
+	return ls.lvl.Close()
+}
@@ -18,35 +18,386 @@
  *
  */
 
-
 package storer
 
 import (
+	"fmt"
+	"sync"
 	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/dht"
+	dm		"github.com/yeeco/p2p/dht/dhtmsg"
+)
+
+//
+// Storer manager errno
+//
+const (
+	DhtstMgrEnoNone	= iota
+	DhtstMgrEnoParameter
+	DhtstMgrEnoConfig
+	DhtstMgrEnoDatabase
+	DhtstMgrEnoNotFound
+	DhtstMgrEnoScheduler
+	DhtstMgrEnoUnknown
 )
 
+type DhtstMgrErrno int
+
 //
 // store manager
 //
-const DhtstMgrName = "DhtstMgr"
+const DhtstMgrName = sch.DhtstMgrName
 
 type dhtStorerManager struct {
 	name	string				// name
 	tep		sch.SchUserTaskEp	// entry
+	sdl		*sch.Scheduler		// pointer to scheduler
+	ptnMe	interface{}			// pointer to myself task node
+	lock	sync.RWMutex		// lock for store
+	store	ChunkStore			// chunk store backend
 }
 
-var dhtstMgr = dhtStorerManager{
-	name:	DhtstMgrName,
-	tep:	DhtstMgrProc,
+//
+// Create storer manager, one for each p2p instance
+//
+func NewDhtstMgr() interface{} {
+	return &dhtStorerManager{
+		name:	DhtstMgrName,
+		tep:	DhtstMgrProc,
+		ptnMe:	nil,
+		store:	nil,
+	}
+}
+
+//
+// Get storer manager of a p2p instance by its' scheduler, for functions exported
+// to other dht tasks.
+//
+func dhtstGetManager(sdl *sch.Scheduler) *dhtStorerManager {
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(sdl, DhtstMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+		return nil
+	}
+
+	dhtstMgr, _ := sch.SchinfGetUserDataArea(ptn).(*dhtStorerManager)
+
+	return dhtstMgr
 }
 
 //
 // store manager entry
 //
 func DhtstMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtstMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtstMgr := sch.SchinfGetUserDataArea(ptn).(*dhtStorerManager)
+
+	var eno DhtstMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = dhtstMgr.dhtstMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = dhtstMgr.dhtstMgrPoweroff(ptn)
+
+	case sch.EvDhtStoreReq:
+		eno = dhtstMgr.dhtstMgrStoreReq(msg.Body.(*sch.MsgDhtStoreReq))
+
+	case sch.EvDhtPeerLkStoreReq:
+		eno = dhtstMgr.dhtstMgrPeerStoreReq(msg.Body.(*sch.MsgDhtPeerLkStoreReq))
+
+	default:
+		yclog.LogCallerFileLine("DhtstMgrProc: invalid message: %d", msg.Id)
+		eno = DhtstMgrEnoParameter
+	}
+
+	if eno != DhtstMgrEnoNone {
+		yclog.LogCallerFileLine("DhtstMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweron handler: open the chunk store configured
+//
+func (dhtstMgr *dhtStorerManager) dhtstMgrPoweron(ptn interface{}) DhtstMgrErrno {
+
+	dhtstMgr.sdl = sch.SchinfGetScheduler(ptn)
+	dhtstMgr.ptnMe = ptn
+
+	cfg := ycfg.P2pConfig4DhtStorer(sch.SchinfGetP2pConfig(dhtstMgr.sdl))
+	if cfg == nil {
+		yclog.LogCallerFileLine("dhtstMgrPoweron: P2pConfig4DhtStorer failed")
+		return DhtstMgrEnoConfig
+	}
+
+	var store ChunkStore
+	var err error
+
+	switch cfg.Backend {
+
+	case ChunkStoreMemory:
+		store = NewMemChunkStore()
+
+	case ChunkStoreLevelDb:
+		if store, err = NewLdbChunkStore(cfg.Path); err != nil {
+
+			yclog.LogCallerFileLine("dhtstMgrPoweron: " +
+				"NewLdbChunkStore failed, path: %s, err: %s",
+				cfg.Path, err.Error())
+
+			return DhtstMgrEnoDatabase
+		}
+
+	default:
+		yclog.LogCallerFileLine("dhtstMgrPoweron: " +
+			"invalid backend: %s",
+			cfg.Backend)
+		return DhtstMgrEnoConfig
+	}
+
+	dhtstMgr.lock.Lock()
+	dhtstMgr.store = store
+	dhtstMgr.lock.Unlock()
+
+	yclog.LogCallerFileLine("dhtstMgrPoweron: " +
+		"chunk store opened, backend: %s",
+		cfg.Backend)
+
+	return DhtstMgrEnoNone
+}
+
+//
+// Poweroff handler: close the chunk store
+//
+func (dhtstMgr *dhtStorerManager) dhtstMgrPoweroff(ptn interface{}) DhtstMgrErrno {
+
+	dhtstMgr.lock.Lock()
+
+	if dhtstMgr.store != nil {
+		if err := dhtstMgr.store.Close(); err != nil {
+			yclog.LogCallerFileLine("dhtstMgrPoweroff: " +
+				"Close failed, err: %s",
+				err.Error())
+		}
+		dhtstMgr.store = nil
+	}
+
+	dhtstMgr.lock.Unlock()
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtstMgrEnoUnknown
+	}
+
+	return DhtstMgrEnoNone
+}
+
+//
+// Store request handler
+//
+func (dhtstMgr *dhtStorerManager) dhtstMgrStoreReq(req *sch.MsgDhtStoreReq) DhtstMgrErrno {
 
+	var cfm = sch.MsgDhtStoreCfm {
+		Eno:	DhtstMgrEnoNone,
+		Key:	req.Key,
+		Id:		req.Id,
+	}
+
+	if eno := DhtstPutChunk(dhtstMgr.sdl, req.Key, req.Chunk); eno != DhtstMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtstMgrStoreReq: " +
+			"DhtstPutChunk failed, eno: %d, key: %s",
+			eno, fmt.Sprintf("%X", req.Key))
+
+		cfm.Eno = int(eno)
+	}
+
+	//
+	// confirm to the requester always
+	//
+
+	dht.DhtConfirmTo(dhtstMgr.ptnMe, req.Ptn, sch.EvDhtStoreCfm, &cfm)
+
+	return DhtstMgrErrno(cfm.Eno)
+}
+
+//
+// Store request from peer handler: store it and response the result
+//
+func (dhtstMgr *dhtStorerManager) dhtstMgrPeerStoreReq(req *sch.MsgDhtPeerLkStoreReq) DhtstMgrErrno {
+
+	st := req.Store
+
+	if st == nil {
+		yclog.LogCallerFileLine("dhtstMgrPeerStoreReq: invalid request")
+		return DhtstMgrEnoParameter
+	}
+
+	eno := DhtstPutChunk(dhtstMgr.sdl, st.Key, st.Value)
+	if eno != DhtstMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtstMgrPeerStoreReq: " +
+			"DhtstPutChunk failed, eno: %d, key: %s",
+			eno, fmt.Sprintf("%X", st.Key))
+	}
+
+	var msg = dm.DhtMessage {
+		Mid:		dm.MID_DHT_STORERSP,
+		StoreRsp:	&dm.StoreRsp {
+			Id:		st.Id,
+			Key:	st.Key,
+			Eno:	int(eno),
+		},
+	}
+
+	if de := dht.DhtSendMessage(dhtstMgr.sdl, req.From, &msg); de != dht.DhtMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtstMgrPeerStoreReq: " +
+			"DhtSendMessage failed, eno: %d, to: %s",
+			de, fmt.Sprintf("%X", req.From))
+
+		return DhtstMgrEnoUnknown
+	}
+
+	return DhtstMgrEnoNone
+}
+
+//
+// Put a chunk into local store. Notice: this function is exported for other
+// dht tasks to access local store directly, and since the backend is safe
+// for concurrency, it's not necessary to go through the storer task.
+//
+func DhtstPutChunk(sdl *sch.Scheduler, key []byte, chunk []byte) DhtstMgrErrno {
+
+	dhtstMgr := dhtstGetManager(sdl)
+	if dhtstMgr == nil {
+		return DhtstMgrEnoScheduler
+	}
+
+	if len(key) == 0 {
+		yclog.LogCallerFileLine("DhtstPutChunk: empty key")
+		return DhtstMgrEnoParameter
+	}
+
+	dhtstMgr.lock.RLock()
+	defer dhtstMgr.lock.RUnlock()
+
+	if dhtstMgr.store == nil {
+		yclog.LogCallerFileLine("DhtstPutChunk: store not opened")
+		return DhtstMgrEnoDatabase
+	}
+
+	if err := dhtstMgr.store.Put(key, chunk); err != nil {
+
+		yclog.LogCallerFileLine("DhtstPutChunk: " +
+			"Put failed, err: %s",
+			err.Error())
+
+		return DhtstMgrEnoDatabase
+	}
+
+	return DhtstMgrEnoNone
+}
+
+//
+// Get a chunk from local store
+//
+func DhtstGetChunk(sdl *sch.Scheduler, key []byte) ([]byte, DhtstMgrErrno) {
+
+	dhtstMgr := dhtstGetManager(sdl)
+	if dhtstMgr == nil {
+		return nil, DhtstMgrEnoScheduler
+	}
+
+	dhtstMgr.lock.RLock()
+	defer dhtstMgr.lock.RUnlock()
+
+	if dhtstMgr.store == nil {
+		yclog.LogCallerFileLine("DhtstGetChunk: store not opened")
+		return nil, DhtstMgrEnoDatabase
+	}
+
+	chunk, err := dhtstMgr.store.Get(key)
+
+	if err == ErrChunkNotFound {
+		return nil, DhtstMgrEnoNotFound
+	} else if err != nil {
+
+		yclog.LogCallerFileLine("DhtstGetChunk: " +
+			"Get failed, err: %s",
+			err.Error())
+
+		return nil, DhtstMgrEnoDatabase
+	}
+
+	return chunk, DhtstMgrEnoNone
+}
+
+//
+// Delete a chunk from local store
+//
+func DhtstDelChunk(sdl *sch.Scheduler, key []byte) DhtstMgrErrno {
+
+	dhtstMgr := dhtstGetManager(sdl)
+	if dhtstMgr == nil {
+		return DhtstMgrEnoScheduler
+	}
+
+	dhtstMgr.lock.RLock()
+	defer dhtstMgr.lock.RUnlock()
+
+	if dhtstMgr.store == nil {
+		yclog.LogCallerFileLine("DhtstDelChunk: store not opened")
+		return DhtstMgrEnoDatabase
+	}
+
+	if err := dhtstMgr.store.Delete(key); err != nil {
+
+		yclog.LogCallerFileLine("DhtstDelChunk: " +
+			"Delete failed, err: %s",
+			err.Error())
+
+		return DhtstMgrEnoDatabase
+	}
+
+	return DhtstMgrEnoNone
+}
+
+//
+// Walk keys of local store
+//
+func DhtstForEachKey(sdl *sch.Scheduler, fn func(key []byte) bool) DhtstMgrErrno {
+
+	dhtstMgr := dhtstGetManager(sdl)
+	if dhtstMgr == nil {
+		return DhtstMgrEnoScheduler
+	}
+
+	dhtstMgr.lock.RLock()
+	store := dhtstMgr.store
+	dhtstMgr.lock.RUnlock()
+
+	if store == nil {
+		yclog.LogCallerFileLine("DhtstForEachKey: store not opened")
+		return DhtstMgrEnoDatabase
+	}
+
+	if err := store.ForEach(fn); err != nil {
+
+		yclog.LogCallerFileLine("DhtstForEachKey: " +
+			"ForEach failed, err: %s",
+			err.Error())
+
+		return DhtstMgrEnoDatabase
+	}
+
+	return DhtstMgrEnoNone
+}
@@ -21,31 +21,540 @@
 package syncer
 
 import (
+	"fmt"
+	"time"
 	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/peer"
+			"github.com/yeeco/p2p/dht"
+	dm		"github.com/yeeco/p2p/dht/dhtmsg"
+	dhtst	"github.com/yeeco/p2p/dht/storer"
+	dhtr	"github.com/yeeco/p2p/dht/route"
 )
 
+//
+// errno
+//
+const (
+	DhtsyMgrEnoNone	= iota
+	DhtsyMgrEnoParameter
+	DhtsyMgrEnoScheduler
+	DhtsyMgrEnoConfig
+	DhtsyMgrEnoDatabase
+	DhtsyMgrEnoMessage
+	DhtsyMgrEnoUnknown
+)
+
+type DhtsyMgrErrno int
+
+//
+// The syncer keeps each chunk in local store replicated on the "replicas"
+// nodes closest to its' key, the local node counted. It walks the local
+// store periodically, and also shortly after a peer activated or closed,
+// then:
+//
+// 1) if local node is one of the closest, pushes the chunk to those closest
+// peers not known holding it yet;
+//
+// 2) if local node is not one of the closest, hands the chunk off: pushes
+// it to the closest peers, and deletes it from local store after all of
+// them confirmed.
+//
+const (
+	sySyncCycle			= time.Minute * 10		// cycle to walk local store
+	syKickDelay			= time.Second * 5		// delay to walk after peers changed
+	syStoreTimeout		= time.Second * 30		// timeout for a store pushed
+	syMaxInflight		= peer.PeInstMaxP2packages / 2	// max stores pending on a peer
+)
+
+//
+// State of a replica on a peer
+//
+const (
+	syStatePending	= iota		// store pushed, waiting response
+	syStateHeld					// peer confirmed holding it
+)
+
+//
+// Store pushed to a peer
+//
+type syPending struct {
+	key			[]byte			// key of chunk
+	peer		ycfg.NodeID		// peer pushed to
+	sent		time.Time		// time the store pushed
+	deadline	time.Time		// time to wait response till
+}
+
 //
 // sync manager
 //
-const DhtsyMgrName = "DhtsyMgr"
+const DhtsyMgrName = sch.DhtsyMgrName
 
 type dhtSyncerManager struct {
-	name	string				// name
-	tep		sch.SchUserTaskEp	// entry
+	name		string									// name
+	tep			sch.SchUserTaskEp						// entry
+	sdl			*sch.Scheduler							// pointer to scheduler
+	ptnMe		interface{}								// pointer to myself task node
+	local		ycfg.NodeID								// local node identity
+	replicas	int										// replication factor
+	tidSync		int										// sync timer identity
+	tidKick		int										// kick timer identity
+	seq			uint64									// sequence for message identities
+	more		bool									// last walk left something undone
+	replicaTab	map[string]map[ycfg.NodeID]int			// replica states by key
+	pendings	map[uint64]*syPending					// stores pushed to peers
+	inflight	map[ycfg.NodeID]int						// number of stores pending by peer
 }
 
-var dhtsyMgr = dhtSyncerManager{
-	name:	DhtsyMgrName,
-	tep:	DhtsyMgrProc,
+//
+// Create syncer manager, one for each p2p instance
+//
+func NewDhtsyMgr() interface{} {
+	return &dhtSyncerManager{
+		name:		DhtsyMgrName,
+		tep:		DhtsyMgrProc,
+		ptnMe:		nil,
+		replicas:	0,
+		tidSync:	sch.SchInvalidTid,
+		tidKick:	sch.SchInvalidTid,
+		seq:		0,
+		more:		false,
+		replicaTab:	map[string]map[ycfg.NodeID]int{},
+		pendings:	map[uint64]*syPending{},
+		inflight:	map[ycfg.NodeID]int{},
+	}
 }
 
 //
 // sync manager entry
 //
 func DhtsyMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
 	yclog.LogCallerFileLine("DhtsyMgrProc: scheduled, msg: %d", msg.Id)
+
+	dhtsyMgr := sch.SchinfGetUserDataArea(ptn).(*dhtSyncerManager)
+
+	var eno DhtsyMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = dhtsyMgr.dhtsyMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = dhtsyMgr.dhtsyMgrPoweroff(ptn)
+
+	case sch.EvDhtsySyncTimer:
+		eno = dhtsyMgr.dhtsySync()
+
+	case sch.EvDhtsyKickTimer:
+		dhtsyMgr.tidKick = sch.SchInvalidTid
+		eno = dhtsyMgr.dhtsySync()
+
+	case sch.EvDhtMgrPeerInd:
+		eno = dhtsyMgr.dhtsyMgrPeerInd(msg.Body.(*sch.MsgDhtPeerInd))
+
+	case sch.EvDhtPeerLkStoreRsp:
+		eno = dhtsyMgr.dhtsyMgrPeerStoreRsp(msg.Body.(*sch.MsgDhtPeerLkStoreRsp))
+
+	default:
+		yclog.LogCallerFileLine("DhtsyMgrProc: invalid message: %d", msg.Id)
+		eno = DhtsyMgrEnoParameter
+	}
+
+	if eno != DhtsyMgrEnoNone {
+		yclog.LogCallerFileLine("DhtsyMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
 	return sch.SchEnoNone
 }
 
+//
+// Poweron handler
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyMgrPoweron(ptn interface{}) DhtsyMgrErrno {
+
+	dhtsyMgr.sdl = sch.SchinfGetScheduler(ptn)
+	dhtsyMgr.ptnMe = ptn
+
+	cfg := ycfg.P2pConfig4DhtSyncer(sch.SchinfGetP2pConfig(dhtsyMgr.sdl))
+	if cfg == nil || cfg.Replicas <= 0 {
+		yclog.LogCallerFileLine("dhtsyMgrPoweron: invalid configuration")
+		return DhtsyMgrEnoConfig
+	}
+
+	dhtsyMgr.local = cfg.Local
+	dhtsyMgr.replicas = cfg.Replicas
+
+	var td = sch.TimerDescription {
+		Name:	DhtsyMgrName + "_sync",
+		Utid:	sch.DhtsySyncTimerId,
+		Tmt:	sch.SchTmTypePeriod,
+		Dur:	sySyncCycle,
+		Extra:	nil,
+	}
+
+	eno, tid := sch.SchInfSetTimer(ptn, &td)
+	if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+		yclog.LogCallerFileLine("dhtsyMgrPoweron: " +
+			"SchInfSetTimer failed, eno: %d, timer: %s",
+			eno, td.Name)
+
+		return DhtsyMgrEnoScheduler
+	}
+
+	dhtsyMgr.tidSync = tid
+
+	return DhtsyMgrEnoNone
+}
+
+//
+// Poweroff handler
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyMgrPoweroff(ptn interface{}) DhtsyMgrErrno {
+
+	for _, tid := range []*int{&dhtsyMgr.tidSync, &dhtsyMgr.tidKick} {
+		if *tid != sch.SchInvalidTid {
+			sch.SchinfKillTimer(ptn, *tid)
+			*tid = sch.SchInvalidTid
+		}
+	}
+
+	dhtsyMgr.more = false
+	dhtsyMgr.replicaTab = map[string]map[ycfg.NodeID]int{}
+	dhtsyMgr.pendings = map[uint64]*syPending{}
+	dhtsyMgr.inflight = map[ycfg.NodeID]int{}
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return DhtsyMgrEnoUnknown
+	}
+
+	return DhtsyMgrEnoNone
+}
+
+//
+// Peer activated or closed indication handler. For a closed peer, what it
+// holds are forgotten, since it might not come back. In both cases, a walk
+// is scheduled a little later, so a burst of indications would not cause
+// a burst of walks.
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyMgrPeerInd(ind *sch.MsgDhtPeerInd) DhtsyMgrErrno {
+
+	if ind.Ind == peer.P2pIndPeerClosed {
+
+		for qid, pd := range dhtsyMgr.pendings {
+			if pd.peer == ind.Node.ID {
+				dhtsyMgr.dhtsyPendingDone(qid, pd)
+			}
+		}
+
+		for key, states := range dhtsyMgr.replicaTab {
+			if delete(states, ind.Node.ID); len(states) == 0 {
+				delete(dhtsyMgr.replicaTab, key)
+			}
+		}
+	}
+
+	return dhtsyMgr.dhtsyKick()
+}
+
+//
+// Store response from peer handler
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyMgrPeerStoreRsp(rsp *sch.MsgDhtPeerLkStoreRsp) DhtsyMgrErrno {
+
+	sr := rsp.StoreRsp
+
+	if sr == nil {
+		yclog.LogCallerFileLine("dhtsyMgrPeerStoreRsp: invalid response")
+		return DhtsyMgrEnoParameter
+	}
+
+	pd, ok := dhtsyMgr.pendings[sr.Id]
+	if !ok || pd.peer != rsp.From {
+
+		yclog.LogCallerFileLine("dhtsyMgrPeerStoreRsp: " +
+			"not pending, id: %d, from: %s",
+			sr.Id, fmt.Sprintf("%X", rsp.From))
+
+		return DhtsyMgrEnoNone
+	}
+
+	dhtsyMgr.dhtsyPendingDone(sr.Id, pd)
+	dhtr.DhtrReportLatency(dhtsyMgr.sdl, pd.peer, time.Since(pd.sent))
+
+	if sr.Eno == 0 {
+
+		if states, ok := dhtsyMgr.replicaTab[string(pd.key)]; ok {
+			states[pd.peer] = syStateHeld
+		}
+
+	} else {
+
+		yclog.LogCallerFileLine("dhtsyMgrPeerStoreRsp: " +
+			"store failed, eno: %d, from: %s, key: %s",
+			sr.Eno, fmt.Sprintf("%X", rsp.From), fmt.Sprintf("%X", pd.key))
+	}
+
+	//
+	// continue the work left by last walk when all pushed are done
+	//
+
+	if len(dhtsyMgr.pendings) == 0 && dhtsyMgr.more {
+		return dhtsyMgr.dhtsyKick()
+	}
+
+	return DhtsyMgrEnoNone
+}
+
+//
+// Schedule a walk later, nothing done if one scheduled already
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyKick() DhtsyMgrErrno {
+
+	if dhtsyMgr.tidKick != sch.SchInvalidTid {
+		return DhtsyMgrEnoNone
+	}
+
+	var td = sch.TimerDescription {
+		Name:	DhtsyMgrName + "_kick",
+		Utid:	sch.DhtsyKickTimerId,
+		Tmt:	sch.SchTmTypeAbsolute,
+		Dur:	syKickDelay,
+		Extra:	nil,
+	}
+
+	eno, tid := sch.SchInfSetTimer(dhtsyMgr.ptnMe, &td)
+	if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+		yclog.LogCallerFileLine("dhtsyKick: " +
+			"SchInfSetTimer failed, eno: %d",
+			eno)
+
+		return DhtsyMgrEnoScheduler
+	}
+
+	dhtsyMgr.tidKick = tid
+
+	return DhtsyMgrEnoNone
+}
+
+//
+// Walk the local store to check replicas of each chunk
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsySync() DhtsyMgrErrno {
+
+	dhtsyMgr.dhtsyExpirePendings()
+
+	var handoff = make([][]byte, 0)
+	var walked = make(map[string]bool)
+
+	dhtsyMgr.more = false
+
+	eno := dhtst.DhtstForEachKey(dhtsyMgr.sdl, func(key []byte) bool {
+
+		walked[string(key)] = true
+
+		if dhtsyMgr.dhtsySyncKey(key) {
+			handoff = append(handoff, key)
+		}
+
+		return true
+	})
+
+	if eno != dhtst.DhtstMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtsySync: " +
+			"DhtstForEachKey failed, eno: %d",
+			eno)
+
+		return DhtsyMgrEnoDatabase
+	}
+
+	for _, key := range handoff {
+
+		if eno := dhtst.DhtstDelChunk(dhtsyMgr.sdl, key); eno != dhtst.DhtstMgrEnoNone {
+
+			yclog.LogCallerFileLine("dhtsySync: " +
+				"DhtstDelChunk failed, eno: %d, key: %s",
+				eno, fmt.Sprintf("%X", key))
+
+			continue
+		}
+
+		delete(dhtsyMgr.replicaTab, string(key))
+	}
+
+	//
+	// forget those keys not in local store any more
+	//
+
+	for key := range dhtsyMgr.replicaTab {
+		if !walked[key] {
+			delete(dhtsyMgr.replicaTab, key)
+		}
+	}
+
+	return DhtsyMgrEnoNone
+}
+
+//
+// Check replicas of a chunk and push it to those closest peers not holding
+// it. Returns true if the local node is not responsible for it any more and
+// all the closest peers confirmed holding it, so it can be deleted.
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsySyncKey(key []byte) bool {
+
+	target := dht.DhtKey2Hash(key)
+	closest := dhtr.DhtrClosestPeers(dhtsyMgr.sdl, target, &dhtsyMgr.local, dhtsyMgr.replicas)
+
+	//
+	// check if local node is one of the closest, the farthest peer is not
+	// needed then.
+	//
+
+	responsible := true
+
+	if len(closest) >= dhtsyMgr.replicas {
+
+		farthest := dht.DhtNodeId2Hash(closest[len(closest)-1].ID)
+		responsible = dht.DhtDistCmp(target, dht.DhtNodeId2Hash(dhtsyMgr.local), farthest) < 0
+
+		if responsible {
+			closest = closest[:len(closest)-1]
+		}
+	}
+
+	if len(closest) == 0 {
+		return false
+	}
+
+	states, ok := dhtsyMgr.replicaTab[string(key)]
+	if !ok {
+		states = make(map[ycfg.NodeID]int)
+		dhtsyMgr.replicaTab[string(key)] = states
+	}
+
+	var chunk []byte
+	var held = 0
+
+	for _, n := range closest {
+
+		if state, ok := states[n.ID]; ok {
+			if state == syStateHeld {
+				held++
+			}
+			continue
+		}
+
+		if dhtsyMgr.inflight[n.ID] >= syMaxInflight {
+			dhtsyMgr.more = true
+			continue
+		}
+
+		if chunk == nil {
+
+			var eno dhtst.DhtstMgrErrno
+
+			if chunk, eno = dhtst.DhtstGetChunk(dhtsyMgr.sdl, key); eno != dhtst.DhtstMgrEnoNone {
+
+				yclog.LogCallerFileLine("dhtsySyncKey: " +
+					"DhtstGetChunk failed, eno: %d, key: %s",
+					eno, fmt.Sprintf("%X", key))
+
+				return false
+			}
+		}
+
+		dhtsyMgr.dhtsyPush(key, chunk, n.ID)
+	}
+
+	if !responsible && held < len(closest) {
+		dhtsyMgr.more = true
+	}
+
+	return !responsible && held == len(closest)
+}
+
+//
+// Push a chunk to peer
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyPush(key []byte, chunk []byte, to ycfg.NodeID) {
+
+	dhtsyMgr.seq++
+
+	var msg = dm.DhtMessage {
+		Mid:	dm.MID_DHT_STORE,
+		Store:	&dm.Store {
+			Id:		dhtsyMgr.seq,
+			Key:	key,
+			Value:	chunk,
+		},
+	}
+
+	if eno := dht.DhtSendMessage(dhtsyMgr.sdl, to, &msg); eno != dht.DhtMgrEnoNone {
+
+		yclog.LogCallerFileLine("dhtsyPush: " +
+			"DhtSendMessage failed, eno: %d, to: %s",
+			eno, fmt.Sprintf("%X", to))
+
+		dhtsyMgr.more = true
 
+		return
+	}
+
+	now := time.Now()
+
+	dhtsyMgr.pendings[dhtsyMgr.seq] = &syPending {
+		key:		key,
+		peer:		to,
+		sent:		now,
+		deadline:	now.Add(syStoreTimeout),
+	}
+
+	dhtsyMgr.replicaTab[string(key)][to] = syStatePending
+	dhtsyMgr.inflight[to]++
+}
+
+//
+// Remove a pending store, and the replica state if it's still pending
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyPendingDone(qid uint64, pd *syPending) {
+
+	delete(dhtsyMgr.pendings, qid)
+
+	if dhtsyMgr.inflight[pd.peer]--; dhtsyMgr.inflight[pd.peer] <= 0 {
+		delete(dhtsyMgr.inflight, pd.peer)
+	}
+
+	if states, ok := dhtsyMgr.replicaTab[string(pd.key)]; ok {
+		if state, ok := states[pd.peer]; ok && state == syStatePending {
+			delete(states, pd.peer)
+		}
+	}
+}
+
+//
+// Remove pending stores timeout, they would be pushed again by the walk
+//
+func (dhtsyMgr *dhtSyncerManager) dhtsyExpirePendings() {
+
+	now := time.Now()
+
+	for qid, pd := range dhtsyMgr.pendings {
+
+		if now.Before(pd.deadline) {
+			continue
+		}
+
+		yclog.LogCallerFileLine("dhtsyExpirePendings: " +
+			"store timeout, qid: %d, peer: %s",
+			qid, fmt.Sprintf("%X", pd.peer))
+
+		dhtsyMgr.dhtsyPendingDone(qid, pd)
+		dhtr.DhtrReportFailure(dhtsyMgr.sdl, pd.peer)
+	}
+}
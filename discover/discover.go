@@ -55,20 +55,19 @@ type discoverManager struct {
 	more		int					// number more peers are needed
 }
 
-var dcvMgr = discoverManager {}
-
 //
-// Init, we put more in poweron event handler. We can't init the entry point filed
-// "tep" with dcvMgr declartion which would result in complier "initialization loop"
-// error.
+// Create discover manager, we put more in poweron event handler. The manager
+// is the user data area of the static task DcvMgrName of a p2p instance.
 //
-func init() {
-	dcvMgr.name		= DcvMgrName
-	dcvMgr.tep		= DcvMgrProc
-	dcvMgr.ptnMe	= nil
-	dcvMgr.ptnTab	= nil
-	dcvMgr.ptnPeMgr = nil
-	dcvMgr.more		= ycfg.MaxOutbounds
+func NewDcvMgr() interface{} {
+	return &discoverManager {
+		name:		DcvMgrName,
+		tep:		DcvMgrProc,
+		ptnMe:		nil,
+		ptnTab:		nil,
+		ptnPeMgr:	nil,
+		more:		ycfg.MaxOutbounds,
+	}
 }
 
 //
@@ -81,20 +80,21 @@ func DcvMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 		sch.SchinfGetMessageSender(msg), sch.SchinfGetMessageRecver(msg), msg.Id)
 
 	var eno DcvMgrErrno = DcvMgrEnoNone
+	var dcvMgr = sch.SchinfGetUserDataArea(ptn).(*discoverManager)
 
 	switch msg.Id {
 
 	case sch.EvSchPoweron:
-		eno = DcvMgrPoweron(ptn)
+		eno = dcvMgr.DcvMgrPoweron(ptn)
 
 	case sch.EvSchPoweroff:
-		eno = DcvMgrPoweroff(ptn)
+		eno = dcvMgr.DcvMgrPoweroff(ptn)
 
 	case sch.EvDcvFindNodeReq:
-		eno = DcvMgrFindNodeReq(msg.Body.(*sch.MsgDcvFindNodeReq))
+		eno = dcvMgr.DcvMgrFindNodeReq(msg.Body.(*sch.MsgDcvFindNodeReq))
 
 	case sch.EvTabRefreshRsp:
-		eno = DcvMgrTabRefreshRsp(msg.Body.(*sch.MsgTabRefreshRsp))
+		eno = dcvMgr.DcvMgrTabRefreshRsp(msg.Body.(*sch.MsgTabRefreshRsp))
 
 	default:
 		yclog.LogCallerFileLine("DcvMgrProc: invalid message: %d", msg.Id)
@@ -112,18 +112,19 @@ func DcvMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 //
 // Poweron handler
 //
-func DcvMgrPoweron(ptn interface{}) DcvMgrErrno {
+func (dcvMgr *discoverManager) DcvMgrPoweron(ptn interface{}) DcvMgrErrno {
 
 	var eno sch.SchErrno
+	var sdl = sch.SchinfGetScheduler(ptn)
 
 	dcvMgr.ptnMe = ptn
 
-	if eno, dcvMgr.ptnTab = sch.SchinfGetTaskNodeByName(sch.TabMgrName); eno != sch.SchEnoNone {
+	if eno, dcvMgr.ptnTab = sch.SchinfGetTaskNodeByName(sdl, sch.TabMgrName); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("DcvMgrPoweron: get task node failed, task: %s", sch.TabMgrName)
 		return DcvMgrEnoScheduler
 	}
 
-	if eno, dcvMgr.ptnPeMgr = sch.SchinfGetTaskNodeByName(sch.PeerMgrName); eno != sch.SchEnoNone {
+	if eno, dcvMgr.ptnPeMgr = sch.SchinfGetTaskNodeByName(sdl, sch.PeerMgrName); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("DcvMgrPoweron: get task node failed, task: %s", sch.PeerMgrName)
 		return DcvMgrEnoScheduler
 	}
@@ -140,7 +141,7 @@ func DcvMgrPoweron(ptn interface{}) DcvMgrErrno {
 //
 // Poweroff handler
 //
-func DcvMgrPoweroff(ptn interface{}) DcvMgrErrno {
+func (dcvMgr *discoverManager) DcvMgrPoweroff(ptn interface{}) DcvMgrErrno {
 
 	if eno := sch.SchinfTaskDone(ptn, sch.SchEnoKilled); eno != sch.SchEnoNone {
 
@@ -156,7 +157,7 @@ func DcvMgrPoweroff(ptn interface{}) DcvMgrErrno {
 //
 // FindNode request handler
 //
-func DcvMgrFindNodeReq(req *sch.MsgDcvFindNodeReq) DcvMgrErrno {
+func (dcvMgr *discoverManager) DcvMgrFindNodeReq(req *sch.MsgDcvFindNodeReq) DcvMgrErrno {
 
 	//
 	// When peer manager task considers that more peers needed, it then send FindNode
@@ -212,7 +213,7 @@ func DcvMgrFindNodeReq(req *sch.MsgDcvFindNodeReq) DcvMgrErrno {
 //
 // Table refreshed response handler
 //
-func DcvMgrTabRefreshRsp(rsp *sch.MsgTabRefreshRsp) DcvMgrErrno {
+func (dcvMgr *discoverManager) DcvMgrTabRefreshRsp(rsp *sch.MsgTabRefreshRsp) DcvMgrErrno {
 
 	//
 	// We receive the response about event sch.EvTabRefreshReq we hand sent to table
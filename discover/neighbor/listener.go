@@ -24,12 +24,14 @@ import (
 	"os"
 	"syscall"
 	"net"
-	"fmt"
+	"strconv"
 	"time"
+	"crypto/ecdsa"
 	sch		"github.com/yeeco/p2p/scheduler"
 	cfg		"github.com/yeeco/p2p/config"
 	umsg	"github.com/yeeco/p2p/discover/udpmsg"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/acl"
 )
 
 //
@@ -38,21 +40,25 @@ import (
 const LsnMgrName = sch.NgbLsnName
 
 type listenerConfig struct {
-	IP	net.IP		// IP
-	UDP	uint16		// UDP port number
-	TCP	uint16		// TCP port number
-	ID	cfg.NodeID	// node identity: the public key
+	IP			net.IP				// IP
+	UDP			uint16				// UDP port number
+	TCP			uint16				// TCP port number
+	ID			cfg.NodeID			// node identity: the public key
+	PrivateKey	*ecdsa.PrivateKey	// key for signing udp messages
+	Network		[]byte				// tag of network, mixed into digests signed
 }
 
 type listenerManager struct {
 	name		string				// name
 	tep			sch.SchUserTaskEp	// entry
+	sdl			*sch.Scheduler		// scheduler of the p2p instance
 	cfg			listenerConfig		// configuration
 	conn		*net.UDPConn		// udp connection
 	addr		net.UDPAddr			// real udp address
 	state		int					// state
 	ptnMe		interface{}			// pointer to myself task
 	ptnReader	interface{}			// pointer to udp reader task
+	udpReader	*udpReaderTask		// udp reader
 }
 
 //
@@ -65,22 +71,24 @@ const (
 	LmsStopped				// stopped, configurations are still validate
 )
 
-var lsnMgr = listenerManager{
-	name:		LsnMgrName,
-	tep:		nil,
-	conn:		nil,
-	state:		LmsNull,
-	ptnMe:		nil,
-	ptnReader:	nil,
-}
-
 //
-// To escape the compiler "initialization loop" error
+// Create listener manager, it's the user data area of the static task
+// LsnMgrName of a p2p instance.
 //
-func init() {
-	lsnMgr.tep			= LsnMgrProc
-	udpReader.tep		= udpReaderLoop
-	udpReader.desc.Ep	= udpReaderLoop
+func NewLsnMgr() interface{} {
+
+	var lsnMgr = listenerManager{
+		name:		LsnMgrName,
+		tep:		LsnMgrProc,
+		conn:		nil,
+		state:		LmsNull,
+		ptnMe:		nil,
+		ptnReader:	nil,
+	}
+
+	lsnMgr.udpReader = newUdpReader(&lsnMgr)
+
+	return &lsnMgr
 }
 
 //
@@ -93,11 +101,12 @@ func LsnMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 		sch.SchinfGetMessageSender(msg), sch.SchinfGetMessageRecver(msg), msg.Id)
 
 	var eno = sch.SchEnoUnknown
+	var lsnMgr = sch.SchinfGetUserDataArea(ptn).(*listenerManager)
 
 	switch msg.Id {
 
 	case sch.EvSchPoweron:
-		eno = lsnMgr.procPoweron()
+		eno = lsnMgr.procPoweron(ptn)
 
 	case sch.EvSchPoweroff:
 		eno = lsnMgr.procPoweroff()
@@ -123,15 +132,34 @@ func (mgr *listenerManager) setupConfig() sch.SchErrno {
 
 	var ptCfg *cfg.Cfg4UdpListener = nil
 
-	if ptCfg = cfg.P2pConfig4UdpListener(); ptCfg == nil {
+	if ptCfg = cfg.P2pConfig4UdpListener(sch.SchinfGetP2pConfig(mgr.sdl)); ptCfg == nil {
 		yclog.LogCallerFileLine("setupConfig: P2pConfig4UdpListener failed")
 		return sch.SchEnoConfig
 	}
 
-	lsnMgr.cfg.IP	= ptCfg.IP
-	lsnMgr.cfg.UDP	= ptCfg.UDP
-	lsnMgr.cfg.TCP	= ptCfg.TCP
-	lsnMgr.cfg.ID	= ptCfg.ID
+	mgr.cfg.IP	= ptCfg.IP
+	mgr.cfg.UDP	= ptCfg.UDP
+	mgr.cfg.TCP	= ptCfg.TCP
+	mgr.cfg.ID	= ptCfg.ID
+
+	//
+	// all udp messages sent are signed with the node key, see udpmsg.go
+	//
+
+	if ptCfg.PrivateKey == nil {
+		yclog.LogCallerFileLine("setupConfig: private key not configured")
+		return sch.SchEnoConfig
+	}
+
+	mgr.cfg.PrivateKey = ptCfg.PrivateKey
+	mgr.cfg.Network = ptCfg.Network
+
+	//
+	// the decoder of reader checks messages against the network tag, which
+	// is known only now.
+	//
+
+	mgr.udpReader.pum = umsg.NewUdpMsg(nil, mgr.cfg.Network)
 
 	return sch.SchEnoNone
 }
@@ -145,7 +173,7 @@ func (mgr *listenerManager)setupUdpConn() sch.SchErrno {
 	var realAddr	*net.UDPAddr = nil
 
 	// setup udp address
-	strAddr := fmt.Sprintf("%s:%d", lsnMgr.cfg.IP.String(), lsnMgr.cfg.UDP)
+	strAddr := net.JoinHostPort(mgr.cfg.IP.String(), strconv.Itoa(int(mgr.cfg.UDP)))
 	udpAddr, err := net.ResolveUDPAddr("udp", strAddr)
 	if err != nil {
 		yclog.LogCallerFileLine("setupUdpConn: ResolveUDPAddr failed, err: %s", err.Error())
@@ -226,8 +254,8 @@ func (mgr *listenerManager) start() sch.SchErrno {
 // Transfer to next state
 //
 func (mgr *listenerManager) nextState(s int) sch.SchErrno {
-	yclog.LogCallerFileLine("nextState: transfer from %d to %d", lsnMgr.state, s)
-	lsnMgr.state = s
+	yclog.LogCallerFileLine("nextState: transfer from %d to %d", mgr.state, s)
+	mgr.state = s
 	return sch.SchEnoNone
 }
 
@@ -262,7 +290,7 @@ func (mgr *listenerManager) canStop() sch.SchErrno {
 //
 // Poweron event handler
 //
-func (mgr *listenerManager) procPoweron() sch.SchErrno {
+func (mgr *listenerManager) procPoweron(ptn interface{}) sch.SchErrno {
 
 	var eno sch.SchErrno
 
@@ -270,7 +298,9 @@ func (mgr *listenerManager) procPoweron() sch.SchErrno {
 	// get pointer to myself
 	//
 
-	if eno, mgr.ptnMe = sch.SchinfGetTaskNodeByName(mgr.name); eno != sch.SchEnoNone || mgr.ptnMe == nil {
+	mgr.sdl = sch.SchinfGetScheduler(ptn)
+
+	if eno, mgr.ptnMe = sch.SchinfGetTaskNodeByName(mgr.sdl, mgr.name); eno != sch.SchEnoNone || mgr.ptnMe == nil {
 		if eno == sch.SchEnoNone {
 			yclog.LogCallerFileLine("procPoweron: internal errors, eno mismatched")
 			eno = sch.SchEnoInternal
@@ -291,7 +321,7 @@ func (mgr *listenerManager) procPoweron() sch.SchErrno {
 	// fetch configurations
 	//
 
-	if eno = lsnMgr.setupConfig(); eno != sch.SchEnoNone {
+	if eno = mgr.setupConfig(); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("procPoweron：setupConfig failed, eno: %d", eno)
 		return eno
 	}
@@ -306,7 +336,7 @@ func (mgr *listenerManager) procPoweron() sch.SchErrno {
 	// start listening(reading on udp)
 	//
 
-	if eno = lsnMgr.start(); eno != sch.SchEnoNone {
+	if eno = mgr.start(); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("procPoweron：start failed, eno: %d", eno)
 		return eno
 	}
@@ -354,7 +384,7 @@ func (mgr *listenerManager) procStart() sch.SchErrno {
 	// setup connection
 	//
 
-	if eno = lsnMgr.setupUdpConn(); eno != sch.SchEnoNone {
+	if eno = mgr.setupUdpConn(); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("procStart：setupUdpConn failed, eno: %d", eno)
 		return eno
 	}
@@ -363,8 +393,8 @@ func (mgr *listenerManager) procStart() sch.SchErrno {
 	// create the reader task
 	//
 
-	udpReader.conn = mgr.conn
-	eno, ptnLoop = sch.SchinfCreateTask(&udpReader.desc)
+	mgr.udpReader.conn = mgr.conn
+	eno, ptnLoop = sch.SchinfCreateTask(mgr.sdl, &mgr.udpReader.desc)
 
 	if eno != sch.SchEnoNone || ptnLoop == nil {
 
@@ -417,8 +447,9 @@ func (mgr *listenerManager) procStop() sch.SchErrno {
 	// it's is closed by the manager.
 	//
 
+	conn := mgr.conn
 	mgr.conn = nil
-	mgr.conn.Close()
+	conn.Close()
 
 	//
 	// stop task after connection had been closed. notice that the reader
@@ -431,6 +462,8 @@ func (mgr *listenerManager) procStop() sch.SchErrno {
 		return eno
 	}
 
+	mgr.ptnReader = nil
+
 	//
 	// update manager state
 	//
@@ -455,12 +488,19 @@ type udpReaderTask struct {
 	ptnMe		interface{}				// pointer to myself task
 	ptnNgbMgr	interface{}				// pointer to neighbor manager task
 	desc		sch.SchTaskDescription	// description
+	lsnMgr		*listenerManager		// pointer to listener manager owns the reader
+	pum			*umsg.UdpMsg			// decoder for udp messages received
 }
 
-var udpReader  = udpReaderTask {
-	name:	udpReaderName,
-	tep:	nil,
-	conn:	nil,
+func newUdpReader(lsnMgr *listenerManager) *udpReaderTask {
+
+	var udpReader = udpReaderTask {
+		name:	udpReaderName,
+		tep:	udpReaderLoop,
+		conn:	nil,
+		lsnMgr:	lsnMgr,
+		pum:	umsg.NewUdpMsg(nil, nil),
+	}
 
 	//
 	// description: notice that this task would going in a dead loop, so
@@ -468,14 +508,17 @@ var udpReader  = udpReaderTask {
 	// no mailbox needed for it.
 	//
 
-	desc:	sch.SchTaskDescription{
+	udpReader.desc = sch.SchTaskDescription{
 		Name:	udpReaderName,
 		MbSize: 0,
-		Ep:		nil,
+		Ep:		udpReaderLoop,
 		Wd:		&noDog,
 		Flag:	sch.SchCreatedGo,
 		DieCb:	nil,
-	},
+		UserDa:	&udpReader,
+	}
+
+	return &udpReader
 }
 
 
@@ -502,13 +545,15 @@ func udpReaderLoop(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 
 	eno := sch.SchEnoNone
 	buf := make([]byte, udpMaxMsgSize)
+	udpReader := sch.SchinfGetUserDataArea(ptn).(*udpReaderTask)
+	lsnMgr := udpReader.lsnMgr
 
 	//
 	// get related task node pointers
 	//
 
 	udpReader.ptnMe = ptn
-	eno, udpReader.ptnNgbMgr = sch.SchinfGetTaskNodeByName(NgbMgrName)
+	eno, udpReader.ptnNgbMgr = sch.SchinfGetTaskNodeByName(lsnMgr.sdl, NgbMgrName)
 
 	if eno != sch.SchEnoNone {
 
@@ -592,7 +637,7 @@ _loop:
 
 		yclog.LogCallerFileLine("udpReaderLoop: abnormal case, stop the task")
 
-		(&lsnMgr).procStop()
+		lsnMgr.procStop()
 	}
 
 	//
@@ -610,10 +655,15 @@ _loop:
 //
 // Check if an error can be ignored while reading
 //
-func (rd udpReaderTask) canErrIgnored(err error) bool {
+func (rd *udpReaderTask) canErrIgnored(err error) bool {
 	const WSAEMSGSIZE = syscall.Errno(10040)
 	if opErr, ok := err.(*net.OpError); ok {
-		return opErr.Temporary() || opErr.Err.(*os.SyscallError).Err == WSAEMSGSIZE
+		if opErr.Temporary() {
+			return true
+		}
+		if sysErr, ok := opErr.Err.(*os.SyscallError); ok {
+			return sysErr.Err == WSAEMSGSIZE
+		}
 	}
 	return false
 }
@@ -621,7 +671,7 @@ func (rd udpReaderTask) canErrIgnored(err error) bool {
 //
 // Decode message
 //
-func (rd udpReaderTask) msgHandler(pbuf *[]byte, len int, from *net.UDPAddr) sch.SchErrno {
+func (rd *udpReaderTask) msgHandler(pbuf *[]byte, len int, from *net.UDPAddr) sch.SchErrno {
 
 	//
 	// We need not to interprete the message, we jsut decode it and
@@ -633,12 +683,12 @@ func (rd udpReaderTask) msgHandler(pbuf *[]byte, len int, from *net.UDPAddr) sch
 	var msg sch.SchMessage
 	var eno umsg.UdpMsgErrno
 
-	if eno := umsg.PtrUdpMsg.SetRawMessage(pbuf, len, from); eno != umsg.UdpMsgEnoNone {
+	if eno := rd.pum.SetRawMessage(pbuf, len, from); eno != umsg.UdpMsgEnoNone {
 		yclog.LogCallerFileLine("msgHandler: SetRawMessage failed, eno: %d", eno)
 		return sch.SchEnoUserTask
 	}
 
-	if eno = umsg.PtrUdpMsg.Decode(); eno != umsg.UdpMsgEnoNone {
+	if eno = rd.pum.Decode(); eno != umsg.UdpMsgEnoNone {
 		yclog.LogCallerFileLine("msgHandler: Decode failed, eno: %d", eno)
 		return sch.SchEnoUserTask
 	}
@@ -648,16 +698,25 @@ func (rd udpReaderTask) msgHandler(pbuf *[]byte, len int, from *net.UDPAddr) sch
 	//
 
 	var udpMsgInd = UdpMsgInd {
-		msgType:umsg.PtrUdpMsg.GetDecodedMsgType(),
-		msgBody:umsg.PtrUdpMsg.GetDecodedMsg(),
+		msgType:rd.pum.GetDecodedMsgType(),
+		msgBody:rd.pum.GetDecodedMsg(),
 	}
 
 	// check this message agaigst the endpoint sent it
-	if umsg.PtrUdpMsg.CheckUdpMsgFromPeer(from) != true {
+	if rd.pum.CheckUdpMsgFromPeer(from) != true {
 		yclog.LogCallerFileLine("msgHandler: invalid udp message, CheckUdpMsg failed")
 		return sch.SchEnoUserTask
 	}
 
+	// check the sender against the deny and allow lists
+	if id, ok := rd.pum.GetDecodedSenderId(); !ok || !acl.AclCheck(rd.lsnMgr.sdl, id, from.IP) {
+		yclog.LogCallerFileLine("msgHandler: udp message rejected by acl, from: %s", from.String())
+		return sch.SchEnoUserTask
+	}
+
+	// take the address the sender contacted us with, see udpMsgSetFromIP
+	udpMsgSetFromIP(udpMsgInd.msgBody, from.IP)
+
 	schEno = sch.SchinfMakeMessage(&msg, rd.ptnMe, rd.ptnNgbMgr, sch.EvNblMsgInd, &udpMsgInd)
 	if schEno != sch.SchEnoNone {
 
@@ -693,7 +752,9 @@ func (rd udpReaderTask) msgHandler(pbuf *[]byte, len int, from *net.UDPAddr) sch
 //
 // Send message: we might need a singal task to handle the sending later.
 //
-func sendUdpMsg(buf []byte, toAddr *net.UDPAddr) sch.SchErrno {
+func (lsnMgr *listenerManager) sendUdpMsg(buf []byte, toAddr *net.UDPAddr) sch.SchErrno {
+
+	udpReader := lsnMgr.udpReader
 
 	if udpReader.conn == nil {
 		yclog.LogCallerFileLine("sendUdpMsg: invalid UDP connection")
@@ -729,3 +790,30 @@ func sendUdpMsg(buf []byte, toAddr *net.UDPAddr) sch.SchErrno {
 	return sch.SchEnoNone
 }
 
+
+//
+// Set ip address of the sender in message decoded to the one the message comes
+// from. A node might have both IPv4 and IPv6 addresses, or listen on all of
+// them with an unspecified address advertised, while the source address is the
+// one known to work, so responses and the node put into route table should go
+// with it. Notice: the message must had been checked by CheckUdpMsgFromPeer.
+//
+func udpMsgSetFromIP(body interface{}, ip net.IP) {
+
+	ip = cfg.P2pNormalizeIP(ip)
+
+	switch m := body.(type) {
+
+	case *umsg.Ping:
+		m.From.IP = ip
+
+	case *umsg.Pong:
+		m.From.IP = ip
+
+	case *umsg.FindNode:
+		m.From.IP = ip
+
+	case *umsg.Neighbors:
+		m.From.IP = ip
+	}
+}
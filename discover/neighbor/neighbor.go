@@ -67,12 +67,13 @@ const ngbProcMailboxSize = 8
 // The control block of neighbor task instance
 //
 type neighborInst struct {
-	ptn		interface{}		// task node pointer
-	name	string			// task instance name
-	msgType	um.UdpMsgType	// message type to inited this instance
-	msgBody	interface{}		// message body
-	tidFN	int				// FindNode timer identity
-	tidPP	int				// Pingpong timer identity
+	ptn		interface{}			// task node pointer
+	name	string				// task instance name
+	msgType	um.UdpMsgType		// message type to inited this instance
+	msgBody	interface{}			// message body
+	tidFN	int					// FindNode timer identity
+	tidPP	int					// Pingpong timer identity
+	ngbMgr	*neighborManager	// pointer to neighbor manager
 }
 
 //
@@ -174,7 +175,9 @@ func (inst *neighborInst) NgbProtoFindNodeReq(ptn interface{}, fn *um.FindNode)
 	// encode request
 	//
 
-	var pum = new(um.UdpMsg)
+	fn.From.IP = um.UdpMsgFromIP(fn.From.IP, fn.To.IP)
+
+	var pum = um.NewUdpMsg(inst.ngbMgr.lsnMgr.cfg.PrivateKey, inst.ngbMgr.lsnMgr.cfg.Network)
 	if eno := pum.Encode(um.UdpMsgTypeFindNode, fn); eno != um.UdpMsgEnoNone {
 
 		yclog.LogCallerFileLine("NgbProtoFindNodeReq: " +
@@ -201,7 +204,7 @@ func (inst *neighborInst) NgbProtoFindNodeReq(ptn interface{}, fn *um.FindNode)
 	dst.IP = append(dst.IP, fn.To.IP...)
 	dst.Port = int(fn.To.UDP)
 
-	if eno := sendUdpMsg(buf, &dst); eno != sch.SchEnoNone {
+	if eno := inst.ngbMgr.lsnMgr.sendUdpMsg(buf, &dst); eno != sch.SchEnoNone {
 
 		//
 		// response FindNode  NgbProtoEnoUdp to table task
@@ -217,7 +220,7 @@ func (inst *neighborInst) NgbProtoFindNodeReq(ptn interface{}, fn *um.FindNode)
 		rsp.Result = (NgbProtoEnoUdp << 16) + tab.TabMgrEnoUdp
 		rsp.FindNode = inst.msgBody.(*um.FindNode)
 
-		if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, ngbMgr.ptnTab,
+		if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, inst.ngbMgr.ptnTab,
 			sch.EvNblFindNodeRsp, &rsp); eno != sch.SchEnoNone {
 
 			yclog.LogCallerFileLine("NgbProtoFindNodeReq: " +
@@ -240,7 +243,7 @@ func (inst *neighborInst) NgbProtoFindNodeReq(ptn interface{}, fn *um.FindNode)
 
 		yclog.LogCallerFileLine("NgbProtoFindNodeReq: " +
 			"EvNblFindNodeRsp sent ok, target: %s",
-			sch.SchinfGetTaskName(ngbMgr.ptnTab))
+			sch.SchinfGetTaskName(inst.ngbMgr.ptnTab))
 
 		//
 		// remove ourself from map in manager. notice: since we had setup the calback
@@ -248,7 +251,7 @@ func (inst *neighborInst) NgbProtoFindNodeReq(ptn interface{}, fn *um.FindNode)
 		// we do cleaning here to obtain a more clear seen.
 		//
 
-		ngbMgr.cleanMap(inst.name)
+		inst.ngbMgr.cleanMap(inst.name)
 
 		//
 		// done the instance task
@@ -323,7 +326,9 @@ func (inst *neighborInst) NgbProtoPingReq(ptn interface{}, ping *um.Ping) NgbPro
 	// encode request
 	//
 
-	var pum = new(um.UdpMsg)
+	ping.From.IP = um.UdpMsgFromIP(ping.From.IP, ping.To.IP)
+
+	var pum = um.NewUdpMsg(inst.ngbMgr.lsnMgr.cfg.PrivateKey, inst.ngbMgr.lsnMgr.cfg.Network)
 	if eno := pum.Encode(um.UdpMsgTypePing, ping); eno != um.UdpMsgEnoNone {
 
 		yclog.LogCallerFileLine("NgbProtoPingReq: " +
@@ -350,7 +355,7 @@ func (inst *neighborInst) NgbProtoPingReq(ptn interface{}, ping *um.Ping) NgbPro
 	dst.IP = append(dst.IP, ping.To.IP...)
 	dst.Port = int(ping.To.UDP)
 
-	if eno := sendUdpMsg(buf, &dst); eno != sch.SchEnoNone {
+	if eno := inst.ngbMgr.lsnMgr.sendUdpMsg(buf, &dst); eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("NgbProtoPingReq：" +
 			"sendUdpMsg failed, dst: %s, eno: %d",
@@ -458,7 +463,7 @@ func (inst *neighborInst) NgbProtoPingRsp(msg *um.Pong) NgbProtoErrno {
 	rsp.Ping = inst.msgBody.(*um.Ping)
 	rsp.Pong = msg
 
-	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, ngbMgr.ptnTab,
+	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, inst.ngbMgr.ptnTab,
 		sch.EvNblPingpongRsp, &rsp); eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("NgbProtoPingRsp: " +
@@ -489,7 +494,7 @@ func (inst *neighborInst) NgbProtoPingRsp(msg *um.Pong) NgbProtoErrno {
 	// we do cleaning here to obtain a more clear seen.
 	//
 
-	ngbMgr.cleanMap(inst.name)
+	inst.ngbMgr.cleanMap(inst.name)
 
 	//
 	// done the instance task
@@ -572,7 +577,7 @@ func (inst *neighborInst) NgbProtoFindNodeRsp(msg *um.Neighbors) NgbProtoErrno {
 	rsp.FindNode = inst.msgBody.(*um.FindNode)
 	rsp.Neighbors = msg
 
-	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, ngbMgr.ptnTab,
+	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, inst.ngbMgr.ptnTab,
 		sch.EvNblFindNodeRsp, &rsp); eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("NgbProtoFindNodeRsp: " +
@@ -595,7 +600,7 @@ func (inst *neighborInst) NgbProtoFindNodeRsp(msg *um.Neighbors) NgbProtoErrno {
 
 	yclog.LogCallerFileLine("NgbProtoFindNodeRsp: " +
 		"EvNblFindNodeRsp sent ok, target: %s",
-		sch.SchinfGetTaskName(ngbMgr.ptnTab))
+		sch.SchinfGetTaskName(inst.ngbMgr.ptnTab))
 
 	//
 	// remove ourself from map in manager. notice: since we had setup the calback
@@ -603,7 +608,7 @@ func (inst *neighborInst) NgbProtoFindNodeRsp(msg *um.Neighbors) NgbProtoErrno {
 	// we do cleaning here to obtain a more clear seen.
 	//
 
-	ngbMgr.cleanMap(inst.name)
+	inst.ngbMgr.cleanMap(inst.name)
 
 	//
 	// done the instance task
@@ -636,7 +641,7 @@ func (inst *neighborInst) NgbProtoFindNodeTimeout() NgbProtoErrno {
 	rsp.Result = (NgbProtoEnoTimeout << 16) + tab.TabMgrEnoTimeout
 	rsp.FindNode = inst.msgBody.(*um.FindNode)
 
-	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, ngbMgr.ptnTab,
+	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, inst.ngbMgr.ptnTab,
 		sch.EvNblFindNodeRsp, &rsp); eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("NgbProtoFindNodeTimeout: " +
@@ -659,7 +664,7 @@ func (inst *neighborInst) NgbProtoFindNodeTimeout() NgbProtoErrno {
 
 	yclog.LogCallerFileLine("NgbProtoFindNodeTimeout: " +
 		"EvNblFindNodeRsp sent ok, target: %s",
-		sch.SchinfGetTaskName(ngbMgr.ptnTab))
+		sch.SchinfGetTaskName(inst.ngbMgr.ptnTab))
 
 	//
 	// remove ourself from map in manager. notice: since we had setup the calback
@@ -667,7 +672,7 @@ func (inst *neighborInst) NgbProtoFindNodeTimeout() NgbProtoErrno {
 	// we do cleaning here to obtain a more clear seen.
 	//
 
-	ngbMgr.cleanMap(inst.name)
+	inst.ngbMgr.cleanMap(inst.name)
 
 	//
 	// done the instance task
@@ -701,7 +706,7 @@ func (inst *neighborInst) NgbProtoPingTimeout() NgbProtoErrno {
 	rsp.Result = NgbProtoEnoTimeout
 	rsp.Ping = inst.msgBody.(*um.Ping)
 
-	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, ngbMgr.ptnTab,
+	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptn, inst.ngbMgr.ptnTab,
 		sch.EvNblPingpongRsp, &rsp); eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("NgbProtoPingTimeout: " +
@@ -728,7 +733,7 @@ func (inst *neighborInst) NgbProtoPingTimeout() NgbProtoErrno {
 	// we do cleaning here to obtain a more clear seen.
 	//
 
-	ngbMgr.cleanMap(inst.name)
+	inst.ngbMgr.cleanMap(inst.name)
 
 	//
 	// done the instance task
@@ -800,7 +805,7 @@ func (ni *neighborInst) NgbProtoDieCb(ptn interface{}) sch.SchErrno {
 	// clean the map
 	//
 
-	ngbMgr.cleanMap(inst.name)
+	inst.ngbMgr.cleanMap(inst.name)
 
 	//
 	// More ... ?
@@ -828,33 +833,27 @@ type neighborManager struct {
 	lock		sync.Mutex					// lock for protection
 	name		string						// name
 	tep			sch.SchUserTaskEp			// entry
+	sdl			*sch.Scheduler				// scheduler of the p2p instance
 	ptnMe		interface{}					// pointer to task node of myself
 	ptnTab		interface{}					// pointer to task node of table task
+	lsnMgr		*listenerManager			// pointer to udp listener manager
 	ngbMap		map[string]*neighborInst	// map neighbor node id to task node pointer
+	fnInstSeq	int							// sequence for findnode instance task name
+	ppInstSeq	int							// sequence for pingpong instance task name
 }
 
 //
-// It's a static task, only one instance would be
-//
-var ngbMgr = &neighborManager {
-	name:	NgbMgrName,
-	tep:	nil,
-	ptnMe:	nil,
-	ptnTab:	nil,
-	ngbMap:	make(map[string]*neighborInst),
-}
-
-//
-// The scheduler need unique task name, here is sequence for dynamic instance task name
+// Create neighbor manager, it's a static task, only one instance would be in
+// a p2p instance, the manager is the user data area of the task.
 //
-var fnInstSeq = 0	// for findnode instance
-var ppInstSeq = 0	// for pingpong instance
-
-//
-// To escape the compiler "initialization loop" error
-//
-func init() {
-	ngbMgr.tep = NgbMgrProc
+func NewNgbMgr() interface{} {
+	return &neighborManager {
+		name:	NgbMgrName,
+		tep:	NgbMgrProc,
+		ptnMe:	nil,
+		ptnTab:	nil,
+		ngbMap:	make(map[string]*neighborInst),
+	}
 }
 
 //
@@ -881,6 +880,7 @@ func NgbMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 	//
 
 	var eno NgbMgrErrno
+	var ngbMgr = sch.SchinfGetUserDataArea(ptn).(*neighborManager)
 
 	switch msg.Id {
 
@@ -929,7 +929,8 @@ func NgbMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 func (ngbMgr *neighborManager)PoweronHandler(ptn interface{}) sch.SchErrno {
 
 	ngbMgr.ptnMe = ptn
-	eno, ptnTab := sch.SchinfGetTaskNodeByName(sch.TabMgrName)
+	ngbMgr.sdl = sch.SchinfGetScheduler(ptn)
+	eno, ptnTab := sch.SchinfGetTaskNodeByName(ngbMgr.sdl, sch.TabMgrName)
 
 	if 	eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("PoweronHandler: " +
@@ -944,8 +945,18 @@ func (ngbMgr *neighborManager)PoweronHandler(ptn interface{}) sch.SchErrno {
 		return sch.SchEnoUnknown
 	}
 
+	eno, ptnLsn := sch.SchinfGetTaskNodeByName(ngbMgr.sdl, LsnMgrName)
+
+	if eno != sch.SchEnoNone || ptnLsn == nil {
+		yclog.LogCallerFileLine("PoweronHandler: " +
+			"SchinfGetTaskNodeByName failed, eno: %d, name: %s",
+			eno, LsnMgrName)
+		return sch.SchEnoUnknown
+	}
+
 	ngbMgr.ptnMe = ptn
 	ngbMgr.ptnTab = ptnTab
+	ngbMgr.lsnMgr = sch.SchinfGetUserDataArea(ptnLsn).(*listenerManager)
 
 	return sch.SchEnoNone
 }
@@ -954,6 +965,33 @@ func (ngbMgr *neighborManager)PoweronHandler(ptn interface{}) sch.SchErrno {
 // Poweroff handler
 //
 func (ngbMgr *neighborManager)PoweroffHandler(ptn interface{}) sch.SchErrno {
+
+	//
+	// Stop all protocol instances. Notice that the map would be cleaned by
+	// the instances in their NgbProtoDieCb, so we can't hold the lock while
+	// stopping them.
+	//
+
+	ngbMgr.lock.Lock()
+
+	var ptns = make([]interface{}, 0, len(ngbMgr.ngbMap))
+
+	for _, inst := range ngbMgr.ngbMap {
+		ptns = append(ptns, inst.ptn)
+	}
+
+	ngbMgr.lock.Unlock()
+
+	for _, ptnInst := range ptns {
+
+		if eno := sch.SchinfStopTask(ptnInst); eno != sch.SchEnoNone {
+
+			yclog.LogCallerFileLine("PoweroffHandler: " +
+				"SchinfStopTask failed, eno: %d, task: %s",
+				eno, sch.SchinfGetTaskName(ptnInst))
+		}
+	}
+
 	yclog.LogCallerFileLine("PoweroffHandler: done for poweroff event")
 	return sch.SchinfTaskDone(ptn, sch.SchEnoKilled)
 }
@@ -1013,11 +1051,11 @@ func (ngbMgr *neighborManager)PingHandler(ping *um.Ping) NgbMgrErrno {
 
 	yclog.LogCallerFileLine("PingHandler: handle Ping message from peer")
 
-	if ping.To.NodeId != lsnMgr.cfg.ID {
+	if ping.To.NodeId != ngbMgr.lsnMgr.cfg.ID {
 
 		yclog.LogCallerFileLine("PingHandler: " +
 			"not the target: %s",
-			ycfg.P2pNodeId2HexString(lsnMgr.cfg.ID))
+			ycfg.P2pNodeId2HexString(ngbMgr.lsnMgr.cfg.ID))
 
 		return NgbMgrEnoParameter
 	}
@@ -1034,17 +1072,19 @@ func (ngbMgr *neighborManager)PingHandler(ping *um.Ping) NgbMgrErrno {
 	pong := um.Pong{
 		From:		ping.To,
 		To:			ping.From,
-		Expiration:	0,
+		Expiration:	um.UdpMsgExpiration(),
 		Extra:		nil,
 	}
 
+	pong.From.IP = um.UdpMsgFromIP(pong.From.IP, pong.To.IP)
+
 	toAddr := net.UDPAddr {
 		IP: 	ping.From.IP,
 		Port:	int(ping.From.UDP),
 		Zone:	"",
 	}
 
-	pum := new(um.UdpMsg)
+	pum := um.NewUdpMsg(ngbMgr.lsnMgr.cfg.PrivateKey, ngbMgr.lsnMgr.cfg.Network)
 	if eno := pum.Encode(um.UdpMsgTypePong, &pong); eno != um.UdpMsgEnoNone {
 
 		yclog.LogCallerFileLine("PingHandler: " +
@@ -1056,7 +1096,7 @@ func (ngbMgr *neighborManager)PingHandler(ping *um.Ping) NgbMgrErrno {
 
 	if buf, bytes := pum.GetRawMessage(); buf != nil && bytes > 0 {
 
-		if eno := sendUdpMsg(buf, &toAddr); eno != sch.SchEnoNone {
+		if eno := ngbMgr.lsnMgr.sendUdpMsg(buf, &toAddr); eno != sch.SchEnoNone {
 
 			yclog.LogCallerFileLine("PingHandler: " +
 				"sendUdpMsg failed, eno: %d",
@@ -1133,11 +1173,11 @@ func (ngbMgr *neighborManager)PongHandler(pong *um.Pong) NgbMgrErrno {
 
 	yclog.LogCallerFileLine("PongHandler: handle Pong message from peer")
 
-	if pong.To.NodeId != lsnMgr.cfg.ID {
+	if pong.To.NodeId != ngbMgr.lsnMgr.cfg.ID {
 
 		yclog.LogCallerFileLine("PongHandler: " +
 			"not the target: %s",
-			ycfg.P2pNodeId2HexString(lsnMgr.cfg.ID))
+			ycfg.P2pNodeId2HexString(ngbMgr.lsnMgr.cfg.ID))
 
 		return NgbMgrEnoParameter
 	}
@@ -1237,7 +1277,7 @@ func (ngbMgr *neighborManager)FindNodeHandler(findNode *um.FindNode) NgbMgrErrno
 	// to add the sender node to bucket and node database.
 	//
 
-	if findNode.To.NodeId != lsnMgr.cfg.ID {
+	if findNode.To.NodeId != ngbMgr.lsnMgr.cfg.ID {
 		yclog.LogCallerFileLine("FindNodeHandler: " +
 				"local is not the destination: %s",
 				fmt.Sprintf("%X", findNode.To.NodeId))
@@ -1263,22 +1303,22 @@ func (ngbMgr *neighborManager)FindNodeHandler(findNode *um.FindNode) NgbMgrErrno
 	var umNodes = make([]*um.Node, 0)
 
 	local := ngbMgr.localNode()
-	nodes = append(nodes, tab.TabClosest(tab.NodeID(findNode.Target), tab.TabInstQPendingMax)...)
+	nodes = append(nodes, tab.TabClosest(ngbMgr.sdl, tab.NodeID(findNode.Target), tab.TabInstQPendingMax)...)
 
 	if len(nodes) == 0 {
 
-		nodes = append(nodes, tab.TabBuildNode(&ycfg.PtrConfig.Local))
+		nodes = append(nodes, tab.TabBuildNode(&sch.SchinfGetP2pConfig(ngbMgr.sdl).Local))
 
 	} else if findNode.From.NodeId == findNode.Target {
 
 		num := len(nodes)
 		if num < tab.TabInstQPendingMax {
 
-			nodes = append(nodes, tab.TabBuildNode(&ycfg.PtrConfig.Local))
+			nodes = append(nodes, tab.TabBuildNode(&sch.SchinfGetP2pConfig(ngbMgr.sdl).Local))
 
 		} else {
 
-			nodes[num-1] = tab.TabBuildNode(&ycfg.PtrConfig.Local)
+			nodes[num-1] = tab.TabBuildNode(&sch.SchinfGetP2pConfig(ngbMgr.sdl).Local)
 		}
 	}
 
@@ -1297,18 +1337,20 @@ func (ngbMgr *neighborManager)FindNodeHandler(findNode *um.FindNode) NgbMgrErrno
 		To:			findNode.From,
 		Id:			uint64(time.Now().UnixNano()),
 		Nodes:		umNodes,
-		Expiration:	0,
+		Expiration:	um.UdpMsgExpiration(),
 		Extra:		nil,
 
 	}
 
+	neighbors.From.IP = um.UdpMsgFromIP(neighbors.From.IP, neighbors.To.IP)
+
 	toAddr := net.UDPAddr {
 		IP: 	findNode.From.IP,
 		Port:	int(findNode.From.UDP),
 		Zone:	"",
 	}
 
-	pum := new(um.UdpMsg)
+	pum := um.NewUdpMsg(ngbMgr.lsnMgr.cfg.PrivateKey, ngbMgr.lsnMgr.cfg.Network)
 	if eno := pum.Encode(um.UdpMsgTypeNeighbors, &neighbors); eno != um.UdpMsgEnoNone {
 
 		yclog.LogCallerFileLine("FindNodeHandler: " +
@@ -1319,7 +1361,7 @@ func (ngbMgr *neighborManager)FindNodeHandler(findNode *um.FindNode) NgbMgrErrno
 
 	if buf, bytes := pum.GetRawMessage(); buf != nil && bytes > 0 {
 
-		if eno := sendUdpMsg(buf, &toAddr); eno != sch.SchEnoNone {
+		if eno := ngbMgr.lsnMgr.sendUdpMsg(buf, &toAddr); eno != sch.SchEnoNone {
 
 			yclog.LogCallerFileLine("FindNodeHandler: " +
 				"sendUdpMsg failed, eno: %d", eno)
@@ -1385,11 +1427,11 @@ func (ngbMgr *neighborManager)NeighborsHandler(nbs *um.Neighbors) NgbMgrErrno {
 	// instead, we discard this "Neighbors" message then return at once.
 	//
 
-	if nbs.To.NodeId != lsnMgr.cfg.ID {
+	if nbs.To.NodeId != ngbMgr.lsnMgr.cfg.ID {
 
 		yclog.LogCallerFileLine("NeighborsHandler: " +
 			"not the target: %s",
-			ycfg.P2pNodeId2HexString(lsnMgr.cfg.ID))
+			ycfg.P2pNodeId2HexString(ngbMgr.lsnMgr.cfg.ID))
 
 		return NgbMgrEnoParameter
 	}
@@ -1501,6 +1543,7 @@ func (ngbMgr *neighborManager)FindNodeReq(findNode *um.FindNode) NgbMgrErrno {
 		msgBody:	findNode,
 		tidFN:		sch.SchInvalidTid,
 		tidPP:		sch.SchInvalidTid,
+		ngbMgr:		ngbMgr,
 	}
 
 	var noDog = sch.SchWatchDog {
@@ -1512,9 +1555,9 @@ func (ngbMgr *neighborManager)FindNodeReq(findNode *um.FindNode) NgbMgrErrno {
 	// when everything is ok, see bellow pls.
 	//
 
-	fnInstSeq++
+	ngbMgr.fnInstSeq++
 	var dc = sch.SchTaskDescription {
-		Name:	fmt.Sprintf("%s%d_findnode_%s", NgbProcName, fnInstSeq, strPeerNodeId),
+		Name:	fmt.Sprintf("%s%d_findnode_%s", NgbProcName, ngbMgr.fnInstSeq, strPeerNodeId),
 		MbSize:	ngbProcMailboxSize,
 		Ep:		NgbProtoProc,
 		Wd:		&noDog,
@@ -1523,7 +1566,7 @@ func (ngbMgr *neighborManager)FindNodeReq(findNode *um.FindNode) NgbMgrErrno {
 		UserDa: &ngbInst,
 	}
 
-	eno, ptn := sch.SchinfCreateTask(&dc)
+	eno, ptn := sch.SchinfCreateTask(ngbMgr.sdl, &dc)
 	if eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("FindNodeReq: " +
@@ -1676,16 +1719,17 @@ func (ngbMgr *neighborManager)PingpongReq(ping *um.Ping) NgbMgrErrno {
 		msgBody:	ping,
 		tidFN:		sch.SchInvalidTid,
 		tidPP:		sch.SchInvalidTid,
+		ngbMgr:		ngbMgr,
 	}
 
 	var noDog = sch.SchWatchDog {
 		HaveDog:false,
 	}
 
-	ppInstSeq++
+	ngbMgr.ppInstSeq++
 
 	var dc = sch.SchTaskDescription {
-		Name:	fmt.Sprintf("%s%d_pingpong_%s", NgbProcName, ppInstSeq, strPeerNodeId),
+		Name:	fmt.Sprintf("%s%d_pingpong_%s", NgbProcName, ngbMgr.ppInstSeq, strPeerNodeId),
 		MbSize:	ngbProcMailboxSize,
 		Ep:		NgbProtoProc,
 		Wd:		&noDog,
@@ -1694,7 +1738,7 @@ func (ngbMgr *neighborManager)PingpongReq(ping *um.Ping) NgbMgrErrno {
 		UserDa: &ngbInst,
 	}
 
-	eno, ptn := sch.SchinfCreateTask(&dc)
+	eno, ptn := sch.SchinfCreateTask(ngbMgr.sdl, &dc)
 
 	if eno != sch.SchEnoNone {
 
@@ -1787,9 +1831,9 @@ func (ngbMgr *neighborManager) getMap(name string) *neighborInst {
 //
 func (ngbMgr *neighborManager) localEndpoint() *um.Endpoint {
 	return &um.Endpoint {
-		IP:		lsnMgr.cfg.IP,
-		UDP:	lsnMgr.cfg.UDP,
-		TCP:	lsnMgr.cfg.TCP,
+		IP:		ngbMgr.lsnMgr.cfg.IP,
+		UDP:	ngbMgr.lsnMgr.cfg.UDP,
+		TCP:	ngbMgr.lsnMgr.cfg.TCP,
 	}
 }
 
@@ -1798,10 +1842,10 @@ func (ngbMgr *neighborManager) localEndpoint() *um.Endpoint {
 //
 func (ngbMgr *neighborManager) localNode() *um.Node {
 	return &um.Node {
-		IP:		lsnMgr.cfg.IP,
-		UDP:	lsnMgr.cfg.UDP,
-		TCP:	lsnMgr.cfg.TCP,
-		NodeId:	lsnMgr.cfg.ID,
+		IP:		ngbMgr.lsnMgr.cfg.IP,
+		UDP:	ngbMgr.lsnMgr.cfg.UDP,
+		TCP:	ngbMgr.lsnMgr.cfg.TCP,
+		NodeId:	ngbMgr.lsnMgr.cfg.ID,
 	}
 }
 
@@ -43,6 +43,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
+	"net"
 	"os"
 	"sync"
 	"time"
@@ -441,26 +442,38 @@ func (db *nodeDB) close() {
 
 
 //
-// Added by yeeco to remove the reference to Ethereum's rlp
+// Added by yeeco to remove the reference to Ethereum's rlp. A node is encoded
+// as:
+//
+//	ip(16 bytes) | udp(2 bytes) | tcp(2 bytes) | id | sha
 //
+// where the ip is always in its' 16 bytes form, so both IPv4 and IPv6 nodes
+// can be held. Records with 4 bytes ip, written by former versions, can still
+// be decoded.
+//
+const (
+	nodeBlobIPv4Size = net.IPv4len + 4 + len(NodeID{}) + len(Hash{})
+	nodeBlobSize     = net.IPv6len + 4 + len(NodeID{}) + len(Hash{})
+)
+
 func EncodeToBytes(node *Node) ([]byte, error) {
 	if node == nil {
 		return nil, nil
 	}
 
-	blob := make([]byte, 0)
-
-	blob = append(blob, node.IP...)
+	ip := node.IP.To16()
+	if ip == nil {
+		return nil, errors.New("invalid ip address")
+	}
 
-	udp := node.UDP
-	hb := byte((udp >> 8) & 0xff)
-	lb := byte(udp & 0xff)
-	blob = append(blob, []byte{hb,lb}...)
+	blob := make([]byte, 0, nodeBlobSize)
+	blob = append(blob, ip...)
 
-	tcp := node.TCP
-	hb = byte((tcp >> 8) & 0xff)
-	lb = byte(tcp & 0xff)
-	blob = append(blob, []byte{hb,lb}...)
+	var port = make([]byte, 2)
+	binary.BigEndian.PutUint16(port, node.UDP)
+	blob = append(blob, port...)
+	binary.BigEndian.PutUint16(port, node.TCP)
+	blob = append(blob, port...)
 
 	blob = append(blob, node.ID[:]...)
 	blob = append(blob, node.sha[:]...)
@@ -472,10 +485,28 @@ func EncodeToBytes(node *Node) ([]byte, error) {
 // Added by yeeco to remove the reference to Ethereum's rlp
 //
 func DecodeBytes(blob []byte, node *Node) error {
-	copy(node.IP, blob[0:16])
-	node.UDP = uint16((blob[16] << 8) + blob[17])
-	node.TCP = uint16((blob[18] << 8) + blob[19])
-	copy(node.ID[0:], blob[20:20+cap(node.ID)])
-	copy(node.sha[0:], blob[20+cap(node.ID):])
+
+	var ipLen int
+
+	switch len(blob) {
+	case nodeBlobSize:
+		ipLen = net.IPv6len
+	case nodeBlobIPv4Size:
+		ipLen = net.IPv4len
+	default:
+		return errors.New("invalid node record size")
+	}
+
+	node.IP = append(net.IP{}, blob[0:ipLen]...)
+	if ip4 := node.IP.To4(); ip4 != nil {
+		node.IP = ip4
+	}
+
+	blob = blob[ipLen:]
+	node.UDP = binary.BigEndian.Uint16(blob[0:2])
+	node.TCP = binary.BigEndian.Uint16(blob[2:4])
+	copy(node.ID[0:], blob[4:4+len(node.ID)])
+	copy(node.sha[0:], blob[4+len(node.ID):])
+
 	return nil
-}
\ No newline at end of file
+}
@@ -32,6 +32,7 @@ import (
 	ycfg	"github.com/yeeco/p2p/config"
 	um		"github.com/yeeco/p2p/discover/udpmsg"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/acl"
 )
 
 
@@ -53,6 +54,7 @@ const (
 	TabMgrEnoUdp
 	TabMgrEnoResource
 	TabMgrEnoRemove
+	TabMgrEnoRejected
 )
 
 type TabMgrErrno int
@@ -185,6 +187,7 @@ type tableManager struct {
 	lock			sync.Mutex			// lock for sync
 	name			string				// name
 	tep				sch.SchUserTaskEp	// entry
+	sdl				*sch.Scheduler		// scheduler of the p2p instance
 	cfg				tabConfig			// configuration
 	ptnMe			interface{}			// pointer to task node of myself
 	ptnNgbMgr		interface{}			// pointer to neighbor manager task node
@@ -209,37 +212,55 @@ type tableManager struct {
 	nodeDb			*nodeDB				// node database object pointer
 }
 
-var tabMgr = tableManager{
-	name:			TabMgrName,
-	tep:			nil,
-	cfg:			tabConfig{},
-	ptnMe:			nil,
-	ptnNgbMgr:		nil,
-	ptnDcvMgr:		nil,
-	shaLocal:		Hash{},
-	buckets:		[nBuckets]*bucket{},
-	queryIcb:		make([]*instCtrlBlock, 0, TabInstQueringMax),
-	boundIcb:		make([]*instCtrlBlock, 0, TabInstBondingMax),
-	queryPending:	make([]*queryPendingEntry, 0, TabInstQPendingMax),
-	boundPending:	make([]*Node, 0, TabInstBPendingMax),
-	dlkTab:			make([]int, 256),
-	refreshing:		false,
-	dataDir:		"",
-	nodeDb:			nil,
-	arfTid:			sch.SchInvalidTid,
-}
-
-//
-// To escape the compiler "initialization loop" error
-//
-func init() {
-	tabMgr.tep = TabMgrProc
-	ndbCleaner.tep = NdbcProc
+//
+// Create table manager, it's the user data area of the static task TabMgrName
+// of a p2p instance.
+//
+func NewTabMgr() interface{} {
+
+	var tabMgr = tableManager{
+		name:			TabMgrName,
+		tep:			TabMgrProc,
+		cfg:			tabConfig{},
+		ptnMe:			nil,
+		ptnNgbMgr:		nil,
+		ptnDcvMgr:		nil,
+		shaLocal:		Hash{},
+		buckets:		[nBuckets]*bucket{},
+		queryIcb:		make([]*instCtrlBlock, 0, TabInstQueringMax),
+		boundIcb:		make([]*instCtrlBlock, 0, TabInstBondingMax),
+		queryPending:	make([]*queryPendingEntry, 0, TabInstQPendingMax),
+		boundPending:	make([]*Node, 0, TabInstBPendingMax),
+		dlkTab:			make([]int, 256),
+		refreshing:		false,
+		dataDir:		"",
+		nodeDb:			nil,
+		arfTid:			sch.SchInvalidTid,
+	}
+
 	for loop := 0; loop < cap(tabMgr.buckets); loop++ {
 		b := new(bucket)
 		tabMgr.buckets[loop] = b
 		b.nodes = make([]*bucketEntry, 0, bucketSize)
 	}
+
+	return &tabMgr
+}
+
+//
+// Get table manager of a p2p instance by its' scheduler, for functions
+// exported to other tasks.
+//
+func tabGetManager(sdl *sch.Scheduler) *tableManager {
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(sdl, TabMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+		return nil
+	}
+
+	tabMgr, _ := sch.SchinfGetUserDataArea(ptn).(*tableManager)
+
+	return tabMgr
 }
 
 //
@@ -257,41 +278,42 @@ func TabMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 	}
 
 	var eno TabMgrErrno = TabMgrEnoNone
+	var tabMgr = sch.SchinfGetUserDataArea(ptn).(*tableManager)
 
 	switch msg.Id {
 
 	case sch.EvSchPoweron:
-		eno = tabMgrPoweron(ptn)
+		eno = tabMgr.tabMgrPoweron(ptn)
 
 	case sch.EvSchPoweroff:
-		eno = tabMgrPoweroff(ptn)
+		eno = tabMgr.tabMgrPoweroff(ptn)
 
 	case sch.EvTabRefreshTimer:
-		eno = tabMgrRefreshTimerHandler()
+		eno = tabMgr.tabMgrRefreshTimerHandler()
 
 	case sch.EvTabPingpongTimer:
-		eno = tabMgrPingpongTimerHandler(msg.Body.(*instCtrlBlock))
+		eno = tabMgr.tabMgrPingpongTimerHandler(msg.Body.(*instCtrlBlock))
 
 	case sch.EvTabFindNodeTimer:
-		eno = tabMgrFindNodeTimerHandler(msg.Body.(*instCtrlBlock))
+		eno = tabMgr.tabMgrFindNodeTimerHandler(msg.Body.(*instCtrlBlock))
 
 	case sch.EvTabRefreshReq:
-		eno = tabMgrRefreshReq(msg.Body.(*sch.MsgTabRefreshReq))
+		eno = tabMgr.tabMgrRefreshReq(msg.Body.(*sch.MsgTabRefreshReq))
 
 	case sch.EvNblFindNodeRsp:
-		eno = tabMgrFindNodeRsp(msg.Body.(*sch.NblFindNodeRsp))
+		eno = tabMgr.tabMgrFindNodeRsp(msg.Body.(*sch.NblFindNodeRsp))
 
 	case sch.EvNblPingpongRsp:
-		eno = tabMgrPingpongRsp(msg.Body.(*sch.NblPingRsp))
+		eno = tabMgr.tabMgrPingpongRsp(msg.Body.(*sch.NblPingRsp))
 
 	case sch.EvNblPingedInd:
-		eno = tabMgrPingedInd(msg.Body.(*um.Ping))
+		eno = tabMgr.tabMgrPingedInd(msg.Body.(*um.Ping))
 
 	case sch.EvNblPongedInd:
-		eno = tabMgrPongedInd(msg.Body.(*um.Pong))
+		eno = tabMgr.tabMgrPongedInd(msg.Body.(*um.Pong))
 
 	case sch.EvNblQueriedInd:
-		eno = tabMgrQueriedInd(msg.Body.(*um.FindNode))
+		eno = tabMgr.tabMgrQueriedInd(msg.Body.(*um.FindNode))
 
 	default:
 		yclog.LogCallerFileLine("TabMgrProc: invalid message: %d", msg.Id)
@@ -309,7 +331,7 @@ func TabMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 //
 // Poweron handler
 //
-func tabMgrPoweron(ptn interface{}) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrPoweron(ptn interface{}) TabMgrErrno {
 
 	if ptn == nil {
 		yclog.LogCallerFileLine("tabMgrPoweron: invalid parameters")
@@ -318,11 +340,13 @@ func tabMgrPoweron(ptn interface{}) TabMgrErrno {
 
 	var eno TabMgrErrno = TabMgrEnoNone
 
+	tabMgr.sdl = sch.SchinfGetScheduler(ptn)
+
 	//
 	// fetch configurations
 	//
 
-	if eno = tabGetConfig(&tabMgr.cfg); eno != TabMgrEnoNone {
+	if eno = tabMgr.tabGetConfig(&tabMgr.cfg); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPoweron: tabGetConfig failed, eno: %d", eno)
 		return eno
 	}
@@ -331,7 +355,7 @@ func tabMgrPoweron(ptn interface{}) TabMgrErrno {
 	// prepare node database
 	//
 
-	if eno = tabNodeDbPrepare(); eno != TabMgrEnoNone {
+	if eno = tabMgr.tabNodeDbPrepare(); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPoweron: tabNodeDbPrepare failed, eno: %d", eno)
 		return eno
 	}
@@ -340,7 +364,7 @@ func tabMgrPoweron(ptn interface{}) TabMgrErrno {
 	// build local node identity hash for neighbors finding
 	//
 
-	if eno = tabSetupLocalHashId(); eno != TabMgrEnoNone {
+	if eno = tabMgr.tabSetupLocalHashId(); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPoweron: tabSetupLocalHash failed, eno: %d", eno)
 		return eno
 	}
@@ -349,7 +373,7 @@ func tabMgrPoweron(ptn interface{}) TabMgrErrno {
 	// preapare related task ponters
 	//
 
-	if eno = tabRelatedTaskPrepare(ptn); eno != TabMgrEnoNone {
+	if eno = tabMgr.tabRelatedTaskPrepare(ptn); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPoweron: tabRelatedTaskPrepare failed, eno: %d", eno)
 		return eno
 	}
@@ -372,7 +396,7 @@ func tabMgrPoweron(ptn interface{}) TabMgrErrno {
 		cycle = autoBsnRefreshCycle
 	}
 
-	if eno = tabStartTimer(nil, sch.TabRefreshTimerId, cycle); eno != TabMgrEnoNone {
+	if eno = tabMgr.tabStartTimer(nil, sch.TabRefreshTimerId, cycle); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPoweron: tabStartTimer failed, eno: %d", eno)
 		return eno
 	}
@@ -386,7 +410,7 @@ func tabMgrPoweron(ptn interface{}) TabMgrErrno {
 	rand.Seed(time.Now().UnixNano())
 	tabMgr.refreshing = false
 
-	if eno = tabRefresh(nil); eno != TabMgrEnoNone {
+	if eno = tabMgr.tabRefresh(nil); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPoweron: tabRefresh failed, eno: %d", eno)
 		return eno
 	}
@@ -397,39 +421,68 @@ func tabMgrPoweron(ptn interface{}) TabMgrErrno {
 //
 // Poweroff handler
 //
-func tabMgrPoweroff(ptn interface{}) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrPoweroff(ptn interface{}) TabMgrErrno {
 
 	if ptn == nil {
 		yclog.LogCallerFileLine("tabMgrPoweroff: invalid parameters")
 		return TabMgrEnoParameter
 	}
 
+	if tabMgr.arfTid != sch.SchInvalidTid {
+		sch.SchinfKillTimer(ptn, tabMgr.arfTid)
+		tabMgr.arfTid = sch.SchInvalidTid
+	}
+
+	//
+	// Timers of query and bound instances are killed by the scheduler when
+	// the task is done; clean the tables here so the manager would start
+	// from scratch when it's powered on again.
+	//
+
+	tabMgr.lock.Lock()
+
+	for _, b := range tabMgr.buckets {
+		b.nodes = b.nodes[:0]
+	}
+
+	tabMgr.queryIcb = tabMgr.queryIcb[:0]
+	tabMgr.boundIcb = tabMgr.boundIcb[:0]
+	tabMgr.queryPending = tabMgr.queryPending[:0]
+	tabMgr.boundPending = tabMgr.boundPending[:0]
+	tabMgr.refreshing = false
+
+	tabMgr.lock.Unlock()
+
+	//
+	// close the node database, leveldb flushes its' journal on closing
+	//
+
 	if tabMgr.nodeDb != nil {
 		tabMgr.nodeDb.close()
 		tabMgr.nodeDb = nil
 	}
 
-	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) == sch.SchEnoNone {
-		yclog.LogCallerFileLine("tabMgrPoweroff: done task failed")
-		return TabMgrEnoNone
+	if eno := sch.SchinfTaskDone(ptn, sch.SchEnoKilled); eno != sch.SchEnoNone {
+		yclog.LogCallerFileLine("tabMgrPoweroff: done task failed, eno: %d", eno)
+		return TabMgrEnoScheduler
 	}
 
 	yclog.LogCallerFileLine("tabMgrPoweroff: task done")
-	return TabMgrEnoScheduler
+	return TabMgrEnoNone
 }
 
 //
 // Auto-Refresh timer handler
 //
-func tabMgrRefreshTimerHandler()TabMgrErrno {
+func (tabMgr *tableManager) tabMgrRefreshTimerHandler()TabMgrErrno {
 	yclog.LogCallerFileLine("tabMgrRefreshTimerHandler: atuo refresh timer expired, refresh table ...")
-	return tabRefresh(nil)
+	return tabMgr.tabRefresh(nil)
 }
 
 //
 // Pingpong timer expired event handler
 //
-func tabMgrPingpongTimerHandler(inst *instCtrlBlock) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrPingpongTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 
 	yclog.LogCallerFileLine("tabMgrPingpongTimerHandler: timer expired")
 
@@ -442,7 +495,7 @@ func tabMgrPingpongTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 	// update buckets
 	//
 
-	if eno := tabUpdateBucket(inst, TabMgrEnoTimeout); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabUpdateBucket(inst, TabMgrEnoTimeout); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPingpongTimerHandler: tabUpdateBucket failed, eno: %d", eno)
 		return eno
 	}
@@ -451,7 +504,7 @@ func tabMgrPingpongTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 	// delete the active instance
 	//
 
-	if eno := tabDeleteActiveBoundInst(inst); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabDeleteActiveBoundInst(inst); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPingpongTimerHandler: tabDeleteActiveQueryInst failed, eno: %d", eno)
 		return eno
 	}
@@ -460,7 +513,7 @@ func tabMgrPingpongTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 	// try to active more query instances
 	//
 
-	if eno := tabActiveBoundInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveBoundInst(); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPingpongTimerHandler: tabActiveQueryInst failed, eno: %d", eno)
 		return eno
 	}
@@ -471,7 +524,7 @@ func tabMgrPingpongTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 //
 // FindNode timer expired event handler
 //
-func tabMgrFindNodeTimerHandler(inst *instCtrlBlock) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrFindNodeTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 
 	yclog.LogCallerFileLine("tabMgrFindNodeTimerHandler: timer expired")
 
@@ -486,7 +539,7 @@ func tabMgrFindNodeTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 
 	inst.state = TabInstStateQTimeout
 	inst.rsp = nil
-	if eno := tabUpdateNodeDb4Query(inst, TabMgrEnoTimeout); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabUpdateNodeDb4Query(inst, TabMgrEnoTimeout); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeTimerHandler: tabUpdateNodeDb4Query failed, eno: %d", eno)
 		return eno
 	}
@@ -495,7 +548,7 @@ func tabMgrFindNodeTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 	// update buckets
 	//
 
-	if eno := tabUpdateBucket(inst, TabMgrEnoTimeout); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabUpdateBucket(inst, TabMgrEnoTimeout); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeTimerHandler: tabUpdateBucket failed, eno: %d", eno)
 		return eno
 	}
@@ -504,7 +557,7 @@ func tabMgrFindNodeTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 	// delete the active instance
 	//
 
-	if eno := tabDeleteActiveQueryInst(inst); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabDeleteActiveQueryInst(inst); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeTimerHandler: tabDeleteActiveQueryInst failed, eno: %d", eno)
 		return eno
 	}
@@ -513,7 +566,7 @@ func tabMgrFindNodeTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 	// try to active more query instances
 	//
 
-	if eno := tabActiveQueryInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveQueryInst(); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeTimerHandler: tabActiveQueryInst failed, eno: %d", eno)
 		return eno
 	}
@@ -524,16 +577,16 @@ func tabMgrFindNodeTimerHandler(inst *instCtrlBlock) TabMgrErrno {
 //
 // Refresh request handler
 //
-func tabMgrRefreshReq(msg *sch.MsgTabRefreshReq)TabMgrErrno {
+func (tabMgr *tableManager) tabMgrRefreshReq(msg *sch.MsgTabRefreshReq)TabMgrErrno {
 	yclog.LogCallerFileLine("tabMgrRefreshReq: requst to refresh table ...")
 	_ = msg
-	return tabRefresh(nil)
+	return tabMgr.tabRefresh(nil)
 }
 
 //
 // FindNode response handler
 //
-func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
+func (tabMgr *tableManager) tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 
 	yclog.LogCallerFileLine("tabMgrFindNodeRsp: FindNode response received")
 
@@ -548,7 +601,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 
 	var inst *instCtrlBlock = nil
 
-	inst = tabFindInst(&msg.FindNode.To, TabInstStateQuering)
+	inst = tabMgr.tabFindInst(&msg.FindNode.To, TabInstStateQuering)
 	if inst == nil {
 		yclog.LogCallerFileLine("tabMgrFindNodeRsp: instance not found")
 		return TabMgrEnoNotFound
@@ -576,7 +629,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 		// delete the active instance
 		//
 
-		if eno := tabDeleteActiveQueryInst(inst); eno != TabMgrEnoNone {
+		if eno := tabMgr.tabDeleteActiveQueryInst(inst); eno != TabMgrEnoNone {
 			yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabDeleteActiveQueryInst failed, eno: %d", eno)
 			return eno
 		}
@@ -585,7 +638,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 		// try to active more query instances
 		//
 
-		if eno := tabActiveQueryInst(); eno != TabMgrEnoNone {
+		if eno := tabMgr.tabActiveQueryInst(); eno != TabMgrEnoNone {
 			yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabActiveQueryInst failed, eno: %d", eno)
 			return eno
 		}
@@ -599,7 +652,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 	// see bellow.
 	//
 
-	if eno := tabUpdateNodeDb4Query(inst, result); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabUpdateNodeDb4Query(inst, result); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabUpdateNodeDb4Query failed, eno: %d", eno)
 	}
 
@@ -608,7 +661,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 	// see bellow.
 	//
 
-	if eno := tabUpdateBucket(inst, result); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabUpdateBucket(inst, result); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabUpdateBucket failed, eno: %d", eno)
 	}
 
@@ -616,7 +669,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 	// delete the active instance
 	//
 
-	if eno := tabDeleteActiveQueryInst(inst); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabDeleteActiveQueryInst(inst); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabDeleteActiveQueryInst failed, eno: %d", eno)
 		return eno
 	}
@@ -625,7 +678,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 	// try to active more query instances
 	//
 
-	if eno := tabActiveQueryInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveQueryInst(); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabActiveQueryInst failed, eno: %d", eno)
 	}
 
@@ -643,7 +696,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 	//
 
 	for _, node := range msg.Neighbors.Nodes {
-		if eno := tabAddPendingBoundInst(node); eno != TabMgrEnoNone {
+		if eno := tabMgr.tabAddPendingBoundInst(node); eno != TabMgrEnoNone {
 			yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabAddPendingBoundInst failed, eno: %d", eno)
 			break
 		}
@@ -653,7 +706,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 	// try to active more BOUND instances
 	//
 
-	if eno := tabActiveBoundInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveBoundInst(); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrFindNodeRsp: tabActiveBoundInst failed, eno: %d", eno)
 		return eno
 	}
@@ -664,7 +717,7 @@ func tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp)TabMgrErrno {
 //
 // Pingpong respone handler
 //
-func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 
 	//
 	// Lookup active instance for the response. Notice: some respons without actived
@@ -683,7 +736,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 	}
 
 	var inst *instCtrlBlock = nil
-	inst = tabFindInst(&msg.Ping.To, TabInstStateBonding)
+	inst = tabMgr.tabFindInst(&msg.Ping.To, TabInstStateBonding)
 
 	if inst == nil {
 
@@ -726,7 +779,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 			"node: %s",
 			fmt.Sprintf("%+v", msg.Pong.From))
 
-		return tabUpdateBootstarpNode(&msg.Pong.From)
+		return tabMgr.tabUpdateBootstarpNode(&msg.Pong.From)
 	}
 
 	inst.rsp = msg
@@ -743,7 +796,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 	// failed, for some nodes might not be added into any buckets.
 	//
 
-	if eno := tabUpdateBucket(inst, result); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabUpdateBucket(inst, result); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPingpongRsp: tabUpdateBucket failed, eno: %d", eno)
 	}
 
@@ -751,7 +804,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 	// delete the active instance
 	//
 
-	if eno := tabDeleteActiveBoundInst(inst); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabDeleteActiveBoundInst(inst); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPingpongRsp: tabDeleteActiveQueryInst failed, eno: %d", eno)
 		return eno
 	}
@@ -760,7 +813,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 	// try to active more BOUND instances
 	//
 
-	if eno := tabActiveBoundInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveBoundInst(); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPingpongRsp: tabActiveBoundInst failed, eno: %d", eno)
 		return eno
 	}
@@ -780,7 +833,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 
 	pot	:= time.Now()
 
-	if eno := tabBucketUpdateBoundTime(NodeID(inst.req.(*um.Ping).To.NodeId), nil, &pot);
+	if eno := tabMgr.tabBucketUpdateBoundTime(NodeID(inst.req.(*um.Ping).To.NodeId), nil, &pot);
 	eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrPingpongRsp: " +
@@ -804,7 +857,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 		sha: *tabNodeId2Hash(NodeID(msg.Pong.From.NodeId)),
 	}
 
-	if eno := tabUpdateNodeDb4Bounding(&n, nil, &pot);
+	if eno := tabMgr.tabUpdateNodeDb4Bounding(&n, nil, &pot);
 		eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrPingpongRsp: " +
@@ -822,7 +875,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 		"going to response discover task with the node bound: %s",
 		fmt.Sprintf("%+v", msg.Pong.From))
 
-	if eno := tabDiscoverResp(&msg.Pong.From); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabDiscoverResp(&msg.Pong.From); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabMgrPingpongRsp: tabDiscoverResp failed, eno: %d", eno)
 		return eno
 	}
@@ -833,7 +886,7 @@ func tabMgrPingpongRsp(msg *sch.NblPingRsp) TabMgrErrno {
 //
 // Pinged indication handler
 //
-func tabMgrPingedInd(ping *um.Ping) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrPingedInd(ping *um.Ping) TabMgrErrno {
 
 	//
 	// check if remote node should be bound
@@ -843,7 +896,7 @@ func tabMgrPingedInd(ping *um.Ping) TabMgrErrno {
 		"check if should be bound: %s",
 		fmt.Sprintf("%X", ping.From.NodeId))
 
-	if tabShouldBound(NodeID(ping.From.NodeId)) != true {
+	if tabMgr.tabShouldBound(NodeID(ping.From.NodeId)) != true {
 
 		yclog.LogCallerFileLine("tabMgrPingedInd: " +
 			"node should not be bound: %s",
@@ -860,7 +913,7 @@ func tabMgrPingedInd(ping *um.Ping) TabMgrErrno {
 		"add node to pending queue for bounding: %s",
 		fmt.Sprintf("%X", ping.From.NodeId))
 
-	if eno := tabAddPendingBoundInst(&ping.From); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabAddPendingBoundInst(&ping.From); eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrPingedInd: " +
 			"tabAddPendingBoundInst failed, eno: %d",
@@ -876,7 +929,7 @@ func tabMgrPingedInd(ping *um.Ping) TabMgrErrno {
 	yclog.LogCallerFileLine("tabMgrPingedInd: " +
 		"try more for active bounding")
 
-	if eno := tabActiveBoundInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveBoundInst(); eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrPingedInd: " +
 			"tabActiveBoundInst failed, eno: %d",
@@ -891,7 +944,7 @@ func tabMgrPingedInd(ping *um.Ping) TabMgrErrno {
 //
 // Ponged indication handler
 //
-func tabMgrPongedInd(pong *um.Pong) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrPongedInd(pong *um.Pong) TabMgrErrno {
 
 	//
 	// check if remote node should be bound
@@ -901,7 +954,7 @@ func tabMgrPongedInd(pong *um.Pong) TabMgrErrno {
 		"check if should be bound: %s",
 		fmt.Sprintf("%X", pong.From.NodeId))
 
-	if tabShouldBound(NodeID(pong.From.NodeId)) != true {
+	if tabMgr.tabShouldBound(NodeID(pong.From.NodeId)) != true {
 
 		yclog.LogCallerFileLine("tabMgrPongedInd: " +
 			"node should not be bound: %s",
@@ -918,7 +971,7 @@ func tabMgrPongedInd(pong *um.Pong) TabMgrErrno {
 		"add node to pending queue for bounding: %s",
 		fmt.Sprintf("%X", pong.From.NodeId))
 
-	if eno := tabAddPendingBoundInst(&pong.From); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabAddPendingBoundInst(&pong.From); eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrPongedInd: " +
 			"tabAddPendingBoundInst failed, eno: %d",
@@ -934,7 +987,7 @@ func tabMgrPongedInd(pong *um.Pong) TabMgrErrno {
 	yclog.LogCallerFileLine("tabMgrPongedInd: " +
 		"try more for active bounding")
 
-	if eno := tabActiveBoundInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveBoundInst(); eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrPongedInd: " +
 			"tabActiveBoundInst failed, eno: %d",
@@ -949,13 +1002,13 @@ func tabMgrPongedInd(pong *um.Pong) TabMgrErrno {
 //
 // Queried indication handler
 //
-func tabMgrQueriedInd(findNode *um.FindNode) TabMgrErrno {
+func (tabMgr *tableManager) tabMgrQueriedInd(findNode *um.FindNode) TabMgrErrno {
 
 	//
 	// check if remote node should be bound
 	//
 
-	if tabShouldBound(NodeID(findNode.From.NodeId)) != true {
+	if tabMgr.tabShouldBound(NodeID(findNode.From.NodeId)) != true {
 		return TabMgrEnoNone
 	}
 
@@ -963,7 +1016,7 @@ func tabMgrQueriedInd(findNode *um.FindNode) TabMgrErrno {
 	// add node into pending queue for bounding
 	//
 
-	if eno := tabAddPendingBoundInst(&findNode.From); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabAddPendingBoundInst(&findNode.From); eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrQueriedInd: " +
 			"tabAddPendingBoundInst failed, eno: %d",
@@ -976,7 +1029,7 @@ func tabMgrQueriedInd(findNode *um.FindNode) TabMgrErrno {
 	// try to active more bounding instances
 	//
 
-	if eno := tabActiveBoundInst(); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabActiveBoundInst(); eno != TabMgrEnoNone {
 
 		yclog.LogCallerFileLine("tabMgrQueriedInd: " +
 			"tabActiveBoundInst failed, eno: %d",
@@ -999,12 +1052,19 @@ type nodeDbCleaner struct {
 	name	string				// name
 	tep		sch.SchUserTaskEp	// entry point
 	tid		int					// cleaner timer
+	tabMgr	*tableManager		// table manager owns the node database
 }
 
-var ndbCleaner = nodeDbCleaner{
-	name:	NdbcName,
-	tep:	nil,
-	tid:	sch.SchInvalidTid,
+//
+// Create node database cleaner, it's the user data area of the static task
+// NdbcName of a p2p instance.
+//
+func NewNdbCleaner() interface{} {
+	return &nodeDbCleaner{
+		name:	NdbcName,
+		tep:	NdbcProc,
+		tid:	sch.SchInvalidTid,
+	}
 }
 
 //
@@ -1022,17 +1082,18 @@ func NdbcProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 	}
 
 	var eno TabMgrErrno
+	var ndbCleaner = sch.SchinfGetUserDataArea(ptn).(*nodeDbCleaner)
 
 	switch msg.Id {
 
 	case sch.EvSchPoweron:
-		eno = ndbcPoweron(ptn)
+		eno = ndbCleaner.ndbcPoweron(ptn)
 
 	case sch.EvSchPoweroff:
-		eno = ndbcPoweroff(ptn)
+		eno = ndbCleaner.ndbcPoweroff(ptn)
 
 	case sch.EvNdbCleanerTimer:
-		eno = ndbcAutoCleanTimerHandler()
+		eno = ndbCleaner.ndbcAutoCleanTimerHandler()
 
 	default:
 		yclog.LogCallerFileLine("NdbcProc: invalid message: %d", msg.Id)
@@ -1050,7 +1111,7 @@ func NdbcProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 //
 // Pwoeron handler
 //
-func ndbcPoweron(ptn interface{}) TabMgrErrno {
+func (ndbCleaner *nodeDbCleaner) ndbcPoweron(ptn interface{}) TabMgrErrno {
 
 	if ptn == nil {
 		yclog.LogCallerFileLine("ndbcPoweron: invalid parameters")
@@ -1077,19 +1138,30 @@ func ndbcPoweron(ptn interface{}) TabMgrErrno {
 
 	ndbCleaner.tid = tid
 
+	//
+	// table manager is powered on before us, see TaskStaticPoweronOrder
+	//
+
+	if ndbCleaner.tabMgr = tabGetManager(sch.SchinfGetScheduler(ptn)); ndbCleaner.tabMgr == nil {
+		yclog.LogCallerFileLine("ndbcPoweron: table manager not found")
+		return TabMgrEnoScheduler
+	}
+
 	return TabMgrEnoNone
 }
 
 //
 // Poweroff handler
 //
-func ndbcPoweroff(ptn interface{}) TabMgrErrno {
+func (ndbCleaner *nodeDbCleaner) ndbcPoweroff(ptn interface{}) TabMgrErrno {
 
 	if ptn == nil {
 		yclog.LogCallerFileLine("ndbcPoweroff: invalid parameters")
 		return TabMgrEnoParameter
 	}
 
+	ndbCleaner.tabMgr = nil
+
 	if ndbCleaner.tid != sch.SchInvalidTid {
 		if eno := sch.SchinfKillTimer(ptn, ndbCleaner.tid); eno != sch.SchEnoNone {
 			yclog.LogCallerFileLine("ndbcPoweroff: SchinfKillTimer failed, eno: %d", eno)
@@ -1109,7 +1181,7 @@ func ndbcPoweroff(ptn interface{}) TabMgrErrno {
 //
 // Auto clean timer handler
 //
-func ndbcAutoCleanTimerHandler() TabMgrErrno {
+func (ndbCleaner *nodeDbCleaner) ndbcAutoCleanTimerHandler() TabMgrErrno {
 
 	//
 	// Carry out cleanup procedure
@@ -1118,7 +1190,7 @@ func ndbcAutoCleanTimerHandler() TabMgrErrno {
 	yclog.LogCallerFileLine("ndbcAutoCleanTimerHandler: " +
 		"auto cleanup timer expired, it's time to clean ...")
 
-	err := tabMgr.nodeDb.expireNodes()
+	err := ndbCleaner.tabMgr.nodeDb.expireNodes()
 
 	if err != nil {
 
@@ -1137,7 +1209,7 @@ func ndbcAutoCleanTimerHandler() TabMgrErrno {
 //
 // Fetch configuration
 //
-func tabGetConfig(tabCfg *tabConfig) TabMgrErrno {
+func (tabMgr *tableManager) tabGetConfig(tabCfg *tabConfig) TabMgrErrno {
 
 	if tabCfg == nil {
 		yclog.LogCallerFileLine("tabGetConfig: invalid parameters")
@@ -1149,7 +1221,7 @@ func tabGetConfig(tabCfg *tabConfig) TabMgrErrno {
 		return TabMgrEnoParameter
 	}
 
-	cfg := ycfg.P2pConfig4TabManager()
+	cfg := ycfg.P2pConfig4TabManager(sch.SchinfGetP2pConfig(tabMgr.sdl))
 	if cfg == nil {
 		yclog.LogCallerFileLine("tabGetConfig: P2pConfig4TabManager failed")
 		return TabMgrEnoConfig
@@ -1173,7 +1245,7 @@ func tabGetConfig(tabCfg *tabConfig) TabMgrErrno {
 //
 // Prepare node database when poweron
 //
-func tabNodeDbPrepare() TabMgrErrno {
+func (tabMgr *tableManager) tabNodeDbPrepare() TabMgrErrno {
 	if tabMgr.nodeDb != nil {
 		yclog.LogCallerFileLine("tabNodeDbPrepare: node database had been opened")
 		return TabMgrEnoDatabase
@@ -1201,7 +1273,7 @@ func tabNodeId2Hash(id NodeID) *Hash {
 //
 // Setup local node id hash
 //
-func tabSetupLocalHashId() TabMgrErrno {
+func (tabMgr *tableManager) tabSetupLocalHashId() TabMgrErrno {
 	if cap(tabMgr.shaLocal) != 32 {
 		yclog.LogCallerFileLine("tabSetupLocalHashId: hash identity should be 32 bytes")
 		return TabMgrEnoParameter
@@ -1214,7 +1286,7 @@ func tabSetupLocalHashId() TabMgrErrno {
 //
 // Prepare pointers to related tasks
 //
-func tabRelatedTaskPrepare(ptnMe interface{}) TabMgrErrno {
+func (tabMgr *tableManager) tabRelatedTaskPrepare(ptnMe interface{}) TabMgrErrno {
 
 	if ptnMe == nil {
 		yclog.LogCallerFileLine("tabRelatedTaskPrepare: invalid parameters")
@@ -1223,12 +1295,12 @@ func tabRelatedTaskPrepare(ptnMe interface{}) TabMgrErrno {
 
 	var eno = sch.SchEnoNone
 	tabMgr.ptnMe = ptnMe
-	if eno, tabMgr.ptnNgbMgr = sch.SchinfGetTaskNodeByName(sch.NgbMgrName); eno != sch.SchEnoNone {
+	if eno, tabMgr.ptnNgbMgr = sch.SchinfGetTaskNodeByName(tabMgr.sdl, sch.NgbMgrName); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("tabRelatedTaskPrepare: " +
 			"get task node failed, name: %s", sch.NgbMgrName)
 		return TabMgrEnoScheduler
 	}
-	if eno, tabMgr.ptnDcvMgr = sch.SchinfGetTaskNodeByName(sch.DcvMgrName); eno != sch.SchEnoNone {
+	if eno, tabMgr.ptnDcvMgr = sch.SchinfGetTaskNodeByName(tabMgr.sdl, sch.DcvMgrName); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("tabRelatedTaskPrepare: " +
 			"get task node failed, name: %s", sch.DcvMgrName)
 		return TabMgrEnoScheduler
@@ -1258,7 +1330,7 @@ func tabSetupLog2DistanceLookupTable(lkt []int) TabMgrErrno {
 //
 // Init a refreshing procedure
 //
-func tabRefresh(tid *NodeID) TabMgrErrno {
+func (tabMgr *tableManager) tabRefresh(tid *NodeID) TabMgrErrno {
 
 	//
 	// If we are in refreshing, return at once. When the pending table for query
@@ -1291,7 +1363,7 @@ func tabRefresh(tid *NodeID) TabMgrErrno {
 		target = *tid
 	}
 
-	if nodes = tabClosest(target, TabInstQPendingMax); len(nodes) == 0 {
+	if nodes = tabMgr.tabClosest(target, TabInstQPendingMax); len(nodes) == 0 {
 
 		//
 		// Here all our buckets are empty, we then apply our local node as
@@ -1303,7 +1375,7 @@ func tabRefresh(tid *NodeID) TabMgrErrno {
 
 		target = NodeID(tabMgr.cfg.local.ID)
 
-		seeds := tabSeedsFromDb(TabInstQPendingMax, seedMaxAge)
+		seeds := tabMgr.tabSeedsFromDb(TabInstQPendingMax, seedMaxAge)
 		var seedsBackup = make([]*Node, 0)
 
 		if len(seeds) == 0 {
@@ -1320,7 +1392,7 @@ func tabRefresh(tid *NodeID) TabMgrErrno {
 
 			for _, dbn := range seeds {
 
-				if tabShouldBoundDbNode(NodeID(dbn.ID)) == false {
+				if tabMgr.tabShouldBoundDbNode(NodeID(dbn.ID)) == false {
 
 					var umNode = um.Node {
 						IP:		dbn.IP,
@@ -1329,7 +1401,7 @@ func tabRefresh(tid *NodeID) TabMgrErrno {
 						NodeId:	dbn.ID,
 					}
 
-					if eno := tabDiscoverResp(&umNode); eno != TabMgrEnoNone {
+					if eno := tabMgr.tabDiscoverResp(&umNode); eno != TabMgrEnoNone {
 						yclog.LogCallerFileLine("tabRefresh: " +
 							"tabDiscoverResp failed, eno: %d",
 							eno)
@@ -1364,7 +1436,7 @@ func tabRefresh(tid *NodeID) TabMgrErrno {
 
 	var eno TabMgrErrno
 
-	if eno := tabQuery(&target, nodes); eno != TabMgrEnoNone {
+	if eno := tabMgr.tabQuery(&target, nodes); eno != TabMgrEnoNone {
 		yclog.LogCallerFileLine("tabRefresh: tabQuery failed, eno: %d", eno)
 	} else {
 		tabMgr.refreshing = true
@@ -1377,7 +1449,7 @@ func tabRefresh(tid *NodeID) TabMgrErrno {
 // Caculate the distance between two nodes.
 // Notice: the return "d" more larger, it's more closer
 //
-func tabLog2Dist(h1 Hash, h2 Hash) int {
+func (tabMgr *tableManager) tabLog2Dist(h1 Hash, h2 Hash) int {
 	var d = 0
 	for i, b := range h2 {
 		delta := tabMgr.dlkTab[h1[i] ^ b]
@@ -1392,7 +1464,7 @@ func tabLog2Dist(h1 Hash, h2 Hash) int {
 //
 // Get nodes closest to target
 //
-func tabClosest(target NodeID, size int) []*Node {
+func (tabMgr *tableManager) tabClosest(target NodeID, size int) []*Node {
 
 	//
 	// Notice: in this function, we got []*Node with a approximate order,
@@ -1412,7 +1484,7 @@ func tabClosest(target NodeID, size int) []*Node {
 	}
 
 	ht := tabNodeId2Hash(target)
-	dt := tabLog2Dist(tabMgr.shaLocal, *ht)
+	dt := tabMgr.tabLog2Dist(tabMgr.shaLocal, *ht)
 
 	var addClosest = func (bk *bucket) int {
 
@@ -1483,7 +1555,7 @@ func tabClosest(target NodeID, size int) []*Node {
 //
 // Fetch seeds from node database
 //
-func tabSeedsFromDb(size int, age time.Duration) []*Node {
+func (tabMgr *tableManager) tabSeedsFromDb(size int, age time.Duration) []*Node {
 
 	if size == 0 {
 		yclog.LogCallerFileLine("tabSeedsFromDb: invalid zero size")
@@ -1514,7 +1586,7 @@ func tabSeedsFromDb(size int, age time.Duration) []*Node {
 //
 // Query nodes
 //
-func tabQuery(target *NodeID, nodes []*Node) TabMgrErrno {
+func (tabMgr *tableManager) tabQuery(target *NodeID, nodes []*Node) TabMgrErrno {
 
 	//
 	// check: since we apply doing best to active more, it's impossible that the active
@@ -1608,7 +1680,7 @@ func tabQuery(target *NodeID, nodes []*Node) TabMgrErrno {
 
 			msg.Target		= ycfg.NodeID(*target)
 			msg.Id			= uint64(time.Now().UnixNano())
-			msg.Expiration	= 0
+			msg.Expiration	= um.UdpMsgExpiration()
 			msg.Extra		= nil
 
 			if eno := sch.SchinfMakeMessage(&schMsg, tabMgr.ptnMe, tabMgr.ptnNgbMgr, sch.EvNblFindNodeReq, msg);
@@ -1622,7 +1694,7 @@ func tabQuery(target *NodeID, nodes []*Node) TabMgrErrno {
 				return TabMgrEnoScheduler
 			}
 
-			if eno := tabStartTimer(icb, sch.TabFindNodeTimerId, findNodeExpiration); eno != TabMgrEnoNone {
+			if eno := tabMgr.tabStartTimer(icb, sch.TabFindNodeTimerId, findNodeExpiration); eno != TabMgrEnoNone {
 				yclog.LogCallerFileLine("tabQuery: tabStartTimer failed, eno: %d", eno)
 				return eno
 			}
@@ -1685,7 +1757,7 @@ func tabQuery(target *NodeID, nodes []*Node) TabMgrErrno {
 //
 // Find active instance by node
 //
-func tabFindInst(node *um.Node, state int) *instCtrlBlock {
+func (tabMgr *tableManager) tabFindInst(node *um.Node, state int) *instCtrlBlock {
 
 	if node == nil {
 		yclog.LogCallerFileLine("tabFindInst: invalid parameters")
@@ -1726,7 +1798,7 @@ func tabFindInst(node *um.Node, state int) *instCtrlBlock {
 //
 // Update node database for FindNode procedure
 //
-func tabUpdateNodeDb4Query(inst *instCtrlBlock, result int) TabMgrErrno {
+func (tabMgr *tableManager) tabUpdateNodeDb4Query(inst *instCtrlBlock, result int) TabMgrErrno {
 
 	//
 	// The logic:
@@ -1803,7 +1875,7 @@ func tabUpdateNodeDb4Query(inst *instCtrlBlock, result int) TabMgrErrno {
 //
 // Update node database for Pingpong procedure
 //
-func tabUpdateNodeDb4Bounding(pn *Node, pit *time.Time, pot *time.Time) TabMgrErrno {
+func (tabMgr *tableManager) tabUpdateNodeDb4Bounding(pn *Node, pit *time.Time, pot *time.Time) TabMgrErrno {
 
 	if node := tabMgr.nodeDb.node(NodeID(pn.ID)); node == nil {
 
@@ -1856,7 +1928,7 @@ func tabUpdateNodeDb4Bounding(pn *Node, pit *time.Time, pot *time.Time) TabMgrEr
 
 // Update buckets
 //
-func tabUpdateBucket(inst *instCtrlBlock, result int) TabMgrErrno {
+func (tabMgr *tableManager) tabUpdateBucket(inst *instCtrlBlock, result int) TabMgrErrno {
 
 	if inst == nil {
 		yclog.LogCallerFileLine("tabUpdateBucket: invaliNd parameters")
@@ -1882,9 +1954,9 @@ func tabUpdateBucket(inst *instCtrlBlock, result int) TabMgrErrno {
 
 		id := NodeID(inst.req.(*um.FindNode).To.NodeId)
 
-		if eno = tabBucketUpdateFailCounter(id, +1); eno == TabMgrEnoRemove {
+		if eno = tabMgr.tabBucketUpdateFailCounter(id, +1); eno == TabMgrEnoRemove {
 
-			return tabBucketRemoveNode(id)
+			return tabMgr.tabBucketRemoveNode(id)
 		}
 
 		return eno
@@ -1893,12 +1965,12 @@ func tabUpdateBucket(inst *instCtrlBlock, result int) TabMgrErrno {
 
 		node := &inst.req.(*um.Ping).To
 		inst.pot = time.Now()
-		return TabBucketAddNode(node, &inst.pit, &inst.pot)
+		return TabBucketAddNode(tabMgr.sdl, node, &inst.pit, &inst.pot)
 
 	case (inst.state == TabInstStateBonding || inst.state == TabInstStateBTimeout) && result != TabMgrEnoNone:
 
 		node := &inst.req.(*um.Ping).To
-		return TabBucketAddNode(node, &inst.pit,nil)
+		return TabBucketAddNode(tabMgr.sdl, node, &inst.pit,nil)
 
 	default:
 
@@ -1918,7 +1990,7 @@ func tabUpdateBucket(inst *instCtrlBlock, result int) TabMgrErrno {
 // received, a Pong sent firstly), and when Pong recvied, it is sent to here the
 // table manager task, see Ping, Pong handler in file neighbor.go for details pls.
 //
-func tabUpdateBootstarpNode(n *um.Node) TabMgrErrno {
+func (tabMgr *tableManager) tabUpdateBootstarpNode(n *um.Node) TabMgrErrno {
 
 	if n == nil {
 		yclog.LogCallerFileLine("tabUpdateBootstarpNode: invalid parameters")
@@ -1959,13 +2031,13 @@ func tabUpdateBootstarpNode(n *um.Node) TabMgrErrno {
 		NodeId:	node.ID,
 	}
 
-	return TabBucketAddNode(&umn, &now, &now)
+	return TabBucketAddNode(tabMgr.sdl, &umn, &now, &now)
 }
 
 //
 // Start timer according instance, timer type, and duration
 //
-func tabStartTimer(inst *instCtrlBlock, tmt int, dur time.Duration) TabMgrErrno {
+func (tabMgr *tableManager) tabStartTimer(inst *instCtrlBlock, tmt int, dur time.Duration) TabMgrErrno {
 
 	if tmt != sch.TabRefreshTimerId && inst == nil {
 		yclog.LogCallerFileLine("tabStartTimer: invalid parameters")
@@ -2017,10 +2089,10 @@ func tabStartTimer(inst *instCtrlBlock, tmt int, dur time.Duration) TabMgrErrno
 //
 // Find node in buckets
 //
-func tabBucketFindNode(id NodeID) (int, int, TabMgrErrno) {
+func (tabMgr *tableManager) tabBucketFindNode(id NodeID) (int, int, TabMgrErrno) {
 
 	h := tabNodeId2Hash(id)
-	d := tabLog2Dist(tabMgr.shaLocal, *h)
+	d := tabMgr.tabLog2Dist(tabMgr.shaLocal, *h)
 	b := tabMgr.buckets[d]
 
 	if nidx, eno := b.findNode(id); eno == TabMgrEnoNone {
@@ -2033,9 +2105,9 @@ func tabBucketFindNode(id NodeID) (int, int, TabMgrErrno) {
 //
 // Remove node from bucket
 //
-func tabBucketRemoveNode(id NodeID) TabMgrErrno {
+func (tabMgr *tableManager) tabBucketRemoveNode(id NodeID) TabMgrErrno {
 
-	bidx, nidx, eno := tabBucketFindNode(id)
+	bidx, nidx, eno := tabMgr.tabBucketFindNode(id)
 
 	if eno != TabMgrEnoNone {
 
@@ -2060,9 +2132,9 @@ func tabBucketRemoveNode(id NodeID) TabMgrErrno {
 //
 // Update FindNode failed counter
 //
-func tabBucketUpdateFailCounter(id NodeID, delta int) TabMgrErrno {
+func (tabMgr *tableManager) tabBucketUpdateFailCounter(id NodeID, delta int) TabMgrErrno {
 
-	bidx, nidx, eno := tabBucketFindNode(id)
+	bidx, nidx, eno := tabMgr.tabBucketFindNode(id)
 
 	if eno != TabMgrEnoNone {
 
@@ -2085,9 +2157,9 @@ func tabBucketUpdateFailCounter(id NodeID, delta int) TabMgrErrno {
 //
 // Update pingpong time
 //
-func tabBucketUpdateBoundTime(id NodeID, pit *time.Time, pot *time.Time) TabMgrErrno {
+func (tabMgr *tableManager) tabBucketUpdateBoundTime(id NodeID, pit *time.Time, pot *time.Time) TabMgrErrno {
 
-	bidx, nidx, eno := tabBucketFindNode(id)
+	bidx, nidx, eno := tabMgr.tabBucketFindNode(id)
 
 	if eno != TabMgrEnoNone {
 
@@ -2244,7 +2316,7 @@ func (b *bucket) eldestPong(src []*bucketEntry) ([]*bucketEntry) {
 //
 // Add node to bucket
 //
-func tabBucketAddNode(n *um.Node, lastPing *time.Time, lastPong *time.Time) TabMgrErrno {
+func (tabMgr *tableManager) tabBucketAddNode(n *um.Node, lastPing *time.Time, lastPong *time.Time) TabMgrErrno {
 
 	//
 	// node must be pinged can it be added into a bucket, if pong does not received
@@ -2261,6 +2333,19 @@ func tabBucketAddNode(n *um.Node, lastPing *time.Time, lastPong *time.Time) TabM
 		lastPong = &veryOld
 	}
 
+	//
+	// nodes rejected by the deny and allow lists are never put into buckets
+	//
+
+	if !acl.AclCheck(tabMgr.sdl, &n.NodeId, n.IP) {
+
+		yclog.LogCallerFileLine("tabBucketAddNode: " +
+			"rejected by acl, node: %s",
+			fmt.Sprintf("%X", n.NodeId))
+
+		return TabMgrEnoRejected
+	}
+
 	//
 	// locate bucket for node
 	//
@@ -2269,7 +2354,7 @@ func tabBucketAddNode(n *um.Node, lastPing *time.Time, lastPong *time.Time) TabM
 
 	id := NodeID(n.NodeId)
 	h := tabNodeId2Hash(id)
-	d := tabLog2Dist(tabMgr.shaLocal, *h)
+	d := tabMgr.tabLog2Dist(tabMgr.shaLocal, *h)
 	b := tabMgr.buckets[d]
 
 	//
@@ -2379,7 +2464,7 @@ kickSelected:
 //
 // Delete active query instance
 //
-func tabDeleteActiveQueryInst(inst *instCtrlBlock) TabMgrErrno {
+func (tabMgr *tableManager) tabDeleteActiveQueryInst(inst *instCtrlBlock) TabMgrErrno {
 
 	if inst == nil {
 		yclog.LogCallerFileLine("tabDeleteActiveQueryInst: invalid parameters")
@@ -2422,7 +2507,7 @@ func tabDeleteActiveQueryInst(inst *instCtrlBlock) TabMgrErrno {
 //
 // Active query instance
 //
-func tabActiveQueryInst() TabMgrErrno {
+func (tabMgr *tableManager) tabActiveQueryInst() TabMgrErrno {
 
 	//
 	// check if we can activate more
@@ -2486,7 +2571,7 @@ func tabActiveQueryInst() TabMgrErrno {
 		// Do query
 		//
 
-		if eno := tabQuery(p.target, nodes); eno != TabMgrEnoNone {
+		if eno := tabMgr.tabQuery(p.target, nodes); eno != TabMgrEnoNone {
 
 			yclog.LogCallerFileLine("tabActiveQueryInst: tabQuery failed, eno: %d", eno)
 			return eno
@@ -2502,7 +2587,7 @@ func tabActiveQueryInst() TabMgrErrno {
 //
 // Delete active bound instance
 //
-func tabDeleteActiveBoundInst(inst *instCtrlBlock) TabMgrErrno {
+func (tabMgr *tableManager) tabDeleteActiveBoundInst(inst *instCtrlBlock) TabMgrErrno {
 
 	if inst == nil {
 		yclog.LogCallerFileLine("tabDeleteActiveBoundInst: invalid parameters")
@@ -2542,7 +2627,7 @@ func tabDeleteActiveBoundInst(inst *instCtrlBlock) TabMgrErrno {
 //
 // Add pending bound instance for node
 //
-func tabAddPendingBoundInst(node *um.Node) TabMgrErrno {
+func (tabMgr *tableManager) tabAddPendingBoundInst(node *um.Node) TabMgrErrno {
 
 	if node == nil {
 		yclog.LogCallerFileLine("tabAddPendingBoundInst: invalid parameters")
@@ -2580,7 +2665,7 @@ func tabAddPendingBoundInst(node *um.Node) TabMgrErrno {
 //
 // Active bound instance
 //
-func tabActiveBoundInst() TabMgrErrno {
+func (tabMgr *tableManager) tabActiveBoundInst() TabMgrErrno {
 
 	if len(tabMgr.boundIcb) == TabInstBondingMax {
 		yclog.LogCallerFileLine("tabActiveBoundInst: active bounding table is full")
@@ -2634,7 +2719,7 @@ func tabActiveBoundInst() TabMgrErrno {
 		// Check if bounding needed
 		//
 
-		if tabShouldBound(NodeID(pn.ID)) == false {
+		if tabMgr.tabShouldBound(NodeID(pn.ID)) == false {
 
 			tabMgr.boundPending = append(tabMgr.boundPending[:0], tabMgr.boundPending[1:]...)
 
@@ -2651,7 +2736,7 @@ func tabActiveBoundInst() TabMgrErrno {
 				NodeId:	pn.ID,
 			}
 
-			if eno := tabDiscoverResp(&umNode); eno != TabMgrEnoNone {
+			if eno := tabMgr.tabDiscoverResp(&umNode); eno != TabMgrEnoNone {
 
 				yclog.LogCallerFileLine("tabActiveBoundInst: " +
 					"tabDiscoverResp failed, eno: %d",
@@ -2674,7 +2759,7 @@ func tabActiveBoundInst() TabMgrErrno {
 				TCP:	pn.Node.TCP,
 				NodeId:	pn.Node.ID,
 			},
-			Expiration:	0,
+			Expiration:	um.UdpMsgExpiration(),
 			Id: 		uint64(time.Now().UnixNano()),
 			Extra:		nil,
 		}
@@ -2710,7 +2795,7 @@ func tabActiveBoundInst() TabMgrErrno {
 		pot	:= time.Time{}
 		pit := time.Now()
 
-		if eno := tabBucketUpdateBoundTime(NodeID(pn.ID), &pit, &pot);
+		if eno := tabMgr.tabBucketUpdateBoundTime(NodeID(pn.ID), &pit, &pot);
 		eno != TabMgrEnoNone && eno != TabMgrEnoNotFound {
 
 			yclog.LogCallerFileLine("tabActiveBoundInst: " +
@@ -2724,7 +2809,7 @@ func tabActiveBoundInst() TabMgrErrno {
 		// Update node database for pingpong related info
 		//
 
-		if eno := tabUpdateNodeDb4Bounding(pn, &pit, &pot);
+		if eno := tabMgr.tabUpdateNodeDb4Bounding(pn, &pit, &pot);
 		eno != TabMgrEnoNone {
 
 			yclog.LogCallerFileLine("tabActiveBoundInst: " +
@@ -2747,7 +2832,7 @@ func tabActiveBoundInst() TabMgrErrno {
 			return TabMgrEnoScheduler
 		}
 
-		if eno := tabStartTimer(icb, sch.TabPingpongTimerId, pingpongExpiration);
+		if eno := tabMgr.tabStartTimer(icb, sch.TabPingpongTimerId, pingpongExpiration);
 		eno != TabMgrEnoNone {
 
 			yclog.LogCallerFileLine("tabActiveBoundInst: " +
@@ -2767,7 +2852,7 @@ func tabActiveBoundInst() TabMgrErrno {
 //
 // Send respone to discover task for a bounded node
 //
-func tabDiscoverResp(node *um.Node) TabMgrErrno {
+func (tabMgr *tableManager) tabDiscoverResp(node *um.Node) TabMgrErrno {
 
 	if node == nil {
 		yclog.LogCallerFileLine("tabDiscoverResp: invalid parameter")
@@ -2809,7 +2894,7 @@ func tabDiscoverResp(node *um.Node) TabMgrErrno {
 //
 // Check should bounding procedure inited for a node
 //
-func tabShouldBound(id NodeID) bool {
+func (tabMgr *tableManager) tabShouldBound(id NodeID) bool {
 
 	//
 	// If node specified not found in database, bounding needed
@@ -2836,8 +2921,8 @@ func tabShouldBound(id NodeID) bool {
 //
 // Check if node from database needs to be bound
 //
-func tabShouldBoundDbNode(id NodeID) bool {
-	return tabShouldBound(id)
+func (tabMgr *tableManager) tabShouldBoundDbNode(id NodeID) bool {
+	return tabMgr.tabShouldBound(id)
 }
 
 //
@@ -2845,17 +2930,22 @@ func tabShouldBoundDbNode(id NodeID) bool {
 // Notice: inside the table manager task, this function MUST NOT be called,
 // since we had obtain the lock at the entry of the task handler.
 //
-func TabBucketAddNode(n *um.Node, lastPing *time.Time, lastPong *time.Time) TabMgrErrno {
+func TabBucketAddNode(sdl *sch.Scheduler, n *um.Node, lastPing *time.Time, lastPong *time.Time) TabMgrErrno {
 
 	//
 	// We would be called by other task, we need to lock and
 	// defer unlock.
 	//
 
+	var tabMgr = tabGetManager(sdl)
+	if tabMgr == nil {
+		return TabMgrEnoNotFound
+	}
+
 	tabMgr.lock.Lock()
 	defer tabMgr.lock.Unlock()
 
-	return tabBucketAddNode(n, lastPing, lastPong)
+	return tabMgr.tabBucketAddNode(n, lastPing, lastPong)
 }
 
 
@@ -2864,7 +2954,7 @@ func TabBucketAddNode(n *um.Node, lastPing *time.Time, lastPong *time.Time) TabM
 // Notice: inside the table manager task, this function MUST NOT be called,
 // since we had obtain the lock at the entry of the task handler.
 //
-func TabUpdateNode(umn *um.Node) TabMgrErrno {
+func TabUpdateNode(sdl *sch.Scheduler, umn *um.Node) TabMgrErrno {
 
 	//
 	// We would be called by other task, we need to lock and
@@ -2879,6 +2969,11 @@ func TabUpdateNode(umn *um.Node) TabMgrErrno {
 		return TabMgrEnoParameter
 	}
 
+	var tabMgr = tabGetManager(sdl)
+	if tabMgr == nil || tabMgr.nodeDb == nil {
+		return TabMgrEnoNotFound
+	}
+
 	tabMgr.lock.Lock()
 	defer tabMgr.lock.Unlock()
 
@@ -2909,17 +3004,22 @@ func TabUpdateNode(umn *um.Node) TabMgrErrno {
 // Notice: inside the table manager task, this function MUST NOT be called,
 // since we had obtain the lock at the entry of the task handler.
 //
-func TabClosest(target NodeID, size int) []*Node {
+func TabClosest(sdl *sch.Scheduler, target NodeID, size int) []*Node {
 
 	//
 	// We would be called by other task, we need to lock and
 	// defer unlock.
 	//
 
+	var tabMgr = tabGetManager(sdl)
+	if tabMgr == nil {
+		return nil
+	}
+
 	tabMgr.lock.Lock()
 	defer tabMgr.lock.Unlock()
 
-	return tabClosest(target, size)
+	return tabMgr.tabClosest(target, size)
 }
 
 //
@@ -22,6 +22,10 @@ package udpmsg
 
 import (
 	"net"
+	"time"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
 	yclog	"github.com/yeeco/p2p/logger"
 	ycfg	"github.com/yeeco/p2p/config"
 	pb		"github.com/yeeco/p2p/discover/udpmsg/pb"
@@ -58,8 +62,9 @@ type (
 	}
 
 	//
-	// Notice: if (Expiration == 0) is true, it would be never expired
-	// for a message.
+	// Notice: Expiration is the time in unix seconds a message expires at,
+	// see UdpMsgExpiration; messages with zero Expiration, which would be
+	// never expired, are rejected, see Decode.
 	//
 
 	// Ping
@@ -106,29 +111,38 @@ type (
 // protobuf message. for decoding, protobuf message will be extract from
 // the raw one; for encoding, bytes will be wriiten into raw buffer.
 //
-// Notice: since we would only one UDP reader for descovering, we can put
-// an UdpMsg instance here for income messages decoding, but for outcome
-// message decoding, since multiple instances might be activated, each
-// should obtain its' own encoder.
+// Notice: since we would only one UDP reader for descovering in a p2p
+// instance, the reader can own an UdpMsg for income messages decoding, but
+// for outcome message encoding, since multiple instances might be activated,
+// each should obtain its' own encoder, see NewUdpMsg.
 //
 type UdpMsg struct {
-	Pbuf	*[]byte			// buffer pointer
-	Len		int				// bytes buffered
-	From	*net.UDPAddr	// source address from underlying network library
-	Msg		pb.UdpMessage	// protobuf message
-	Eno		UdpMsgErrno		// current errno
+	Pbuf	*[]byte				// buffer pointer
+	Len		int					// bytes buffered
+	From	*net.UDPAddr		// source address from underlying network library
+	Msg		pb.UdpMessage		// protobuf message
+	Eno		UdpMsgErrno			// current errno
+	signKey	*ecdsa.PrivateKey	// key for signing outcome messages
+	network	[]byte				// tag of network, see ycfg.P2pNetworkTag
 }
 
-var udpMsg = UdpMsg {
-	Pbuf:	nil,
-	Len:	0,
-	From:	nil,
-	Msg:	pb.UdpMessage{},
-	Eno:	UdpMsgEnoUnknown,
+//
+// Create an UdpMsg, the key would be applied to sign messages encoded by it,
+// it can be nil if the UdpMsg is for decoding only. The network tag is mixed
+// into digests signed and verified, nil for the default network.
+//
+func NewUdpMsg(key *ecdsa.PrivateKey, network []byte) *UdpMsg {
+	return &UdpMsg {
+		Pbuf:		nil,
+		Len:		0,
+		From:		nil,
+		Msg:		pb.UdpMessage{},
+		Eno:		UdpMsgEnoUnknown,
+		signKey:	key,
+		network:	network,
+	}
 }
 
-var PtrUdpMsg = &udpMsg
-
 const (
 	UdpMsgEnoNone 		= iota
 	UdpMsgEnoParameter
@@ -136,10 +150,138 @@ const (
 	UdpMsgEnoDecodeFailed
 	UdpMsgEnoMessage
 	UdpMsgEnoUnknown
+	UdpMsgEnoSignature
+	UdpMsgEnoExpired
 )
 
 type UdpMsgErrno int
 
+//
+// Envelope for signed UDP message, like:
+//
+//	hash(32 bytes) | signature(64 bytes) | protobuf message
+//
+// where hash = sha256(signature | protobuf message) for integrity, and the
+// signature is over sha256(network tag | protobuf message) by the sender's
+// node key. The receiver takes the public key from the "From.NodeId" of the
+// message, and checks the signature with it, so nobody can forge a message
+// for others, and messages from other networks are dropped.
+//
+const (
+	udpMsgHashSize		= sha256.Size
+	udpMsgSigSize		= ycfg.P2pSignatureBytes
+	udpMsgHeadSize		= udpMsgHashSize + udpMsgSigSize
+)
+
+//
+// Life time of messages. Since messages signed can be replayed by anyone,
+// Decode rejects those expired, or expiring too far in the future, and the
+// max life time is a bit longer than the one for senders to tolerate some
+// clock drifts between nodes.
+//
+const (
+	UdpMsgLifetime		= 20 * time.Second
+	udpMsgMaxLifetime	= 3 * UdpMsgLifetime
+)
+
+//
+// Get expiration for a message to be sent now
+//
+func UdpMsgExpiration() uint64 {
+	return uint64(time.Now().Add(UdpMsgLifetime).Unix())
+}
+
+//
+// Digest of protobuf message for signing
+//
+func (pum *UdpMsg) digest(payload []byte) []byte {
+	h := sha256.New()
+	h.Write(pum.network)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+//
+// Put the encoded protobuf message into a signed envelope
+//
+func (pum *UdpMsg) wrapEnvelope(payload []byte) UdpMsgErrno {
+
+	if pum.signKey == nil {
+		yclog.LogCallerFileLine("wrapEnvelope: sign key not set")
+		return UdpMsgEnoSignature
+	}
+
+	sig := ycfg.P2pSign(pum.signKey, pum.digest(payload))
+
+	if sig == nil {
+		yclog.LogCallerFileLine("wrapEnvelope: P2pSign failed")
+		return UdpMsgEnoSignature
+	}
+
+	buf := make([]byte, udpMsgHeadSize, udpMsgHeadSize + len(payload))
+	copy(buf[udpMsgHashSize:], sig)
+	buf = append(buf, payload...)
+	hash := sha256.Sum256(buf[udpMsgHashSize:])
+	copy(buf[0:udpMsgHashSize], hash[:])
+
+	pum.Pbuf = &buf
+	pum.Len = len(buf)
+
+	return UdpMsgEnoNone
+}
+
+//
+// Get sender node identity from decoded message
+//
+func (pum *UdpMsg) getSenderId() ([]byte, bool) {
+
+	var from *pb.UdpMessage_Node
+
+	switch pum.Msg.GetMsgType() {
+
+	case pb.UdpMessage_PING:
+		from = pum.Msg.GetPing().GetFrom()
+
+	case pb.UdpMessage_PONG:
+		from = pum.Msg.GetPong().GetFrom()
+
+	case pb.UdpMessage_FINDNODE:
+		from = pum.Msg.GetFindNode().GetFrom()
+
+	case pb.UdpMessage_NEIGHBORS:
+		from = pum.Msg.GetNeighbors().GetFrom()
+	}
+
+	if from == nil || len(from.NodeId) != ycfg.NodeIDBytes {
+		return nil, false
+	}
+
+	return from.NodeId, true
+}
+
+//
+// Get expiration from decoded message
+//
+func (pum *UdpMsg) getExpiration() uint64 {
+
+	switch pum.Msg.GetMsgType() {
+
+	case pb.UdpMessage_PING:
+		return pum.Msg.GetPing().GetExpiration()
+
+	case pb.UdpMessage_PONG:
+		return pum.Msg.GetPong().GetExpiration()
+
+	case pb.UdpMessage_FINDNODE:
+		return pum.Msg.GetFindNode().GetExpiration()
+
+	case pb.UdpMessage_NEIGHBORS:
+		return pum.Msg.GetNeighbors().GetExpiration()
+	}
+
+	return 0
+}
+
 //
 // Set raw message
 //
@@ -163,7 +305,35 @@ func (pum *UdpMsg) SetRawMessage(pbuf *[]byte, bytes int, from *net.UDPAddr) Udp
 //
 func (pum *UdpMsg) Decode() UdpMsgErrno {
 
-	if err := (&pum.Msg).Unmarshal((*pum.Pbuf)[0:pum.Len]); err != nil {
+	//
+	// check the envelope
+	//
+
+	if pum.Len <= udpMsgHeadSize {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"message too short, length: %d",
+			pum.Len)
+
+		return UdpMsgEnoDecodeFailed
+	}
+
+	raw := (*pum.Pbuf)[0:pum.Len]
+	hash := sha256.Sum256(raw[udpMsgHashSize:])
+
+	if bytes.Equal(hash[:], raw[0:udpMsgHashSize]) != true {
+		yclog.LogCallerFileLine("Decode: hash mismatched")
+		return UdpMsgEnoDecodeFailed
+	}
+
+	sig := raw[udpMsgHashSize:udpMsgHeadSize]
+	payload := raw[udpMsgHeadSize:]
+
+	//
+	// decode protobuf message
+	//
+
+	if err := (&pum.Msg).Unmarshal(payload); err != nil {
 
 		yclog.LogCallerFileLine("Decode: " +
 			"Unmarshal failed, err: %s",
@@ -172,6 +342,53 @@ func (pum *UdpMsg) Decode() UdpMsgErrno {
 		return UdpMsgEnoDecodeFailed
 	}
 
+	//
+	// check signature against the node identity the sender claimed
+	//
+
+	nid, ok := pum.getSenderId()
+	if !ok {
+		yclog.LogCallerFileLine("Decode: invalid sender node identity")
+		return UdpMsgEnoMessage
+	}
+
+	var id ycfg.NodeID
+	copy(id[:], nid)
+
+	if ycfg.P2pVerify(id, pum.digest(payload), sig) != true {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"signature mismatched, forged or from other network, sender: %s",
+			ycfg.P2pNodeId2HexString(id))
+
+		return UdpMsgEnoSignature
+	}
+
+	//
+	// check expiration, messages replayed are dropped here
+	//
+
+	exp := pum.getExpiration()
+	now := time.Now()
+
+	if exp == 0 || exp > uint64(now.Add(udpMsgMaxLifetime).Unix()) {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"expiration too far, expiration: %d, sender: %s",
+			exp, ycfg.P2pNodeId2HexString(id))
+
+		return UdpMsgEnoExpired
+	}
+
+	if exp < uint64(now.Unix()) {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"expired, expiration: %d, sender: %s",
+			exp, ycfg.P2pNodeId2HexString(id))
+
+		return UdpMsgEnoExpired
+	}
+
 	return UdpMsgEnoNone
 }
 
@@ -257,6 +474,22 @@ func (pum *UdpMsg) GetDecodedMsgType() UdpMsgType {
 	return val
 }
 
+//
+// Get sender node identity of decoded message
+//
+func (pum *UdpMsg) GetDecodedSenderId() (*ycfg.NodeID, bool) {
+
+	nid, ok := pum.getSenderId()
+	if !ok {
+		return nil, false
+	}
+
+	var id ycfg.NodeID
+	copy(id[:], nid)
+
+	return &id, true
+}
+
 //
 // Get decoded Ping
 //
@@ -375,34 +608,65 @@ func (pum *UdpMsg) GetNeighbors() interface{} {
 func (pum *UdpMsg) CheckUdpMsgFromPeer(from *net.UDPAddr) bool {
 
 	//
-	// We just check the ip address simply now, more might be needed.
-	// Also notice that, only IPV4 supported currently.
+	// We just check the ip address simply now, more might be needed. The
+	// address claimed must be the one the message comes from, or unspecified
+	// of the same family for a node listening on all addresses, see function
+	// UdpMsgFromIP, in which case the caller should take the source address.
+	// Both IPv4 and IPv6 are supported, and an IPv4-mapped IPv6 address, got
+	// from dual-stack socket, equals to its' IPv4 form.
 	//
 
-	var ipv4 = net.IPv4zero
+	var ip net.IP
 
 	if *pum.Msg.MsgType == pb.UdpMessage_PING {
 
-		ipv4 = net.IP(pum.Msg.Ping.From.IP).To4()
+		ip = net.IP(pum.Msg.Ping.From.IP)
 
 	} else if *pum.Msg.MsgType == pb.UdpMessage_PONG  {
 
-		ipv4 = net.IP(pum.Msg.Pong.From.IP).To4()
+		ip = net.IP(pum.Msg.Pong.From.IP)
 
 	} else if *pum.Msg.MsgType == pb.UdpMessage_FINDNODE {
 
-		ipv4 = net.IP(pum.Msg.FindNode.From.IP).To4()
+		ip = net.IP(pum.Msg.FindNode.From.IP)
 
 	} else if *pum.Msg.MsgType == pb.UdpMessage_NEIGHBORS {
 
-		ipv4 = net.IP(pum.Msg.Neighbors.From.IP).To4()
+		ip = net.IP(pum.Msg.Neighbors.From.IP)
 
 	} else {
 
 		return false
 	}
 
-	return ipv4.Equal(from.IP.To4())
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		return false
+	}
+
+	if ip.IsUnspecified() {
+		return (ip.To4() != nil) == (from.IP.To4() != nil)
+	}
+
+	return ip.Equal(from.IP)
+}
+
+//
+// Get the ip address to be claimed as "From" in messages sent to a node. A
+// node listening on all addresses claims the unspecified address of family
+// the same as the destination, for the message would be sent from an address
+// of that family, see CheckUdpMsgFromPeer.
+//
+func UdpMsgFromIP(local net.IP, to net.IP) net.IP {
+
+	if !local.IsUnspecified() {
+		return local
+	}
+
+	if to.To4() != nil {
+		return net.IPv4zero.To4()
+	}
+
+	return net.IPv6unspecified
 }
 
 //
@@ -413,8 +677,9 @@ func (pum *UdpMsg) CheckUdpMsgFromPeer(from *net.UDPAddr) bool {
 func (pum *UdpMsg) EncodePbMsg() UdpMsgErrno {
 
 	var err error
+	var buf []byte
 
-	if *pum.Pbuf, err = (&pum.Msg).Marshal(); err != nil {
+	if buf, err = (&pum.Msg).Marshal(); err != nil {
 
 		yclog.LogCallerFileLine("Encode: " +
 			"Marshal failed, err: %s",
@@ -425,7 +690,7 @@ func (pum *UdpMsg) EncodePbMsg() UdpMsgErrno {
 		return pum.Eno
 	}
 
-	pum.Eno = UdpMsgEnoNone
+	pum.Eno = pum.wrapEnvelope(buf)
 
 	return pum.Eno
 }
@@ -516,10 +781,7 @@ func (pum *UdpMsg) EncodePing(ping *Ping) UdpMsgErrno {
 		return UdpMsgEnoEncodeFailed
 	}
 
-	pum.Pbuf = &buf
-	pum.Len = len(buf)
-
-	return UdpMsgEnoNone
+	return pum.wrapEnvelope(buf)
 }
 
 //
@@ -575,10 +837,7 @@ func (pum *UdpMsg) EncodePong(pong *Pong) UdpMsgErrno {
 		return UdpMsgEnoEncodeFailed
 	}
 
-	pum.Pbuf = &buf
-	pum.Len = len(buf)
-
-	return UdpMsgEnoNone
+	return pum.wrapEnvelope(buf)
 }
 
 //
@@ -647,10 +906,7 @@ func (pum *UdpMsg) EncodeFindNode(fn *FindNode) UdpMsgErrno {
 		return UdpMsgEnoEncodeFailed
 	}
 
-	pum.Pbuf = &buf
-	pum.Len = len(buf)
-
-	return UdpMsgEnoNone
+	return pum.wrapEnvelope(buf)
 }
 
 //
@@ -721,10 +977,7 @@ func (pum *UdpMsg) EncodeNeighbors(ngb *Neighbors) UdpMsgErrno {
 		return UdpMsgEnoEncodeFailed
 	}
 
-	pum.Pbuf = &buf
-	pum.Len = len(buf)
-
-	return UdpMsgEnoNone
+	return pum.wrapEnvelope(buf)
 }
 
 //
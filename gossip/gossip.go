@@ -0,0 +1,870 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gossip
+
+import (
+	"fmt"
+	"time"
+	"math/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/peer"
+	pb		"github.com/yeeco/p2p/peer/pb"
+	gm		"github.com/yeeco/p2p/gossip/gspmsg"
+)
+
+//
+// errno
+//
+const (
+	GspMgrEnoNone	= iota
+	GspMgrEnoParameter
+	GspMgrEnoScheduler
+	GspMgrEnoConfig
+	GspMgrEnoMessage
+	GspMgrEnoPeer
+	GspMgrEnoNotFound
+	GspMgrEnoUnknown
+)
+
+type GspMgrErrno int
+
+//
+// The gossip manager implements a topic based publish/subscribe over the
+// peers advertised PID_GOSSIP in their handshakes. For each topic subscribed
+// locally, a mesh of about gspD peers subscribed to the same topic is kept,
+// messages are pushed along the mesh, and for those peers not in the mesh,
+// identities of recent messages are announced (IHAVE) at each heartbeat, the
+// peers can then pull (IWANT) what they missed. For topics published to but
+// not subscribed locally, a "fanout" set of peers is kept for a while, the
+// messages published are pushed to them.
+//
+// A message is identified by sha256 of its' publisher and the sequence number,
+// and messages seen are remembered for gspSeenTtl, so each message would be
+// delivered and forwarded once at most. A message also carries a hop limit,
+// it's decreased at each forwarding and the message is no longer forwarded
+// when it's exhausted.
+//
+const (
+	gspD				= 6						// desired mesh degree
+	gspDlo				= 4						// lower bound of mesh degree
+	gspDhi				= 12					// upper bound of mesh degree
+	gspDlazy			= 6						// number of peers to gossip to
+	gspHbCycle			= time.Second			// heartbeat interval
+	gspFanoutTtl		= time.Minute			// fanout kept since last publishing
+	gspSeenTtl			= time.Minute * 2		// time to remember messages seen
+	gspHistoryLen		= 5						// heartbeats messages cached for IWANT
+	gspHistoryGossip	= 3						// heartbeats messages announced in IHAVE
+	gspDftHops			= 16					// default hop limit of message published
+	gspMaxHops			= 32					// max hop limit accepted
+	gspMaxIds			= 256					// max message identities in an IHAVE or IWANT
+)
+
+//
+// Message handler of user for a topic
+//
+type GspHandler func(topic string, from ycfg.NodeID, data []byte)
+
+//
+// Message cached
+//
+type gspCached struct {
+	pub			*gm.Publish		// the message
+	topic		string			// topic
+}
+
+//
+// gossip manager
+//
+const GspMgrName = sch.GspMgrName
+
+type gossipManager struct {
+	name		string									// name
+	tep			sch.SchUserTaskEp						// entry
+	sdl			*sch.Scheduler							// pointer to scheduler
+	ptnMe		interface{}								// pointer to myself task node
+	local		ycfg.NodeID								// local node identity
+	tidHb		int										// heartbeat timer identity
+	seqno		uint64									// sequence number of messages published
+	peers		map[ycfg.NodeID]map[string]bool			// gossip peers and topics they subscribed
+	topics		map[string]GspHandler					// topics subscribed locally
+	mesh		map[string]map[ycfg.NodeID]bool			// mesh peers by topic
+	fanout		map[string]map[ycfg.NodeID]bool			// fanout peers by topic
+	fanoutPub	map[string]time.Time					// time of last publishing by fanout topic
+	seen		map[string]time.Time					// messages seen and their expirations
+	mcache		map[string]*gspCached					// messages cached by identity
+	history		[][]string								// identities cached at each heartbeat, newest first
+}
+
+//
+// Create gossip manager, one for each p2p instance
+//
+func NewGspMgr() interface{} {
+
+	var gspMgr = gossipManager{
+		name:		GspMgrName,
+		tep:		GspMgrProc,
+		ptnMe:		nil,
+		tidHb:		sch.SchInvalidTid,
+	}
+
+	gspMgr.gspReset()
+
+	return &gspMgr
+}
+
+//
+// Reset the states
+//
+func (gspMgr *gossipManager) gspReset() {
+	gspMgr.peers = map[ycfg.NodeID]map[string]bool{}
+	gspMgr.topics = map[string]GspHandler{}
+	gspMgr.mesh = map[string]map[ycfg.NodeID]bool{}
+	gspMgr.fanout = map[string]map[ycfg.NodeID]bool{}
+	gspMgr.fanoutPub = map[string]time.Time{}
+	gspMgr.seen = map[string]time.Time{}
+	gspMgr.mcache = map[string]*gspCached{}
+	gspMgr.history = make([][]string, gspHistoryLen)
+}
+
+//
+// gossip manager entry
+//
+func GspMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
+	yclog.LogCallerFileLine("GspMgrProc: scheduled, msg: %d", msg.Id)
+
+	gspMgr := sch.SchinfGetUserDataArea(ptn).(*gossipManager)
+
+	var eno GspMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = gspMgr.gspMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = gspMgr.gspMgrPoweroff(ptn)
+
+	case sch.EvGspHeartbeatTimer:
+		eno = gspMgr.gspHeartbeat()
+
+	case sch.EvGspMgrPeerInd:
+		eno = gspMgr.gspMgrPeerInd(msg.Body.(*sch.MsgGspPeerInd))
+
+	case sch.EvGspMgrPkgInd:
+		eno = gspMgr.gspMgrPkgInd(msg.Body.(*sch.MsgGspPkgInd))
+
+	case sch.EvGspSubscribeReq:
+		eno = gspMgr.gspMgrSubscribeReq(msg.Body.(*sch.MsgGspSubscribeReq))
+
+	case sch.EvGspUnsubscribeReq:
+		eno = gspMgr.gspMgrUnsubscribeReq(msg.Body.(*sch.MsgGspUnsubscribeReq))
+
+	case sch.EvGspPublishReq:
+		eno = gspMgr.gspMgrPublishReq(msg.Body.(*sch.MsgGspPublishReq))
+
+	default:
+		yclog.LogCallerFileLine("GspMgrProc: invalid message: %d", msg.Id)
+		eno = GspMgrEnoParameter
+	}
+
+	if eno != GspMgrEnoNone {
+		yclog.LogCallerFileLine("GspMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
+	return sch.SchEnoNone
+}
+
+//
+// Poweron handler
+//
+func (gspMgr *gossipManager) gspMgrPoweron(ptn interface{}) GspMgrErrno {
+
+	gspMgr.sdl = sch.SchinfGetScheduler(ptn)
+	gspMgr.ptnMe = ptn
+
+	cfg := ycfg.P2pConfig4Gossip(sch.SchinfGetP2pConfig(gspMgr.sdl))
+	if cfg == nil {
+		yclog.LogCallerFileLine("gspMgrPoweron: invalid configuration")
+		return GspMgrEnoConfig
+	}
+
+	gspMgr.local = cfg.Local
+
+	//
+	// sequence numbers start from the current time, so messages published
+	// after a restart would not be taken as those seen by others.
+	//
+
+	gspMgr.seqno = uint64(time.Now().UnixNano())
+
+	var td = sch.TimerDescription {
+		Name:	GspMgrName + "_heartbeat",
+		Utid:	sch.GspHeartbeatTimerId,
+		Tmt:	sch.SchTmTypePeriod,
+		Dur:	gspHbCycle,
+		Extra:	nil,
+	}
+
+	eno, tid := sch.SchInfSetTimer(ptn, &td)
+	if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+		yclog.LogCallerFileLine("gspMgrPoweron: " +
+			"SchInfSetTimer failed, eno: %d, timer: %s",
+			eno, td.Name)
+
+		return GspMgrEnoScheduler
+	}
+
+	gspMgr.tidHb = tid
+
+	return GspMgrEnoNone
+}
+
+//
+// Poweroff handler
+//
+func (gspMgr *gossipManager) gspMgrPoweroff(ptn interface{}) GspMgrErrno {
+
+	if gspMgr.tidHb != sch.SchInvalidTid {
+		sch.SchinfKillTimer(ptn, gspMgr.tidHb)
+		gspMgr.tidHb = sch.SchInvalidTid
+	}
+
+	gspMgr.gspReset()
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return GspMgrEnoUnknown
+	}
+
+	return GspMgrEnoNone
+}
+
+//
+// Peer activated or closed indication handler
+//
+func (gspMgr *gossipManager) gspMgrPeerInd(ind *sch.MsgGspPeerInd) GspMgrErrno {
+
+	id := ind.Node.ID
+
+	switch ind.Ind {
+
+	case peer.P2pIndPeerActivated:
+
+		gspMgr.peers[id] = map[string]bool{}
+
+		//
+		// tell the peer what we had subscribed
+		//
+
+		if len(gspMgr.topics) == 0 {
+			break
+		}
+
+		subs := make([]*gm.Subscription, 0, len(gspMgr.topics))
+		for topic := range gspMgr.topics {
+			subs = append(subs, &gm.Subscription{Topic: topic, Subscribe: true})
+		}
+
+		gspMgr.gspSend([]ycfg.NodeID{id}, &gm.GspMessage{Mid: gm.MID_GSP_SUBSCRIBE, Subscriptions: subs})
+
+	case peer.P2pIndPeerClosed:
+
+		delete(gspMgr.peers, id)
+
+		for _, peers := range gspMgr.mesh {
+			delete(peers, id)
+		}
+
+		for _, peers := range gspMgr.fanout {
+			delete(peers, id)
+		}
+
+	default:
+
+		yclog.LogCallerFileLine("gspMgrPeerInd: " +
+			"invalid indication: %d, peer: %s",
+			ind.Ind, fmt.Sprintf("%X", id))
+
+		return GspMgrEnoParameter
+	}
+
+	return GspMgrEnoNone
+}
+
+//
+// Package from peer indication handler
+//
+func (gspMgr *gossipManager) gspMgrPkgInd(ind *sch.MsgGspPkgInd) GspMgrErrno {
+
+	msg, eno := gm.Decode(ind.Payload)
+	if eno != gm.GspMsgEnoNone {
+
+		yclog.LogCallerFileLine("gspMgrPkgInd: " +
+			"Decode failed, eno: %d, from: %s",
+			eno, fmt.Sprintf("%X", ind.From))
+
+		return GspMgrEnoMessage
+	}
+
+	if _, ok := gspMgr.peers[ind.From]; !ok {
+		gspMgr.peers[ind.From] = map[string]bool{}
+	}
+
+	switch msg.Mid {
+
+	case gm.MID_GSP_SUBSCRIBE:
+		return gspMgr.gspSubscriptionInd(ind.From, msg.Subscriptions)
+
+	case gm.MID_GSP_PUBLISH:
+		return gspMgr.gspPublishInd(ind.From, msg.Publish)
+
+	case gm.MID_GSP_GRAFT:
+		return gspMgr.gspGraftInd(ind.From, msg.Control)
+
+	case gm.MID_GSP_PRUNE:
+		return gspMgr.gspPruneInd(ind.From, msg.Control)
+
+	case gm.MID_GSP_IHAVE:
+		return gspMgr.gspIHaveInd(ind.From, msg.Control)
+
+	case gm.MID_GSP_IWANT:
+		return gspMgr.gspIWantInd(ind.From, msg.Control)
+	}
+
+	yclog.LogCallerFileLine("gspMgrPkgInd: invalid mid: %d", msg.Mid)
+
+	return GspMgrEnoMessage
+}
+
+//
+// Subscribe request handler
+//
+func (gspMgr *gossipManager) gspMgrSubscribeReq(req *sch.MsgGspSubscribeReq) GspMgrErrno {
+
+	if len(req.Topic) == 0 || req.Handler == nil {
+		yclog.LogCallerFileLine("gspMgrSubscribeReq: invalid parameters")
+		return GspMgrEnoParameter
+	}
+
+	if _, dup := gspMgr.topics[req.Topic]; dup {
+		gspMgr.topics[req.Topic] = req.Handler
+		return GspMgrEnoNone
+	}
+
+	gspMgr.topics[req.Topic] = req.Handler
+
+	gspMgr.gspAnnounce(req.Topic, true)
+
+	//
+	// build the mesh: peers in fanout first, then others subscribed
+	//
+
+	mesh := map[ycfg.NodeID]bool{}
+
+	for id := range gspMgr.fanout[req.Topic] {
+		if len(mesh) < gspD {
+			mesh[id] = true
+		}
+	}
+
+	delete(gspMgr.fanout, req.Topic)
+	delete(gspMgr.fanoutPub, req.Topic)
+
+	for _, id := range gspMgr.gspPickPeers(req.Topic, gspD - len(mesh), mesh) {
+		mesh[id] = true
+	}
+
+	gspMgr.mesh[req.Topic] = mesh
+
+	gspMgr.gspControl(gspPeerList(mesh), gm.MID_GSP_GRAFT, req.Topic, nil)
+
+	return GspMgrEnoNone
+}
+
+//
+// Unsubscribe request handler
+//
+func (gspMgr *gossipManager) gspMgrUnsubscribeReq(req *sch.MsgGspUnsubscribeReq) GspMgrErrno {
+
+	if _, ok := gspMgr.topics[req.Topic]; !ok {
+
+		yclog.LogCallerFileLine("gspMgrUnsubscribeReq: " +
+			"not subscribed, topic: %s",
+			req.Topic)
+
+		return GspMgrEnoNotFound
+	}
+
+	delete(gspMgr.topics, req.Topic)
+
+	gspMgr.gspAnnounce(req.Topic, false)
+	gspMgr.gspControl(gspPeerList(gspMgr.mesh[req.Topic]), gm.MID_GSP_PRUNE, req.Topic, nil)
+
+	delete(gspMgr.mesh, req.Topic)
+
+	return GspMgrEnoNone
+}
+
+//
+// Publish request handler. Notice: the message is not delivered to handler
+// of the local node even the topic is subscribed locally.
+//
+func (gspMgr *gossipManager) gspMgrPublishReq(req *sch.MsgGspPublishReq) GspMgrErrno {
+
+	if len(req.Topic) == 0 {
+		yclog.LogCallerFileLine("gspMgrPublishReq: invalid topic")
+		return GspMgrEnoParameter
+	}
+
+	gspMgr.seqno++
+
+	pub := &gm.Publish {
+		From:	gspMgr.local,
+		Seqno:	gspMgr.seqno,
+		Topic:	req.Topic,
+		Data:	req.Data,
+		Hops:	gspDftHops,
+	}
+
+	mid := gspMsgId(pub)
+	gspMgr.seen[mid] = time.Now().Add(gspSeenTtl)
+	gspMgr.gspCache(mid, pub)
+
+	var peers map[ycfg.NodeID]bool
+
+	if _, ok := gspMgr.topics[req.Topic]; ok {
+
+		peers = gspMgr.mesh[req.Topic]
+
+	} else {
+
+		if peers = gspMgr.fanout[req.Topic]; peers == nil {
+			peers = map[ycfg.NodeID]bool{}
+			gspMgr.fanout[req.Topic] = peers
+		}
+
+		if len(peers) < gspD {
+			for _, id := range gspMgr.gspPickPeers(req.Topic, gspD - len(peers), peers) {
+				peers[id] = true
+			}
+		}
+
+		gspMgr.fanoutPub[req.Topic] = time.Now()
+	}
+
+	if len(peers) == 0 {
+
+		yclog.LogCallerFileLine("gspMgrPublishReq: " +
+			"no peers for topic, it would be gossiped later, topic: %s",
+			req.Topic)
+
+		return GspMgrEnoNone
+	}
+
+	return gspMgr.gspSend(gspPeerList(peers), &gm.GspMessage{Mid: gm.MID_GSP_PUBLISH, Publish: pub})
+}
+
+//
+// Subscriptions from peer
+//
+func (gspMgr *gossipManager) gspSubscriptionInd(from ycfg.NodeID, subs []*gm.Subscription) GspMgrErrno {
+
+	topics := gspMgr.peers[from]
+
+	for _, s := range subs {
+
+		if s.Subscribe {
+			topics[s.Topic] = true
+			continue
+		}
+
+		delete(topics, s.Topic)
+
+		if peers, ok := gspMgr.mesh[s.Topic]; ok {
+			delete(peers, from)
+		}
+
+		if peers, ok := gspMgr.fanout[s.Topic]; ok {
+			delete(peers, from)
+		}
+	}
+
+	return GspMgrEnoNone
+}
+
+//
+// Message published from peer
+//
+func (gspMgr *gossipManager) gspPublishInd(from ycfg.NodeID, pub *gm.Publish) GspMgrErrno {
+
+	if pub.From == gspMgr.local {
+		return GspMgrEnoNone
+	}
+
+	mid := gspMsgId(pub)
+
+	if _, seen := gspMgr.seen[mid]; seen {
+		return GspMgrEnoNone
+	}
+
+	gspMgr.seen[mid] = time.Now().Add(gspSeenTtl)
+
+	if pub.Hops > gspMaxHops {
+		pub.Hops = gspMaxHops
+	}
+
+	gspMgr.gspCache(mid, pub)
+
+	handler, subscribed := gspMgr.topics[pub.Topic]
+	if !subscribed {
+		return GspMgrEnoNone
+	}
+
+	handler(pub.Topic, pub.From, pub.Data)
+
+	if pub.Hops <= 1 {
+		return GspMgrEnoNone
+	}
+
+	var to = make([]ycfg.NodeID, 0, len(gspMgr.mesh[pub.Topic]))
+
+	for id := range gspMgr.mesh[pub.Topic] {
+		if id != from && id != pub.From {
+			to = append(to, id)
+		}
+	}
+
+	if len(to) == 0 {
+		return GspMgrEnoNone
+	}
+
+	fwd := *pub
+	fwd.Hops--
+
+	return gspMgr.gspSend(to, &gm.GspMessage{Mid: gm.MID_GSP_PUBLISH, Publish: &fwd})
+}
+
+//
+// GRAFT from peer: peer added us into its' mesh of topic
+//
+func (gspMgr *gossipManager) gspGraftInd(from ycfg.NodeID, ctl *gm.Control) GspMgrErrno {
+
+	mesh, subscribed := gspMgr.mesh[ctl.Topic]
+
+	if !subscribed || len(mesh) >= gspDhi {
+		return gspMgr.gspControl([]ycfg.NodeID{from}, gm.MID_GSP_PRUNE, ctl.Topic, nil)
+	}
+
+	mesh[from] = true
+	gspMgr.peers[from][ctl.Topic] = true
+
+	return GspMgrEnoNone
+}
+
+//
+// PRUNE from peer: peer removed us from its' mesh of topic
+//
+func (gspMgr *gossipManager) gspPruneInd(from ycfg.NodeID, ctl *gm.Control) GspMgrErrno {
+
+	if mesh, ok := gspMgr.mesh[ctl.Topic]; ok {
+		delete(mesh, from)
+	}
+
+	return GspMgrEnoNone
+}
+
+//
+// IHAVE from peer: ask for those not seen
+//
+func (gspMgr *gossipManager) gspIHaveInd(from ycfg.NodeID, ctl *gm.Control) GspMgrErrno {
+
+	if _, subscribed := gspMgr.topics[ctl.Topic]; !subscribed {
+		return GspMgrEnoNone
+	}
+
+	want := make([][]byte, 0)
+
+	for _, id := range ctl.MsgIds {
+
+		if len(want) >= gspMaxIds {
+			break
+		}
+
+		if _, seen := gspMgr.seen[string(id)]; !seen {
+			want = append(want, id)
+		}
+	}
+
+	if len(want) == 0 {
+		return GspMgrEnoNone
+	}
+
+	return gspMgr.gspControl([]ycfg.NodeID{from}, gm.MID_GSP_IWANT, ctl.Topic, want)
+}
+
+//
+// IWANT from peer: send those cached
+//
+func (gspMgr *gossipManager) gspIWantInd(from ycfg.NodeID, ctl *gm.Control) GspMgrErrno {
+
+	for idx, id := range ctl.MsgIds {
+
+		if idx >= gspMaxIds {
+			break
+		}
+
+		if c, ok := gspMgr.mcache[string(id)]; ok {
+			gspMgr.gspSend([]ycfg.NodeID{from}, &gm.GspMessage{Mid: gm.MID_GSP_PUBLISH, Publish: c.pub})
+		}
+	}
+
+	return GspMgrEnoNone
+}
+
+//
+// Heartbeat: maintain the meshes and fanouts, gossip, shift the history
+// and remove messages seen expired.
+//
+func (gspMgr *gossipManager) gspHeartbeat() GspMgrErrno {
+
+	now := time.Now()
+
+	for topic, mesh := range gspMgr.mesh {
+
+		if len(mesh) < gspDlo {
+
+			graft := gspMgr.gspPickPeers(topic, gspD - len(mesh), mesh)
+
+			for _, id := range graft {
+				mesh[id] = true
+			}
+
+			gspMgr.gspControl(graft, gm.MID_GSP_GRAFT, topic, nil)
+
+		} else if len(mesh) > gspDhi {
+
+			peers := gspPeerList(mesh)
+			rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+			prune := peers[gspD:]
+
+			for _, id := range prune {
+				delete(mesh, id)
+			}
+
+			gspMgr.gspControl(prune, gm.MID_GSP_PRUNE, topic, nil)
+		}
+
+		gspMgr.gspGossip(topic, mesh)
+	}
+
+	for topic, fanout := range gspMgr.fanout {
+
+		if now.Sub(gspMgr.fanoutPub[topic]) > gspFanoutTtl {
+			delete(gspMgr.fanout, topic)
+			delete(gspMgr.fanoutPub, topic)
+			continue
+		}
+
+		if len(fanout) < gspD {
+			for _, id := range gspMgr.gspPickPeers(topic, gspD - len(fanout), fanout) {
+				fanout[id] = true
+			}
+		}
+
+		gspMgr.gspGossip(topic, fanout)
+	}
+
+	for _, mid := range gspMgr.history[gspHistoryLen - 1] {
+		delete(gspMgr.mcache, mid)
+	}
+
+	copy(gspMgr.history[1:], gspMgr.history[:gspHistoryLen - 1])
+	gspMgr.history[0] = nil
+
+	for mid, expired := range gspMgr.seen {
+		if now.After(expired) {
+			delete(gspMgr.seen, mid)
+		}
+	}
+
+	return GspMgrEnoNone
+}
+
+//
+// Announce identities of messages recently cached for topic to some peers
+// subscribed but not in the mesh (or fanout) specified.
+//
+func (gspMgr *gossipManager) gspGossip(topic string, except map[ycfg.NodeID]bool) {
+
+	ids := make([][]byte, 0)
+
+	for _, win := range gspMgr.history[:gspHistoryGossip] {
+		for _, mid := range win {
+			if c, ok := gspMgr.mcache[mid]; ok && c.topic == topic && len(ids) < gspMaxIds {
+				ids = append(ids, []byte(mid))
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	gspMgr.gspControl(gspMgr.gspPickPeers(topic, gspDlazy, except), gm.MID_GSP_IHAVE, topic, ids)
+}
+
+//
+// Cache a message for IWANT
+//
+func (gspMgr *gossipManager) gspCache(mid string, pub *gm.Publish) {
+	gspMgr.mcache[mid] = &gspCached{pub: pub, topic: pub.Topic}
+	gspMgr.history[0] = append(gspMgr.history[0], mid)
+}
+
+//
+// Announce subscription of topic to all peers
+//
+func (gspMgr *gossipManager) gspAnnounce(topic string, subscribe bool) GspMgrErrno {
+
+	peers := make([]ycfg.NodeID, 0, len(gspMgr.peers))
+	for id := range gspMgr.peers {
+		peers = append(peers, id)
+	}
+
+	var msg = gm.GspMessage {
+		Mid:			gm.MID_GSP_SUBSCRIBE,
+		Subscriptions:	[]*gm.Subscription{{Topic: topic, Subscribe: subscribe}},
+	}
+
+	return gspMgr.gspSend(peers, &msg)
+}
+
+//
+// Send a control message
+//
+func (gspMgr *gossipManager) gspControl(to []ycfg.NodeID, mid pb.MessageId, topic string, ids [][]byte) GspMgrErrno {
+
+	var msg = gm.GspMessage {
+		Mid:		mid,
+		Control:	&gm.Control{Topic: topic, MsgIds: ids},
+	}
+
+	return gspMgr.gspSend(to, &msg)
+}
+
+//
+// Pick peers subscribed to topic randomly, those in except are skipped
+//
+func (gspMgr *gossipManager) gspPickPeers(topic string, count int, except map[ycfg.NodeID]bool) []ycfg.NodeID {
+
+	if count <= 0 {
+		return nil
+	}
+
+	cands := make([]ycfg.NodeID, 0)
+
+	for id, topics := range gspMgr.peers {
+		if topics[topic] && !except[id] {
+			cands = append(cands, id)
+		}
+	}
+
+	rand.Shuffle(len(cands), func(i, j int) { cands[i], cands[j] = cands[j], cands[i] })
+
+	if len(cands) > count {
+		cands = cands[:count]
+	}
+
+	return cands
+}
+
+//
+// Identities of a peer set
+//
+func gspPeerList(peers map[ycfg.NodeID]bool) []ycfg.NodeID {
+	list := make([]ycfg.NodeID, 0, len(peers))
+	for id := range peers {
+		list = append(list, id)
+	}
+	return list
+}
+
+//
+// Message identity: sha256 of publisher and sequence number
+//
+func gspMsgId(pub *gm.Publish) string {
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], pub.Seqno)
+	h := sha256.New()
+	h.Write(pub.From[:])
+	h.Write(seq[:])
+	return string(h.Sum(nil))
+}
+
+//
+// Send gossip message to peers
+//
+func (gspMgr *gossipManager) gspSend(to []ycfg.NodeID, msg *gm.GspMessage) GspMgrErrno {
+
+	if len(to) == 0 {
+		return GspMgrEnoNone
+	}
+
+	payload, eno := msg.Encode()
+	if eno != gm.GspMsgEnoNone {
+
+		yclog.LogCallerFileLine("gspSend: " +
+			"Encode failed, eno: %d",
+			eno)
+
+		return GspMgrEnoMessage
+	}
+
+	var idList = make([]peer.PeerId, 0, len(to))
+	for _, id := range to {
+		idList = append(idList, peer.PeerId(id))
+	}
+
+	var pkg = peer.P2pPackage2Peer {
+		IdList:			idList,
+		ProtoId:		int(peer.PID_GOSSIP),
+		Priority:		peer.P2pPrioHigh,
+		PayloadLength:	len(payload),
+		Payload:		payload,
+	}
+
+	if pe, failed := peer.SendPackage(gspMgr.sdl, &pkg); pe != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("gspSend: " +
+			"SendPackage failed, eno: %d, mid: %d, failed: %d",
+			pe, msg.Mid, len(failed))
+
+		return GspMgrEnoPeer
+	}
+
+	return GspMgrEnoNone
+}
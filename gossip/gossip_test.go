@@ -0,0 +1,267 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gossip
+
+import (
+	"time"
+	"testing"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	gm		"github.com/yeeco/p2p/gossip/gspmsg"
+)
+
+//
+// Gossip manager not started, the scheduler has no peer manager, so any
+// message sent by it fails with GspMgrEnoPeer, which tells a sending is
+// tried, while the states are updated as usual.
+//
+func gspTestManager(t *testing.T, peers int, topic string) *gossipManager {
+
+	eno, sdl := sch.SchinfSchedulerInit(&ycfg.Config{})
+	if eno != sch.SchEnoNone {
+		t.Fatalf("SchinfSchedulerInit failed, eno: %d", eno)
+	}
+
+	gspMgr := NewGspMgr().(*gossipManager)
+	gspMgr.sdl = sdl
+	gspMgr.local = ycfg.NodeID{0xff}
+
+	for i := 0; i < peers; i++ {
+		gspMgr.peers[gspTestNode(i)] = map[string]bool{topic: true}
+	}
+
+	return gspMgr
+}
+
+func gspTestNode(i int) ycfg.NodeID {
+	return ycfg.NodeID{byte(i >> 8), byte(i), 1}
+}
+
+func TestGspSeen(t *testing.T) {
+
+	gspMgr := gspTestManager(t, 3, "t")
+
+	delivered := 0
+	gspMgr.gspMgrSubscribeReq(&sch.MsgGspSubscribeReq{
+		Topic:		"t",
+		Handler:	func(string, ycfg.NodeID, []byte) { delivered++ },
+	})
+
+	pub := func(from ycfg.NodeID, seqno uint64) *gm.Publish {
+		return &gm.Publish{From: from, Seqno: seqno, Topic: "t", Data: []byte("x"), Hops: gspDftHops}
+	}
+
+	publisher := ycfg.NodeID{0x10}
+
+	cases := []struct {
+		name	string
+		from	ycfg.NodeID
+		pub		*gm.Publish
+		want	int
+	}{
+		{"first", gspTestNode(0), pub(publisher, 1), 1},
+		{"again", gspTestNode(0), pub(publisher, 1), 1},
+		{"again from other peer", gspTestNode(1), pub(publisher, 1), 1},
+		{"next seqno", gspTestNode(0), pub(publisher, 2), 2},
+		{"other publisher", gspTestNode(0), pub(ycfg.NodeID{0x11}, 1), 3},
+		{"published locally", gspTestNode(0), pub(gspMgr.local, 1), 3},
+	}
+
+	for _, c := range cases {
+		gspMgr.gspPublishInd(c.from, c.pub)
+		if delivered != c.want {
+			t.Fatalf("%s: delivered %d, want %d", c.name, delivered, c.want)
+		}
+	}
+
+	//
+	// a message published locally is seen, the IHAVE of it is ignored
+	//
+
+	gspMgr.gspMgrPublishReq(&sch.MsgGspPublishReq{Topic: "t", Data: []byte("y")})
+
+	local := gspMsgId(&gm.Publish{From: gspMgr.local, Seqno: gspMgr.seqno})
+	if eno := gspMgr.gspIHaveInd(gspTestNode(0), &gm.Control{Topic: "t", MsgIds: [][]byte{[]byte(local)}}); eno != GspMgrEnoNone {
+		t.Fatalf("IWANT sent for message seen, eno: %d", eno)
+	}
+
+	//
+	// forgotten after expired, then delivered again
+	//
+
+	mid := gspMsgId(pub(publisher, 1))
+	gspMgr.seen[mid] = time.Now().Add(-time.Second)
+	gspMgr.gspHeartbeat()
+
+	if _, ok := gspMgr.seen[mid]; ok {
+		t.Fatalf("expired message still seen")
+	}
+
+	gspMgr.gspPublishInd(gspTestNode(0), pub(publisher, 1))
+	if delivered != 4 {
+		t.Fatalf("expired: delivered %d, want 4", delivered)
+	}
+}
+
+func TestGspHops(t *testing.T) {
+
+	cases := []struct {
+		name		string
+		hops		uint32
+		forwarded	bool
+		cached		uint32
+	}{
+		{"exhausted", 0, false, 0},
+		{"last hop", 1, false, 1},
+		{"more hops", 2, true, 2},
+		{"default", gspDftHops, true, gspDftHops},
+		{"max", gspMaxHops, true, gspMaxHops},
+		{"over max", gspMaxHops + 1, true, gspMaxHops},
+	}
+
+	for seqno, c := range cases {
+
+		gspMgr := gspTestManager(t, 3, "t")
+
+		delivered := 0
+		gspMgr.gspMgrSubscribeReq(&sch.MsgGspSubscribeReq{
+			Topic:		"t",
+			Handler:	func(string, ycfg.NodeID, []byte) { delivered++ },
+		})
+
+		pub := &gm.Publish{From: ycfg.NodeID{0x10}, Seqno: uint64(seqno), Topic: "t", Hops: c.hops}
+
+		eno := gspMgr.gspPublishInd(gspTestNode(0), pub)
+		if forwarded := eno == GspMgrEnoPeer; forwarded != c.forwarded {
+			t.Fatalf("%s: forwarded %t, want %t", c.name, forwarded, c.forwarded)
+		}
+
+		if delivered != 1 {
+			t.Fatalf("%s: delivered %d, want 1", c.name, delivered)
+		}
+
+		if cached := gspMgr.mcache[gspMsgId(pub)].pub.Hops; cached != c.cached {
+			t.Fatalf("%s: cached with hops %d, want %d", c.name, cached, c.cached)
+		}
+	}
+
+	//
+	// not forwarded back to the sender or the publisher
+	//
+
+	gspMgr := gspTestManager(t, 2, "t")
+	gspMgr.gspMgrSubscribeReq(&sch.MsgGspSubscribeReq{Topic: "t", Handler: func(string, ycfg.NodeID, []byte) {}})
+
+	pub := &gm.Publish{From: gspTestNode(1), Seqno: 1, Topic: "t", Hops: gspDftHops}
+	if eno := gspMgr.gspPublishInd(gspTestNode(0), pub); eno != GspMgrEnoNone {
+		t.Fatalf("forwarded to sender or publisher, eno: %d", eno)
+	}
+}
+
+func TestGspMesh(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		peers	int
+		want	int
+	}{
+		{"no peers", 0, 0},
+		{"fewer than D", gspDlo - 1, gspDlo - 1},
+		{"D", gspD, gspD},
+		{"more than D", gspDhi * 2, gspD},
+	}
+
+	for _, c := range cases {
+
+		gspMgr := gspTestManager(t, c.peers, "t")
+		gspMgr.gspMgrSubscribeReq(&sch.MsgGspSubscribeReq{Topic: "t", Handler: func(string, ycfg.NodeID, []byte) {}})
+
+		if n := len(gspMgr.mesh["t"]); n != c.want {
+			t.Fatalf("%s: mesh of %d peers, want %d", c.name, n, c.want)
+		}
+
+		gspMgr.gspMgrPublishReq(&sch.MsgGspPublishReq{Topic: "f", Data: []byte("x")})
+
+		for id := range gspMgr.peers {
+			gspMgr.peers[id]["f"] = true
+		}
+
+		gspMgr.gspMgrPublishReq(&sch.MsgGspPublishReq{Topic: "f", Data: []byte("x")})
+
+		if n := len(gspMgr.fanout["f"]); n != c.want {
+			t.Fatalf("%s: fanout of %d peers, want %d", c.name, n, c.want)
+		}
+	}
+
+	//
+	// GRAFT accepted till the upper bound, then the mesh is pruned down to
+	// D, and grafted up to D once under the lower bound, at heartbeats
+	//
+
+	gspMgr := gspTestManager(t, gspDhi * 2, "t")
+	gspMgr.gspMgrSubscribeReq(&sch.MsgGspSubscribeReq{Topic: "t", Handler: func(string, ycfg.NodeID, []byte) {}})
+
+	mesh := gspMgr.mesh["t"]
+
+	for i := 0; i < gspDhi * 2; i++ {
+		gspMgr.gspGraftInd(gspTestNode(i), &gm.Control{Topic: "t"})
+		if len(mesh) > gspDhi {
+			t.Fatalf("mesh of %d peers by GRAFT, bound %d", len(mesh), gspDhi)
+		}
+	}
+
+	if len(mesh) != gspDhi {
+		t.Fatalf("mesh of %d peers by GRAFT, want %d", len(mesh), gspDhi)
+	}
+
+	for i := 0; len(mesh) <= gspDhi; i++ {
+		mesh[gspTestNode(i)] = true
+	}
+
+	gspMgr.gspHeartbeat()
+
+	if len(mesh) != gspD {
+		t.Fatalf("mesh of %d peers after pruned, want %d", len(mesh), gspD)
+	}
+
+	for id := range mesh {
+		if len(mesh) < gspDlo {
+			break
+		}
+		gspMgr.gspPruneInd(id, &gm.Control{Topic: "t"})
+	}
+
+	gspMgr.gspHeartbeat()
+
+	if len(mesh) != gspD {
+		t.Fatalf("mesh of %d peers after grafted, want %d", len(mesh), gspD)
+	}
+
+	//
+	// GRAFT for topic not subscribed is refused
+	//
+
+	gspMgr.gspGraftInd(gspTestNode(0), &gm.Control{Topic: "none"})
+
+	if _, ok := gspMgr.mesh["none"]; ok {
+		t.Fatalf("mesh created by GRAFT for topic not subscribed")
+	}
+}
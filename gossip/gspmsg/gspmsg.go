@@ -0,0 +1,244 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package gspmsg
+
+import (
+	ycfg	"github.com/yeeco/p2p/config"
+	pb		"github.com/yeeco/p2p/peer/pb"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// Gossip messages carried by packages with protocol identity PID_GOSSIP over
+// the peer connections, see tcpmsg.proto for the protobuf specification.
+//
+const (
+	MID_GSP_SUBSCRIBE	= pb.MessageId_MID_GSP_SUBSCRIBE
+	MID_GSP_PUBLISH		= pb.MessageId_MID_GSP_PUBLISH
+	MID_GSP_GRAFT		= pb.MessageId_MID_GSP_GRAFT
+	MID_GSP_PRUNE		= pb.MessageId_MID_GSP_PRUNE
+	MID_GSP_IHAVE		= pb.MessageId_MID_GSP_IHAVE
+	MID_GSP_IWANT		= pb.MessageId_MID_GSP_IWANT
+)
+
+//
+// errno
+//
+const (
+	GspMsgEnoNone	= iota
+	GspMsgEnoParameter
+	GspMsgEnoEncode
+	GspMsgEnoDecode
+	GspMsgEnoMessage
+)
+
+type GspMsgErrno int
+
+type (
+
+	// Subscription: subscribe or unsubscribe a topic
+	Subscription struct {
+		Topic		string			// topic
+		Subscribe	bool			// true: subscribe, false: unsubscribe
+	}
+
+	// Publish: a message published to a topic
+	Publish struct {
+		From		ycfg.NodeID		// the original publisher
+		Seqno		uint64			// sequence number of publisher
+		Topic		string			// topic
+		Data		[]byte			// message data
+		Hops		uint32			// hops left
+	}
+
+	// Control: GRAFT, PRUNE, IHAVE, IWANT
+	Control struct {
+		Topic		string			// topic
+		MsgIds		[][]byte		// message identities, for IHAVE and IWANT
+	}
+
+	// GspMessage: one of those above
+	GspMessage struct {
+		Mid				pb.MessageId	// message identity
+		Subscriptions	[]*Subscription	// subscriptions, for SUBSCRIBE
+		Publish			*Publish		// published message, for PUBLISH
+		Control			*Control		// control, for others
+	}
+)
+
+//
+// Encode gossip message to payload of package
+//
+func (gm *GspMessage) Encode() ([]byte, GspMsgErrno) {
+
+	pbMsg := new(pb.GossipMessage)
+	pbMsg.Mid = new(pb.MessageId)
+	*pbMsg.Mid = gm.Mid
+
+	var absent = false
+
+	switch gm.Mid {
+
+	case MID_GSP_SUBSCRIBE:
+
+		if absent = len(gm.Subscriptions) == 0; !absent {
+			for _, s := range gm.Subscriptions {
+				pbMsg.Subscriptions = append(pbMsg.Subscriptions, &pb.GossipMessage_Subscription {
+					Topic:		pbString(s.Topic),
+					Subscribe:	pbBool(s.Subscribe),
+				})
+			}
+		}
+
+	case MID_GSP_PUBLISH:
+
+		if absent = gm.Publish == nil; !absent {
+			pbMsg.Publish = &pb.GossipMessage_Publish {
+				From:	append([]byte{}, gm.Publish.From[:]...),
+				Seqno:	pbUint64(gm.Publish.Seqno),
+				Topic:	pbString(gm.Publish.Topic),
+				Data:	append([]byte{}, gm.Publish.Data...),
+				Hops:	pbUint32(gm.Publish.Hops),
+			}
+		}
+
+	case MID_GSP_GRAFT,
+		MID_GSP_PRUNE,
+		MID_GSP_IHAVE,
+		MID_GSP_IWANT:
+
+		if absent = gm.Control == nil; !absent {
+			pbMsg.Control = &pb.GossipMessage_Control {
+				Topic:	pbString(gm.Control.Topic),
+				MsgIds:	gm.Control.MsgIds,
+			}
+		}
+
+	default:
+		yclog.LogCallerFileLine("Encode: invalid mid: %d", gm.Mid)
+		return nil, GspMsgEnoParameter
+	}
+
+	if absent {
+		yclog.LogCallerFileLine("Encode: message absent, mid: %d", gm.Mid)
+		return nil, GspMsgEnoParameter
+	}
+
+	buf, err := pbMsg.Marshal()
+	if err != nil {
+
+		yclog.LogCallerFileLine("Encode: " +
+			"Marshal failed, err: %s",
+			err.Error())
+
+		return nil, GspMsgEnoEncode
+	}
+
+	return buf, GspMsgEnoNone
+}
+
+//
+// Decode gossip message from payload of package
+//
+func Decode(payload []byte) (*GspMessage, GspMsgErrno) {
+
+	pbMsg := new(pb.GossipMessage)
+
+	if err := pbMsg.Unmarshal(payload); err != nil {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"Unmarshal failed, err: %s",
+			err.Error())
+
+		return nil, GspMsgEnoDecode
+	}
+
+	gm := &GspMessage{Mid: pbMsg.GetMid()}
+	var absent = false
+
+	switch gm.Mid {
+
+	case MID_GSP_SUBSCRIBE:
+
+		for _, ps := range pbMsg.GetSubscriptions() {
+			if ps != nil {
+				gm.Subscriptions = append(gm.Subscriptions, &Subscription {
+					Topic:		ps.GetTopic(),
+					Subscribe:	ps.GetSubscribe(),
+				})
+			}
+		}
+		absent = len(gm.Subscriptions) == 0
+
+	case MID_GSP_PUBLISH:
+
+		if pbPub := pbMsg.GetPublish(); pbPub != nil && len(pbPub.From) == ycfg.NodeIDBytes {
+			gm.Publish = &Publish {
+				Seqno:	pbPub.GetSeqno(),
+				Topic:	pbPub.GetTopic(),
+				Data:	append([]byte{}, pbPub.Data...),
+				Hops:	pbPub.GetHops(),
+			}
+			copy(gm.Publish.From[:], pbPub.From)
+		}
+		absent = gm.Publish == nil
+
+	case MID_GSP_GRAFT,
+		MID_GSP_PRUNE,
+		MID_GSP_IHAVE,
+		MID_GSP_IWANT:
+
+		if pbCtl := pbMsg.GetControl(); pbCtl != nil {
+			gm.Control = &Control {
+				Topic:	pbCtl.GetTopic(),
+				MsgIds:	pbCtl.MsgIds,
+			}
+		}
+		absent = gm.Control == nil
+
+	default:
+		yclog.LogCallerFileLine("Decode: invalid mid: %d", gm.Mid)
+		return nil, GspMsgEnoMessage
+	}
+
+	if absent {
+		yclog.LogCallerFileLine("Decode: message absent or invalid, mid: %d", gm.Mid)
+		return nil, GspMsgEnoMessage
+	}
+
+	return gm, GspMsgEnoNone
+}
+
+func pbUint64(v uint64) *uint64 {
+	return &v
+}
+
+func pbUint32(v uint32) *uint32 {
+	return &v
+}
+
+func pbString(v string) *string {
+	return &v
+}
+
+func pbBool(v bool) *bool {
+	return &v
+}
@@ -40,6 +40,11 @@ import (
 //
 var p2pCfg *ycfg.Config = nil
 
+//
+// The p2p instance
+//
+var p2pInst = shell.NewP2pInstance()
+
 
 //
 // Indication/Package handlers
@@ -110,7 +115,7 @@ func txProc(id peer.PeerId) {
 			pkg.Payload = []byte(txString)
 			pkg.PayloadLength = len(pkg.Payload)
 
-			if eno := shell.P2pInfSendPackage(&pkg); eno != shell.P2pInfEnoNone {
+			if eno := p2pInst.P2pInfSendPackage(&pkg); eno != shell.P2pInfEnoNone {
 				yclog.LogCallerFileLine("txProc: "+
 					"send package failed, eno: %d, id: %s",
 					eno,
@@ -180,7 +185,7 @@ func p2pIndProc(what int, para interface{}) interface{} {
 			"P2pIndPeerActivated, para: %s",
 			fmt.Sprintf("%+v", *pap))
 
-		if eno := shell.P2pInfRegisterCallback(shell.P2pInfPkgCb, p2pPkgHandler, pap.Ptn);
+		if eno := p2pInst.P2pInfRegisterCallback(shell.P2pInfPkgCb, p2pPkgHandler, pap.Ptn);
 		eno != shell.P2pInfEnoNone {
 
 			yclog.LogCallerFileLine("p2pIndProc: " +
@@ -222,7 +227,7 @@ func p2pIndProc(what int, para interface{}) interface{} {
 					"try to close the instance, peer: %s",
 					fmt.Sprintf("%X", (*peer.PeerId)(&psp.PeerInfo.NodeId)))
 
-				if eno := shell.P2pInfClosePeer((*peer.PeerId)(&psp.PeerInfo.NodeId));
+				if eno := p2pInst.P2pInfClosePeer((*peer.PeerId)(&psp.PeerInfo.NodeId));
 					eno != shell.P2pInfEnoNone {
 					yclog.LogCallerFileLine("p2pIndProc: "+
 						"P2pInfClosePeer failed, eno: %d, peer: %s",
@@ -255,6 +260,31 @@ func p2pIndProc(what int, para interface{}) interface{} {
 			"done failed, id: %s",
 			fmt.Sprintf("%X", pcp.PeerId))
 
+	case shell.P2pIndPkgRejected:
+
+		//
+		// A package is discarded since no handler registered for its' protocol,
+		// or the peer had not advertised the protocol in handshake.
+		//
+
+		prp := para.(*peer.P2pIndPkgRejectedPara)
+
+		yclog.LogCallerFileLine("p2pIndProc: " +
+			"P2pIndPkgRejected, para: %s",
+			fmt.Sprintf("%+v", *prp))
+
+	case shell.P2pIndTxBackpressure:
+
+		//
+		// The send queue of a peer is congested or relieved, producers should
+		// slow down when congested.
+		//
+
+		bpp := para.(*peer.P2pIndTxBackpressurePara)
+
+		yclog.LogCallerFileLine("p2pIndProc: " +
+			"P2pIndTxBackpressure, para: %s",
+			fmt.Sprintf("%+v", *bpp))
 
 	default:
 
@@ -303,14 +333,14 @@ func main() {
 	//
 
 	myCfg := *dftCfg
-	shell.ShellSetConfig(&myCfg)
-	p2pCfg = shell.ShellGetConfig()
+	p2pInst.ShellSetConfig(&myCfg)
+	p2pCfg = p2pInst.ShellGetConfig()
 
 	//
 	// init underlying p2p logic and then start
 	//
 
-	if eno := shell.P2pInit(); eno != sch.SchEnoNone {
+	if eno := p2pInst.P2pInit(); eno != sch.SchEnoNone {
 		yclog.LogCallerFileLine("main: SchinfSchedulerInit failed, eno: %d", eno)
 		return
 	}
@@ -323,13 +353,13 @@ func main() {
 	// package handler p2pPkgHandler for more please.
 	//
 
-	if eno := shell.P2pInfRegisterCallback(shell.P2pInfIndCb, p2pIndHandler, nil);
+	if eno := p2pInst.P2pInfRegisterCallback(shell.P2pInfIndCb, p2pIndHandler, nil);
 	eno != shell.P2pInfEnoNone {
 		yclog.LogCallerFileLine("main: P2pInfRegisterCallback failed, eno: %d", eno)
 		return
 	}
 
-	eno, _ := shell.P2pStart()
+	eno, _ := p2pInst.P2pStart()
 
 	yclog.LogCallerFileLine("main: ycp2p started, eno: %d", eno)
 
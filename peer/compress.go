@@ -0,0 +1,208 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"fmt"
+	"sync/atomic"
+	"github.com/golang/snappy"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// Payload compression. Algorithms supported are advertised as a bit mask in
+// the handshake, and the one both sides support is used for the connection.
+// Payloads not smaller than the configured min size are compressed, and sent
+// with the algorithm flagged in the package, while others, and those could
+// not get smaller, are sent raw without the flag. So peers advertised nothing
+// never receive compressed packages, and the receiver need not know which
+// packages are compressed in advance.
+//
+const (
+	PeCompressNone		= 0			// no compression
+	PeCompressSnappy	= 1 << 0	// snappy
+)
+
+//
+// Statistics about payloads of a peer, Raw for the sizes of payloads before
+// compressing or after decompressing, Wire for those on the wire.
+//
+type PeCompressStat struct {
+	Algo		string		// algorithm negotiated with peer
+	TxRaw		uint64		// bytes of payloads sent
+	TxWire		uint64		// bytes of payloads sent on wire
+	RxRaw		uint64		// bytes of payloads received
+	RxWire		uint64		// bytes of payloads received on wire
+}
+
+//
+// Ratio of compression for sending, wire over raw, 1 if nothing sent
+//
+func (st *PeCompressStat) TxRatio() float64 {
+	if st.TxRaw == 0 {
+		return 1
+	}
+	return float64(st.TxWire) / float64(st.TxRaw)
+}
+
+//
+// Ratio of compression for receiving, wire over raw, 1 if nothing received
+//
+func (st *PeCompressStat) RxRatio() float64 {
+	if st.RxRaw == 0 {
+		return 1
+	}
+	return float64(st.RxWire) / float64(st.RxRaw)
+}
+
+//
+// Algorithms supported for configuration
+//
+func peCompressAlgos(cfg string) uint32 {
+	if cfg == ycfg.P2pCompressSnappy {
+		return PeCompressSnappy
+	}
+	return PeCompressNone
+}
+
+//
+// Name of algorithm
+//
+func peCompressName(algo uint32) string {
+	if algo == PeCompressSnappy {
+		return ycfg.P2pCompressSnappy
+	}
+	return ycfg.P2pCompressNone
+}
+
+//
+// Select the algorithm for a connection from those supported by both sides
+//
+func peNegotiateCompress(local uint32, remote uint32) uint32 {
+	if local & remote & PeCompressSnappy != 0 {
+		return PeCompressSnappy
+	}
+	return PeCompressNone
+}
+
+//
+// Compress payload to be sent, false returned if it's not compressed
+//
+func piCompress(inst *peerInstance, payload []byte) ([]byte, uint32, bool) {
+
+	if inst.compress == PeCompressNone || len(payload) < inst.peMgr.cfg.compressMin {
+		return payload, PeCompressNone, false
+	}
+
+	var out []byte
+
+	switch inst.compress {
+	case PeCompressSnappy:
+		out = snappy.Encode(nil, payload)
+	default:
+		return payload, PeCompressNone, false
+	}
+
+	if len(out) >= len(payload) {
+		return payload, PeCompressNone, false
+	}
+
+	return out, inst.compress, true
+}
+
+//
+// Decompress payload received. Since the decompressed one is not limited by
+// the reader of connection, it's checked against the max package size here.
+//
+func piDecompress(inst *peerInstance, algo uint32, payload []byte) ([]byte, PeMgrErrno) {
+
+	if algo != inst.compress {
+
+		yclog.LogCallerFileLine("piDecompress: " +
+			"algorithm not negotiated, algo: %d, negotiated: %d",
+			algo, inst.compress)
+
+		return nil, PeMgrEnoMessage
+	}
+
+	switch algo {
+
+	case PeCompressSnappy:
+
+		size, err := snappy.DecodedLen(payload)
+		if err != nil || size > inst.maxPkgSize {
+
+			yclog.LogCallerFileLine("piDecompress: " +
+				"invalid length, size: %d, err: %v",
+				size, err)
+
+			return nil, PeMgrEnoMessage
+		}
+
+		out, err := snappy.Decode(nil, payload)
+		if err != nil {
+
+			yclog.LogCallerFileLine("piDecompress: " +
+				"Decode failed, err: %s",
+				err.Error())
+
+			return nil, PeMgrEnoMessage
+		}
+
+		return out, PeMgrEnoNone
+	}
+
+	return nil, PeMgrEnoMessage
+}
+
+//
+// Get statistics about payloads of a peer
+//
+func CompressStatus(sdl *sch.Scheduler, id PeerId) (PeCompressStat, PeMgrErrno) {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeCompressStat{}, PeMgrEnoNotfound
+	}
+
+	peMgr.infLock.Lock()
+	inst := peMgr.workers[ycfg.NodeID(id)]
+	peMgr.infLock.Unlock()
+
+	if inst == nil {
+
+		yclog.LogCallerFileLine("CompressStatus: " +
+			"peer not found: %s",
+			fmt.Sprintf("%X", id))
+
+		return PeCompressStat{}, PeMgrEnoNotfound
+	}
+
+	return PeCompressStat {
+		Algo:	peCompressName(inst.compress),
+		TxRaw:	atomic.LoadUint64(&inst.txRaw),
+		TxWire:	atomic.LoadUint64(&inst.txWire),
+		RxRaw:	atomic.LoadUint64(&inst.rxRaw),
+		RxWire:	atomic.LoadUint64(&inst.rxWire),
+	}, PeMgrEnoNone
+}
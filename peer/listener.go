@@ -23,7 +23,7 @@ package peer
 
 import (
 	"net"
-	"fmt"
+	"strconv"
 	ycfg	"github.com/yeeco/p2p/config"
 	sch		"github.com/yeeco/p2p/scheduler"
 	yclog	"github.com/yeeco/p2p/logger"
@@ -38,24 +38,30 @@ const PeerLsnMgrName = sch.PeerLsnMgrName
 type listenerManager struct {
 	name		string					// name
 	tep			sch.SchUserTaskEp		// entry
+	sdl			*sch.Scheduler			// scheduler of the p2p instance
 	ptn			interface{}				// the listner task node pointer
 	ptnPeerMgr	interface{}				// the peer manager task node pointer
 	cfg			*ycfg.Cfg4PeerListener	// configuration
 	listener	net.Listener			// listener of net
 	listenAddr	*net.TCPAddr			// listen address
+	acceptTCB	*acceptTskCtrlBlock		// control block of the accept task
 }
 
-var lsnMgr = listenerManager{
-	name:	PeerLsnMgrName,
-	tep:	nil,
-}
-
-
 //
-// To escape the compiler "initialization loop" error
+// Create listener manager, it's the user data area of the static task
+// PeerLsnMgrName of a p2p instance.
 //
-func init() {
-	lsnMgr.tep = LsnMgrProc
+func NewLsnMgr() interface{} {
+	return &listenerManager{
+		name:	PeerLsnMgrName,
+		tep:	LsnMgrProc,
+		acceptTCB:	&acceptTskCtrlBlock {
+			ptnLsnMgr:	nil,
+			listener:	nil,
+			event:		sch.SchEnoNone,
+			curError:	nil,
+		},
+	}
 }
 
 
@@ -79,20 +85,21 @@ func LsnMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 		sch.SchinfGetMessageSender(msg), sch.SchinfGetMessageRecver(msg), msg.Id)
 
 	var eno sch.SchErrno
+	var lsnMgr = sch.SchinfGetUserDataArea(ptn).(*listenerManager)
 
 	switch msg.Id {
 
 	case sch.EvSchPoweron:
-		eno = lsnMgrPoweron(ptn)
+		eno = lsnMgr.lsnMgrPoweron(ptn)
 
 	case sch.EvSchPoweroff:
-		eno = lsnMgrPoweroff()
+		eno = lsnMgr.lsnMgrPoweroff()
 
 	case sch.EvPeLsnStartReq:
-		eno = lsnMgrStart()
+		eno = lsnMgr.lsnMgrStart()
 
 	case sch.EvPeLsnStopReq:
-		eno = lsnMgrStop()
+		eno = lsnMgr.lsnMgrStop()
 
 	default:
 		yclog.LogCallerFileLine("LsnMgrProc: invalid message: %d", msg.Id)
@@ -109,7 +116,7 @@ func LsnMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 //
 // Poweron event handler
 //
-func lsnMgrPoweron(ptn interface{}) sch.SchErrno {
+func (lsnMgr *listenerManager) lsnMgrPoweron(ptn interface{}) sch.SchErrno {
 
 	yclog.LogCallerFileLine("lsnMgrPoweron: poweron, carry out task initilization")
 
@@ -119,7 +126,8 @@ func lsnMgrPoweron(ptn interface{}) sch.SchErrno {
 	//
 
 	lsnMgr.ptn = ptn
-	_, lsnMgr.ptnPeerMgr = sch.SchinfGetTaskNodeByName(PeerMgrName)
+	lsnMgr.sdl = sch.SchinfGetScheduler(ptn)
+	_, lsnMgr.ptnPeerMgr = sch.SchinfGetTaskNodeByName(lsnMgr.sdl, PeerMgrName)
 
 	if lsnMgr.ptnPeerMgr == nil {
 		yclog.LogCallerFileLine("lsnMgrPoweron: invalid peer manager task node pointer")
@@ -130,7 +138,7 @@ func lsnMgrPoweron(ptn interface{}) sch.SchErrno {
 	// Get configuration
 	//
 
-	lsnMgr.cfg = ycfg.P2pConfig4PeerListener()
+	lsnMgr.cfg = ycfg.P2pConfig4PeerListener(sch.SchinfGetP2pConfig(lsnMgr.sdl))
 
 	if lsnMgr.cfg == nil {
 		yclog.LogCallerFileLine("lsnMgrPoweron: invalid configuration pointer")
@@ -143,11 +151,11 @@ func lsnMgrPoweron(ptn interface{}) sch.SchErrno {
 //
 // Setup net lsitener
 //
-func lsnMgrSetupListener() sch.SchErrno {
+func (lsnMgr *listenerManager) lsnMgrSetupListener() sch.SchErrno {
 
 	var err error
 
-	lsnAddr := fmt.Sprintf("%s:%d", lsnMgr.cfg.IP.String(), lsnMgr.cfg.Port)
+	lsnAddr := net.JoinHostPort(lsnMgr.cfg.IP.String(), strconv.Itoa(int(lsnMgr.cfg.Port)))
 
 	if lsnMgr.listener, err = net.Listen("tcp", lsnAddr); err != nil {
 
@@ -170,7 +178,7 @@ func lsnMgrSetupListener() sch.SchErrno {
 //
 // Poweroff event handler
 //
-func lsnMgrPoweroff() sch.SchErrno {
+func (lsnMgr *listenerManager) lsnMgrPoweroff() sch.SchErrno {
 
 	yclog.LogCallerFileLine("lsnMgrPoweroff: poweroff, done")
 
@@ -178,8 +186,8 @@ func lsnMgrPoweroff() sch.SchErrno {
 	// kill accepter task if needed
 	//
 
-	if _, ptn := sch.SchinfGetTaskNodeByName(PeerAccepterName); ptn != nil {
-		lsnMgrStop()
+	if _, ptn := sch.SchinfGetTaskNodeByName(lsnMgr.sdl, PeerAccepterName); ptn != nil {
+		lsnMgr.lsnMgrStop()
 	}
 
 	return sch.SchinfTaskDone(lsnMgr.ptn, sch.SchEnoKilled)
@@ -188,7 +196,7 @@ func lsnMgrPoweroff() sch.SchErrno {
 //
 // Startup event handler
 //
-func lsnMgrStart() sch.SchErrno {
+func (lsnMgr *listenerManager) lsnMgrStart() sch.SchErrno {
 
 	//
 	// When startup signal rceived, we create task which would go into
@@ -201,7 +209,7 @@ func lsnMgrStart() sch.SchErrno {
 
 	yclog.LogCallerFileLine("lsnMgrStart: try to create accept task ...")
 
-	if eno := lsnMgrSetupListener(); eno != sch.SchEnoNone {
+	if eno := lsnMgr.lsnMgrSetupListener(); eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("lsnMgrStart: " +
 			"setup listener failed, eno: %d",
@@ -217,10 +225,10 @@ func lsnMgrStart() sch.SchErrno {
 		Wd:			&sch.SchWatchDog{HaveDog:false,},
 		Flag:		sch.SchCreatedGo,
 		DieCb:		nil,
-		UserDa:		nil,
+		UserDa:		lsnMgr,
 	}
 
-	if eno, ptn := sch.SchinfCreateTask(&tskDesc); eno != sch.SchEnoNone || ptn == nil {
+	if eno, ptn := sch.SchinfCreateTask(lsnMgr.sdl, &tskDesc); eno != sch.SchEnoNone || ptn == nil {
 
 		yclog.LogCallerFileLine("lsnMgrStart: " +
 			"SchinfCreateTask failed, eno: %d, ptn: %X",
@@ -241,20 +249,25 @@ func lsnMgrStart() sch.SchErrno {
 //
 // Stop event handler
 //
-func lsnMgrStop() sch.SchErrno {
+func (lsnMgr *listenerManager) lsnMgrStop() sch.SchErrno {
 
 	yclog.LogCallerFileLine("lsnMgrStop: listner will be closed")
 
-	acceptTCB.lockTcb.Lock()
-	defer acceptTCB.lockTcb.Unlock()
+	lsnMgr.acceptTCB.lockTcb.Lock()
+	defer lsnMgr.acceptTCB.lockTcb.Unlock()
 
 	//
 	// Close the listener to force the acceptor task out of the loop,
 	// see function acceptProc for details please.
 	//
 
-	acceptTCB.event = sch.SchEnoKilled
-	acceptTCB.listener = nil
+	lsnMgr.acceptTCB.event = sch.SchEnoKilled
+	lsnMgr.acceptTCB.listener = nil
+
+	if lsnMgr.listener == nil {
+		yclog.LogCallerFileLine("lsnMgrStop: listner had been closed")
+		return sch.SchEnoNone
+	}
 
 	if err := lsnMgr.listener.Close(); err != nil {
 
@@ -284,13 +297,6 @@ type acceptTskCtrlBlock struct {
 	lockAccept	sync.Mutex		// lock to pause/resume acception
 }
 
-var acceptTCB = acceptTskCtrlBlock {
-	ptnLsnMgr:	nil,
-	listener:	nil,
-	event:		sch.SchEnoNone,
-	curError:	nil,
-}
-
 //
 // message for sch.EvPeLsnConnAcceptedInd
 //
@@ -307,37 +313,39 @@ func PeerAcceptProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 
 	_ = msg
 
+	var lsnMgr = sch.SchinfGetUserDataArea(ptn).(*listenerManager)
+
 	//
 	// Go into a longlong loop to accept peer connections. Please see
 	// comments in function lsnMgrStart for more.
 	//
 
-	_, acceptTCB.ptnLsnMgr = sch.SchinfGetTaskNodeByName(PeerLsnMgrName)
+	_, lsnMgr.acceptTCB.ptnLsnMgr = sch.SchinfGetTaskNodeByName(lsnMgr.sdl, PeerLsnMgrName)
 
-	if acceptTCB.ptnLsnMgr == nil {
+	if lsnMgr.acceptTCB.ptnLsnMgr == nil {
 		yclog.LogCallerFileLine("PeerAcceptProc: invalid listener manager task pointer")
 		sch.SchinfTaskDone(ptn, sch.SchEnoInternal)
 		return sch.SchEnoInternal
 	}
 
-	_, acceptTCB.ptnPeMgr = sch.SchinfGetTaskNodeByName(PeerMgrName)
+	_, lsnMgr.acceptTCB.ptnPeMgr = sch.SchinfGetTaskNodeByName(lsnMgr.sdl, PeerMgrName)
 
-	if acceptTCB.ptnPeMgr == nil {
+	if lsnMgr.acceptTCB.ptnPeMgr == nil {
 		yclog.LogCallerFileLine("PeerAcceptProc: invalid peer manager task pointer")
 		sch.SchinfTaskDone(ptn, sch.SchEnoInternal)
 		return sch.SchEnoInternal
 	}
 
-	acceptTCB.listener = lsnMgr.listener
+	lsnMgr.acceptTCB.listener = lsnMgr.listener
 
-	if acceptTCB.listener == nil {
+	if lsnMgr.acceptTCB.listener == nil {
 		yclog.LogCallerFileLine("PeerAcceptProc: invalid listener, done accepter")
 		sch.SchinfTaskDone(ptn, sch.SchEnoInternal)
 		return sch.SchEnoInternal
 	}
 
-	acceptTCB.event = sch.EvSchNull
-	acceptTCB.curError = nil
+	lsnMgr.acceptTCB.event = sch.EvSchNull
+	lsnMgr.acceptTCB.curError = nil
 
 	yclog.LogCallerFileLine("PeerAcceptProc: inited ok, tring to accept ...")
 
@@ -349,7 +357,7 @@ acceptLoop:
 		// lock: to know if we are allowed to accept
 		//
 
-		acceptTCB.lockAccept.Lock()
+		lsnMgr.acceptTCB.lockAccept.Lock()
 
 		//
 		// Check if had been kill by manager: we first obtain the lock then check the listener and
@@ -360,23 +368,23 @@ acceptLoop:
 		// do currently.
 		//
 
-		acceptTCB.lockTcb.Lock()
+		lsnMgr.acceptTCB.lockTcb.Lock()
 
-		if acceptTCB.listener == nil || acceptTCB.event != sch.SchEnoNone {
+		if lsnMgr.acceptTCB.listener == nil || lsnMgr.acceptTCB.event != sch.SchEnoNone {
 			yclog.LogCallerFileLine("PeerAcceptProc: break the loop, for we might have been killed")
 			break acceptLoop
 		}
 
-		listener := acceptTCB.listener
+		listener := lsnMgr.acceptTCB.listener
 
-		acceptTCB.lockTcb.Unlock()
+		lsnMgr.acceptTCB.lockTcb.Unlock()
 
 		//
 		// Get lock to accept: unlock it at once since we just want to know if we
 		// are allowed to accept, and we might be blocked in calling to Accept().
 		//
 
-		acceptTCB.lockAccept.Unlock()
+		lsnMgr.acceptTCB.lockAccept.Unlock()
 
 		//
 		// Try to accept. Since we had never set deadline for the listener, we
@@ -394,7 +402,7 @@ acceptLoop:
 		// Lock the control block since following statements need to access it
 		//
 
-		acceptTCB.lockTcb.Lock()
+		lsnMgr.acceptTCB.lockTcb.Lock()
 
 		//
 		// Check errors
@@ -405,7 +413,7 @@ acceptLoop:
 			yclog.LogCallerFileLine("PeerAcceptProc: " +
 				"break loop for non-temporary error while accepting, err: %s", err.Error())
 
-			acceptTCB.curError = err
+			lsnMgr.acceptTCB.curError = err
 			break acceptLoop
 		}
 
@@ -418,7 +426,7 @@ acceptLoop:
 			yclog.LogCallerFileLine("PeerAcceptProc: " +
 				"break loop for null connection accepted without errors")
 
-			acceptTCB.event = sch.EvSchException
+			lsnMgr.acceptTCB.event = sch.EvSchException
 
 			break acceptLoop
 		}
@@ -439,14 +447,14 @@ acceptLoop:
 			remoteAddr:	conn.RemoteAddr().(*net.TCPAddr),
 		}
 
-		eno := sch.SchinfMakeMessage(&msg, ptn, acceptTCB.ptnPeMgr, sch.EvPeLsnConnAcceptedInd, &msgBody)
+		eno := sch.SchinfMakeMessage(&msg, ptn, lsnMgr.acceptTCB.ptnPeMgr, sch.EvPeLsnConnAcceptedInd, &msgBody)
 		if eno != sch.SchEnoNone {
 
 			yclog.LogCallerFileLine("PeerAcceptProc: " +
 				"SchinfMakeMessage for EvPeLsnConnAcceptedInd failed, eno: %d",
 				eno)
 
-			acceptTCB.lockTcb.Unlock()
+			lsnMgr.acceptTCB.lockTcb.Unlock()
 
 			continue
 		}
@@ -456,18 +464,18 @@ acceptLoop:
 
 			yclog.LogCallerFileLine("PeerAcceptProc: " +
 				"SchinfSendMessage for EvPeLsnConnAcceptedInd failed, target: %s",
-				sch.SchinfGetTaskName(acceptTCB.ptnPeMgr))
+				sch.SchinfGetTaskName(lsnMgr.acceptTCB.ptnPeMgr))
 
-			acceptTCB.lockTcb.Unlock()
+			lsnMgr.acceptTCB.lockTcb.Unlock()
 
 			continue
 		}
 
 		yclog.LogCallerFileLine("PeerAcceptProc: " +
 			"send EvPeLsnConnAcceptedInd ok, target: %s",
-			sch.SchinfGetTaskName(acceptTCB.ptnPeMgr))
+			sch.SchinfGetTaskName(lsnMgr.acceptTCB.ptnPeMgr))
 
-		acceptTCB.lockTcb.Unlock()
+		lsnMgr.acceptTCB.lockTcb.Unlock()
 	}
 
 	//
@@ -478,19 +486,19 @@ acceptLoop:
 	// for accepting above.
 	//
 
-	if acceptTCB.curError != nil && acceptTCB.event != sch.SchEnoNone {
+	if lsnMgr.acceptTCB.curError != nil && lsnMgr.acceptTCB.event != sch.SchEnoNone {
 
 		//
 		// This is the normal case: the loop is broken by manager task, or
 		// errors fired from underlying network.
 		//
 
-		yclog.LogCallerFileLine("PeerAcceptProc: broken for event: %d", acceptTCB.event)
+		yclog.LogCallerFileLine("PeerAcceptProc: broken for event: %d", lsnMgr.acceptTCB.event)
 
-		sch.SchinfTaskDone(ptn, acceptTCB.event)
-		acceptTCB.lockTcb.Unlock()
+		sch.SchinfTaskDone(ptn, lsnMgr.acceptTCB.event)
+		lsnMgr.acceptTCB.lockTcb.Unlock()
 
-		return acceptTCB.event
+		return lsnMgr.acceptTCB.event
 	}
 
 	//
@@ -498,18 +506,18 @@ acceptLoop:
 	// accepter task.
 	//
 
-	if acceptTCB.curError != nil {
+	if lsnMgr.acceptTCB.curError != nil {
 
 		yclog.LogCallerFileLine("PeerAcceptProc: abnormal exit, event: %d, err: %s",
-			acceptTCB.event, acceptTCB.curError.Error())
+			lsnMgr.acceptTCB.event, lsnMgr.acceptTCB.curError.Error())
 
 	} else {
 
 		yclog.LogCallerFileLine("PeerAcceptProc: abnormal exit, event: %d, err: nil",
-			acceptTCB.event)
+			lsnMgr.acceptTCB.event)
 	}
 
-	acceptTCB.lockTcb.Unlock()
+	lsnMgr.acceptTCB.lockTcb.Unlock()
 	sch.SchinfTaskDone(ptn, sch.SchEnoUnknown)
 
 	return sch.SchEnoUnknown
@@ -518,17 +526,17 @@ acceptLoop:
 //
 // Pause accept
 //
-func PauseAccept() bool {
+func (lsnMgr *listenerManager) PauseAccept() bool {
 	yclog.LogCallerFileLine("PauseAccept: try to pause accepting inbound")
-	acceptTCB.lockAccept.Lock()
+	lsnMgr.acceptTCB.lockAccept.Lock()
 	return true
 }
 
 //
 // Resume accept
 //
-func ResumeAccept() bool {
+func (lsnMgr *listenerManager) ResumeAccept() bool {
 	yclog.LogCallerFileLine("PauseAccept: try to resume accepting inbound")
-	acceptTCB.lockAccept.Unlock()
+	lsnMgr.acceptTCB.lockAccept.Unlock()
 	return true
 }
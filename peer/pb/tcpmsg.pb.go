@@ -1,23 +1,16 @@
 // Code generated by protoc-gen-gogo. DO NOT EDIT.
 // source: tcpmsg.proto
 
-/*
-	Package tcpmsg_pb is a generated protocol buffer package.
-
-	It is generated from these files:
-		tcpmsg.proto
-
-	It has these top-level messages:
-		P2PPackage
-		P2PMessage
-*/
 package tcpmsg_pb
 
-import proto "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-
-import io "io"
+import (
+	fmt "fmt"
+	github_com_golang_protobuf_proto "github.com/golang/protobuf/proto"
+	proto "github.com/golang/protobuf/proto"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -28,22 +21,35 @@ var _ = math.Inf
 // is compatible with the proto package it is being compiled against.
 // A compilation error at this line likely means your copy of the
 // proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type ProtocolId int32
 
 const (
-	ProtocolId_PID_P2P ProtocolId = 0
-	ProtocolId_PID_EXT ProtocolId = 255
+	ProtocolId_PID_P2P    ProtocolId = 0
+	ProtocolId_PID_DHT    ProtocolId = 1
+	ProtocolId_PID_GOSSIP ProtocolId = 2
+	ProtocolId_PID_RPC    ProtocolId = 3
+	ProtocolId_PID_STREAM ProtocolId = 4
+	ProtocolId_PID_EXT    ProtocolId = 255
 )
 
 var ProtocolId_name = map[int32]string{
 	0:   "PID_P2P",
+	1:   "PID_DHT",
+	2:   "PID_GOSSIP",
+	3:   "PID_RPC",
+	4:   "PID_STREAM",
 	255: "PID_EXT",
 }
+
 var ProtocolId_value = map[string]int32{
-	"PID_P2P": 0,
-	"PID_EXT": 255,
+	"PID_P2P":    0,
+	"PID_DHT":    1,
+	"PID_GOSSIP": 2,
+	"PID_RPC":    3,
+	"PID_STREAM": 4,
+	"PID_EXT":    255,
 }
 
 func (x ProtocolId) Enum() *ProtocolId {
@@ -51,9 +57,11 @@ func (x ProtocolId) Enum() *ProtocolId {
 	*p = x
 	return p
 }
+
 func (x ProtocolId) String() string {
 	return proto.EnumName(ProtocolId_name, int32(x))
 }
+
 func (x *ProtocolId) UnmarshalJSON(data []byte) error {
 	value, err := proto.UnmarshalJSONEnum(ProtocolId_value, data, "ProtocolId")
 	if err != nil {
@@ -62,25 +70,101 @@ func (x *ProtocolId) UnmarshalJSON(data []byte) error {
 	*x = ProtocolId(value)
 	return nil
 }
-func (ProtocolId) EnumDescriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{0} }
+
+func (ProtocolId) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{0}
+}
 
 type MessageId int32
 
 const (
-	MessageId_MID_HANDSHAKE MessageId = 0
-	MessageId_MID_PING      MessageId = 1
-	MessageId_MID_PONG      MessageId = 2
+	MessageId_MID_HANDSHAKE        MessageId = 0
+	MessageId_MID_PING             MessageId = 1
+	MessageId_MID_PONG             MessageId = 2
+	MessageId_MID_DHT_FINDVALUE    MessageId = 100
+	MessageId_MID_DHT_VALUE        MessageId = 101
+	MessageId_MID_DHT_FINDNODE     MessageId = 102
+	MessageId_MID_DHT_NEIGHBORS    MessageId = 103
+	MessageId_MID_DHT_STORE        MessageId = 104
+	MessageId_MID_DHT_STORERSP     MessageId = 105
+	MessageId_MID_DHT_GETPROVIDERS MessageId = 106
+	MessageId_MID_DHT_PROVIDERS    MessageId = 107
+	MessageId_MID_DHT_ADDPROVIDER  MessageId = 108
+	MessageId_MID_GSP_SUBSCRIBE    MessageId = 200
+	MessageId_MID_GSP_PUBLISH      MessageId = 201
+	MessageId_MID_GSP_GRAFT        MessageId = 202
+	MessageId_MID_GSP_PRUNE        MessageId = 203
+	MessageId_MID_GSP_IHAVE        MessageId = 204
+	MessageId_MID_GSP_IWANT        MessageId = 205
+	MessageId_MID_RPC_REQUEST      MessageId = 300
+	MessageId_MID_RPC_RESPONSE     MessageId = 301
+	MessageId_MID_RPC_CANCEL       MessageId = 302
+	MessageId_MID_STREAM_OPEN      MessageId = 400
+	MessageId_MID_STREAM_ACCEPT    MessageId = 401
+	MessageId_MID_STREAM_DATA      MessageId = 402
+	MessageId_MID_STREAM_WINDOW    MessageId = 403
+	MessageId_MID_STREAM_CLOSE     MessageId = 404
+	MessageId_MID_STREAM_RESET     MessageId = 405
 )
 
 var MessageId_name = map[int32]string{
-	0: "MID_HANDSHAKE",
-	1: "MID_PING",
-	2: "MID_PONG",
+	0:   "MID_HANDSHAKE",
+	1:   "MID_PING",
+	2:   "MID_PONG",
+	100: "MID_DHT_FINDVALUE",
+	101: "MID_DHT_VALUE",
+	102: "MID_DHT_FINDNODE",
+	103: "MID_DHT_NEIGHBORS",
+	104: "MID_DHT_STORE",
+	105: "MID_DHT_STORERSP",
+	106: "MID_DHT_GETPROVIDERS",
+	107: "MID_DHT_PROVIDERS",
+	108: "MID_DHT_ADDPROVIDER",
+	200: "MID_GSP_SUBSCRIBE",
+	201: "MID_GSP_PUBLISH",
+	202: "MID_GSP_GRAFT",
+	203: "MID_GSP_PRUNE",
+	204: "MID_GSP_IHAVE",
+	205: "MID_GSP_IWANT",
+	300: "MID_RPC_REQUEST",
+	301: "MID_RPC_RESPONSE",
+	302: "MID_RPC_CANCEL",
+	400: "MID_STREAM_OPEN",
+	401: "MID_STREAM_ACCEPT",
+	402: "MID_STREAM_DATA",
+	403: "MID_STREAM_WINDOW",
+	404: "MID_STREAM_CLOSE",
+	405: "MID_STREAM_RESET",
 }
+
 var MessageId_value = map[string]int32{
-	"MID_HANDSHAKE": 0,
-	"MID_PING":      1,
-	"MID_PONG":      2,
+	"MID_HANDSHAKE":        0,
+	"MID_PING":             1,
+	"MID_PONG":             2,
+	"MID_DHT_FINDVALUE":    100,
+	"MID_DHT_VALUE":        101,
+	"MID_DHT_FINDNODE":     102,
+	"MID_DHT_NEIGHBORS":    103,
+	"MID_DHT_STORE":        104,
+	"MID_DHT_STORERSP":     105,
+	"MID_DHT_GETPROVIDERS": 106,
+	"MID_DHT_PROVIDERS":    107,
+	"MID_DHT_ADDPROVIDER":  108,
+	"MID_GSP_SUBSCRIBE":    200,
+	"MID_GSP_PUBLISH":      201,
+	"MID_GSP_GRAFT":        202,
+	"MID_GSP_PRUNE":        203,
+	"MID_GSP_IHAVE":        204,
+	"MID_GSP_IWANT":        205,
+	"MID_RPC_REQUEST":      300,
+	"MID_RPC_RESPONSE":     301,
+	"MID_RPC_CANCEL":       302,
+	"MID_STREAM_OPEN":      400,
+	"MID_STREAM_ACCEPT":    401,
+	"MID_STREAM_DATA":      402,
+	"MID_STREAM_WINDOW":    403,
+	"MID_STREAM_CLOSE":     404,
+	"MID_STREAM_RESET":     405,
 }
 
 func (x MessageId) Enum() *MessageId {
@@ -88,9 +172,11 @@ func (x MessageId) Enum() *MessageId {
 	*p = x
 	return p
 }
+
 func (x MessageId) String() string {
 	return proto.EnumName(MessageId_name, int32(x))
 }
+
 func (x *MessageId) UnmarshalJSON(data []byte) error {
 	value, err := proto.UnmarshalJSONEnum(MessageId_value, data, "MessageId")
 	if err != nil {
@@ -99,19 +185,53 @@ func (x *MessageId) UnmarshalJSON(data []byte) error {
 	*x = MessageId(value)
 	return nil
 }
-func (MessageId) EnumDescriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{1} }
+
+func (MessageId) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1}
+}
 
 type P2PPackage struct {
-	Pid              *ProtocolId `protobuf:"varint,1,req,name=Pid,enum=tcpmsg.pb.ProtocolId" json:"Pid,omitempty"`
-	PayloadLength    *uint32     `protobuf:"varint,2,req,name=PayloadLength" json:"PayloadLength,omitempty"`
-	Payload          []byte      `protobuf:"bytes,3,opt,name=Payload" json:"Payload,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	Pid                  *ProtocolId `protobuf:"varint,1,req,name=Pid,enum=tcpmsg.pb.ProtocolId" json:"Pid,omitempty"`
+	PayloadLength        *uint32     `protobuf:"varint,2,req,name=PayloadLength" json:"PayloadLength,omitempty"`
+	Payload              []byte      `protobuf:"bytes,3,opt,name=Payload" json:"Payload,omitempty"`
+	Compress             *uint32     `protobuf:"varint,4,opt,name=Compress" json:"Compress,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *P2PPackage) Reset()         { *m = P2PPackage{} }
+func (m *P2PPackage) String() string { return proto.CompactTextString(m) }
+func (*P2PPackage) ProtoMessage()    {}
+func (*P2PPackage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{0}
+}
+func (m *P2PPackage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *P2PPackage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_P2PPackage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *P2PPackage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PPackage.Merge(m, src)
+}
+func (m *P2PPackage) XXX_Size() int {
+	return m.Size()
+}
+func (m *P2PPackage) XXX_DiscardUnknown() {
+	xxx_messageInfo_P2PPackage.DiscardUnknown(m)
 }
 
-func (m *P2PPackage) Reset()                    { *m = P2PPackage{} }
-func (m *P2PPackage) String() string            { return proto.CompactTextString(m) }
-func (*P2PPackage) ProtoMessage()               {}
-func (*P2PPackage) Descriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{0} }
+var xxx_messageInfo_P2PPackage proto.InternalMessageInfo
 
 func (m *P2PPackage) GetPid() ProtocolId {
 	if m != nil && m.Pid != nil {
@@ -134,18 +254,55 @@ func (m *P2PPackage) GetPayload() []byte {
 	return nil
 }
 
+func (m *P2PPackage) GetCompress() uint32 {
+	if m != nil && m.Compress != nil {
+		return *m.Compress
+	}
+	return 0
+}
+
 type P2PMessage struct {
-	Mid              *MessageId            `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
-	Handshake        *P2PMessage_Handshake `protobuf:"bytes,2,opt,name=handshake" json:"handshake,omitempty"`
-	Ping             *P2PMessage_Ping      `protobuf:"bytes,3,opt,name=ping" json:"ping,omitempty"`
-	Pong             *P2PMessage_Pong      `protobuf:"bytes,4,opt,name=pong" json:"pong,omitempty"`
-	XXX_unrecognized []byte                `json:"-"`
+	Mid                  *MessageId            `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
+	Handshake            *P2PMessage_Handshake `protobuf:"bytes,2,opt,name=handshake" json:"handshake,omitempty"`
+	Ping                 *P2PMessage_Ping      `protobuf:"bytes,3,opt,name=ping" json:"ping,omitempty"`
+	Pong                 *P2PMessage_Pong      `protobuf:"bytes,4,opt,name=pong" json:"pong,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *P2PMessage) Reset()         { *m = P2PMessage{} }
+func (m *P2PMessage) String() string { return proto.CompactTextString(m) }
+func (*P2PMessage) ProtoMessage()    {}
+func (*P2PMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1}
+}
+func (m *P2PMessage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *P2PMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_P2PMessage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *P2PMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage.Merge(m, src)
+}
+func (m *P2PMessage) XXX_Size() int {
+	return m.Size()
+}
+func (m *P2PMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_P2PMessage.DiscardUnknown(m)
 }
 
-func (m *P2PMessage) Reset()                    { *m = P2PMessage{} }
-func (m *P2PMessage) String() string            { return proto.CompactTextString(m) }
-func (*P2PMessage) ProtoMessage()               {}
-func (*P2PMessage) Descriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{1} }
+var xxx_messageInfo_P2PMessage proto.InternalMessageInfo
 
 func (m *P2PMessage) GetMid() MessageId {
 	if m != nil && m.Mid != nil {
@@ -176,15 +333,45 @@ func (m *P2PMessage) GetPong() *P2PMessage_Pong {
 }
 
 type P2PMessage_Protocol struct {
-	Pid              *ProtocolId `protobuf:"varint,1,req,name=Pid,enum=tcpmsg.pb.ProtocolId" json:"Pid,omitempty"`
-	Ver              []byte      `protobuf:"bytes,2,req,name=Ver" json:"Ver,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	Pid                  *ProtocolId `protobuf:"varint,1,req,name=Pid,enum=tcpmsg.pb.ProtocolId" json:"Pid,omitempty"`
+	Ver                  []byte      `protobuf:"bytes,2,req,name=Ver" json:"Ver,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *P2PMessage_Protocol) Reset()         { *m = P2PMessage_Protocol{} }
+func (m *P2PMessage_Protocol) String() string { return proto.CompactTextString(m) }
+func (*P2PMessage_Protocol) ProtoMessage()    {}
+func (*P2PMessage_Protocol) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 0}
+}
+func (m *P2PMessage_Protocol) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *P2PMessage_Protocol) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_P2PMessage_Protocol.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *P2PMessage_Protocol) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Protocol.Merge(m, src)
+}
+func (m *P2PMessage_Protocol) XXX_Size() int {
+	return m.Size()
+}
+func (m *P2PMessage_Protocol) XXX_DiscardUnknown() {
+	xxx_messageInfo_P2PMessage_Protocol.DiscardUnknown(m)
 }
 
-func (m *P2PMessage_Protocol) Reset()                    { *m = P2PMessage_Protocol{} }
-func (m *P2PMessage_Protocol) String() string            { return proto.CompactTextString(m) }
-func (*P2PMessage_Protocol) ProtoMessage()               {}
-func (*P2PMessage_Protocol) Descriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{1, 0} }
+var xxx_messageInfo_P2PMessage_Protocol proto.InternalMessageInfo
 
 func (m *P2PMessage_Protocol) GetPid() ProtocolId {
 	if m != nil && m.Pid != nil {
@@ -201,20 +388,54 @@ func (m *P2PMessage_Protocol) GetVer() []byte {
 }
 
 type P2PMessage_Handshake struct {
-	NodeId           []byte                 `protobuf:"bytes,1,req,name=NodeId" json:"NodeId,omitempty"`
-	IP               []byte                 `protobuf:"bytes,2,req,name=IP" json:"IP,omitempty"`
-	UDP              *uint32                `protobuf:"varint,3,req,name=UDP" json:"UDP,omitempty"`
-	TCP              *uint32                `protobuf:"varint,4,req,name=TCP" json:"TCP,omitempty"`
-	ProtoNum         *uint32                `protobuf:"varint,5,req,name=ProtoNum" json:"ProtoNum,omitempty"`
-	Protocols        []*P2PMessage_Protocol `protobuf:"bytes,6,rep,name=Protocols" json:"Protocols,omitempty"`
-	Extra            []byte                 `protobuf:"bytes,7,opt,name=Extra" json:"Extra,omitempty"`
-	XXX_unrecognized []byte                 `json:"-"`
+	NodeId               []byte                 `protobuf:"bytes,1,req,name=NodeId" json:"NodeId,omitempty"`
+	IP                   []byte                 `protobuf:"bytes,2,req,name=IP" json:"IP,omitempty"`
+	UDP                  *uint32                `protobuf:"varint,3,req,name=UDP" json:"UDP,omitempty"`
+	TCP                  *uint32                `protobuf:"varint,4,req,name=TCP" json:"TCP,omitempty"`
+	ProtoNum             *uint32                `protobuf:"varint,5,req,name=ProtoNum" json:"ProtoNum,omitempty"`
+	Protocols            []*P2PMessage_Protocol `protobuf:"bytes,6,rep,name=Protocols" json:"Protocols,omitempty"`
+	Extra                []byte                 `protobuf:"bytes,7,opt,name=Extra" json:"Extra,omitempty"`
+	EphPubKey            []byte                 `protobuf:"bytes,8,opt,name=EphPubKey" json:"EphPubKey,omitempty"`
+	Nonce                []byte                 `protobuf:"bytes,9,opt,name=Nonce" json:"Nonce,omitempty"`
+	Signature            []byte                 `protobuf:"bytes,10,opt,name=Signature" json:"Signature,omitempty"`
+	Compress             *uint32                `protobuf:"varint,11,opt,name=Compress" json:"Compress,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *P2PMessage_Handshake) Reset()         { *m = P2PMessage_Handshake{} }
+func (m *P2PMessage_Handshake) String() string { return proto.CompactTextString(m) }
+func (*P2PMessage_Handshake) ProtoMessage()    {}
+func (*P2PMessage_Handshake) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 1}
+}
+func (m *P2PMessage_Handshake) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *P2PMessage_Handshake) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_P2PMessage_Handshake.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *P2PMessage_Handshake) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Handshake.Merge(m, src)
+}
+func (m *P2PMessage_Handshake) XXX_Size() int {
+	return m.Size()
+}
+func (m *P2PMessage_Handshake) XXX_DiscardUnknown() {
+	xxx_messageInfo_P2PMessage_Handshake.DiscardUnknown(m)
 }
 
-func (m *P2PMessage_Handshake) Reset()                    { *m = P2PMessage_Handshake{} }
-func (m *P2PMessage_Handshake) String() string            { return proto.CompactTextString(m) }
-func (*P2PMessage_Handshake) ProtoMessage()               {}
-func (*P2PMessage_Handshake) Descriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{1, 1} }
+var xxx_messageInfo_P2PMessage_Handshake proto.InternalMessageInfo
 
 func (m *P2PMessage_Handshake) GetNodeId() []byte {
 	if m != nil {
@@ -265,16 +486,74 @@ func (m *P2PMessage_Handshake) GetExtra() []byte {
 	return nil
 }
 
+func (m *P2PMessage_Handshake) GetEphPubKey() []byte {
+	if m != nil {
+		return m.EphPubKey
+	}
+	return nil
+}
+
+func (m *P2PMessage_Handshake) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+func (m *P2PMessage_Handshake) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *P2PMessage_Handshake) GetCompress() uint32 {
+	if m != nil && m.Compress != nil {
+		return *m.Compress
+	}
+	return 0
+}
+
 type P2PMessage_Ping struct {
-	Seq              *uint64 `protobuf:"varint,1,req,name=seq" json:"seq,omitempty"`
-	Extra            []byte  `protobuf:"bytes,2,opt,name=Extra" json:"Extra,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Seq                  *uint64  `protobuf:"varint,1,req,name=seq" json:"seq,omitempty"`
+	Extra                []byte   `protobuf:"bytes,2,opt,name=Extra" json:"Extra,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *P2PMessage_Ping) Reset()         { *m = P2PMessage_Ping{} }
+func (m *P2PMessage_Ping) String() string { return proto.CompactTextString(m) }
+func (*P2PMessage_Ping) ProtoMessage()    {}
+func (*P2PMessage_Ping) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 2}
+}
+func (m *P2PMessage_Ping) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *P2PMessage_Ping) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_P2PMessage_Ping.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *P2PMessage_Ping) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Ping.Merge(m, src)
+}
+func (m *P2PMessage_Ping) XXX_Size() int {
+	return m.Size()
+}
+func (m *P2PMessage_Ping) XXX_DiscardUnknown() {
+	xxx_messageInfo_P2PMessage_Ping.DiscardUnknown(m)
 }
 
-func (m *P2PMessage_Ping) Reset()                    { *m = P2PMessage_Ping{} }
-func (m *P2PMessage_Ping) String() string            { return proto.CompactTextString(m) }
-func (*P2PMessage_Ping) ProtoMessage()               {}
-func (*P2PMessage_Ping) Descriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{1, 2} }
+var xxx_messageInfo_P2PMessage_Ping proto.InternalMessageInfo
 
 func (m *P2PMessage_Ping) GetSeq() uint64 {
 	if m != nil && m.Seq != nil {
@@ -291,15 +570,45 @@ func (m *P2PMessage_Ping) GetExtra() []byte {
 }
 
 type P2PMessage_Pong struct {
-	Seq              *uint64 `protobuf:"varint,1,req,name=seq" json:"seq,omitempty"`
-	Extra            []byte  `protobuf:"bytes,2,opt,name=Extra" json:"Extra,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Seq                  *uint64  `protobuf:"varint,1,req,name=seq" json:"seq,omitempty"`
+	Extra                []byte   `protobuf:"bytes,2,opt,name=Extra" json:"Extra,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *P2PMessage_Pong) Reset()         { *m = P2PMessage_Pong{} }
+func (m *P2PMessage_Pong) String() string { return proto.CompactTextString(m) }
+func (*P2PMessage_Pong) ProtoMessage()    {}
+func (*P2PMessage_Pong) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 3}
+}
+func (m *P2PMessage_Pong) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *P2PMessage_Pong) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_P2PMessage_Pong.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *P2PMessage_Pong) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Pong.Merge(m, src)
+}
+func (m *P2PMessage_Pong) XXX_Size() int {
+	return m.Size()
+}
+func (m *P2PMessage_Pong) XXX_DiscardUnknown() {
+	xxx_messageInfo_P2PMessage_Pong.DiscardUnknown(m)
 }
 
-func (m *P2PMessage_Pong) Reset()                    { *m = P2PMessage_Pong{} }
-func (m *P2PMessage_Pong) String() string            { return proto.CompactTextString(m) }
-func (*P2PMessage_Pong) ProtoMessage()               {}
-func (*P2PMessage_Pong) Descriptor() ([]byte, []int) { return fileDescriptorTcpmsg, []int{1, 3} }
+var xxx_messageInfo_P2PMessage_Pong proto.InternalMessageInfo
 
 func (m *P2PMessage_Pong) GetSeq() uint64 {
 	if m != nil && m.Seq != nil {
@@ -315,445 +624,7206 @@ func (m *P2PMessage_Pong) GetExtra() []byte {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*P2PPackage)(nil), "tcpmsg.pb.P2PPackage")
-	proto.RegisterType((*P2PMessage)(nil), "tcpmsg.pb.P2PMessage")
-	proto.RegisterType((*P2PMessage_Protocol)(nil), "tcpmsg.pb.P2PMessage.Protocol")
-	proto.RegisterType((*P2PMessage_Handshake)(nil), "tcpmsg.pb.P2PMessage.Handshake")
-	proto.RegisterType((*P2PMessage_Ping)(nil), "tcpmsg.pb.P2PMessage.Ping")
-	proto.RegisterType((*P2PMessage_Pong)(nil), "tcpmsg.pb.P2PMessage.Pong")
-	proto.RegisterEnum("tcpmsg.pb.ProtocolId", ProtocolId_name, ProtocolId_value)
-	proto.RegisterEnum("tcpmsg.pb.MessageId", MessageId_name, MessageId_value)
-}
-func (m *P2PPackage) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
+type DhtMessage struct {
+	Mid                  *MessageId               `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
+	FindValue            *DhtMessage_FindValue    `protobuf:"bytes,2,opt,name=findValue" json:"findValue,omitempty"`
+	Value                *DhtMessage_Value        `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	FindNode             *DhtMessage_FindNode     `protobuf:"bytes,4,opt,name=findNode" json:"findNode,omitempty"`
+	Neighbors            *DhtMessage_Neighbors    `protobuf:"bytes,5,opt,name=neighbors" json:"neighbors,omitempty"`
+	Store                *DhtMessage_Store        `protobuf:"bytes,6,opt,name=store" json:"store,omitempty"`
+	StoreRsp             *DhtMessage_StoreRsp     `protobuf:"bytes,7,opt,name=storeRsp" json:"storeRsp,omitempty"`
+	GetProviders         *DhtMessage_GetProviders `protobuf:"bytes,8,opt,name=getProviders" json:"getProviders,omitempty"`
+	ProvidersRsp         *DhtMessage_Providers    `protobuf:"bytes,9,opt,name=providersRsp" json:"providersRsp,omitempty"`
+	AddProvider          *DhtMessage_AddProvider  `protobuf:"bytes,10,opt,name=addProvider" json:"addProvider,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
 }
 
-func (m *P2PPackage) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Pid == nil {
-		return 0, new(proto.RequiredNotSetError)
+func (m *DhtMessage) Reset()         { *m = DhtMessage{} }
+func (m *DhtMessage) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage) ProtoMessage()    {}
+func (*DhtMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2}
+}
+func (m *DhtMessage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage.Marshal(b, m, deterministic)
 	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Pid))
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.PayloadLength == nil {
-		return 0, new(proto.RequiredNotSetError)
-	} else {
-		dAtA[i] = 0x10
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.PayloadLength))
+}
+func (m *DhtMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage.Merge(m, src)
+}
+func (m *DhtMessage) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage proto.InternalMessageInfo
+
+func (m *DhtMessage) GetMid() MessageId {
+	if m != nil && m.Mid != nil {
+		return *m.Mid
 	}
-	if m.Payload != nil {
-		dAtA[i] = 0x1a
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Payload)))
-		i += copy(dAtA[i:], m.Payload)
+	return MessageId_MID_HANDSHAKE
+}
+
+func (m *DhtMessage) GetFindValue() *DhtMessage_FindValue {
+	if m != nil {
+		return m.FindValue
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	return nil
+}
+
+func (m *DhtMessage) GetValue() *DhtMessage_Value {
+	if m != nil {
+		return m.Value
 	}
-	return i, nil
+	return nil
 }
 
-func (m *P2PMessage) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *DhtMessage) GetFindNode() *DhtMessage_FindNode {
+	if m != nil {
+		return m.FindNode
 	}
-	return dAtA[:n], nil
+	return nil
 }
 
-func (m *P2PMessage) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Mid == nil {
-		return 0, new(proto.RequiredNotSetError)
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Mid))
+func (m *DhtMessage) GetNeighbors() *DhtMessage_Neighbors {
+	if m != nil {
+		return m.Neighbors
 	}
-	if m.Handshake != nil {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(m.Handshake.Size()))
-		n1, err := m.Handshake.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n1
+	return nil
+}
+
+func (m *DhtMessage) GetStore() *DhtMessage_Store {
+	if m != nil {
+		return m.Store
 	}
-	if m.Ping != nil {
-		dAtA[i] = 0x1a
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(m.Ping.Size()))
-		n2, err := m.Ping.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n2
+	return nil
+}
+
+func (m *DhtMessage) GetStoreRsp() *DhtMessage_StoreRsp {
+	if m != nil {
+		return m.StoreRsp
 	}
-	if m.Pong != nil {
-		dAtA[i] = 0x22
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(m.Pong.Size()))
-		n3, err := m.Pong.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n3
+	return nil
+}
+
+func (m *DhtMessage) GetGetProviders() *DhtMessage_GetProviders {
+	if m != nil {
+		return m.GetProviders
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	return nil
+}
+
+func (m *DhtMessage) GetProvidersRsp() *DhtMessage_Providers {
+	if m != nil {
+		return m.ProvidersRsp
 	}
-	return i, nil
+	return nil
 }
 
-func (m *P2PMessage_Protocol) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *DhtMessage) GetAddProvider() *DhtMessage_AddProvider {
+	if m != nil {
+		return m.AddProvider
 	}
-	return dAtA[:n], nil
+	return nil
 }
 
-func (m *P2PMessage_Protocol) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Pid == nil {
-		return 0, new(proto.RequiredNotSetError)
+type DhtMessage_Node struct {
+	NodeId               []byte   `protobuf:"bytes,1,req,name=NodeId" json:"NodeId,omitempty"`
+	IP                   []byte   `protobuf:"bytes,2,req,name=IP" json:"IP,omitempty"`
+	UDP                  *uint32  `protobuf:"varint,3,req,name=UDP" json:"UDP,omitempty"`
+	TCP                  *uint32  `protobuf:"varint,4,req,name=TCP" json:"TCP,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DhtMessage_Node) Reset()         { *m = DhtMessage_Node{} }
+func (m *DhtMessage_Node) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_Node) ProtoMessage()    {}
+func (*DhtMessage_Node) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 0}
+}
+func (m *DhtMessage_Node) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_Node) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_Node.Marshal(b, m, deterministic)
 	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Pid))
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.Ver == nil {
-		return 0, new(proto.RequiredNotSetError)
-	} else {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Ver)))
-		i += copy(dAtA[i:], m.Ver)
+}
+func (m *DhtMessage_Node) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_Node.Merge(m, src)
+}
+func (m *DhtMessage_Node) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_Node) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_Node.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_Node proto.InternalMessageInfo
+
+func (m *DhtMessage_Node) GetNodeId() []byte {
+	if m != nil {
+		return m.NodeId
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	return nil
+}
+
+func (m *DhtMessage_Node) GetIP() []byte {
+	if m != nil {
+		return m.IP
 	}
-	return i, nil
+	return nil
 }
 
-func (m *P2PMessage_Handshake) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *DhtMessage_Node) GetUDP() uint32 {
+	if m != nil && m.UDP != nil {
+		return *m.UDP
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *P2PMessage_Handshake) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.NodeId == nil {
-		return 0, new(proto.RequiredNotSetError)
-	} else {
-		dAtA[i] = 0xa
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.NodeId)))
-		i += copy(dAtA[i:], m.NodeId)
+func (m *DhtMessage_Node) GetTCP() uint32 {
+	if m != nil && m.TCP != nil {
+		return *m.TCP
 	}
-	if m.IP == nil {
-		return 0, new(proto.RequiredNotSetError)
+	return 0
+}
+
+type DhtMessage_FindValue struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DhtMessage_FindValue) Reset()         { *m = DhtMessage_FindValue{} }
+func (m *DhtMessage_FindValue) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_FindValue) ProtoMessage()    {}
+func (*DhtMessage_FindValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 1}
+}
+func (m *DhtMessage_FindValue) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_FindValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_FindValue.Marshal(b, m, deterministic)
 	} else {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.IP)))
-		i += copy(dAtA[i:], m.IP)
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.UDP == nil {
-		return 0, new(proto.RequiredNotSetError)
-	} else {
-		dAtA[i] = 0x18
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.UDP))
+}
+func (m *DhtMessage_FindValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_FindValue.Merge(m, src)
+}
+func (m *DhtMessage_FindValue) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_FindValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_FindValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_FindValue proto.InternalMessageInfo
+
+func (m *DhtMessage_FindValue) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if m.TCP == nil {
-		return 0, new(proto.RequiredNotSetError)
-	} else {
-		dAtA[i] = 0x20
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.TCP))
+	return 0
+}
+
+func (m *DhtMessage_FindValue) GetKey() []byte {
+	if m != nil {
+		return m.Key
 	}
-	if m.ProtoNum == nil {
-		return 0, new(proto.RequiredNotSetError)
+	return nil
+}
+
+type DhtMessage_Value struct {
+	Id                   *uint64            `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Key                  []byte             `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	Value                []byte             `protobuf:"bytes,3,opt,name=Value" json:"Value,omitempty"`
+	Closer               []*DhtMessage_Node `protobuf:"bytes,4,rep,name=Closer" json:"Closer,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *DhtMessage_Value) Reset()         { *m = DhtMessage_Value{} }
+func (m *DhtMessage_Value) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_Value) ProtoMessage()    {}
+func (*DhtMessage_Value) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 2}
+}
+func (m *DhtMessage_Value) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_Value) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_Value.Marshal(b, m, deterministic)
 	} else {
-		dAtA[i] = 0x28
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.ProtoNum))
-	}
-	if len(m.Protocols) > 0 {
-		for _, msg := range m.Protocols {
-			dAtA[i] = 0x32
-			i++
-			i = encodeVarintTcpmsg(dAtA, i, uint64(msg.Size()))
-			n, err := msg.MarshalTo(dAtA[i:])
-			if err != nil {
-				return 0, err
-			}
-			i += n
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
+		return b[:n], nil
 	}
-	if m.Extra != nil {
-		dAtA[i] = 0x3a
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Extra)))
-		i += copy(dAtA[i:], m.Extra)
+}
+func (m *DhtMessage_Value) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_Value.Merge(m, src)
+}
+func (m *DhtMessage_Value) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_Value) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_Value.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_Value proto.InternalMessageInfo
+
+func (m *DhtMessage_Value) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+	return 0
+}
+
+func (m *DhtMessage_Value) GetKey() []byte {
+	if m != nil {
+		return m.Key
 	}
-	return i, nil
+	return nil
 }
 
-func (m *P2PMessage_Ping) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *DhtMessage_Value) GetValue() []byte {
+	if m != nil {
+		return m.Value
 	}
-	return dAtA[:n], nil
+	return nil
 }
 
-func (m *P2PMessage_Ping) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Seq == nil {
-		return 0, new(proto.RequiredNotSetError)
-	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Seq))
+func (m *DhtMessage_Value) GetCloser() []*DhtMessage_Node {
+	if m != nil {
+		return m.Closer
 	}
-	if m.Extra != nil {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Extra)))
-		i += copy(dAtA[i:], m.Extra)
+	return nil
+}
+
+type DhtMessage_FindNode struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Target               []byte   `protobuf:"bytes,2,req,name=Target" json:"Target,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DhtMessage_FindNode) Reset()         { *m = DhtMessage_FindNode{} }
+func (m *DhtMessage_FindNode) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_FindNode) ProtoMessage()    {}
+func (*DhtMessage_FindNode) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 3}
+}
+func (m *DhtMessage_FindNode) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_FindNode) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_FindNode.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+}
+func (m *DhtMessage_FindNode) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_FindNode.Merge(m, src)
+}
+func (m *DhtMessage_FindNode) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_FindNode) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_FindNode.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_FindNode proto.InternalMessageInfo
+
+func (m *DhtMessage_FindNode) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	return i, nil
+	return 0
 }
 
-func (m *P2PMessage_Pong) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+func (m *DhtMessage_FindNode) GetTarget() []byte {
+	if m != nil {
+		return m.Target
 	}
-	return dAtA[:n], nil
+	return nil
 }
 
-func (m *P2PMessage_Pong) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Seq == nil {
-		return 0, new(proto.RequiredNotSetError)
+type DhtMessage_Neighbors struct {
+	Id                   *uint64            `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Target               []byte             `protobuf:"bytes,2,req,name=Target" json:"Target,omitempty"`
+	Nodes                []*DhtMessage_Node `protobuf:"bytes,3,rep,name=Nodes" json:"Nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *DhtMessage_Neighbors) Reset()         { *m = DhtMessage_Neighbors{} }
+func (m *DhtMessage_Neighbors) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_Neighbors) ProtoMessage()    {}
+func (*DhtMessage_Neighbors) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 4}
+}
+func (m *DhtMessage_Neighbors) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_Neighbors) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_Neighbors.Marshal(b, m, deterministic)
 	} else {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Seq))
-	}
-	if m.Extra != nil {
-		dAtA[i] = 0x12
-		i++
-		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Extra)))
-		i += copy(dAtA[i:], m.Extra)
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.XXX_unrecognized != nil {
-		i += copy(dAtA[i:], m.XXX_unrecognized)
+}
+func (m *DhtMessage_Neighbors) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_Neighbors.Merge(m, src)
+}
+func (m *DhtMessage_Neighbors) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_Neighbors) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_Neighbors.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_Neighbors proto.InternalMessageInfo
+
+func (m *DhtMessage_Neighbors) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	return i, nil
+	return 0
 }
 
-func encodeVarintTcpmsg(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *DhtMessage_Neighbors) GetTarget() []byte {
+	if m != nil {
+		return m.Target
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
+	return nil
 }
-func (m *P2PPackage) Size() (n int) {
-	var l int
-	_ = l
-	if m.Pid != nil {
-		n += 1 + sovTcpmsg(uint64(*m.Pid))
+
+func (m *DhtMessage_Neighbors) GetNodes() []*DhtMessage_Node {
+	if m != nil {
+		return m.Nodes
 	}
-	if m.PayloadLength != nil {
-		n += 1 + sovTcpmsg(uint64(*m.PayloadLength))
+	return nil
+}
+
+type DhtMessage_Store struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	Value                []byte   `protobuf:"bytes,3,req,name=Value" json:"Value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DhtMessage_Store) Reset()         { *m = DhtMessage_Store{} }
+func (m *DhtMessage_Store) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_Store) ProtoMessage()    {}
+func (*DhtMessage_Store) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 5}
+}
+func (m *DhtMessage_Store) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_Store) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_Store.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.Payload != nil {
-		l = len(m.Payload)
-		n += 1 + l + sovTcpmsg(uint64(l))
+}
+func (m *DhtMessage_Store) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_Store.Merge(m, src)
+}
+func (m *DhtMessage_Store) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_Store) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_Store.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_Store proto.InternalMessageInfo
+
+func (m *DhtMessage_Store) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	return 0
+}
+
+func (m *DhtMessage_Store) GetKey() []byte {
+	if m != nil {
+		return m.Key
 	}
-	return n
+	return nil
 }
 
-func (m *P2PMessage) Size() (n int) {
-	var l int
-	_ = l
-	if m.Mid != nil {
-		n += 1 + sovTcpmsg(uint64(*m.Mid))
+func (m *DhtMessage_Store) GetValue() []byte {
+	if m != nil {
+		return m.Value
 	}
-	if m.Handshake != nil {
-		l = m.Handshake.Size()
-		n += 1 + l + sovTcpmsg(uint64(l))
+	return nil
+}
+
+type DhtMessage_StoreRsp struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	Eno                  *uint32  `protobuf:"varint,3,req,name=Eno" json:"Eno,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DhtMessage_StoreRsp) Reset()         { *m = DhtMessage_StoreRsp{} }
+func (m *DhtMessage_StoreRsp) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_StoreRsp) ProtoMessage()    {}
+func (*DhtMessage_StoreRsp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 6}
+}
+func (m *DhtMessage_StoreRsp) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_StoreRsp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_StoreRsp.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.Ping != nil {
-		l = m.Ping.Size()
-		n += 1 + l + sovTcpmsg(uint64(l))
+}
+func (m *DhtMessage_StoreRsp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_StoreRsp.Merge(m, src)
+}
+func (m *DhtMessage_StoreRsp) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_StoreRsp) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_StoreRsp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_StoreRsp proto.InternalMessageInfo
+
+func (m *DhtMessage_StoreRsp) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if m.Pong != nil {
-		l = m.Pong.Size()
-		n += 1 + l + sovTcpmsg(uint64(l))
+	return 0
+}
+
+func (m *DhtMessage_StoreRsp) GetKey() []byte {
+	if m != nil {
+		return m.Key
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	return nil
+}
+
+func (m *DhtMessage_StoreRsp) GetEno() uint32 {
+	if m != nil && m.Eno != nil {
+		return *m.Eno
 	}
-	return n
+	return 0
 }
 
-func (m *P2PMessage_Protocol) Size() (n int) {
-	var l int
-	_ = l
-	if m.Pid != nil {
-		n += 1 + sovTcpmsg(uint64(*m.Pid))
+type DhtMessage_GetProviders struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Key                  []byte   `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DhtMessage_GetProviders) Reset()         { *m = DhtMessage_GetProviders{} }
+func (m *DhtMessage_GetProviders) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_GetProviders) ProtoMessage()    {}
+func (*DhtMessage_GetProviders) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 7}
+}
+func (m *DhtMessage_GetProviders) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_GetProviders) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_GetProviders.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.Ver != nil {
-		l = len(m.Ver)
-		n += 1 + l + sovTcpmsg(uint64(l))
+}
+func (m *DhtMessage_GetProviders) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_GetProviders.Merge(m, src)
+}
+func (m *DhtMessage_GetProviders) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_GetProviders) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_GetProviders.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_GetProviders proto.InternalMessageInfo
+
+func (m *DhtMessage_GetProviders) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	return 0
+}
+
+func (m *DhtMessage_GetProviders) GetKey() []byte {
+	if m != nil {
+		return m.Key
 	}
-	return n
+	return nil
 }
 
-func (m *P2PMessage_Handshake) Size() (n int) {
-	var l int
+type DhtMessage_Providers struct {
+	Id                   *uint64            `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Key                  []byte             `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	Providers            []*DhtMessage_Node `protobuf:"bytes,3,rep,name=Providers" json:"Providers,omitempty"`
+	Closer               []*DhtMessage_Node `protobuf:"bytes,4,rep,name=Closer" json:"Closer,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *DhtMessage_Providers) Reset()         { *m = DhtMessage_Providers{} }
+func (m *DhtMessage_Providers) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_Providers) ProtoMessage()    {}
+func (*DhtMessage_Providers) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 8}
+}
+func (m *DhtMessage_Providers) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_Providers) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_Providers.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *DhtMessage_Providers) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_Providers.Merge(m, src)
+}
+func (m *DhtMessage_Providers) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_Providers) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_Providers.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_Providers proto.InternalMessageInfo
+
+func (m *DhtMessage_Providers) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *DhtMessage_Providers) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *DhtMessage_Providers) GetProviders() []*DhtMessage_Node {
+	if m != nil {
+		return m.Providers
+	}
+	return nil
+}
+
+func (m *DhtMessage_Providers) GetCloser() []*DhtMessage_Node {
+	if m != nil {
+		return m.Closer
+	}
+	return nil
+}
+
+type DhtMessage_AddProvider struct {
+	Id                   *uint64          `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Key                  []byte           `protobuf:"bytes,2,req,name=Key" json:"Key,omitempty"`
+	Provider             *DhtMessage_Node `protobuf:"bytes,3,req,name=Provider" json:"Provider,omitempty"`
+	Ttl                  *uint32          `protobuf:"varint,4,opt,name=Ttl" json:"Ttl,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *DhtMessage_AddProvider) Reset()         { *m = DhtMessage_AddProvider{} }
+func (m *DhtMessage_AddProvider) String() string { return proto.CompactTextString(m) }
+func (*DhtMessage_AddProvider) ProtoMessage()    {}
+func (*DhtMessage_AddProvider) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 9}
+}
+func (m *DhtMessage_AddProvider) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *DhtMessage_AddProvider) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_DhtMessage_AddProvider.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *DhtMessage_AddProvider) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DhtMessage_AddProvider.Merge(m, src)
+}
+func (m *DhtMessage_AddProvider) XXX_Size() int {
+	return m.Size()
+}
+func (m *DhtMessage_AddProvider) XXX_DiscardUnknown() {
+	xxx_messageInfo_DhtMessage_AddProvider.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DhtMessage_AddProvider proto.InternalMessageInfo
+
+func (m *DhtMessage_AddProvider) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *DhtMessage_AddProvider) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *DhtMessage_AddProvider) GetProvider() *DhtMessage_Node {
+	if m != nil {
+		return m.Provider
+	}
+	return nil
+}
+
+func (m *DhtMessage_AddProvider) GetTtl() uint32 {
+	if m != nil && m.Ttl != nil {
+		return *m.Ttl
+	}
+	return 0
+}
+
+type GossipMessage struct {
+	Mid                  *MessageId                    `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
+	Subscriptions        []*GossipMessage_Subscription `protobuf:"bytes,2,rep,name=subscriptions" json:"subscriptions,omitempty"`
+	Publish              *GossipMessage_Publish        `protobuf:"bytes,3,opt,name=publish" json:"publish,omitempty"`
+	Control              *GossipMessage_Control        `protobuf:"bytes,4,opt,name=control" json:"control,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                      `json:"-"`
+	XXX_unrecognized     []byte                        `json:"-"`
+	XXX_sizecache        int32                         `json:"-"`
+}
+
+func (m *GossipMessage) Reset()         { *m = GossipMessage{} }
+func (m *GossipMessage) String() string { return proto.CompactTextString(m) }
+func (*GossipMessage) ProtoMessage()    {}
+func (*GossipMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{3}
+}
+func (m *GossipMessage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GossipMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GossipMessage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GossipMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GossipMessage.Merge(m, src)
+}
+func (m *GossipMessage) XXX_Size() int {
+	return m.Size()
+}
+func (m *GossipMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_GossipMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GossipMessage proto.InternalMessageInfo
+
+func (m *GossipMessage) GetMid() MessageId {
+	if m != nil && m.Mid != nil {
+		return *m.Mid
+	}
+	return MessageId_MID_HANDSHAKE
+}
+
+func (m *GossipMessage) GetSubscriptions() []*GossipMessage_Subscription {
+	if m != nil {
+		return m.Subscriptions
+	}
+	return nil
+}
+
+func (m *GossipMessage) GetPublish() *GossipMessage_Publish {
+	if m != nil {
+		return m.Publish
+	}
+	return nil
+}
+
+func (m *GossipMessage) GetControl() *GossipMessage_Control {
+	if m != nil {
+		return m.Control
+	}
+	return nil
+}
+
+type GossipMessage_Subscription struct {
+	Topic                *string  `protobuf:"bytes,1,req,name=Topic" json:"Topic,omitempty"`
+	Subscribe            *bool    `protobuf:"varint,2,req,name=Subscribe" json:"Subscribe,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GossipMessage_Subscription) Reset()         { *m = GossipMessage_Subscription{} }
+func (m *GossipMessage_Subscription) String() string { return proto.CompactTextString(m) }
+func (*GossipMessage_Subscription) ProtoMessage()    {}
+func (*GossipMessage_Subscription) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{3, 0}
+}
+func (m *GossipMessage_Subscription) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GossipMessage_Subscription) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GossipMessage_Subscription.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GossipMessage_Subscription) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GossipMessage_Subscription.Merge(m, src)
+}
+func (m *GossipMessage_Subscription) XXX_Size() int {
+	return m.Size()
+}
+func (m *GossipMessage_Subscription) XXX_DiscardUnknown() {
+	xxx_messageInfo_GossipMessage_Subscription.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GossipMessage_Subscription proto.InternalMessageInfo
+
+func (m *GossipMessage_Subscription) GetTopic() string {
+	if m != nil && m.Topic != nil {
+		return *m.Topic
+	}
+	return ""
+}
+
+func (m *GossipMessage_Subscription) GetSubscribe() bool {
+	if m != nil && m.Subscribe != nil {
+		return *m.Subscribe
+	}
+	return false
+}
+
+type GossipMessage_Publish struct {
+	From                 []byte   `protobuf:"bytes,1,req,name=From" json:"From,omitempty"`
+	Seqno                *uint64  `protobuf:"varint,2,req,name=Seqno" json:"Seqno,omitempty"`
+	Topic                *string  `protobuf:"bytes,3,req,name=Topic" json:"Topic,omitempty"`
+	Data                 []byte   `protobuf:"bytes,4,req,name=Data" json:"Data,omitempty"`
+	Hops                 *uint32  `protobuf:"varint,5,req,name=Hops" json:"Hops,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GossipMessage_Publish) Reset()         { *m = GossipMessage_Publish{} }
+func (m *GossipMessage_Publish) String() string { return proto.CompactTextString(m) }
+func (*GossipMessage_Publish) ProtoMessage()    {}
+func (*GossipMessage_Publish) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{3, 1}
+}
+func (m *GossipMessage_Publish) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GossipMessage_Publish) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GossipMessage_Publish.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GossipMessage_Publish) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GossipMessage_Publish.Merge(m, src)
+}
+func (m *GossipMessage_Publish) XXX_Size() int {
+	return m.Size()
+}
+func (m *GossipMessage_Publish) XXX_DiscardUnknown() {
+	xxx_messageInfo_GossipMessage_Publish.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GossipMessage_Publish proto.InternalMessageInfo
+
+func (m *GossipMessage_Publish) GetFrom() []byte {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *GossipMessage_Publish) GetSeqno() uint64 {
+	if m != nil && m.Seqno != nil {
+		return *m.Seqno
+	}
+	return 0
+}
+
+func (m *GossipMessage_Publish) GetTopic() string {
+	if m != nil && m.Topic != nil {
+		return *m.Topic
+	}
+	return ""
+}
+
+func (m *GossipMessage_Publish) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *GossipMessage_Publish) GetHops() uint32 {
+	if m != nil && m.Hops != nil {
+		return *m.Hops
+	}
+	return 0
+}
+
+type GossipMessage_Control struct {
+	Topic                *string  `protobuf:"bytes,1,req,name=Topic" json:"Topic,omitempty"`
+	MsgIds               [][]byte `protobuf:"bytes,2,rep,name=MsgIds" json:"MsgIds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GossipMessage_Control) Reset()         { *m = GossipMessage_Control{} }
+func (m *GossipMessage_Control) String() string { return proto.CompactTextString(m) }
+func (*GossipMessage_Control) ProtoMessage()    {}
+func (*GossipMessage_Control) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{3, 2}
+}
+func (m *GossipMessage_Control) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *GossipMessage_Control) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_GossipMessage_Control.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *GossipMessage_Control) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GossipMessage_Control.Merge(m, src)
+}
+func (m *GossipMessage_Control) XXX_Size() int {
+	return m.Size()
+}
+func (m *GossipMessage_Control) XXX_DiscardUnknown() {
+	xxx_messageInfo_GossipMessage_Control.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GossipMessage_Control proto.InternalMessageInfo
+
+func (m *GossipMessage_Control) GetTopic() string {
+	if m != nil && m.Topic != nil {
+		return *m.Topic
+	}
+	return ""
+}
+
+func (m *GossipMessage_Control) GetMsgIds() [][]byte {
+	if m != nil {
+		return m.MsgIds
+	}
+	return nil
+}
+
+type RpcMessage struct {
+	Mid                  *MessageId           `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
+	Request              *RpcMessage_Request  `protobuf:"bytes,2,opt,name=request" json:"request,omitempty"`
+	Response             *RpcMessage_Response `protobuf:"bytes,3,opt,name=response" json:"response,omitempty"`
+	Cancel               *RpcMessage_Cancel   `protobuf:"bytes,4,opt,name=cancel" json:"cancel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *RpcMessage) Reset()         { *m = RpcMessage{} }
+func (m *RpcMessage) String() string { return proto.CompactTextString(m) }
+func (*RpcMessage) ProtoMessage()    {}
+func (*RpcMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{4}
+}
+func (m *RpcMessage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RpcMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RpcMessage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RpcMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RpcMessage.Merge(m, src)
+}
+func (m *RpcMessage) XXX_Size() int {
+	return m.Size()
+}
+func (m *RpcMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_RpcMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RpcMessage proto.InternalMessageInfo
+
+func (m *RpcMessage) GetMid() MessageId {
+	if m != nil && m.Mid != nil {
+		return *m.Mid
+	}
+	return MessageId_MID_HANDSHAKE
+}
+
+func (m *RpcMessage) GetRequest() *RpcMessage_Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (m *RpcMessage) GetResponse() *RpcMessage_Response {
+	if m != nil {
+		return m.Response
+	}
+	return nil
+}
+
+func (m *RpcMessage) GetCancel() *RpcMessage_Cancel {
+	if m != nil {
+		return m.Cancel
+	}
+	return nil
+}
+
+type RpcMessage_Request struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Method               *string  `protobuf:"bytes,2,req,name=Method" json:"Method,omitempty"`
+	Timeout              *uint32  `protobuf:"varint,3,req,name=Timeout" json:"Timeout,omitempty"`
+	Payload              []byte   `protobuf:"bytes,4,opt,name=Payload" json:"Payload,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RpcMessage_Request) Reset()         { *m = RpcMessage_Request{} }
+func (m *RpcMessage_Request) String() string { return proto.CompactTextString(m) }
+func (*RpcMessage_Request) ProtoMessage()    {}
+func (*RpcMessage_Request) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{4, 0}
+}
+func (m *RpcMessage_Request) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RpcMessage_Request) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RpcMessage_Request.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RpcMessage_Request) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RpcMessage_Request.Merge(m, src)
+}
+func (m *RpcMessage_Request) XXX_Size() int {
+	return m.Size()
+}
+func (m *RpcMessage_Request) XXX_DiscardUnknown() {
+	xxx_messageInfo_RpcMessage_Request.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RpcMessage_Request proto.InternalMessageInfo
+
+func (m *RpcMessage_Request) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *RpcMessage_Request) GetMethod() string {
+	if m != nil && m.Method != nil {
+		return *m.Method
+	}
+	return ""
+}
+
+func (m *RpcMessage_Request) GetTimeout() uint32 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+func (m *RpcMessage_Request) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+type RpcMessage_Response struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	Eno                  *uint32  `protobuf:"varint,2,req,name=Eno" json:"Eno,omitempty"`
+	Error                *string  `protobuf:"bytes,3,opt,name=Error" json:"Error,omitempty"`
+	Payload              []byte   `protobuf:"bytes,4,opt,name=Payload" json:"Payload,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RpcMessage_Response) Reset()         { *m = RpcMessage_Response{} }
+func (m *RpcMessage_Response) String() string { return proto.CompactTextString(m) }
+func (*RpcMessage_Response) ProtoMessage()    {}
+func (*RpcMessage_Response) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{4, 1}
+}
+func (m *RpcMessage_Response) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RpcMessage_Response) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RpcMessage_Response.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RpcMessage_Response) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RpcMessage_Response.Merge(m, src)
+}
+func (m *RpcMessage_Response) XXX_Size() int {
+	return m.Size()
+}
+func (m *RpcMessage_Response) XXX_DiscardUnknown() {
+	xxx_messageInfo_RpcMessage_Response.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RpcMessage_Response proto.InternalMessageInfo
+
+func (m *RpcMessage_Response) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *RpcMessage_Response) GetEno() uint32 {
+	if m != nil && m.Eno != nil {
+		return *m.Eno
+	}
+	return 0
+}
+
+func (m *RpcMessage_Response) GetError() string {
+	if m != nil && m.Error != nil {
+		return *m.Error
+	}
+	return ""
+}
+
+func (m *RpcMessage_Response) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+type RpcMessage_Cancel struct {
+	Id                   *uint64  `protobuf:"varint,1,req,name=Id" json:"Id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RpcMessage_Cancel) Reset()         { *m = RpcMessage_Cancel{} }
+func (m *RpcMessage_Cancel) String() string { return proto.CompactTextString(m) }
+func (*RpcMessage_Cancel) ProtoMessage()    {}
+func (*RpcMessage_Cancel) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{4, 2}
+}
+func (m *RpcMessage_Cancel) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RpcMessage_Cancel) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RpcMessage_Cancel.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RpcMessage_Cancel) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RpcMessage_Cancel.Merge(m, src)
+}
+func (m *RpcMessage_Cancel) XXX_Size() int {
+	return m.Size()
+}
+func (m *RpcMessage_Cancel) XXX_DiscardUnknown() {
+	xxx_messageInfo_RpcMessage_Cancel.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RpcMessage_Cancel proto.InternalMessageInfo
+
+func (m *RpcMessage_Cancel) GetId() uint64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+type StreamMessage struct {
+	Mid                  *MessageId `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
+	Id                   *uint32    `protobuf:"varint,2,req,name=Id" json:"Id,omitempty"`
+	Pid                  *uint32    `protobuf:"varint,3,opt,name=Pid" json:"Pid,omitempty"`
+	Window               *uint32    `protobuf:"varint,4,opt,name=Window" json:"Window,omitempty"`
+	Data                 []byte     `protobuf:"bytes,5,opt,name=Data" json:"Data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *StreamMessage) Reset()         { *m = StreamMessage{} }
+func (m *StreamMessage) String() string { return proto.CompactTextString(m) }
+func (*StreamMessage) ProtoMessage()    {}
+func (*StreamMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{5}
+}
+func (m *StreamMessage) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *StreamMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_StreamMessage.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *StreamMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamMessage.Merge(m, src)
+}
+func (m *StreamMessage) XXX_Size() int {
+	return m.Size()
+}
+func (m *StreamMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamMessage proto.InternalMessageInfo
+
+func (m *StreamMessage) GetMid() MessageId {
+	if m != nil && m.Mid != nil {
+		return *m.Mid
+	}
+	return MessageId_MID_HANDSHAKE
+}
+
+func (m *StreamMessage) GetId() uint32 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *StreamMessage) GetPid() uint32 {
+	if m != nil && m.Pid != nil {
+		return *m.Pid
+	}
+	return 0
+}
+
+func (m *StreamMessage) GetWindow() uint32 {
+	if m != nil && m.Window != nil {
+		return *m.Window
+	}
+	return 0
+}
+
+func (m *StreamMessage) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("tcpmsg.pb.ProtocolId", ProtocolId_name, ProtocolId_value)
+	proto.RegisterEnum("tcpmsg.pb.MessageId", MessageId_name, MessageId_value)
+	proto.RegisterType((*P2PPackage)(nil), "tcpmsg.pb.P2PPackage")
+	proto.RegisterType((*P2PMessage)(nil), "tcpmsg.pb.P2PMessage")
+	proto.RegisterType((*P2PMessage_Protocol)(nil), "tcpmsg.pb.P2PMessage.Protocol")
+	proto.RegisterType((*P2PMessage_Handshake)(nil), "tcpmsg.pb.P2PMessage.Handshake")
+	proto.RegisterType((*P2PMessage_Ping)(nil), "tcpmsg.pb.P2PMessage.Ping")
+	proto.RegisterType((*P2PMessage_Pong)(nil), "tcpmsg.pb.P2PMessage.Pong")
+	proto.RegisterType((*DhtMessage)(nil), "tcpmsg.pb.DhtMessage")
+	proto.RegisterType((*DhtMessage_Node)(nil), "tcpmsg.pb.DhtMessage.Node")
+	proto.RegisterType((*DhtMessage_FindValue)(nil), "tcpmsg.pb.DhtMessage.FindValue")
+	proto.RegisterType((*DhtMessage_Value)(nil), "tcpmsg.pb.DhtMessage.Value")
+	proto.RegisterType((*DhtMessage_FindNode)(nil), "tcpmsg.pb.DhtMessage.FindNode")
+	proto.RegisterType((*DhtMessage_Neighbors)(nil), "tcpmsg.pb.DhtMessage.Neighbors")
+	proto.RegisterType((*DhtMessage_Store)(nil), "tcpmsg.pb.DhtMessage.Store")
+	proto.RegisterType((*DhtMessage_StoreRsp)(nil), "tcpmsg.pb.DhtMessage.StoreRsp")
+	proto.RegisterType((*DhtMessage_GetProviders)(nil), "tcpmsg.pb.DhtMessage.GetProviders")
+	proto.RegisterType((*DhtMessage_Providers)(nil), "tcpmsg.pb.DhtMessage.Providers")
+	proto.RegisterType((*DhtMessage_AddProvider)(nil), "tcpmsg.pb.DhtMessage.AddProvider")
+	proto.RegisterType((*GossipMessage)(nil), "tcpmsg.pb.GossipMessage")
+	proto.RegisterType((*GossipMessage_Subscription)(nil), "tcpmsg.pb.GossipMessage.Subscription")
+	proto.RegisterType((*GossipMessage_Publish)(nil), "tcpmsg.pb.GossipMessage.Publish")
+	proto.RegisterType((*GossipMessage_Control)(nil), "tcpmsg.pb.GossipMessage.Control")
+	proto.RegisterType((*RpcMessage)(nil), "tcpmsg.pb.RpcMessage")
+	proto.RegisterType((*RpcMessage_Request)(nil), "tcpmsg.pb.RpcMessage.Request")
+	proto.RegisterType((*RpcMessage_Response)(nil), "tcpmsg.pb.RpcMessage.Response")
+	proto.RegisterType((*RpcMessage_Cancel)(nil), "tcpmsg.pb.RpcMessage.Cancel")
+	proto.RegisterType((*StreamMessage)(nil), "tcpmsg.pb.StreamMessage")
+}
+
+func init() { proto.RegisterFile("tcpmsg.proto", fileDescriptor_8bfe5b2d2751a4c4) }
+
+var fileDescriptor_8bfe5b2d2751a4c4 = []byte{
+	// 1516 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xdd, 0x6e, 0xdb, 0x46,
+	0x16, 0x8e, 0x44, 0xfd, 0x1e, 0x49, 0xde, 0xc9, 0xc4, 0xf1, 0x12, 0xdc, 0xac, 0x57, 0x6b, 0xec,
+	0x8f, 0x11, 0x60, 0x85, 0xac, 0xb0, 0xd8, 0x2c, 0x82, 0x6d, 0x0b, 0x99, 0xa2, 0x25, 0xc2, 0x36,
+	0xc5, 0x0e, 0x69, 0xbb, 0x17, 0x05, 0x0c, 0x5a, 0x62, 0x24, 0x36, 0x12, 0x29, 0x93, 0x54, 0x5a,
+	0x3f, 0x40, 0xef, 0x9b, 0xfe, 0x00, 0x05, 0xfa, 0x0a, 0x6d, 0x2f, 0x0a, 0xf4, 0xb6, 0x40, 0xef,
+	0xd2, 0x5f, 0xf4, 0x11, 0x8a, 0xf4, 0xb6, 0xef, 0xd0, 0x62, 0x86, 0x43, 0x8a, 0x72, 0x65, 0x47,
+	0x0e, 0x7a, 0x37, 0xe7, 0x9b, 0xef, 0x3b, 0xf3, 0xcd, 0x9c, 0xc3, 0xe1, 0x40, 0x35, 0xec, 0x4f,
+	0x27, 0xc1, 0xb0, 0x31, 0xf5, 0xbd, 0xd0, 0xc3, 0xe5, 0x38, 0x3a, 0xdd, 0x7a, 0x92, 0x01, 0xd0,
+	0x9b, 0xba, 0x6e, 0xf5, 0x1f, 0x59, 0x43, 0x1b, 0xff, 0x13, 0x04, 0xdd, 0x19, 0x88, 0x99, 0x7a,
+	0x76, 0x7b, 0xad, 0x79, 0xbb, 0x91, 0xf0, 0x1a, 0x3a, 0x15, 0xf6, 0xbd, 0xb1, 0x3a, 0x20, 0x94,
+	0x81, 0xff, 0x06, 0x35, 0xdd, 0x3a, 0x1f, 0x7b, 0xd6, 0x60, 0xdf, 0x76, 0x87, 0xe1, 0x48, 0xcc,
+	0xd6, 0xb3, 0xdb, 0x35, 0xb2, 0x08, 0x62, 0x11, 0x8a, 0x1c, 0x10, 0x85, 0x7a, 0x66, 0xbb, 0x4a,
+	0xe2, 0x10, 0x4b, 0x50, 0x92, 0xbd, 0xc9, 0xd4, 0xb7, 0x83, 0x40, 0xcc, 0xd5, 0x33, 0xdb, 0x35,
+	0x92, 0xc4, 0x5b, 0x9f, 0xe5, 0x99, 0xa7, 0x03, 0x3b, 0x08, 0xa8, 0xa7, 0x7f, 0x80, 0x30, 0x49,
+	0x3c, 0xad, 0xa7, 0x3c, 0x71, 0x02, 0xb5, 0x34, 0x71, 0x06, 0xf8, 0x25, 0x28, 0x8f, 0x2c, 0x77,
+	0x10, 0x8c, 0xac, 0x47, 0xb6, 0x98, 0xad, 0x67, 0xb6, 0x2b, 0xcd, 0xbf, 0xa4, 0x77, 0x90, 0x64,
+	0x6c, 0x74, 0x63, 0x1a, 0x99, 0x2b, 0x70, 0x03, 0x72, 0x53, 0xc7, 0x1d, 0x32, 0xa3, 0x95, 0xa6,
+	0xb4, 0x5c, 0xa9, 0x3b, 0xee, 0x90, 0x30, 0x1e, 0xe3, 0x7b, 0xee, 0x50, 0xcc, 0x5d, 0xc9, 0xf7,
+	0x18, 0xdf, 0x73, 0x87, 0x92, 0x02, 0xa5, 0xf8, 0x10, 0x57, 0x3f, 0x66, 0x04, 0xc2, 0x91, 0xed,
+	0xb3, 0xc3, 0xad, 0x12, 0x3a, 0x94, 0x3e, 0xcf, 0x42, 0x39, 0xf1, 0x8f, 0x37, 0xa0, 0xa0, 0x79,
+	0x03, 0x5b, 0x8d, 0x72, 0x55, 0x09, 0x8f, 0xf0, 0x1a, 0x64, 0x55, 0x9d, 0xcb, 0xb2, 0xaa, 0x4e,
+	0xf3, 0x1c, 0xb6, 0x75, 0x51, 0x60, 0x45, 0xa2, 0x43, 0x8a, 0x98, 0xb2, 0x2e, 0xe6, 0x22, 0xc4,
+	0x94, 0x75, 0x5a, 0x12, 0xb6, 0xbc, 0x36, 0x9b, 0x88, 0x79, 0x06, 0x27, 0x31, 0xfe, 0x3f, 0x94,
+	0x63, 0x6b, 0x81, 0x58, 0xa8, 0x0b, 0xdb, 0x95, 0xe6, 0xe6, 0x25, 0x3b, 0xe6, 0x34, 0x32, 0x17,
+	0xe0, 0x75, 0xc8, 0x2b, 0x6f, 0x85, 0xbe, 0x25, 0x16, 0x59, 0x13, 0x44, 0x01, 0xbe, 0x03, 0x65,
+	0x65, 0x3a, 0xd2, 0x67, 0xa7, 0x7b, 0xf6, 0xb9, 0x58, 0x62, 0x33, 0x73, 0x80, 0x6a, 0x34, 0xcf,
+	0xed, 0xdb, 0x62, 0x39, 0xd2, 0xb0, 0x80, 0x6a, 0x0c, 0x67, 0xe8, 0x5a, 0xe1, 0xcc, 0xb7, 0x45,
+	0x88, 0x34, 0x09, 0xb0, 0xd0, 0x54, 0x95, 0xc5, 0xa6, 0x92, 0x1a, 0x90, 0xa3, 0xc5, 0xa3, 0xfb,
+	0x0e, 0xec, 0x33, 0x76, 0x5c, 0x39, 0x42, 0x87, 0x73, 0x77, 0xd9, 0x94, 0x3b, 0xc6, 0xf7, 0x56,
+	0xe7, 0x6f, 0x7d, 0x59, 0x01, 0x68, 0x8f, 0xc2, 0x17, 0x68, 0xda, 0x87, 0x8e, 0x3b, 0x38, 0xb2,
+	0xc6, 0xb3, 0x65, 0x4d, 0x3b, 0xcf, 0xd8, 0xd8, 0x8d, 0x69, 0x64, 0xae, 0xc0, 0xff, 0x86, 0xfc,
+	0x63, 0x26, 0x8d, 0xba, 0xf6, 0x4f, 0xcb, 0xa5, 0x91, 0x2c, 0x62, 0xe2, 0x07, 0x50, 0xa2, 0x7a,
+	0xda, 0x28, 0xbc, 0x77, 0x37, 0x2f, 0x5f, 0x90, 0xb2, 0x48, 0xc2, 0xa7, 0x6e, 0x5d, 0xdb, 0x19,
+	0x8e, 0x4e, 0x3d, 0x3f, 0x10, 0xf3, 0x57, 0xb9, 0xd5, 0x62, 0x1a, 0x99, 0x2b, 0xa8, 0xdb, 0x20,
+	0xf4, 0x7c, 0x5b, 0x2c, 0x5c, 0xe5, 0xd6, 0xa0, 0x14, 0x12, 0x31, 0xa9, 0x5b, 0x36, 0x20, 0xc1,
+	0x54, 0x2c, 0x5e, 0xe5, 0xd6, 0xe0, 0x2c, 0x92, 0xf0, 0xf1, 0x2e, 0x54, 0x87, 0x76, 0xa8, 0xfb,
+	0xde, 0x63, 0x67, 0x60, 0xfb, 0x01, 0xeb, 0xb1, 0x4a, 0x73, 0x6b, 0xb9, 0xbe, 0x93, 0x62, 0x92,
+	0x05, 0x1d, 0x96, 0xa1, 0x3a, 0x4d, 0xa6, 0x82, 0xa9, 0x58, 0xbe, 0x6a, 0xe3, 0xa9, 0x24, 0x69,
+	0x11, 0x96, 0xa1, 0x62, 0x0d, 0x06, 0xf1, 0x2c, 0xeb, 0xdd, 0x4a, 0xf3, 0xaf, 0xcb, 0x73, 0xb4,
+	0xe6, 0x44, 0x92, 0x56, 0x49, 0x04, 0x72, 0xac, 0x0e, 0xbf, 0xe3, 0x67, 0x2f, 0xfd, 0x0b, 0xca,
+	0x49, 0x6b, 0xb1, 0x04, 0x03, 0xde, 0xec, 0x59, 0x95, 0xdd, 0x3f, 0xf4, 0xeb, 0xe4, 0xf7, 0xcf,
+	0x9e, 0x7d, 0x2e, 0x05, 0x90, 0x5f, 0x91, 0x8a, 0xd7, 0x39, 0x95, 0xdf, 0xfd, 0x5c, 0xd7, 0x84,
+	0x82, 0x3c, 0xf6, 0x02, 0xdb, 0x17, 0x73, 0x75, 0xe1, 0xc2, 0xcd, 0x99, 0x6e, 0x20, 0xda, 0x79,
+	0x9c, 0x29, 0x35, 0xa1, 0x14, 0x77, 0xe3, 0x6f, 0xd6, 0xdd, 0x80, 0x82, 0x69, 0xf9, 0x43, 0x3b,
+	0xe4, 0x4b, 0xf3, 0x48, 0xb2, 0xa1, 0x9c, 0x34, 0xe1, 0xaa, 0x22, 0x7c, 0x8f, 0xde, 0x3a, 0x03,
+	0x3b, 0x10, 0x85, 0xe7, 0x7a, 0x8b, 0x88, 0xd2, 0x2b, 0x90, 0x67, 0xad, 0x77, 0xbd, 0xf3, 0xc8,
+	0x26, 0xe7, 0x21, 0xbd, 0x0c, 0xa5, 0xb8, 0x77, 0x57, 0xc8, 0x81, 0x40, 0x50, 0x5c, 0x2f, 0xae,
+	0xa8, 0xe2, 0x7a, 0xd2, 0x3d, 0xa8, 0xa6, 0x7b, 0x77, 0x85, 0x12, 0x7e, 0x94, 0x81, 0xf2, 0x35,
+	0xf8, 0xf8, 0x7f, 0x29, 0xfa, 0x0a, 0x07, 0x93, 0xca, 0xfd, 0x22, 0xb5, 0x3e, 0x87, 0x4a, 0xaa,
+	0xff, 0x57, 0xb0, 0xf7, 0x5f, 0x28, 0xc5, 0x6c, 0x76, 0x2e, 0x57, 0x2f, 0x93, 0x70, 0xd9, 0xa7,
+	0x10, 0x8e, 0xf9, 0xeb, 0x83, 0x0e, 0xb7, 0x7e, 0x16, 0xa0, 0xd6, 0xf1, 0x82, 0xc0, 0x99, 0x5e,
+	0xf7, 0x1a, 0xdf, 0x83, 0x5a, 0x30, 0x3b, 0x0d, 0xfa, 0xbe, 0x33, 0x0d, 0x1d, 0xcf, 0x0d, 0xc4,
+	0x2c, 0xdb, 0xef, 0xdf, 0x53, 0x8a, 0x85, 0xc4, 0x0d, 0x23, 0xc5, 0x26, 0x8b, 0x5a, 0xfc, 0x00,
+	0x8a, 0xd3, 0xd9, 0xe9, 0xd8, 0x09, 0x46, 0xfc, 0x5a, 0xaf, 0x5f, 0x9a, 0x46, 0x8f, 0x78, 0x24,
+	0x16, 0x50, 0x6d, 0xdf, 0x73, 0x43, 0xdf, 0x1b, 0x8b, 0xb9, 0xe7, 0x68, 0xe5, 0x88, 0x47, 0x62,
+	0x81, 0xb4, 0x03, 0xd5, 0xb4, 0x2d, 0xda, 0xaf, 0xa6, 0x37, 0x75, 0xfa, 0x6c, 0xfb, 0x65, 0x12,
+	0x05, 0xec, 0x17, 0x1c, 0xb1, 0x4e, 0x6d, 0x56, 0x86, 0x12, 0x99, 0x03, 0xd2, 0x19, 0x14, 0xb9,
+	0x27, 0x8c, 0x21, 0xb7, 0xeb, 0x7b, 0x13, 0x7e, 0x45, 0xb1, 0x31, 0x4d, 0x69, 0xd8, 0x67, 0xae,
+	0xc7, 0x84, 0x39, 0x12, 0x05, 0xf3, 0x85, 0x84, 0xf4, 0x42, 0x18, 0x72, 0x6d, 0x2b, 0xb4, 0xd8,
+	0x5d, 0x55, 0x25, 0x6c, 0x4c, 0xb1, 0xae, 0x37, 0x0d, 0xf8, 0xfb, 0x84, 0x8d, 0xa5, 0xfb, 0x50,
+	0xe4, 0x5b, 0xb9, 0xc4, 0xf1, 0x06, 0x14, 0x0e, 0x82, 0xa1, 0x3a, 0x88, 0xaa, 0x52, 0x25, 0x3c,
+	0xda, 0xfa, 0x42, 0x00, 0x20, 0xd3, 0xfe, 0x75, 0x6b, 0x7d, 0x1f, 0x8a, 0xbe, 0x7d, 0x36, 0xb3,
+	0x83, 0x90, 0xff, 0xb0, 0xff, 0x9c, 0xe2, 0xce, 0xf3, 0x35, 0x48, 0x44, 0x22, 0x31, 0x9b, 0xfe,
+	0xcb, 0x7c, 0x3b, 0x98, 0x7a, 0x6e, 0x10, 0xff, 0xaf, 0x37, 0x2f, 0x53, 0x46, 0x2c, 0x92, 0xf0,
+	0xf1, 0x7f, 0xa0, 0xd0, 0xb7, 0xdc, 0xbe, 0x1d, 0x97, 0xf5, 0xce, 0x72, 0xa5, 0xcc, 0x38, 0x84,
+	0x73, 0x25, 0x1b, 0x8a, 0xdc, 0xc5, 0xb2, 0x1b, 0xf0, 0xc0, 0x0e, 0x47, 0xde, 0x80, 0x95, 0xa2,
+	0x4c, 0x78, 0x44, 0x9f, 0xec, 0xa6, 0x33, 0xb1, 0xbd, 0x59, 0xc8, 0x2f, 0x99, 0x38, 0x4c, 0x3f,
+	0xe6, 0x73, 0x0b, 0x8f, 0x79, 0xe9, 0x75, 0x28, 0xc5, 0x96, 0x97, 0x7d, 0xaf, 0x0a, 0xaf, 0x77,
+	0x74, 0x61, 0xb1, 0xf7, 0x93, 0xef, 0x7b, 0x3e, 0x3b, 0x83, 0x32, 0x89, 0x82, 0x2b, 0xb2, 0x8b,
+	0x50, 0x88, 0xb6, 0x75, 0x31, 0xf7, 0xd6, 0xdb, 0x19, 0xa8, 0x19, 0xa1, 0x6f, 0x5b, 0x93, 0xeb,
+	0xd6, 0x30, 0xca, 0x14, 0x99, 0xe2, 0x2e, 0xe9, 0x83, 0x5c, 0x88, 0xee, 0x02, 0xfa, 0xf2, 0xde,
+	0x80, 0xc2, 0xb1, 0xe3, 0x0e, 0xbc, 0x37, 0xf9, 0x05, 0xc1, 0xa3, 0xa4, 0x2b, 0xf3, 0xcc, 0x24,
+	0x1b, 0xdf, 0xb5, 0x00, 0xe6, 0x0f, 0x77, 0x5c, 0x81, 0xa2, 0xae, 0xb6, 0x4f, 0xf4, 0xa6, 0x8e,
+	0x6e, 0xc4, 0x41, 0xbb, 0x6b, 0xa2, 0x0c, 0x5e, 0x03, 0xa0, 0x41, 0xa7, 0x67, 0x18, 0xaa, 0x8e,
+	0xb2, 0xf1, 0x24, 0xd1, 0x65, 0x24, 0xc4, 0x93, 0x86, 0x49, 0x94, 0xd6, 0x01, 0xca, 0xe1, 0x6a,
+	0x34, 0xa9, 0xbc, 0x66, 0xa2, 0x5f, 0x32, 0x77, 0xbf, 0xcf, 0x41, 0x39, 0xd9, 0x03, 0xbe, 0x09,
+	0xb5, 0x03, 0xb5, 0x7d, 0xd2, 0x6d, 0x69, 0x6d, 0xa3, 0xdb, 0xda, 0x53, 0xd0, 0x0d, 0x5c, 0x85,
+	0x12, 0x85, 0x74, 0x55, 0xeb, 0xa0, 0x4c, 0x12, 0xf5, 0xb4, 0x0e, 0xca, 0xe2, 0xdb, 0x70, 0xf3,
+	0x20, 0x32, 0x71, 0xb2, 0xab, 0x6a, 0xed, 0xa3, 0xd6, 0xfe, 0xa1, 0x82, 0x92, 0x2c, 0x14, 0x8e,
+	0x20, 0x1b, 0xaf, 0x03, 0x4a, 0x33, 0xb5, 0x5e, 0x5b, 0x41, 0x0f, 0xd3, 0x7a, 0x4d, 0x51, 0x3b,
+	0xdd, 0x9d, 0x1e, 0x31, 0xd0, 0x30, 0xad, 0x37, 0xcc, 0x1e, 0x51, 0xd0, 0x28, 0xad, 0x67, 0x10,
+	0x31, 0x74, 0xe4, 0x60, 0x11, 0xd6, 0x63, 0xb4, 0xa3, 0x98, 0x3a, 0xe9, 0x1d, 0xa9, 0x6d, 0x85,
+	0x18, 0xe8, 0x8d, 0x74, 0xe6, 0x39, 0xfc, 0x08, 0xff, 0x11, 0x6e, 0xc5, 0x70, 0xab, 0xdd, 0x8e,
+	0x67, 0xd0, 0x18, 0x6f, 0x44, 0xfc, 0x8e, 0xa1, 0x9f, 0x18, 0x87, 0x3b, 0x86, 0x4c, 0xd4, 0x1d,
+	0x05, 0x3d, 0xcd, 0xe0, 0x75, 0xf8, 0x43, 0x8c, 0xeb, 0x87, 0x3b, 0xfb, 0xaa, 0xd1, 0x45, 0x5f,
+	0x65, 0x30, 0x86, 0x5a, 0x8c, 0x76, 0x48, 0x6b, 0xd7, 0x44, 0x5f, 0x2f, 0x60, 0x3a, 0x39, 0xd4,
+	0x14, 0xf4, 0xcd, 0x02, 0xa6, 0x76, 0x5b, 0x47, 0x0a, 0xfa, 0x76, 0x11, 0x3b, 0x6e, 0x69, 0x26,
+	0xfa, 0x2e, 0x59, 0x85, 0xe8, 0xf2, 0x09, 0x51, 0x5e, 0x3d, 0x54, 0x0c, 0x13, 0x7d, 0x4c, 0x4f,
+	0x17, 0xcd, 0x51, 0x43, 0xef, 0x69, 0x86, 0x82, 0x3e, 0xc9, 0xe2, 0x5b, 0xb0, 0x16, 0xc3, 0x72,
+	0x4b, 0x93, 0x95, 0x7d, 0xf4, 0x69, 0x36, 0xce, 0x10, 0x15, 0xf9, 0xa4, 0xa7, 0x2b, 0x1a, 0x7a,
+	0x47, 0x88, 0x77, 0xc5, 0xd1, 0x96, 0x2c, 0x2b, 0xba, 0x89, 0x9e, 0x08, 0x17, 0xd8, 0xed, 0x96,
+	0xd9, 0x42, 0xef, 0x5e, 0x64, 0x1f, 0xab, 0x5a, 0xbb, 0x77, 0x8c, 0xde, 0x13, 0x62, 0x1f, 0x1c,
+	0x97, 0xf7, 0x7b, 0x86, 0x82, 0xde, 0xbf, 0x08, 0x13, 0xc5, 0x50, 0x4c, 0xf4, 0x81, 0xb0, 0x83,
+	0x9e, 0x3e, 0xdb, 0xcc, 0xfc, 0xf0, 0x6c, 0x33, 0xf3, 0xe3, 0xb3, 0xcd, 0xcc, 0x87, 0x3f, 0x6d,
+	0xde, 0xf8, 0x75, 0x00, 0xd7, 0x25, 0xe1, 0xf1, 0x24, 0x10, 0x00, 0x00,
+}
+
+func (m *P2PPackage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *P2PPackage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *P2PPackage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Compress != nil {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Compress))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Payload != nil {
+		i -= len(m.Payload)
+		copy(dAtA[i:], m.Payload)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Payload)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.PayloadLength == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.PayloadLength))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Pid == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Pid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *P2PMessage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *P2PMessage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *P2PMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Pong != nil {
+		{
+			size, err := m.Pong.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Ping != nil {
+		{
+			size, err := m.Ping.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Handshake != nil {
+		{
+			size, err := m.Handshake.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Mid == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Mid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *P2PMessage_Protocol) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *P2PMessage_Protocol) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *P2PMessage_Protocol) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Ver == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Ver)
+		copy(dAtA[i:], m.Ver)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Ver)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Pid == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Pid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *P2PMessage_Handshake) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *P2PMessage_Handshake) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *P2PMessage_Handshake) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Compress != nil {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Compress))
+		i--
+		dAtA[i] = 0x58
+	}
+	if m.Signature != nil {
+		i -= len(m.Signature)
+		copy(dAtA[i:], m.Signature)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Signature)))
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.Nonce != nil {
+		i -= len(m.Nonce)
+		copy(dAtA[i:], m.Nonce)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Nonce)))
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.EphPubKey != nil {
+		i -= len(m.EphPubKey)
+		copy(dAtA[i:], m.EphPubKey)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.EphPubKey)))
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.Extra != nil {
+		i -= len(m.Extra)
+		copy(dAtA[i:], m.Extra)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Extra)))
+		i--
+		dAtA[i] = 0x3a
+	}
+	if len(m.Protocols) > 0 {
+		for iNdEx := len(m.Protocols) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Protocols[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x32
+		}
+	}
+	if m.ProtoNum == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.ProtoNum))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.TCP == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.TCP))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.UDP == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.UDP))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.IP == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.IP)
+		copy(dAtA[i:], m.IP)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.IP)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.NodeId == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.NodeId)
+		copy(dAtA[i:], m.NodeId)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.NodeId)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *P2PMessage_Ping) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *P2PMessage_Ping) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *P2PMessage_Ping) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Extra != nil {
+		i -= len(m.Extra)
+		copy(dAtA[i:], m.Extra)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Extra)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Seq == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Seq))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *P2PMessage_Pong) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *P2PMessage_Pong) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *P2PMessage_Pong) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Extra != nil {
+		i -= len(m.Extra)
+		copy(dAtA[i:], m.Extra)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Extra)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Seq == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Seq))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.AddProvider != nil {
+		{
+			size, err := m.AddProvider.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.ProvidersRsp != nil {
+		{
+			size, err := m.ProvidersRsp.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.GetProviders != nil {
+		{
+			size, err := m.GetProviders.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.StoreRsp != nil {
+		{
+			size, err := m.StoreRsp.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x3a
+	}
+	if m.Store != nil {
+		{
+			size, err := m.Store.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.Neighbors != nil {
+		{
+			size, err := m.Neighbors.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.FindNode != nil {
+		{
+			size, err := m.FindNode.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Value != nil {
+		{
+			size, err := m.Value.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.FindValue != nil {
+		{
+			size, err := m.FindValue.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Mid == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Mid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_Node) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_Node) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_Node) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.TCP == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.TCP))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.UDP == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.UDP))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.IP == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.IP)
+		copy(dAtA[i:], m.IP)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.IP)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.NodeId == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.NodeId)
+		copy(dAtA[i:], m.NodeId)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.NodeId)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_FindValue) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_FindValue) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_FindValue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Key == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_Value) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_Value) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_Value) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Closer) > 0 {
+		for iNdEx := len(m.Closer) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Closer[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if m.Value != nil {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Key == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_FindNode) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_FindNode) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_FindNode) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Target == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Target)
+		copy(dAtA[i:], m.Target)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Target)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_Neighbors) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_Neighbors) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_Neighbors) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Nodes) > 0 {
+		for iNdEx := len(m.Nodes) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Nodes[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.Target == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Target)
+		copy(dAtA[i:], m.Target)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Target)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_Store) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_Store) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_Store) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Value == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Key == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_StoreRsp) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_StoreRsp) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_StoreRsp) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Eno == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Eno))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Key == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_GetProviders) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_GetProviders) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_GetProviders) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Key == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_Providers) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_Providers) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_Providers) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Closer) > 0 {
+		for iNdEx := len(m.Closer) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Closer[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Providers) > 0 {
+		for iNdEx := len(m.Providers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Providers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.Key == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *DhtMessage_AddProvider) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *DhtMessage_AddProvider) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *DhtMessage_AddProvider) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Ttl != nil {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Ttl))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Provider == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		{
+			size, err := m.Provider.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Key == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Key)
+		copy(dAtA[i:], m.Key)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Key)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GossipMessage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GossipMessage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GossipMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Control != nil {
+		{
+			size, err := m.Control.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Publish != nil {
+		{
+			size, err := m.Publish.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Subscriptions) > 0 {
+		for iNdEx := len(m.Subscriptions) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Subscriptions[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if m.Mid == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Mid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GossipMessage_Subscription) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GossipMessage_Subscription) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GossipMessage_Subscription) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Subscribe == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i--
+		if *m.Subscribe {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Topic == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(*m.Topic)
+		copy(dAtA[i:], *m.Topic)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(*m.Topic)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GossipMessage_Publish) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GossipMessage_Publish) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GossipMessage_Publish) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Hops == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Hops))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Data == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Topic == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(*m.Topic)
+		copy(dAtA[i:], *m.Topic)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(*m.Topic)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Seqno == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Seqno))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.From == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(m.From)
+		copy(dAtA[i:], m.From)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.From)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *GossipMessage_Control) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *GossipMessage_Control) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *GossipMessage_Control) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.MsgIds) > 0 {
+		for iNdEx := len(m.MsgIds) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.MsgIds[iNdEx])
+			copy(dAtA[i:], m.MsgIds[iNdEx])
+			i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.MsgIds[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if m.Topic == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(*m.Topic)
+		copy(dAtA[i:], *m.Topic)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(*m.Topic)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *RpcMessage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RpcMessage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RpcMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Cancel != nil {
+		{
+			size, err := m.Cancel.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Response != nil {
+		{
+			size, err := m.Response.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Request != nil {
+		{
+			size, err := m.Request.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTcpmsg(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Mid == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Mid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *RpcMessage_Request) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RpcMessage_Request) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RpcMessage_Request) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Payload != nil {
+		i -= len(m.Payload)
+		copy(dAtA[i:], m.Payload)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Payload)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Timeout == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Timeout))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Method == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i -= len(*m.Method)
+		copy(dAtA[i:], *m.Method)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(*m.Method)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *RpcMessage_Response) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RpcMessage_Response) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RpcMessage_Response) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Payload != nil {
+		i -= len(m.Payload)
+		copy(dAtA[i:], m.Payload)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Payload)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Error != nil {
+		i -= len(*m.Error)
+		copy(dAtA[i:], *m.Error)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(*m.Error)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Eno == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Eno))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *RpcMessage_Cancel) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RpcMessage_Cancel) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RpcMessage_Cancel) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *StreamMessage) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *StreamMessage) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *StreamMessage) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Data != nil {
+		i -= len(m.Data)
+		copy(dAtA[i:], m.Data)
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Data)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.Window != nil {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Window))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Pid != nil {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Pid))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Id == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Id))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Mid == nil {
+		return 0, new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	} else {
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Mid))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintTcpmsg(dAtA []byte, offset int, v uint64) int {
+	offset -= sovTcpmsg(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *P2PPackage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Pid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Pid))
+	}
+	if m.PayloadLength != nil {
+		n += 1 + sovTcpmsg(uint64(*m.PayloadLength))
+	}
+	if m.Payload != nil {
+		l = len(m.Payload)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Compress != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Compress))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *P2PMessage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Mid))
+	}
+	if m.Handshake != nil {
+		l = m.Handshake.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Ping != nil {
+		l = m.Ping.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Pong != nil {
+		l = m.Pong.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *P2PMessage_Protocol) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Pid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Pid))
+	}
+	if m.Ver != nil {
+		l = len(m.Ver)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *P2PMessage_Handshake) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.NodeId != nil {
+		l = len(m.NodeId)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.IP != nil {
+		l = len(m.IP)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.UDP != nil {
+		n += 1 + sovTcpmsg(uint64(*m.UDP))
+	}
+	if m.TCP != nil {
+		n += 1 + sovTcpmsg(uint64(*m.TCP))
+	}
+	if m.ProtoNum != nil {
+		n += 1 + sovTcpmsg(uint64(*m.ProtoNum))
+	}
+	if len(m.Protocols) > 0 {
+		for _, e := range m.Protocols {
+			l = e.Size()
+			n += 1 + l + sovTcpmsg(uint64(l))
+		}
+	}
+	if m.Extra != nil {
+		l = len(m.Extra)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.EphPubKey != nil {
+		l = len(m.EphPubKey)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Nonce != nil {
+		l = len(m.Nonce)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Signature != nil {
+		l = len(m.Signature)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Compress != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Compress))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *P2PMessage_Ping) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Seq != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Seq))
+	}
+	if m.Extra != nil {
+		l = len(m.Extra)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *P2PMessage_Pong) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Seq != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Seq))
+	}
+	if m.Extra != nil {
+		l = len(m.Extra)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Mid))
+	}
+	if m.FindValue != nil {
+		l = m.FindValue.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Value != nil {
+		l = m.Value.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.FindNode != nil {
+		l = m.FindNode.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Neighbors != nil {
+		l = m.Neighbors.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Store != nil {
+		l = m.Store.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.StoreRsp != nil {
+		l = m.StoreRsp.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.GetProviders != nil {
+		l = m.GetProviders.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.ProvidersRsp != nil {
+		l = m.ProvidersRsp.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.AddProvider != nil {
+		l = m.AddProvider.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_Node) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.NodeId != nil {
+		l = len(m.NodeId)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.IP != nil {
+		l = len(m.IP)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.UDP != nil {
+		n += 1 + sovTcpmsg(uint64(*m.UDP))
+	}
+	if m.TCP != nil {
+		n += 1 + sovTcpmsg(uint64(*m.TCP))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_FindValue) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_Value) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Value != nil {
+		l = len(m.Value)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if len(m.Closer) > 0 {
+		for _, e := range m.Closer {
+			l = e.Size()
+			n += 1 + l + sovTcpmsg(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_FindNode) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Target != nil {
+		l = len(m.Target)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_Neighbors) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Target != nil {
+		l = len(m.Target)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if len(m.Nodes) > 0 {
+		for _, e := range m.Nodes {
+			l = e.Size()
+			n += 1 + l + sovTcpmsg(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_Store) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Value != nil {
+		l = len(m.Value)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_StoreRsp) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Eno != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Eno))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_GetProviders) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_Providers) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if len(m.Providers) > 0 {
+		for _, e := range m.Providers {
+			l = e.Size()
+			n += 1 + l + sovTcpmsg(uint64(l))
+		}
+	}
+	if len(m.Closer) > 0 {
+		for _, e := range m.Closer {
+			l = e.Size()
+			n += 1 + l + sovTcpmsg(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *DhtMessage_AddProvider) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Key != nil {
+		l = len(m.Key)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Provider != nil {
+		l = m.Provider.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Ttl != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Ttl))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *GossipMessage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Mid))
+	}
+	if len(m.Subscriptions) > 0 {
+		for _, e := range m.Subscriptions {
+			l = e.Size()
+			n += 1 + l + sovTcpmsg(uint64(l))
+		}
+	}
+	if m.Publish != nil {
+		l = m.Publish.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Control != nil {
+		l = m.Control.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *GossipMessage_Subscription) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
 	_ = l
-	if m.NodeId != nil {
-		l = len(m.NodeId)
+	if m.Topic != nil {
+		l = len(*m.Topic)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Subscribe != nil {
+		n += 2
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *GossipMessage_Publish) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.From != nil {
+		l = len(m.From)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Seqno != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Seqno))
+	}
+	if m.Topic != nil {
+		l = len(*m.Topic)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Data != nil {
+		l = len(m.Data)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Hops != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Hops))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *GossipMessage_Control) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Topic != nil {
+		l = len(*m.Topic)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if len(m.MsgIds) > 0 {
+		for _, b := range m.MsgIds {
+			l = len(b)
+			n += 1 + l + sovTcpmsg(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RpcMessage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Mid))
+	}
+	if m.Request != nil {
+		l = m.Request.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Response != nil {
+		l = m.Response.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Cancel != nil {
+		l = m.Cancel.Size()
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RpcMessage_Request) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Method != nil {
+		l = len(*m.Method)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Timeout != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Timeout))
+	}
+	if m.Payload != nil {
+		l = len(m.Payload)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RpcMessage_Response) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Eno != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Eno))
+	}
+	if m.Error != nil {
+		l = len(*m.Error)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Payload != nil {
+		l = len(m.Payload)
+		n += 1 + l + sovTcpmsg(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RpcMessage_Cancel) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *StreamMessage) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Mid))
+	}
+	if m.Id != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Id))
+	}
+	if m.Pid != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Pid))
+	}
+	if m.Window != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Window))
+	}
+	if m.Data != nil {
+		l = len(m.Data)
 		n += 1 + l + sovTcpmsg(uint64(l))
 	}
-	if m.IP != nil {
-		l = len(m.IP)
-		n += 1 + l + sovTcpmsg(uint64(l))
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovTcpmsg(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozTcpmsg(x uint64) (n int) {
+	return sovTcpmsg(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *P2PPackage) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: P2PPackage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: P2PPackage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pid", wireType)
+			}
+			var v ProtocolId
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= ProtocolId(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Pid = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PayloadLength", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.PayloadLength = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
+			if m.Payload == nil {
+				m.Payload = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compress", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compress = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *P2PMessage) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: P2PMessage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: P2PMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mid", wireType)
+			}
+			var v MessageId
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= MessageId(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Mid = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Handshake", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Handshake == nil {
+				m.Handshake = &P2PMessage_Handshake{}
+			}
+			if err := m.Handshake.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ping", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Ping == nil {
+				m.Ping = &P2PMessage_Ping{}
+			}
+			if err := m.Ping.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pong", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Pong == nil {
+				m.Pong = &P2PMessage_Pong{}
+			}
+			if err := m.Pong.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Protocol: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Protocol: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pid", wireType)
+			}
+			var v ProtocolId
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= ProtocolId(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Pid = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ver", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Ver = append(m.Ver[:0], dAtA[iNdEx:postIndex]...)
+			if m.Ver == nil {
+				m.Ver = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Handshake: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Handshake: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NodeId", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.NodeId = append(m.NodeId[:0], dAtA[iNdEx:postIndex]...)
+			if m.NodeId == nil {
+				m.NodeId = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IP", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.IP = append(m.IP[:0], dAtA[iNdEx:postIndex]...)
+			if m.IP == nil {
+				m.IP = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UDP", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.UDP = &v
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TCP", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.TCP = &v
+			hasFields[0] |= uint64(0x00000008)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProtoNum", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ProtoNum = &v
+			hasFields[0] |= uint64(0x00000010)
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Protocols", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Protocols = append(m.Protocols, &P2PMessage_Protocol{})
+			if err := m.Protocols[len(m.Protocols)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
+			if m.Extra == nil {
+				m.Extra = []byte{}
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field EphPubKey", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.EphPubKey = append(m.EphPubKey[:0], dAtA[iNdEx:postIndex]...)
+			if m.EphPubKey == nil {
+				m.EphPubKey = []byte{}
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nonce", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Nonce = append(m.Nonce[:0], dAtA[iNdEx:postIndex]...)
+			if m.Nonce == nil {
+				m.Nonce = []byte{}
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Signature", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Signature = append(m.Signature[:0], dAtA[iNdEx:postIndex]...)
+			if m.Signature == nil {
+				m.Signature = []byte{}
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compress", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compress = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000010) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Ping: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Ping: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Seq = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
+			if m.Extra == nil {
+				m.Extra = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Pong: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Pong: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Seq = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
+			if m.Extra == nil {
+				m.Extra = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: DhtMessage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: DhtMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mid", wireType)
+			}
+			var v MessageId
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= MessageId(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Mid = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FindValue", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.FindValue == nil {
+				m.FindValue = &DhtMessage_FindValue{}
+			}
+			if err := m.FindValue.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Value == nil {
+				m.Value = &DhtMessage_Value{}
+			}
+			if err := m.Value.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FindNode", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.FindNode == nil {
+				m.FindNode = &DhtMessage_FindNode{}
+			}
+			if err := m.FindNode.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Neighbors", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Neighbors == nil {
+				m.Neighbors = &DhtMessage_Neighbors{}
+			}
+			if err := m.Neighbors.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Store", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Store == nil {
+				m.Store = &DhtMessage_Store{}
+			}
+			if err := m.Store.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StoreRsp", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.StoreRsp == nil {
+				m.StoreRsp = &DhtMessage_StoreRsp{}
+			}
+			if err := m.StoreRsp.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GetProviders", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.GetProviders == nil {
+				m.GetProviders = &DhtMessage_GetProviders{}
+			}
+			if err := m.GetProviders.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ProvidersRsp", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.ProvidersRsp == nil {
+				m.ProvidersRsp = &DhtMessage_Providers{}
+			}
+			if err := m.ProvidersRsp.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AddProvider", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.AddProvider == nil {
+				m.AddProvider = &DhtMessage_AddProvider{}
+			}
+			if err := m.AddProvider.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_Node) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Node: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Node: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NodeId", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.NodeId = append(m.NodeId[:0], dAtA[iNdEx:postIndex]...)
+			if m.NodeId == nil {
+				m.NodeId = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IP", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.IP = append(m.IP[:0], dAtA[iNdEx:postIndex]...)
+			if m.IP == nil {
+				m.IP = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UDP", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.UDP = &v
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TCP", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.TCP = &v
+			hasFields[0] |= uint64(0x00000008)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_FindValue) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FindValue: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FindValue: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_Value) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Value: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Value: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Closer", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Closer = append(m.Closer, &DhtMessage_Node{})
+			if err := m.Closer[len(m.Closer)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_FindNode) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: FindNode: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: FindNode: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Target", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Target = append(m.Target[:0], dAtA[iNdEx:postIndex]...)
+			if m.Target == nil {
+				m.Target = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_Neighbors) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Neighbors: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Neighbors: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Target", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Target = append(m.Target[:0], dAtA[iNdEx:postIndex]...)
+			if m.Target == nil {
+				m.Target = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Nodes = append(m.Nodes, &DhtMessage_Node{})
+			if err := m.Nodes[len(m.Nodes)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_Store) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Store: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Store: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000004)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_StoreRsp) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: StoreRsp: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: StoreRsp: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Eno", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Eno = &v
+			hasFields[0] |= uint64(0x00000004)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_GetProviders) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GetProviders: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GetProviders: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_Providers) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Providers: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Providers: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Providers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Providers = append(m.Providers, &DhtMessage_Node{})
+			if err := m.Providers[len(m.Providers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Closer", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Closer = append(m.Closer, &DhtMessage_Node{})
+			if err := m.Closer[len(m.Closer)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *DhtMessage_AddProvider) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AddProvider: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AddProvider: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Provider", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Provider == nil {
+				m.Provider = &DhtMessage_Node{}
+			}
+			if err := m.Provider.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ttl", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Ttl = &v
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GossipMessage) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: GossipMessage: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: GossipMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mid", wireType)
+			}
+			var v MessageId
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= MessageId(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Mid = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Subscriptions", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Subscriptions = append(m.Subscriptions, &GossipMessage_Subscription{})
+			if err := m.Subscriptions[len(m.Subscriptions)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Publish", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Publish == nil {
+				m.Publish = &GossipMessage_Publish{}
+			}
+			if err := m.Publish.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Control", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Control == nil {
+				m.Control = &GossipMessage_Control{}
+			}
+			if err := m.Control.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
-	if m.UDP != nil {
-		n += 1 + sovTcpmsg(uint64(*m.UDP))
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	if m.TCP != nil {
-		n += 1 + sovTcpmsg(uint64(*m.TCP))
+	return nil
+}
+func (m *GossipMessage_Subscription) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Subscription: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Subscription: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Topic", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Topic = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Subscribe", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			b := bool(v != 0)
+			m.Subscribe = &b
+			hasFields[0] |= uint64(0x00000002)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
 	}
-	if m.ProtoNum != nil {
-		n += 1 + sovTcpmsg(uint64(*m.ProtoNum))
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
-	if len(m.Protocols) > 0 {
-		for _, e := range m.Protocols {
-			l = e.Size()
-			n += 1 + l + sovTcpmsg(uint64(l))
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *GossipMessage_Publish) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTcpmsg
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Publish: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Publish: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field From", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.From = append(m.From[:0], dAtA[iNdEx:postIndex]...)
+			if m.From == nil {
+				m.From = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Seqno", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Seqno = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Topic", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Topic = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000008)
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hops", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Hops = &v
+			hasFields[0] |= uint64(0x00000010)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTcpmsg(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
 		}
 	}
-	if m.Extra != nil {
-		l = len(m.Extra)
-		n += 1 + l + sovTcpmsg(uint64(l))
-	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
-	}
-	return n
-}
-
-func (m *P2PMessage_Ping) Size() (n int) {
-	var l int
-	_ = l
-	if m.Seq != nil {
-		n += 1 + sovTcpmsg(uint64(*m.Seq))
-	}
-	if m.Extra != nil {
-		l = len(m.Extra)
-		n += 1 + l + sovTcpmsg(uint64(l))
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
-	return n
-}
-
-func (m *P2PMessage_Pong) Size() (n int) {
-	var l int
-	_ = l
-	if m.Seq != nil {
-		n += 1 + sovTcpmsg(uint64(*m.Seq))
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
-	if m.Extra != nil {
-		l = len(m.Extra)
-		n += 1 + l + sovTcpmsg(uint64(l))
+	if hasFields[0]&uint64(0x00000008) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	if hasFields[0]&uint64(0x00000010) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
-	return n
-}
 
-func sovTcpmsg(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
-		}
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
 	}
-	return n
-}
-func sozTcpmsg(x uint64) (n int) {
-	return sovTcpmsg(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	return nil
 }
-func (m *P2PPackage) Unmarshal(dAtA []byte) error {
+func (m *GossipMessage_Control) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -769,7 +7839,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -777,17 +7847,17 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: P2PPackage: wiretype end group for non-group")
+			return fmt.Errorf("proto: Control: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: P2PPackage: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Control: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Pid", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Topic", wireType)
 			}
-			var v ProtocolId
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTcpmsg
@@ -797,37 +7867,29 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (ProtocolId(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Pid = &v
-			hasFields[0] |= uint64(0x00000001)
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PayloadLength", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTcpmsg
 			}
-			var v uint32
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTcpmsg
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
 			}
-			m.PayloadLength = &v
-			hasFields[0] |= uint64(0x00000002)
-		case 3:
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Topic = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field MsgIds", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -839,7 +7901,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -848,13 +7910,14 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
-			if m.Payload == nil {
-				m.Payload = []byte{}
-			}
+			m.MsgIds = append(m.MsgIds, make([]byte, postIndex-iNdEx))
+			copy(m.MsgIds[len(m.MsgIds)-1], dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -862,7 +7925,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
 			if (iNdEx + skippy) > l {
@@ -873,10 +7936,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 		}
 	}
 	if hasFields[0]&uint64(0x00000001) == 0 {
-		return new(proto.RequiredNotSetError)
-	}
-	if hasFields[0]&uint64(0x00000002) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 
 	if iNdEx > l {
@@ -884,7 +7944,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *P2PMessage) Unmarshal(dAtA []byte) error {
+func (m *RpcMessage) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -900,7 +7960,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -908,10 +7968,10 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: P2PMessage: wiretype end group for non-group")
+			return fmt.Errorf("proto: RpcMessage: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: P2PMessage: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: RpcMessage: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -928,7 +7988,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (MessageId(b) & 0x7F) << shift
+				v |= MessageId(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -937,7 +7997,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Handshake", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Request", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -949,7 +8009,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -958,19 +8018,22 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Handshake == nil {
-				m.Handshake = &P2PMessage_Handshake{}
+			if m.Request == nil {
+				m.Request = &RpcMessage_Request{}
 			}
-			if err := m.Handshake.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.Request.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ping", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Response", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -982,7 +8045,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -991,19 +8054,22 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Ping == nil {
-				m.Ping = &P2PMessage_Ping{}
+			if m.Response == nil {
+				m.Response = &RpcMessage_Response{}
 			}
-			if err := m.Ping.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.Response.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
 		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Pong", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Cancel", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -1015,7 +8081,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1024,13 +8090,16 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Pong == nil {
-				m.Pong = &P2PMessage_Pong{}
+			if m.Cancel == nil {
+				m.Cancel = &RpcMessage_Cancel{}
 			}
-			if err := m.Pong.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.Cancel.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
@@ -1040,7 +8109,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
 			if (iNdEx + skippy) > l {
@@ -1051,7 +8120,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 		}
 	}
 	if hasFields[0]&uint64(0x00000001) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 
 	if iNdEx > l {
@@ -1059,7 +8128,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
+func (m *RpcMessage_Request) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -1075,7 +8144,7 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1083,17 +8152,17 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Protocol: wiretype end group for non-group")
+			return fmt.Errorf("proto: Request: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Protocol: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Request: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Pid", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
 			}
-			var v ProtocolId
+			var v uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTcpmsg
@@ -1103,16 +8172,71 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (ProtocolId(b) & 0x7F) << shift
+				v |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Pid = &v
+			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ver", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Method", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Method = &s
+			iNdEx = postIndex
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timeout", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Timeout = &v
+			hasFields[0] |= uint64(0x00000004)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -1124,7 +8248,7 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1133,22 +8257,24 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Ver = append(m.Ver[:0], dAtA[iNdEx:postIndex]...)
-			if m.Ver == nil {
-				m.Ver = []byte{}
+			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
+			if m.Payload == nil {
+				m.Payload = []byte{}
 			}
 			iNdEx = postIndex
-			hasFields[0] |= uint64(0x00000002)
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTcpmsg(dAtA[iNdEx:])
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
 			if (iNdEx + skippy) > l {
@@ -1159,10 +8285,13 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 		}
 	}
 	if hasFields[0]&uint64(0x00000001) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 	if hasFields[0]&uint64(0x00000002) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000004) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 
 	if iNdEx > l {
@@ -1170,7 +8299,7 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
+func (m *RpcMessage_Response) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -1186,7 +8315,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1194,17 +8323,17 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Handshake: wiretype end group for non-group")
+			return fmt.Errorf("proto: Response: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Handshake: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Response: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field NodeId", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
 			}
-			var byteLen int
+			var v uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTcpmsg
@@ -1214,101 +8343,16 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				v |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthTcpmsg
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.NodeId = append(m.NodeId[:0], dAtA[iNdEx:postIndex]...)
-			if m.NodeId == nil {
-				m.NodeId = []byte{}
-			}
-			iNdEx = postIndex
+			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IP", wireType)
-			}
-			var byteLen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTcpmsg
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if byteLen < 0 {
-				return ErrInvalidLengthTcpmsg
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.IP = append(m.IP[:0], dAtA[iNdEx:postIndex]...)
-			if m.IP == nil {
-				m.IP = []byte{}
-			}
-			iNdEx = postIndex
-			hasFields[0] |= uint64(0x00000002)
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UDP", wireType)
-			}
-			var v uint32
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTcpmsg
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.UDP = &v
-			hasFields[0] |= uint64(0x00000004)
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TCP", wireType)
-			}
-			var v uint32
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTcpmsg
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.TCP = &v
-			hasFields[0] |= uint64(0x00000008)
-		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ProtoNum", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Eno", wireType)
 			}
 			var v uint32
 			for shift := uint(0); ; shift += 7 {
@@ -1320,18 +8364,18 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
+				v |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.ProtoNum = &v
-			hasFields[0] |= uint64(0x00000010)
-		case 6:
+			m.Eno = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Protocols", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Error", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTcpmsg
@@ -1341,26 +8385,28 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
-			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Protocols = append(m.Protocols, &P2PMessage_Protocol{})
-			if err := m.Protocols[len(m.Protocols)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			s := string(dAtA[iNdEx:postIndex])
+			m.Error = &s
 			iNdEx = postIndex
-		case 7:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Payload", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -1372,7 +8418,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1381,12 +8427,15 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
-			if m.Extra == nil {
-				m.Extra = []byte{}
+			m.Payload = append(m.Payload[:0], dAtA[iNdEx:postIndex]...)
+			if m.Payload == nil {
+				m.Payload = []byte{}
 			}
 			iNdEx = postIndex
 		default:
@@ -1395,7 +8444,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
 			if (iNdEx + skippy) > l {
@@ -1406,19 +8455,10 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 		}
 	}
 	if hasFields[0]&uint64(0x00000001) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 	if hasFields[0]&uint64(0x00000002) == 0 {
-		return new(proto.RequiredNotSetError)
-	}
-	if hasFields[0]&uint64(0x00000004) == 0 {
-		return new(proto.RequiredNotSetError)
-	}
-	if hasFields[0]&uint64(0x00000008) == 0 {
-		return new(proto.RequiredNotSetError)
-	}
-	if hasFields[0]&uint64(0x00000010) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 
 	if iNdEx > l {
@@ -1426,7 +8466,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
+func (m *RpcMessage_Cancel) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -1442,7 +8482,7 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1450,15 +8490,15 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Ping: wiretype end group for non-group")
+			return fmt.Errorf("proto: Cancel: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Ping: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Cancel: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
 			}
 			var v uint64
 			for shift := uint(0); ; shift += 7 {
@@ -1470,51 +8510,20 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				v |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Seq = &v
+			m.Id = &v
 			hasFields[0] |= uint64(0x00000001)
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
-			}
-			var byteLen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTcpmsg
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if byteLen < 0 {
-				return ErrInvalidLengthTcpmsg
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
-			if m.Extra == nil {
-				m.Extra = []byte{}
-			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTcpmsg(dAtA[iNdEx:])
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
 			if (iNdEx + skippy) > l {
@@ -1525,7 +8534,7 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 		}
 	}
 	if hasFields[0]&uint64(0x00000001) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 
 	if iNdEx > l {
@@ -1533,7 +8542,7 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
+func (m *StreamMessage) Unmarshal(dAtA []byte) error {
 	var hasFields [1]uint64
 	l := len(dAtA)
 	iNdEx := 0
@@ -1549,7 +8558,7 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1557,17 +8566,17 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Pong: wiretype end group for non-group")
+			return fmt.Errorf("proto: StreamMessage: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Pong: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: StreamMessage: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Seq", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Mid", wireType)
 			}
-			var v uint64
+			var v MessageId
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTcpmsg
@@ -1577,16 +8586,77 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				v |= MessageId(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.Seq = &v
+			m.Mid = &v
 			hasFields[0] |= uint64(0x00000001)
 		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Id", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Id = &v
+			hasFields[0] |= uint64(0x00000002)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Pid", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Pid = &v
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Window", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Window = &v
+		case 5:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -1598,7 +8668,7 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1607,12 +8677,15 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
-			if m.Extra == nil {
-				m.Extra = []byte{}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
 			iNdEx = postIndex
 		default:
@@ -1621,7 +8694,7 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
 			if (iNdEx + skippy) > l {
@@ -1632,7 +8705,10 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 		}
 	}
 	if hasFields[0]&uint64(0x00000001) == 0 {
-		return new(proto.RequiredNotSetError)
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
+	}
+	if hasFields[0]&uint64(0x00000002) == 0 {
+		return new(github_com_golang_protobuf_proto.RequiredNotSetError)
 	}
 
 	if iNdEx > l {
@@ -1643,6 +8719,7 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 func skipTcpmsg(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
+	depth := 0
 	for iNdEx < l {
 		var wire uint64
 		for shift := uint(0); ; shift += 7 {
@@ -1674,10 +8751,8 @@ func skipTcpmsg(dAtA []byte) (n int, err error) {
 					break
 				}
 			}
-			return iNdEx, nil
 		case 1:
 			iNdEx += 8
-			return iNdEx, nil
 		case 2:
 			var length int
 			for shift := uint(0); ; shift += 7 {
@@ -1694,88 +8769,34 @@ func skipTcpmsg(dAtA []byte) (n int, err error) {
 					break
 				}
 			}
-			iNdEx += length
 			if length < 0 {
 				return 0, ErrInvalidLengthTcpmsg
 			}
-			return iNdEx, nil
+			iNdEx += length
 		case 3:
-			for {
-				var innerWire uint64
-				var start int = iNdEx
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return 0, ErrIntOverflowTcpmsg
-					}
-					if iNdEx >= l {
-						return 0, io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					innerWire |= (uint64(b) & 0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				innerWireType := int(innerWire & 0x7)
-				if innerWireType == 4 {
-					break
-				}
-				next, err := skipTcpmsg(dAtA[start:])
-				if err != nil {
-					return 0, err
-				}
-				iNdEx = start + next
-			}
-			return iNdEx, nil
+			depth++
 		case 4:
-			return iNdEx, nil
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupTcpmsg
+			}
+			depth--
 		case 5:
 			iNdEx += 4
-			return iNdEx, nil
 		default:
 			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
 		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthTcpmsg
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
 	}
-	panic("unreachable")
+	return 0, io.ErrUnexpectedEOF
 }
 
 var (
-	ErrInvalidLengthTcpmsg = fmt.Errorf("proto: negative length found during unmarshaling")
-	ErrIntOverflowTcpmsg   = fmt.Errorf("proto: integer overflow")
+	ErrInvalidLengthTcpmsg        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowTcpmsg          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupTcpmsg = fmt.Errorf("proto: unexpected end of group")
 )
-
-func init() { proto.RegisterFile("tcpmsg.proto", fileDescriptorTcpmsg) }
-
-var fileDescriptorTcpmsg = []byte{
-	// 450 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xc1, 0x6e, 0xd3, 0x40,
-	0x10, 0x86, 0xbb, 0x6b, 0xb7, 0xa9, 0x27, 0x4e, 0x65, 0x56, 0x05, 0xad, 0x7c, 0x30, 0x56, 0x85,
-	0xa8, 0xd5, 0x83, 0x0f, 0x3e, 0x22, 0x38, 0x94, 0x26, 0x6a, 0x2c, 0xa8, 0x19, 0x2d, 0x01, 0x71,
-	0xab, 0x4c, 0x6c, 0x39, 0x51, 0x1b, 0x6f, 0x88, 0x53, 0x09, 0xde, 0x84, 0x07, 0x42, 0x82, 0x23,
-	0x8f, 0x80, 0xc2, 0x83, 0x80, 0x76, 0x63, 0x3b, 0x05, 0x41, 0x95, 0xdb, 0xfc, 0xbf, 0xbe, 0x99,
-	0xf9, 0x3d, 0x6b, 0xb0, 0x97, 0xe3, 0xf9, 0xac, 0x2a, 0xc2, 0xf9, 0x42, 0x2e, 0x25, 0xb3, 0x1a,
-	0xf5, 0xfe, 0xe8, 0x06, 0x00, 0x23, 0xc4, 0x74, 0x7c, 0x95, 0x16, 0x39, 0x3b, 0x06, 0x03, 0xa7,
-	0x19, 0x27, 0x3e, 0x0d, 0x0e, 0xa2, 0xfb, 0x61, 0x8b, 0x85, 0xa8, 0xfa, 0xc6, 0xf2, 0x3a, 0xce,
-	0x84, 0x22, 0xd8, 0x23, 0xe8, 0x61, 0xfa, 0xe9, 0x5a, 0xa6, 0xd9, 0xcb, 0xbc, 0x2c, 0x96, 0x13,
-	0x4e, 0x7d, 0x1a, 0xf4, 0xc4, 0x9f, 0x26, 0xe3, 0xd0, 0xa9, 0x0d, 0x6e, 0xf8, 0x24, 0xb0, 0x45,
-	0x23, 0x8f, 0xbe, 0x98, 0x7a, 0xef, 0x45, 0x5e, 0x55, 0x6a, 0xef, 0x63, 0x30, 0x66, 0xed, 0xde,
-	0xc3, 0x5b, 0x7b, 0x6b, 0x40, 0xad, 0x9d, 0x4d, 0x33, 0xf6, 0x0c, 0xac, 0x49, 0x5a, 0x66, 0xd5,
-	0x24, 0xbd, 0xca, 0x39, 0xf5, 0x49, 0xd0, 0x8d, 0x1e, 0xde, 0x4e, 0xd9, 0x4e, 0x0c, 0x87, 0x0d,
-	0x26, 0x36, 0x1d, 0x2c, 0x04, 0x73, 0x3e, 0x2d, 0x0b, 0x1d, 0xa6, 0x1b, 0xb9, 0xff, 0xee, 0xc4,
-	0x69, 0x59, 0x08, 0xcd, 0x69, 0x5e, 0x96, 0x05, 0x37, 0xef, 0xe4, 0xa5, 0xe6, 0x65, 0x59, 0xb8,
-	0x03, 0xd8, 0x6f, 0x0e, 0xb5, 0xfd, 0x29, 0x1d, 0x30, 0xde, 0xe6, 0x0b, 0x7d, 0x40, 0x5b, 0xa8,
-	0xd2, 0xfd, 0x4a, 0xc0, 0x6a, 0xf3, 0xb3, 0x07, 0xb0, 0x97, 0xc8, 0x2c, 0x8f, 0xd7, 0xb3, 0x6c,
-	0x51, 0x2b, 0x76, 0x00, 0x34, 0xc6, 0xba, 0x8d, 0xc6, 0xa8, 0xe6, 0xbc, 0xe9, 0x23, 0x37, 0xf4,
-	0x43, 0xa8, 0x52, 0x39, 0xa3, 0x33, 0xe4, 0xe6, 0xda, 0x19, 0x9d, 0x21, 0x73, 0xeb, 0x80, 0xc9,
-	0xcd, 0x8c, 0xef, 0x6a, 0xbb, 0xd5, 0xec, 0x29, 0x58, 0x4d, 0xb4, 0x8a, 0xef, 0xf9, 0x46, 0xd0,
-	0x8d, 0xbc, 0xff, 0x7c, 0x71, 0x8d, 0x89, 0x4d, 0x03, 0x3b, 0x84, 0xdd, 0xc1, 0xc7, 0xe5, 0x22,
-	0xe5, 0x1d, 0xfd, 0xd0, 0x6b, 0xe1, 0x86, 0x60, 0xaa, 0x73, 0xaa, 0x24, 0x55, 0xfe, 0x41, 0x7f,
-	0x80, 0x29, 0x54, 0xb9, 0xe1, 0xe9, 0xdf, 0xbc, 0xdc, 0x9e, 0x3f, 0x39, 0x06, 0xd8, 0x9c, 0x93,
-	0x75, 0xa1, 0x83, 0x71, 0xff, 0x12, 0x23, 0x74, 0x76, 0x98, 0xbd, 0x16, 0x83, 0x77, 0x23, 0xe7,
-	0x17, 0x39, 0x79, 0x02, 0x56, 0xfb, 0x2b, 0xb1, 0x7b, 0xd0, 0xbb, 0x88, 0xfb, 0x97, 0xc3, 0xd3,
-	0xa4, 0xff, 0x7a, 0x78, 0xfa, 0x62, 0xa0, 0xe9, 0x7d, 0x65, 0x61, 0x9c, 0x9c, 0x3b, 0xa4, 0x55,
-	0xaf, 0x92, 0x73, 0x87, 0x3e, 0x77, 0xbe, 0xad, 0x3c, 0xf2, 0x7d, 0xe5, 0x91, 0x1f, 0x2b, 0x8f,
-	0x7c, 0xfe, 0xe9, 0xed, 0xfc, 0x0e, 0x00, 0x00, 0xff, 0xff, 0x2c, 0x16, 0xb8, 0xba, 0x4e, 0x03,
-	0x00, 0x00,
-}
@@ -27,13 +27,17 @@ import (
 	"fmt"
 	"sync"
 	"io"
+	"bytes"
 	"math/rand"
+	"crypto/ecdsa"
+	"crypto/cipher"
 	ggio "github.com/gogo/protobuf/io"
 	ycfg	"github.com/yeeco/p2p/config"
 	sch 	"github.com/yeeco/p2p/scheduler"
 	tab		"github.com/yeeco/p2p/discover/table"
 	um		"github.com/yeeco/p2p/discover/udpmsg"
 	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/acl"
 )
 
 //
@@ -52,6 +56,8 @@ const (
 	PeMgrEnoInternal
 	PeMgrEnoPingpongTh
 	PeMgrEnoUnknown
+	PeMgrEnoMismatched
+	PeMgrEnoTxFull
 )
 
 type PeMgrErrno int
@@ -95,6 +101,7 @@ type peMgrConfig struct {
 	port			uint16			// tcp port number
 	udp				uint16			// udp port number, used with handshake procedure
 	nodeId			ycfg.NodeID		// the node's public key
+	priKey			*ecdsa.PrivateKey	// the node's private key
 	statics			[]*ycfg.Node	// statics nodes
 	noDial			bool			// do not dial outbound
 	bootstrapNode	bool			// local is a bootstrap node
@@ -104,6 +111,10 @@ type peMgrConfig struct {
 	maxMsgSize		int				// max tcpmsg package size
 	protoNum		uint32			// local protocol number
 	protocols		[]Protocol		// local protocol table
+	txQueueSize		int				// max packages queued to be sent for a peer
+	compress		uint32			// compression algorithms advertised, see PeCompressXXX
+	compressMin		int				// min size of payloads to be compressed
+	network			[]byte			// tag of network, mixed into handshake digests
 }
 
 //
@@ -131,6 +142,10 @@ type peerManager struct {
 	ptnLsn			interface{}						// pointer to peer listener manager task node
 	ptnAcp			interface{}						// pointer to peer acceptor manager task node
 	ptnDcv			interface{}						// pointer to discover task node
+	ptnDht			interface{}						// pointer to dht manager task node
+	ptnGsp			interface{}						// pointer to gossip manager task node
+	ptnRpc			interface{}						// pointer to rpc manager task node
+	ptnStr			interface{}						// pointer to stream manager task node
 	peers			map[interface{}]*peerInstance	// map peer instance's task node pointer to instance pointer
 	nodes			map[ycfg.NodeID]*peerInstance	// map peer node identity to instance pointer
 	workers			map[ycfg.NodeID]*peerInstance	// map peer node identity to pointer of instance in work
@@ -140,35 +155,61 @@ type peerManager struct {
 	acceptPaused	bool							// if accept task paused
 	randoms			[]*ycfg.Node					// random nodes found by discover
 	stats			map[ycfg.NodeID]peHistory		// history for successful and failed
+	rep				*peReputation					// reputation of peers
 	infLock			sync.Mutex						// lock for interface action from shell
+	sdl				*sch.Scheduler					// scheduler of the p2p instance
+	lsnMgr			*listenerManager				// pointer to peer listener manager
+	ibInstSeq		int								// sequence for inbound instance task name
+	obInstSeq		int								// sequence for outbound instance task name
+	indHandler		P2pInfIndCallback				// indication handler
+	lock4Cb			sync.Mutex						// lock for syncing callbacks
+	protoHandlers	map[P2pProtoKey]P2pInfPkgCallback	// package handlers by protocol, see SetProtoHandler
+	protoLock		sync.RWMutex					// lock for package handlers
 }
 
-var peMgr = peerManager{
-	name:			PeerMgrName,
-	inited:			make(chan PeMgrErrno),
-	tep:			nil,
-	cfg:			peMgrConfig{},
-	tidFindNode:	sch.SchInvalidTid,
-	ptnMe:			nil,
-	ptnTab:			nil,
-	ptnLsn:			nil,
-	peers:			map[interface{}]*peerInstance{},
-	nodes:			map[ycfg.NodeID]*peerInstance{},
-	workers:		map[ycfg.NodeID]*peerInstance{},
-	wrkNum:			0,
-	ibpNum:			0,
-	obpNum:			0,
-	acceptPaused:	false,
-	randoms:		[]*ycfg.Node{},
-	stats:			map[ycfg.NodeID]peHistory{},
+//
+// Create peer manager, it's the user data area of the static task PeerMgrName
+// of a p2p instance.
+//
+func NewPeerMgr() interface{} {
+	return &peerManager{
+		name:			PeerMgrName,
+		inited:			make(chan PeMgrErrno),
+		tep:			PeerMgrProc,
+		cfg:			peMgrConfig{},
+		tidFindNode:	sch.SchInvalidTid,
+		ptnMe:			nil,
+		ptnTab:			nil,
+		ptnLsn:			nil,
+		peers:			map[interface{}]*peerInstance{},
+		nodes:			map[ycfg.NodeID]*peerInstance{},
+		workers:		map[ycfg.NodeID]*peerInstance{},
+		wrkNum:			0,
+		ibpNum:			0,
+		obpNum:			0,
+		acceptPaused:	false,
+		randoms:		[]*ycfg.Node{},
+		stats:			map[ycfg.NodeID]peHistory{},
+		rep:			newPeReputation(),
+		protoHandlers:	map[P2pProtoKey]P2pInfPkgCallback{},
+	}
 }
 
-
 //
-// To escape the compiler "initialization loop" error
+// Get peer manager of a p2p instance by its' scheduler, for functions
+// exported to other modules.
 //
-func init() {
-	peMgr.tep = PeerMgrProc
+func peGetManager(sdl *sch.Scheduler) *peerManager {
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(sdl, PeerMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+		yclog.LogCallerFileLine("peGetManager: peer manager not found, eno: %d", eno)
+		return nil
+	}
+
+	peMgr, _ := sch.SchinfGetUserDataArea(ptn).(*peerManager)
+
+	return peMgr
 }
 
 //
@@ -182,47 +223,48 @@ func PeerMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 
 	var schEno = sch.SchEnoNone
 	var eno PeMgrErrno = PeMgrEnoNone
+	var peMgr = sch.SchinfGetUserDataArea(ptn).(*peerManager)
 
 	switch msg.Id {
 
 	case sch.EvSchPoweron:
-		eno = peMgrPoweron(ptn)
+		eno = peMgr.peMgrPoweron(ptn)
 
 	case sch.EvSchPoweroff:
-		eno = peMgrPoweroff(ptn)
+		eno = peMgr.peMgrPoweroff(ptn)
 
 	case sch.EvPeMgrStartReq:
-		eno = peMgrStartReq(msg.Body)
+		eno = peMgr.peMgrStartReq(msg.Body)
 
 	case sch.EvDcvFindNodeRsp:
-		eno = peMgrDcvFindNodeRsp(msg.Body)
+		eno = peMgr.peMgrDcvFindNodeRsp(msg.Body)
 
 	case sch.EvPeDcvFindNodeTimer:
-		eno = peMgrDcvFindNodeTimerHandler()
+		eno = peMgr.peMgrDcvFindNodeTimerHandler()
 
 	case sch.EvPeLsnConnAcceptedInd:
-		eno = peMgrLsnConnAcceptedInd(msg.Body)
+		eno = peMgr.peMgrLsnConnAcceptedInd(msg.Body)
 
 	case sch.EvPeOutboundReq:
-		eno = peMgrOutboundReq(msg.Body)
+		eno = peMgr.peMgrOutboundReq(msg.Body)
 
 	case sch.EvPeConnOutRsp:
-		eno = peMgrConnOutRsp(msg.Body)
+		eno = peMgr.peMgrConnOutRsp(msg.Body)
 
 	case sch.EvPeHandshakeRsp:
-		eno = peMgrHandshakeRsp(msg.Body)
+		eno = peMgr.peMgrHandshakeRsp(msg.Body)
 
 	case sch.EvPePingpongRsp:
-		eno = peMgrPingpongRsp(msg.Body)
+		eno = peMgr.peMgrPingpongRsp(msg.Body)
 
 	case sch.EvPeCloseReq:
-		eno = peMgrCloseReq(msg.Body)
+		eno = peMgr.peMgrCloseReq(msg.Body)
 
 	case sch.EvPeCloseCfm:
-		eno = peMgrConnCloseCfm(msg.Body)
+		eno = peMgr.peMgrConnCloseCfm(msg.Body)
 
 	case sch.EvPeCloseInd:
-		eno = peMgrConnCloseInd(msg.Body)
+		eno = peMgr.peMgrConnCloseInd(msg.Body)
 
 	default:
 		yclog.LogCallerFileLine("PeerMgrProc: invalid message: %d", msg.Id)
@@ -240,7 +282,7 @@ func PeerMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 //
 // Poweron event handler
 //
-func peMgrPoweron(ptn interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrPoweron(ptn interface{}) PeMgrErrno {
 
 	var eno = sch.SchEnoNone
 
@@ -249,7 +291,8 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 	//
 
 	peMgr.ptnMe	= ptn
-	eno, peMgr.ptnTab = sch.SchinfGetTaskNodeByName(sch.TabMgrName)
+	peMgr.sdl = sch.SchinfGetScheduler(ptn)
+	eno, peMgr.ptnTab = sch.SchinfGetTaskNodeByName(peMgr.sdl, sch.TabMgrName)
 
 	if eno != sch.SchEnoNone || peMgr.ptnTab == nil {
 
@@ -261,7 +304,7 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 		return PeMgrEnoScheduler
 	}
 
-	eno, peMgr.ptnLsn = sch.SchinfGetTaskNodeByName(PeerLsnMgrName)
+	eno, peMgr.ptnLsn = sch.SchinfGetTaskNodeByName(peMgr.sdl, PeerLsnMgrName)
 	if eno != sch.SchEnoNone || peMgr.ptnTab == nil {
 
 		yclog.LogCallerFileLine("peMgrPoweron: " +
@@ -272,7 +315,9 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 		return PeMgrEnoScheduler
 	}
 
-	eno, peMgr.ptnDcv = sch.SchinfGetTaskNodeByName(sch.DcvMgrName)
+	peMgr.lsnMgr = sch.SchinfGetUserDataArea(peMgr.ptnLsn).(*listenerManager)
+
+	eno, peMgr.ptnDcv = sch.SchinfGetTaskNodeByName(peMgr.sdl, sch.DcvMgrName)
 	if eno != sch.SchEnoNone || peMgr.ptnDcv == nil {
 
 		yclog.LogCallerFileLine("peMgrPoweron: " +
@@ -283,13 +328,70 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 		return PeMgrEnoScheduler
 	}
 
+	//
+	// the dht manager is optional, if it's not there, packages of PID_DHT
+	// received would be discarded.
+	//
+
+	eno, peMgr.ptnDht = sch.SchinfGetTaskNodeByName(peMgr.sdl, sch.DhtMgrName)
+	if eno != sch.SchEnoNone || peMgr.ptnDht == nil {
+
+		yclog.LogCallerFileLine("peMgrPoweron: " +
+			"dht manager not found, eno: %d, target: %s",
+			eno, sch.DhtMgrName)
+
+		peMgr.ptnDht = nil
+	}
+
+	//
+	// so is the gossip manager for packages of PID_GOSSIP
+	//
+
+	eno, peMgr.ptnGsp = sch.SchinfGetTaskNodeByName(peMgr.sdl, sch.GspMgrName)
+	if eno != sch.SchEnoNone || peMgr.ptnGsp == nil {
+
+		yclog.LogCallerFileLine("peMgrPoweron: " +
+			"gossip manager not found, eno: %d, target: %s",
+			eno, sch.GspMgrName)
+
+		peMgr.ptnGsp = nil
+	}
+
+	//
+	// and the rpc manager for packages of PID_RPC
+	//
+
+	eno, peMgr.ptnRpc = sch.SchinfGetTaskNodeByName(peMgr.sdl, sch.RpcMgrName)
+	if eno != sch.SchEnoNone || peMgr.ptnRpc == nil {
+
+		yclog.LogCallerFileLine("peMgrPoweron: " +
+			"rpc manager not found, eno: %d, target: %s",
+			eno, sch.RpcMgrName)
+
+		peMgr.ptnRpc = nil
+	}
+
+	//
+	// and the stream manager for packages of PID_STREAM
+	//
+
+	eno, peMgr.ptnStr = sch.SchinfGetTaskNodeByName(peMgr.sdl, sch.StrMgrName)
+	if eno != sch.SchEnoNone || peMgr.ptnStr == nil {
+
+		yclog.LogCallerFileLine("peMgrPoweron: " +
+			"stream manager not found, eno: %d, target: %s",
+			eno, sch.StrMgrName)
+
+		peMgr.ptnStr = nil
+	}
+
 	//
 	// fetch configration
 	//
 
 	var cfg *ycfg.Cfg4PeerManager = nil
 
-	if cfg = ycfg.P2pConfig4PeerManager(); cfg == nil {
+	if cfg = ycfg.P2pConfig4PeerManager(sch.SchinfGetP2pConfig(peMgr.sdl)); cfg == nil {
 
 		yclog.LogCallerFileLine("peMgrPoweron: P2pConfig4PeerManager failed")
 
@@ -305,6 +407,7 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 		port:			cfg.Port,
 		udp:			cfg.UDP,
 		nodeId:			cfg.ID,
+		priKey:			cfg.PrivateKey,
 		statics:		cfg.Statics,
 		noDial:			cfg.NoDial,
 		bootstrapNode:	cfg.BootstrapNode,
@@ -314,6 +417,10 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 		maxMsgSize:		maxTcpmsgSize,
 		protoNum:		cfg.ProtoNum,
 		protocols:		make([]Protocol, 0),
+		txQueueSize:	cfg.TxQueueSize,
+		compress:		peCompressAlgos(cfg.Compress),
+		compressMin:	cfg.CompressMin,
+		network:		cfg.Network,
 	}
 
 	for _, p := range cfg.Protocols {
@@ -322,6 +429,12 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 		)
 	}
 
+	//
+	// load scores of peers
+	//
+
+	peMgr.rep.open(cfg)
+
 	//
 	// tell initialization result
 	//
@@ -339,7 +452,13 @@ func peMgrPoweron(ptn interface{}) PeMgrErrno {
 // Get initialization result of peer manager. This function is exported to
 // outside telling the initialization result of peer manager.
 //
-func PeMgrInited() PeMgrErrno {
+func PeMgrInited(sdl *sch.Scheduler) PeMgrErrno {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound
+	}
+
 	return <-peMgr.inited
 }
 
@@ -347,7 +466,12 @@ func PeMgrInited() PeMgrErrno {
 // Startup the peer manager. This function is exported to outside modules to
 // choose a "good" chance to start the manager up.
 //
-func PeMgrStart() PeMgrErrno {
+func PeMgrStart(sdl *sch.Scheduler) PeMgrErrno {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound
+	}
 
 	//
 	// Notice: in current implement, the peer module would start its inbound and outbound
@@ -389,7 +513,7 @@ func PeMgrStart() PeMgrErrno {
 //
 // Poweroff event handler
 //
-func peMgrPoweroff(ptn interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrPoweroff(ptn interface{}) PeMgrErrno {
 
 	yclog.LogCallerFileLine("peMgrPoweroff: pwoeroff received, done the task")
 
@@ -403,6 +527,53 @@ func peMgrPoweroff(ptn interface{}) PeMgrErrno {
 		peMgr.tidFindNode = sch.SchInvalidTid
 	}
 
+	//
+	// Kill all peer instances. For those activated, the tx/rx routines must
+	// be stopped firstly as piCloseReq does, and the connection is closed
+	// before that to get them out from blocked reading or writing.
+	//
+
+	for ptnInst, inst := range peMgr.peers {
+
+		inst.txrxLock.Lock()
+
+		if inst.state == peInstStateActivated && inst.rxDone != nil {
+
+			if inst.conn != nil {
+				inst.conn.Close()
+			}
+
+			piStopTxRx(inst)
+		}
+
+		inst.txrxLock.Unlock()
+
+		if eno := peMgr.peMgrKillInst(ptnInst, nil); eno != PeMgrEnoNone {
+
+			yclog.LogCallerFileLine("peMgrPoweroff: " +
+				"peMgrKillInst failed, eno: %d, peer: %s",
+				eno, fmt.Sprintf("%X", inst.node.ID))
+		}
+	}
+
+	//
+	// Clean the manager, so it can be powered on again
+	//
+
+	peMgr.infLock.Lock()
+	peMgr.workers = map[ycfg.NodeID]*peerInstance{}
+	peMgr.infLock.Unlock()
+
+	peMgr.peers = map[interface{}]*peerInstance{}
+	peMgr.nodes = map[ycfg.NodeID]*peerInstance{}
+	peMgr.wrkNum = 0
+	peMgr.ibpNum = 0
+	peMgr.obpNum = 0
+	peMgr.acceptPaused = false
+	peMgr.randoms = []*ycfg.Node{}
+	peMgr.stats = map[ycfg.NodeID]peHistory{}
+	peMgr.rep.close()
+
 	if eno := sch.SchinfTaskDone(ptn, sch.SchEnoKilled); eno != sch.SchEnoNone {
 
 		yclog.LogCallerFileLine("peMgrPoweroff: SchinfTaskDone failed, eno: %d", eno)
@@ -415,7 +586,7 @@ func peMgrPoweroff(ptn interface{}) PeMgrErrno {
 //
 // Peer manager start request handler
 //
-func peMgrStartReq(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrStartReq(msg interface{}) PeMgrErrno {
 
 	//
 	// Notice: when this event received, we are required startup to deal with
@@ -491,7 +662,7 @@ func peMgrStartReq(msg interface{}) PeMgrErrno {
 //
 // FindNode response handler
 //
-func peMgrDcvFindNodeRsp(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrDcvFindNodeRsp(msg interface{}) PeMgrErrno {
 
 	//
 	// Here we got response about FindNode from discover task, which should contain
@@ -617,7 +788,7 @@ func peMgrDcvFindNodeRsp(msg interface{}) PeMgrErrno {
 //
 // handler of timer for find node response expired
 //
-func peMgrDcvFindNodeTimerHandler() PeMgrErrno {
+func (peMgr *peerManager) peMgrDcvFindNodeTimerHandler() PeMgrErrno {
 
 	//
 	// This timer is set after a find node request is sent peer manager to discover task.
@@ -630,15 +801,13 @@ func peMgrDcvFindNodeTimerHandler() PeMgrErrno {
 	yclog.LogCallerFileLine("peMgrDcvFindNodeTimerHandler: " +
 		"find node expired, try it again")
 
-	return peMgrAsk4More()
+	return peMgr.peMgrAsk4More()
 }
 
 //
 // Peer connection accepted indication handler
 //
-var ibInstSeq = 0
-
-func peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 
 	//
 	// Here we are indicated that an inbound connection had been accepted. We should
@@ -663,13 +832,31 @@ func peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 	}
 
 	//
-	// Init peer instance control block
+	// Check the remote address against the deny list, the identity of peer is
+	// not known till handshake, it would be checked again then.
 	//
 
 	var ibInd = msg.(*msgConnAcceptedInd)
+
+	if !acl.AclCheck(peMgr.sdl, nil, ibInd.remoteAddr.IP) {
+
+		yclog.LogCallerFileLine("peMgrLsnConnAcceptedInd: " +
+			"rejected by acl, raddr: %s",
+			ibInd.remoteAddr.String())
+
+		ibInd.conn.Close()
+
+		return PeMgrEnoNone
+	}
+
+	//
+	// Init peer instance control block
+	//
+
 	var peInst = new(peerInstance)
 
 	*peInst				= peerInstDefault
+	peInst.peMgr		= peMgr
 	peInst.ptnMgr		= peMgr.ptnMe
 	peInst.state		= peInstStateAccepted
 	peInst.cto			= peMgr.cfg.defaultCto
@@ -684,7 +871,7 @@ func peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 
 	peInst.p2pkgLock	= sync.Mutex{}
 	peInst.p2pkgRx		= nil
-	peInst.p2pkgTx		= make([]*P2pPackage, 0, PeInstMaxP2packages)
+	peInst.txq			= newPeTxQueue(peMgr.cfg.txQueueSize)
 	peInst.txDone		= make(chan PeMgrErrno, 1)
 	peInst.txExit		= make(chan PeMgrErrno)
 	peInst.rxDone		= make(chan PeMgrErrno, 1)
@@ -694,10 +881,10 @@ func peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 	// Create peer instance task
 	//
 
-	ibInstSeq++
+	peMgr.ibInstSeq++
 
 	var tskDesc  = sch.SchTaskDescription {
-		Name:		fmt.Sprintf("inbound_%s", fmt.Sprintf("%d_", ibInstSeq) + peInst.raddr.String()),
+		Name:		fmt.Sprintf("inbound_%s", fmt.Sprintf("%d_", peMgr.ibInstSeq) + peInst.raddr.String()),
 		MbSize:		PeInstMailboxSize,
 		Ep:			PeerInstProc,
 		Wd:			&sch.SchWatchDog{HaveDog:false,},
@@ -707,7 +894,7 @@ func peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 	}
 	peInst.name = peInst.name + tskDesc.Name
 
-	if eno, ptnInst = sch.SchinfCreateTask(&tskDesc);
+	if eno, ptnInst = sch.SchinfCreateTask(peMgr.sdl, &tskDesc);
 	eno != sch.SchEnoNone || ptnInst == nil {
 
 		yclog.LogCallerFileLine("peMgrLsnConnAcceptedInd: " +
@@ -779,7 +966,7 @@ func peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 		yclog.LogCallerFileLine("peMgrLsnConnAcceptedInd: " +
 			"maxInbounds reached, try to pause accept task ...")
 
-		peMgr.acceptPaused = PauseAccept()
+		peMgr.acceptPaused = peMgr.lsnMgr.PauseAccept()
 
 		yclog.LogCallerFileLine("peMgrLsnConnAcceptedInd: " +
 			"pause result: %d", peMgr.acceptPaused)
@@ -791,7 +978,7 @@ func peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 //
 // Outbound request handler
 //
-func peMgrOutboundReq(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrOutboundReq(msg interface{}) PeMgrErrno {
 
 	//
 	// Notice: the event sch.EvPeOutboundReq, which is designed to drive the manager
@@ -854,7 +1041,7 @@ func peMgrOutboundReq(msg interface{}) PeMgrErrno {
 	var count = 0
 
 	for _, n := range peMgr.cfg.statics {
-		if _, ok := peMgr.nodes[n.ID]; !ok {
+		if _, ok := peMgr.nodes[n.ID]; !ok && !peMgr.peBanned(n.ID) && acl.AclCheck(peMgr.sdl, &n.ID, n.IP) {
 			candidates = append(candidates, n)
 			count++
 		}
@@ -863,7 +1050,7 @@ func peMgrOutboundReq(msg interface{}) PeMgrErrno {
 	var rdCnt = 0
 
 	for _, n := range peMgr.randoms {
-		if _, ok := peMgr.nodes[n.ID]; !ok {
+		if _, ok := peMgr.nodes[n.ID]; !ok && !peMgr.peBanned(n.ID) && acl.AclCheck(peMgr.sdl, &n.ID, n.IP) {
 			candidates = append(candidates, n)
 			count++
 		}
@@ -905,7 +1092,7 @@ func peMgrOutboundReq(msg interface{}) PeMgrErrno {
 		// Create instance
 		//
 
-		if eno := peMgrCreateOutboundInst(n); eno != PeMgrEnoNone {
+		if eno := peMgr.peMgrCreateOutboundInst(n); eno != PeMgrEnoNone {
 
 			yclog.LogCallerFileLine("peMgrOutboundReq: " +
 				"create outbound instance failed, eno: %d", eno)
@@ -942,7 +1129,7 @@ func peMgrOutboundReq(msg interface{}) PeMgrErrno {
 
 	if peMgr.obpNum < peMgr.cfg.maxOutbounds {
 
-		if eno := peMgrAsk4More(); eno != PeMgrEnoNone {
+		if eno := peMgr.peMgrAsk4More(); eno != PeMgrEnoNone {
 
 			yclog.LogCallerFileLine("peMgrOutboundReq: " +
 				"peMgrAsk4More failed, eno: %d", eno)
@@ -957,7 +1144,7 @@ func peMgrOutboundReq(msg interface{}) PeMgrErrno {
 //
 // Outbound response handler
 //
-func peMgrConnOutRsp(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrConnOutRsp(msg interface{}) PeMgrErrno {
 
 	//
 	// This is an event from an instance task of outbound peer, telling the result
@@ -980,7 +1167,7 @@ func peMgrConnOutRsp(msg interface{}) PeMgrErrno {
 			"outbound failed, result: %d, node: %s",
 			rsp.result, fmt.Sprintf("%+v", rsp.peNode.ID))
 
-		if eno := peMgrKillInst(rsp.ptn, rsp.peNode); eno != PeMgrEnoNone {
+		if eno := peMgr.peMgrKillInst(rsp.ptn, rsp.peNode); eno != PeMgrEnoNone {
 
 			yclog.LogCallerFileLine("peMgrConnOutRsp: " +
 				"peMgrKillInst failed, eno: %d",
@@ -1028,7 +1215,7 @@ func peMgrConnOutRsp(msg interface{}) PeMgrErrno {
 //
 // Handshake response handler
 //
-func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 
 	//
 	// This is an event from an instance task of outbound or inbound peer, telling
@@ -1067,7 +1254,17 @@ func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 			rsp.result,
 			fmt.Sprintf("%X", rsp.peNode.ID))
 
-		if eno := peMgrKillInst(rsp.ptn, rsp.peNode); eno != PeMgrEnoNone {
+		//
+		// score the failure only if the peer had proved the node identity, else
+		// it might be not set yet for inbound, or not owned by the remote for
+		// outbound, and we would punish someone innocent.
+		//
+
+		if rsp.verified {
+			peMgr.peReport(rsp.peNode.ID, PeScoreHandshake, "handshake failed")
+		}
+
+		if eno := peMgr.peMgrKillInst(rsp.ptn, rsp.peNode); eno != PeMgrEnoNone {
 
 			yclog.LogCallerFileLine("peMgrHandshakeRsp: " +
 				"peMgrKillInst failed, node: %s",
@@ -1079,6 +1276,40 @@ func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 		return PeMgrEnoNone
 	}
 
+	//
+	// Check if the peer is banned, for inbound instances mostly, since banned
+	// peers are not dialed.
+	//
+
+	if peMgr.peBanned(rsp.peNode.ID) {
+
+		yclog.LogCallerFileLine("peMgrHandshakeRsp: " +
+			"banned, node: %s",
+			fmt.Sprintf("%X", rsp.peNode.ID))
+
+		return peMgr.peMgrKillInst(rsp.ptn, rsp.peNode)
+	}
+
+	//
+	// Check the peer against the deny and allow lists, with the address it
+	// connected from or to, rather than the one it claimed in handshake.
+	//
+
+	var raddr net.IP
+
+	if inst.raddr != nil {
+		raddr = inst.raddr.IP
+	}
+
+	if !acl.AclCheck(peMgr.sdl, &rsp.peNode.ID, raddr) {
+
+		yclog.LogCallerFileLine("peMgrHandshakeRsp: " +
+			"rejected by acl, node: %s",
+			fmt.Sprintf("%X", rsp.peNode.ID))
+
+		return peMgr.peMgrKillInst(rsp.ptn, rsp.peNode)
+	}
+
 	//
 	// Check duplicated for inbound instance. Notice: only here the peer manager can known the
 	// identity of peer to determine if it's duplicated to a outbound instance, which is an
@@ -1135,7 +1366,7 @@ func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 				"node2Kill: %s",
 				fmt.Sprintf("%X", *node2Kill))
 
-			if eno := peMgrKillInst(ptn2Kill, node2Kill); eno != PeMgrEnoNone {
+			if eno := peMgr.peMgrKillInst(ptn2Kill, node2Kill); eno != PeMgrEnoNone {
 
 				yclog.LogCallerFileLine("peMgrHandshakeRsp: "+
 					"peMgrKillInst failed, node: %s",
@@ -1189,7 +1420,9 @@ func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 	// identity of a inbound peer.
 	//
 
+	peMgr.infLock.Lock()
 	peMgr.workers[rsp.peNode.ID] = inst
+	peMgr.infLock.Unlock()
 	peMgr.wrkNum++
 
 	if inst.dir == PeInstDirInbound {
@@ -1215,7 +1448,7 @@ func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 			NodeId: rsp.peNode.ID,
 		}
 
-		tabEno := tab.TabBucketAddNode(&n, &lastPing, &lastPong)
+		tabEno := tab.TabBucketAddNode(peMgr.sdl, &n, &lastPing, &lastPong)
 		if tabEno != tab.TabMgrEnoNone {
 
 			yclog.LogCallerFileLine("peMgrHandshakeRsp: "+
@@ -1229,7 +1462,7 @@ func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 		// should not care the result returned from interface of table module.
 		//
 
-		tabEno = tab.TabUpdateNode(&n)
+		tabEno = tab.TabUpdateNode(peMgr.sdl, &n)
 		if tabEno != tab.TabMgrEnoNone {
 
 			yclog.LogCallerFileLine("peMgrHandshakeRsp: "+
@@ -1244,7 +1477,7 @@ func peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 //
 // Pingpong response handler
 //
-func peMgrPingpongRsp(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrPingpongRsp(msg interface{}) PeMgrErrno {
 
 	//
 	// This is an event from an instance task of outbound or inbound peer, telling
@@ -1267,7 +1500,9 @@ func peMgrPingpongRsp(msg interface{}) PeMgrErrno {
 			"outbound failed, result: %d, node: %s",
 			rsp.result, ycfg.P2pNodeId2HexString(rsp.peNode.ID))
 
-		if eno := peMgrKillInst(rsp.ptn, rsp.peNode); eno != PeMgrEnoNone {
+		peMgr.peReport(rsp.peNode.ID, PeScorePingpong, "pingpong failed")
+
+		if eno := peMgr.peMgrKillInst(rsp.ptn, rsp.peNode); eno != PeMgrEnoNone {
 
 			yclog.LogCallerFileLine("peMgrPingpongRsp: " +
 				"kill instance failed, inst: %s, node: %s",
@@ -1282,7 +1517,7 @@ func peMgrPingpongRsp(msg interface{}) PeMgrErrno {
 //
 // Event request to close peer handler
 //
-func peMgrCloseReq(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrCloseReq(msg interface{}) PeMgrErrno {
 
 	//
 	// This is an event from other module requests to close a peer connection,
@@ -1327,7 +1562,7 @@ func peMgrCloseReq(msg interface{}) PeMgrErrno {
 //
 // Peer connection closed confirm handler
 //
-func peMgrConnCloseCfm(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrConnCloseCfm(msg interface{}) PeMgrErrno {
 
 	//
 	// This is an event from an instance task of outbound or inbound peer whom
@@ -1349,7 +1584,7 @@ func peMgrConnCloseCfm(msg interface{}) PeMgrErrno {
 			cfm.result, ycfg.P2pNodeId2HexString(cfm.peNode.ID))
 	}
 
-	if eno = peMgrKillInst(cfm.ptn, cfm.peNode); eno != PeMgrEnoNone {
+	if eno = peMgr.peMgrKillInst(cfm.ptn, cfm.peNode); eno != PeMgrEnoNone {
 
 		yclog.LogCallerFileLine("peMgrConnCloseCfm: " +
 			"kill instance failed, inst: %s, node: %s",
@@ -1363,21 +1598,26 @@ func peMgrConnCloseCfm(msg interface{}) PeMgrErrno {
 	// callback to the user of p2p to tell peer closed
 	//
 
-	Lock4Cb.Lock()
+	peMgr.lock4Cb.Lock()
 
-	if P2pIndHandler != nil {
+	if peMgr.indHandler != nil {
 
 		para := P2pIndPeerClosedPara {
 			PeerId:		PeerId(cfm.peNode.ID),
 		}
 
-		P2pIndHandler(P2pIndPeerClosed, &para)
+		peMgr.indHandler(P2pIndPeerClosed, &para)
 
 	} else {
 		yclog.LogCallerFileLine("peMgrConnCloseCfm: indication callback not installed yet")
 	}
 
-	Lock4Cb.Unlock()
+	peMgr.lock4Cb.Unlock()
+
+	peMgr.peDhtPeerInd(peMgr.ptnMe, P2pIndPeerClosed, cfm.peNode)
+	peMgr.peGspPeerInd(peMgr.ptnMe, P2pIndPeerClosed, cfm.peNode)
+	peMgr.peRpcPeerInd(peMgr.ptnMe, P2pIndPeerClosed, cfm.peNode)
+	peMgr.peStrPeerInd(peMgr.ptnMe, P2pIndPeerClosed, cfm.peNode)
 
 
 	//
@@ -1413,7 +1653,7 @@ func peMgrConnCloseCfm(msg interface{}) PeMgrErrno {
 //
 // Peer connection closed indication handler
 //
-func peMgrConnCloseInd(msg interface{}) PeMgrErrno {
+func (peMgr *peerManager) peMgrConnCloseInd(msg interface{}) PeMgrErrno {
 
 	//
 	// This is an event from an instance task of outbound or inbound peer whom
@@ -1431,7 +1671,7 @@ func peMgrConnCloseInd(msg interface{}) PeMgrErrno {
 		"cause: %d, node: %s",
 		ind.cause, ycfg.P2pNodeId2HexString(ind.peNode.ID))
 
-	if eno := peMgrKillInst(ind.ptn, ind.peNode); eno != PeMgrEnoNone {
+	if eno := peMgr.peMgrKillInst(ind.ptn, ind.peNode); eno != PeMgrEnoNone {
 
 		yclog.LogCallerFileLine("peMgrConnCloseInd: " +
 			"kill instance failed, inst: %s, node: %s",
@@ -1445,21 +1685,26 @@ func peMgrConnCloseInd(msg interface{}) PeMgrErrno {
 	// callback to the user of p2p to tell peer closed
 	//
 
-	Lock4Cb.Lock()
+	peMgr.lock4Cb.Lock()
 
-	if P2pIndHandler != nil {
+	if peMgr.indHandler != nil {
 
 		para := P2pIndPeerClosedPara {
 			PeerId:		PeerId(ind.peNode.ID),
 		}
 
-		P2pIndHandler(P2pIndPeerClosed, &para)
+		peMgr.indHandler(P2pIndPeerClosed, &para)
 
 	} else {
 		yclog.LogCallerFileLine("peMgrConnCloseInd: indication callback not installed yet")
 	}
 
-	Lock4Cb.Unlock()
+	peMgr.lock4Cb.Unlock()
+
+	peMgr.peDhtPeerInd(peMgr.ptnMe, P2pIndPeerClosed, ind.peNode)
+	peMgr.peGspPeerInd(peMgr.ptnMe, P2pIndPeerClosed, ind.peNode)
+	peMgr.peRpcPeerInd(peMgr.ptnMe, P2pIndPeerClosed, ind.peNode)
+	peMgr.peStrPeerInd(peMgr.ptnMe, P2pIndPeerClosed, ind.peNode)
 
 	//
 	// since we had lost a peer, we need to drive ourself to startup outbound
@@ -1494,9 +1739,7 @@ func peMgrConnCloseInd(msg interface{}) PeMgrErrno {
 //
 // Create outbound instance
 //
-var obInstSeq = 0
-
-func peMgrCreateOutboundInst(node *ycfg.Node) PeMgrErrno {
+func (peMgr *peerManager) peMgrCreateOutboundInst(node *ycfg.Node) PeMgrErrno {
 
 	//
 	// Create outbound task instance for specific node
@@ -1512,6 +1755,7 @@ func peMgrCreateOutboundInst(node *ycfg.Node) PeMgrErrno {
 	var peInst = new(peerInstance)
 
 	*peInst				= peerInstDefault
+	peInst.peMgr		= peMgr
 	peInst.ptnMgr		= peMgr.ptnMe
 	peInst.state		= peInstStateConnOut
 	peInst.cto			= peMgr.cfg.defaultCto
@@ -1527,7 +1771,7 @@ func peMgrCreateOutboundInst(node *ycfg.Node) PeMgrErrno {
 
 	peInst.p2pkgLock	= sync.Mutex{}
 	peInst.p2pkgRx		= nil
-	peInst.p2pkgTx		= make([]*P2pPackage, 0, PeInstMaxP2packages)
+	peInst.txq			= newPeTxQueue(peMgr.cfg.txQueueSize)
 	peInst.txDone		= make(chan PeMgrErrno, 1)
 	peInst.txExit		= make(chan PeMgrErrno)
 	peInst.rxDone		= make(chan PeMgrErrno, 1)
@@ -1537,10 +1781,10 @@ func peMgrCreateOutboundInst(node *ycfg.Node) PeMgrErrno {
 	// Create peer instance task
 	//
 
-	obInstSeq++
+	peMgr.obInstSeq++
 
 	var tskDesc  = sch.SchTaskDescription {
-		Name:		fmt.Sprintf("Outbound_%s", fmt.Sprintf("%d", obInstSeq)),
+		Name:		fmt.Sprintf("Outbound_%s", fmt.Sprintf("%d", peMgr.obInstSeq)),
 		MbSize:		PeInstMailboxSize,
 		Ep:			PeerInstProc,
 		Wd:			&sch.SchWatchDog{HaveDog:false,},
@@ -1550,7 +1794,7 @@ func peMgrCreateOutboundInst(node *ycfg.Node) PeMgrErrno {
 	}
 	peInst.name = peInst.name + tskDesc.Name
 
-	if eno, ptnInst = sch.SchinfCreateTask(&tskDesc);
+	if eno, ptnInst = sch.SchinfCreateTask(peMgr.sdl, &tskDesc);
 	eno != sch.SchEnoNone || ptnInst == nil {
 
 		yclog.LogCallerFileLine("peMgrCreateOutboundInst: " +
@@ -1617,7 +1861,7 @@ func peMgrCreateOutboundInst(node *ycfg.Node) PeMgrErrno {
 //
 // Kill specific instance
 //
-func peMgrKillInst(ptn interface{}, node *ycfg.Node) PeMgrErrno {
+func (peMgr *peerManager) peMgrKillInst(ptn interface{}, node *ycfg.Node) PeMgrErrno {
 
 	//
 	// Notice: when an instance is activated into state peInstStateActivated,
@@ -1661,6 +1905,20 @@ func peMgrKillInst(ptn interface{}, node *ycfg.Node) PeMgrErrno {
 
 	var peInst = peMgr.peers[ptn]
 
+	if peInst == nil {
+
+		//
+		// killed already, for example, a close-confirm received for an
+		// instance killed while it's closing.
+		//
+
+		yclog.LogCallerFileLine("peMgrKillInst: " +
+			"instance not found, task: %s",
+			sch.SchinfGetTaskName(ptn))
+
+		return PeMgrEnoNotfound
+	}
+
 	if peInst.ppTid != sch.SchInvalidTid {
 
 		if eno := sch.SchinfKillTimer(ptn, peInst.ppTid); eno != sch.SchEnoNone {
@@ -1705,7 +1963,9 @@ func peMgrKillInst(ptn interface{}, node *ycfg.Node) PeMgrErrno {
 
 	if peInst.state == peInstStateActivated {
 
+		peMgr.infLock.Lock()
 		delete(peMgr.workers, peInst.node.ID)
+		peMgr.infLock.Unlock()
 		peMgr.wrkNum--
 	}
 
@@ -1736,7 +1996,7 @@ func peMgrKillInst(ptn interface{}, node *ycfg.Node) PeMgrErrno {
 	//
 
 	if peMgr.acceptPaused == true {
-		peMgr.acceptPaused = !ResumeAccept()
+		peMgr.acceptPaused = !peMgr.lsnMgr.ResumeAccept()
 	}
 
 	return PeMgrEnoNone
@@ -1745,7 +2005,7 @@ func peMgrKillInst(ptn interface{}, node *ycfg.Node) PeMgrErrno {
 //
 // Request the discover task to findout more node for outbound
 //
-func peMgrAsk4More() PeMgrErrno {
+func (peMgr *peerManager) peMgrAsk4More() PeMgrErrno {
 
 	//
 	// Send EvDcvFindNodeReq to discover task. The filters “include" and
@@ -1873,7 +2133,7 @@ const PeInstDirOutbound		= +1	// outbound connection
 const PeInstDirInbound		= -1	// inbound connection
 
 const PeInstMailboxSize 	= 32				// mailbox size
-const PeInstMaxP2packages	= 32				// max p2p packages pending to be sent
+const PeInstMaxP2packages	= 32				// default max p2p packages pending to be sent
 const PeInstMaxPingpongCnt	= 4					// max pingpong counter value
 const PeInstPingpongCycle	= time.Second *2	// pingpong period
 
@@ -1895,12 +2155,15 @@ type peerInstance struct {
 	dir			int							// direction: outbound(+1) or inbound(-1)
 	node		ycfg.Node					// peer "node" information
 	protoNum	uint32						// peer protocol number
-	protocols	[]Protocol					// peer protocol table
+	protocols	[]Protocol					// protocols negotiated with peer
+	peerProtos	[]Protocol					// protocols advertised by peer
 	maxPkgSize	int							// max size of tcpmsg package
 	ppTid		int							// pingpong timer identity
 	p2pkgLock	sync.Mutex					// lock for p2p package tx-sync
 	p2pkgRx		P2pInfPkgCallback			// incoming p2p package callback
-	p2pkgTx		[]*P2pPackage				// outcoming p2p packages
+	txq			*peTxQueue					// queue of packages to be sent
+	txrxLock	sync.Mutex					// lock for stopping tx/rx routines
+	txrxStopped	bool						// tx/rx routines stopped
 	txDone		chan PeMgrErrno				// TX chan
 	txExit		chan PeMgrErrno				// TX had been done
 	rxDone		chan PeMgrErrno				// RX chan
@@ -1910,6 +2173,17 @@ type peerInstance struct {
 	rxEno		PeMgrErrno					// rx errno
 	txEno		PeMgrErrno					// tx errno
 	ppEno		PeMgrErrno					// pingpong errno
+	ephKey		*ecdsa.PrivateKey			// ephemeral key for handshake
+	ephNonce	[]byte						// local nonce for handshake
+	txAead		cipher.AEAD					// AEAD to seal packages sent
+	rxAead		cipher.AEAD					// AEAD to open packages received
+	verified	bool						// node identity of peer verified in handshake
+	compress	uint32						// compression algorithm negotiated, see PeCompressXXX
+	txRaw		uint64						// bytes of payloads sent
+	txWire		uint64						// bytes of payloads sent on wire
+	rxRaw		uint64						// bytes of payloads received
+	rxWire		uint64						// bytes of payloads received on wire
+	peMgr		*peerManager				// pointer to peer manager
 }
 
 //
@@ -1934,10 +2208,11 @@ var peerInstDefault = peerInstance {
 	maxPkgSize:	maxTcpmsgSize,
 	protoNum:	0,
 	protocols:	[]Protocol{{}},
+	peerProtos:	nil,
 	ppTid:		sch.SchInvalidTid,
 	p2pkgLock:	sync.Mutex{},
 	p2pkgRx:	nil,
-	p2pkgTx:	nil,
+	txq:		nil,
 	txDone:		nil,
 	txExit:		nil,
 	rxDone:		nil,
@@ -1947,6 +2222,13 @@ var peerInstDefault = peerInstance {
 	rxEno:		PeMgrEnoNone,
 	txEno:		PeMgrEnoNone,
 	ppEno:		PeMgrEnoNone,
+	ephKey:		nil,
+	ephNonce:	nil,
+	txAead:		nil,
+	rxAead:		nil,
+	verified:	false,
+	compress:	PeCompressNone,
+	peMgr:		nil,
 }
 
 //
@@ -1962,9 +2244,10 @@ type msgConnOutRsp struct {
 // EvPeHandshakeRsp message
 //
 type msgHandshakeRsp struct {
-	result	PeMgrErrno		// result of handshake action
-	peNode 	*ycfg.Node		// target node
-	ptn		interface{}		// pointer to task instance node of sender
+	result		PeMgrErrno		// result of handshake action
+	peNode 		*ycfg.Node		// target node
+	ptn			interface{}		// pointer to task instance node of sender
+	verified	bool			// node identity verified, see peerInstance.verified
 }
 
 //
@@ -2225,9 +2508,10 @@ func piHandshakeReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 	var schMsg = sch.SchMessage{}
 
 	var rsp = msgHandshakeRsp {
-		result:	eno,
-		peNode:	&inst.node,
-		ptn:	inst.ptnMe,
+		result:		eno,
+		peNode:		&inst.node,
+		ptn:		inst.ptnMe,
+		verified:	inst.verified,
 	}
 
 	schEno = sch.SchinfMakeMessage(&schMsg, inst.ptnMe, inst.ptnMgr, sch.EvPeHandshakeRsp, &rsp)
@@ -2262,6 +2546,8 @@ func piHandshakeReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 //
 func piPingpongReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	//
 	// The ping procedure is fired by a timer internal the peer task
 	// instance, or from outside module for some purpose. Notice, it
@@ -2323,11 +2609,11 @@ func piPingpongReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 			eno,
 			fmt.Sprintf("%X", inst.node.ID))
 
-		Lock4Cb.Lock()
+		peMgr.lock4Cb.Lock()
 
 		inst.ppEno = eno
 
-		if P2pIndHandler != nil {
+		if peMgr.indHandler != nil {
 
 			para := P2pIndConnStatusPara {
 				Ptn:		inst.ptnMe,
@@ -2341,13 +2627,13 @@ func piPingpongReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 				Description	:"piPingpongReq: upkg.ping failed",
 			}
 
-			P2pIndHandler(P2pIndConnStatus, &para)
+			peMgr.indHandler(P2pIndConnStatus, &para)
 
 		} else {
 			yclog.LogCallerFileLine("piPingpongReq: indication callback not installed yet")
 		}
 
-		Lock4Cb.Unlock()
+		peMgr.lock4Cb.Unlock()
 
 		return eno
 	}
@@ -2365,6 +2651,8 @@ func piPingpongReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 //
 func piCloseReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	//
 	// Notice: do not kill the instance task here in this function, just the
 	// connection of the peer is closed, and event EvPeCloseCfm sent to the
@@ -2383,16 +2671,14 @@ func piCloseReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 	var node = inst.node
 
 	//
-	// stop tx/rx rontines
+	// stop tx/rx rontines. Notice: the peer manager might be stopping them
+	// for poweroff at the same time, see peMgrPoweroff.
 	//
 
-	if inst.state == peInstStateActivated {
-
-		inst.rxDone <- PeMgrEnoNone
-		<-inst.rxExit
+	inst.txrxLock.Lock()
 
-		inst.txDone <- PeMgrEnoNone
-		<-inst.txExit
+	if inst.state == peInstStateActivated {
+		piStopTxRx(inst)
 	}
 
 	close(inst.rxDone)
@@ -2404,11 +2690,14 @@ func piCloseReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 	close(inst.txExit)
 	inst.txExit = nil
 
+	inst.txrxLock.Unlock()
+
 	inst.p2pkgLock.Lock()
 	inst.p2pkgRx = nil
-	inst.p2pkgTx = nil
 	inst.p2pkgLock.Unlock()
 
+	inst.txq.close()
+
 	//
 	// stop timer
 	//
@@ -2488,6 +2777,8 @@ func piCloseReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 //
 func piEstablishedInd(inst *peerInstance, msg interface{}) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	//
 	// When sch.EvPeEstablishedInd received, an peer instance should go into serving,
 	// means data sending and receiving. In this case, an instance should first the
@@ -2547,14 +2838,15 @@ func piEstablishedInd(inst *peerInstance, msg interface{}) PeMgrErrno {
 	inst.ppEno = PeMgrEnoNone
 
 	//
-	// setup IO writer and reader
+	// setup IO writer and reader, all packages are sealed with the keys
+	// derived while handshaking, see seclink.go for details pls.
 	//
 
 	w := inst.conn.(io.Writer)
-	inst.iow = ggio.NewDelimitedWriter(w)
+	inst.iow = newSecWriter(w, inst.txAead)
 
 	r := inst.conn.(io.Reader)
-	inst.ior = ggio.NewDelimitedReader(r, inst.maxPkgSize)
+	inst.ior = newSecReader(r, inst.rxAead, inst.maxPkgSize)
 
 	yclog.LogCallerFileLine("piEstablishedInd: " +
 		"instance is in service now, inst: %s",
@@ -2564,26 +2856,48 @@ func piEstablishedInd(inst *peerInstance, msg interface{}) PeMgrErrno {
 	// callback to the user of p2p
 	//
 
-	Lock4Cb.Lock()
+	peMgr.lock4Cb.Lock()
 
-	if P2pIndHandler != nil {
+	if peMgr.indHandler != nil {
 
 		para := P2pIndPeerActivatedPara {
 			Ptn: inst.ptnMe,
 			PeerInfo: & Handshake {
 				NodeId:		inst.node.ID,
 				ProtoNum:	inst.protoNum,
-				Protocols:	inst.protocols,
+				Protocols:	inst.peerProtos,
 			},
+			Protocols: append([]Protocol{}, inst.protocols...),
 		}
 
-		P2pIndHandler(P2pIndPeerActivated, &para)
+		peMgr.indHandler(P2pIndPeerActivated, &para)
 
 	} else {
 		yclog.LogCallerFileLine("piEstablishedInd: indication callback not installed yet")
 	}
 
-	Lock4Cb.Unlock()
+	peMgr.lock4Cb.Unlock()
+
+	//
+	// only those peers negotiated the dht protocol are told to dht, so is
+	// the gossip.
+	//
+
+	if piProtocolSupported(inst, uint32(PID_DHT)) {
+		peMgr.peDhtPeerInd(inst.ptnMe, P2pIndPeerActivated, &inst.node)
+	}
+
+	if piProtocolSupported(inst, uint32(PID_GOSSIP)) {
+		peMgr.peGspPeerInd(inst.ptnMe, P2pIndPeerActivated, &inst.node)
+	}
+
+	if piProtocolSupported(inst, uint32(PID_RPC)) {
+		peMgr.peRpcPeerInd(inst.ptnMe, P2pIndPeerActivated, &inst.node)
+	}
+
+	if piProtocolSupported(inst, uint32(PID_STREAM)) {
+		peMgr.peStrPeerInd(inst.ptnMe, P2pIndPeerActivated, &inst.node)
+	}
 
 	//
 	// :( here we go routines for tx/rx on the activated peer):
@@ -2604,6 +2918,8 @@ func piEstablishedInd(inst *peerInstance, msg interface{}) PeMgrErrno {
 //
 func piPingpongTimerHandler(inst *peerInstance) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	//
 	// This timer is for pingpong after peer is established, as heartbit.
 	// We send EvPePingpongReq event to ourselves with nil message, see
@@ -2646,9 +2962,9 @@ func piPingpongTimerHandler(inst *peerInstance) PeMgrErrno {
 			"call P2pIndHandler noping threshold reached, ppCnt: %d",
 			inst.ppCnt)
 
-		Lock4Cb.Lock()
+		peMgr.lock4Cb.Lock()
 
-		if P2pIndHandler != nil {
+		if peMgr.indHandler != nil {
 
 			para := P2pIndConnStatusPara {
 				Ptn:		inst.ptnMe,
@@ -2662,13 +2978,13 @@ func piPingpongTimerHandler(inst *peerInstance) PeMgrErrno {
 				Description	:	"piPingpongTimerHandler: threshold reached",
 			}
 
-			P2pIndHandler(P2pIndConnStatus, &para)
+			peMgr.indHandler(P2pIndConnStatus, &para)
 
 		} else {
 			yclog.LogCallerFileLine("piPingpongTimerHandler: indication callback not installed yet")
 		}
 
-		Lock4Cb.Unlock()
+		peMgr.lock4Cb.Unlock()
 
 		//
 		// close the peer instance
@@ -2738,6 +3054,8 @@ func piDataReq(inst *peerInstance, msg interface{}) PeMgrErrno {
 //
 func piHandshakeInbound(inst *peerInstance) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	var eno PeMgrErrno = PeMgrEnoNone
 	var pkg = new(P2pPackage)
 	var hs *Handshake
@@ -2764,16 +3082,34 @@ func piHandshakeInbound(inst *peerInstance) PeMgrErrno {
 		fmt.Sprintf("%+v", hs),
 		inst.raddr.String())
 
+	//
+	// check the remote does own the node identity it claimed
+	//
+
+	if eno = secHandshakeVerify(inst, hs, nil); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeInbound: " +
+			"secHandshakeVerify failed, eno: %d, peer: %s",
+			eno, inst.raddr.String())
+
+		return eno
+	}
+
+	var peerHs = hs
+
 	//
 	// backup info about protocols supported by peer. notice that here we can
 	// check against the ip and tcp port from handshake with that obtained from
-	// underlying network, but we not now.
+	// underlying network, but we not now. the ip advertised is taken unless
+	// it's unspecified or of the other family than the one the peer connected
+	// from, see ycfg.P2pPreferredIP.
 	//
 
 	inst.protoNum = hs.ProtoNum
-	inst.protocols = hs.Protocols
+	inst.peerProtos = hs.Protocols
+	inst.compress = peNegotiateCompress(peMgr.cfg.compress, hs.Compress)
 	inst.node.ID = hs.NodeId
-	inst.node.IP = append(inst.node.IP, hs.IP...)
+	inst.node.IP = ycfg.P2pPreferredIP(hs.IP, inst.raddr.IP)
 	inst.node.TCP = uint16(hs.TCP)
 	inst.node.UDP = uint16(hs.UDP)
 
@@ -2786,12 +3122,32 @@ func piHandshakeInbound(inst *peerInstance) PeMgrErrno {
 		fmt.Sprintf("%+v", hs),
 		inst.raddr.String())
 
+	hs = new(Handshake)
 	hs.NodeId = peMgr.cfg.nodeId
 	hs.IP = append(hs.IP, peMgr.cfg.ip ...)
 	hs.UDP = uint32(peMgr.cfg.udp)
 	hs.TCP = uint32(peMgr.cfg.port)
 	hs.ProtoNum = peMgr.cfg.protoNum
 	hs.Protocols = peMgr.cfg.protocols
+	hs.Compress = peMgr.cfg.compress
+
+	if eno = secHandshakePrepare(inst); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeInbound: " +
+			"secHandshakePrepare failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	if eno = secHandshakeSign(inst, hs, peerHs.Nonce); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeInbound: " +
+			"secHandshakeSign failed, eno: %d",
+			eno)
+
+		return eno
+	}
 
 	if eno = pkg.putHandshakeOutbound(inst, hs); eno != PeMgrEnoNone {
 
@@ -2802,6 +3158,59 @@ func piHandshakeInbound(inst *peerInstance) PeMgrErrno {
 		return eno
 	}
 
+	//
+	// derive keys for the secure link
+	//
+
+	if eno = secDeriveKeys(inst, peerHs); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeInbound: " +
+			"secDeriveKeys failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	//
+	// read the confirmation of remote peer, which is signed over our nonce, so
+	// it does own the node identity in this session, not just replaying some
+	// handshake captured before.
+	//
+
+	if hs, eno = pkg.getHandshakeInbound(inst); hs == nil || eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeInbound: " +
+			"read handshake confirmation failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	if eno = secConfirmVerify(inst, peerHs, hs); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeInbound: " +
+			"secConfirmVerify failed, eno: %d, peer: %s",
+			eno, inst.raddr.String())
+
+		return eno
+	}
+
+	inst.verified = true
+
+	//
+	// agree on protocols with peer, we had told peer ours above, so it can
+	// refuse us too if nothing in common.
+	//
+
+	if eno = piNegotiateProtocols(inst); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeInbound: " +
+			"piNegotiateProtocols failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
 	//
 	// update instance state
 	//
@@ -2822,6 +3231,8 @@ func piHandshakeInbound(inst *peerInstance) PeMgrErrno {
 //
 func piHandshakeOutbound(inst *peerInstance) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	var eno PeMgrErrno = PeMgrEnoNone
 	var pkg = new(P2pPackage)
 	var hs = new(Handshake)
@@ -2836,6 +3247,25 @@ func piHandshakeOutbound(inst *peerInstance) PeMgrErrno {
 	hs.TCP = uint32(peMgr.cfg.port)
 	hs.ProtoNum = peMgr.cfg.protoNum
 	hs.Protocols = append(hs.Protocols, peMgr.cfg.protocols ...)
+	hs.Compress = peMgr.cfg.compress
+
+	if eno = secHandshakePrepare(inst); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeOutbound: " +
+			"secHandshakePrepare failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	if eno = secHandshakeSign(inst, hs, nil); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeOutbound: " +
+			"secHandshakeSign failed, eno: %d",
+			eno)
+
+		return eno
+	}
 
 	yclog.LogCallerFileLine("piHandshakeOutbound: " +
 		"write handshake: %s, peer: %s",
@@ -2854,6 +3284,8 @@ func piHandshakeOutbound(inst *peerInstance) PeMgrErrno {
 	yclog.LogCallerFileLine("piHandshakeOutbound: " +
 		"write outbound Handshake message ok, try to read the incoming Handshake ...")
 
+	var localHs = hs
+
 	//
 	// read inbound handshake from remote peer
 	//
@@ -2883,41 +3315,105 @@ func piHandshakeOutbound(inst *peerInstance) PeMgrErrno {
 		return PeMgrEnoMessage
 	}
 
-	inst.node.TCP = uint16(hs.TCP)
-	inst.node.UDP = uint16(hs.UDP)
-	inst.node.IP = append(inst.node.IP, hs.IP ...)
-
 	//
-	// backup info about protocols supported by peer;
-	// update instance state;
+	// check the remote does own the node identity, and it signed our nonce,
+	// then derive keys for the secure link.
 	//
 
-	inst.protoNum = hs.ProtoNum
-	inst.protocols = hs.Protocols
-	inst.state = peInstStateHandshook
-
-	yclog.LogCallerFileLine("piHandshakeOutbound: " +
-		"the total Handshake procedure completed ok, laddr: %s, raddr: %s: peer: %s",
-		inst.laddr.String(),
-		inst.raddr.String(),
-		fmt.Sprintf("%+v", inst.node)	)
-
-	return PeMgrEnoNone
-}
+	if eno = secHandshakeVerify(inst, hs, inst.ephNonce); eno != PeMgrEnoNone {
 
-//
-// Set callback for incoming packages
-//
-func SetP2pkgCallback(cb interface{}, ptn interface{}) PeMgrErrno {
+		yclog.LogCallerFileLine("piHandshakeOutbound: " +
+			"secHandshakeVerify failed, eno: %d, peer: %s",
+			eno, inst.raddr.String())
 
-	if ptn == nil {
-		yclog.LogCallerFileLine("SetP2pkgCallback: invalid parameters")
-		return PeMgrEnoParameter
+		return eno
 	}
 
-	inst := sch.SchinfGetUserDataArea(ptn).(*peerInstance)
+	inst.verified = true
 
-	if inst == nil {
+	if eno = secDeriveKeys(inst, hs); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeOutbound: " +
+			"secDeriveKeys failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	//
+	// confirm our handshake with signature over the nonce of remote peer, see
+	// secConfirmVerify for details pls.
+	//
+
+	cfm, eno := secConfirmSign(inst, localHs, hs.Nonce)
+	if eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeOutbound: " +
+			"secConfirmSign failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	if eno = pkg.putHandshakeOutbound(inst, cfm); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeOutbound: " +
+			"write handshake confirmation failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	inst.node.TCP = uint16(hs.TCP)
+	inst.node.UDP = uint16(hs.UDP)
+
+	//
+	// the ip we dialed is kept, it's the one known to work, while the one
+	// peer advertised might be unspecified or of the other family.
+	//
+
+	//
+	// backup info about protocols supported by peer;
+	// update instance state;
+	//
+
+	inst.protoNum = hs.ProtoNum
+	inst.peerProtos = hs.Protocols
+	inst.compress = peNegotiateCompress(peMgr.cfg.compress, hs.Compress)
+
+	if eno = piNegotiateProtocols(inst); eno != PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("piHandshakeOutbound: " +
+			"piNegotiateProtocols failed, eno: %d",
+			eno)
+
+		return eno
+	}
+
+	inst.state = peInstStateHandshook
+
+	yclog.LogCallerFileLine("piHandshakeOutbound: " +
+		"the total Handshake procedure completed ok, laddr: %s, raddr: %s: peer: %s",
+		inst.laddr.String(),
+		inst.raddr.String(),
+		fmt.Sprintf("%+v", inst.node)	)
+
+	return PeMgrEnoNone
+}
+
+//
+// Set callback for incoming packages
+//
+func SetP2pkgCallback(cb interface{}, ptn interface{}) PeMgrErrno {
+
+	if ptn == nil {
+		yclog.LogCallerFileLine("SetP2pkgCallback: invalid parameters")
+		return PeMgrEnoParameter
+	}
+
+	inst := sch.SchinfGetUserDataArea(ptn).(*peerInstance)
+
+	if inst == nil {
 
 		yclog.LogCallerFileLine("SetP2pkgCallback: " +
 			"nil instance data area, task: %s",
@@ -2938,9 +3434,85 @@ func SetP2pkgCallback(cb interface{}, ptn interface{}) PeMgrErrno {
 }
 
 //
-// Send package
+// Set handler for peer status indications
+//
+func SetP2pIndHandler(sdl *sch.Scheduler, cb P2pInfIndCallback) PeMgrErrno {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound
+	}
+
+	peMgr.lock4Cb.Lock()
+	defer peMgr.lock4Cb.Unlock()
+
+	if peMgr.indHandler != nil {
+		yclog.LogCallerFileLine("SetP2pIndHandler: old one will be overlapped")
+	}
+	peMgr.indHandler = cb
+
+	return PeMgrEnoNone
+}
+
+//
+// Set package handler for a protocol of specific version, it's global for all
+// peers of a p2p instance. Packages received are routed by their protocol
+// identity and the version of the protocol negotiated with peer in handshake;
+// nil cb removes the handler. Protocols internal to p2p can't be registered.
+//
+func SetProtoHandler(sdl *sch.Scheduler, pid uint32, ver [4]byte, cb P2pInfPkgCallback) PeMgrErrno {
+
+	if pid == uint32(PID_P2P) || pid == uint32(PID_DHT) || pid == uint32(PID_GOSSIP) ||
+		pid == uint32(PID_RPC) || pid == uint32(PID_STREAM) {
+
+		yclog.LogCallerFileLine("SetProtoHandler: " +
+			"internal protocol can't be registered, pid: %d",
+			pid)
+
+		return PeMgrEnoParameter
+	}
+
+	key := P2pProtoKey{Pid: pid, Ver: ver}
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound
+	}
+
+	peMgr.protoLock.Lock()
+	defer peMgr.protoLock.Unlock()
+
+	if cb == nil {
+		delete(peMgr.protoHandlers, key)
+		return PeMgrEnoNone
+	}
+
+	if _, dup := peMgr.protoHandlers[key]; dup {
+		yclog.LogCallerFileLine("SetProtoHandler: old one will be overlapped")
+	}
+
+	peMgr.protoHandlers[key] = cb
+
+	return PeMgrEnoNone
+}
+
+//
+// Get package handler for a protocol of specific version
+//
+func (peMgr *peerManager) getProtoHandler(pid uint32, ver [4]byte) P2pInfPkgCallback {
+	peMgr.protoLock.RLock()
+	defer peMgr.protoLock.RUnlock()
+	return peMgr.protoHandlers[P2pProtoKey{Pid: pid, Ver: ver}]
+}
+
+//
+// Send package. If IdList of package is empty, it's broadcasted as what the
+// *P2pBroadcast in Extra tells. Packages are put into the send queues of the
+// peers, with TxMode P2pTxModeBlock, the caller would be blocked till there
+// is room in the queue or TxTimeout expired, so it should not be called by
+// tasks of p2p in this mode.
 //
-func SendPackage(pkg *P2pPackage2Peer) (PeMgrErrno, []*PeerId){
+func SendPackage(sdl *sch.Scheduler, pkg *P2pPackage2Peer) (PeMgrErrno, []*PeerId){
 
 	//
 	// Notice: if PeMgrEnoParameter returned, then the fail list return with nil,
@@ -2952,45 +3524,106 @@ func SendPackage(pkg *P2pPackage2Peer) (PeMgrErrno, []*PeerId){
 		return PeMgrEnoParameter, nil
 	}
 
+	var bcast *P2pBroadcast = nil
+
 	if len(pkg.IdList) == 0 {
-		yclog.LogCallerFileLine("SendPackage: invalid parameter")
+
+		var ok bool
+
+		if bcast, ok = pkg.Extra.(*P2pBroadcast); !ok || bcast == nil {
+			yclog.LogCallerFileLine("SendPackage: invalid parameter")
+			return PeMgrEnoParameter, nil
+		}
+	}
+
+	class, ok := peTxClass(pkg.Priority)
+	if !ok {
+		yclog.LogCallerFileLine("SendPackage: invalid priority: %d", pkg.Priority)
 		return PeMgrEnoParameter, nil
 	}
 
-	peMgr.infLock.Lock()
-	defer peMgr.infLock.Unlock()
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound, nil
+	}
 
+	var idList = pkg.IdList
 	var failed = make([]*PeerId, 0)
-	var inst *peerInstance = nil
+	var insts = make([]*peerInstance, 0, len(idList))
+
+	//
+	// get instances of peers with peMgr.infLock, but packages are queued without
+	// it since we might be blocked.
+	//
 
-	for _, pid := range pkg.IdList {
+	peMgr.infLock.Lock()
 
-		if inst = peMgr.workers[ycfg.NodeID(pid)]; inst == nil {
+	if bcast != nil {
 
-			yclog.LogCallerFileLine("SendPackage: " +
-				"instance not exist, id: %s",
-				fmt.Sprintf("%X", pid))
+		var eno PeMgrErrno
 
-			failed = append(failed, &pid)
-			continue
+		if eno, idList = peMgr.peBroadcastIdList(bcast, uint32(pkg.ProtoId)); eno != PeMgrEnoNone {
+			peMgr.infLock.Unlock()
+			return eno, nil
 		}
+	}
 
-		inst.p2pkgLock.Lock()
+	for idx := range idList {
 
-		if len(inst.p2pkgTx) >= PeInstMaxP2packages {
-			yclog.LogCallerFileLine("SendPackage: tx buffer full")
-			failed = append(failed, &pid)
+		pid := idList[idx]
+
+		if inst := peMgr.workers[ycfg.NodeID(pid)]; inst != nil {
+			insts = append(insts, inst)
 			continue
 		}
 
+		yclog.LogCallerFileLine("SendPackage: " +
+			"instance not exist, id: %s",
+			fmt.Sprintf("%X", pid))
+
+		failed = append(failed, &pid)
+	}
+
+	peMgr.infLock.Unlock()
+
+	var wait = pkg.TxMode == P2pTxModeBlock
+	var deadline = time.Now().Add(pkg.TxTimeout)
+	var full = false
+
+	for _, inst := range insts {
+
+		var timeout time.Duration
+
+		if wait && pkg.TxTimeout > 0 {
+			if timeout = deadline.Sub(time.Now()); timeout <= 0 {
+				timeout = time.Nanosecond
+			}
+		}
+
 		_pkg := new(P2pPackage)
 		_pkg.Pid = uint32(pkg.ProtoId)
 		_pkg.PayloadLength = uint32(pkg.PayloadLength)
 		_pkg.Payload = append(_pkg.Payload, pkg.Payload...)
 
-		inst.p2pkgTx = append(inst.p2pkgTx, _pkg)
+		eno, congested := inst.txq.enqueue(_pkg, class, wait, timeout)
+
+		if congested {
+			peMgr.peTxBackpressureInd(inst, true)
+		}
+
+		if eno != PeMgrEnoNone {
+
+			yclog.LogCallerFileLine("SendPackage: " +
+				"enqueue failed, eno: %d, id: %s",
+				eno, fmt.Sprintf("%X", inst.node.ID))
+
+			if eno == PeMgrEnoTxFull {
+				full = true
+			}
 
-		inst.p2pkgLock.Unlock()
+			pid := PeerId(inst.node.ID)
+			failed = append(failed, &pid)
+		}
 	}
 
 	if len(failed) == 0 {
@@ -2999,13 +3632,128 @@ func SendPackage(pkg *P2pPackage2Peer) (PeMgrErrno, []*PeerId){
 
 	yclog.LogCallerFileLine("SendPackage: seems failed to send packages to nodes, check it pls")
 
+	if full {
+		return PeMgrEnoTxFull, failed
+	}
+
 	return PeMgrEnoUnknown, failed
 }
 
+//
+// Get depth and capacity of the send queue of a peer
+//
+func TxQueueStatus(sdl *sch.Scheduler, id PeerId) (depth int, capacity int, eno PeMgrErrno) {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return 0, 0, PeMgrEnoNotfound
+	}
+
+	peMgr.infLock.Lock()
+	inst := peMgr.workers[ycfg.NodeID(id)]
+	peMgr.infLock.Unlock()
+
+	if inst == nil {
+		return 0, 0, PeMgrEnoNotfound
+	}
+
+	depth, capacity = inst.txq.status()
+
+	return depth, capacity, PeMgrEnoNone
+}
+
+//
+// Tell user that the send queue of a peer is congested or relieved
+//
+func (peMgr *peerManager) peTxBackpressureInd(inst *peerInstance, congested bool) {
+
+	depth, capacity := inst.txq.status()
+
+	yclog.LogCallerFileLine("peTxBackpressureInd: " +
+		"congested: %t, depth: %d, capacity: %d, peer: %s",
+		congested, depth, capacity, fmt.Sprintf("%X", inst.node.ID))
+
+	peMgr.lock4Cb.Lock()
+	defer peMgr.lock4Cb.Unlock()
+
+	if peMgr.indHandler == nil {
+		yclog.LogCallerFileLine("peTxBackpressureInd: indication callback not installed yet")
+		return
+	}
+
+	para := P2pIndTxBackpressurePara {
+		Ptn:		inst.ptnMe,
+		PeerId:		PeerId(inst.node.ID),
+		Congested:	congested,
+		Depth:		depth,
+		Capacity:	capacity,
+	}
+
+	peMgr.indHandler(P2pIndTxBackpressure, &para)
+}
+
+//
+// Get identities of peers to broadcast to. Notice: peMgr.infLock should be
+// held by caller.
+//
+func (peMgr *peerManager) peBroadcastIdList(bcast *P2pBroadcast, pid uint32) (PeMgrErrno, []PeerId) {
+
+	var idList = make([]PeerId, 0, len(peMgr.workers))
+
+	for id, inst := range peMgr.workers {
+
+		switch bcast.Mode {
+
+		case P2pBcastAll:
+
+		case P2pBcastExceptOne:
+			if PeerId(id) == bcast.Except {
+				continue
+			}
+
+		case P2pBcastProtocol:
+			if !piProtocolSupported(inst, pid) {
+				continue
+			}
+
+		default:
+			yclog.LogCallerFileLine("peBroadcastIdList: invalid mode: %d", bcast.Mode)
+			return PeMgrEnoParameter, nil
+		}
+
+		idList = append(idList, PeerId(id))
+	}
+
+	return PeMgrEnoNone, idList
+}
+
+//
+// Get nodes of those peers activated
+//
+func ActivePeers(sdl *sch.Scheduler) []*ycfg.Node {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return nil
+	}
+
+	peMgr.infLock.Lock()
+	defer peMgr.infLock.Unlock()
+
+	var nodes = make([]*ycfg.Node, 0, len(peMgr.workers))
+
+	for _, inst := range peMgr.workers {
+		node := inst.node
+		nodes = append(nodes, &node)
+	}
+
+	return nodes
+}
+
 //
 // Close connection to a peer
 //
-func ClosePeer(id *PeerId) PeMgrErrno {
+func ClosePeer(sdl *sch.Scheduler, id *PeerId) PeMgrErrno {
 
 	//
 	// Notice: this function should only be called to kill instance when it
@@ -3019,6 +3767,11 @@ func ClosePeer(id *PeerId) PeMgrErrno {
 
 	var inst *peerInstance = nil
 
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound
+	}
+
 	if inst = peMgr.workers[ycfg.NodeID(*id)]; inst == nil {
 
 		yclog.LogCallerFileLine("ClosePeer: " +
@@ -3066,115 +3819,164 @@ func ClosePeer(id *PeerId) PeMgrErrno {
 	return PeMgrEnoNone
 }
 
+//
+// Close those activated peers rejected by the deny and allow lists, it's
+// called after the lists changed. The number of peers closed is returned.
+//
+func CloseRejectedPeers(sdl *sch.Scheduler) int {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return 0
+	}
+
+	var rejected = make([]PeerId, 0)
+
+	peMgr.infLock.Lock()
+
+	for id, inst := range peMgr.workers {
+
+		var raddr net.IP
+
+		if inst.raddr != nil {
+			raddr = inst.raddr.IP
+		}
+
+		if !acl.AclCheck(sdl, &id, raddr) {
+			rejected = append(rejected, PeerId(id))
+		}
+	}
+
+	peMgr.infLock.Unlock()
+
+	var closed = 0
+
+	for idx := range rejected {
+		if ClosePeer(sdl, &rejected[idx]) == PeMgrEnoNone {
+			closed++
+		}
+	}
+
+	return closed
+}
+
+//
+// Stop tx/rx routines of an activated instance, txrxLock should be held by
+// caller. They are stopped only once, even if both the instance and the
+// peer manager try to.
+//
+func piStopTxRx(inst *peerInstance) {
+
+	if inst.txrxStopped {
+		return
+	}
+
+	inst.rxDone <- PeMgrEnoNone
+	<-inst.rxExit
+
+	inst.txq.close()
+	inst.txDone <- PeMgrEnoNone
+	<-inst.txExit
+
+	inst.txrxStopped = true
+}
+
 //
 // Instance TX routine
 //
 func piTx(inst *peerInstance) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	//
 	// This function is "go" when an instance of peer is activated to work,
-	// inbound or outbound. When use try to close the peer, this routine
-	// would then exit.
+	// inbound or outbound. When use try to close the peer, the send queue is
+	// closed and this routine would then exit.
 	//
 
 	var done PeMgrErrno = PeMgrEnoNone
 
-txBreak:
-
 	for {
 
 		//
-		// check if we are done
+		// get a package to send, blocked till one queued or the queue closed;
+		// after sending failed, we just wait to be done.
 		//
 
-		select {
+		var upkg *P2pPackage = nil
+		var relieved = false
+
+		if inst.txEno == PeMgrEnoNone {
+			upkg, relieved = inst.txq.dequeue()
+		}
 
-		case done = <-inst.txDone:
+		if relieved {
+			peMgr.peTxBackpressureInd(inst, false)
+		}
+
+		if upkg == nil {
+
+			done = <-inst.txDone
 
 			yclog.LogCallerFileLine("piTx: done with: %d", done)
 
 			inst.txExit<-done
-			break txBreak
-
-		default:
+			break
 		}
 
 		//
-		// send user package, lock needed
+		// encode and send it
 		//
 
-		if inst.txEno != PeMgrEnoNone {
-			yclog.LogCallerFileLine("piTx: txEno: %d", inst.txEno)
-			continue
-		}
-
-		inst.p2pkgLock.Lock()
+		yclog.LogCallerFileLine("piTx: " +
+			"send package, Pid: %d, PayloadLength: %d",
+			upkg.Pid,
+			upkg.PayloadLength)
 
-		if len(inst.p2pkgTx) > 0 {
+		if eno := upkg.SendPackage(inst); eno != PeMgrEnoNone {
 
 			//
-			// pick the first one
-			//
-
-			upkg := inst.p2pkgTx[0]
-			inst.p2pkgTx = inst.p2pkgTx[1:]
-
+			// 1) if failed, callback to the user, so he can close
+			// this peer seems in troubles, we will be done then.
 			//
-			// encode and send it
+			// 2) it is possible that, while we are blocked here in
+			// writing and the connection is closed for some reasons
+			// (for example the user close the peer), in this case,
+			// we would get an error.
 			//
 
 			yclog.LogCallerFileLine("piTx: " +
-				"send package, Pid: %d, PayloadLength: %d",
-				upkg.Pid,
-				upkg.PayloadLength)
-
-			if eno := upkg.SendPackage(inst); eno != PeMgrEnoNone {
-
-				//
-				// 1) if failed, callback to the user, so he can close
-				// this peer seems in troubles, we will be done then.
-				//
-				// 2) it is possible that, while we are blocked here in
-				// writing and the connection is closed for some reasons
-				// (for example the user close the peer), in this case,
-				// we would get an error.
-				//
-
-				yclog.LogCallerFileLine("piTx: " +
-					"call P2pIndHandler for SendPackage failed, eno: %d",
-					eno)
-
-				inst.txEno = eno
-
-				Lock4Cb.Lock()
+				"call P2pIndHandler for SendPackage failed, eno: %d",
+				eno)
 
-				if P2pIndHandler != nil {
+			inst.txEno = eno
 
-					hs := Handshake {
-						NodeId:		inst.node.ID,
-						ProtoNum:	inst.protoNum,
-						Protocols:	inst.protocols,
-					}
+			peMgr.lock4Cb.Lock()
 
-					info := P2pIndConnStatusPara{
-						Ptn:		inst.ptnMe,
-						PeerInfo:	&hs,
-						Status:		int(eno),
-						Flag:		false,
-						Description:"piTx: SendPackage failed",
-					}
+			if peMgr.indHandler != nil {
 
-					P2pIndHandler(P2pIndConnStatus, &info)
+				hs := Handshake {
+					NodeId:		inst.node.ID,
+					ProtoNum:	inst.protoNum,
+					Protocols:	inst.protocols,
+				}
 
-				} else {
-					yclog.LogCallerFileLine("piTx: indication callback not installed yet")
+				info := P2pIndConnStatusPara{
+					Ptn:		inst.ptnMe,
+					PeerInfo:	&hs,
+					Status:		int(eno),
+					Flag:		false,
+					Description:"piTx: SendPackage failed",
 				}
 
-				Lock4Cb.Unlock()
+				peMgr.indHandler(P2pIndConnStatus, &info)
+
+			} else {
+				yclog.LogCallerFileLine("piTx: indication callback not installed yet")
 			}
-		}
 
-		inst.p2pkgLock.Unlock()
+			peMgr.lock4Cb.Unlock()
+		}
 	}
 
 	return done
@@ -3185,6 +3987,8 @@ txBreak:
 //
 func piRx(inst *peerInstance) PeMgrErrno {
 
+	var peMgr = inst.peMgr
+
 	//
 	// This function is "go" when an instance of peer is activated to work,
 	// inbound or outbound. When use try to close the peer, this routine
@@ -3244,11 +4048,15 @@ rxBreak:
 				"call P2pIndHandler for RecvPackage failed, eno: %d",
 				eno)
 
-			Lock4Cb.Lock()
+			if eno == PeMgrEnoMessage {
+				peMgr.peReport(inst.node.ID, PeScoreMalformed, "malformed package")
+			}
+
+			peMgr.lock4Cb.Lock()
 
 			inst.rxEno = eno
 
-			if P2pIndHandler != nil {
+			if peMgr.indHandler != nil {
 
 				hs := Handshake {
 					NodeId:		inst.node.ID,
@@ -3264,13 +4072,13 @@ rxBreak:
 					Description:"piRx: RecvPackage failed",
 				}
 
-				P2pIndHandler(P2pIndConnStatus, &info)
+				peMgr.indHandler(P2pIndConnStatus, &info)
 
 			} else {
 				yclog.LogCallerFileLine("piRx: indication callback not installed yet")
 			}
 
-			Lock4Cb.Unlock()
+			peMgr.lock4Cb.Unlock()
 
 			continue
 		}
@@ -3283,7 +4091,11 @@ rxBreak:
 		// check the package received to filter out those not for p2p internal only
 		//
 
-		if upkg.Pid == uint32(PID_P2P) {
+		if upkg.Pid == uint32(PID_P2P) ||
+			upkg.Pid == uint32(PID_DHT) ||
+			upkg.Pid == uint32(PID_GOSSIP) ||
+			upkg.Pid == uint32(PID_RPC) ||
+			upkg.Pid == uint32(PID_STREAM) {
 
 			if eno := piP2pPkgProc(inst, upkg); eno != PeMgrEnoNone {
 
@@ -3291,17 +4103,28 @@ rxBreak:
 					"piP2pMsgProc failed, eno: %d, inst: %s",
 					eno,
 					fmt.Sprintf("%+v", *inst))
+
+				if eno == PeMgrEnoMessage {
+					peMgr.peReport(inst.node.ID, PeScoreMalformed, "malformed package")
+				}
 			}
 
-		} else if upkg.Pid == uint32(PID_EXT) {
+		} else {
 
 			//
-			// callback to the user for package incoming
+			// route to the handler registered for the protocol, and for PID_EXT,
+			// the package callback of instance is tried if no one registered.
 			//
 
-			inst.p2pkgLock.Lock()
+			ver, agreed := piProtocolVersion(inst, upkg.Pid)
+			cb := peMgr.getProtoHandler(upkg.Pid, ver)
+
+			if !agreed && upkg.Pid != uint32(PID_EXT) {
+
+				piPkgRejectedInd(inst, upkg.Pid, ver, "protocol not negotiated with peer")
+				peMgr.peReport(inst.node.ID, PeScoreViolation, "protocol not negotiated")
 
-			if inst.p2pkgRx != nil {
+			} else if cb != nil {
 
 				peerInfo.Protocols	= nil
 				peerInfo.NodeId		= inst.node.ID
@@ -3311,26 +4134,50 @@ rxBreak:
 				pkgCb.Payload		= nil
 				pkgCb.PeerInfo		= &peerInfo
 				pkgCb.ProtoId		= int(upkg.Pid)
+				pkgCb.ProtoVer		= ver
 				pkgCb.PayloadLength	= int(upkg.PayloadLength)
 				pkgCb.Payload		= append(pkgCb.Payload, upkg.Payload...)
 
-				inst.p2pkgRx(&pkgCb)
+				cb(&pkgCb)
 
-			} else {
-				yclog.LogCallerFileLine("piRx: package callback not installed yet")
-			}
+			} else if upkg.Pid == uint32(PID_EXT) {
 
-			inst.p2pkgLock.Unlock()
+				//
+				// callback to the user for package incoming
+				//
 
-		} else {
+				inst.p2pkgLock.Lock()
 
-			//
-			// unknow protocol identity
-			//
+				if inst.p2pkgRx != nil {
 
-			yclog.LogCallerFileLine("piRx: " +
-				"package discarded for unknown pid: %d",
-				upkg.Pid)
+					peerInfo.Protocols	= nil
+					peerInfo.NodeId		= inst.node.ID
+					peerInfo.ProtoNum	= inst.protoNum
+					peerInfo.Protocols	= append(peerInfo.Protocols, inst.protocols...)
+
+					pkgCb.Payload		= nil
+					pkgCb.PeerInfo		= &peerInfo
+					pkgCb.ProtoId		= int(upkg.Pid)
+					pkgCb.ProtoVer		= ver
+					pkgCb.PayloadLength	= int(upkg.PayloadLength)
+					pkgCb.Payload		= append(pkgCb.Payload, upkg.Payload...)
+
+					inst.p2pkgRx(&pkgCb)
+
+				} else {
+					yclog.LogCallerFileLine("piRx: package callback not installed yet")
+				}
+
+				inst.p2pkgLock.Unlock()
+
+			} else {
+
+				//
+				// unknow protocol identity
+				//
+
+				piPkgRejectedInd(inst, upkg.Pid, ver, "no handler for protocol")
+			}
 		}
 	}
 
@@ -3351,6 +4198,27 @@ func piP2pPkgProc(inst *peerInstance, upkg *P2pPackage) PeMgrErrno {
 		return PeMgrEnoParameter
 	}
 
+	//
+	// packages of dht are handed over to the dht manager, but not the user
+	// package callback.
+	//
+
+	if upkg.Pid == uint32(PID_DHT) {
+		return piDhtPkgProc(inst, upkg)
+	}
+
+	if upkg.Pid == uint32(PID_GOSSIP) {
+		return piGspPkgProc(inst, upkg)
+	}
+
+	if upkg.Pid == uint32(PID_RPC) {
+		return piRpcPkgProc(inst, upkg)
+	}
+
+	if upkg.Pid == uint32(PID_STREAM) {
+		return piStrPkgProc(inst, upkg)
+	}
+
 	if upkg.Pid != uint32(PID_P2P) {
 
 		yclog.LogCallerFileLine("piP2pPkgProc: " +
@@ -3423,6 +4291,436 @@ func piP2pPkgProc(inst *peerInstance, upkg *P2pPackage) PeMgrErrno {
 	return PeMgrEnoNone
 }
 
+//
+// Handler for dht packages received: they are handed over to the dht manager
+//
+func piDhtPkgProc(inst *peerInstance, upkg *P2pPackage) PeMgrErrno {
+
+	var peMgr = inst.peMgr
+
+	if inst == nil || upkg == nil {
+		yclog.LogCallerFileLine("piDhtPkgProc: invalid parameters")
+		return PeMgrEnoParameter
+	}
+
+	if len(upkg.Payload) == 0 || len(upkg.Payload) != int(upkg.PayloadLength) {
+
+		yclog.LogCallerFileLine("piDhtPkgProc: " +
+			"invalid payload, PlLen: %d, real: %d",
+			upkg.PayloadLength,
+			len(upkg.Payload))
+
+		return PeMgrEnoMessage
+	}
+
+	if peMgr.ptnDht == nil {
+		yclog.LogCallerFileLine("piDhtPkgProc: dht manager not found, discarded")
+		return PeMgrEnoNotfound
+	}
+
+	if piProtocolSupported(inst, uint32(PID_DHT)) != true {
+
+		yclog.LogCallerFileLine("piDhtPkgProc: " +
+			"dht not advertised by peer, discarded, peer: %s",
+			fmt.Sprintf("%X", inst.node.ID))
+
+		return PeMgrEnoMessage
+	}
+
+	var ind = sch.MsgDhtPkgInd {
+		From:		inst.node.ID,
+		Payload:	upkg.Payload,
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno := sch.SchinfMakeMessage(&schMsg, inst.ptnMe, peMgr.ptnDht, sch.EvDhtMgrPkgInd, &ind);
+	eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("piDhtPkgProc: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return PeMgrEnoScheduler
+	}
+
+	if eno := sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("piDhtPkgProc: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, sch.SchinfGetTaskName(peMgr.ptnDht))
+
+		return PeMgrEnoScheduler
+	}
+
+	return PeMgrEnoNone
+}
+
+//
+// Handler for gossip packages received: they are handed over to the gossip
+// manager
+//
+func piGspPkgProc(inst *peerInstance, upkg *P2pPackage) PeMgrErrno {
+
+	var peMgr = inst.peMgr
+
+	if inst == nil || upkg == nil {
+		yclog.LogCallerFileLine("piGspPkgProc: invalid parameters")
+		return PeMgrEnoParameter
+	}
+
+	if len(upkg.Payload) == 0 || len(upkg.Payload) != int(upkg.PayloadLength) {
+
+		yclog.LogCallerFileLine("piGspPkgProc: " +
+			"invalid payload, PlLen: %d, real: %d",
+			upkg.PayloadLength,
+			len(upkg.Payload))
+
+		return PeMgrEnoMessage
+	}
+
+	if peMgr.ptnGsp == nil {
+		yclog.LogCallerFileLine("piGspPkgProc: gossip manager not found, discarded")
+		return PeMgrEnoNotfound
+	}
+
+	if piProtocolSupported(inst, uint32(PID_GOSSIP)) != true {
+
+		yclog.LogCallerFileLine("piGspPkgProc: " +
+			"gossip not advertised by peer, discarded, peer: %s",
+			fmt.Sprintf("%X", inst.node.ID))
+
+		return PeMgrEnoMessage
+	}
+
+	var ind = sch.MsgGspPkgInd {
+		From:		inst.node.ID,
+		Payload:	upkg.Payload,
+	}
+
+	return peSendInd(inst.ptnMe, peMgr.ptnGsp, sch.EvGspMgrPkgInd, &ind)
+}
+
+//
+// Handler for rpc packages received: they are handed over to the rpc manager
+//
+func piRpcPkgProc(inst *peerInstance, upkg *P2pPackage) PeMgrErrno {
+
+	if inst == nil || upkg == nil {
+		yclog.LogCallerFileLine("piRpcPkgProc: invalid parameters")
+		return PeMgrEnoParameter
+	}
+
+	var peMgr = inst.peMgr
+
+	if len(upkg.Payload) == 0 || len(upkg.Payload) != int(upkg.PayloadLength) {
+
+		yclog.LogCallerFileLine("piRpcPkgProc: " +
+			"invalid payload, PlLen: %d, real: %d",
+			upkg.PayloadLength,
+			len(upkg.Payload))
+
+		return PeMgrEnoMessage
+	}
+
+	if peMgr.ptnRpc == nil {
+		yclog.LogCallerFileLine("piRpcPkgProc: rpc manager not found, discarded")
+		return PeMgrEnoNotfound
+	}
+
+	if piProtocolSupported(inst, uint32(PID_RPC)) != true {
+
+		yclog.LogCallerFileLine("piRpcPkgProc: " +
+			"rpc not advertised by peer, discarded, peer: %s",
+			fmt.Sprintf("%X", inst.node.ID))
+
+		return PeMgrEnoMessage
+	}
+
+	var ind = sch.MsgRpcPkgInd {
+		From:		inst.node.ID,
+		Payload:	upkg.Payload,
+	}
+
+	return peSendInd(inst.ptnMe, peMgr.ptnRpc, sch.EvRpcMgrPkgInd, &ind)
+}
+
+//
+// Handler for stream packages received: they are handed over to the stream
+// manager
+//
+func piStrPkgProc(inst *peerInstance, upkg *P2pPackage) PeMgrErrno {
+
+	if inst == nil || upkg == nil {
+		yclog.LogCallerFileLine("piStrPkgProc: invalid parameters")
+		return PeMgrEnoParameter
+	}
+
+	var peMgr = inst.peMgr
+
+	if len(upkg.Payload) == 0 || len(upkg.Payload) != int(upkg.PayloadLength) {
+
+		yclog.LogCallerFileLine("piStrPkgProc: " +
+			"invalid payload, PlLen: %d, real: %d",
+			upkg.PayloadLength,
+			len(upkg.Payload))
+
+		return PeMgrEnoMessage
+	}
+
+	if peMgr.ptnStr == nil {
+		yclog.LogCallerFileLine("piStrPkgProc: stream manager not found, discarded")
+		return PeMgrEnoNotfound
+	}
+
+	if piProtocolSupported(inst, uint32(PID_STREAM)) != true {
+
+		yclog.LogCallerFileLine("piStrPkgProc: " +
+			"stream not advertised by peer, discarded, peer: %s",
+			fmt.Sprintf("%X", inst.node.ID))
+
+		return PeMgrEnoMessage
+	}
+
+	var ind = sch.MsgStrPkgInd {
+		From:		inst.node.ID,
+		Payload:	upkg.Payload,
+	}
+
+	return peSendInd(inst.ptnMe, peMgr.ptnStr, sch.EvStrMgrPkgInd, &ind)
+}
+
+//
+// Tell the dht manager that a peer activated or closed
+//
+func (peMgr *peerManager) peDhtPeerInd(ptnFrom interface{}, what int, node *ycfg.Node) PeMgrErrno {
+
+	if peMgr.ptnDht == nil {
+		return PeMgrEnoNone
+	}
+
+	var ind = sch.MsgDhtPeerInd {
+		Ind:	what,
+		Node:	*node,
+	}
+
+	return peSendInd(ptnFrom, peMgr.ptnDht, sch.EvDhtMgrPeerInd, &ind)
+}
+
+//
+// Tell the gossip manager that a peer activated or closed
+//
+func (peMgr *peerManager) peGspPeerInd(ptnFrom interface{}, what int, node *ycfg.Node) PeMgrErrno {
+
+	if peMgr.ptnGsp == nil {
+		return PeMgrEnoNone
+	}
+
+	var ind = sch.MsgGspPeerInd {
+		Ind:	what,
+		Node:	*node,
+	}
+
+	return peSendInd(ptnFrom, peMgr.ptnGsp, sch.EvGspMgrPeerInd, &ind)
+}
+
+//
+// Tell the rpc manager that a peer activated or closed
+//
+func (peMgr *peerManager) peRpcPeerInd(ptnFrom interface{}, what int, node *ycfg.Node) PeMgrErrno {
+
+	if peMgr.ptnRpc == nil {
+		return PeMgrEnoNone
+	}
+
+	var ind = sch.MsgRpcPeerInd {
+		Ind:	what,
+		Node:	*node,
+	}
+
+	return peSendInd(ptnFrom, peMgr.ptnRpc, sch.EvRpcMgrPeerInd, &ind)
+}
+
+//
+// Tell the stream manager that a peer activated or closed
+//
+func (peMgr *peerManager) peStrPeerInd(ptnFrom interface{}, what int, node *ycfg.Node) PeMgrErrno {
+
+	if peMgr.ptnStr == nil {
+		return PeMgrEnoNone
+	}
+
+	var ind = sch.MsgStrPeerInd {
+		Ind:	what,
+		Node:	*node,
+	}
+
+	return peSendInd(ptnFrom, peMgr.ptnStr, sch.EvStrMgrPeerInd, &ind)
+}
+
+//
+// Send an indication to a task
+//
+func peSendInd(ptnFrom interface{}, ptnTo interface{}, evId int, ind interface{}) PeMgrErrno {
+
+	var schMsg = sch.SchMessage{}
+
+	if eno := sch.SchinfMakeMessage(&schMsg, ptnFrom, ptnTo, evId, ind); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("peSendInd: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return PeMgrEnoScheduler
+	}
+
+	if eno := sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("peSendInd: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, sch.SchinfGetTaskName(ptnTo))
+
+		return PeMgrEnoScheduler
+	}
+
+	return PeMgrEnoNone
+}
+
+//
+// Check if a protocol is negotiated with peer in handshake
+//
+func piProtocolSupported(inst *peerInstance, pid uint32) bool {
+	_, ok := piProtocolVersion(inst, pid)
+	return ok
+}
+
+//
+// Negotiate protocols with peer: for each protocol both sides support, the
+// highest version both sides support is taken. Peer having nothing in common
+// with us is refused.
+//
+func piNegotiateProtocols(inst *peerInstance) PeMgrErrno {
+
+	var peMgr = inst.peMgr
+
+	if inst.protoNum != uint32(len(inst.peerProtos)) {
+
+		yclog.LogCallerFileLine("piNegotiateProtocols: " +
+			"protocol number mismatched, ProtoNum: %d, real: %d",
+			inst.protoNum, len(inst.peerProtos))
+
+		return PeMgrEnoMessage
+	}
+
+	inst.protocols = peNegotiateProtocols(peMgr.cfg.protocols, inst.peerProtos)
+
+	if len(inst.protocols) == 0 {
+
+		yclog.LogCallerFileLine("piNegotiateProtocols: " +
+			"no protocol in common, ours: %v, peer: %v",
+			peMgr.cfg.protocols, inst.peerProtos)
+
+		return PeMgrEnoMismatched
+	}
+
+	yclog.LogCallerFileLine("piNegotiateProtocols: " +
+		"negotiated: %v, peer: %s",
+		inst.protocols, fmt.Sprintf("%X", inst.node.ID))
+
+	return PeMgrEnoNone
+}
+
+//
+// Get the highest mutually supported version for each protocol in both tables,
+// the result is in the order of local table.
+//
+func peNegotiateProtocols(local []Protocol, remote []Protocol) []Protocol {
+
+	var agreed = make([]Protocol, 0, len(local))
+	var index = make(map[uint32]int)
+
+	for _, l := range local {
+		for _, r := range remote {
+
+			if l.Pid != r.Pid || l.Ver != r.Ver {
+				continue
+			}
+
+			if idx, ok := index[l.Pid]; !ok {
+				index[l.Pid] = len(agreed)
+				agreed = append(agreed, l)
+			} else if bytes.Compare(l.Ver[:], agreed[idx].Ver[:]) > 0 {
+				agreed[idx].Ver = l.Ver
+			}
+		}
+	}
+
+	return agreed
+}
+
+//
+// Get protocols negotiated with an activated peer
+//
+func PeerProtocols(sdl *sch.Scheduler, id PeerId) ([]Protocol, PeMgrErrno) {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return nil, PeMgrEnoNotfound
+	}
+
+	peMgr.infLock.Lock()
+	defer peMgr.infLock.Unlock()
+
+	inst, ok := peMgr.workers[ycfg.NodeID(id)]
+	if !ok {
+		return nil, PeMgrEnoNotfound
+	}
+
+	return append([]Protocol{}, inst.protocols...), PeMgrEnoNone
+}
+
+//
+// Get version of a protocol negotiated with peer
+//
+func piProtocolVersion(inst *peerInstance, pid uint32) ([4]byte, bool) {
+	for _, p := range inst.protocols {
+		if p.Pid == pid {
+			return p.Ver, true
+		}
+	}
+	return [4]byte{}, false
+}
+
+//
+// Tell the user that a package is rejected for its' protocol not supported
+//
+func piPkgRejectedInd(inst *peerInstance, pid uint32, ver [4]byte, why string) {
+
+	var peMgr = inst.peMgr
+
+	yclog.LogCallerFileLine("piPkgRejectedInd: " +
+		"package discarded, pid: %d, ver: %v, reason: %s, peer: %s",
+		pid, ver, why, fmt.Sprintf("%X", inst.node.ID))
+
+	peMgr.lock4Cb.Lock()
+	defer peMgr.lock4Cb.Unlock()
+
+	if peMgr.indHandler == nil {
+		yclog.LogCallerFileLine("piPkgRejectedInd: indication callback not installed yet")
+		return
+	}
+
+	para := P2pIndPkgRejectedPara {
+		Ptn:			inst.ptnMe,
+		PeerId:			PeerId(inst.node.ID),
+		ProtoId:		int(pid),
+		ProtoVer:		ver,
+		Description:	why,
+	}
+
+	peMgr.indHandler(P2pIndPkgRejected, &para)
+}
+
 //
 // handler for ping message from peer
 //
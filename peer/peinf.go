@@ -19,9 +19,8 @@
  */
 
 package peer
-import "sync"
-
 
+import "time"
 
 //
 // Package passed into user's callback
@@ -29,6 +28,7 @@ import "sync"
 type P2pPackage4Callback struct {
 	PeerInfo		*PeerInfo	// peer information
 	ProtoId			int				// protocol identity
+	ProtoVer		[4]byte			// protocol version negotiated with peer
 	PayloadLength	int				// bytes in payload buffer
 	Payload			[]byte			// payload buffer
 }
@@ -43,7 +43,48 @@ type P2pPackage2Peer struct {
 	Payload			[]byte			// payload
 	Extra			interface{}		// extra info: user this field to tell p2p more about this message,
 									// for example, if broadcasting is wanted, then set IdList to nil
-									// and setup thie extra info field.
+									// and setup thie extra info field with a *P2pBroadcast.
+	Priority		int				// P2pPrioXXX, priority class of package
+	TxMode			int				// P2pTxModeXXX, how to wait when the send queue of a peer is full
+	TxTimeout		time.Duration	// max time to wait for P2pTxModeBlock, 0 for no limit
+}
+
+//
+// Priority classes of package. Control ones are always sent ahead of others,
+// they should be small and few, pingpong between peers is sent in this class;
+// others are sent in weighted round robin, see peTxQueue. The zero value is
+// normal, so packages without priority specified are sent as normal ones.
+//
+const (
+	P2pPrioNormal		= iota		// normal
+	P2pPrioControl					// control
+	P2pPrioHigh						// high, latency sensitive
+	P2pPrioBulk						// bulk transfer
+)
+
+//
+// Send mode, see SendPackage
+//
+const (
+	P2pTxModeNonblock	= iota		// fail at once if the send queue is full
+	P2pTxModeBlock					// wait for room in the send queue
+)
+
+//
+// Broadcast mode
+//
+const (
+	P2pBcastAll			= iota		// to all peers activated
+	P2pBcastExceptOne				// to all peers activated except one, for relaying
+	P2pBcastProtocol				// to all peers advertised the protocol of package
+)
+
+//
+// Broadcast info, see P2pPackage2Peer.Extra
+//
+type P2pBroadcast struct {
+	Mode			int				// broadcast mode, P2pBcastXXX
+	Except			PeerId			// the peer excluded for P2pBcastExceptOne
 }
 
 //
@@ -61,11 +102,14 @@ const (
 	P2pIndPeerActivated	= iota		// peer activated
 	P2pIndConnStatus				// connection status changed
 	P2pIndPeerClosed				// connection closed
+	P2pIndPkgRejected				// package rejected for protocol not supported
+	P2pIndTxBackpressure			// send queue of peer congested or relieved
 )
 
 type P2pIndPeerActivatedPara struct {
 	Ptn			interface{}			// task node pointer
 	PeerInfo	*Handshake			// handshake info
+	Protocols	[]Protocol			// protocols negotiated, with the highest version both supported
 }
 
 type P2pIndConnStatusPara struct {
@@ -89,6 +133,22 @@ type P2pIndPeerClosedPara struct {
 	PeerId		PeerId				// peer identity
 }
 
+type P2pIndPkgRejectedPara struct {
+	Ptn			interface{}			// task node pointer
+	PeerId		PeerId				// peer identity
+	ProtoId		int					// protocol identity of package
+	ProtoVer	[4]byte				// protocol version negotiated with peer, zero if not negotiated
+	Description	string				// description
+}
+
+type P2pIndTxBackpressurePara struct {
+	Ptn			interface{}			// task node pointer
+	PeerId		PeerId				// peer identity
+	Congested	bool				// true: congested, should slow down; false: relieved
+	Depth		int					// packages in the send queue
+	Capacity	int					// capacity of the send queue
+}
+
 type P2pInfIndCallback func(what int, para interface{}) interface{}
 
 //
@@ -97,13 +157,11 @@ type P2pInfIndCallback func(what int, para interface{}) interface{}
 type P2pInfPkgCallback func(msg *P2pPackage4Callback) interface{}
 
 //
-// Indication handler
+// Package handlers by protocol, see SetProtoHandler
 //
-var P2pIndHandler P2pInfIndCallback = nil
-
-//
-// Lock for syncing callbacks
-//
-var Lock4Cb sync.Mutex
+type P2pProtoKey struct {
+	Pid			uint32				// protocol identity
+	Ver			[4]byte				// protocol version
+}
 
 
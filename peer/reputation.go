@@ -0,0 +1,476 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"encoding/binary"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// Reputation of peers. Each peer has a score in [PeScoreMin, PeScoreMax],
+// which starts from zero, lowered by misbehaviors of the peer detected in
+// peer manager, and changed by feedbacks from application. Scores decay to
+// zero exponentially, halved each configured decay time, so misbehaviors
+// are forgiven, and feedbacks forgotten, as time goes by. A peer scored
+// below the drop threshold is disconnected, and below the ban threshold is
+// banned for the configured ban time, during which it's neither dialed nor
+// accepted. Scores are saved into a database, so they survive restarts.
+//
+const (
+	PeScoreMax			= 100		// max score
+	PeScoreMin			= -100		// min score
+	PeScoreHandshake	= -10		// handshake failed
+	PeScorePingpong		= -5		// pingpong timeout
+	PeScoreMalformed	= -10		// malformed package received
+	PeScoreViolation	= -20		// protocol violation
+)
+
+//
+// Actions for scores
+//
+const (
+	peRepActNone	= iota		// nothing to do
+	peRepActDrop				// disconnect the peer
+	peRepActBan					// disconnect and ban the peer
+)
+
+//
+// Scores below it are taken as zero, and records removed then
+//
+const peRepEpsilon = 0.01
+
+//
+// Score of a peer, for user
+//
+type PeScoreInfo struct {
+	Score		float64			// current score
+	Banned		bool			// banned or not
+	BanExpire	time.Time		// time the ban expired
+}
+
+//
+// Score record of a peer
+//
+type peScore struct {
+	score		float64			// score at time updated
+	updated		time.Time		// time updated
+	banExpire	time.Time		// time the ban expired, zero if never banned
+}
+
+//
+// Reputation of peers
+//
+type peReputation struct {
+	lock		sync.Mutex					// lock, since scores are reported by routines of instances
+	opened		bool						// opened or not
+	db			*leveldb.DB					// database, nil if failed to open
+	drop		float64						// peers scored below are disconnected
+	ban			float64						// peers scored below are banned
+	banTime		time.Duration				// time peers banned for
+	decay		time.Duration				// time for scores to decay to half
+	scores		map[ycfg.NodeID]*peScore	// scores of peers
+}
+
+//
+// Score records are keyed with a prefix in the database, and valued with
+// the score, time updated and time the ban expired.
+//
+var peRepPrefix = []byte("s:")
+
+const peRepValueSize = 24
+
+func peRepKey(id ycfg.NodeID) []byte {
+	return append(append(make([]byte, 0, len(peRepPrefix) + len(id)), peRepPrefix...), id[:]...)
+}
+
+//
+// Create reputation, it should be opened before used
+//
+func newPeReputation() *peReputation {
+	return &peReputation {
+		opened:	false,
+		db:		nil,
+		scores:	map[ycfg.NodeID]*peScore{},
+	}
+}
+
+//
+// Open reputation, scores are loaded from database configured. Errors about
+// the database are logged and ignored, scores are just not persisted then.
+//
+func (rep *peReputation) open(cfg *ycfg.Cfg4PeerManager) {
+
+	rep.lock.Lock()
+	defer rep.lock.Unlock()
+
+	rep.drop = float64(cfg.DropScore)
+	rep.ban = float64(cfg.BanScore)
+	rep.banTime = cfg.BanTime
+	rep.decay = cfg.ScoreDecay
+	rep.scores = map[ycfg.NodeID]*peScore{}
+	rep.opened = true
+
+	if len(cfg.ScoreDb) == 0 {
+		return
+	}
+
+	db, err := leveldb.OpenFile(cfg.ScoreDb, &opt.Options{OpenFilesCacheCapacity: 4})
+	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+		db, err = leveldb.RecoverFile(cfg.ScoreDb, nil)
+	}
+
+	if err != nil {
+
+		yclog.LogCallerFileLine("open: " +
+			"OpenFile failed, path: %s, err: %s",
+			cfg.ScoreDb, err.Error())
+
+		return
+	}
+
+	rep.db = db
+
+	now := time.Now()
+	it := db.NewIterator(util.BytesPrefix(peRepPrefix), nil)
+
+	for it.Next() {
+
+		key, val := it.Key(), it.Value()
+		if len(key) != len(peRepPrefix) + ycfg.NodeIDBytes || len(val) != peRepValueSize {
+			continue
+		}
+
+		var id ycfg.NodeID
+		copy(id[:], key[len(peRepPrefix):])
+
+		ps := &peScore {
+			score:		math.Float64frombits(binary.BigEndian.Uint64(val[0:8])),
+			updated:	time.Unix(0, int64(binary.BigEndian.Uint64(val[8:16]))),
+		}
+
+		if ban := int64(binary.BigEndian.Uint64(val[16:24])); ban != 0 {
+			ps.banExpire = time.Unix(0, ban)
+		}
+
+		if rep.expired(ps, now) {
+			db.Delete(key, nil)
+			continue
+		}
+
+		rep.scores[id] = ps
+	}
+
+	it.Release()
+
+	yclog.LogCallerFileLine("open: " +
+		"scores loaded: %d, path: %s",
+		len(rep.scores), cfg.ScoreDb)
+}
+
+//
+// Close reputation, the database is closed. Since records are saved when
+// they're changed, nothing else to do.
+//
+func (rep *peReputation) close() {
+
+	rep.lock.Lock()
+	defer rep.lock.Unlock()
+
+	if rep.db != nil {
+		rep.db.Close()
+		rep.db = nil
+	}
+
+	rep.scores = map[ycfg.NodeID]*peScore{}
+	rep.opened = false
+}
+
+//
+// Decay score to now, lock should be held by caller
+//
+func (rep *peReputation) current(ps *peScore, now time.Time) float64 {
+
+	if elapsed := now.Sub(ps.updated); elapsed > 0 {
+		ps.score *= math.Pow(0.5, float64(elapsed) / float64(rep.decay))
+		ps.updated = now
+	}
+
+	if math.Abs(ps.score) < peRepEpsilon {
+		ps.score = 0
+	}
+
+	return ps.score
+}
+
+//
+// Check if a record is useless any more, lock should be held by caller
+//
+func (rep *peReputation) expired(ps *peScore, now time.Time) bool {
+	return rep.current(ps, now) == 0 && !now.Before(ps.banExpire)
+}
+
+//
+// Save record to database, or remove it if expired, lock should be held by
+// caller.
+//
+func (rep *peReputation) save(id ycfg.NodeID, ps *peScore, now time.Time) {
+
+	if rep.expired(ps, now) {
+
+		delete(rep.scores, id)
+
+		if rep.db != nil {
+			rep.db.Delete(peRepKey(id), nil)
+		}
+
+		return
+	}
+
+	if rep.db == nil {
+		return
+	}
+
+	var ban int64
+
+	if !ps.banExpire.IsZero() {
+		ban = ps.banExpire.UnixNano()
+	}
+
+	val := make([]byte, peRepValueSize)
+	binary.BigEndian.PutUint64(val[0:8], math.Float64bits(ps.score))
+	binary.BigEndian.PutUint64(val[8:16], uint64(ps.updated.UnixNano()))
+	binary.BigEndian.PutUint64(val[16:24], uint64(ban))
+
+	if err := rep.db.Put(peRepKey(id), val, nil); err != nil {
+
+		yclog.LogCallerFileLine("save: " +
+			"Put failed, err: %s, peer: %s",
+			err.Error(), fmt.Sprintf("%X", id))
+	}
+}
+
+//
+// Change score of a peer, the action for the new score returned
+//
+func (rep *peReputation) report(id ycfg.NodeID, delta float64) (float64, int) {
+
+	rep.lock.Lock()
+	defer rep.lock.Unlock()
+
+	if !rep.opened {
+		return 0, peRepActNone
+	}
+
+	now := time.Now()
+
+	ps, ok := rep.scores[id]
+	if !ok {
+		ps = &peScore{updated: now}
+		rep.scores[id] = ps
+	}
+
+	score := math.Max(PeScoreMin, math.Min(PeScoreMax, rep.current(ps, now) + delta))
+	ps.score = score
+
+	act := peRepActNone
+
+	if score < rep.ban {
+		ps.banExpire = now.Add(rep.banTime)
+		act = peRepActBan
+	} else if score < rep.drop {
+		act = peRepActDrop
+	}
+
+	rep.save(id, ps, now)
+
+	return score, act
+}
+
+//
+// Check if a peer is banned
+//
+func (rep *peReputation) banned(id ycfg.NodeID) bool {
+
+	rep.lock.Lock()
+	defer rep.lock.Unlock()
+
+	ps, ok := rep.scores[id]
+
+	return ok && time.Now().Before(ps.banExpire)
+}
+
+//
+// Get score of a peer
+//
+func (rep *peReputation) info(id ycfg.NodeID) PeScoreInfo {
+
+	rep.lock.Lock()
+	defer rep.lock.Unlock()
+
+	ps, ok := rep.scores[id]
+	if !ok {
+		return PeScoreInfo{}
+	}
+
+	now := time.Now()
+
+	return PeScoreInfo {
+		Score:		rep.current(ps, now),
+		Banned:		now.Before(ps.banExpire),
+		BanExpire:	ps.banExpire,
+	}
+}
+
+//
+// Lift the ban of a peer, and reset its' score to zero
+//
+func (rep *peReputation) unban(id ycfg.NodeID) {
+
+	rep.lock.Lock()
+	defer rep.lock.Unlock()
+
+	if ps, ok := rep.scores[id]; ok {
+		ps.score = 0
+		ps.banExpire = time.Time{}
+		rep.save(id, ps, time.Now())
+	}
+}
+
+//
+// Change score of a peer, and disconnect it if it's scored too low. It can be
+// called in any routine: the request to close the peer is sent to the peer
+// manager if necessary.
+//
+func (peMgr *peerManager) peReport(id ycfg.NodeID, delta float64, why string) {
+
+	if id == (ycfg.NodeID{}) {
+		return
+	}
+
+	score, act := peMgr.rep.report(id, delta)
+
+	yclog.LogCallerFileLine("peReport: " +
+		"delta: %.2f, score: %.2f, act: %d, why: %s, peer: %s",
+		delta, score, act, why, fmt.Sprintf("%X", id))
+
+	if act == peRepActNone {
+		return
+	}
+
+	peMgr.infLock.Lock()
+	inst := peMgr.workers[id]
+	peMgr.infLock.Unlock()
+
+	if inst == nil {
+		return
+	}
+
+	var req = sch.MsgPeCloseReq {
+		Ptn:	inst.ptnMe,
+		Node:	inst.node,
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno := sch.SchinfMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeCloseReq, &req);
+	eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("peReport: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return
+	}
+
+	if eno := sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("peReport: " +
+			"SchinfSendMessage EvPeCloseReq failed, eno: %d, target: %s",
+			eno, sch.SchinfGetTaskName(peMgr.ptnMe))
+	}
+}
+
+//
+// Check if a peer is banned
+//
+func (peMgr *peerManager) peBanned(id ycfg.NodeID) bool {
+	return peMgr.rep.banned(id)
+}
+
+//
+// Feedback about a peer from application: the score of the peer is changed
+// by delta, which is limited in [PeScoreMin, PeScoreMax].
+//
+func ReportPeer(sdl *sch.Scheduler, id PeerId, delta int) PeMgrErrno {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound
+	}
+
+	if delta < PeScoreMin || delta > PeScoreMax {
+		yclog.LogCallerFileLine("ReportPeer: invalid delta: %d", delta)
+		return PeMgrEnoParameter
+	}
+
+	peMgr.peReport(ycfg.NodeID(id), float64(delta), "feedback")
+
+	return PeMgrEnoNone
+}
+
+//
+// Get score of a peer, peers never scored are zero scored
+//
+func PeerScore(sdl *sch.Scheduler, id PeerId) (PeScoreInfo, PeMgrErrno) {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeScoreInfo{}, PeMgrEnoNotfound
+	}
+
+	return peMgr.rep.info(ycfg.NodeID(id)), PeMgrEnoNone
+}
+
+//
+// Lift the ban of a peer, its' score is reset to zero
+//
+func UnbanPeer(sdl *sch.Scheduler, id PeerId) PeMgrErrno {
+
+	var peMgr = peGetManager(sdl)
+	if peMgr == nil {
+		return PeMgrEnoNotfound
+	}
+
+	peMgr.rep.unban(ycfg.NodeID(id))
+
+	return PeMgrEnoNone
+}
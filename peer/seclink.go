@@ -0,0 +1,431 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"io"
+	"bytes"
+	"bufio"
+	"errors"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/gogo/protobuf/proto"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// Secure link between peers. While handshaking, each side generates an
+// ephemeral key pair and a random nonce, puts them into the Handshake
+// message and signs the message with its' node key, so the remote can
+// check that we do own the node identity we claimed. The responder (the
+// inbound side) signs the initiator's nonce too, to bind its' handshake
+// to this session. Since the initiator's handshake is signed before it
+// knows the responder's nonce, it's sent again as a confirmation, signed
+// over the responder's nonce, so a handshake captured from some session
+// can't be replayed to a responder to pass as others. After handshake, a
+// shared secret is obtained by ECDH
+// on the ephemeral keys, from which one AES-GCM key for each direction is
+// derived, and every package on the link is then sealed with it.
+//
+const (
+	secNonceSize	= 32					// size of handshake nonce
+	secKeySize		= 32					// size of AEAD key
+	secLabelHs		= "ycp2p-handshake"		// label for handshake digest
+	secLabelCfm		= "ycp2p-confirm"		// label for confirmation digest
+	secLabelI2r		= "ycp2p-initiator"		// label for key: initiator to responder
+	secLabelR2i		= "ycp2p-responder"		// label for key: responder to initiator
+)
+
+//
+// Prepare the ephemeral key and nonce for handshake of an instance
+//
+func secHandshakePrepare(inst *peerInstance) PeMgrErrno {
+
+	key, err := ecdsa.GenerateKey(ycfg.S256(), rand.Reader)
+	if err != nil {
+
+		yclog.LogCallerFileLine("secHandshakePrepare: " +
+			"GenerateKey failed, err: %s",
+			err.Error())
+
+		return PeMgrEnoOs
+	}
+
+	nonce := make([]byte, secNonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+
+		yclog.LogCallerFileLine("secHandshakePrepare: " +
+			"ReadFull failed, err: %s",
+			err.Error())
+
+		return PeMgrEnoOs
+	}
+
+	inst.ephKey = key
+	inst.ephNonce = nonce
+
+	return PeMgrEnoNone
+}
+
+//
+// Digest of handshake for signing. peerNonce is the nonce of the remote
+// peer, it's nil for the handshake of initiator. network is the tag of network, see
+// ycfg.P2pNetworkTag, which is not sent but mixed into the digest, so the
+// signature can't be verified by nodes of other networks.
+//
+func secHandshakeDigest(hs *Handshake, peerNonce []byte, network []byte) []byte {
+
+	var u32 = make([]byte, 4)
+
+	h := sha256.New()
+	h.Write([]byte(secLabelHs))
+	h.Write(hs.NodeId[:])
+	h.Write(hs.IP)
+
+	binary.BigEndian.PutUint32(u32, hs.UDP)
+	h.Write(u32)
+	binary.BigEndian.PutUint32(u32, hs.TCP)
+	h.Write(u32)
+	binary.BigEndian.PutUint32(u32, hs.ProtoNum)
+	h.Write(u32)
+
+	for _, p := range hs.Protocols {
+		binary.BigEndian.PutUint32(u32, p.Pid)
+		h.Write(u32)
+		h.Write(p.Ver[:])
+	}
+
+	h.Write(hs.EphPubKey)
+	h.Write(hs.Nonce)
+	h.Write(peerNonce)
+
+	//
+	// compression is covered only when advertised, so that the digest is not
+	// changed for peers without it.
+	//
+
+	if hs.Compress != PeCompressNone {
+		binary.BigEndian.PutUint32(u32, hs.Compress)
+		h.Write(u32)
+	}
+
+	h.Write(network)
+
+	return h.Sum(nil)
+}
+
+//
+// Fill the security fields of handshake to be sent and sign it
+//
+func secHandshakeSign(inst *peerInstance, hs *Handshake, peerNonce []byte) PeMgrErrno {
+
+	var peMgr = inst.peMgr
+
+	if peMgr.cfg.priKey == nil {
+		yclog.LogCallerFileLine("secHandshakeSign: no private key configured")
+		return PeMgrEnoConfig
+	}
+
+	if inst.ephKey == nil || len(inst.ephNonce) != secNonceSize {
+		yclog.LogCallerFileLine("secHandshakeSign: handshake not prepared")
+		return PeMgrEnoInternal
+	}
+
+	pub := &inst.ephKey.PublicKey
+	hs.EphPubKey = elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+	hs.Nonce = append([]byte{}, inst.ephNonce...)
+
+	if hs.Signature = ycfg.P2pSign(peMgr.cfg.priKey, secHandshakeDigest(hs, peerNonce, peMgr.cfg.network));
+	hs.Signature == nil {
+		yclog.LogCallerFileLine("secHandshakeSign: P2pSign failed")
+		return PeMgrEnoInternal
+	}
+
+	return PeMgrEnoNone
+}
+
+//
+// Check the signature of handshake received against the node identity
+// it claimed, and the network we are in.
+//
+func secHandshakeVerify(inst *peerInstance, hs *Handshake, peerNonce []byte) PeMgrErrno {
+
+	if len(hs.Nonce) != secNonceSize || len(hs.EphPubKey) == 0 {
+
+		yclog.LogCallerFileLine("secHandshakeVerify: " +
+			"invalid nonce or ephemeral key, nonce: %d, key: %d",
+			len(hs.Nonce), len(hs.EphPubKey))
+
+		return PeMgrEnoMessage
+	}
+
+	if ycfg.P2pVerify(hs.NodeId, secHandshakeDigest(hs, peerNonce, inst.peMgr.cfg.network), hs.Signature) != true {
+		yclog.LogCallerFileLine("secHandshakeVerify: invalid signature, forged or from other network")
+		return PeMgrEnoMessage
+	}
+
+	return PeMgrEnoNone
+}
+
+//
+// Digest of confirmation for signing. It's the digest of the initiator's
+// handshake over the nonce of responder, under another label, so that a
+// confirmation can't be taken as a handshake, nor the reverse.
+//
+func secConfirmDigest(hs *Handshake, peerNonce []byte, network []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(secLabelCfm))
+	h.Write(secHandshakeDigest(hs, peerNonce, network))
+	return h.Sum(nil)
+}
+
+//
+// Make the confirmation of the handshake sent by the initiator, which is the
+// same handshake but signed over the nonce of responder.
+//
+func secConfirmSign(inst *peerInstance, hs *Handshake, peerNonce []byte) (*Handshake, PeMgrErrno) {
+
+	var peMgr = inst.peMgr
+
+	if len(peerNonce) != secNonceSize {
+		yclog.LogCallerFileLine("secConfirmSign: invalid nonce of peer")
+		return nil, PeMgrEnoMessage
+	}
+
+	cfm := *hs
+
+	if cfm.Signature = ycfg.P2pSign(peMgr.cfg.priKey, secConfirmDigest(hs, peerNonce, peMgr.cfg.network));
+	cfm.Signature == nil {
+		yclog.LogCallerFileLine("secConfirmSign: P2pSign failed")
+		return nil, PeMgrEnoInternal
+	}
+
+	return &cfm, PeMgrEnoNone
+}
+
+//
+// Check the confirmation received by responder against the handshake the
+// initiator sent before, it must be signed over our nonce.
+//
+func secConfirmVerify(inst *peerInstance, hs *Handshake, cfm *Handshake) PeMgrErrno {
+
+	if cfm.NodeId != hs.NodeId ||
+		bytes.Equal(cfm.EphPubKey, hs.EphPubKey) != true ||
+		bytes.Equal(cfm.Nonce, hs.Nonce) != true {
+		yclog.LogCallerFileLine("secConfirmVerify: confirmation mismatched with handshake")
+		return PeMgrEnoMessage
+	}
+
+	if ycfg.P2pVerify(hs.NodeId, secConfirmDigest(hs, inst.ephNonce, inst.peMgr.cfg.network), cfm.Signature) != true {
+		yclog.LogCallerFileLine("secConfirmVerify: invalid signature, replayed or forged")
+		return PeMgrEnoMessage
+	}
+
+	return PeMgrEnoNone
+}
+
+//
+// Derive the AEADs for both directions from the handshake remote sent
+//
+func secDeriveKeys(inst *peerInstance, hs *Handshake) PeMgrErrno {
+
+	curve := ycfg.S256()
+	x, y := elliptic.Unmarshal(curve, hs.EphPubKey)
+
+	if x == nil || y == nil {
+		yclog.LogCallerFileLine("secDeriveKeys: invalid ephemeral public key")
+		return PeMgrEnoMessage
+	}
+
+	sx, _ := curve.ScalarMult(x, y, inst.ephKey.D.Bytes())
+	secret := make([]byte, (curve.Params().BitSize + 7) / 8)
+	sb := sx.Bytes()
+	copy(secret[len(secret)-len(sb):], sb)
+
+	var iNonce, rNonce []byte
+
+	if inst.dir == PeInstDirOutbound {
+		iNonce, rNonce = inst.ephNonce, hs.Nonce
+	} else {
+		iNonce, rNonce = hs.Nonce, inst.ephNonce
+	}
+
+	kdf := func(label string) []byte {
+		h := sha256.New()
+		h.Write(secret)
+		h.Write(iNonce)
+		h.Write(rNonce)
+		h.Write([]byte(label))
+		return h.Sum(nil)[:secKeySize]
+	}
+
+	i2r, err1 := secNewAead(kdf(secLabelI2r))
+	r2i, err2 := secNewAead(kdf(secLabelR2i))
+
+	if err1 != nil || err2 != nil {
+		yclog.LogCallerFileLine("secDeriveKeys: secNewAead failed")
+		return PeMgrEnoInternal
+	}
+
+	if inst.dir == PeInstDirOutbound {
+		inst.txAead, inst.rxAead = i2r, r2i
+	} else {
+		inst.txAead, inst.rxAead = r2i, i2r
+	}
+
+	//
+	// the ephemeral key is useless now, drop it
+	//
+
+	inst.ephKey = nil
+
+	return PeMgrEnoNone
+}
+
+//
+// Create AES-GCM AEAD with key
+//
+func secNewAead(key []byte) (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+//
+// AEAD nonce from sequence number. Since each direction has its' own
+// key, the sequence numbers of two directions can overlap.
+//
+func secSeqNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+//
+// Sealed message writer. Each message is framed as:
+//
+//	uvarint(length of sealed bytes) | AEAD sealed message bytes
+//
+// where the sequence number of message is used as AEAD nonce.
+//
+type secWriter struct {
+	w		io.Writer		// underlying writer
+	aead	cipher.AEAD		// AEAD for tx
+	seq		uint64			// tx sequence number
+}
+
+func newSecWriter(w io.Writer, aead cipher.AEAD) *secWriter {
+	return &secWriter{w: w, aead: aead, seq: 0}
+}
+
+func (sw *secWriter) WriteMsg(msg proto.Message) error {
+
+	m, ok := msg.(interface{ Marshal() ([]byte, error) })
+	if !ok {
+		return errors.New("secWriter: message can't be marshaled")
+	}
+
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+
+	sealed := sw.aead.Seal(nil, secSeqNonce(sw.aead, sw.seq), data, nil)
+	sw.seq++
+
+	frame := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64 + len(sealed))
+	n := binary.PutUvarint(frame, uint64(len(sealed)))
+	frame = append(frame[:n], sealed...)
+
+	_, err = sw.w.Write(frame)
+
+	return err
+}
+
+func (sw *secWriter) Close() error {
+	if c, ok := sw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+//
+// Sealed message reader, see secWriter for frame format
+//
+type secReader struct {
+	r		*bufio.Reader	// buffered underlying reader
+	c		io.Closer		// underlying closer
+	aead	cipher.AEAD		// AEAD for rx
+	seq		uint64			// rx sequence number
+	maxSize	int				// max size of message
+}
+
+func newSecReader(r io.Reader, aead cipher.AEAD, maxSize int) *secReader {
+	c, _ := r.(io.Closer)
+	return &secReader{r: bufio.NewReader(r), c: c, aead: aead, seq: 0, maxSize: maxSize}
+}
+
+func (sr *secReader) ReadMsg(msg proto.Message) error {
+
+	m, ok := msg.(interface{ Unmarshal([]byte) error })
+	if !ok {
+		return errors.New("secReader: message can't be unmarshaled")
+	}
+
+	length, err := binary.ReadUvarint(sr.r)
+	if err != nil {
+		return err
+	}
+
+	if length > uint64(sr.maxSize + sr.aead.Overhead()) {
+		return io.ErrShortBuffer
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(sr.r, sealed); err != nil {
+		return err
+	}
+
+	data, err := sr.aead.Open(sealed[:0], secSeqNonce(sr.aead, sr.seq), sealed, nil)
+	if err != nil {
+		return err
+	}
+	sr.seq++
+
+	return m.Unmarshal(data)
+}
+
+func (sr *secReader) Close() error {
+	if sr.c != nil {
+		return sr.c.Close()
+	}
+	return nil
+}
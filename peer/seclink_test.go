@@ -0,0 +1,441 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"testing"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	ycfg	"github.com/yeeco/p2p/config"
+	pb		"github.com/yeeco/p2p/peer/pb"
+)
+
+const secTestMaxSize = 1024 * 64
+
+//
+// Package with payload of size specified
+//
+func secTestPackage(size int) *pb.P2PPackage {
+	pid := pb.ProtocolId_PID_EXT
+	length := uint32(size)
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	return &pb.P2PPackage{Pid: &pid, PayloadLength: &length, Payload: payload}
+}
+
+//
+// Seal packages of sizes specified, each into a frame
+//
+func secTestFrames(t *testing.T, sizes []int) [][]byte {
+	aead, _ := secNewAead(bytes.Repeat([]byte{1}, secKeySize))
+	var buf bytes.Buffer
+	sw := newSecWriter(&buf, aead)
+	frames := make([][]byte, 0, len(sizes))
+	for _, size := range sizes {
+		if err := sw.WriteMsg(secTestPackage(size)); err != nil {
+			t.Fatalf("WriteMsg failed, err: %s", err.Error())
+		}
+		frames = append(frames, append([]byte{}, buf.Bytes()...))
+		buf.Reset()
+	}
+	return frames
+}
+
+func TestSecLinkRoundTrip(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		sizes	[]int
+	}{
+		{"empty", []int{0}},
+		{"single", []int{100}},
+		{"sequence", []int{1, 2, 3, 1000, 0, 7}},
+		{"max size", []int{secTestMaxSize - 16, secTestMaxSize - 16}},
+	}
+
+	for _, c := range cases {
+
+		aead, _ := secNewAead(bytes.Repeat([]byte{1}, secKeySize))
+		var buf bytes.Buffer
+
+		sw := newSecWriter(&buf, aead)
+		for _, size := range c.sizes {
+			if err := sw.WriteMsg(secTestPackage(size)); err != nil {
+				t.Fatalf("%s: WriteMsg failed, err: %s", c.name, err.Error())
+			}
+		}
+
+		sr := newSecReader(&buf, aead, secTestMaxSize)
+		for idx, size := range c.sizes {
+			pkg := new(pb.P2PPackage)
+			if err := sr.ReadMsg(pkg); err != nil {
+				t.Fatalf("%s: ReadMsg %d failed, err: %s", c.name, idx, err.Error())
+			}
+			if !bytes.Equal(pkg.Payload, secTestPackage(size).Payload) || pkg.GetPayloadLength() != uint32(size) {
+				t.Fatalf("%s: package %d mismatched", c.name, idx)
+			}
+		}
+
+		if buf.Len() != 0 {
+			t.Fatalf("%s: %d bytes left", c.name, buf.Len())
+		}
+	}
+}
+
+func TestSecLinkReject(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		key		byte
+		frames	func(f [][]byte) [][]byte
+	}{
+		{"tampered ciphertext", 1, func(f [][]byte) [][]byte {
+			f[0][2] ^= 0x01
+			return f
+		}},
+		{"tampered tag", 1, func(f [][]byte) [][]byte {
+			f[0][len(f[0])-1] ^= 0x80
+			return f
+		}},
+		{"reordered", 1, func(f [][]byte) [][]byte {
+			return [][]byte{f[1], f[0]}
+		}},
+		{"replayed", 1, func(f [][]byte) [][]byte {
+			return [][]byte{f[0], f[0]}
+		}},
+		{"dropped", 1, func(f [][]byte) [][]byte {
+			return [][]byte{f[1]}
+		}},
+		{"wrong key", 2, func(f [][]byte) [][]byte {
+			return f
+		}},
+	}
+
+	for _, c := range cases {
+
+		frames := c.frames(secTestFrames(t, []int{10, 20}))
+
+		var buf bytes.Buffer
+		for _, f := range frames {
+			buf.Write(f)
+		}
+
+		aead, _ := secNewAead(bytes.Repeat([]byte{c.key}, secKeySize))
+		sr := newSecReader(&buf, aead, secTestMaxSize)
+
+		var err error
+		for range frames {
+			if err = sr.ReadMsg(new(pb.P2PPackage)); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			t.Fatalf("%s: accepted", c.name)
+		}
+	}
+}
+
+func TestSecLinkOversize(t *testing.T) {
+
+	frames := secTestFrames(t, []int{secTestMaxSize + 1})
+	aead, _ := secNewAead(bytes.Repeat([]byte{1}, secKeySize))
+	sr := newSecReader(bytes.NewReader(frames[0]), aead, secTestMaxSize)
+
+	if err := sr.ReadMsg(new(pb.P2PPackage)); err == nil {
+		t.Fatalf("oversize message accepted")
+	}
+}
+
+//
+// Peer instance with a node key, prepared for handshake
+//
+func secTestInstance(t *testing.T) *peerInstance {
+
+	key, err := ecdsa.GenerateKey(ycfg.S256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed, err: %s", err.Error())
+	}
+
+	inst := &peerInstance{
+		peMgr: &peerManager{
+			cfg: peMgrConfig{
+				priKey:	key,
+			},
+		},
+	}
+
+	pub := elliptic.Marshal(key.Curve, key.X, key.Y)
+	copy(inst.node.ID[:], pub[1:])
+
+	if eno := secHandshakePrepare(inst); eno != PeMgrEnoNone {
+		t.Fatalf("secHandshakePrepare failed, eno: %d", eno)
+	}
+
+	return inst
+}
+
+//
+// Handshake of an instance signed over the peer nonce specified
+//
+func secTestHandshake(t *testing.T, inst *peerInstance, peerNonce []byte) *Handshake {
+
+	hs := &Handshake{
+		NodeId:		inst.node.ID,
+		IP:			[]byte{127, 0, 0, 1},
+		UDP:		30303,
+		TCP:		30303,
+		ProtoNum:	1,
+		Protocols:	[]Protocol{{Pid: uint32(PID_P2P), Ver: [4]byte{0, 1, 0, 0}}},
+	}
+
+	if eno := secHandshakeSign(inst, hs, peerNonce); eno != PeMgrEnoNone {
+		t.Fatalf("secHandshakeSign failed, eno: %d", eno)
+	}
+
+	return hs
+}
+
+func TestSecHandshakeVerify(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		nonce	bool
+		modify	func(hs *Handshake, other *peerInstance)
+		want	PeMgrErrno
+	}{
+		{"initiator", false, func(hs *Handshake, other *peerInstance) {}, PeMgrEnoNone},
+		{"responder", true, func(hs *Handshake, other *peerInstance) {}, PeMgrEnoNone},
+		{"other node claimed", false, func(hs *Handshake, other *peerInstance) {
+			hs.NodeId = other.node.ID
+		}, PeMgrEnoMessage},
+		{"tampered signature", false, func(hs *Handshake, other *peerInstance) {
+			hs.Signature[0] ^= 0x01
+		}, PeMgrEnoMessage},
+		{"tampered nonce", false, func(hs *Handshake, other *peerInstance) {
+			hs.Nonce[0] ^= 0x01
+		}, PeMgrEnoMessage},
+		{"tampered ephemeral key", true, func(hs *Handshake, other *peerInstance) {
+			hs.EphPubKey[1] ^= 0x01
+		}, PeMgrEnoMessage},
+		{"short nonce", false, func(hs *Handshake, other *peerInstance) {
+			hs.Nonce = hs.Nonce[1:]
+		}, PeMgrEnoMessage},
+		{"signed for other session", true, func(hs *Handshake, other *peerInstance) {
+			secHandshakePrepare(other)
+		}, PeMgrEnoMessage},
+	}
+
+	for _, c := range cases {
+
+		signer := secTestInstance(t)
+		checker := secTestInstance(t)
+
+		var peerNonce []byte
+		if c.nonce {
+			peerNonce = checker.ephNonce
+		}
+
+		hs := secTestHandshake(t, signer, peerNonce)
+		c.modify(hs, checker)
+
+		if c.nonce {
+			peerNonce = checker.ephNonce
+		}
+
+		if eno := secHandshakeVerify(checker, hs, peerNonce); eno != c.want {
+			t.Fatalf("%s: secHandshakeVerify returned %d, want %d", c.name, eno, c.want)
+		}
+	}
+}
+
+func TestSecDeriveKeys(t *testing.T) {
+
+	initiator := secTestInstance(t)
+	initiator.dir = PeInstDirOutbound
+	responder := secTestInstance(t)
+	responder.dir = PeInstDirInbound
+
+	hsI := secTestHandshake(t, initiator, nil)
+	hsR := secTestHandshake(t, responder, hsI.Nonce)
+
+	if eno := secDeriveKeys(initiator, hsR); eno != PeMgrEnoNone {
+		t.Fatalf("secDeriveKeys of initiator failed, eno: %d", eno)
+	}
+
+	if eno := secDeriveKeys(responder, hsI); eno != PeMgrEnoNone {
+		t.Fatalf("secDeriveKeys of responder failed, eno: %d", eno)
+	}
+
+	if initiator.ephKey != nil || responder.ephKey != nil {
+		t.Fatalf("ephemeral keys not dropped")
+	}
+
+	cases := []struct {
+		name	string
+		seal	cipher.AEAD
+		open	cipher.AEAD
+		want	bool
+	}{
+		{"initiator to responder", initiator.txAead, responder.rxAead, true},
+		{"responder to initiator", responder.txAead, initiator.rxAead, true},
+		{"initiator to itself", initiator.txAead, initiator.rxAead, false},
+		{"responder to itself", responder.txAead, responder.rxAead, false},
+	}
+
+	for _, c := range cases {
+
+		msg := []byte("ycp2p")
+		nonce := secSeqNonce(c.seal, 7)
+		sealed := c.seal.Seal(nil, nonce, msg, nil)
+		opened, err := c.open.Open(nil, nonce, sealed, nil)
+
+		if ok := err == nil && bytes.Equal(opened, msg); ok != c.want {
+			t.Fatalf("%s: opened %t, want %t", c.name, ok, c.want)
+		}
+	}
+}
+
+func TestSecConfirm(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		confirm	func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake
+		want	PeMgrErrno
+	}{
+		{"confirmed", func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake {
+			cfm, _ := secConfirmSign(initiator, hs, responder.ephNonce)
+			return cfm
+		}, PeMgrEnoNone},
+		{"handshake replayed", func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake {
+			return hs
+		}, PeMgrEnoMessage},
+		{"confirmed for other responder", func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake {
+			cfm, _ := secConfirmSign(initiator, hs, other.ephNonce)
+			return cfm
+		}, PeMgrEnoMessage},
+		{"handshake signature over our nonce", func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake {
+			cfm := *hs
+			cfm.Signature = ycfg.P2pSign(initiator.peMgr.cfg.priKey, secHandshakeDigest(hs, responder.ephNonce, nil))
+			return &cfm
+		}, PeMgrEnoMessage},
+		{"other handshake confirmed", func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake {
+			secHandshakePrepare(initiator)
+			cfm, _ := secConfirmSign(initiator, secTestHandshake(t, initiator, nil), responder.ephNonce)
+			return cfm
+		}, PeMgrEnoMessage},
+		{"confirmed by other node", func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake {
+			cfm, _ := secConfirmSign(other, hs, responder.ephNonce)
+			return cfm
+		}, PeMgrEnoMessage},
+		{"tampered signature", func(t *testing.T, initiator, responder, other *peerInstance, hs *Handshake) *Handshake {
+			cfm, _ := secConfirmSign(initiator, hs, responder.ephNonce)
+			cfm.Signature[len(cfm.Signature)-1] ^= 0x01
+			return cfm
+		}, PeMgrEnoMessage},
+	}
+
+	for _, c := range cases {
+
+		initiator := secTestInstance(t)
+		responder := secTestInstance(t)
+		other := secTestInstance(t)
+
+		hs := secTestHandshake(t, initiator, nil)
+		if eno := secHandshakeVerify(responder, hs, nil); eno != PeMgrEnoNone {
+			t.Fatalf("%s: secHandshakeVerify failed, eno: %d", c.name, eno)
+		}
+
+		cfm := c.confirm(t, initiator, responder, other, hs)
+		if cfm == nil {
+			t.Fatalf("%s: secConfirmSign failed", c.name)
+		}
+
+		if eno := secConfirmVerify(responder, hs, cfm); eno != c.want {
+			t.Fatalf("%s: secConfirmVerify returned %d, want %d", c.name, eno, c.want)
+		}
+	}
+}
+
+func TestSecHandshakeNetwork(t *testing.T) {
+
+	tagA := ycfg.P2pNetworkTag(&ycfg.Config{NetworkId: 1})
+	tagB := ycfg.P2pNetworkTag(&ycfg.Config{NetworkId: 2})
+	tagK := ycfg.P2pNetworkTag(&ycfg.Config{NetworkId: 1, NetworkKey: []byte("psk")})
+
+	cases := []struct {
+		name	string
+		signer	[]byte
+		checker	[]byte
+		nonce	bool
+		want	PeMgrErrno
+	}{
+		{"default network", nil, nil, false, PeMgrEnoNone},
+		{"same network", tagA, tagA, false, PeMgrEnoNone},
+		{"same network responder", tagA, tagA, true, PeMgrEnoNone},
+		{"other network", tagA, tagB, false, PeMgrEnoMessage},
+		{"other network responder", tagA, tagB, true, PeMgrEnoMessage},
+		{"default to private", nil, tagA, false, PeMgrEnoMessage},
+		{"private to default", tagA, nil, false, PeMgrEnoMessage},
+		{"without key", tagA, tagK, false, PeMgrEnoMessage},
+	}
+
+	for _, c := range cases {
+
+		signer := secTestInstance(t)
+		signer.peMgr.cfg.network = c.signer
+		checker := secTestInstance(t)
+		checker.peMgr.cfg.network = c.checker
+
+		var peerNonce []byte
+		if c.nonce {
+			peerNonce = checker.ephNonce
+		}
+
+		hs := secTestHandshake(t, signer, peerNonce)
+
+		if eno := secHandshakeVerify(checker, hs, peerNonce); eno != c.want {
+			t.Fatalf("%s: secHandshakeVerify returned %d, want %d", c.name, eno, c.want)
+		}
+
+		//
+		// the confirmation of initiator is bound to the network as well
+		//
+
+		if c.nonce {
+			continue
+		}
+
+		cfm, eno := secConfirmSign(signer, hs, checker.ephNonce)
+		if eno != PeMgrEnoNone {
+			t.Fatalf("%s: secConfirmSign failed, eno: %d", c.name, eno)
+		}
+
+		if eno := secConfirmVerify(checker, hs, cfm); eno != c.want {
+			t.Fatalf("%s: secConfirmVerify returned %d, want %d", c.name, eno, c.want)
+		}
+	}
+}
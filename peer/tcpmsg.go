@@ -23,6 +23,7 @@ package peer
 import (
 	"io"
 	"time"
+	"sync/atomic"
 	"net"
 	ggio "github.com/gogo/protobuf/io"
 	ycfg	"github.com/yeeco/p2p/config"
@@ -42,6 +43,10 @@ const MaxProtocols = ycfg.MaxProtocols
 const (
 	PID_P2P			= pb.ProtocolId_PID_P2P
 	PID_EXT			= pb.ProtocolId_PID_EXT
+	PID_DHT			= pb.ProtocolId_PID_DHT
+	PID_GOSSIP		= pb.ProtocolId_PID_GOSSIP
+	PID_RPC			= pb.ProtocolId_PID_RPC
+	PID_STREAM		= pb.ProtocolId_PID_STREAM
 )
 
 //
@@ -71,6 +76,10 @@ type Handshake struct {
 	TCP			uint32		// tcp port number
 	ProtoNum	uint32		// number of protocols supported
 	Protocols	[]Protocol	// version of protocol
+	EphPubKey	[]byte		// ephemeral public key for key exchange
+	Nonce		[]byte		// random nonce of sender
+	Signature	[]byte		// signature over handshake by node key
+	Compress	uint32		// compression algorithms supported, see PeCompressXXX
 }
 
 //
@@ -242,6 +251,11 @@ func (upkg *P2pPackage)getHandshakeInbound(inst *peerInstance) (*Handshake, PeMg
 		copy(ptrMsg.Protocols[i].Ver[:], p.Ver)
 	}
 
+	ptrMsg.EphPubKey = append(ptrMsg.EphPubKey, pbHS.EphPubKey...)
+	ptrMsg.Nonce = append(ptrMsg.Nonce, pbHS.Nonce...)
+	ptrMsg.Signature = append(ptrMsg.Signature, pbHS.Signature...)
+	ptrMsg.Compress = pbHS.GetCompress()
+
 	return ptrMsg, PeMgrEnoNone
 }
 
@@ -266,10 +280,18 @@ func (upkg *P2pPackage)putHandshakeOutbound(inst *peerInstance, hs *Handshake) P
 		var pbProto = new(pb.P2PMessage_Protocol)
 		pbHandshakeMsg.Protocols[i] = pbProto
 		pbProto.Pid = new(pb.ProtocolId)
-		*pbProto.Pid = pb.ProtocolId_PID_P2P
+		*pbProto.Pid = pb.ProtocolId(p.Pid)
 		pbProto.Ver = append(pbProto.Ver, p.Ver[:]...)
 	}
 
+	pbHandshakeMsg.EphPubKey = append(pbHandshakeMsg.EphPubKey, hs.EphPubKey...)
+	pbHandshakeMsg.Nonce = append(pbHandshakeMsg.Nonce, hs.Nonce...)
+	pbHandshakeMsg.Signature = append(pbHandshakeMsg.Signature, hs.Signature...)
+
+	if hs.Compress != PeCompressNone {
+		pbHandshakeMsg.Compress = &hs.Compress
+	}
+
 	pbMsg := new(pb.P2PMessage)
 	pbMsg.Mid = new(pb.MessageId)
 	*pbMsg.Mid = pb.MessageId_MID_HANDSHAKE
@@ -352,49 +374,23 @@ func (upkg *P2pPackage)ping(inst *peerInstance, ping *Pingpong) PeMgrErrno {
 	}
 
 	//
-	// setup package for payload
-	//
-
-	pbPkg := pb.P2PPackage {
-		Pid:			new(pb.ProtocolId),
-		PayloadLength:	new(uint32),
-		Payload:		make([]byte, 0),
-	}
-
-	*pbPkg.PayloadLength = uint32(len(payload))
-	*pbPkg.Pid = pb.ProtocolId_PID_P2P
-	pbPkg.Payload = append(pbPkg.Payload, payload...)
-
-	//
-	// Set deadline
-	//
-
-	if inst.hto != 0 {
-		inst.conn.SetWriteDeadline(time.Now().Add(inst.ato))
-	} else {
-		inst.conn.SetWriteDeadline(time.Time{})
-	}
-
-	//
-	// write package to peer. in practice, we find that two routines can not
-	// share a conection to write without sync.
+	// setup package for payload and queue it as control traffic, so it would
+	// be sent ahead of all others, see piTx.
 	//
 
-	inst.p2pkgLock.Lock()
+	upkg.Pid = uint32(PID_P2P)
+	upkg.PayloadLength = uint32(len(payload))
+	upkg.Payload = payload
 
-	if err := inst.iow.WriteMsg(&pbPkg); err != nil {
+	if eno, _ := inst.txq.enqueue(upkg, peTxClassControl, false, 0); eno != PeMgrEnoNone {
 
-		yclog.LogCallerFileLine("ping:" +
-			"Write failed, err: %s",
-			err.Error())
+		yclog.LogCallerFileLine("ping: " +
+			"enqueue failed, eno: %d",
+			eno)
 
-		inst.p2pkgLock.Unlock()
-
-		return PeMgrEnoOs
+		return eno
 	}
 
-	inst.p2pkgLock.Unlock()
-
 	return PeMgrEnoNone
 }
 
@@ -428,49 +424,23 @@ func (upkg *P2pPackage)pong(inst *peerInstance, pong *Pingpong) PeMgrErrno {
 	}
 
 	//
-	// setup package for payload
+	// setup package for payload and queue it as control traffic, so it would
+	// be sent ahead of all others, see piTx.
 	//
 
-	pbPkg := pb.P2PPackage {
-		Pid:			new(pb.ProtocolId),
-		PayloadLength:	new(uint32),
-		Payload:		make([]byte, 0),
-	}
+	upkg.Pid = uint32(PID_P2P)
+	upkg.PayloadLength = uint32(len(payload))
+	upkg.Payload = payload
 
-	*pbPkg.PayloadLength = uint32(len(payload))
-	*pbPkg.Pid = pb.ProtocolId_PID_P2P
-	pbPkg.Payload = append(pbPkg.Payload, payload...)
+	if eno, _ := inst.txq.enqueue(upkg, peTxClassControl, false, 0); eno != PeMgrEnoNone {
 
-	//
-	// Set deadline
-	//
+		yclog.LogCallerFileLine("pong: " +
+			"enqueue failed, eno: %d",
+			eno)
 
-	if inst.hto != 0 {
-		inst.conn.SetWriteDeadline(time.Now().Add(inst.ato))
-	} else {
-		inst.conn.SetWriteDeadline(time.Time{})
-	}
-
-	//
-	// write package to peer. in practice, we find that two routines can not
-	// share a conection to write without sync.
-	//
-
-	inst.p2pkgLock.Lock()
-
-	if err := inst.iow.WriteMsg(&pbPkg); err != nil {
-
-		yclog.LogCallerFileLine("pong:" +
-			"Write failed, err: %s",
-			err.Error())
-
-		inst.p2pkgLock.Unlock()
-
-		return PeMgrEnoOs
+		return eno
 	}
 
-	inst.p2pkgLock.Unlock()
-
 	return PeMgrEnoNone
 }
 
@@ -486,15 +456,25 @@ func (upkg *P2pPackage)SendPackage(inst *peerInstance) PeMgrErrno {
 	}
 
 	//
-	// Setup the protobuf "package"
+	// Setup the protobuf "package", the payload is compressed if possible,
+	// and the PayloadLength is that on the wire then.
 	//
 
+	payload, algo, compressed := piCompress(inst, upkg.Payload)
+
 	pbPkg := new(pb.P2PPackage)
 	pbPkg.Pid = new(pb.ProtocolId)
 	*pbPkg.Pid = pb.ProtocolId(upkg.Pid)
 	pbPkg.PayloadLength = new(uint32)
-	*pbPkg.PayloadLength = uint32(upkg.PayloadLength)
-	pbPkg.Payload = append(pbPkg.Payload, upkg.Payload...)
+
+	if compressed {
+		*pbPkg.PayloadLength = uint32(len(payload))
+		pbPkg.Payload = payload
+		pbPkg.Compress = &algo
+	} else {
+		*pbPkg.PayloadLength = uint32(upkg.PayloadLength)
+		pbPkg.Payload = append(pbPkg.Payload, upkg.Payload...)
+	}
 
 	//
 	// Set deadline
@@ -509,8 +489,8 @@ func (upkg *P2pPackage)SendPackage(inst *peerInstance) PeMgrErrno {
 	//
 	// Write package to peer. In practice, we find that two routines can not
 	// share a conection to write without sync. But sync is unnecessary here
-	// for this function here had enter the critical section, see how it is
-	// called pls.
+	// for this function is called by piTx only, all packages to the peer,
+	// pingpong included, are queued and then sent there.
 	//
 
 	if err := inst.iow.WriteMsg(pbPkg); err != nil {
@@ -522,6 +502,9 @@ func (upkg *P2pPackage)SendPackage(inst *peerInstance) PeMgrErrno {
 		return PeMgrEnoOs
 	}
 
+	atomic.AddUint64(&inst.txRaw, uint64(len(upkg.Payload)))
+	atomic.AddUint64(&inst.txWire, uint64(len(payload)))
+
 	return PeMgrEnoNone
 }
 
@@ -569,6 +552,35 @@ func (upkg *P2pPackage)RecvPackage(inst *peerInstance) PeMgrErrno {
 	upkg.PayloadLength	= *pkg.PayloadLength
 	upkg.Payload		= append(upkg.Payload, pkg.Payload ...)
 
+	atomic.AddUint64(&inst.rxWire, uint64(len(pkg.Payload)))
+
+	//
+	// decompress payload if it's flagged, the PayloadLength is checked against
+	// that on the wire, and then updated.
+	//
+
+	if algo := pkg.GetCompress(); algo != PeCompressNone {
+
+		if int(upkg.PayloadLength) != len(upkg.Payload) {
+
+			yclog.LogCallerFileLine("RecvPackage: " +
+				"invalid compressed payload, PlLen: %d, real: %d",
+				upkg.PayloadLength, len(upkg.Payload))
+
+			return PeMgrEnoMessage
+		}
+
+		payload, eno := piDecompress(inst, algo, upkg.Payload)
+		if eno != PeMgrEnoNone {
+			return eno
+		}
+
+		upkg.Payload = payload
+		upkg.PayloadLength = uint32(len(payload))
+	}
+
+	atomic.AddUint64(&inst.rxRaw, uint64(len(upkg.Payload)))
+
 	yclog.LogCallerFileLine("RecvPackage: " +
 		"package got, Pid: %d, PayloadLength: %d",
 		upkg.Pid, upkg.PayloadLength)
@@ -620,6 +632,7 @@ func (upkg *P2pPackage)GetMessage(pmsg *P2pMessage) PeMgrErrno {
 			hs.Protocols[i].Pid = uint32(*p.Pid)
 			copy(hs.Protocols[i].Ver[:], p.Ver)
 		}
+		hs.Compress = pbHS.GetCompress()
 
 	} else if pmsg.Mid == uint32(MID_PING) {
 
@@ -0,0 +1,319 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync"
+	"time"
+)
+
+//
+// Send queue of a peer instance. Packages to be sent to the peer are queued
+// here by SendPackage and pingpong, and drained by piTx. The queue is bounded:
+// when it's full, a non-blocking enqueue fails at once, while a blocking one
+// waits for room till timeout.
+//
+// Packages are queued by priority classes. Control packages, pingpong for
+// example, are always sent ahead of all others. A package can be queued only
+// when the depth of the queue is under the limit of its' class, the lower the
+// class, the lower the limit, see peTxLimits, so part of the capacity is kept
+// for higher classes, and control packages would never starve behind other
+// traffic. Packages of other classes are sent in weighted round robin: in
+// each round, at most peTxWeightHigh packages of high class, peTxWeightNormal
+// of normal class and peTxWeightBulk of bulk class are sent, so the bulk
+// traffic is slowed down but not stopped by others.
+//
+// When the depth of the queue reaches the high water mark, the queue is
+// taken as congested and a P2pIndTxBackpressure is indicated, producers
+// should then slow down; when drained down to the low water mark, another
+// P2pIndTxBackpressure is indicated to tell that it's relieved.
+//
+const (
+	peTxClassControl	= 0		// control
+	peTxClassHigh		= 1		// high
+	peTxClassNormal		= 2		// normal
+	peTxClassBulk		= 3		// bulk
+	peTxClassNum		= 4		// number of classes
+)
+
+const (
+	peTxWeightHigh		= 8		// packages of high class in a round
+	peTxWeightNormal	= 4		// packages of normal class in a round
+	peTxWeightBulk		= 1		// packages of bulk class in a round
+)
+
+//
+// Depth limits of classes, in eighths of capacity
+//
+var peTxLimits = [peTxClassNum]int {
+	peTxClassControl:	8,
+	peTxClassHigh:		7,
+	peTxClassNormal:	6,
+	peTxClassBulk:		5,
+}
+
+var peTxWeights = [peTxClassNum]int {
+	peTxClassControl:	0,
+	peTxClassHigh:		peTxWeightHigh,
+	peTxClassNormal:	peTxWeightNormal,
+	peTxClassBulk:		peTxWeightBulk,
+}
+
+type peTxQueue struct {
+	lock		sync.Mutex					// lock for queue
+	lanes		[peTxClassNum][]*P2pPackage	// packages queued by class
+	credits		[peTxClassNum]int			// packages can be sent by class in current round
+	depth		int							// number of packages queued
+	capacity	int							// max packages can be queued
+	hiWater		int							// depth to be congested
+	loWater		int							// depth to be relieved
+	congested	bool						// congested flag
+	closed		bool						// closed flag
+	notEmpty	chan struct{}				// signaled when a package queued
+	notFull		chan struct{}				// signaled when a package dequeued
+	done		chan struct{}				// closed when queue closed
+}
+
+//
+// Create send queue
+//
+func newPeTxQueue(capacity int) *peTxQueue {
+
+	if capacity <= 0 {
+		capacity = PeInstMaxP2packages
+	}
+
+	return &peTxQueue {
+		credits:	peTxWeights,
+		capacity:	capacity,
+		hiWater:	capacity / 2,
+		loWater:	capacity / 4,
+		notEmpty:	make(chan struct{}, 1),
+		notFull:	make(chan struct{}, 1),
+		done:		make(chan struct{}),
+	}
+}
+
+//
+// Map priority of user package to class
+//
+func peTxClass(prio int) (int, bool) {
+	switch prio {
+	case P2pPrioControl:
+		return peTxClassControl, true
+	case P2pPrioHigh:
+		return peTxClassHigh, true
+	case P2pPrioNormal:
+		return peTxClassNormal, true
+	case P2pPrioBulk:
+		return peTxClassBulk, true
+	}
+	return peTxClassNormal, false
+}
+
+//
+// Put a package into queue. If wait is false, PeMgrEnoTxFull is returned at
+// once when there is no room for it; else wait till timeout for room, zero
+// timeout for waiting till it's queued or the queue closed. The congested
+// flag returned tells if the queue just turned to be congested.
+//
+func (txq *peTxQueue) enqueue(pkg *P2pPackage, class int, wait bool, timeout time.Duration) (eno PeMgrErrno, congested bool) {
+
+	if class < 0 || class >= peTxClassNum {
+		return PeMgrEnoParameter, false
+	}
+
+	limit := txq.capacity * peTxLimits[class] / 8
+	if limit <= 0 {
+		limit = 1
+	}
+
+	var expired <-chan time.Time
+
+	if wait && timeout > 0 {
+		tm := time.NewTimer(timeout)
+		defer tm.Stop()
+		expired = tm.C
+	}
+
+	for {
+
+		txq.lock.Lock()
+
+		if txq.closed {
+			txq.lock.Unlock()
+			return PeMgrEnoNotfound, false
+		}
+
+		if txq.depth < limit {
+
+			txq.lanes[class] = append(txq.lanes[class], pkg)
+			txq.depth++
+
+			if txq.depth >= txq.hiWater && txq.congested == false {
+				txq.congested = true
+				congested = true
+			}
+
+			room := txq.depth < limit
+
+			txq.lock.Unlock()
+
+			txq.signal(txq.notEmpty)
+
+			//
+			// pass the chance on to other producers waiting
+			//
+
+			if room {
+				txq.signal(txq.notFull)
+			}
+
+			return PeMgrEnoNone, congested
+		}
+
+		txq.lock.Unlock()
+
+		if !wait {
+			return PeMgrEnoTxFull, false
+		}
+
+		select {
+		case <-txq.notFull:
+		case <-expired:
+			return PeMgrEnoTxFull, false
+		case <-txq.done:
+		}
+	}
+}
+
+//
+// Get a package from queue, block till one is available or the queue closed,
+// in which case nil is returned. The relieved flag returned tells if the
+// queue just turned to be not congested.
+//
+func (txq *peTxQueue) dequeue() (pkg *P2pPackage, relieved bool) {
+
+	for {
+
+		txq.lock.Lock()
+
+		if txq.closed {
+			txq.lock.Unlock()
+			return nil, false
+		}
+
+		if class := txq.pick(); class >= 0 {
+
+			pkg = txq.lanes[class][0]
+			txq.lanes[class][0] = nil
+			txq.lanes[class] = txq.lanes[class][1:]
+			txq.depth--
+
+			if txq.depth <= txq.loWater && txq.congested {
+				txq.congested = false
+				relieved = true
+			}
+
+			txq.lock.Unlock()
+
+			txq.signal(txq.notFull)
+
+			return pkg, relieved
+		}
+
+		txq.lock.Unlock()
+
+		select {
+		case <-txq.notEmpty:
+		case <-txq.done:
+		}
+	}
+}
+
+//
+// Pick the class to send a package from, -1 if nothing queued. Notice: the
+// lock should be held by caller.
+//
+func (txq *peTxQueue) pick() int {
+
+	if len(txq.lanes[peTxClassControl]) > 0 {
+		return peTxClassControl
+	}
+
+	for round := 0; round < 2; round++ {
+
+		for class := peTxClassHigh; class < peTxClassNum; class++ {
+			if len(txq.lanes[class]) > 0 && txq.credits[class] > 0 {
+				txq.credits[class]--
+				return class
+			}
+		}
+
+		//
+		// classes with packages queued had used up their credits, or nothing
+		// queued at all, start a new round.
+		//
+
+		txq.credits = peTxWeights
+	}
+
+	return -1
+}
+
+//
+// Get depth and capacity of queue
+//
+func (txq *peTxQueue) status() (depth int, capacity int) {
+	txq.lock.Lock()
+	defer txq.lock.Unlock()
+	return txq.depth, txq.capacity
+}
+
+//
+// Close queue, packages queued are discarded and those blocked in enqueue
+// or dequeue are woken up.
+//
+func (txq *peTxQueue) close() {
+
+	txq.lock.Lock()
+	defer txq.lock.Unlock()
+
+	if txq.closed {
+		return
+	}
+
+	txq.closed = true
+	txq.lanes = [peTxClassNum][]*P2pPackage{}
+	txq.depth = 0
+
+	close(txq.done)
+}
+
+//
+// Signal without blocking, a signal pending is enough for waiters
+//
+func (txq *peTxQueue) signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
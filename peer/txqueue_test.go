@@ -0,0 +1,296 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTxQueueBackpressure(t *testing.T) {
+
+	cases := []struct {
+		capacity	int
+		hiWater		int
+		loWater		int
+	}{
+		{16, 8, 4},
+		{64, 32, 16},
+		{7, 3, 1},
+	}
+
+	for _, c := range cases {
+
+		txq := newPeTxQueue(c.capacity)
+
+		//
+		// congested once, when the depth reaches the high water
+		//
+
+		for depth := 1; depth <= c.hiWater + 2; depth++ {
+			eno, congested := txq.enqueue(&P2pPackage{}, peTxClassControl, false, 0)
+			if eno != PeMgrEnoNone {
+				t.Fatalf("capacity %d: enqueue %d failed, eno: %d", c.capacity, depth, eno)
+			}
+			if congested != (depth == c.hiWater) {
+				t.Fatalf("capacity %d: congested %t at depth %d", c.capacity, congested, depth)
+			}
+		}
+
+		//
+		// relieved once, when drained down to the low water
+		//
+
+		for depth := c.hiWater + 1; depth >= 0; depth-- {
+			pkg, relieved := txq.dequeue()
+			if pkg == nil {
+				t.Fatalf("capacity %d: dequeue failed at depth %d", c.capacity, depth)
+			}
+			if relieved != (depth == c.loWater) {
+				t.Fatalf("capacity %d: relieved %t at depth %d", c.capacity, relieved, depth)
+			}
+		}
+
+		//
+		// congested again after relieved
+		//
+
+		var again = false
+		for depth := 1; depth <= c.hiWater; depth++ {
+			_, congested := txq.enqueue(&P2pPackage{}, peTxClassControl, false, 0)
+			again = again || congested
+		}
+		if !again {
+			t.Fatalf("capacity %d: not congested again", c.capacity)
+		}
+	}
+}
+
+func TestTxQueueFull(t *testing.T) {
+
+	const capacity = 8
+
+	txq := newPeTxQueue(capacity)
+
+	//
+	// packages of normal class can't take the last quarter
+	//
+
+	for i := 0; i < capacity; i++ {
+		eno, _ := txq.enqueue(&P2pPackage{}, peTxClassNormal, false, 0)
+		if full := i >= capacity - capacity / 4; full != (eno == PeMgrEnoTxFull) {
+			t.Fatalf("normal enqueue %d, eno: %d", i, eno)
+		}
+	}
+
+	for depth, _ := txq.status(); depth > 0; depth-- {
+		txq.dequeue()
+	}
+
+	for i := 0; i < capacity; i++ {
+		if eno, _ := txq.enqueue(&P2pPackage{}, peTxClassControl, false, 0); eno != PeMgrEnoNone {
+			t.Fatalf("enqueue %d failed, eno: %d", i, eno)
+		}
+	}
+
+	if eno, _ := txq.enqueue(&P2pPackage{}, peTxClassControl, false, 0); eno != PeMgrEnoTxFull {
+		t.Fatalf("non-blocking enqueue on full queue, eno: %d", eno)
+	}
+
+	//
+	// blocking enqueue times out if nothing dequeued
+	//
+
+	start := time.Now()
+	if eno, _ := txq.enqueue(&P2pPackage{}, peTxClassControl, true, 50 * time.Millisecond); eno != PeMgrEnoTxFull {
+		t.Fatalf("blocking enqueue on full queue, eno: %d", eno)
+	}
+	if time.Since(start) < 50 * time.Millisecond {
+		t.Fatalf("blocking enqueue returned before timeout")
+	}
+
+	//
+	// and it's queued once room made
+	//
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		txq.dequeue()
+	}()
+
+	if eno, _ := txq.enqueue(&P2pPackage{}, peTxClassControl, true, time.Second); eno != PeMgrEnoNone {
+		t.Fatalf("blocking enqueue with room made, eno: %d", eno)
+	}
+
+	//
+	// closing wakes up those blocked
+	//
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		txq.close()
+	}()
+
+	if eno, _ := txq.enqueue(&P2pPackage{}, peTxClassControl, true, 0); eno != PeMgrEnoNotfound {
+		t.Fatalf("blocking enqueue on closed queue, eno: %d", eno)
+	}
+
+	if pkg, _ := txq.dequeue(); pkg != nil {
+		t.Fatalf("dequeue from closed queue")
+	}
+}
+
+func TestTxQueueControlFirst(t *testing.T) {
+
+	txq := newPeTxQueue(64)
+
+	classes := []int{
+		peTxClassBulk, peTxClassNormal, peTxClassControl, peTxClassHigh,
+		peTxClassBulk, peTxClassControl, peTxClassNormal, peTxClassControl,
+	}
+
+	for seq, class := range classes {
+		if eno, _ := txq.enqueue(&P2pPackage{Pid: uint32(seq)}, class, false, 0); eno != PeMgrEnoNone {
+			t.Fatalf("enqueue %d failed, eno: %d", seq, eno)
+		}
+	}
+
+	for _, want := range []uint32{2, 5, 7} {
+		if pkg, _ := txq.dequeue(); pkg == nil || pkg.Pid != want {
+			t.Fatalf("control package %d not picked first, got: %+v", want, pkg)
+		}
+	}
+
+	//
+	// a control package queued later still goes ahead of others
+	//
+
+	txq.enqueue(&P2pPackage{Pid: 100}, peTxClassControl, false, 0)
+
+	if pkg, _ := txq.dequeue(); pkg == nil || pkg.Pid != 100 {
+		t.Fatalf("late control package not picked first, got: %+v", pkg)
+	}
+}
+
+func TestTxQueueClassLimits(t *testing.T) {
+
+	const capacity = 64
+
+	cases := []struct {
+		name	string
+		class	int
+		limit	int
+	}{
+		{"control", peTxClassControl, capacity * 8 / 8},
+		{"high", peTxClassHigh, capacity * 7 / 8},
+		{"normal", peTxClassNormal, capacity * 6 / 8},
+		{"bulk", peTxClassBulk, capacity * 5 / 8},
+	}
+
+	for _, c := range cases {
+
+		txq := newPeTxQueue(capacity)
+
+		queued := 0
+		for ; queued <= capacity; queued++ {
+			if eno, _ := txq.enqueue(&P2pPackage{}, c.class, false, 0); eno != PeMgrEnoNone {
+				if eno != PeMgrEnoTxFull {
+					t.Fatalf("%s: enqueue failed, eno: %d", c.name, eno)
+				}
+				break
+			}
+		}
+
+		if queued != c.limit {
+			t.Fatalf("%s: %d queued, limit: %d", c.name, queued, c.limit)
+		}
+
+		//
+		// higher classes still have room up to their limits
+		//
+
+		for _, h := range cases {
+			if h.class < c.class {
+				if eno, _ := txq.enqueue(&P2pPackage{}, h.class, false, 0); eno != PeMgrEnoNone {
+					t.Fatalf("%s: %s rejected at depth %d, eno: %d", c.name, h.name, queued, eno)
+				}
+				queued++
+			}
+		}
+	}
+}
+
+func TestTxQueueWeights(t *testing.T) {
+
+	const each = 16
+
+	txq := newPeTxQueue(256)
+
+	for _, class := range []int{peTxClassBulk, peTxClassNormal, peTxClassHigh} {
+		for i := 0; i < each; i++ {
+			txq.enqueue(&P2pPackage{Pid: uint32(class)}, class, false, 0)
+		}
+	}
+
+	//
+	// rounds of 8 high, 4 normal and 1 bulk while all classes are queued
+	//
+
+	for round := 0; round < 2; round++ {
+
+		var sent [peTxClassNum]int
+
+		for i := 0; i < peTxWeightHigh + peTxWeightNormal + peTxWeightBulk; i++ {
+			pkg, _ := txq.dequeue()
+			sent[pkg.Pid]++
+		}
+
+		if sent[peTxClassHigh] != peTxWeightHigh ||
+			sent[peTxClassNormal] != peTxWeightNormal ||
+			sent[peTxClassBulk] != peTxWeightBulk {
+			t.Fatalf("round %d: sent %v", round, sent)
+		}
+	}
+
+	//
+	// high class used up, the others go on in 4:1 till normal used up, then
+	// bulk alone, nothing lost
+	//
+
+	var sent [peTxClassNum]int
+	for i := 0; i < 10; i++ {
+		pkg, _ := txq.dequeue()
+		sent[pkg.Pid]++
+	}
+
+	if sent[peTxClassNormal] != 8 || sent[peTxClassBulk] != 2 {
+		t.Fatalf("without high: sent %v", sent)
+	}
+
+	for depth, _ := txq.status(); depth > 0; depth, _ = txq.status() {
+		pkg, _ := txq.dequeue()
+		sent[pkg.Pid]++
+	}
+
+	if sent[peTxClassNormal] != each - 2 * peTxWeightNormal || sent[peTxClassBulk] != each - 2 * peTxWeightBulk {
+		t.Fatalf("drained: sent %v", sent)
+	}
+}
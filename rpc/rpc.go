@@ -0,0 +1,719 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package rpc
+
+import (
+	"fmt"
+	"time"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/peer"
+	rm		"github.com/yeeco/p2p/rpc/rpcmsg"
+)
+
+//
+// errno. Notice: values here are carried in responses over the wire, so the
+// order should not be changed, append new ones before RpcMgrEnoUnknown.
+//
+const (
+	RpcMgrEnoNone	= iota
+	RpcMgrEnoParameter
+	RpcMgrEnoScheduler
+	RpcMgrEnoMessage
+	RpcMgrEnoNoPeer
+	RpcMgrEnoTimeout
+	RpcMgrEnoCanceled
+	RpcMgrEnoNoMethod
+	RpcMgrEnoRemote
+	RpcMgrEnoPeerClosed
+	RpcMgrEnoBusy
+	RpcMgrEnoUnknown
+)
+
+type RpcMgrErrno int
+
+//
+// The rpc manager implements request/response over the peers advertised
+// PID_RPC in their handshakes. Each call is identified by an identity which
+// is unique in the p2p instance of the caller, and has a deadline, it's
+// confirmed exactly once: by the response from remote, or by the deadline
+// expired, cancelled locally, or the peer closed, whichever comes first.
+// The time left to the deadline is carried in the request, so the callee
+// can give up a request in time; when a call is cancelled, a CANCEL is sent
+// to tell the callee the same.
+//
+// Handlers of methods are called in routines of their own, their results
+// are posted back to the rpc task and then responsed, those late ones for
+// requests cancelled or expired are discarded.
+//
+const (
+	rpcTickCycle		= time.Millisecond * 100	// deadline checking interval
+	rpcDftTimeout		= time.Second * 30			// default timeout of call
+	rpcMaxTimeout		= time.Minute * 5			// max timeout accepted from remote
+	rpcMaxServing		= 64						// max requests in serving for a peer
+)
+
+//
+// Method handler
+//
+type RpcHandler func(from ycfg.NodeID, method string, req []byte, done <-chan struct{}) ([]byte, error)
+
+//
+// Call pending
+//
+type rpcCall struct {
+	to			ycfg.NodeID					// peer called
+	method		string						// method
+	deadline	time.Time					// deadline
+	cb			func(*sch.MsgRpcCallCfm)	// callback for result
+}
+
+//
+// Request in serving
+//
+type rpcServingKey struct {
+	from		ycfg.NodeID		// the caller
+	id			uint64			// identity of request
+}
+
+type rpcServing struct {
+	method		string			// method
+	deadline	time.Time		// deadline
+	done		chan struct{}	// closed when request is not interested any more
+}
+
+//
+// rpc manager
+//
+const RpcMgrName = sch.RpcMgrName
+
+type rpcManager struct {
+	name		string									// name
+	tep			sch.SchUserTaskEp						// entry
+	sdl			*sch.Scheduler							// pointer to scheduler
+	ptnMe		interface{}								// pointer to myself task node
+	tidTick		int										// deadline timer identity
+	peers		map[ycfg.NodeID]bool					// rpc peers
+	handlers	map[string]RpcHandler					// method handlers
+	calls		map[uint64]*rpcCall						// calls pending by identity
+	serving		map[rpcServingKey]*rpcServing			// requests in serving
+	servingNum	map[ycfg.NodeID]int						// number of requests in serving by peer
+}
+
+//
+// Create rpc manager, one for each p2p instance
+//
+func NewRpcMgr() interface{} {
+
+	var rpcMgr = rpcManager{
+		name:		RpcMgrName,
+		tep:		RpcMgrProc,
+		ptnMe:		nil,
+		tidTick:	sch.SchInvalidTid,
+	}
+
+	rpcMgr.rpcReset()
+
+	return &rpcMgr
+}
+
+//
+// Reset the states
+//
+func (rpcMgr *rpcManager) rpcReset() {
+	rpcMgr.peers = map[ycfg.NodeID]bool{}
+	rpcMgr.handlers = map[string]RpcHandler{}
+	rpcMgr.calls = map[uint64]*rpcCall{}
+	rpcMgr.serving = map[rpcServingKey]*rpcServing{}
+	rpcMgr.servingNum = map[ycfg.NodeID]int{}
+}
+
+//
+// rpc manager entry
+//
+func RpcMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
+	yclog.LogCallerFileLine("RpcMgrProc: scheduled, msg: %d", msg.Id)
+
+	rpcMgr := sch.SchinfGetUserDataArea(ptn).(*rpcManager)
+
+	var eno RpcMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = rpcMgr.rpcMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = rpcMgr.rpcMgrPoweroff(ptn)
+
+	case sch.EvRpcDeadlineTimer:
+		eno = rpcMgr.rpcDeadlineTick()
+
+	case sch.EvRpcMgrPeerInd:
+		eno = rpcMgr.rpcMgrPeerInd(msg.Body.(*sch.MsgRpcPeerInd))
+
+	case sch.EvRpcMgrPkgInd:
+		eno = rpcMgr.rpcMgrPkgInd(msg.Body.(*sch.MsgRpcPkgInd))
+
+	case sch.EvRpcRegisterReq:
+		eno = rpcMgr.rpcMgrRegisterReq(msg.Body.(*sch.MsgRpcRegisterReq))
+
+	case sch.EvRpcCallReq:
+		eno = rpcMgr.rpcMgrCallReq(msg.Body.(*sch.MsgRpcCallReq))
+
+	case sch.EvRpcCancelReq:
+		eno = rpcMgr.rpcMgrCancelReq(msg.Body.(*sch.MsgRpcCancelReq))
+
+	case sch.EvRpcReplyReq:
+		eno = rpcMgr.rpcMgrReplyReq(msg.Body.(*sch.MsgRpcReplyReq))
+
+	default:
+		yclog.LogCallerFileLine("RpcMgrProc: invalid message: %d", msg.Id)
+		eno = RpcMgrEnoParameter
+	}
+
+	if eno != RpcMgrEnoNone {
+		yclog.LogCallerFileLine("RpcMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
+	return sch.SchEnoNone
+}
+
+//
+// Poweron handler
+//
+func (rpcMgr *rpcManager) rpcMgrPoweron(ptn interface{}) RpcMgrErrno {
+
+	rpcMgr.sdl = sch.SchinfGetScheduler(ptn)
+	rpcMgr.ptnMe = ptn
+
+	var td = sch.TimerDescription {
+		Name:	RpcMgrName + "_deadline",
+		Utid:	sch.RpcDeadlineTimerId,
+		Tmt:	sch.SchTmTypePeriod,
+		Dur:	rpcTickCycle,
+		Extra:	nil,
+	}
+
+	eno, tid := sch.SchInfSetTimer(ptn, &td)
+	if eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
+
+		yclog.LogCallerFileLine("rpcMgrPoweron: " +
+			"SchInfSetTimer failed, eno: %d, timer: %s",
+			eno, td.Name)
+
+		return RpcMgrEnoScheduler
+	}
+
+	rpcMgr.tidTick = tid
+
+	return RpcMgrEnoNone
+}
+
+//
+// Poweroff handler. Calls pending are confirmed with RpcMgrEnoCanceled, and
+// handlers still running are told to give up.
+//
+func (rpcMgr *rpcManager) rpcMgrPoweroff(ptn interface{}) RpcMgrErrno {
+
+	if rpcMgr.tidTick != sch.SchInvalidTid {
+		sch.SchinfKillTimer(ptn, rpcMgr.tidTick)
+		rpcMgr.tidTick = sch.SchInvalidTid
+	}
+
+	for id, call := range rpcMgr.calls {
+		delete(rpcMgr.calls, id)
+		rpcConfirm(call, id, RpcMgrEnoCanceled, "", nil)
+	}
+
+	for _, srv := range rpcMgr.serving {
+		close(srv.done)
+	}
+
+	rpcMgr.rpcReset()
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return RpcMgrEnoUnknown
+	}
+
+	return RpcMgrEnoNone
+}
+
+//
+// Deadline timer handler
+//
+func (rpcMgr *rpcManager) rpcDeadlineTick() RpcMgrErrno {
+
+	now := time.Now()
+
+	for id, call := range rpcMgr.calls {
+		if now.After(call.deadline) {
+
+			yclog.LogCallerFileLine("rpcDeadlineTick: " +
+				"call timeout, id: %d, method: %s, peer: %s",
+				id, call.method, fmt.Sprintf("%X", call.to))
+
+			delete(rpcMgr.calls, id)
+			rpcConfirm(call, id, RpcMgrEnoTimeout, "", nil)
+		}
+	}
+
+	for key, srv := range rpcMgr.serving {
+		if now.After(srv.deadline) {
+			rpcMgr.rpcServingDone(key, srv)
+		}
+	}
+
+	return RpcMgrEnoNone
+}
+
+//
+// Peer activated or closed indication handler
+//
+func (rpcMgr *rpcManager) rpcMgrPeerInd(ind *sch.MsgRpcPeerInd) RpcMgrErrno {
+
+	id := ind.Node.ID
+
+	switch ind.Ind {
+
+	case peer.P2pIndPeerActivated:
+
+		rpcMgr.peers[id] = true
+
+	case peer.P2pIndPeerClosed:
+
+		delete(rpcMgr.peers, id)
+
+		for cid, call := range rpcMgr.calls {
+			if call.to == id {
+				delete(rpcMgr.calls, cid)
+				rpcConfirm(call, cid, RpcMgrEnoPeerClosed, "", nil)
+			}
+		}
+
+		for key, srv := range rpcMgr.serving {
+			if key.from == id {
+				rpcMgr.rpcServingDone(key, srv)
+			}
+		}
+
+	default:
+
+		yclog.LogCallerFileLine("rpcMgrPeerInd: " +
+			"invalid indication: %d, peer: %s",
+			ind.Ind, fmt.Sprintf("%X", id))
+
+		return RpcMgrEnoParameter
+	}
+
+	return RpcMgrEnoNone
+}
+
+//
+// Package from peer indication handler
+//
+func (rpcMgr *rpcManager) rpcMgrPkgInd(ind *sch.MsgRpcPkgInd) RpcMgrErrno {
+
+	msg, eno := rm.Decode(ind.Payload)
+	if eno != rm.RpcMsgEnoNone {
+
+		yclog.LogCallerFileLine("rpcMgrPkgInd: " +
+			"Decode failed, eno: %d, from: %s",
+			eno, fmt.Sprintf("%X", ind.From))
+
+		return RpcMgrEnoMessage
+	}
+
+	switch msg.Mid {
+
+	case rm.MID_RPC_REQUEST:
+		return rpcMgr.rpcRequestInd(ind.From, msg.Request)
+
+	case rm.MID_RPC_RESPONSE:
+		return rpcMgr.rpcResponseInd(ind.From, msg.Response)
+
+	case rm.MID_RPC_CANCEL:
+		return rpcMgr.rpcCancelInd(ind.From, msg.Cancel)
+	}
+
+	yclog.LogCallerFileLine("rpcMgrPkgInd: invalid mid: %d", msg.Mid)
+
+	return RpcMgrEnoMessage
+}
+
+//
+// Request from peer handler
+//
+func (rpcMgr *rpcManager) rpcRequestInd(from ycfg.NodeID, req *rm.Request) RpcMgrErrno {
+
+	if req == nil {
+		yclog.LogCallerFileLine("rpcRequestInd: invalid request")
+		return RpcMgrEnoMessage
+	}
+
+	key := rpcServingKey{from: from, id: req.Id}
+
+	if _, dup := rpcMgr.serving[key]; dup {
+
+		yclog.LogCallerFileLine("rpcRequestInd: " +
+			"duplicated, id: %d, from: %s",
+			req.Id, fmt.Sprintf("%X", from))
+
+		return RpcMgrEnoNone
+	}
+
+	h, eno, why := rpcMgr.rpcAdmit(from, req.Method)
+	if eno != RpcMgrEnoNone {
+		return rpcMgr.rpcRespond(from, req.Id, eno, why, nil)
+	}
+
+	srv := &rpcServing {
+		method:		req.Method,
+		deadline:	time.Now().Add(rpcServingTimeout(req.Timeout)),
+		done:		make(chan struct{}),
+	}
+
+	rpcMgr.serving[key] = srv
+	rpcMgr.servingNum[from]++
+
+	go rpcMgr.rpcServe(h, from, req, srv.done)
+
+	return RpcMgrEnoNone
+}
+
+//
+// Check if a request from peer can be served, if not, the errno and the
+// description to be responded are returned.
+//
+func (rpcMgr *rpcManager) rpcAdmit(from ycfg.NodeID, method string) (RpcHandler, RpcMgrErrno, string) {
+
+	h, ok := rpcMgr.handlers[method]
+	if !ok {
+		return nil, RpcMgrEnoNoMethod, "no such method: " + method
+	}
+
+	if rpcMgr.servingNum[from] >= rpcMaxServing {
+		return nil, RpcMgrEnoBusy, "too many requests"
+	}
+
+	return h, RpcMgrEnoNone, ""
+}
+
+//
+// Timeout of request from peer by the time left to deadline it carried, in
+// milliseconds, the default one taken for zero.
+//
+func rpcServingTimeout(ms uint32) time.Duration {
+
+	timeout := time.Duration(ms) * time.Millisecond
+
+	if timeout <= 0 {
+		return rpcDftTimeout
+	} else if timeout > rpcMaxTimeout {
+		return rpcMaxTimeout
+	}
+
+	return timeout
+}
+
+//
+// Call handler of method and post the result back to rpc task, it's run in
+// a routine of its' own.
+//
+func (rpcMgr *rpcManager) rpcServe(h RpcHandler, from ycfg.NodeID, req *rm.Request, done chan struct{}) {
+
+	rsp, err := h(from, req.Method, req.Payload, done)
+
+	var reply = sch.MsgRpcReplyReq {
+		To:			from,
+		Id:			req.Id,
+		Payload:	rsp,
+		Err:		err,
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	sch.SchinfMakeMessage(&schMsg, rpcMgr.ptnMe, rpcMgr.ptnMe, sch.EvRpcReplyReq, &reply)
+
+	if eno := sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("rpcServe: " +
+			"SchinfSendMessage failed, eno: %d, id: %d",
+			eno, req.Id)
+	}
+}
+
+//
+// Result from handler of method
+//
+func (rpcMgr *rpcManager) rpcMgrReplyReq(reply *sch.MsgRpcReplyReq) RpcMgrErrno {
+
+	key := rpcServingKey{from: reply.To, id: reply.Id}
+
+	srv, ok := rpcMgr.serving[key]
+	if !ok {
+
+		yclog.LogCallerFileLine("rpcMgrReplyReq: " +
+			"request not in serving, discarded, id: %d, to: %s",
+			reply.Id, fmt.Sprintf("%X", reply.To))
+
+		return RpcMgrEnoNone
+	}
+
+	rpcMgr.rpcServingDone(key, srv)
+
+	if reply.Err != nil {
+		return rpcMgr.rpcRespond(reply.To, reply.Id, RpcMgrEnoRemote, reply.Err.Error(), nil)
+	}
+
+	return rpcMgr.rpcRespond(reply.To, reply.Id, RpcMgrEnoNone, "", reply.Payload)
+}
+
+//
+// Cancel from peer handler
+//
+func (rpcMgr *rpcManager) rpcCancelInd(from ycfg.NodeID, cancel *rm.Cancel) RpcMgrErrno {
+
+	if cancel == nil {
+		yclog.LogCallerFileLine("rpcCancelInd: invalid cancel")
+		return RpcMgrEnoMessage
+	}
+
+	key := rpcServingKey{from: from, id: cancel.Id}
+
+	if srv, ok := rpcMgr.serving[key]; ok {
+		rpcMgr.rpcServingDone(key, srv)
+	}
+
+	return RpcMgrEnoNone
+}
+
+//
+// Response from peer handler
+//
+func (rpcMgr *rpcManager) rpcResponseInd(from ycfg.NodeID, rsp *rm.Response) RpcMgrErrno {
+
+	if rsp == nil {
+		yclog.LogCallerFileLine("rpcResponseInd: invalid response")
+		return RpcMgrEnoMessage
+	}
+
+	call, ok := rpcMgr.calls[rsp.Id]
+	if !ok || call.to != from {
+
+		yclog.LogCallerFileLine("rpcResponseInd: " +
+			"call not pending, discarded, id: %d, from: %s",
+			rsp.Id, fmt.Sprintf("%X", from))
+
+		return RpcMgrEnoNone
+	}
+
+	delete(rpcMgr.calls, rsp.Id)
+	rpcConfirm(call, rsp.Id, RpcMgrErrno(rsp.Eno), rsp.Error, rsp.Payload)
+
+	return RpcMgrEnoNone
+}
+
+//
+// Register request handler
+//
+func (rpcMgr *rpcManager) rpcMgrRegisterReq(req *sch.MsgRpcRegisterReq) RpcMgrErrno {
+
+	if len(req.Method) == 0 {
+		yclog.LogCallerFileLine("rpcMgrRegisterReq: invalid method")
+		return RpcMgrEnoParameter
+	}
+
+	if req.Handler == nil {
+		delete(rpcMgr.handlers, req.Method)
+		return RpcMgrEnoNone
+	}
+
+	rpcMgr.handlers[req.Method] = req.Handler
+
+	return RpcMgrEnoNone
+}
+
+//
+// Call request handler
+//
+func (rpcMgr *rpcManager) rpcMgrCallReq(req *sch.MsgRpcCallReq) RpcMgrErrno {
+
+	if len(req.Method) == 0 || req.Cb == nil {
+		yclog.LogCallerFileLine("rpcMgrCallReq: invalid parameters")
+		return RpcMgrEnoParameter
+	}
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = rpcDftTimeout
+	}
+
+	var call = rpcCall {
+		to:			req.To,
+		method:		req.Method,
+		deadline:	time.Now().Add(timeout),
+		cb:			req.Cb,
+	}
+
+	if _, dup := rpcMgr.calls[req.Id]; dup {
+
+		yclog.LogCallerFileLine("rpcMgrCallReq: duplicated id: %d", req.Id)
+
+		rpcConfirm(&call, req.Id, RpcMgrEnoParameter, "", nil)
+		return RpcMgrEnoParameter
+	}
+
+	if rpcMgr.peers[req.To] != true {
+		rpcConfirm(&call, req.Id, RpcMgrEnoNoPeer, "", nil)
+		return RpcMgrEnoNone
+	}
+
+	var msg = rm.RpcMessage {
+		Mid:		rm.MID_RPC_REQUEST,
+		Request:	&rm.Request {
+			Id:			req.Id,
+			Method:		req.Method,
+			Timeout:	uint32(timeout / time.Millisecond),
+			Payload:	req.Payload,
+		},
+	}
+
+	if eno := rpcMgr.rpcSend(req.To, &msg); eno != RpcMgrEnoNone {
+		rpcConfirm(&call, req.Id, eno, "", nil)
+		return RpcMgrEnoNone
+	}
+
+	rpcMgr.calls[req.Id] = &call
+
+	return RpcMgrEnoNone
+}
+
+//
+// Cancel request handler
+//
+func (rpcMgr *rpcManager) rpcMgrCancelReq(req *sch.MsgRpcCancelReq) RpcMgrErrno {
+
+	call, ok := rpcMgr.calls[req.Id]
+	if !ok {
+		return RpcMgrEnoNone
+	}
+
+	delete(rpcMgr.calls, req.Id)
+
+	var msg = rm.RpcMessage {
+		Mid:	rm.MID_RPC_CANCEL,
+		Cancel:	&rm.Cancel{Id: req.Id},
+	}
+
+	rpcMgr.rpcSend(call.to, &msg)
+	rpcConfirm(call, req.Id, RpcMgrEnoCanceled, "", nil)
+
+	return RpcMgrEnoNone
+}
+
+//
+// Request in serving done, tell the handler if it's still running
+//
+func (rpcMgr *rpcManager) rpcServingDone(key rpcServingKey, srv *rpcServing) {
+
+	close(srv.done)
+	delete(rpcMgr.serving, key)
+
+	if rpcMgr.servingNum[key.from]--; rpcMgr.servingNum[key.from] <= 0 {
+		delete(rpcMgr.servingNum, key.from)
+	}
+}
+
+//
+// Confirm a call with result
+//
+func rpcConfirm(call *rpcCall, id uint64, eno RpcMgrErrno, err string, payload []byte) {
+
+	var cfm = sch.MsgRpcCallCfm {
+		Eno:		int(eno),
+		Error:		err,
+		Id:			id,
+		Payload:	payload,
+	}
+
+	call.cb(&cfm)
+}
+
+//
+// Send response to peer
+//
+func (rpcMgr *rpcManager) rpcRespond(to ycfg.NodeID, id uint64, eno RpcMgrErrno, err string, payload []byte) RpcMgrErrno {
+
+	var msg = rm.RpcMessage {
+		Mid:		rm.MID_RPC_RESPONSE,
+		Response:	&rm.Response {
+			Id:			id,
+			Eno:		uint32(eno),
+			Error:		err,
+			Payload:	payload,
+		},
+	}
+
+	return rpcMgr.rpcSend(to, &msg)
+}
+
+//
+// Send rpc message to peer
+//
+func (rpcMgr *rpcManager) rpcSend(to ycfg.NodeID, msg *rm.RpcMessage) RpcMgrErrno {
+
+	payload, eno := msg.Encode()
+	if eno != rm.RpcMsgEnoNone {
+
+		yclog.LogCallerFileLine("rpcSend: " +
+			"Encode failed, eno: %d",
+			eno)
+
+		return RpcMgrEnoMessage
+	}
+
+	var pkg = peer.P2pPackage2Peer {
+		IdList:			[]peer.PeerId{peer.PeerId(to)},
+		ProtoId:		int(peer.PID_RPC),
+		Priority:		peer.P2pPrioHigh,
+		PayloadLength:	len(payload),
+		Payload:		payload,
+	}
+
+	if pe, _ := peer.SendPackage(rpcMgr.sdl, &pkg); pe != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("rpcSend: " +
+			"SendPackage failed, eno: %d, mid: %d, to: %s",
+			pe, msg.Mid, fmt.Sprintf("%X", to))
+
+		if pe == peer.PeMgrEnoTxFull {
+			return RpcMgrEnoBusy
+		}
+
+		return RpcMgrEnoNoPeer
+	}
+
+	return RpcMgrEnoNone
+}
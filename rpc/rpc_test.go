@@ -0,0 +1,320 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package rpc
+
+import (
+	"sync"
+	"testing"
+	"time"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	rm		"github.com/yeeco/p2p/rpc/rpcmsg"
+)
+
+//
+// Rpc manager not started, the scheduler has no peer manager, so messages
+// sent by it fail with RpcMgrEnoNoPeer.
+//
+func rpcTestManager(t *testing.T) *rpcManager {
+
+	eno, sdl := sch.SchinfSchedulerInit(&ycfg.Config{})
+	if eno != sch.SchEnoNone {
+		t.Fatalf("SchinfSchedulerInit failed, eno: %d", eno)
+	}
+
+	rpcMgr := NewRpcMgr().(*rpcManager)
+	rpcMgr.sdl = sdl
+
+	return rpcMgr
+}
+
+//
+// Handler blocked till the request is not interested any more, it tells
+// when it's called and when it's done.
+//
+type rpcTestHandler struct {
+	lock	sync.Mutex
+	called	int
+	started	chan struct{}
+	exited	chan struct{}
+}
+
+func newRpcTestHandler() *rpcTestHandler {
+	return &rpcTestHandler {
+		started:	make(chan struct{}, rpcMaxServing * 2),
+		exited:		make(chan struct{}, rpcMaxServing * 2),
+	}
+}
+
+func (h *rpcTestHandler) handle(from ycfg.NodeID, method string, req []byte, done <-chan struct{}) ([]byte, error) {
+	h.lock.Lock()
+	h.called++
+	h.lock.Unlock()
+	h.started <- struct{}{}
+	<-done
+	h.exited <- struct{}{}
+	return nil, nil
+}
+
+func (h *rpcTestHandler) calls() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.called
+}
+
+func TestRpcServingTimeout(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		ms		uint32
+		want	time.Duration
+	}{
+		{"default", 0, rpcDftTimeout},
+		{"one millisecond", 1, time.Millisecond},
+		{"in range", 1500, 1500 * time.Millisecond},
+		{"max", uint32(rpcMaxTimeout / time.Millisecond), rpcMaxTimeout},
+		{"over max", uint32(rpcMaxTimeout / time.Millisecond) + 1, rpcMaxTimeout},
+		{"max of uint32", 0xffffffff, rpcMaxTimeout},
+	}
+
+	for _, c := range cases {
+		if timeout := rpcServingTimeout(c.ms); timeout != c.want {
+			t.Fatalf("%s: timeout %s, want %s", c.name, timeout, c.want)
+		}
+	}
+
+	//
+	// and the deadline of request in serving is set with it
+	//
+
+	rpcMgr := rpcTestManager(t)
+	h := newRpcTestHandler()
+	rpcMgr.handlers["echo"] = h.handle
+
+	from := ycfg.NodeID{1}
+
+	for id, c := range cases {
+
+		start := time.Now()
+		rpcMgr.rpcRequestInd(from, &rm.Request{Id: uint64(id), Method: "echo", Timeout: c.ms})
+
+		srv, ok := rpcMgr.serving[rpcServingKey{from: from, id: uint64(id)}]
+		if !ok {
+			t.Fatalf("%s: not in serving", c.name)
+		}
+
+		if d := srv.deadline.Sub(start); d < c.want || d > c.want + time.Second {
+			t.Fatalf("%s: deadline in %s, want %s", c.name, d, c.want)
+		}
+	}
+}
+
+func TestRpcRequestDuplicated(t *testing.T) {
+
+	rpcMgr := rpcTestManager(t)
+	h := newRpcTestHandler()
+	rpcMgr.handlers["echo"] = h.handle
+
+	from := ycfg.NodeID{1}
+	other := ycfg.NodeID{2}
+
+	rpcMgr.rpcRequestInd(from, &rm.Request{Id: 1, Method: "echo"})
+	rpcMgr.rpcRequestInd(from, &rm.Request{Id: 1, Method: "echo"})
+	rpcMgr.rpcRequestInd(from, &rm.Request{Id: 2, Method: "echo"})
+	rpcMgr.rpcRequestInd(other, &rm.Request{Id: 1, Method: "echo"})
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-h.started:
+		case <-time.After(time.Second):
+			t.Fatalf("handler %d not called", i)
+		}
+	}
+
+	if n := h.calls(); n != 3 {
+		t.Fatalf("handler called %d times, want 3", n)
+	}
+
+	if rpcMgr.servingNum[from] != 2 || rpcMgr.servingNum[other] != 1 || len(rpcMgr.serving) != 3 {
+		t.Fatalf("in serving: %d, %d, total %d, want 2, 1, 3",
+			rpcMgr.servingNum[from], rpcMgr.servingNum[other], len(rpcMgr.serving))
+	}
+}
+
+func TestRpcRequestBusy(t *testing.T) {
+
+	rpcMgr := rpcTestManager(t)
+	h := newRpcTestHandler()
+	rpcMgr.handlers["echo"] = h.handle
+
+	from := ycfg.NodeID{1}
+	other := ycfg.NodeID{2}
+
+	cases := []struct {
+		name	string
+		from	ycfg.NodeID
+		method	string
+		want	RpcMgrErrno
+	}{
+		{"no method", from, "none", RpcMgrEnoNoMethod},
+		{"under limit", from, "echo", RpcMgrEnoNone},
+		{"limit reached", from, "echo", RpcMgrEnoBusy},
+		{"other peer", other, "echo", RpcMgrEnoNone},
+	}
+
+	for id, c := range cases {
+
+		if c.want == RpcMgrEnoBusy {
+			for i := rpcMgr.servingNum[c.from]; i < rpcMaxServing; i++ {
+				rpcMgr.rpcRequestInd(c.from, &rm.Request{Id: uint64(1000 + i), Method: c.method})
+			}
+		}
+
+		if _, eno, _ := rpcMgr.rpcAdmit(c.from, c.method); eno != c.want {
+			t.Fatalf("%s: rpcAdmit returned %d, want %d", c.name, eno, c.want)
+		}
+
+		num := rpcMgr.servingNum[c.from]
+		rpcMgr.rpcRequestInd(c.from, &rm.Request{Id: uint64(id), Method: c.method})
+
+		_, serving := rpcMgr.serving[rpcServingKey{from: c.from, id: uint64(id)}]
+		if serving != (c.want == RpcMgrEnoNone) {
+			t.Fatalf("%s: in serving %t", c.name, serving)
+		}
+
+		if c.want != RpcMgrEnoNone && rpcMgr.servingNum[c.from] != num {
+			t.Fatalf("%s: serving %d, want %d", c.name, rpcMgr.servingNum[c.from], num)
+		}
+	}
+
+	if rpcMgr.servingNum[from] != rpcMaxServing {
+		t.Fatalf("serving %d, want %d", rpcMgr.servingNum[from], rpcMaxServing)
+	}
+}
+
+func TestRpcCancel(t *testing.T) {
+
+	rpcMgr := rpcTestManager(t)
+	h := newRpcTestHandler()
+	rpcMgr.handlers["echo"] = h.handle
+
+	from := ycfg.NodeID{1}
+	other := ycfg.NodeID{2}
+
+	rpcMgr.rpcRequestInd(from, &rm.Request{Id: 1, Method: "echo"})
+	<-h.started
+
+	//
+	// cancel from other peer, or of other request, changes nothing
+	//
+
+	rpcMgr.rpcCancelInd(other, &rm.Cancel{Id: 1})
+	rpcMgr.rpcCancelInd(from, &rm.Cancel{Id: 2})
+
+	select {
+	case <-h.exited:
+		t.Fatalf("handler done by cancel of others")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	rpcMgr.rpcCancelInd(from, &rm.Cancel{Id: 1})
+
+	select {
+	case <-h.exited:
+	case <-time.After(time.Second):
+		t.Fatalf("handler not done by cancel")
+	}
+
+	if len(rpcMgr.serving) != 0 || len(rpcMgr.servingNum) != 0 {
+		t.Fatalf("still in serving: %d, %d", len(rpcMgr.serving), len(rpcMgr.servingNum))
+	}
+
+	//
+	// the result of the handler is discarded
+	//
+
+	rpcMgr.rpcMgrReplyReq(&sch.MsgRpcReplyReq{To: from, Id: 1})
+
+	if len(rpcMgr.servingNum) != 0 {
+		t.Fatalf("serving number changed by late reply")
+	}
+}
+
+func TestRpcResponse(t *testing.T) {
+
+	to := ycfg.NodeID{1}
+
+	cases := []struct {
+		name		string
+		deadline	time.Duration
+		from		ycfg.NodeID
+		want		[]int
+		again		[]int
+	}{
+		{"in time", time.Minute, to, []int{RpcMgrEnoNone}, []int{RpcMgrEnoNone}},
+		{"from other peer", time.Minute, ycfg.NodeID{2}, nil, []int{RpcMgrEnoNone}},
+		{"after deadline", -time.Millisecond, to, []int{RpcMgrEnoTimeout}, []int{RpcMgrEnoTimeout}},
+	}
+
+	equal := func(a, b []int) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, c := range cases {
+
+		rpcMgr := rpcTestManager(t)
+
+		var got []int
+
+		rpcMgr.calls[1] = &rpcCall {
+			to:			to,
+			method:		"echo",
+			deadline:	time.Now().Add(c.deadline),
+			cb:			func(cfm *sch.MsgRpcCallCfm) { got = append(got, cfm.Eno) },
+		}
+
+		rpcMgr.rpcDeadlineTick()
+		rpcMgr.rpcResponseInd(c.from, &rm.Response{Id: 1, Payload: []byte("pong")})
+
+		if !equal(got, c.want) {
+			t.Fatalf("%s: confirmed %v, want %v", c.name, got, c.want)
+		}
+
+		//
+		// a call is confirmed exactly once, response from the callee again
+		// is discarded if it's confirmed already
+		//
+
+		rpcMgr.rpcResponseInd(to, &rm.Response{Id: 1, Payload: []byte("pong")})
+
+		if !equal(got, c.again) {
+			t.Fatalf("%s: confirmed %v, want %v", c.name, got, c.again)
+		}
+	}
+}
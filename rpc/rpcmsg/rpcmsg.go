@@ -0,0 +1,226 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package rpcmsg
+
+import (
+	pb		"github.com/yeeco/p2p/peer/pb"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// RPC messages carried by packages with protocol identity PID_RPC over the
+// peer connections, see tcpmsg.proto for the protobuf specification.
+//
+const (
+	MID_RPC_REQUEST		= pb.MessageId_MID_RPC_REQUEST
+	MID_RPC_RESPONSE	= pb.MessageId_MID_RPC_RESPONSE
+	MID_RPC_CANCEL		= pb.MessageId_MID_RPC_CANCEL
+)
+
+//
+// errno
+//
+const (
+	RpcMsgEnoNone	= iota
+	RpcMsgEnoParameter
+	RpcMsgEnoEncode
+	RpcMsgEnoDecode
+	RpcMsgEnoMessage
+)
+
+type RpcMsgErrno int
+
+type (
+
+	// Request: a method called by remote
+	Request struct {
+		Id			uint64			// identity of request, by the caller
+		Method		string			// method called
+		Timeout		uint32			// time left to deadline in milliseconds
+		Payload		[]byte			// request payload
+	}
+
+	// Response: result of a request
+	Response struct {
+		Id			uint64			// identity of request
+		Eno			uint32			// result, 0: ok, others: errno
+		Error		string			// error description
+		Payload		[]byte			// response payload
+	}
+
+	// Cancel: the caller is not interested in the result of a request
+	Cancel struct {
+		Id			uint64			// identity of request
+	}
+
+	// RpcMessage: one of those above
+	RpcMessage struct {
+		Mid			pb.MessageId	// message identity
+		Request		*Request		// for REQUEST
+		Response	*Response		// for RESPONSE
+		Cancel		*Cancel			// for CANCEL
+	}
+)
+
+//
+// Encode rpc message to payload of package
+//
+func (rm *RpcMessage) Encode() ([]byte, RpcMsgErrno) {
+
+	pbMsg := new(pb.RpcMessage)
+	pbMsg.Mid = new(pb.MessageId)
+	*pbMsg.Mid = rm.Mid
+
+	var absent = false
+
+	switch rm.Mid {
+
+	case MID_RPC_REQUEST:
+
+		if absent = rm.Request == nil; !absent {
+			pbMsg.Request = &pb.RpcMessage_Request {
+				Id:			pbUint64(rm.Request.Id),
+				Method:		pbString(rm.Request.Method),
+				Timeout:	pbUint32(rm.Request.Timeout),
+				Payload:	rm.Request.Payload,
+			}
+		}
+
+	case MID_RPC_RESPONSE:
+
+		if absent = rm.Response == nil; !absent {
+			pbMsg.Response = &pb.RpcMessage_Response {
+				Id:			pbUint64(rm.Response.Id),
+				Eno:		pbUint32(rm.Response.Eno),
+				Error:		pbString(rm.Response.Error),
+				Payload:	rm.Response.Payload,
+			}
+		}
+
+	case MID_RPC_CANCEL:
+
+		if absent = rm.Cancel == nil; !absent {
+			pbMsg.Cancel = &pb.RpcMessage_Cancel {
+				Id:	pbUint64(rm.Cancel.Id),
+			}
+		}
+
+	default:
+		yclog.LogCallerFileLine("Encode: invalid mid: %d", rm.Mid)
+		return nil, RpcMsgEnoParameter
+	}
+
+	if absent {
+		yclog.LogCallerFileLine("Encode: message absent, mid: %d", rm.Mid)
+		return nil, RpcMsgEnoParameter
+	}
+
+	buf, err := pbMsg.Marshal()
+	if err != nil {
+
+		yclog.LogCallerFileLine("Encode: " +
+			"Marshal failed, err: %s",
+			err.Error())
+
+		return nil, RpcMsgEnoEncode
+	}
+
+	return buf, RpcMsgEnoNone
+}
+
+//
+// Decode rpc message from payload of package
+//
+func Decode(payload []byte) (*RpcMessage, RpcMsgErrno) {
+
+	pbMsg := new(pb.RpcMessage)
+
+	if err := pbMsg.Unmarshal(payload); err != nil {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"Unmarshal failed, err: %s",
+			err.Error())
+
+		return nil, RpcMsgEnoDecode
+	}
+
+	rm := &RpcMessage{Mid: pbMsg.GetMid()}
+	var absent = false
+
+	switch rm.Mid {
+
+	case MID_RPC_REQUEST:
+
+		if pbReq := pbMsg.GetRequest(); pbReq != nil {
+			rm.Request = &Request {
+				Id:			pbReq.GetId(),
+				Method:		pbReq.GetMethod(),
+				Timeout:	pbReq.GetTimeout(),
+				Payload:	append([]byte{}, pbReq.Payload...),
+			}
+		}
+		absent = rm.Request == nil
+
+	case MID_RPC_RESPONSE:
+
+		if pbRsp := pbMsg.GetResponse(); pbRsp != nil {
+			rm.Response = &Response {
+				Id:			pbRsp.GetId(),
+				Eno:		pbRsp.GetEno(),
+				Error:		pbRsp.GetError(),
+				Payload:	append([]byte{}, pbRsp.Payload...),
+			}
+		}
+		absent = rm.Response == nil
+
+	case MID_RPC_CANCEL:
+
+		if pbCnl := pbMsg.GetCancel(); pbCnl != nil {
+			rm.Cancel = &Cancel {
+				Id:	pbCnl.GetId(),
+			}
+		}
+		absent = rm.Cancel == nil
+
+	default:
+		yclog.LogCallerFileLine("Decode: invalid mid: %d", rm.Mid)
+		return nil, RpcMsgEnoMessage
+	}
+
+	if absent {
+		yclog.LogCallerFileLine("Decode: message absent or invalid, mid: %d", rm.Mid)
+		return nil, RpcMsgEnoMessage
+	}
+
+	return rm, RpcMsgEnoNone
+}
+
+func pbUint64(v uint64) *uint64 {
+	return &v
+}
+
+func pbUint32(v uint32) *uint32 {
+	return &v
+}
+
+func pbString(v string) *string {
+	return &v
+}
@@ -29,8 +29,10 @@
 package scheduler
 
 import (
+	"time"
 	ycfg	"github.com/yeeco/p2p/config"
 	um		"github.com/yeeco/p2p/discover/udpmsg"
+	dm		"github.com/yeeco/p2p/dht/dhtmsg"
 )
 
 
@@ -250,14 +252,487 @@ type MsgPeCloseReq struct {
 //
 // DHT manager event
 //
-const EvDhtMgrBase = 1900
+const (
+	EvDhtMgrBase		= 1900
+	EvDhtStoreReq		= EvDhtMgrBase + 1
+	EvDhtMgrPkgInd		= EvDhtMgrBase + 2
+	EvDhtRetriveReq		= EvDhtMgrBase + 3
+	EvDhtStoreCfm		= EvDhtMgrBase + 4
+	EvDhtRetriveCfm		= EvDhtMgrBase + 5
+	EvDhtObjStoreReq	= EvDhtMgrBase + 6
+	EvDhtObjRetriveReq	= EvDhtMgrBase + 7
+	EvDhtMgrPeerInd		= EvDhtMgrBase + 8
+	EvDhtObjStoreCfm	= EvDhtMgrBase + 9
+	EvDhtObjRetriveCfm	= EvDhtMgrBase + 10
+)
+
+//
+// DHT syncer timers
+//
+const (
+	DhtsySyncTimerId	= 0
+	DhtsyKickTimerId	= 1
+	EvDhtsySyncTimer	= EvTimerBase + DhtsySyncTimerId
+	EvDhtsyKickTimer	= EvTimerBase + DhtsyKickTimerId
+)
+
+//
+// EvDhtStoreReq
+//
+type MsgDhtStoreReq struct {
+	Key		[]byte		// key for the chunk
+	Chunk	[]byte		// the chunk data
+	Id		uint64		// identity of request
+	Ptn		interface{}	// task to confirm to, nil for the user of dht
+}
+
+//
+// Confirm for EvDhtStoreReq. If the request is from the user of dht, it's not
+// an event, it is handed over to the confirm callback of dht, see dht.DhtConfirm
+// pls; else it's sent to the requester task as event EvDhtStoreCfm.
+//
+type MsgDhtStoreCfm struct {
+	Eno		int			// result, 0: ok, others: errno
+	Key		[]byte		// key for the chunk
+	Id		uint64		// identity of request
+}
+
+//
+// EvDhtMgrPkgInd: package of PID_DHT received from peer
+//
+type MsgDhtPkgInd struct {
+	From	ycfg.NodeID	// where the package from
+	Payload	[]byte		// payload of package
+}
+
+//
+// EvDhtMgrPeerInd: peer activated or closed, it's sent by peer manager to
+// dht manager, and then dispatched to those dht tasks interested in.
+//
+type MsgDhtPeerInd struct {
+	Ind		int			// peer.P2pIndPeerActivated or peer.P2pIndPeerClosed
+	Node	ycfg.Node	// peer node
+}
+
+//
+// EvDhtRetriveReq
+//
+type MsgDhtRetriveReq struct {
+	Key		[]byte			// key for the chunk
+	Id		uint64			// identity of request
+	Ptn		interface{}		// task to confirm to, nil for the user of dht
+	Exclude	[]ycfg.NodeID	// peers not to be queried
+}
+
+//
+// Confirm for EvDhtRetriveReq, see MsgDhtStoreCfm pls.
+//
+type MsgDhtRetriveCfm struct {
+	Eno		int				// result, 0: ok, others: errno
+	Key		[]byte			// key for the chunk
+	Chunk	[]byte			// the chunk data
+	Id		uint64			// identity of request
+	Queried	[]ycfg.NodeID	// peers queried
+}
+
+//
+// EvDhtObjStoreReq
+//
+type MsgDhtObjStoreReq struct {
+	Data	[]byte		// the object data
+	Mode	int			// chunking mode
+	Id		uint64		// identity of request
+	Ptn		interface{}	// task to confirm to, nil for the user of dht
+}
+
+//
+// Confirm for EvDhtObjStoreReq, see MsgDhtStoreCfm pls.
+//
+type MsgDhtObjStoreCfm struct {
+	Eno		int			// result, 0: ok, others: errno
+	Key		[]byte		// key of the root block
+	Size	uint64		// size of the object
+	Id		uint64		// identity of request
+}
+
+//
+// EvDhtObjRetriveReq
+//
+type MsgDhtObjRetriveReq struct {
+	Key		[]byte		// key of the root block
+	Id		uint64		// identity of request
+	Ptn		interface{}	// task to confirm to, nil for the user of dht
+}
+
+//
+// Confirm for EvDhtObjRetriveReq, see MsgDhtStoreCfm pls.
+//
+type MsgDhtObjRetriveCfm struct {
+	Eno		int			// result, 0: ok, others: errno
+	Key		[]byte		// key of the root block
+	Data	[]byte		// the object data
+	Id		uint64		// identity of request
+}
 
 //
 // DHT peer lookup on Tcp event
 //
-const EvDhtPeerLkBase = 2000
+const DhtreQueryTimerId = 0
+const (
+	EvDhtPeerLkBase				= 2000
+	EvDhtreQueryTimer			= EvTimerBase		+ DhtreQueryTimerId
+	EvDhtPeerLkFindValueReq		= EvDhtPeerLkBase + 1
+	EvDhtPeerLkValueRsp			= EvDhtPeerLkBase + 2
+	EvDhtPeerLkFindNodeReq		= EvDhtPeerLkBase + 3
+	EvDhtPeerLkNeighborsRsp		= EvDhtPeerLkBase + 4
+	EvDhtPeerLkStoreReq			= EvDhtPeerLkBase + 5
+	EvDhtPeerLkStoreRsp			= EvDhtPeerLkBase + 6
+)
+
+//
+// EvDhtPeerLkFindValueReq
+//
+type MsgDhtPeerLkFindValueReq struct {
+	From		ycfg.NodeID		// where the request from
+	FindValue	*dm.FindValue	// the request
+}
+
+//
+// EvDhtPeerLkValueRsp
+//
+type MsgDhtPeerLkValueRsp struct {
+	From		ycfg.NodeID		// where the response from
+	Value		*dm.Value		// the response
+}
+
+//
+// EvDhtPeerLkFindNodeReq
+//
+type MsgDhtPeerLkFindNodeReq struct {
+	From		ycfg.NodeID		// where the request from
+	FindNode	*dm.FindNode	// the request
+}
+
+//
+// EvDhtPeerLkNeighborsRsp
+//
+type MsgDhtPeerLkNeighborsRsp struct {
+	From		ycfg.NodeID		// where the response from
+	Neighbors	*dm.Neighbors	// the response
+}
+
+//
+// EvDhtPeerLkStoreReq
+//
+type MsgDhtPeerLkStoreReq struct {
+	From		ycfg.NodeID		// where the request from
+	Store		*dm.Store		// the request
+}
+
+//
+// EvDhtPeerLkStoreRsp
+//
+type MsgDhtPeerLkStoreRsp struct {
+	From		ycfg.NodeID		// where the response from
+	StoreRsp	*dm.StoreRsp	// the response
+}
 
 //
 // DHT provider event
 //
-const EvDhtPrdBase = 2100
+const (
+	DhtPrdRepublishTimerId	= 0
+	DhtPrdCleanupTimerId	= 1
+	DhtPrdQueryTimerId		= 2
+)
+
+const (
+	EvDhtPrdBase				= 2100
+	EvDhtPrdRepublishTimer		= EvTimerBase	+ DhtPrdRepublishTimerId
+	EvDhtPrdCleanupTimer		= EvTimerBase	+ DhtPrdCleanupTimerId
+	EvDhtPrdQueryTimer			= EvTimerBase	+ DhtPrdQueryTimerId
+	EvDhtPrdGetProvidersReq		= EvDhtPrdBase + 1
+	EvDhtPrdProvidersRsp		= EvDhtPrdBase + 2
+	EvDhtPrdAddProviderReq		= EvDhtPrdBase + 3
+	EvDhtPrdProvideReq			= EvDhtPrdBase + 4
+	EvDhtPrdFindReq				= EvDhtPrdBase + 5
+	EvDhtPrdProvideCfm			= EvDhtPrdBase + 6
+	EvDhtPrdFindCfm				= EvDhtPrdBase + 7
+)
+
+//
+// EvDhtPrdGetProvidersReq
+//
+type MsgDhtPrdGetProvidersReq struct {
+	From			ycfg.NodeID			// where the request from
+	GetProviders	*dm.GetProviders	// the request
+}
+
+//
+// EvDhtPrdProvidersRsp
+//
+type MsgDhtPrdProvidersRsp struct {
+	From			ycfg.NodeID			// where the response from
+	Providers		*dm.Providers		// the response
+}
+
+//
+// EvDhtPrdAddProviderReq
+//
+type MsgDhtPrdAddProviderReq struct {
+	From			ycfg.NodeID			// where the request from
+	AddProvider		*dm.AddProvider		// the request
+}
+
+//
+// EvDhtPrdProvideReq: announce local node as a provider of key
+//
+type MsgDhtPrdProvideReq struct {
+	Key				[]byte				// key provided
+	Id				uint64				// identity of request
+	Ptn				interface{}			// task to confirm to, nil for the user of dht
+}
+
+//
+// Confirm for EvDhtPrdProvideReq, see MsgDhtStoreCfm pls.
+//
+type MsgDhtPrdProvideCfm struct {
+	Eno				int					// result, 0: ok, others: errno
+	Key				[]byte				// key provided
+	Peers			int					// number of peers published to
+	Id				uint64				// identity of request
+}
+
+//
+// EvDhtPrdFindReq: find providers of key
+//
+type MsgDhtPrdFindReq struct {
+	Key				[]byte				// key wanted
+	Id				uint64				// identity of request
+	Ptn				interface{}			// task to confirm to, nil for the user of dht
+}
+
+//
+// Confirm for EvDhtPrdFindReq, see MsgDhtStoreCfm pls.
+//
+type MsgDhtPrdFindCfm struct {
+	Eno				int					// result, 0: ok, others: errno
+	Key				[]byte				// key wanted
+	Providers		[]*ycfg.Node		// providers found
+	Id				uint64				// identity of request
+}
+
+//
+// DHT dispatcher timers
+//
+const DhtdiDeadlineTimerId = 0
+
+//
+// DHT dispatcher event
+//
+const (
+	EvDhtdiBase				= 2200
+	EvDhtdiDeadlineTimer	= EvTimerBase + DhtdiDeadlineTimerId
+	EvDhtdiReq				= EvDhtdiBase + 1
+)
+
+//
+// EvDhtdiReq: request from the user of dht, it's tracked by the dispatcher
+// and handed over to the dht task serving it.
+//
+type MsgDhtdiReq struct {
+	Req				interface{}			// one of MsgDhtXxxReq above, with Ptn nil
+	Timeout			time.Duration		// deadline of request, 0 for default
+}
+
+//
+// Confirm for EvDhtdiReq, it's not an event, see MsgDhtStoreCfm pls.
+//
+type MsgDhtdiCfm struct {
+	Eno				int					// result of dispatcher, 0: ok, others: errno
+	Id				uint64				// identity of request
+	Req				interface{}			// the request
+	Cfm				interface{}			// confirm from the task, nil if Eno is not 0
+}
+
+//
+// Gossip manager timers
+//
+const GspHeartbeatTimerId = 0
+
+//
+// Gossip manager event
+//
+const (
+	EvGspMgrBase			= 2300
+	EvGspHeartbeatTimer		= EvTimerBase + GspHeartbeatTimerId
+	EvGspMgrPkgInd			= EvGspMgrBase + 1
+	EvGspMgrPeerInd			= EvGspMgrBase + 2
+	EvGspSubscribeReq		= EvGspMgrBase + 3
+	EvGspUnsubscribeReq		= EvGspMgrBase + 4
+	EvGspPublishReq			= EvGspMgrBase + 5
+)
+
+//
+// EvGspMgrPkgInd: package of PID_GOSSIP received from peer
+//
+type MsgGspPkgInd struct {
+	From			ycfg.NodeID			// where the package from
+	Payload			[]byte				// payload of package
+}
+
+//
+// EvGspMgrPeerInd: peer activated or closed, sent by peer manager
+//
+type MsgGspPeerInd struct {
+	Ind				int					// peer.P2pIndPeerActivated or peer.P2pIndPeerClosed
+	Node			ycfg.Node			// peer node
+}
+
+//
+// EvGspSubscribeReq
+//
+type MsgGspSubscribeReq struct {
+	Topic			string				// topic
+	Handler			func(topic string, from ycfg.NodeID, data []byte)	// handler for messages of topic
+}
+
+//
+// EvGspUnsubscribeReq
+//
+type MsgGspUnsubscribeReq struct {
+	Topic			string				// topic
+}
+
+//
+// EvGspPublishReq
+//
+type MsgGspPublishReq struct {
+	Topic			string				// topic
+	Data			[]byte				// data to publish
+}
+
+//
+// RPC manager timers
+//
+const RpcDeadlineTimerId = 0
+
+//
+// RPC manager event
+//
+const (
+	EvRpcMgrBase			= 2400
+	EvRpcDeadlineTimer		= EvTimerBase + RpcDeadlineTimerId
+	EvRpcMgrPkgInd			= EvRpcMgrBase + 1
+	EvRpcMgrPeerInd			= EvRpcMgrBase + 2
+	EvRpcRegisterReq		= EvRpcMgrBase + 3
+	EvRpcCallReq			= EvRpcMgrBase + 4
+	EvRpcCancelReq			= EvRpcMgrBase + 5
+	EvRpcReplyReq			= EvRpcMgrBase + 6
+)
+
+//
+// EvRpcMgrPkgInd: package of PID_RPC received from peer
+//
+type MsgRpcPkgInd struct {
+	From			ycfg.NodeID			// where the package from
+	Payload			[]byte				// payload of package
+}
+
+//
+// EvRpcMgrPeerInd: peer activated or closed, sent by peer manager
+//
+type MsgRpcPeerInd struct {
+	Ind				int					// peer.P2pIndPeerActivated or peer.P2pIndPeerClosed
+	Node			ycfg.Node			// peer node
+}
+
+//
+// EvRpcRegisterReq: register handler for a method, nil handler removes it.
+// The handler is called in a routine of its' own for each request, done is
+// closed when the caller is not interested in the result any more: request
+// cancelled, deadline expired or the peer closed.
+//
+type MsgRpcRegisterReq struct {
+	Method			string				// method
+	Handler			func(from ycfg.NodeID, method string, req []byte, done <-chan struct{}) ([]byte, error)
+}
+
+//
+// EvRpcCallReq: call a method of peer
+//
+type MsgRpcCallReq struct {
+	Id				uint64				// identity of call, unique in a p2p instance
+	To				ycfg.NodeID			// peer called
+	Method			string				// method
+	Payload			[]byte				// request payload
+	Timeout			time.Duration		// deadline of call, 0 for default
+	Cb				func(cfm *MsgRpcCallCfm)	// callback for result, called in rpc task
+}
+
+//
+// Confirm for EvRpcCallReq, it's not an event but passed to MsgRpcCallReq.Cb
+// exactly once.
+//
+type MsgRpcCallCfm struct {
+	Eno				int					// result, 0: ok, others: errno
+	Error			string				// error description from remote
+	Id				uint64				// identity of call
+	Payload			[]byte				// response payload
+}
+
+//
+// EvRpcCancelReq: cancel a call
+//
+type MsgRpcCancelReq struct {
+	Id				uint64				// identity of call
+}
+
+//
+// EvRpcReplyReq: result from handler of a method, sent by the routine the
+// handler called in.
+//
+type MsgRpcReplyReq struct {
+	To				ycfg.NodeID			// the caller
+	Id				uint64				// identity of call
+	Payload			[]byte				// response payload
+	Err				error				// error from handler
+}
+
+//
+// Stream manager event
+//
+const (
+	EvStrMgrBase			= 2500
+	EvStrMgrPkgInd			= EvStrMgrBase + 1
+	EvStrMgrPeerInd			= EvStrMgrBase + 2
+)
+
+//
+// EvStrMgrPkgInd: package of PID_STREAM received from peer
+//
+type MsgStrPkgInd struct {
+	From			ycfg.NodeID			// where the package from
+	Payload			[]byte				// payload of package
+}
+
+//
+// EvStrMgrPeerInd: peer activated or closed, sent by peer manager
+//
+type MsgStrPeerInd struct {
+	Ind				int					// peer.P2pIndPeerActivated or peer.P2pIndPeerClosed
+	Node			ycfg.Node			// peer node
+}
+
+//
+// Deny and allow lists manager timers
+//
+const AclPurgeTimerId = 0
+
+//
+// Deny and allow lists manager event
+//
+const (
+	EvAclMgrBase			= 2600
+	EvAclPurgeTimer			= EvTimerBase + AclPurgeTimerId
+)
@@ -25,16 +25,10 @@ import (
 	"time"
 	"strings"
 	golog	"log"
+	ycfg	"github.com/yeeco/p2p/config"
 	yclog	"github.com/yeeco/p2p/logger"
 )
 
-//
-// The scheduler for p2p module, and its' pointer. Notice: we do not plan to
-// export any scheduler logic to other modules in any system, so we prefert
-// a static var here than creating a shceduler objcet on demand, see it pls.
-//
-var p2pSDL = scheduler{}
-
 //
 // Default task node for shceduler to send event
 //
@@ -58,46 +52,62 @@ var rawTmTsk = schTaskNode {
 }
 
 //
-// Scheduler initilization
+// Scheduler initilization: a new scheduler object is created and returned,
+// each p2p instance should own one.
 //
-func schimplSchedulerInit() SchErrno {
+func schimplSchedulerInit(cfg *ycfg.Config) (SchErrno, *scheduler) {
+
+	var sdl = new(scheduler)
+
+	sdl.p2pCfg = cfg
 
 	//
 	// make maps
 	//
 
-	p2pSDL.tkMap = make(map[schTaskName] *schTaskNode)
-	p2pSDL.tmMap = make(map[*schTmcbNode] *schTaskNode)
-	p2pSDL.grpMap = make(map[schTaskGroupName][]*schTaskNode)
+	sdl.tkMap = make(map[schTaskName] *schTaskNode)
+	sdl.tmMap = make(map[*schTmcbNode] *schTaskNode)
+	sdl.grpMap = make(map[schTaskGroupName][]*schTaskNode)
 
 	//
 	// setup free task node queue
 	//
 
 	for loop := 0; loop < schTaskNodePoolSize; loop++ {
-		schTaskNodePool[loop].last = &schTaskNodePool[(loop - 1 + schTaskNodePoolSize) & (schTaskNodePoolSize - 1)]
-		schTaskNodePool[loop].next = &schTaskNodePool[(loop + 1) & (schTaskNodePoolSize - 1)]
-		schTaskNodePool[loop].task.tmIdxTab = make(map[*schTmcbNode] int)
+		sdl.tkPool[loop].last = &sdl.tkPool[(loop - 1 + schTaskNodePoolSize) & (schTaskNodePoolSize - 1)]
+		sdl.tkPool[loop].next = &sdl.tkPool[(loop + 1) & (schTaskNodePoolSize - 1)]
+		sdl.tkPool[loop].task.tmIdxTab = make(map[*schTmcbNode] int)
+		sdl.tkPool[loop].task.sdl = sdl
 	}
 
-	p2pSDL.freeSize = schTaskNodePoolSize
-	p2pSDL.tkFree = &schTaskNodePool[0]
+	sdl.freeSize = schTaskNodePoolSize
+	sdl.tkFree = &sdl.tkPool[0]
 
 	//
 	// setup free timer node queue
 	//
 
 	for loop := 0; loop < schTimerNodePoolSize; loop++ {
-		schTimerNodePool[loop].last = &schTimerNodePool[(loop - 1 + schTimerNodePoolSize) & (schTimerNodePoolSize - 1)]
-		schTimerNodePool[loop].next = &schTimerNodePool[(loop + 1) & (schTimerNodePoolSize - 1)]
-		schTimerNodePool[loop].tmcb.stop = make(chan bool, 1)
-		schTimerNodePool[loop].tmcb.stopped = make(chan bool)
+		sdl.tmPool[loop].last = &sdl.tmPool[(loop - 1 + schTimerNodePoolSize) & (schTimerNodePoolSize - 1)]
+		sdl.tmPool[loop].next = &sdl.tmPool[(loop + 1) & (schTimerNodePoolSize - 1)]
+		sdl.tmPool[loop].tmcb.stop = make(chan bool, 1)
+		sdl.tmPool[loop].tmcb.stopped = make(chan bool)
+		sdl.tmPool[loop].tmcb.sdl = sdl
 	}
 
-	p2pSDL.tmFreeSize = schTimerNodePoolSize
-	p2pSDL.tmFree = &schTimerNodePool[0]
+	sdl.tmFreeSize = schTimerNodePoolSize
+	sdl.tmFree = &sdl.tmPool[0]
 
-	return SchEnoNone
+	//
+	// setup the watchdog
+	//
+
+	sdl.wdCB = watchDogCtrlBlock {
+		scb:		sdl,
+		dogKill:	make(chan SchErrno, 1),
+	}
+
+	return SchEnoNone, sdl
 }
 
 //
@@ -229,6 +239,8 @@ func schimplTimerCommonTask(ptm *schTmcbNode) SchErrno {
 	var tid = ptm.tmcb.taskNode.task.tmIdxTab[ptm]
 	ptm.tmcb.taskNode.task.lock.Unlock()
 
+	var sdl = ptm.tmcb.sdl
+
 	var killed = false
 
 	//
@@ -244,7 +256,7 @@ func schimplTimerCommonTask(ptm *schTmcbNode) SchErrno {
 		//
 
 		delete(tn.tmcb.taskNode.task.tmIdxTab, tn)
-		delete(p2pSDL.tmMap, tn)
+		delete(sdl.tmMap, tn)
 		tn.tmcb.taskNode.task.tmTab[tid] = nil
 
 		tn.tmcb.name	= ""
@@ -365,7 +377,7 @@ absTimerLoop:
 				//
 
 				ptm.tmcb.taskNode.task.lock.Lock()
-				p2pSDL.lock.Lock()
+				sdl.lock.Lock()
 
 				if eno := schimplSendTimerEvent(ptm); eno != SchEnoNone {
 
@@ -376,14 +388,14 @@ absTimerLoop:
 				}
 
 				funcAbsTimerClean(ptm)
-				p2pSDL.lock.Unlock()
+				sdl.lock.Unlock()
 
 				break absTimerLoop
 
 			case stop := <-ptm.tmcb.stop:
 
 				ptm.tmcb.taskNode.task.lock.Lock()
-				p2pSDL.lock.Lock()
+				sdl.lock.Lock()
 
 				if stop == true {
 
@@ -391,7 +403,7 @@ absTimerLoop:
 					killed = true
 				}
 
-				p2pSDL.lock.Unlock()
+				sdl.lock.Unlock()
 
 				break absTimerLoop
 			}
@@ -443,18 +455,18 @@ absTimerLoop:
 //
 // Get timer node
 //
-func schimplGetTimerNode() (SchErrno, *schTmcbNode) {
+func schimplGetTimerNode(sdl *scheduler) (SchErrno, *schTmcbNode) {
 
 	var tmn *schTmcbNode = nil
 
-	p2pSDL.lock.Lock()
-	defer p2pSDL.lock.Unlock()
+	sdl.lock.Lock()
+	defer sdl.lock.Unlock()
 
 	//
 	// if empty
 	//
 
-	if p2pSDL.tmFree == nil {
+	if sdl.tmFree == nil {
 		yclog.LogCallerFileLine("schimplGetTimerNode: free queue is empty")
 		return SchEnoResource, nil
 	}
@@ -463,29 +475,29 @@ func schimplGetTimerNode() (SchErrno, *schTmcbNode) {
 	// dequeue one node
 	//
 
-	tmn = p2pSDL.tmFree
+	tmn = sdl.tmFree
 
 	if tmn.last == tmn && tmn.next == tmn {
 
-		if p2pSDL.tmFreeSize - 1 != 0 {
+		if sdl.tmFreeSize - 1 != 0 {
 
 			yclog.LogCallerFileLine("schimplGetTimerNode: " +
 				"internal errors, should be 0, but free size: %d",
-				p2pSDL.tmFreeSize)
+				sdl.tmFreeSize)
 
 			return SchEnoInternal, nil
 		}
 
-		p2pSDL.tmFreeSize--
-		p2pSDL.tmFree = nil
+		sdl.tmFreeSize--
+		sdl.tmFree = nil
 
 	} else {
 
-		if p2pSDL.tmFreeSize - 1 <= 0 {
+		if sdl.tmFreeSize - 1 <= 0 {
 
 			yclog.LogCallerFileLine("schimplGetTimerNode: " +
 				"internal errors, should equal or less than 0, but free size: %d",
-				p2pSDL.tmFreeSize)
+				sdl.tmFreeSize)
 
 			return SchEnoInternal, nil
 		}
@@ -495,8 +507,8 @@ func schimplGetTimerNode() (SchErrno, *schTmcbNode) {
 		next.last = last
 		last.next = next
 
-		p2pSDL.tmFree = next
-		p2pSDL.tmFreeSize--
+		sdl.tmFree = next
+		sdl.tmFreeSize--
 	}
 
 	tmn.next = tmn
@@ -515,30 +527,32 @@ func schimplRetTimerNode(ptm *schTmcbNode) SchErrno {
 		return SchEnoParameter
 	}
 
-	p2pSDL.lock.Lock()
-	defer  p2pSDL.lock.Unlock()
+	var sdl = ptm.tmcb.sdl
+
+	sdl.lock.Lock()
+	defer  sdl.lock.Unlock()
 
 	//
 	// enqueue a node
 	//
 
-	if p2pSDL.tmFree == nil {
+	if sdl.tmFree == nil {
 
 		ptm.last = ptm
 		ptm.next = ptm
 
 	} else {
 
-		last := p2pSDL.tmFree.last
+		last := sdl.tmFree.last
 		ptm.last = last
 		last.next = ptm
-		ptm.next = p2pSDL.tmFree
-		p2pSDL.tmFree.last = ptm
+		ptm.next = sdl.tmFree
+		sdl.tmFree.last = ptm
 
 	}
 
-	p2pSDL.tmFree = ptm
-	p2pSDL.tmFreeSize++
+	sdl.tmFree = ptm
+	sdl.tmFreeSize++
 
 	return SchEnoNone
 }
@@ -546,18 +560,18 @@ func schimplRetTimerNode(ptm *schTmcbNode) SchErrno {
 //
 // Get task node
 //
-func schimplGetTaskNode() (SchErrno, *schTaskNode) {
+func schimplGetTaskNode(sdl *scheduler) (SchErrno, *schTaskNode) {
 
 	var tkn *schTaskNode = nil
 
-	p2pSDL.lock.Lock()
-	defer p2pSDL.lock.Unlock()
+	sdl.lock.Lock()
+	defer sdl.lock.Unlock()
 
 	//
 	// if free node queue empty
 	//
 
-	if p2pSDL.tkFree== nil {
+	if sdl.tkFree== nil {
 		yclog.LogCallerFileLine("schimplGetTaskNode: free queue is empty")
 		return SchEnoResource, nil
 	}
@@ -566,13 +580,13 @@ func schimplGetTaskNode() (SchErrno, *schTaskNode) {
 	// dequeue one node
 	//
 
-	tkn = p2pSDL.tkFree
+	tkn = sdl.tkFree
 
 	if tkn.last == tkn && tkn.next == tkn {
 
-		p2pSDL.tkFree = nil
+		sdl.tkFree = nil
 
-		if p2pSDL.freeSize--; p2pSDL.freeSize != 0 {
+		if sdl.freeSize--; sdl.freeSize != 0 {
 
 			yclog.LogCallerFileLine("schimplGetTaskNode: internal errors")
 
@@ -585,9 +599,9 @@ func schimplGetTaskNode() (SchErrno, *schTaskNode) {
 		next := tkn.next
 		next.last = last
 		last.next = next
-		p2pSDL.tkFree = next
+		sdl.tkFree = next
 
-		if p2pSDL.freeSize--; p2pSDL.freeSize <= 0 {
+		if sdl.freeSize--; sdl.freeSize <= 0 {
 
 			yclog.LogCallerFileLine("schimplGetTaskNode: internal errors")
 			return SchEnoInternal, nil
@@ -610,30 +624,32 @@ func schimplRetTaskNode(ptn *schTaskNode) SchErrno {
 		return SchEnoParameter
 	}
 
-	p2pSDL.lock.Lock()
-	defer  p2pSDL.lock.Unlock()
+	var sdl = ptn.task.sdl
+
+	sdl.lock.Lock()
+	defer  sdl.lock.Unlock()
 
 	//
 	// enqueue a node
 	//
 
-	if p2pSDL.tkFree == nil {
+	if sdl.tkFree == nil {
 
 		ptn.last = ptn
 		ptn.next = ptn
 
 	} else {
 
-		last := p2pSDL.tkFree.last
+		last := sdl.tkFree.last
 		ptn.last = last
 		last.next = ptn
-		ptn.next = p2pSDL.tkFree
-		p2pSDL.tkFree.last = ptn
+		ptn.next = sdl.tkFree
+		sdl.tkFree.last = ptn
 
 	}
 
-	p2pSDL.tkFree = ptn
-	p2pSDL.freeSize++
+	sdl.tkFree = ptn
+	sdl.freeSize++
 
 	return SchEnoNone
 }
@@ -652,30 +668,32 @@ func schimplTaskBusyEnque(ptn *schTaskNode) SchErrno {
 	// lock/unlock scheduler control block
 	//
 
-	p2pSDL.lock.Lock()
-	defer  p2pSDL.lock.Unlock()
+	var sdl = ptn.task.sdl
+
+	sdl.lock.Lock()
+	defer  sdl.lock.Unlock()
 
 	//
 	// enqueue a node
 	//
 
-	if p2pSDL.tkBusy == nil {
+	if sdl.tkBusy == nil {
 
 		ptn.last = ptn
 		ptn.next = ptn
 
 	} else {
 
-		last := p2pSDL.tkBusy.last
+		last := sdl.tkBusy.last
 		ptn.last = last
 		last.next = ptn
-		ptn.next = p2pSDL.tkBusy
-		p2pSDL.tkBusy.last = ptn
+		ptn.next = sdl.tkBusy
+		sdl.tkBusy.last = ptn
 
 	}
 
-	p2pSDL.tkBusy = ptn
-	p2pSDL.busySize++
+	sdl.tkBusy = ptn
+	sdl.busySize++
 
 	return SchEnoNone
 }
@@ -694,38 +712,40 @@ func schimplTaskBusyDeque(ptn *schTaskNode) SchErrno {
 	// lock/unlock schduler control block
 	//
 
-	p2pSDL.lock.Lock()
-	defer p2pSDL.lock.Unlock()
+	var sdl = ptn.task.sdl
+
+	sdl.lock.Lock()
+	defer sdl.lock.Unlock()
 
 	//
 	// remove the busy node
 	//
 
-	if p2pSDL.busySize <= 0 {
+	if sdl.busySize <= 0 {
 
 		yclog.LogCallerFileLine("schimplTaskBusyDeque: invalid parameter")
 		return SchEnoInternal
 
-	} else if p2pSDL.busySize == 1 {
+	} else if sdl.busySize == 1 {
 
-		if p2pSDL.tkBusy != ptn {
+		if sdl.tkBusy != ptn {
 
 			yclog.LogCallerFileLine("schimplTaskBusyDeque: invalid parameter")
 			return SchEnoInternal
 
 		} else {
 
-			p2pSDL.tkBusy = nil
-			p2pSDL.busySize = 0
+			sdl.tkBusy = nil
+			sdl.busySize = 0
 			return SchEnoNone
 		}
 	}
 
 	if ptn.last == ptn && ptn.next == ptn {
 
-		if ptn == p2pSDL.tkBusy {
+		if ptn == sdl.tkBusy {
 
-			p2pSDL.tkBusy = nil
+			sdl.tkBusy = nil
 
 		} else {
 
@@ -733,7 +753,7 @@ func schimplTaskBusyDeque(ptn *schTaskNode) SchErrno {
 
 			return SchEnoInternal
 		}
-		if p2pSDL.busySize--; p2pSDL.busySize != 0 {
+		if sdl.busySize--; sdl.busySize != 0 {
 
 			yclog.LogCallerFileLine("schimplTaskBusyDeque: internal errors")
 
@@ -746,12 +766,12 @@ func schimplTaskBusyDeque(ptn *schTaskNode) SchErrno {
 		last.next = next
 		next.last = last
 
-		if p2pSDL.tkBusy == ptn {
+		if sdl.tkBusy == ptn {
 
-			p2pSDL.tkBusy = next
+			sdl.tkBusy = next
 		}
 
-		if p2pSDL.busySize--; p2pSDL.busySize <= 0 {
+		if sdl.busySize--; sdl.busySize <= 0 {
 
 			yclog.LogCallerFileLine("schimplTaskBusyDeque: internal errors")
 
@@ -789,9 +809,14 @@ func schimplSendTimerEvent(ptm *schTmcbNode) SchErrno {
 	msg.Id = EvTimerBase + ptm.tmcb.utid
 
 	//
-	// put message to task mailbox
+	// put message to task mailbox, the task lock is held by caller, so the
+	// task could not be cleaned while we are sending.
 	//
 
+	if task.stopping {
+		return SchEnoNotFound
+	}
+
 	*task.mailbox.que<-msg
 
 	return SchEnoNone
@@ -802,7 +827,7 @@ func schimplSendTimerEvent(ptm *schTmcbNode) SchErrno {
 //
 type schTaskDescription SchTaskDescription
 
-func schimplCreateTask(taskDesc *schTaskDescription) (SchErrno, interface{}){
+func schimplCreateTask(sdl *scheduler, taskDesc *schTaskDescription) (SchErrno, interface{}){
 
 	var eno SchErrno
 	var ptn *schTaskNode
@@ -816,7 +841,7 @@ func schimplCreateTask(taskDesc *schTaskDescription) (SchErrno, interface{}){
 	// get task node
 	//
 
-	if eno, ptn = schimplGetTaskNode(); eno != SchEnoNone || ptn == nil {
+	if eno, ptn = schimplGetTaskNode(sdl); eno != SchEnoNone || ptn == nil {
 
 		yclog.LogCallerFileLine("schimplCreateTask: " +
 			"schimplGetTaskNode failed, eno: %d",
@@ -863,6 +888,8 @@ func schimplCreateTask(taskDesc *schTaskDescription) (SchErrno, interface{}){
 	ptn.task.mailbox.size	= taskDesc.MbSize
 	ptn.task.done			= make(chan SchErrno, 1)
 	ptn.task.stopped		= make(chan bool, 1)
+	ptn.task.stopping		= false
+	ptn.task.quit			= make(chan struct{})
 	ptn.task.dog			= schWatchDog(*taskDesc.Wd)
 	ptn.task.dieCb			= taskDesc.DieCb
 	ptn.task.userData		= taskDesc.UserDa
@@ -894,28 +921,28 @@ func schimplCreateTask(taskDesc *schTaskDescription) (SchErrno, interface{}){
 	// table, and this task could not be found by function schimplGetTaskNodeByName
 	//
 
-	p2pSDL.lock.Lock()
+	sdl.lock.Lock()
 
 	if len(ptn.task.name) <= 0 {
 
 		yclog.LogCallerFileLine("schimplCreateTask: task with empty name")
 
-	} else if _, dup := p2pSDL.tkMap[schTaskName(ptn.task.name)]; dup == true {
+	} else if _, dup := sdl.tkMap[schTaskName(ptn.task.name)]; dup == true {
 
 		yclog.LogCallerFileLine("schimplCreateTask: " +
 			"duplicated task name: %s",
 			ptn.task.name)
 
-		p2pSDL.lock.Unlock()
+		sdl.lock.Unlock()
 
 		return SchEnoDuplicated, nil
 
 	} else {
 
-		p2pSDL.tkMap[schTaskName(ptn.task.name)] = ptn
+		sdl.tkMap[schTaskName(ptn.task.name)] = ptn
 	}
 
-	p2pSDL.lock.Unlock()
+	sdl.lock.Unlock()
 
 	//
 	// put task node to busy queue
@@ -962,7 +989,7 @@ func schimplCreateTask(taskDesc *schTaskDescription) (SchErrno, interface{}){
 //
 // Start a single task by task name
 //
-func schimplStartTask(name string) SchErrno {
+func schimplStartTask(sdl *scheduler, name string) SchErrno {
 
 	//
 	// Notice: only those suspended user task can be started, so this function does
@@ -974,7 +1001,7 @@ func schimplStartTask(name string) SchErrno {
 	// get task node pointer by name
 	//
 
-	eno, ptn := schimplGetTaskNodeByName(name)
+	eno, ptn := schimplGetTaskNodeByName(sdl, name)
 
 	if eno != SchEnoNone || ptn == nil {
 
@@ -1048,7 +1075,7 @@ func schimplStartTaskEx(ptn *schTaskNode) SchErrno {
 //
 // Stop a single task by task name
 //
-func schimplStopTask(name string) SchErrno {
+func schimplStopTask(sdl *scheduler, name string) SchErrno {
 
 	//
 	// Attention: this function MUST only be called to kill a task than the
@@ -1059,7 +1086,7 @@ func schimplStopTask(name string) SchErrno {
 	// get task node pointer by name
 	//
 
-	eno, ptn := schimplGetTaskNodeByName(name)
+	eno, ptn := schimplGetTaskNodeByName(sdl, name)
 
 	if eno != SchEnoNone || ptn == nil {
 
@@ -1098,6 +1125,13 @@ func schimplStopTaskEx(ptn *schTaskNode) SchErrno {
 		return SchEnoParameter
 	}
 
+	//
+	// backup the name, it's cleaned in schimplTcbClean but we need it to
+	// remove the task from the name map
+	//
+
+	var name = ptn.task.name
+
 	//
 	// dequeue form busy queue
 	//
@@ -1167,7 +1201,7 @@ func schimplStopTaskEx(ptn *schTaskNode) SchErrno {
 
 		yclog.LogCallerFileLine("schimplStopTaskEx: " +
 			"schimplRetTimerNode failed, task: %s, eno: %d",
-			ptn.task.name,
+			name,
 			eno)
 
 		return  eno
@@ -1177,11 +1211,11 @@ func schimplStopTaskEx(ptn *schTaskNode) SchErrno {
 	// remove name to task node pointer map
 	//
 
-	if len(ptn.task.name) > 0 {
+	if len(name) > 0 {
 
-		p2pSDL.lock.Lock()
-		delete(p2pSDL.tkMap, schTaskName(ptn.task.name))
-		p2pSDL.lock.Unlock()
+		ptn.task.sdl.lock.Lock()
+		delete(ptn.task.sdl.tkMap, schTaskName(name))
+		ptn.task.sdl.lock.Unlock()
 	}
 
 	yclog.LogCallerFileLine("schimplStopTaskEx: task stopped, it's cleaned ok")
@@ -1209,6 +1243,15 @@ func schimplTcbClean(tcb *schTask) SchErrno {
 	tcb.dog.DieThreshold	= SchDefaultDogDieThresold
 	tcb.dieCb				= nil
 	tcb.goStatus			= SchCreatedSuspend
+	schimplTaskStopping(tcb)
+
+	//
+	// senders blocked on the mailbox are waked up by the "quit" closed, wait
+	// them to go away before the mailbox is closed. they never take the lock
+	// once blocked, so it's safe to wait with the lock held.
+	//
+
+	tcb.senders.Wait()
 
 	close(*tcb.mailbox.que)
 	tcb.mailbox.que = nil
@@ -1224,7 +1267,7 @@ func schimplTcbClean(tcb *schTask) SchErrno {
 		if tcb.tmTab[loop] != nil {
 
 			delete(tcb.tmIdxTab, tcb.tmTab[loop])
-			delete(p2pSDL.tmMap, tcb.tmTab[loop])
+			delete(tcb.sdl.tmMap, tcb.tmTab[loop])
 
 			tcb.tmTab[loop] = nil
 		}
@@ -1236,19 +1279,19 @@ func schimplTcbClean(tcb *schTask) SchErrno {
 //
 // Delete a single task by task name
 //
-func schimplDeleteTask(name string) SchErrno {
+func schimplDeleteTask(sdl *scheduler, name string) SchErrno {
 
 	//
 	// Currently, "Delete" implented as "Stop"
 	//
 
-	return schimplStopTask(name)
+	return schimplStopTask(sdl, name)
 }
 
 //
 // Send message to a specific task
 //
-func schimplSendMsg(msg *schMessage) (eno SchErrno) {
+func schimplSendMsg(msg *schMessage) SchErrno {
 
 	//
 	// check the message to be sent
@@ -1276,20 +1319,78 @@ func schimplSendMsg(msg *schMessage) (eno SchErrno) {
 	// and so on.
 	//
 
-	if msg.recver.task.mailbox.que == nil {
+	//
+	// the target might be done and its' mailbox closed while we are sending,
+	// it's possible when the scheduler is stopping, see schimplSchedulerStop,
+	// so the "stopping" flag is checked with the target locked. the lock is
+	// not held while the mailbox is full, since the target might need it to
+	// handle messages, to set a timer for example; instead, we are counted
+	// in "senders" and block till the message is taken or the target quits,
+	// the mailbox would not be closed before we have gone, see function
+	// schimplTcbClean.
+	//
+
+	var task = &msg.recver.task
+
+	task.lock.Lock()
+
+	if task.stopping {
+		task.lock.Unlock()
+		yclog.LogCallerFileLine("schimplSendMsg: target had been done")
+		return SchEnoNotFound
+	}
+
+	if task.mailbox.que == nil {
+		task.lock.Unlock()
 		yclog.LogCallerFileLine("schimplSendMsg: mailbox of target is empty")
 		return SchEnoInternal
 	}
 
-	*msg.recver.task.mailbox.que<-*msg
+	var que = *task.mailbox.que
+	var quit = task.quit
 
-	return SchEnoNone
+	select {
+	case que<-*msg:
+		task.lock.Unlock()
+		return SchEnoNone
+	default:
+	}
+
+	task.senders.Add(1)
+	task.lock.Unlock()
+
+	defer task.senders.Done()
+
+	select {
+	case que<-*msg:
+		return SchEnoNone
+	case <-quit:
+		yclog.LogCallerFileLine("schimplSendMsg: target done while mailbox full")
+		return SchEnoNotFound
+	}
+}
+
+//
+// Mark task stopping, lock should be held by caller. No more messages are
+// accepted since then, and senders blocked on the mailbox are waked up.
+//
+func schimplTaskStopping(task *schTask) {
+
+	if task.stopping {
+		return
+	}
+
+	task.stopping = true
+
+	if task.quit != nil {
+		close(task.quit)
+	}
 }
 
 //
 // Send message to a specific task group
 //
-func schimplSendMsg2TaskGroup(grp string, msg *schMessage) (SchErrno, int) {
+func schimplSendMsg2TaskGroup(sdl *scheduler, grp string, msg *schMessage) (SchErrno, int) {
 
 	var mtl []*schTaskNode = nil
 	var found bool
@@ -1308,7 +1409,7 @@ func schimplSendMsg2TaskGroup(grp string, msg *schMessage) (SchErrno, int) {
 	// check group
 	//
 
-	if mtl, found = p2pSDL.grpMap[schTaskGroupName(grp)]; found != true {
+	if mtl, found = sdl.grpMap[schTaskGroupName(grp)]; found != true {
 
 		yclog.LogCallerFileLine("schimplSendMsg2TaskGroup: " +
 			"not exist, group: %s",
@@ -1386,7 +1487,7 @@ func schimplSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchErrno, int) {
 	// get a timer node
 	//
 
-	if eno, ptm = schimplGetTimerNode(); eno != SchEnoNone || ptm == nil {
+	if eno, ptm = schimplGetTimerNode(ptn.task.sdl); eno != SchEnoNone || ptm == nil {
 
 		yclog.LogCallerFileLine("schimplSetTimer: " +
 			"schimplGetTimerNode failed, eno: %d",
@@ -1405,9 +1506,9 @@ func schimplSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchErrno, int) {
 	ptn.task.tmTab[tid] = ptm
 	ptn.task.tmIdxTab[ptm] = tid
 
-	p2pSDL.lock.Lock()
-	p2pSDL.tmMap[ptm] = ptn
-	p2pSDL.lock.Unlock()
+	ptn.task.sdl.lock.Lock()
+	ptn.task.sdl.tmMap[ptm] = ptn
+	ptn.task.sdl.lock.Unlock()
 
 	//
 	// setup timer control block
@@ -1500,14 +1601,18 @@ func schimplKillTaskTimers(task *schTask) SchErrno {
 		return SchEnoParameter
 	}
 
+	//
+	// Notice: we can't wait the "stopped" signal with the task locked, since
+	// the timer task would obtain the lock to clean itself before it fires
+	// the signal, see function schimplTimerCommonTask. So we snapshot the
+	// timers, and then kill them one by one as schimplKillTimer does.
+	//
+
 	task.lock.Lock()
-	defer task.lock.Unlock()
 
-	for tm, idx := range task.tmIdxTab {
+	var tms = make([]*schTmcbNode, 0, len(task.tmIdxTab))
 
-		//
-		// check
-		//
+	for tm, idx := range task.tmIdxTab {
 
 		if tm != task.tmTab[idx] {
 
@@ -1517,24 +1622,55 @@ func schimplKillTaskTimers(task *schTask) SchErrno {
 				idx,
 				task.tmTab[idx])
 
+			task.lock.Unlock()
+
 			return SchEnoInternal
 		}
 
+		tms = append(tms, tm)
+	}
+
+	task.lock.Unlock()
+
+	for _, tm := range tms {
+
 		//
-		// done the timer task
+		// the timer might have expired(absolute one) or been killed after the
+		// snapshot, check it again with the task locked.
 		//
 
+		task.lock.Lock()
+
+		if _, ok := task.tmIdxTab[tm]; !ok {
+			task.lock.Unlock()
+			continue
+		}
+
+		var stopped = tm.tmcb.stopped
 		tm.tmcb.stop<-true
 
+		task.lock.Unlock()
+
 		//
 		// wait until done
 		//
 
-		if stopped := <-tm.tmcb.stopped; stopped != true {
+		select {
+
+		case ok := <-stopped:
+
+			if ok != true {
+
+				yclog.LogCallerFileLine("schimplKillTaskTimers: "+
+					"timer stopped with: %t",
+					ok)
+			}
+
+		case <-time.After(schTaskStopTimeout):
 
-			yclog.LogCallerFileLine("schimplKillTaskTimers: "+
-				"timer stopped with: %t",
-				stopped)
+			yclog.LogCallerFileLine("schimplKillTaskTimers: " +
+				"timeout, task: %s",
+				task.name)
 		}
 	}
 
@@ -1545,15 +1681,18 @@ func schimplKillTaskTimers(task *schTask) SchErrno {
 //
 // Get task node pointer by task name
 //
-func schimplGetTaskNodeByName(name string) (SchErrno, *schTaskNode) {
+func schimplGetTaskNodeByName(sdl *scheduler, name string) (SchErrno, *schTaskNode) {
+
+	sdl.lock.Lock()
+	defer sdl.lock.Unlock()
 
 	// if exist
-	if _, err := p2pSDL.tkMap[schTaskName(name)]; !err {
+	if _, err := sdl.tkMap[schTaskName(name)]; !err {
 		return SchEnoNotFound, nil
 	}
 
 	// yes
-	return SchEnoNone, p2pSDL.tkMap[schTaskName(name)]
+	return SchEnoNone, sdl.tkMap[schTaskName(name)]
 }
 
 //
@@ -1585,10 +1724,27 @@ func schimplTaskDone(ptn *schTaskNode, eno SchErrno) SchErrno {
 	}
 
 	//
-	// see function schimplCommonTask for more please
+	// see function schimplCommonTask for more please. the task might have been
+	// done by another one, say, it's killed by the scheduler while stopping,
+	// see function schimplSchedulerStop, in this case the "stopping" flag
+	// is set, we need not to fire it again.
 	//
 
-	ptn.task.done<-eno
+	ptn.task.lock.Lock()
+	defer ptn.task.lock.Unlock()
+
+	if ptn.task.stopping || ptn.task.done == nil {
+		yclog.LogCallerFileLine("schimplTaskDone: task had been done, name: %s", tskName)
+		return SchEnoNotFound
+	}
+
+	schimplTaskStopping(&ptn.task)
+
+	select {
+	case ptn.task.done<-eno:
+	default:
+		yclog.LogCallerFileLine("schimplTaskDone: done had been fired, name: %s", tskName)
+	}
 
 	//
 	// when coming here, it just the "done" fired, it's still not killed really,
@@ -1643,7 +1799,7 @@ func schimplGetTaskName(ptn *schTaskNode) string {
 //
 // Start scheduler
 //
-func schimplSchedulerStart(tsd []TaskStaticDescription, tpo []string) (eno SchErrno, name2Ptn *map[string]interface{}){
+func schimplSchedulerStart(sdl *scheduler, tsd []TaskStaticDescription, tpo []string) (eno SchErrno, name2Ptn *map[string]interface{}){
 
 	golog.Printf("schimplSchedulerStart:")
 	golog.Printf("schimplSchedulerStart:")
@@ -1694,6 +1850,7 @@ func schimplSchedulerStart(tsd []TaskStaticDescription, tpo []string) (eno SchEr
 		tkd.Name	= tsd[loop].Name
 		tkd.DieCb	= tsd[loop].DieCb
 		tkd.Ep		= tsd[loop].Tep
+		tkd.UserDa	= tsd[loop].UserDa
 		tkd.Flag	= SchCreatedGo
 
 		if tsd[loop].MbSize < 0 {
@@ -1706,7 +1863,7 @@ func schimplSchedulerStart(tsd []TaskStaticDescription, tpo []string) (eno SchEr
 		// create task
 		//
 
-		if eno, ptn = schimplCreateTask(&tkd); eno != SchEnoNone {
+		if eno, ptn = schimplCreateTask(sdl, &tkd); eno != SchEnoNone {
 
 			yclog.LogCallerFileLine("schimplSchedulerStart: " +
 				"schimplCreateTask failed, task: %s",
@@ -1751,7 +1908,7 @@ func schimplSchedulerStart(tsd []TaskStaticDescription, tpo []string) (eno SchEr
 
 		yclog.LogCallerFileLine("schimplSchedulerStart: send poweron to task: %s", name)
 
-		eno, tsk := schimplGetTaskNodeByName(name)
+		eno, tsk := schimplGetTaskNodeByName(sdl, name)
 
 		if eno != SchEnoNone {
 
@@ -1792,7 +1949,7 @@ func schimplSchedulerStart(tsd []TaskStaticDescription, tpo []string) (eno SchEr
 
 	yclog.LogCallerFileLine("schimplSchedulerStart: go the watchdog")
 
-	go wdCB.watchDogProc()
+	go sdl.wdCB.watchDogProc()
 
 	golog.Printf("schimplSchedulerStart:")
 	golog.Printf("schimplSchedulerStart:")
@@ -1803,6 +1960,190 @@ func schimplSchedulerStart(tsd []TaskStaticDescription, tpo []string) (eno SchEr
 	return SchEnoNone, &name2PtnMap
 }
 
+//
+// Stop scheduler: poweroff those tasks registed in table "tpo" passed in one
+// by one, each is waited until it's done; after that, all other tasks still
+// alive, dynamic ones mostly, are killed, and the watchdog is killed at last.
+// The scheduler can be inited and started again after this function returns.
+//
+func schimplSchedulerStop(sdl *scheduler, tpo []string) SchErrno {
+
+	golog.Printf("schimplSchedulerStop: going to stop ycp2p scheduler ...")
+
+	var eno = SchEnoNone
+
+	var pf = schMessage {
+		sender:	&rawSchTsk,
+		recver: nil,
+		Id:		EvSchPoweroff,
+		Body:	nil,
+	}
+
+	for _, name := range tpo {
+
+		yclog.LogCallerFileLine("schimplSchedulerStop: send poweroff to task: %s", name)
+
+		sdl.lock.Lock()
+		ptn, ok := sdl.tkMap[schTaskName(name)]
+		sdl.lock.Unlock()
+
+		if !ok || ptn == nil {
+			yclog.LogCallerFileLine("schimplSchedulerStop: task not found, name: %s", name)
+			continue
+		}
+
+		var stopped = ptn.task.stopped
+		pf.recver = ptn
+
+		if schimplSendMsg(&pf) == SchEnoNone &&
+			schimplWaitTaskStopped(ptn, name, stopped) == SchEnoNone {
+			continue
+		}
+
+		//
+		// the task did not response to the poweroff event, kill it
+		//
+
+		yclog.LogCallerFileLine("schimplSchedulerStop: " +
+			"poweroff failed, kill task: %s",
+			name)
+
+		if schimplKillTask(ptn) != SchEnoNone {
+			eno = SchEnoInternal
+		}
+	}
+
+	//
+	// kill all other tasks still in busy queue
+	//
+
+	sdl.lock.Lock()
+
+	var remains = make([]*schTaskNode, 0, sdl.busySize)
+
+	if ptn := sdl.tkBusy; ptn != nil {
+		for {
+			remains = append(remains, ptn)
+			if ptn = ptn.next; ptn == sdl.tkBusy {
+				break
+			}
+		}
+	}
+
+	sdl.lock.Unlock()
+
+	for _, ptn := range remains {
+
+		yclog.LogCallerFileLine("schimplSchedulerStop: " +
+			"kill task: %s",
+			ptn.task.name)
+
+		if schimplKillTask(ptn) != SchEnoNone {
+			eno = SchEnoInternal
+		}
+	}
+
+	//
+	// kill the dog
+	//
+
+	select {
+	case sdl.wdCB.dogKill<-SchEnoKilled:
+	default:
+	}
+
+	golog.Printf("schimplSchedulerStop: ycp2p scheduler stopped, eno: %d", eno)
+
+	return eno
+}
+
+//
+// Kill a task and wait until it's done
+//
+func schimplKillTask(ptn *schTaskNode) SchErrno {
+
+	//
+	// a suspended task has no routine to deal with the "done", we clean it
+	// directly.
+	//
+
+	if ptn.task.done == nil {
+		return SchEnoNone
+	}
+
+	if ptn.task.goStatus != SchCreatedGo {
+		return schimplStopTaskEx(ptn)
+	}
+
+	var name = ptn.task.name
+	var stopped = ptn.task.stopped
+
+	//
+	// the task might have done itself, wait it anyway
+	//
+
+	if eno := schimplTaskDone(ptn, SchEnoKilled); eno != SchEnoNone && eno != SchEnoNotFound {
+		return eno
+	}
+
+	return schimplWaitTaskStopped(ptn, name, stopped)
+}
+
+//
+// Wait a task until it's stopped and cleaned, see function schimplStopTaskEx,
+// a named task is removed from the name map at last, while an unnamed one is
+// removed from the busy queue.
+//
+func schimplWaitTaskStopped(ptn *schTaskNode, name string, stopped chan bool) SchErrno {
+
+	var sdl = ptn.task.sdl
+	var timeout = time.After(schTaskStopTimeout)
+
+	if stopped != nil {
+
+		select {
+		case <-stopped:
+		case <-timeout:
+			return SchEnoInternal
+		}
+	}
+
+	var alive = func() bool {
+
+		sdl.lock.Lock()
+		defer sdl.lock.Unlock()
+
+		if len(name) > 0 {
+			p, ok := sdl.tkMap[schTaskName(name)]
+			return ok && p == ptn
+		}
+
+		if p := sdl.tkBusy; p != nil {
+			for {
+				if p == ptn {
+					return true
+				}
+				if p = p.next; p == sdl.tkBusy {
+					break
+				}
+			}
+		}
+
+		return false
+	}
+
+	for alive() {
+
+		select {
+		case <-timeout:
+			return SchEnoInternal
+		case <-time.After(schTaskStopPoll):
+		}
+	}
+
+	return SchEnoNone
+}
+
 //
 // Watchdog task
 //
@@ -1811,11 +2152,6 @@ type watchDogCtrlBlock struct {
 	dogKill chan SchErrno	// dog killed signal
 }
 
-var wdCB = watchDogCtrlBlock {
-	scb:		&p2pSDL,
-	dogKill:	make(chan SchErrno, 1),
-}
-
 func (wd watchDogCtrlBlock)watchDogProc() SchErrno {
 
 	var wdt	*time.Ticker
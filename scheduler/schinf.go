@@ -25,6 +25,7 @@ import(
 	"fmt"
 	"time"
 	yclog "github.com/yeeco/p2p/logger"
+	ycfg "github.com/yeeco/p2p/config"
 	"sync"
 )
 
@@ -180,34 +181,66 @@ type TaskStaticDescription struct {
 	Wd		SchWatchDog							// watchdog
 	DieCb	func(task interface{}) SchErrno		// callbacked when going to die
 	Flag	int									// flag: start at once or to be suspended
+	UserDa	interface{}							// user data area pointer
 }
 
 //
-// Scheduler initilization
+// Scheduler object. Each p2p instance owns one scheduler, and all the tasks
+// of an instance are created on its' scheduler, so tasks with the same name
+// can run in a process for different instances.
 //
-func SchinfSchedulerInit() SchErrno {
-	return schimplSchedulerInit()
+type Scheduler scheduler
+
+//
+// Scheduler initilization: a new scheduler is created and returned, the
+// configuration of the p2p instance is bound to it, tasks can get it by
+// SchinfGetP2pConfig.
+//
+func SchinfSchedulerInit(cfg *ycfg.Config) (SchErrno, *Scheduler) {
+	eno, sdl := schimplSchedulerInit(cfg)
+	return eno, (*Scheduler)(sdl)
 }
 
 //
 // Start scheduler
 //
-func SchinfSchedulerStart(tsd []TaskStaticDescription, tpo []string) (SchErrno, *map[string]interface{}){
-	return schimplSchedulerStart(tsd, tpo)
+func SchinfSchedulerStart(sdl *Scheduler, tsd []TaskStaticDescription, tpo []string) (SchErrno, *map[string]interface{}){
+	return schimplSchedulerStart((*scheduler)(sdl), tsd, tpo)
+}
+
+//
+// Stop scheduler
+//
+func SchinfSchedulerStop(sdl *Scheduler, tpo []string) SchErrno {
+	return schimplSchedulerStop((*scheduler)(sdl), tpo)
+}
+
+//
+// Get the scheduler a task runs on
+//
+func SchinfGetScheduler(ptn interface{}) *Scheduler {
+	return (*Scheduler)(ptn.(*schTaskNode).task.sdl)
+}
+
+//
+// Get the configuration of the p2p instance owns the scheduler
+//
+func SchinfGetP2pConfig(sdl *Scheduler) *ycfg.Config {
+	return sdl.p2pCfg
 }
 
 //
 // Create a single task
 //
-func SchinfCreateTask(taskDesc *SchTaskDescription)(SchErrno, interface{}) {
-	return schimplCreateTask((*schTaskDescription)(taskDesc))
+func SchinfCreateTask(sdl *Scheduler, taskDesc *SchTaskDescription)(SchErrno, interface{}) {
+	return schimplCreateTask((*scheduler)(sdl), (*schTaskDescription)(taskDesc))
 }
 
 //
 // Start a single task
 //
-func SchinfStartTask(name string) SchErrno {
-	return schimplStartTask(name)
+func SchinfStartTask(sdl *Scheduler, name string) SchErrno {
+	return schimplStartTask((*scheduler)(sdl), name)
 }
 
 //
@@ -220,8 +253,8 @@ func SchinfStartTaskEx(ptn interface{}) SchErrno {
 //
 // Stop a single task by name
 //
-func SchinfStopTaskByName(name string) SchErrno {
-	return schimplStopTask(name)
+func SchinfStopTaskByName(sdl *Scheduler, name string) SchErrno {
+	return schimplStopTask((*scheduler)(sdl), name)
 }
 
 //
@@ -238,8 +271,8 @@ func SchinfStopTask(ptn interface{}) SchErrno {
 //
 // Delete a single task
 //
-func SchinfDeleteTask(name string) SchErrno {
-	return schimplDeleteTask(name)
+func SchinfDeleteTask(sdl *Scheduler, name string) SchErrno {
+	return schimplDeleteTask((*scheduler)(sdl), name)
 }
 
 //
@@ -248,23 +281,27 @@ func SchinfDeleteTask(name string) SchErrno {
 // by that pointer returned. any actions to the task should be carried out by calling funcs
 // exported here in this file, which would call into the core of the scheduler.
 //
-func SchinfGetTaskNodeByName(name string) (eno SchErrno, task interface{}) {
-	return schimplGetTaskNodeByName(name)
+func SchinfGetTaskNodeByName(sdl *Scheduler, name string) (eno SchErrno, task interface{}) {
+	eno, ptn := schimplGetTaskNodeByName((*scheduler)(sdl), name)
+	if ptn == nil {
+		return eno, nil
+	}
+	return eno, ptn
 }
 
 //
 // Send message to a specific task
 //
-func SchinfSendMessageByName(dstTask string, srcTask string, msg *SchMessage) SchErrno {
+func SchinfSendMessageByName(sdl *Scheduler, dstTask string, srcTask string, msg *SchMessage) SchErrno {
 
-	eno, src := SchinfGetTaskNodeByName(srcTask)
+	eno, src := SchinfGetTaskNodeByName(sdl, srcTask)
 	if eno != SchEnoNone || src == nil {
 		yclog.LogCallerFileLine("SchinfSendMessageByName: " +
 			"SchinfGetTaskNodeByName failed, name: %s, eno: %d", srcTask, eno)
 		return eno
 	}
 
-	eno, dst := SchinfGetTaskNodeByName(dstTask)
+	eno, dst := SchinfGetTaskNodeByName(sdl, dstTask)
 	if eno != SchEnoNone || dst == nil {
 		yclog.LogCallerFileLine("SchinfSendMessageByName: " +
 			"SchinfGetTaskNodeByName failed, name: %s, eno: %d", dstTask, eno)
@@ -299,8 +336,8 @@ func SchinfMakeMessage(msg *SchMessage, s, r interface{}, id int, body interface
 //
 // Send message to a specific task group
 //
-func SchinfSendMessageGroup(grp string, msg *SchMessage) (eno SchErrno, failedCount int) {
-	return schimplSendMsg2TaskGroup(grp, (*schMessage)(msg))
+func SchinfSendMessageGroup(sdl *Scheduler, grp string, msg *SchMessage) (eno SchErrno, failedCount int) {
+	return schimplSendMsg2TaskGroup((*scheduler)(sdl), grp, (*schMessage)(msg))
 }
 
 //
@@ -24,6 +24,7 @@ package scheduler
 import (
 	"sync"
 	"time"
+	ycfg	"github.com/yeeco/p2p/config"
 )
 
 //
@@ -52,6 +53,14 @@ const (
 
 type schWatchDog SchWatchDog
 
+//
+// Timeout and poll cycle for waiting a task to be stopped
+//
+const (
+	schTaskStopTimeout			= time.Second * 4
+	schTaskStopPoll				= time.Millisecond * 10
+)
+
 //
 // Mail box
 //
@@ -88,6 +97,7 @@ type schTimerCtrlBlock struct {
 	stopped		chan bool		// had been stopped
 	taskNode	*schTaskNode	// pointer to owner task node
 	extra		interface{}		// extra data return to timer owner when expired
+	sdl			*scheduler		// scheduler owns this timer node
 }
 
 //
@@ -103,7 +113,6 @@ type schTmcbNode struct {
 // Timer node pool
 //
 const schTimerNodePoolSize	= 2048						// timer node pool size, must be (2^n)
-var schTimerNodePool [schTimerNodePoolSize]schTmcbNode	// timer node pool
 
 //
 // Task struct
@@ -123,7 +132,11 @@ type schTask struct {
 	dog			schWatchDog						// wathch dog
 	dieCb		func(interface{}) SchErrno		// callbacked when going to die
 	goStatus	int								// in going or suspended
+	stopping	bool							// done or being cleaned, no more messages accepted
+	quit		chan struct{}					// closed when stopping, wakes up senders blocked
+	senders		sync.WaitGroup					// senders blocked on the mailbox full
 	userData	interface{}						// data area pointer of user task
+	sdl			*scheduler						// scheduler owns this task node
 }
 
 //
@@ -143,15 +156,15 @@ type schTaskGroupName	string							// group name as string
 type schTaskGroup map[schTaskGroupName][]*schTaskNode	// group map group-name to task node array
 
 //
-// The ycp2p scheduler struct: since it's only planed to invoke the scheduler
-// internal modle ycp2p, this struct is not exported, any interface to created
-// such a scheduler object is not provided, see it pls.
+// The ycp2p scheduler struct: each p2p instance owns one scheduler, which owns
+// the task and timer node pools, so several instances can run in a process
+// without interfering with each other. The struct is exported as Scheduler in
+// schinf.go, but its' fields are not, see it pls.
 //
 const schInvalidTaskIndex		= -1					// invalid index
 const schTaskNodePoolSize		= 1024					// task node pool size, must be (2^n)
 type schTaskName 	string								// task name
 type schTaskIndex	int									// index of task node in pool
-var schTaskNodePool	[schTaskNodePoolSize]schTaskNode	// task node pool
 
 type scheduler struct {
 
@@ -172,6 +185,10 @@ type scheduler struct {
 	tmMap		map[*schTmcbNode] *schTaskNode	// map busy timer node pointer to its' owner task node pointer
 	grpMap		schTaskGroup					// group name to group member map
 	grpCnt		int								// group counter
+	tkPool		[schTaskNodePoolSize]schTaskNode	// task node pool
+	tmPool		[schTimerNodePoolSize]schTmcbNode	// timer node pool
+	wdCB		watchDogCtrlBlock				// watchdog control block
+	p2pCfg		*ycfg.Config					// configuration of the p2p instance owns the scheduler
 }
 
 //
@@ -192,4 +209,17 @@ const (
 	PeerLsnMgrName		= "PeerLsnMgr"		// tcp peer listener
 	PeerAccepterName	= "peerAccepter"	// tcp accepter
 	PeerMgrName			= "PeerMgr"			// tcp peer manager
+	DhtMgrName			= "DhtMgr"			// dht manager
+	DhtreMgrName		= "DhtreMgr"		// dht retrive manager
+	DhtroMgrName		= "DhtroMgr"		// dht router manager
+	DhtstMgrName		= "DhtstMgr"		// dht storer manager
+	DhtpMgrName			= "DhtpMgr"			// dht provider manager
+	DhtsyMgrName		= "DhtsyMgr"		// dht syncer manager
+	DhtrMgrName			= "DhtrMgr"			// dht route manager
+	GspMgrName			= "GspMgr"			// gossip manager
+	DhtchMgrName		= "DhtchMgr"		// dht chunker manager
+	DhtdiMgrName		= "DhtdiMgr"		// dht dispatcher manager
+	RpcMgrName			= "RpcMgr"			// rpc manager
+	StrMgrName			= "StrMgr"			// stream manager
+	AclMgrName			= "AclMgr"			// deny and allow lists manager
 )
@@ -0,0 +1,158 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shell
+
+import (
+	"time"
+	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/peer"
+			"github.com/yeeco/p2p/acl"
+)
+
+//
+// Acl errno constants, the same as those of the acl manager, see
+// acl.AclMgrEnoXXX.
+//
+const (
+	ACLINF_ENO_NONE			= acl.AclMgrEnoNone
+	ACLINF_ENO_PARA			= acl.AclMgrEnoParameter
+	ACLINF_ENO_SCHEDULER	= acl.AclMgrEnoScheduler
+	ACLINF_ENO_NOTFOUND		= acl.AclMgrEnoNotFound
+	ACLINF_ENO_DATABASE		= acl.AclMgrEnoDatabase
+	ACLINF_ENO_UNKNOWN		= acl.AclMgrEnoUnknown
+)
+
+//
+// Acl errno type
+//
+type AclErrno int
+
+//
+// Deny and allow lists of nodes, see acl.AclDeny for how they are applied.
+// A target of entries is a node identity in hex string, an address range in
+// CIDR notation like "10.0.0.0/8", or a single ip address. Entries are kept
+// in the node data directory, so they can be managed only when the instance
+// is started, and survive restarts.
+//
+
+//
+// Add or replace an entry, it's expired after ttl, 0 for never
+//
+func (p2p *P2pInstance) aclinfAdd(kind int, target string, ttl time.Duration) AclErrno {
+
+	if ttl < 0 {
+		yclog.LogCallerFileLine("aclinfAdd: invalid ttl: %s", ttl.String())
+		return ACLINF_ENO_PARA
+	}
+
+	if p2p.name2Ptn == nil {
+		return ACLINF_ENO_SCHEDULER
+	}
+
+	id, ipnet, eno := acl.AclParseTarget(target)
+	if eno != acl.AclMgrEnoNone {
+		return AclErrno(eno)
+	}
+
+	e := acl.AclEntry {
+		Kind:	kind,
+		Node:	id,
+		Net:	ipnet,
+	}
+
+	if ttl > 0 {
+		e.Expire = time.Now().Add(ttl)
+	}
+
+	//
+	// the entry is applied even if it fails to be saved, see acl.AclAdd
+	//
+
+	if eno = acl.AclAdd(p2p.sdl, &e); eno != acl.AclMgrEnoNone && eno != acl.AclMgrEnoDatabase {
+		return AclErrno(eno)
+	}
+
+	//
+	// peers connected before might be rejected now
+	//
+
+	if closed := peer.CloseRejectedPeers(p2p.sdl); closed > 0 {
+		yclog.LogCallerFileLine("aclinfAdd: peers closed: %d, target: %s", closed, target)
+	}
+
+	return AclErrno(eno)
+}
+
+//
+// Deny a target, peers connected and rejected are disconnected
+//
+func (p2p *P2pInstance) AclinfDeny(target string, ttl time.Duration) AclErrno {
+	return p2p.aclinfAdd(acl.AclDeny, target, ttl)
+}
+
+//
+// Allow a target, unless it's denied by other entries
+//
+func (p2p *P2pInstance) AclinfAllow(target string, ttl time.Duration) AclErrno {
+	return p2p.aclinfAdd(acl.AclAllow, target, ttl)
+}
+
+//
+// Remove the entry of a target
+//
+func (p2p *P2pInstance) AclinfRemove(target string) AclErrno {
+
+	if p2p.name2Ptn == nil {
+		return ACLINF_ENO_SCHEDULER
+	}
+
+	id, ipnet, eno := acl.AclParseTarget(target)
+	if eno != acl.AclMgrEnoNone {
+		return AclErrno(eno)
+	}
+
+	if eno = acl.AclRemove(p2p.sdl, id, ipnet); eno != acl.AclMgrEnoNone && eno != acl.AclMgrEnoDatabase {
+		return AclErrno(eno)
+	}
+
+	//
+	// removing an allow entry might reject peers in allow only mode, or in
+	// a range denied
+	//
+
+	if closed := peer.CloseRejectedPeers(p2p.sdl); closed > 0 {
+		yclog.LogCallerFileLine("AclinfRemove: peers closed: %d, target: %s", closed, target)
+	}
+
+	return AclErrno(eno)
+}
+
+//
+// Get entries
+//
+func (p2p *P2pInstance) AclinfList() ([]acl.AclEntry, AclErrno) {
+
+	if p2p.name2Ptn == nil {
+		return nil, ACLINF_ENO_SCHEDULER
+	}
+
+	return acl.AclList(p2p.sdl), ACLINF_ENO_NONE
+}
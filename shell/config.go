@@ -41,9 +41,10 @@ func ShellDefaultConfig() *ycfg.Config {
 }
 
 //
-// Set configuration
+// Set configuration of an instance, it's checked and owned by the instance
+// then, so it should not be shared with other instances.
 //
-func ShellSetConfig(cfg *ycfg.Config) ycfg.P2pCfgErrno {
+func (p2p *P2pInstance) ShellSetConfig(cfg *ycfg.Config) ycfg.P2pCfgErrno {
 
 	if cfg == nil {
 		yclog.LogCallerFileLine("ShellSetConfig: invalid parameter")
@@ -53,12 +54,18 @@ func ShellSetConfig(cfg *ycfg.Config) ycfg.P2pCfgErrno {
 	yclog.LogCallerFileLine("ShellSetConfig: %s",
 		fmt.Sprintf("%+v", *cfg))
 
-	return ycfg.P2pSetConfig(cfg)
+	if eno := ycfg.P2pSetConfig(cfg); eno != ycfg.PcfgEnoNone {
+		return eno
+	}
+
+	p2p.cfg = cfg
+
+	return ycfg.PcfgEnoNone
 }
 
 //
-// Set configuration
+// Get configuration of an instance
 //
-func ShellGetConfig() *ycfg.Config {
-	return ycfg.P2pGetConfig()
+func (p2p *P2pInstance) ShellGetConfig() *ycfg.Config {
+	return p2p.cfg
 }
@@ -21,6 +21,16 @@
 
 package shell
 
+import (
+	"time"
+	ycfg	"github.com/yeeco/p2p/config"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	yclog	"github.com/yeeco/p2p/logger"
+	dhtch	"github.com/yeeco/p2p/dht/chunker"
+	dhtre	"github.com/yeeco/p2p/dht/retriver"
+	dhtpr	"github.com/yeeco/p2p/dht/provider"
+	dhtdi	"github.com/yeeco/p2p/dht/dispatcher"
+)
 
 //
 // DHT errno constants
@@ -29,6 +39,12 @@ const (
 	DHTINF_ENO_NONE		= iota
 	DHTINF_ENO_PARA
 	DHTINF_ENO_UNKNOWN
+	DHTINF_ENO_SCHEDULER
+	DHTINF_ENO_STORE
+	DHTINF_ENO_NOTFOUND
+	DHTINF_ENO_NOPEER
+	DHTINF_ENO_INTEGRITY
+	DHTINF_ENO_TIMEOUT
 	DHTINF_ENO_MAX
 )
 
@@ -41,12 +57,20 @@ type DhtErrno int
 // Command type constants
 //
 const (
-	DHTINF_CMD_NULL			= 0		// null
-	DHTINF_CMD_STORE_REQ	= 1		// request to store a chunk
-	DHTINF_CMD_STORE_CFM	= 2		// confirm to store chunk request
-	DHTINF_CMD_RETRIVE_REQ	= 3		// request to retrive a chunk
-	DHTINF_CMD_RETRIVE_RSP	= 4		// confirm to retrive chunk request
-	DHTINF_CMD_MAX			= 5		// max, just for bound checking
+	DHTINF_CMD_NULL				= 0		// null
+	DHTINF_CMD_STORE_REQ		= 1		// request to store a chunk
+	DHTINF_CMD_STORE_CFM		= 2		// confirm to store chunk request
+	DHTINF_CMD_RETRIVE_REQ		= 3		// request to retrive a chunk
+	DHTINF_CMD_RETRIVE_RSP		= 4		// confirm to retrive chunk request
+	DHTINF_CMD_STORE_OBJ_REQ	= 5		// request to store an object
+	DHTINF_CMD_STORE_OBJ_CFM	= 6		// confirm to store object request
+	DHTINF_CMD_RETRIVE_OBJ_REQ	= 7		// request to retrive an object
+	DHTINF_CMD_RETRIVE_OBJ_RSP	= 8		// confirm to retrive object request
+	DHTINF_CMD_PROVIDE_REQ		= 9		// request to announce local node as a provider
+	DHTINF_CMD_PROVIDE_CFM		= 10	// confirm to provide request
+	DHTINF_CMD_FINDPRD_REQ		= 11	// request to find providers
+	DHTINF_CMD_FINDPRD_RSP		= 12	// confirm to find providers request
+	DHTINF_CMD_MAX				= 13	// max, just for bound checking
 )
 
 //
@@ -76,12 +100,14 @@ type DhtinfStoreChunkReq struct {
 	Key		DhtinfKey		// key for the chunk data
 	Chunk	DhtinfChunk		// the chunk data
 	Id		DhtinfId		// identity for this request
+	Timeout	time.Duration	// deadline for this request, 0 for default
 }
 
 //
 // Confirm to sotre a chunk
 //
 type DhtinfStoreChunkCfm struct {
+	Eno		DhtErrno		// result of request
 	Key		DhtinfKey		// key for the chunk data
 	Id		DhtinfId		// identity obtained by DhtinfStoreChunkReq.Id
 }
@@ -92,25 +118,115 @@ type DhtinfStoreChunkCfm struct {
 type DhtinfRetriveChunkReq struct {
 	Key		DhtinfKey		// key for chunk wanted
 	Id		DhtinfId		// identity for this request
+	Timeout	time.Duration	// deadline for this request, 0 for default
 }
 
 //
 // Confirm to retrive a chunk
 //
 type DhtinfRetriveChunkCfm struct {
+	Eno		DhtErrno		// result of request
 	Key		DhtinfKey		// key for chunk wanted
 	Chunk	DhtinfChunk		// the chunk data
 	Id		DhtinfId		// identity obtained by DhtinfRetriveChunkReq.Id
 }
 
+//
+// Chunking mode for objects
+//
+const (
+	DHTINF_CHUNK_FIXED		= dhtch.ChunkModeFixed		// fixed-size chunks
+	DHTINF_CHUNK_ROLLING	= dhtch.ChunkModeRolling	// content-defined chunks by rolling hash
+)
+
+//
+// Request to store an object: the object is split into chunks and a Merkle
+// DAG is built on them, the key of the root identifies the object.
+//
+type DhtinfStoreObjectReq struct {
+	Data	[]byte			// the object data
+	Mode	int				// chunking mode, DHTINF_CHUNK_XXX
+	Id		DhtinfId		// identity for this request
+	Timeout	time.Duration	// deadline for this request, 0 for default
+}
+
+//
+// Confirm to store an object
+//
+type DhtinfStoreObjectCfm struct {
+	Eno		DhtErrno		// result of request
+	Key		DhtinfKey		// key of the object, nil if failed
+	Size	uint64			// size of the object
+	Id		DhtinfId		// identity obtained by DhtinfStoreObjectReq.Id
+}
+
+//
+// Request to retrive an object
+//
+type DhtinfRetriveObjectReq struct {
+	Key		DhtinfKey		// key of the object
+	Id		DhtinfId		// identity for this request
+	Timeout	time.Duration	// deadline for this request, 0 for default
+}
+
+//
+// Confirm to retrive an object
+//
+type DhtinfRetriveObjectCfm struct {
+	Eno		DhtErrno		// result of request
+	Key		DhtinfKey		// key of the object
+	Data	[]byte			// the object data, checked against the key
+	Id		DhtinfId		// identity obtained by DhtinfRetriveObjectReq.Id
+}
+
+//
+// Request to announce local node as a provider of key, the record would be
+// published to the closest peers and republished periodically.
+//
+type DhtinfProvideReq struct {
+	Key		DhtinfKey		// key provided
+	Id		DhtinfId		// identity for this request
+	Timeout	time.Duration	// deadline for this request, 0 for default
+}
+
+//
+// Confirm to provide request
+//
+type DhtinfProvideCfm struct {
+	Eno		DhtErrno		// result of request
+	Key		DhtinfKey		// key provided
+	Peers	int				// number of peers the record published to
+	Id		DhtinfId		// identity obtained by DhtinfProvideReq.Id
+}
+
+//
+// Request to find providers of key
+//
+type DhtinfFindProvidersReq struct {
+	Key		DhtinfKey		// key wanted
+	Id		DhtinfId		// identity for this request
+	Timeout	time.Duration	// deadline for this request, 0 for default
+}
+
+//
+// Confirm to find providers request
+//
+type DhtinfFindProvidersCfm struct {
+	Eno			DhtErrno						// result of request
+	Key			DhtinfKey						// key wanted
+	Providers	map[ycfg.NodeID]ycfg.Node		// set of providers found
+	Id			DhtinfId						// identity obtained by DhtinfFindProvidersReq.Id
+}
+
 //
 // Confirm handler interface
 //
 type DhtinfConfirmHandler interface {
 
 	//
-	// determine what "msg" is by "ty": a pointer to DhtinfRetriveChunkCfm
-	// or DhtinfStoreChunkCfm
+	// determine what "msg" is by "ty": a pointer to DhtinfRetriveChunkCfm,
+	// DhtinfStoreChunkCfm, DhtinfStoreObjectCfm, DhtinfRetriveObjectCfm,
+	// DhtinfProvideCfm or DhtinfFindProvidersCfm
 	//
 
 	DhtCfmCb(ty DhtCommandType, msg interface{}) interface{}
@@ -119,20 +235,388 @@ type DhtinfConfirmHandler interface {
 //
 // Request to store a chunk
 //
-func DhtinfStoreChunk(req *DhtinfStoreChunkReq) DhtErrno {
-	return DHTINF_ENO_NONE
+func (p2p *P2pInstance) DhtinfStoreChunk(req *DhtinfStoreChunkReq) DhtErrno {
+
+	if req == nil || len(req.Key) == 0 {
+		yclog.LogCallerFileLine("DhtinfStoreChunk: invalid parameter")
+		return DHTINF_ENO_PARA
+	}
+
+	//
+	// the request is handed over to the storer task by the dispatcher, and
+	// the result would be confirmed to the handler registered by function
+	// DhtinfRegisterConfirmHandler, exactly once, with DHTINF_ENO_TIMEOUT
+	// if it's not done before the deadline. so are other requests.
+	//
+
+	var msg = sch.MsgDhtStoreReq {
+		Key:	append([]byte{}, req.Key...),
+		Chunk:	append([]byte{}, req.Chunk...),
+		Id:		uint64(req.Id),
+	}
+
+	return p2p.dhtinfDispatch(&msg, req.Timeout)
 }
 
 //
 // Request to retrive a chunk
 //
-func DhtinfRetriveChunk(req *DhtinfRetriveChunkReq) DhtErrno {
+func (p2p *P2pInstance) DhtinfRetriveChunk(req *DhtinfRetriveChunkReq) DhtErrno {
+
+	if req == nil || len(req.Key) == 0 {
+		yclog.LogCallerFileLine("DhtinfRetriveChunk: invalid parameter")
+		return DHTINF_ENO_PARA
+	}
+
+	//
+	// the request is handed over to the retriver task, which looks the chunk
+	// up in local store and then the peers. if it's not found, the dispatcher
+	// retries against those peers not queried yet.
+	//
+
+	var msg = sch.MsgDhtRetriveReq {
+		Key:	append([]byte{}, req.Key...),
+		Id:		uint64(req.Id),
+	}
+
+	return p2p.dhtinfDispatch(&msg, req.Timeout)
+}
+
+//
+// Request to store an object
+//
+func (p2p *P2pInstance) DhtinfStoreObject(req *DhtinfStoreObjectReq) DhtErrno {
+
+	if req == nil {
+		yclog.LogCallerFileLine("DhtinfStoreObject: invalid parameter")
+		return DHTINF_ENO_PARA
+	}
+
+	var msg = sch.MsgDhtObjStoreReq {
+		Data:	append([]byte{}, req.Data...),
+		Mode:	req.Mode,
+		Id:		uint64(req.Id),
+	}
+
+	return p2p.dhtinfDispatch(&msg, req.Timeout)
+}
+
+//
+// Request to retrive an object
+//
+func (p2p *P2pInstance) DhtinfRetriveObject(req *DhtinfRetriveObjectReq) DhtErrno {
+
+	if req == nil || len(req.Key) == 0 {
+		yclog.LogCallerFileLine("DhtinfRetriveObject: invalid parameter")
+		return DHTINF_ENO_PARA
+	}
+
+	var msg = sch.MsgDhtObjRetriveReq {
+		Key:	append([]byte{}, req.Key...),
+		Id:		uint64(req.Id),
+	}
+
+	return p2p.dhtinfDispatch(&msg, req.Timeout)
+}
+
+//
+// Request to announce local node as a provider of key
+//
+func (p2p *P2pInstance) DhtinfProvide(req *DhtinfProvideReq) DhtErrno {
+
+	if req == nil || len(req.Key) == 0 {
+		yclog.LogCallerFileLine("DhtinfProvide: invalid parameter")
+		return DHTINF_ENO_PARA
+	}
+
+	var msg = sch.MsgDhtPrdProvideReq {
+		Key:	append([]byte{}, req.Key...),
+		Id:		uint64(req.Id),
+	}
+
+	return p2p.dhtinfDispatch(&msg, req.Timeout)
+}
+
+//
+// Request to find providers of key
+//
+func (p2p *P2pInstance) DhtinfFindProviders(req *DhtinfFindProvidersReq) DhtErrno {
+
+	if req == nil || len(req.Key) == 0 {
+		yclog.LogCallerFileLine("DhtinfFindProviders: invalid parameter")
+		return DHTINF_ENO_PARA
+	}
+
+	var msg = sch.MsgDhtPrdFindReq {
+		Key:	append([]byte{}, req.Key...),
+		Id:		uint64(req.Id),
+	}
+
+	return p2p.dhtinfDispatch(&msg, req.Timeout)
+}
+
+//
+// Register confirm handler. The confirms from dht tasks are mapped by the
+// instance and handed to it, see P2pStart and dhtinfConfirm.
+//
+func (p2p *P2pInstance) DhtinfRegisterConfirmHandler(h DhtinfConfirmHandler) DhtErrno{
+
+	p2p.lock.Lock()
+	defer p2p.lock.Unlock()
+
+	if p2p.dhtCfmHandler != nil {
+		yclog.LogCallerFileLine("DhtinfRegisterConfirmHandler: old handler will be overlapped")
+	}
+
+	p2p.dhtCfmHandler = h
+
 	return DHTINF_ENO_NONE
 }
 
 //
-// Register confirm handler
+// Map confirms from dht tasks to those of this interface and hand them to
+// the handler registered
+//
+func (p2p *P2pInstance) dhtinfConfirm(msg interface{}) {
+
+	p2p.lock.Lock()
+	h := p2p.dhtCfmHandler
+	p2p.lock.Unlock()
+
+	if h == nil {
+		yclog.LogCallerFileLine("dhtinfConfirm: confirm handler not registered")
+		return
+	}
+
+	dc, ok := msg.(*sch.MsgDhtdiCfm)
+	if !ok {
+		yclog.LogCallerFileLine("dhtinfConfirm: unknown confirm: %T", msg)
+		return
+	}
+
+	if dc.Cfm == nil {
+		dhtinfFailConfirm(h, dc)
+		return
+	}
+
+	switch m := dc.Cfm.(type) {
+
+	case *sch.MsgDhtStoreCfm:
+
+		cfm := DhtinfStoreChunkCfm {
+			Eno:	DHTINF_ENO_NONE,
+			Key:	DhtinfKey(m.Key),
+			Id:		DhtinfId(m.Id),
+		}
+
+		if m.Eno != 0 {
+			cfm.Eno = DHTINF_ENO_STORE
+		}
+
+		h.DhtCfmCb(DHTINF_CMD_STORE_CFM, &cfm)
+
+	case *sch.MsgDhtRetriveCfm:
+
+		cfm := DhtinfRetriveChunkCfm {
+			Eno:	DHTINF_ENO_NONE,
+			Key:	DhtinfKey(m.Key),
+			Chunk:	DhtinfChunk(m.Chunk),
+			Id:		DhtinfId(m.Id),
+		}
+
+		switch m.Eno {
+		case dhtre.DhtreMgrEnoNone:
+		case dhtre.DhtreMgrEnoNotFound:
+			cfm.Eno = DHTINF_ENO_NOTFOUND
+		case dhtre.DhtreMgrEnoNoPeer:
+			cfm.Eno = DHTINF_ENO_NOPEER
+		default:
+			cfm.Eno = DHTINF_ENO_UNKNOWN
+		}
+
+		h.DhtCfmCb(DHTINF_CMD_RETRIVE_RSP, &cfm)
+
+	case *sch.MsgDhtObjStoreCfm:
+
+		cfm := DhtinfStoreObjectCfm {
+			Eno:	dhtinfChunkerEno(m.Eno),
+			Key:	DhtinfKey(m.Key),
+			Size:	m.Size,
+			Id:		DhtinfId(m.Id),
+		}
+
+		h.DhtCfmCb(DHTINF_CMD_STORE_OBJ_CFM, &cfm)
+
+	case *sch.MsgDhtObjRetriveCfm:
+
+		cfm := DhtinfRetriveObjectCfm {
+			Eno:	dhtinfChunkerEno(m.Eno),
+			Key:	DhtinfKey(m.Key),
+			Data:	m.Data,
+			Id:		DhtinfId(m.Id),
+		}
+
+		h.DhtCfmCb(DHTINF_CMD_RETRIVE_OBJ_RSP, &cfm)
+
+	case *sch.MsgDhtPrdProvideCfm:
+
+		cfm := DhtinfProvideCfm {
+			Eno:	DHTINF_ENO_NONE,
+			Key:	DhtinfKey(m.Key),
+			Peers:	m.Peers,
+			Id:		DhtinfId(m.Id),
+		}
+
+		if m.Eno == dhtpr.DhtpMgrEnoNoPeer {
+			cfm.Eno = DHTINF_ENO_NOPEER
+		} else if m.Eno != dhtpr.DhtpMgrEnoNone {
+			cfm.Eno = DHTINF_ENO_UNKNOWN
+		}
+
+		h.DhtCfmCb(DHTINF_CMD_PROVIDE_CFM, &cfm)
+
+	case *sch.MsgDhtPrdFindCfm:
+
+		cfm := DhtinfFindProvidersCfm {
+			Eno:		DHTINF_ENO_NONE,
+			Key:		DhtinfKey(m.Key),
+			Providers:	make(map[ycfg.NodeID]ycfg.Node, len(m.Providers)),
+			Id:			DhtinfId(m.Id),
+		}
+
+		for _, n := range m.Providers {
+			cfm.Providers[n.ID] = *n
+		}
+
+		if m.Eno != dhtpr.DhtpMgrEnoNone {
+			cfm.Eno = DHTINF_ENO_UNKNOWN
+		}
+
+		h.DhtCfmCb(DHTINF_CMD_FINDPRD_RSP, &cfm)
+
+	default:
+		yclog.LogCallerFileLine("dhtinfConfirm: unknown confirm: %T", dc.Cfm)
+	}
+}
+
 //
-func DhtinfRegisterConfirmHandler(h DhtinfConfirmHandler) DhtErrno{
+// Confirm a request failed in the dispatcher, timeout mostly
+//
+func dhtinfFailConfirm(h DhtinfConfirmHandler, dc *sch.MsgDhtdiCfm) {
+
+	var eno DhtErrno
+
+	switch dc.Eno {
+	case dhtdi.DhtdiMgrEnoTimeout:
+		eno = DHTINF_ENO_TIMEOUT
+	case dhtdi.DhtdiMgrEnoScheduler:
+		eno = DHTINF_ENO_SCHEDULER
+	default:
+		eno = DHTINF_ENO_UNKNOWN
+	}
+
+	id := DhtinfId(dc.Id)
+
+	switch m := dc.Req.(type) {
+
+	case *sch.MsgDhtStoreReq:
+		h.DhtCfmCb(DHTINF_CMD_STORE_CFM, &DhtinfStoreChunkCfm{Eno: eno, Key: m.Key, Id: id})
+
+	case *sch.MsgDhtRetriveReq:
+		h.DhtCfmCb(DHTINF_CMD_RETRIVE_RSP, &DhtinfRetriveChunkCfm{Eno: eno, Key: m.Key, Id: id})
+
+	case *sch.MsgDhtObjStoreReq:
+		h.DhtCfmCb(DHTINF_CMD_STORE_OBJ_CFM, &DhtinfStoreObjectCfm{Eno: eno, Size: uint64(len(m.Data)), Id: id})
+
+	case *sch.MsgDhtObjRetriveReq:
+		h.DhtCfmCb(DHTINF_CMD_RETRIVE_OBJ_RSP, &DhtinfRetriveObjectCfm{Eno: eno, Key: m.Key, Id: id})
+
+	case *sch.MsgDhtPrdProvideReq:
+		h.DhtCfmCb(DHTINF_CMD_PROVIDE_CFM, &DhtinfProvideCfm{Eno: eno, Key: m.Key, Id: id})
+
+	case *sch.MsgDhtPrdFindReq:
+		h.DhtCfmCb(DHTINF_CMD_FINDPRD_RSP, &DhtinfFindProvidersCfm{Eno: eno, Key: m.Key, Id: id})
+
+	default:
+		yclog.LogCallerFileLine("dhtinfFailConfirm: unknown request: %T", dc.Req)
+	}
+}
+
+//
+// Map errno of chunker to that of this interface
+//
+func dhtinfChunkerEno(eno int) DhtErrno {
+
+	switch eno {
+	case dhtch.DhtchMgrEnoNone:
+		return DHTINF_ENO_NONE
+	case dhtch.DhtchMgrEnoParameter:
+		return DHTINF_ENO_PARA
+	case dhtch.DhtchMgrEnoScheduler:
+		return DHTINF_ENO_SCHEDULER
+	case dhtch.DhtchMgrEnoStore:
+		return DHTINF_ENO_STORE
+	case dhtch.DhtchMgrEnoRetrive:
+		return DHTINF_ENO_NOTFOUND
+	case dhtch.DhtchMgrEnoIntegrity:
+		return DHTINF_ENO_INTEGRITY
+	}
+
+	return DHTINF_ENO_UNKNOWN
+}
+
+//
+// Hand a request over to the dispatcher
+//
+func (p2p *P2pInstance) dhtinfDispatch(req interface{}, timeout time.Duration) DhtErrno {
+
+	var msg = sch.MsgDhtdiReq {
+		Req:		req,
+		Timeout:	timeout,
+	}
+
+	return p2p.dhtinfSend2Task(dhtdi.DhtdiMgrName, sch.EvDhtdiReq, &msg)
+}
+
+//
+// Send request to a dht task
+//
+func (p2p *P2pInstance) dhtinfSend2Task(name string, id int, body interface{}) DhtErrno {
+
+	if p2p.sdl == nil {
+		yclog.LogCallerFileLine("dhtinfSend2Task: not started")
+		return DHTINF_ENO_SCHEDULER
+	}
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(p2p.sdl, name)
+	if eno != sch.SchEnoNone || ptn == nil {
+
+		yclog.LogCallerFileLine("dhtinfSend2Task: " +
+			"SchinfGetTaskNodeByName failed, eno: %d, name: %s",
+			eno, name)
+
+		return DHTINF_ENO_SCHEDULER
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno = sch.SchinfMakeMessage(&schMsg, ptn, ptn, id, body); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("dhtinfSend2Task: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return DHTINF_ENO_SCHEDULER
+	}
+
+	if eno = sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("dhtinfSend2Task: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, name)
+
+		return DHTINF_ENO_SCHEDULER
+	}
+
 	return DHTINF_ENO_NONE
 }
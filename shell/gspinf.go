@@ -0,0 +1,149 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+
+
+package shell
+
+import (
+	ycfg	"github.com/yeeco/p2p/config"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	yclog	"github.com/yeeco/p2p/logger"
+	gsp		"github.com/yeeco/p2p/gossip"
+)
+
+//
+// Gossip errno constants
+//
+const (
+	GSPINF_ENO_NONE		= iota
+	GSPINF_ENO_PARA
+	GSPINF_ENO_SCHEDULER
+	GSPINF_ENO_MAX
+)
+
+//
+// Gossip errno type
+//
+type GspErrno int
+
+//
+// Handler for messages published to a topic. It's called in the gossip task,
+// so it should not block.
+//
+type GspinfHandler func(topic string, from ycfg.NodeID, data []byte)
+
+//
+// Subscribe a topic, handler h would be called for each message received,
+// the handler is replaced if the topic had been subscribed.
+//
+func (p2p *P2pInstance) GspinfSubscribe(topic string, h GspinfHandler) GspErrno {
+
+	if len(topic) == 0 || h == nil {
+		yclog.LogCallerFileLine("GspinfSubscribe: invalid parameters")
+		return GSPINF_ENO_PARA
+	}
+
+	var req = sch.MsgGspSubscribeReq {
+		Topic:		topic,
+		Handler:	h,
+	}
+
+	return p2p.gspinfSend2Task(sch.EvGspSubscribeReq, &req)
+}
+
+//
+// Unsubscribe a topic
+//
+func (p2p *P2pInstance) GspinfUnsubscribe(topic string) GspErrno {
+
+	if len(topic) == 0 {
+		yclog.LogCallerFileLine("GspinfUnsubscribe: invalid topic")
+		return GSPINF_ENO_PARA
+	}
+
+	var req = sch.MsgGspUnsubscribeReq {
+		Topic:	topic,
+	}
+
+	return p2p.gspinfSend2Task(sch.EvGspUnsubscribeReq, &req)
+}
+
+//
+// Publish data to a topic. Notice: the data would not be delivered to the
+// handler of local node even the topic is subscribed locally.
+//
+func (p2p *P2pInstance) GspinfPublish(topic string, data []byte) GspErrno {
+
+	if len(topic) == 0 {
+		yclog.LogCallerFileLine("GspinfPublish: invalid topic")
+		return GSPINF_ENO_PARA
+	}
+
+	var req = sch.MsgGspPublishReq {
+		Topic:	topic,
+		Data:	append([]byte{}, data...),
+	}
+
+	return p2p.gspinfSend2Task(sch.EvGspPublishReq, &req)
+}
+
+//
+// Send request to the gossip task
+//
+func (p2p *P2pInstance) gspinfSend2Task(id int, body interface{}) GspErrno {
+
+	if p2p.sdl == nil {
+		yclog.LogCallerFileLine("gspinfSend2Task: not started")
+		return GSPINF_ENO_SCHEDULER
+	}
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(p2p.sdl, gsp.GspMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+
+		yclog.LogCallerFileLine("gspinfSend2Task: " +
+			"SchinfGetTaskNodeByName failed, eno: %d, name: %s",
+			eno, gsp.GspMgrName)
+
+		return GSPINF_ENO_SCHEDULER
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno = sch.SchinfMakeMessage(&schMsg, ptn, ptn, id, body); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("gspinfSend2Task: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return GSPINF_ENO_SCHEDULER
+	}
+
+	if eno = sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("gspinfSend2Task: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, gsp.GspMgrName)
+
+		return GSPINF_ENO_SCHEDULER
+	}
+
+	return GSPINF_ENO_NONE
+}
@@ -0,0 +1,263 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shell
+
+import (
+	"time"
+	ycfg	"github.com/yeeco/p2p/config"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/rpc"
+)
+
+//
+// RPC errno constants. Those for results of calls are the same as those of
+// the rpc manager, see rpc.RpcMgrEnoXXX.
+//
+const (
+	RPCINF_ENO_NONE			= rpc.RpcMgrEnoNone
+	RPCINF_ENO_PARA			= rpc.RpcMgrEnoParameter
+	RPCINF_ENO_SCHEDULER	= rpc.RpcMgrEnoScheduler
+	RPCINF_ENO_MESSAGE		= rpc.RpcMgrEnoMessage
+	RPCINF_ENO_NOPEER		= rpc.RpcMgrEnoNoPeer
+	RPCINF_ENO_TIMEOUT		= rpc.RpcMgrEnoTimeout
+	RPCINF_ENO_CANCELED		= rpc.RpcMgrEnoCanceled
+	RPCINF_ENO_NOMETHOD		= rpc.RpcMgrEnoNoMethod
+	RPCINF_ENO_REMOTE		= rpc.RpcMgrEnoRemote
+	RPCINF_ENO_PEERCLOSED	= rpc.RpcMgrEnoPeerClosed
+	RPCINF_ENO_BUSY			= rpc.RpcMgrEnoBusy
+	RPCINF_ENO_UNKNOWN		= rpc.RpcMgrEnoUnknown
+)
+
+//
+// RPC errno type
+//
+type RpcErrno int
+
+//
+// Identity of call
+//
+type RpcinfId uint64
+
+//
+// Call request
+//
+type RpcinfCallReq struct {
+	To			ycfg.NodeID			// peer to call
+	Method		string				// method
+	Payload		[]byte				// request payload
+	Timeout		time.Duration		// deadline of call, 0 for default
+	Cancel		<-chan struct{}		// the call is cancelled when closed, for RpcinfCall only, can be nil
+}
+
+//
+// Result of call. Error is the description of the error from the handler
+// of remote, for RPCINF_ENO_REMOTE.
+//
+type RpcinfResult struct {
+	Eno			RpcErrno			// result
+	Error		string				// error description
+	Id			RpcinfId			// identity of call
+	Payload		[]byte				// response payload
+}
+
+//
+// Callback for result of call. It's called in the rpc task exactly once for
+// each call, so it should not block.
+//
+type RpcinfCallback func(result *RpcinfResult)
+
+//
+// Handler of method. It's called in a routine of its' own for each request,
+// done is closed when the result is no longer needed: the call cancelled,
+// deadline expired or the caller closed. The error returned, if any, is
+// passed to the caller as RPCINF_ENO_REMOTE with its' description.
+//
+type RpcinfHandler func(from ycfg.NodeID, method string, req []byte, done <-chan struct{}) ([]byte, error)
+
+//
+// Register handler for a method, the handler is replaced if the method had
+// been registered; nil h removes the method. Like protocol handlers, it's
+// kept in the instance across restarts, and can be registered before the
+// instance started.
+//
+func (p2p *P2pInstance) RpcinfRegisterHandler(method string, h RpcinfHandler) RpcErrno {
+
+	if len(method) == 0 {
+		yclog.LogCallerFileLine("RpcinfRegisterHandler: invalid method")
+		return RPCINF_ENO_PARA
+	}
+
+	p2p.lock.Lock()
+	defer p2p.lock.Unlock()
+
+	if h == nil {
+		delete(p2p.rpcHandlers, method)
+	} else {
+		p2p.rpcHandlers[method] = h
+	}
+
+	if p2p.name2Ptn == nil {
+		return RPCINF_ENO_NONE
+	}
+
+	return p2p.rpcinfRegister2Task(method, h)
+}
+
+//
+// Register handler for a method to the rpc task
+//
+func (p2p *P2pInstance) rpcinfRegister2Task(method string, h RpcinfHandler) RpcErrno {
+
+	var req = sch.MsgRpcRegisterReq {
+		Method:		method,
+		Handler:	h,
+	}
+
+	return p2p.rpcinfSend2Task(sch.EvRpcRegisterReq, &req)
+}
+
+//
+// Call a method of peer and wait for the result. The call is cancelled if
+// req.Cancel closed before the result.
+//
+func (p2p *P2pInstance) RpcinfCall(req *RpcinfCallReq) *RpcinfResult {
+
+	var ch = make(chan *RpcinfResult, 1)
+
+	id, eno := p2p.RpcinfCallAsync(req, func(result *RpcinfResult) {
+		ch <- result
+	})
+
+	if eno != RPCINF_ENO_NONE {
+		return &RpcinfResult{Eno: eno, Id: id}
+	}
+
+	select {
+
+	case result := <-ch:
+		return result
+
+	case <-req.Cancel:
+
+		if p2p.RpcinfCancel(id) != RPCINF_ENO_NONE {
+			return &RpcinfResult{Eno: RPCINF_ENO_CANCELED, Id: id}
+		}
+
+		//
+		// the result might be confirmed before the cancel handled, so we
+		// take whatever comes.
+		//
+
+		return <-ch
+	}
+}
+
+//
+// Call a method of peer, cb is called with the result. The identity of the
+// call is returned, by which the call can be cancelled.
+//
+func (p2p *P2pInstance) RpcinfCallAsync(req *RpcinfCallReq, cb RpcinfCallback) (RpcinfId, RpcErrno) {
+
+	if req == nil || len(req.Method) == 0 || cb == nil {
+		yclog.LogCallerFileLine("RpcinfCallAsync: invalid parameters")
+		return 0, RPCINF_ENO_PARA
+	}
+
+	p2p.lock.Lock()
+	p2p.rpcSeq++
+	id := p2p.rpcSeq
+	p2p.lock.Unlock()
+
+	var call = sch.MsgRpcCallReq {
+		Id:			id,
+		To:			req.To,
+		Method:		req.Method,
+		Payload:	append([]byte{}, req.Payload...),
+		Timeout:	req.Timeout,
+		Cb:			func(cfm *sch.MsgRpcCallCfm) {
+			cb(&RpcinfResult {
+				Eno:		RpcErrno(cfm.Eno),
+				Error:		cfm.Error,
+				Id:			RpcinfId(cfm.Id),
+				Payload:	cfm.Payload,
+			})
+		},
+	}
+
+	return RpcinfId(id), p2p.rpcinfSend2Task(sch.EvRpcCallReq, &call)
+}
+
+//
+// Cancel a call, the callback of the call is confirmed with RPCINF_ENO_CANCELED
+// if it's still pending.
+//
+func (p2p *P2pInstance) RpcinfCancel(id RpcinfId) RpcErrno {
+
+	var req = sch.MsgRpcCancelReq {
+		Id:	uint64(id),
+	}
+
+	return p2p.rpcinfSend2Task(sch.EvRpcCancelReq, &req)
+}
+
+//
+// Send request to the rpc task
+//
+func (p2p *P2pInstance) rpcinfSend2Task(id int, body interface{}) RpcErrno {
+
+	if p2p.sdl == nil {
+		yclog.LogCallerFileLine("rpcinfSend2Task: not started")
+		return RPCINF_ENO_SCHEDULER
+	}
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(p2p.sdl, rpc.RpcMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+
+		yclog.LogCallerFileLine("rpcinfSend2Task: " +
+			"SchinfGetTaskNodeByName failed, eno: %d, name: %s",
+			eno, rpc.RpcMgrName)
+
+		return RPCINF_ENO_SCHEDULER
+	}
+
+	var schMsg = sch.SchMessage{}
+
+	if eno = sch.SchinfMakeMessage(&schMsg, ptn, ptn, id, body); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("rpcinfSend2Task: " +
+			"SchinfMakeMessage failed, eno: %d",
+			eno)
+
+		return RPCINF_ENO_SCHEDULER
+	}
+
+	if eno = sch.SchinfSendMessage(&schMsg); eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("rpcinfSend2Task: " +
+			"SchinfSendMessage failed, eno: %d, target: %s",
+			eno, rpc.RpcMgrName)
+
+		return RPCINF_ENO_SCHEDULER
+	}
+
+	return RPCINF_ENO_NONE
+}
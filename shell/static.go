@@ -22,6 +22,8 @@
 package shell
 
 import (
+	"sync"
+	ycfg	"github.com/yeeco/p2p/config"
 	sch 	"github.com/yeeco/p2p/scheduler"
 	dcv		"github.com/yeeco/p2p/discover"
 	tab		"github.com/yeeco/p2p/discover/table"
@@ -31,60 +33,111 @@ import (
 	dhtro	"github.com/yeeco/p2p/dht/router"
 	dhtch	"github.com/yeeco/p2p/dht/chunker"
 	dhtre	"github.com/yeeco/p2p/dht/retriver"
+	dhtpr	"github.com/yeeco/p2p/dht/provider"
 	dhtst	"github.com/yeeco/p2p/dht/storer"
 	dhtsy	"github.com/yeeco/p2p/dht/syncer"
+	dhtdi	"github.com/yeeco/p2p/dht/dispatcher"
+	dhtr	"github.com/yeeco/p2p/dht/route"
+	gsp		"github.com/yeeco/p2p/gossip"
+			"github.com/yeeco/p2p/rpc"
+			"github.com/yeeco/p2p/stream"
+			"github.com/yeeco/p2p/acl"
 	yclog	"github.com/yeeco/p2p/logger"
 )
 
+//
+// A p2p instance: it owns its' configuration, scheduler and all the static
+// tasks(and the managers behind them), so several instances can be run in
+// a process independently, each as a p2p node.
+//
+type P2pInstance struct {
+	cfg				*ycfg.Config								// configuration
+	sdl				*sch.Scheduler								// scheduler
+	userTasks		[]sch.TaskStaticDescription					// static tasks appended by user
+	name2Ptn		*map[string]interface{}						// map static task name to task node
+	indHandler		peer.P2pInfIndCallback						// indication handler of user
+	protoHandlers	map[peer.P2pProtoKey]peer.P2pInfPkgCallback	// protocol handlers of user
+	dhtCfmHandler	DhtinfConfirmHandler						// dht confirm handler of user
+	rpcHandlers		map[string]RpcinfHandler					// rpc method handlers of user
+	rpcSeq			uint64										// sequence for identities of rpc calls
+	strListens		map[uint32]int								// backlog of stream protocols listened by user
+	lock			sync.Mutex									// lock for handlers
+}
+
+//
+// Create a p2p instance, ShellSetConfig should be called before P2pInit
+//
+func NewP2pInstance() *P2pInstance {
+	return &P2pInstance{
+		cfg:			nil,
+		sdl:			nil,
+		userTasks:		nil,
+		name2Ptn:		nil,
+		protoHandlers:	map[peer.P2pProtoKey]peer.P2pInfPkgCallback{},
+		rpcHandlers:	map[string]RpcinfHandler{},
+		strListens:		map[uint32]int{},
+	}
+}
+
 //
 // Static tasks should be listed in following table, which would be passed to scheduler to
-// create and schedule them while p2p starts up.
+// create and schedule them while p2p starts up. Since the tasks of an instance must not be
+// shared with others, the table is built with new managers as user data areas of the tasks
+// each time an instance starts.
 //
 var noDog = sch.SchWatchDog {
 	HaveDog:false,
 }
 
-var TaskStaticTab = []sch.TaskStaticDescription {
-
-	//
-	// Following are static tasks for ycp2p module internal. Notice that fields of struct
-	// sch.TaskStaticDescription like MbSize, Wd, Flag will be set to default values internal
-	// scheduler, please see function schimplSchedulerStart for details pls.
-	//
-
-	{	Name:dcv.DcvMgrName,		Tep:dcv.DcvMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:tab.TabMgrName,		Tep:tab.TabMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:tab.NdbcName,			Tep:tab.NdbcProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:ngb.LsnMgrName,		Tep:ngb.LsnMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:ngb.NgbMgrName,		Tep:ngb.NgbMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:peer.PeerLsnMgrName,	Tep:peer.LsnMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:peer.PeerMgrName,		Tep:peer.PeerMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:dht.DhtMgrName,		Tep:dht.DhtMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:dhtro.DhtroMgrName,	Tep:dhtro.DhtroMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:dhtch.DhtchMgrName,	Tep:dhtch.DhtchMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:dhtre.DhtreMgrName,	Tep:dhtre.DhtreMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:dhtst.DhtstMgrName,	Tep:dhtst.DhtstMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-	{	Name:dhtsy.DhtsyMgrName,	Tep:dhtsy.DhtsyMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend},
-
-	//
-	// More static tasks outside ycp2p can be appended bellow
-	// handly or by calling function AppendStaticTasks. When
-	// function SchinfSchedulerStart called, currently, all
-	// tasks registered here would be created and scheduled
-	// to go in order.
-	//
-	// Since static tasks might depend each other, the order
-	// to be scheduled to go might have to be taken into account
-	// in the future, we leave this possible work later.
-	//
+func taskStaticTab() []sch.TaskStaticDescription {
+
+	return []sch.TaskStaticDescription {
+
+		//
+		// Following are static tasks for ycp2p module internal. Notice that fields of struct
+		// sch.TaskStaticDescription like MbSize, Wd, Flag will be set to default values internal
+		// scheduler, please see function schimplSchedulerStart for details pls.
+		//
+
+		{	Name:acl.AclMgrName,		Tep:acl.AclMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:acl.NewAclMgr()},
+		{	Name:dcv.DcvMgrName,		Tep:dcv.DcvMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dcv.NewDcvMgr()},
+		{	Name:tab.TabMgrName,		Tep:tab.TabMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:tab.NewTabMgr()},
+		{	Name:tab.NdbcName,			Tep:tab.NdbcProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:tab.NewNdbCleaner()},
+		{	Name:ngb.LsnMgrName,		Tep:ngb.LsnMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:ngb.NewLsnMgr()},
+		{	Name:ngb.NgbMgrName,		Tep:ngb.NgbMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:ngb.NewNgbMgr()},
+		{	Name:peer.PeerLsnMgrName,	Tep:peer.LsnMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:peer.NewLsnMgr()},
+		{	Name:peer.PeerMgrName,		Tep:peer.PeerMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:peer.NewPeerMgr()},
+		{	Name:dht.DhtMgrName,		Tep:dht.DhtMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dht.NewDhtMgr()},
+		{	Name:dhtr.DhtrMgrName,		Tep:dhtr.DhtrMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtr.NewDhtrMgr()},
+		{	Name:dhtro.DhtroMgrName,	Tep:dhtro.DhtroMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtro.NewDhtroMgr()},
+		{	Name:dhtch.DhtchMgrName,	Tep:dhtch.DhtchMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtch.NewDhtchMgr()},
+		{	Name:dhtre.DhtreMgrName,	Tep:dhtre.DhtreMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtre.NewDhtreMgr()},
+		{	Name:dhtst.DhtstMgrName,	Tep:dhtst.DhtstMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtst.NewDhtstMgr()},
+		{	Name:dhtsy.DhtsyMgrName,	Tep:dhtsy.DhtsyMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtsy.NewDhtsyMgr()},
+		{	Name:dhtpr.DhtpMgrName,		Tep:dhtpr.DhtpMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtpr.NewDhtpMgr()},
+		{	Name:dhtdi.DhtdiMgrName,	Tep:dhtdi.DhtdiMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:dhtdi.NewDhtdiMgr()},
+		{	Name:gsp.GspMgrName,		Tep:gsp.GspMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:gsp.NewGspMgr()},
+		{	Name:rpc.RpcMgrName,		Tep:rpc.RpcMgrProc,			MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:rpc.NewRpcMgr()},
+		{	Name:stream.StrMgrName,		Tep:stream.StrMgrProc,		MbSize:-1,	DieCb: nil,		Wd:noDog,	Flag:sch.SchCreatedSuspend,	UserDa:stream.NewStrMgr()},
+
+		//
+		// More static tasks outside ycp2p can be appended by calling
+		// method AppendStaticTasks of an instance. When function
+		// SchinfSchedulerStart called, currently, all tasks registered
+		// here would be created and scheduled to go in order.
+		//
+		// Since static tasks might depend each other, the order
+		// to be scheduled to go might have to be taken into account
+		// in the future, we leave this possible work later.
+		//
+	}
 }
 
-var taskName2TasNode *map[string]interface{} = nil
-
 //
 // Poweron order of static user tasks
 //
 var TaskStaticPoweronOrder = []string {
+	acl.AclMgrName,
 	dcv.DcvMgrName,
 	tab.TabMgrName,
 	tab.NdbcName,
@@ -93,36 +146,66 @@ var TaskStaticPoweronOrder = []string {
 	peer.PeerMgrName,
 	peer.PeerLsnMgrName,
 	dht.DhtMgrName,
+	dhtr.DhtrMgrName,
 	dhtro.DhtroMgrName,
 	dhtch.DhtchMgrName,
 	dhtre.DhtreMgrName,
 	dhtst.DhtstMgrName,
 	dhtsy.DhtsyMgrName,
+	dhtpr.DhtpMgrName,
+	dhtdi.DhtdiMgrName,
+	gsp.GspMgrName,
+	rpc.RpcMgrName,
+	stream.StrMgrName,
 }
 
 //
-// Append a static user task to table TaskStaticTab
+// Append a static user task to the instance, it should be called before
+// P2pStart. The user data area of the task, if any, is owned by the task
+// and should not be shared with tasks of other instances.
 //
-func AppendStaticTasks(
+func (p2p *P2pInstance) AppendStaticTasks(
 	name string,
 	tep sch.SchUserTaskEp,
 	dcb func(interface{})sch.SchErrno,
-	dog sch.SchWatchDog) sch.SchErrno {
-	TaskStaticTab = append(TaskStaticTab, sch.TaskStaticDescription{Name:name, Tep:tep, DieCb:dcb, Wd:dog})
+	dog sch.SchWatchDog,
+	userDa interface{}) sch.SchErrno {
+	p2p.userTasks = append(p2p.userTasks,
+		sch.TaskStaticDescription{Name:name, Tep:tep, DieCb:dcb, Wd:dog, UserDa:userDa})
 	return sch.SchEnoNone
 }
 
 //
 // Init p2p
 //
-func P2pInit() sch.SchErrno {
-	return sch.SchinfSchedulerInit()
+func (p2p *P2pInstance) P2pInit() sch.SchErrno {
+
+	if p2p.cfg == nil {
+		yclog.LogCallerFileLine("P2pInit: configuration not set")
+		return sch.SchEnoConfig
+	}
+
+	if p2p.sdl != nil {
+		yclog.LogCallerFileLine("P2pInit: not stopped yet")
+		return sch.SchEnoMismatched
+	}
+
+	var eno sch.SchErrno
+
+	eno, p2p.sdl = sch.SchinfSchedulerInit(p2p.cfg)
+
+	return eno
 }
 
 //
 // Start p2p
 //
-func P2pStart() (sch.SchErrno, *map[string]interface{}) {
+func (p2p *P2pInstance) P2pStart() (sch.SchErrno, *map[string]interface{}) {
+
+	if p2p.sdl == nil {
+		yclog.LogCallerFileLine("P2pStart: not inited")
+		return sch.SchEnoMismatched, nil
+	}
 
 	//
 	// Start all static tasks
@@ -130,7 +213,9 @@ func P2pStart() (sch.SchErrno, *map[string]interface{}) {
 
 	var eno sch.SchErrno
 
-	eno, taskName2TasNode = sch.SchinfSchedulerStart(TaskStaticTab, TaskStaticPoweronOrder)
+	tasks := append(taskStaticTab(), p2p.userTasks...)
+
+	eno, p2p.name2Ptn = sch.SchinfSchedulerStart(p2p.sdl, tasks, TaskStaticPoweronOrder)
 
 	if eno != sch.SchEnoNone {
 
@@ -138,9 +223,35 @@ func P2pStart() (sch.SchErrno, *map[string]interface{}) {
 			"SchinfSchedulerStart failed, eno: %d",
 			eno	)
 
-		return eno, taskName2TasNode
+		return eno, p2p.name2Ptn
 	}
 
+	//
+	// Install handlers registered before starting
+	//
+
+	p2p.lock.Lock()
+
+	if p2p.indHandler != nil {
+		peer.SetP2pIndHandler(p2p.sdl, p2p.indHandler)
+	}
+
+	for key, cb := range p2p.protoHandlers {
+		peer.SetProtoHandler(p2p.sdl, key.Pid, key.Ver, cb)
+	}
+
+	for method, h := range p2p.rpcHandlers {
+		p2p.rpcinfRegister2Task(method, h)
+	}
+
+	for pid, backlog := range p2p.strListens {
+		stream.Listen(p2p.sdl, pid, backlog)
+	}
+
+	p2p.lock.Unlock()
+
+	dht.SetConfirmCallback(p2p.sdl, p2p.dhtinfConfirm)
+
 	//
 	// Check peer manager init result, would be blocked until its init
 	// procedure ended.
@@ -148,7 +259,7 @@ func P2pStart() (sch.SchErrno, *map[string]interface{}) {
 
 	var pmEno peer.PeMgrErrno
 
-	pmEno = peer.PeMgrInited()
+	pmEno = peer.PeMgrInited(p2p.sdl)
 
 	if pmEno != peer.PeMgrEnoNone {
 
@@ -156,14 +267,14 @@ func P2pStart() (sch.SchErrno, *map[string]interface{}) {
 			"peer manager init failed, eno: %d",
 			pmEno)
 
-		return sch.SchEnoUserTask, taskName2TasNode
+		return sch.SchEnoUserTask, p2p.name2Ptn
 	}
 
 	//
 	// Startup peer manager
 	//
 
-	pmEno = peer.PeMgrStart()
+	pmEno = peer.PeMgrStart(p2p.sdl)
 
 	if pmEno != peer.PeMgrEnoNone {
 
@@ -171,34 +282,66 @@ func P2pStart() (sch.SchErrno, *map[string]interface{}) {
 			"PeMgrStart failed, eno: %d",
 			pmEno)
 
-		return sch.SchEnoUserTask, taskName2TasNode
+		return sch.SchEnoUserTask, p2p.name2Ptn
+	}
+
+	return sch.SchEnoNone, p2p.name2Ptn
+}
+
+//
+// Stop p2p: static tasks are powered off in the reverse order they are
+// powered on, and then all the other tasks are killed. After this, P2pInit
+// and P2pStart can be called again to restart p2p.
+//
+func (p2p *P2pInstance) P2pStop() sch.SchErrno {
+
+	if p2p.sdl == nil {
+		yclog.LogCallerFileLine("P2pStop: not inited")
+		return sch.SchEnoMismatched
+	}
+
+	var tpo = make([]string, 0, len(TaskStaticPoweronOrder))
+
+	for idx := len(TaskStaticPoweronOrder) - 1; idx >= 0; idx-- {
+		tpo = append(tpo, TaskStaticPoweronOrder[idx])
 	}
 
-	return sch.SchEnoNone, taskName2TasNode
+	eno := sch.SchinfSchedulerStop(p2p.sdl, tpo)
+
+	if eno != sch.SchEnoNone {
+
+		yclog.LogCallerFileLine("P2pStop: " +
+			"SchinfSchedulerStop failed, eno: %d",
+			eno)
+	}
+
+	p2p.sdl = nil
+	p2p.name2Ptn = nil
+
+	return eno
 }
 
 //
 // Get user static task pointer: this pointer would be required when accessing
 // to scheduler, see file schinf.go for more please.
 //
-func GetTaskNode(name string) interface{} {
+func (p2p *P2pInstance) GetTaskNode(name string) interface{} {
 
 	//
-	// Notice: this function should be called only after StartYcp2p is called
+	// Notice: this function should be called only after P2pStart is called
 	// and it returns successfully.
 	//
 
-	if taskName2TasNode == nil {
+	if p2p.name2Ptn == nil {
 		yclog.LogCallerFileLine("GetTaskNode: seems ycp2p is not started")
 		return nil
 	}
-	_, v := (*taskName2TasNode)[name]
-	return v
+	return (*p2p.name2Ptn)[name]
 }
 
-
-
-
-
-
-
+//
+// Get scheduler of the instance, nil if it's not inited
+//
+func (p2p *P2pInstance) GetScheduler() *sch.Scheduler {
+	return p2p.sdl
+}
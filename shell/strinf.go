@@ -0,0 +1,144 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shell
+
+import (
+	"time"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/peer"
+			"github.com/yeeco/p2p/stream"
+)
+
+//
+// Stream errno constants, the same as those of the stream manager, see
+// stream.StrMgrEnoXXX.
+//
+const (
+	STRINF_ENO_NONE			= stream.StrMgrEnoNone
+	STRINF_ENO_PARA			= stream.StrMgrEnoParameter
+	STRINF_ENO_SCHEDULER	= stream.StrMgrEnoScheduler
+	STRINF_ENO_NOPEER		= stream.StrMgrEnoNoPeer
+	STRINF_ENO_NOLISTENER	= stream.StrMgrEnoNoListener
+	STRINF_ENO_DUPLICATED	= stream.StrMgrEnoDuplicated
+	STRINF_ENO_TIMEOUT		= stream.StrMgrEnoTimeout
+	STRINF_ENO_RESET		= stream.StrMgrEnoReset
+	STRINF_ENO_CLOSED		= stream.StrMgrEnoClosed
+	STRINF_ENO_UNKNOWN		= stream.StrMgrEnoUnknown
+)
+
+//
+// Stream errno type
+//
+type StrErrno int
+
+//
+// Streams are multiplexed over the connections to peers, each with flow
+// control of its' own, so a large transfer on a stream would not hold up
+// other packages to the same peer. A stream is an io.ReadWriteCloser, see
+// stream.Stream for details. Streams are identified by protocols of user,
+// which are independent of those registered by P2pInfRegisterProtoHandler.
+//
+
+//
+// Listen to a protocol for streams opened by peers, at most backlog streams
+// are queued till accepted, 0 for default. Like protocol handlers, it's kept
+// in the instance across restarts, and can be called before the instance
+// started.
+//
+func (p2p *P2pInstance) StrinfListen(pid uint32, backlog int) StrErrno {
+
+	if backlog < 0 {
+		yclog.LogCallerFileLine("StrinfListen: invalid backlog: %d", backlog)
+		return STRINF_ENO_PARA
+	}
+
+	p2p.lock.Lock()
+	defer p2p.lock.Unlock()
+
+	if _, dup := p2p.strListens[pid]; dup {
+		return STRINF_ENO_DUPLICATED
+	}
+
+	p2p.strListens[pid] = backlog
+
+	if p2p.name2Ptn == nil {
+		return STRINF_ENO_NONE
+	}
+
+	return StrErrno(stream.Listen(p2p.sdl, pid, backlog))
+}
+
+//
+// Stop listening to a protocol, streams not accepted yet are reset
+//
+func (p2p *P2pInstance) StrinfUnlisten(pid uint32) StrErrno {
+
+	p2p.lock.Lock()
+	defer p2p.lock.Unlock()
+
+	if _, ok := p2p.strListens[pid]; !ok {
+		return STRINF_ENO_NOLISTENER
+	}
+
+	delete(p2p.strListens, pid)
+
+	if p2p.name2Ptn == nil {
+		return STRINF_ENO_NONE
+	}
+
+	return StrErrno(stream.Unlisten(p2p.sdl, pid))
+}
+
+//
+// Accept a stream for a protocol listened, wait till timeout, 0 for no limit
+//
+func (p2p *P2pInstance) StrinfAccept(pid uint32, timeout time.Duration) (*stream.Stream, StrErrno) {
+
+	if p2p.name2Ptn == nil {
+		return nil, STRINF_ENO_SCHEDULER
+	}
+
+	s, eno := stream.Accept(p2p.sdl, pid, timeout)
+
+	return s, StrErrno(eno)
+}
+
+//
+// Open a stream to peer for a protocol, wait till it's accepted by peer or
+// timeout, 0 for no limit. Data of the stream is sent with priority prio,
+// see peer.P2pPrioXXX.
+//
+func (p2p *P2pInstance) StrinfOpen(to ycfg.NodeID, pid uint32, prio int, timeout time.Duration) (*stream.Stream, StrErrno) {
+
+	if prio != peer.P2pPrioNormal && prio != peer.P2pPrioHigh && prio != peer.P2pPrioBulk {
+		yclog.LogCallerFileLine("StrinfOpen: invalid priority: %d", prio)
+		return nil, STRINF_ENO_PARA
+	}
+
+	if p2p.name2Ptn == nil {
+		return nil, STRINF_ENO_SCHEDULER
+	}
+
+	s, eno := stream.Open(p2p.sdl, to, pid, prio, timeout)
+
+	return s, StrErrno(eno)
+}
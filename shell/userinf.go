@@ -41,7 +41,8 @@ const (
 	P2pInfEnoNotImpl	P2pInfErrno = 3	// not implemented
 	P2pInfEnoInternal	P2pInfErrno	= 4	// internal
 	P2pInfEnoUnknown	P2pInfErrno = 5	// unknown
-	P2pInfEnoMax		P2pInfErrno = 6	// max, for bound checking
+	P2pInfEnoTxFull		P2pInfErrno = 6	// send queue full
+	P2pInfEnoMax		P2pInfErrno = 7	// max, for bound checking
 )
 
 //
@@ -50,7 +51,11 @@ const (
 var P2pInfErrnoDescription = []string {
 	"none of errors",
 	"invalid parameters",
+	"scheduler",
+	"not implemented",
+	"internal",
 	"unknown",
+	"send queue full",
 	"max value can errno be",
 }
 
@@ -81,12 +86,14 @@ const (
 )
 
 const (
-	P2pIndPeerActivated	= peer.P2pIndPeerActivated	// indication for a peer activated to work
-	P2pIndConnStatus	= peer.P2pIndConnStatus		// indication for peer connection status changed
-	P2pIndPeerClosed	= peer.P2pIndPeerClosed		// indication for peer connection closed
+	P2pIndPeerActivated		= peer.P2pIndPeerActivated		// indication for a peer activated to work
+	P2pIndConnStatus		= peer.P2pIndConnStatus			// indication for peer connection status changed
+	P2pIndPeerClosed		= peer.P2pIndPeerClosed			// indication for peer connection closed
+	P2pIndPkgRejected		= peer.P2pIndPkgRejected		// indication for package of unknown protocol rejected
+	P2pIndTxBackpressure	= peer.P2pIndTxBackpressure		// indication for send queue of peer congested or relieved
 )
 
-func P2pInfRegisterCallback(what int, cb interface{}, ptn interface{}) P2pInfErrno {
+func (p2p *P2pInstance) P2pInfRegisterCallback(what int, cb interface{}, ptn interface{}) P2pInfErrno {
 
 	if what != P2pInfIndCb && what != P2pInfPkgCb {
 		yclog.LogCallerFileLine("P2pInfRegisterCallback: " +
@@ -96,15 +103,29 @@ func P2pInfRegisterCallback(what int, cb interface{}, ptn interface{}) P2pInfErr
 	}
 
 	if what == P2pInfIndCb {
-		if peer.P2pIndHandler != nil {
-			yclog.LogCallerFileLine("P2pInfRegisterCallback: old handler will be overlapped")
-		}
 		if cb == nil {
 			yclog.LogCallerFileLine("P2pInfRegisterCallback: user registers nil indication handler")
 		}
-		peer.Lock4Cb.Lock()
-		peer.P2pIndHandler = cb.(peer.P2pInfIndCallback)
-		peer.Lock4Cb.Unlock()
+		p2p.lock.Lock()
+		defer p2p.lock.Unlock()
+		if p2p.indHandler != nil {
+			yclog.LogCallerFileLine("P2pInfRegisterCallback: old handler will be overlapped")
+		}
+		p2p.indHandler, _ = cb.(peer.P2pInfIndCallback)
+
+		//
+		// if the instance is not started yet, the handler would be installed
+		// to the peer manager while it's started, see P2pStart.
+		//
+
+		if p2p.name2Ptn != nil {
+			if eno := peer.SetP2pIndHandler(p2p.sdl, p2p.indHandler); eno != peer.PeMgrEnoNone {
+				yclog.LogCallerFileLine("P2pInfRegisterCallback: " +
+					"SetP2pIndHandler failed, eno: %d",
+					eno)
+				return P2pInfEnoInternal
+			}
+		}
 		return P2pInfEnoNone
 	}
 
@@ -132,11 +153,83 @@ func P2pInfRegisterCallback(what int, cb interface{}, ptn interface{}) P2pInfErr
 }
 
 //
-// Send message to peer
+// Register package handler for a protocol of specific version. It's global
+// for all peers of the instance and kept across restarts, packages from a peer are routed by protocol identity and
+// the version negotiated with the peer in handshake. Packages of protocols with
+// no handler registered are rejected and P2pIndPkgRejected is indicated,
+// except that PID_EXT ones are still passed to the package callback of the
+// peer instance. A nil cb removes the handler.
+//
+func (p2p *P2pInstance) P2pInfRegisterProtoHandler(pid uint32, ver [4]byte, cb peer.P2pInfPkgCallback) P2pInfErrno {
+
+	if pid == uint32(peer.PID_P2P) || pid == uint32(peer.PID_DHT) || pid == uint32(peer.PID_GOSSIP) ||
+		pid == uint32(peer.PID_RPC) || pid == uint32(peer.PID_STREAM) {
+
+		yclog.LogCallerFileLine("P2pInfRegisterProtoHandler: " +
+			"internal protocol can't be registered, pid: %d",
+			pid)
+
+		return P2pInfEnoParameter
+	}
+
+	p2p.lock.Lock()
+	defer p2p.lock.Unlock()
+
+	key := peer.P2pProtoKey{Pid: pid, Ver: ver}
+
+	if cb == nil {
+		delete(p2p.protoHandlers, key)
+	} else {
+		p2p.protoHandlers[key] = cb
+	}
+
+	//
+	// if the instance is not started yet, the handler would be installed
+	// to the peer manager while it's started, see P2pStart.
+	//
+
+	if p2p.name2Ptn == nil {
+		return P2pInfEnoNone
+	}
+
+	if eno := peer.SetProtoHandler(p2p.sdl, pid, ver, cb); eno != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("P2pInfRegisterProtoHandler: " +
+			"SetProtoHandler failed, eno: %d, pid: %d, ver: %v",
+			eno, pid, ver)
+
+		return P2pInfEnoInternal
+	}
+
+	return P2pInfEnoNone
+}
+
+//
+// Get protocols negotiated with an activated peer, with the versions agreed
 //
-func P2pInfSendPackage(pkg *peer.P2pPackage2Peer) P2pInfErrno {
+func (p2p *P2pInstance) P2pInfPeerProtocols(id *peer.PeerId) ([]peer.Protocol, P2pInfErrno) {
+
+	protocols, eno := peer.PeerProtocols(p2p.sdl, *id)
+	if eno != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("P2pInfPeerProtocols: " +
+			"PeerProtocols failed, eno: %d, peer: %s",
+			eno,
+			fmt.Sprintf("%X", *id))
+
+		return nil, P2pInfEnoParameter
+	}
 
-	if eno, failed := peer.SendPackage(pkg); eno != peer.PeMgrEnoNone {
+	return protocols, P2pInfEnoNone
+}
+
+//
+// Send message to peer. P2pInfEnoTxFull is returned if the send queue of
+// some peer is full, see P2pPackage2Peer.TxMode for waiting for room.
+//
+func (p2p *P2pInstance) P2pInfSendPackage(pkg *peer.P2pPackage2Peer) P2pInfErrno {
+
+	if eno, failed := peer.SendPackage(p2p.sdl, pkg); eno != peer.PeMgrEnoNone {
 
 		yclog.LogCallerFileLine("P2pInfSendPackage: " +
 			"SendPackage failed, eno: %d, pkg: %s",
@@ -153,6 +246,126 @@ func P2pInfSendPackage(pkg *peer.P2pPackage2Peer) P2pInfErrno {
 			"failed list: %s",
 			str)
 
+		if eno == peer.PeMgrEnoTxFull {
+			return P2pInfEnoTxFull
+		}
+
+		return P2pInfEnoInternal
+	}
+
+	return P2pInfEnoNone
+}
+
+//
+// Get depth and capacity of the send queue of a peer
+//
+func (p2p *P2pInstance) P2pInfTxQueueStatus(id *peer.PeerId) (int, int, P2pInfErrno) {
+
+	if id == nil {
+		yclog.LogCallerFileLine("P2pInfTxQueueStatus: invalid parameter")
+		return 0, 0, P2pInfEnoParameter
+	}
+
+	depth, capacity, eno := peer.TxQueueStatus(p2p.sdl, *id)
+	if eno != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("P2pInfTxQueueStatus: " +
+			"TxQueueStatus failed, eno: %d, peer: %X",
+			eno, *id)
+
+		return 0, 0, P2pInfEnoInternal
+	}
+
+	return depth, capacity, P2pInfEnoNone
+}
+
+//
+// Get statistics about payload compression of a peer
+//
+func (p2p *P2pInstance) P2pInfCompressStatus(id *peer.PeerId) (peer.PeCompressStat, P2pInfErrno) {
+
+	if id == nil {
+		yclog.LogCallerFileLine("P2pInfCompressStatus: invalid parameter")
+		return peer.PeCompressStat{}, P2pInfEnoParameter
+	}
+
+	st, eno := peer.CompressStatus(p2p.sdl, *id)
+	if eno != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("P2pInfCompressStatus: " +
+			"CompressStatus failed, eno: %d, peer: %X",
+			eno, *id)
+
+		return peer.PeCompressStat{}, P2pInfEnoInternal
+	}
+
+	return st, P2pInfEnoNone
+}
+
+//
+// Feedback about a peer from application: delta in [peer.PeScoreMin,
+// peer.PeScoreMax] is added to the score of the peer, negative for bad
+// behaviors, positive for good ones. The peer is disconnected, and banned
+// for a while, if it's scored too low, see Config.PeerDropScore.
+//
+func (p2p *P2pInstance) P2pInfPeerFeedback(id *peer.PeerId, delta int) P2pInfErrno {
+
+	if id == nil || delta < peer.PeScoreMin || delta > peer.PeScoreMax {
+		yclog.LogCallerFileLine("P2pInfPeerFeedback: invalid parameter")
+		return P2pInfEnoParameter
+	}
+
+	if eno := peer.ReportPeer(p2p.sdl, *id, delta); eno != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("P2pInfPeerFeedback: " +
+			"ReportPeer failed, eno: %d, peer: %X",
+			eno, *id)
+
+		return P2pInfEnoInternal
+	}
+
+	return P2pInfEnoNone
+}
+
+//
+// Get score of a peer
+//
+func (p2p *P2pInstance) P2pInfPeerScore(id *peer.PeerId) (peer.PeScoreInfo, P2pInfErrno) {
+
+	if id == nil {
+		yclog.LogCallerFileLine("P2pInfPeerScore: invalid parameter")
+		return peer.PeScoreInfo{}, P2pInfEnoParameter
+	}
+
+	info, eno := peer.PeerScore(p2p.sdl, *id)
+	if eno != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("P2pInfPeerScore: " +
+			"PeerScore failed, eno: %d, peer: %X",
+			eno, *id)
+
+		return peer.PeScoreInfo{}, P2pInfEnoInternal
+	}
+
+	return info, P2pInfEnoNone
+}
+
+//
+// Lift the ban of a peer, its' score is reset to zero
+//
+func (p2p *P2pInstance) P2pInfUnbanPeer(id *peer.PeerId) P2pInfErrno {
+
+	if id == nil {
+		yclog.LogCallerFileLine("P2pInfUnbanPeer: invalid parameter")
+		return P2pInfEnoParameter
+	}
+
+	if eno := peer.UnbanPeer(p2p.sdl, *id); eno != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("P2pInfUnbanPeer: " +
+			"UnbanPeer failed, eno: %d, peer: %X",
+			eno, *id)
+
 		return P2pInfEnoInternal
 	}
 
@@ -162,8 +375,8 @@ func P2pInfSendPackage(pkg *peer.P2pPackage2Peer) P2pInfErrno {
 //
 // Disconnect peer
 //
-func P2pInfClosePeer(id *peer.PeerId) P2pInfErrno {
-	if eno := peer.ClosePeer(id); eno != peer.PeMgrEnoNone {
+func (p2p *P2pInstance) P2pInfClosePeer(id *peer.PeerId) P2pInfErrno {
+	if eno := peer.ClosePeer(p2p.sdl, id); eno != peer.PeMgrEnoNone {
 		yclog.LogCallerFileLine("P2pInfSendPackage: " +
 			"ClosePeer failed, eno: %d, peer: %s",
 			eno,
@@ -176,7 +389,12 @@ func P2pInfClosePeer(id *peer.PeerId) P2pInfErrno {
 //
 // Free total p2p all
 //
-func P2pInfPoweroff() P2pInfErrno {
-	yclog.LogCallerFileLine("P2pInfPoweroff: not supported yet")
-	return P2pInfEnoNotImpl
+func (p2p *P2pInstance) P2pInfPoweroff() P2pInfErrno {
+	if eno := p2p.P2pStop(); eno != scheduler.SchEnoNone {
+		yclog.LogCallerFileLine("P2pInfPoweroff: " +
+			"P2pStop failed, eno: %d",
+			eno)
+		return P2pInfEnoScheduler
+	}
+	return P2pInfEnoNone
 }
\ No newline at end of file
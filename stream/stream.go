@@ -0,0 +1,1006 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stream
+
+import (
+	"io"
+	"fmt"
+	"sync"
+	"time"
+	"bytes"
+	"errors"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+	yclog	"github.com/yeeco/p2p/logger"
+			"github.com/yeeco/p2p/peer"
+	sm		"github.com/yeeco/p2p/stream/strmsg"
+)
+
+//
+// errno
+//
+const (
+	StrMgrEnoNone	= iota
+	StrMgrEnoParameter
+	StrMgrEnoScheduler
+	StrMgrEnoConfig
+	StrMgrEnoMessage
+	StrMgrEnoNoPeer
+	StrMgrEnoNoListener
+	StrMgrEnoDuplicated
+	StrMgrEnoTimeout
+	StrMgrEnoReset
+	StrMgrEnoClosed
+	StrMgrEnoUnknown
+)
+
+type StrMgrErrno int
+
+//
+// Errors returned by Read, Write of streams
+//
+var (
+	ErrStrClosed	= errors.New("stream: closed")
+	ErrStrReset		= errors.New("stream: reset")
+)
+
+//
+// The stream manager multiplexes logical bidirectional streams over the peer
+// connections, with the peers advertised PID_STREAM in their handshakes. A
+// stream belongs to a protocol of user: it's opened to a peer for a protocol,
+// and accepted by the peer only if it's listening to the protocol.
+//
+// Data written to a stream is split into frames of strFrameSize at most, each
+// sent in a package of its' own, so frames of a large transfer are sent with
+// other packages interleaved, see peer.SendPackage for priority classes. Each
+// direction of a stream has a flow control window: the sender can only send
+// as much as the window allows, and the receiver enlarges the window by the
+// data consumed, so a stream never buffers more than strWindow bytes at the
+// receiver, and a slow reader slows down the writer instead of the others.
+//
+// Streams opened by the side with the smaller node identity are identified
+// with odd numbers, by the other side with even numbers, so both sides can
+// open streams independently.
+//
+// Frames of a stream in one direction are sent in the same priority class,
+// so they are never reordered. Streams accepted send in P2pPrioNormal.
+//
+const (
+	strFrameSize		= 16 * 1024			// max data bytes in a frame
+	strWindow			= 256 * 1024		// window of stream
+	strDftBacklog		= 16				// default backlog of listener
+)
+
+//
+// Stream state
+//
+const (
+	strStateOpening		= iota		// OPEN sent, waiting ACCEPT
+	strStateOpen					// opened
+	strStateReset					// reset, by local or remote
+)
+
+//
+// Stream
+//
+type Stream struct {
+	mgr				*strManager			// pointer to manager
+	peer			ycfg.NodeID			// peer
+	id				uint32				// identity
+	pid				uint32				// protocol
+	prio			int					// priority of frames sent
+	lock			sync.Mutex			// lock for states
+	cond			*sync.Cond			// signaled when states changed
+	wlock			sync.Mutex			// lock for writers
+	state			int					// state
+	opened			chan StrMgrErrno	// result of opening
+	rbuf			[]byte				// data received not read yet
+	rwin			uint32				// window for remote to send
+	consumed		uint32				// data read but not granted to remote yet
+	swin			uint32				// window to send
+	localClosed		bool				// closed by local
+	remoteClosed	bool				// closed by remote
+}
+
+type strKey struct {
+	peer			ycfg.NodeID			// peer
+	id				uint32				// stream identity
+}
+
+//
+// stream manager
+//
+const StrMgrName = sch.StrMgrName
+
+type strManager struct {
+	name		string						// name
+	tep			sch.SchUserTaskEp			// entry
+	sdl			*sch.Scheduler				// pointer to scheduler
+	ptnMe		interface{}					// pointer to myself task node
+	local		ycfg.NodeID					// local node identity
+	lock		sync.Mutex					// lock for streams and listeners, accessed by users
+	seq			uint32						// sequence for stream identities
+	peers		map[ycfg.NodeID]bool		// stream peers
+	streams		map[strKey]*Stream			// streams
+	listeners	map[uint32]chan *Stream		// listeners by protocol, with streams not accepted yet
+}
+
+//
+// Create stream manager, one for each p2p instance
+//
+func NewStrMgr() interface{} {
+	return &strManager{
+		name:		StrMgrName,
+		tep:		StrMgrProc,
+		ptnMe:		nil,
+		peers:		map[ycfg.NodeID]bool{},
+		streams:	map[strKey]*Stream{},
+		listeners:	map[uint32]chan *Stream{},
+	}
+}
+
+//
+// Get stream manager of a p2p instance by its' scheduler
+//
+func strGetManager(sdl *sch.Scheduler) *strManager {
+
+	eno, ptn := sch.SchinfGetTaskNodeByName(sdl, StrMgrName)
+	if eno != sch.SchEnoNone || ptn == nil {
+		return nil
+	}
+
+	strMgr, _ := sch.SchinfGetUserDataArea(ptn).(*strManager)
+
+	return strMgr
+}
+
+//
+// stream manager entry
+//
+func StrMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+
+	yclog.LogCallerFileLine("StrMgrProc: scheduled, msg: %d", msg.Id)
+
+	strMgr := sch.SchinfGetUserDataArea(ptn).(*strManager)
+
+	var eno StrMgrErrno
+
+	switch msg.Id {
+
+	case sch.EvSchPoweron:
+		eno = strMgr.strMgrPoweron(ptn)
+
+	case sch.EvSchPoweroff:
+		eno = strMgr.strMgrPoweroff(ptn)
+
+	case sch.EvStrMgrPeerInd:
+		eno = strMgr.strMgrPeerInd(msg.Body.(*sch.MsgStrPeerInd))
+
+	case sch.EvStrMgrPkgInd:
+		eno = strMgr.strMgrPkgInd(msg.Body.(*sch.MsgStrPkgInd))
+
+	default:
+		yclog.LogCallerFileLine("StrMgrProc: invalid message: %d", msg.Id)
+		eno = StrMgrEnoParameter
+	}
+
+	if eno != StrMgrEnoNone {
+		yclog.LogCallerFileLine("StrMgrProc: errors, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
+	return sch.SchEnoNone
+}
+
+//
+// Poweron handler
+//
+func (strMgr *strManager) strMgrPoweron(ptn interface{}) StrMgrErrno {
+
+	strMgr.sdl = sch.SchinfGetScheduler(ptn)
+	strMgr.ptnMe = ptn
+
+	cfg := ycfg.P2pConfig4Stream(sch.SchinfGetP2pConfig(strMgr.sdl))
+	if cfg == nil {
+		yclog.LogCallerFileLine("strMgrPoweron: invalid configuration")
+		return StrMgrEnoConfig
+	}
+
+	strMgr.local = cfg.Local
+
+	return StrMgrEnoNone
+}
+
+//
+// Poweroff handler: all streams are reset, and listeners are closed
+//
+func (strMgr *strManager) strMgrPoweroff(ptn interface{}) StrMgrErrno {
+
+	strMgr.lock.Lock()
+
+	for key, s := range strMgr.streams {
+		s.lock.Lock()
+		s.resetLocked(StrMgrEnoClosed)
+		s.lock.Unlock()
+		delete(strMgr.streams, key)
+	}
+
+	for pid, ch := range strMgr.listeners {
+		close(ch)
+		delete(strMgr.listeners, pid)
+	}
+
+	strMgr.peers = map[ycfg.NodeID]bool{}
+
+	strMgr.lock.Unlock()
+
+	if sch.SchinfTaskDone(ptn, sch.SchEnoKilled) != sch.SchEnoNone {
+		return StrMgrEnoUnknown
+	}
+
+	return StrMgrEnoNone
+}
+
+//
+// Peer activated or closed indication handler
+//
+func (strMgr *strManager) strMgrPeerInd(ind *sch.MsgStrPeerInd) StrMgrErrno {
+
+	id := ind.Node.ID
+
+	strMgr.lock.Lock()
+	defer strMgr.lock.Unlock()
+
+	switch ind.Ind {
+
+	case peer.P2pIndPeerActivated:
+
+		strMgr.peers[id] = true
+
+	case peer.P2pIndPeerClosed:
+
+		delete(strMgr.peers, id)
+
+		for key, s := range strMgr.streams {
+			if key.peer == id {
+				s.lock.Lock()
+				s.resetLocked(StrMgrEnoNoPeer)
+				s.lock.Unlock()
+				delete(strMgr.streams, key)
+			}
+		}
+
+	default:
+
+		yclog.LogCallerFileLine("strMgrPeerInd: " +
+			"invalid indication: %d, peer: %s",
+			ind.Ind, fmt.Sprintf("%X", id))
+
+		return StrMgrEnoParameter
+	}
+
+	return StrMgrEnoNone
+}
+
+//
+// Package from peer indication handler
+//
+func (strMgr *strManager) strMgrPkgInd(ind *sch.MsgStrPkgInd) StrMgrErrno {
+
+	msg, eno := sm.Decode(ind.Payload)
+	if eno != sm.StrMsgEnoNone {
+
+		yclog.LogCallerFileLine("strMgrPkgInd: " +
+			"Decode failed, eno: %d, from: %s",
+			eno, fmt.Sprintf("%X", ind.From))
+
+		return StrMgrEnoMessage
+	}
+
+	if msg.Mid == sm.MID_STREAM_OPEN {
+		return strMgr.strOpenInd(ind.From, msg)
+	}
+
+	strMgr.lock.Lock()
+	s, ok := strMgr.streams[strKey{peer: ind.From, id: msg.Id}]
+	strMgr.lock.Unlock()
+
+	if !ok {
+
+		if msg.Mid == sm.MID_STREAM_RESET {
+			return StrMgrEnoNone
+		}
+
+		yclog.LogCallerFileLine("strMgrPkgInd: " +
+			"stream not found, mid: %d, id: %d, from: %s",
+			msg.Mid, msg.Id, fmt.Sprintf("%X", ind.From))
+
+		strMgr.strSend(ind.From, &sm.StrMessage{Mid: sm.MID_STREAM_RESET, Id: msg.Id}, peer.P2pPrioHigh)
+
+		return StrMgrEnoNone
+	}
+
+	switch msg.Mid {
+
+	case sm.MID_STREAM_ACCEPT:
+		s.acceptInd(msg.Window)
+
+	case sm.MID_STREAM_DATA:
+		if s.dataInd(msg.Data) != true {
+			yclog.LogCallerFileLine("strMgrPkgInd: " +
+				"window exceeded, reset, id: %d, from: %s",
+				msg.Id, fmt.Sprintf("%X", ind.From))
+			strMgr.strReset(s, true)
+		}
+
+	case sm.MID_STREAM_WINDOW:
+		if s.windowInd(msg.Window) != true {
+			yclog.LogCallerFileLine("strMgrPkgInd: " +
+				"window overflowed, reset, id: %d, from: %s",
+				msg.Id, fmt.Sprintf("%X", ind.From))
+			strMgr.strReset(s, true)
+		}
+
+	case sm.MID_STREAM_CLOSE:
+		if s.closeInd() {
+			strMgr.strRemove(s)
+		}
+
+	case sm.MID_STREAM_RESET:
+		strMgr.strReset(s, false)
+
+	default:
+		yclog.LogCallerFileLine("strMgrPkgInd: invalid mid: %d", msg.Mid)
+		return StrMgrEnoMessage
+	}
+
+	return StrMgrEnoNone
+}
+
+//
+// Stream opened by peer
+//
+func (strMgr *strManager) strOpenInd(from ycfg.NodeID, msg *sm.StrMessage) StrMgrErrno {
+
+	var reset = &sm.StrMessage{Mid: sm.MID_STREAM_RESET, Id: msg.Id}
+
+	strMgr.lock.Lock()
+
+	key := strKey{peer: from, id: msg.Id}
+	ch, listened := strMgr.listeners[msg.Pid]
+	_, dup := strMgr.streams[key]
+
+	if !listened || dup || strMgr.strIdParity(from) == msg.Id & 1 {
+
+		strMgr.lock.Unlock()
+
+		yclog.LogCallerFileLine("strOpenInd: " +
+			"rejected, listened: %t, dup: %t, id: %d, pid: %d, from: %s",
+			listened, dup, msg.Id, msg.Pid, fmt.Sprintf("%X", from))
+
+		strMgr.strSend(from, reset, peer.P2pPrioHigh)
+
+		return StrMgrEnoNone
+	}
+
+	s := strMgr.strNew(from, msg.Id, msg.Pid, peer.P2pPrioNormal)
+	s.state = strStateOpen
+	s.swin = strInitWindow(msg.Window)
+
+	strMgr.streams[key] = s
+
+	strMgr.lock.Unlock()
+
+	//
+	// ACCEPT sent before the stream is passed to user, so it would be sent
+	// ahead of data written to the stream.
+	//
+
+	var accept = sm.StrMessage {
+		Mid:	sm.MID_STREAM_ACCEPT,
+		Id:		msg.Id,
+		Window:	strWindow,
+	}
+
+	if eno := strMgr.strSend(from, &accept, s.prio); eno != StrMgrEnoNone {
+		strMgr.strRemove(s)
+		return StrMgrEnoNone
+	}
+
+	strMgr.lock.Lock()
+	defer strMgr.lock.Unlock()
+
+	if strMgr.listeners[msg.Pid] != ch || strMgr.streams[key] != s {
+		return StrMgrEnoNone
+	}
+
+	select {
+
+	case ch <- s:
+
+	default:
+
+		yclog.LogCallerFileLine("strOpenInd: " +
+			"backlog full, reset, id: %d, pid: %d, from: %s",
+			msg.Id, msg.Pid, fmt.Sprintf("%X", from))
+
+		s.lock.Lock()
+		s.resetLocked(StrMgrEnoReset)
+		s.lock.Unlock()
+		delete(strMgr.streams, key)
+
+		go strMgr.strSend(from, reset, peer.P2pPrioHigh)
+	}
+
+	return StrMgrEnoNone
+}
+
+//
+// Parity of identities of streams opened by local to a peer
+//
+func (strMgr *strManager) strIdParity(to ycfg.NodeID) uint32 {
+	if bytes.Compare(strMgr.local[:], to[:]) < 0 {
+		return 1
+	}
+	return 0
+}
+
+//
+// Create a stream, not added to the manager
+//
+func (strMgr *strManager) strNew(to ycfg.NodeID, id uint32, pid uint32, prio int) *Stream {
+
+	s := &Stream {
+		mgr:		strMgr,
+		peer:		to,
+		id:			id,
+		pid:		pid,
+		prio:		prio,
+		state:		strStateOpening,
+		opened:		make(chan StrMgrErrno, 1),
+		rwin:		strWindow,
+	}
+
+	s.cond = sync.NewCond(&s.lock)
+
+	return s
+}
+
+//
+// Remove a stream from manager
+//
+func (strMgr *strManager) strRemove(s *Stream) {
+
+	strMgr.lock.Lock()
+	defer strMgr.lock.Unlock()
+
+	key := strKey{peer: s.peer, id: s.id}
+
+	if strMgr.streams[key] == s {
+		delete(strMgr.streams, key)
+	}
+}
+
+//
+// Reset a stream, tell the peer if notify is true
+//
+func (strMgr *strManager) strReset(s *Stream, notify bool) {
+
+	s.lock.Lock()
+	s.resetLocked(StrMgrEnoReset)
+	s.lock.Unlock()
+
+	strMgr.strRemove(s)
+
+	if notify {
+		strMgr.strSend(s.peer, &sm.StrMessage{Mid: sm.MID_STREAM_RESET, Id: s.id}, peer.P2pPrioHigh)
+	}
+}
+
+//
+// Send stream message to peer without waiting
+//
+func (strMgr *strManager) strSend(to ycfg.NodeID, msg *sm.StrMessage, prio int) StrMgrErrno {
+	return strMgr.strSendWait(to, msg, prio, peer.P2pTxModeNonblock, 0)
+}
+
+//
+// Send stream message to peer, with mode and timeout for waiting room in the
+// send queue of peer.
+//
+func (strMgr *strManager) strSendWait(to ycfg.NodeID, msg *sm.StrMessage, prio int, mode int, timeout time.Duration) StrMgrErrno {
+
+	payload, eno := msg.Encode()
+	if eno != sm.StrMsgEnoNone {
+
+		yclog.LogCallerFileLine("strSendWait: " +
+			"Encode failed, eno: %d",
+			eno)
+
+		return StrMgrEnoMessage
+	}
+
+	var pkg = peer.P2pPackage2Peer {
+		IdList:			[]peer.PeerId{peer.PeerId(to)},
+		ProtoId:		int(peer.PID_STREAM),
+		PayloadLength:	len(payload),
+		Payload:		payload,
+		Priority:		prio,
+		TxMode:			mode,
+		TxTimeout:		timeout,
+	}
+
+	if pe, _ := peer.SendPackage(strMgr.sdl, &pkg); pe != peer.PeMgrEnoNone {
+
+		yclog.LogCallerFileLine("strSendWait: " +
+			"SendPackage failed, eno: %d, mid: %d, to: %s",
+			pe, msg.Mid, fmt.Sprintf("%X", to))
+
+		return StrMgrEnoNoPeer
+	}
+
+	return StrMgrEnoNone
+}
+
+//
+// Listen to a protocol, streams opened by peers for the protocol are queued
+// till accepted, at most backlog ones, others are reset.
+//
+func Listen(sdl *sch.Scheduler, pid uint32, backlog int) StrMgrErrno {
+
+	strMgr := strGetManager(sdl)
+	if strMgr == nil {
+		return StrMgrEnoScheduler
+	}
+
+	if backlog <= 0 {
+		backlog = strDftBacklog
+	}
+
+	strMgr.lock.Lock()
+	defer strMgr.lock.Unlock()
+
+	if _, dup := strMgr.listeners[pid]; dup {
+		return StrMgrEnoDuplicated
+	}
+
+	strMgr.listeners[pid] = make(chan *Stream, backlog)
+
+	return StrMgrEnoNone
+}
+
+//
+// Stop listening to a protocol, streams not accepted yet are reset
+//
+func Unlisten(sdl *sch.Scheduler, pid uint32) StrMgrErrno {
+
+	strMgr := strGetManager(sdl)
+	if strMgr == nil {
+		return StrMgrEnoScheduler
+	}
+
+	strMgr.lock.Lock()
+
+	ch, ok := strMgr.listeners[pid]
+	if !ok {
+		strMgr.lock.Unlock()
+		return StrMgrEnoNoListener
+	}
+
+	delete(strMgr.listeners, pid)
+	close(ch)
+
+	strMgr.lock.Unlock()
+
+	for s := range ch {
+		strMgr.strReset(s, true)
+	}
+
+	return StrMgrEnoNone
+}
+
+//
+// Accept a stream opened by peer for a protocol listened, wait till timeout,
+// zero timeout for no limit.
+//
+func Accept(sdl *sch.Scheduler, pid uint32, timeout time.Duration) (*Stream, StrMgrErrno) {
+
+	strMgr := strGetManager(sdl)
+	if strMgr == nil {
+		return nil, StrMgrEnoScheduler
+	}
+
+	strMgr.lock.Lock()
+	ch, ok := strMgr.listeners[pid]
+	strMgr.lock.Unlock()
+
+	if !ok {
+		return nil, StrMgrEnoNoListener
+	}
+
+	var expired <-chan time.Time
+
+	if timeout > 0 {
+		tm := time.NewTimer(timeout)
+		defer tm.Stop()
+		expired = tm.C
+	}
+
+	select {
+
+	case s, ok := <-ch:
+		if !ok {
+			return nil, StrMgrEnoClosed
+		}
+		return s, StrMgrEnoNone
+
+	case <-expired:
+		return nil, StrMgrEnoTimeout
+	}
+}
+
+//
+// Open a stream to peer for a protocol, wait till accepted by peer or timeout,
+// zero timeout for no limit. Frames of the stream are sent in priority class
+// prio, see peer.P2pPrioXXX.
+//
+func Open(sdl *sch.Scheduler, to ycfg.NodeID, pid uint32, prio int, timeout time.Duration) (*Stream, StrMgrErrno) {
+
+	strMgr := strGetManager(sdl)
+	if strMgr == nil {
+		return nil, StrMgrEnoScheduler
+	}
+
+	strMgr.lock.Lock()
+
+	if strMgr.peers[to] != true {
+		strMgr.lock.Unlock()
+		return nil, StrMgrEnoNoPeer
+	}
+
+	strMgr.seq++
+	id := strMgr.seq << 1 | strMgr.strIdParity(to)
+
+	s := strMgr.strNew(to, id, pid, prio)
+	strMgr.streams[strKey{peer: to, id: id}] = s
+
+	strMgr.lock.Unlock()
+
+	var open = sm.StrMessage {
+		Mid:	sm.MID_STREAM_OPEN,
+		Id:		id,
+		Pid:	pid,
+		Window:	strWindow,
+	}
+
+	if eno := strMgr.strSendWait(to, &open, prio, peer.P2pTxModeBlock, timeout); eno != StrMgrEnoNone {
+		strMgr.strRemove(s)
+		return nil, eno
+	}
+
+	var expired <-chan time.Time
+
+	if timeout > 0 {
+		tm := time.NewTimer(timeout)
+		defer tm.Stop()
+		expired = tm.C
+	}
+
+	select {
+
+	case eno := <-s.opened:
+		if eno != StrMgrEnoNone {
+			return nil, eno
+		}
+		return s, StrMgrEnoNone
+
+	case <-expired:
+		strMgr.strReset(s, true)
+		return nil, StrMgrEnoTimeout
+	}
+}
+
+//
+// Peer of stream
+//
+func (s *Stream) Peer() ycfg.NodeID {
+	return s.peer
+}
+
+//
+// Protocol of stream
+//
+func (s *Stream) Protocol() uint32 {
+	return s.pid
+}
+
+//
+// Identity of stream
+//
+func (s *Stream) Id() uint32 {
+	return s.id
+}
+
+//
+// Read data from stream, blocked till some data available. io.EOF returned
+// when the stream is closed by peer and all data had been read.
+//
+func (s *Stream) Read(p []byte) (int, error) {
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	s.lock.Lock()
+
+	for len(s.rbuf) == 0 && s.state == strStateOpen && !s.remoteClosed {
+		s.cond.Wait()
+	}
+
+	if len(s.rbuf) == 0 {
+
+		defer s.lock.Unlock()
+
+		if s.state == strStateReset {
+			return 0, ErrStrReset
+		}
+
+		return 0, io.EOF
+	}
+
+	n := copy(p, s.rbuf)
+	s.rbuf = s.rbuf[n:]
+	s.consumed += uint32(n)
+
+	//
+	// enlarge the window of remote when half of it consumed
+	//
+
+	var grant uint32 = 0
+
+	if s.consumed >= strWindow / 2 && !s.remoteClosed {
+		grant = s.consumed
+		s.rwin += grant
+		s.consumed = 0
+	}
+
+	s.lock.Unlock()
+
+	if grant > 0 {
+		var win = sm.StrMessage{Mid: sm.MID_STREAM_WINDOW, Id: s.id, Window: grant}
+		s.mgr.strSendWait(s.peer, &win, peer.P2pPrioHigh, peer.P2pTxModeBlock, 0)
+	}
+
+	return n, nil
+}
+
+//
+// Write data to stream, blocked till all data sent or window of stream
+// exhausted.
+//
+func (s *Stream) Write(p []byte) (int, error) {
+
+	s.wlock.Lock()
+	defer s.wlock.Unlock()
+
+	var n = 0
+
+	for len(p) > 0 {
+
+		s.lock.Lock()
+
+		for s.swin == 0 && s.state == strStateOpen && !s.localClosed {
+			s.cond.Wait()
+		}
+
+		if err := s.writeErr(); err != nil {
+			s.lock.Unlock()
+			return n, err
+		}
+
+		size := len(p)
+		if size > strFrameSize {
+			size = strFrameSize
+		}
+		if uint32(size) > s.swin {
+			size = int(s.swin)
+		}
+
+		s.swin -= uint32(size)
+
+		s.lock.Unlock()
+
+		var data = sm.StrMessage{Mid: sm.MID_STREAM_DATA, Id: s.id, Data: p[:size]}
+
+		if eno := s.mgr.strSendWait(s.peer, &data, s.prio, peer.P2pTxModeBlock, 0); eno != StrMgrEnoNone {
+			s.mgr.strReset(s, false)
+			return n, ErrStrReset
+		}
+
+		n += size
+		p = p[size:]
+	}
+
+	return n, nil
+}
+
+//
+// Close stream for writing: the peer would get io.EOF after reading all data
+// sent, while data from peer can still be read till io.EOF. The stream is
+// released when both sides closed it. Writers blocked for window are woken
+// up with ErrStrClosed, while the frame being sent, if any, is waited.
+//
+func (s *Stream) Close() error {
+
+	s.lock.Lock()
+
+	if s.localClosed || s.state == strStateReset {
+		s.lock.Unlock()
+		return nil
+	}
+
+	s.localClosed = true
+	s.cond.Broadcast()
+
+	s.lock.Unlock()
+
+	//
+	// CLOSE sent after the frame being sent and in the same priority class
+	// as data, so it would not go ahead of data.
+	//
+
+	s.wlock.Lock()
+
+	var cls = sm.StrMessage{Mid: sm.MID_STREAM_CLOSE, Id: s.id}
+
+	eno := s.mgr.strSendWait(s.peer, &cls, s.prio, peer.P2pTxModeBlock, 0)
+
+	s.wlock.Unlock()
+
+	s.lock.Lock()
+	remoteClosed := s.remoteClosed
+	s.lock.Unlock()
+
+	if remoteClosed || eno != StrMgrEnoNone {
+		s.mgr.strRemove(s)
+	}
+
+	return nil
+}
+
+//
+// Reset stream: it's aborted in both directions, data not read yet is
+// discarded, and both sides get ErrStrReset from then on.
+//
+func (s *Stream) Reset() {
+	s.mgr.strReset(s, true)
+}
+
+//
+// Error for writing, lock should be held by caller
+//
+func (s *Stream) writeErr() error {
+	switch {
+	case s.state == strStateReset:
+		return ErrStrReset
+	case s.localClosed:
+		return ErrStrClosed
+	}
+	return nil
+}
+
+//
+// Reset stream, lock of stream should be held by caller. Notice: the lock of
+// manager is always obtained before that of stream if both are needed.
+//
+func (s *Stream) resetLocked(why StrMgrErrno) {
+
+	if s.state == strStateOpening {
+		s.opened <- why
+	}
+
+	s.state = strStateReset
+	s.rbuf = nil
+	s.cond.Broadcast()
+}
+
+//
+// ACCEPT received
+//
+func (s *Stream) acceptInd(window uint32) {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.state != strStateOpening {
+		return
+	}
+
+	s.state = strStateOpen
+	s.swin = strInitWindow(window)
+	s.opened <- StrMgrEnoNone
+}
+
+//
+// DATA received, false returned if window exceeded
+//
+func (s *Stream) dataInd(data []byte) bool {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if uint32(len(data)) > s.rwin {
+		return false
+	}
+
+	s.rwin -= uint32(len(data))
+
+	if s.state != strStateOpen {
+		return true
+	}
+
+	s.rbuf = append(s.rbuf, data...)
+	s.cond.Broadcast()
+
+	return true
+}
+
+//
+// WINDOW received, false returned if the window to send would exceed
+// strWindow, which never happens with a peer granting only data we had
+// sent, and would wrap the window around if taken.
+//
+func (s *Stream) windowInd(window uint32) bool {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if window > strWindow - s.swin {
+		return false
+	}
+
+	s.swin += window
+	s.cond.Broadcast()
+
+	return true
+}
+
+//
+// Initial window to send, limited to strWindow, so the grants from peer are
+// bounded with it, see windowInd.
+//
+func strInitWindow(window uint32) uint32 {
+	if window > strWindow {
+		return strWindow
+	}
+	return window
+}
+
+//
+// CLOSE received, true returned if the stream is closed by both sides
+//
+func (s *Stream) closeInd() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.remoteClosed = true
+	s.cond.Broadcast()
+	return s.localClosed
+}
@@ -0,0 +1,312 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stream
+
+import (
+	"io"
+	"bytes"
+	"testing"
+	"time"
+	sch 	"github.com/yeeco/p2p/scheduler"
+	ycfg	"github.com/yeeco/p2p/config"
+			"github.com/yeeco/p2p/peer"
+)
+
+//
+// Stream opened to a peer. The scheduler is not started, so there is no peer
+// manager, and messages sent by the stream fail with StrMgrEnoNoPeer.
+//
+func strTestStream(t *testing.T) *Stream {
+
+	eno, sdl := sch.SchinfSchedulerInit(&ycfg.Config{})
+	if eno != sch.SchEnoNone {
+		t.Fatalf("SchinfSchedulerInit failed, eno: %d", eno)
+	}
+
+	strMgr := NewStrMgr().(*strManager)
+	strMgr.sdl = sdl
+
+	s := strMgr.strNew(ycfg.NodeID{1}, 1, 0, peer.P2pPrioNormal)
+	s.state = strStateOpen
+	s.swin = strWindow
+	strMgr.streams[strKey{peer: s.peer, id: s.id}] = s
+
+	return s
+}
+
+func TestStreamDataInd(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		state	int
+		rwin	uint32
+		size	int
+		ok		bool
+		rbuf	int
+	}{
+		{"within window", strStateOpen, strWindow, 100, true, 100},
+		{"whole window", strStateOpen, 100, 100, true, 100},
+		{"beyond window", strStateOpen, 99, 100, false, 0},
+		{"window exhausted", strStateOpen, 0, 1, false, 0},
+		{"empty", strStateOpen, 0, 0, true, 0},
+		{"reset", strStateReset, strWindow, 100, true, 0},
+	}
+
+	for _, c := range cases {
+
+		s := strTestStream(t)
+		s.state = c.state
+		s.rwin = c.rwin
+
+		if ok := s.dataInd(make([]byte, c.size)); ok != c.ok {
+			t.Fatalf("%s: dataInd returned %t, want %t", c.name, ok, c.ok)
+		}
+
+		if len(s.rbuf) != c.rbuf {
+			t.Fatalf("%s: %d bytes buffered, want %d", c.name, len(s.rbuf), c.rbuf)
+		}
+
+		if rwin := c.rwin - uint32(c.size); c.ok && s.rwin != rwin {
+			t.Fatalf("%s: window %d, want %d", c.name, s.rwin, rwin)
+		}
+
+		if !c.ok && s.rwin != c.rwin {
+			t.Fatalf("%s: window changed to %d", c.name, s.rwin)
+		}
+	}
+}
+
+func TestStreamWindowGrant(t *testing.T) {
+
+	const half = strWindow / 2
+
+	cases := []struct {
+		name		string
+		reads		[]int
+		remote		bool
+		rwin		uint32
+		consumed	uint32
+	}{
+		{"under half", []int{half - 1}, false, half + 1, half - 1},
+		{"half", []int{half}, false, strWindow, 0},
+		{"half by pieces", []int{half / 2, half / 2}, false, strWindow, 0},
+		{"over half", []int{half / 2, half / 2 + 10}, false, strWindow, 0},
+		{"granted twice", []int{half, half}, false, strWindow, 0},
+		{"closed by remote", []int{half}, true, half, half},
+	}
+
+	for _, c := range cases {
+
+		s := strTestStream(t)
+
+		for _, n := range c.reads {
+
+			if !s.dataInd(make([]byte, n)) {
+				t.Fatalf("%s: dataInd failed", c.name)
+			}
+
+			s.remoteClosed = c.remote
+
+			if m, err := s.Read(make([]byte, n)); m != n || err != nil {
+				t.Fatalf("%s: Read returned %d, %v", c.name, m, err)
+			}
+		}
+
+		if s.rwin != c.rwin || s.consumed != c.consumed {
+			t.Fatalf("%s: window %d, consumed %d, want %d, %d",
+				c.name, s.rwin, s.consumed, c.rwin, c.consumed)
+		}
+	}
+}
+
+func TestStreamWindowInd(t *testing.T) {
+
+	cases := []struct {
+		name	string
+		swin	uint32
+		window	uint32
+		ok		bool
+	}{
+		{"grant", 0, strWindow / 2, true},
+		{"up to window", strWindow / 2, strWindow / 2, true},
+		{"empty", strWindow, 0, true},
+		{"beyond window", strWindow / 2, strWindow / 2 + 1, false},
+		{"full", strWindow, 1, false},
+		{"wrap around", 1, 0xffffffff, false},
+	}
+
+	for _, c := range cases {
+
+		s := strTestStream(t)
+		s.swin = c.swin
+
+		if ok := s.windowInd(c.window); ok != c.ok {
+			t.Fatalf("%s: windowInd returned %t, want %t", c.name, ok, c.ok)
+		}
+
+		if swin := c.swin + c.window; c.ok && s.swin != swin || !c.ok && s.swin != c.swin {
+			t.Fatalf("%s: window to send %d", c.name, s.swin)
+		}
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+
+	//
+	// closed by remote: data buffered can still be read then io.EOF, and
+	// we can still write.
+	//
+
+	s := strTestStream(t)
+	s.dataInd([]byte("tail"))
+
+	if both := s.closeInd(); both {
+		t.Fatalf("remote half-close: closed by both")
+	}
+
+	buf := make([]byte, 16)
+	if n, err := s.Read(buf); n != 4 || err != nil || !bytes.Equal(buf[:n], []byte("tail")) {
+		t.Fatalf("remote half-close: Read returned %d, %v", n, err)
+	}
+
+	if n, err := s.Read(buf); n != 0 || err != io.EOF {
+		t.Fatalf("remote half-close: Read returned %d, %v, want io.EOF", n, err)
+	}
+
+	s.lock.Lock()
+	err := s.writeErr()
+	s.lock.Unlock()
+
+	if err != nil {
+		t.Fatalf("remote half-close: write error: %v", err)
+	}
+
+	//
+	// closed by local: no more writes, while data from remote can be read,
+	// and it's closed by both when remote closes too.
+	//
+
+	s = strTestStream(t)
+	s.Close()
+
+	if n, err := s.Write([]byte("data")); n != 0 || err != ErrStrClosed {
+		t.Fatalf("local half-close: Write returned %d, %v, want ErrStrClosed", n, err)
+	}
+
+	s.dataInd([]byte("more"))
+	if n, err := s.Read(buf); n != 4 || err != nil {
+		t.Fatalf("local half-close: Read returned %d, %v", n, err)
+	}
+
+	if both := s.closeInd(); !both {
+		t.Fatalf("full close: not closed by both")
+	}
+
+	if n, err := s.Read(buf); n != 0 || err != io.EOF {
+		t.Fatalf("full close: Read returned %d, %v, want io.EOF", n, err)
+	}
+
+	//
+	// reset: both directions aborted
+	//
+
+	s = strTestStream(t)
+	s.dataInd([]byte("lost"))
+	s.mgr.strReset(s, false)
+
+	if n, err := s.Read(buf); n != 0 || err != ErrStrReset {
+		t.Fatalf("reset: Read returned %d, %v, want ErrStrReset", n, err)
+	}
+
+	if _, ok := s.mgr.streams[strKey{peer: s.peer, id: s.id}]; ok {
+		t.Fatalf("reset: stream not removed")
+	}
+}
+
+func TestStreamWriteBlocked(t *testing.T) {
+
+	s := strTestStream(t)
+	s.swin = 0
+
+	type result struct {
+		n	int
+		err	error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		n, err := s.Write(make([]byte, 100))
+		done <- result{n, err}
+	}()
+
+	select {
+	case r := <-done:
+		t.Fatalf("Write returned %d, %v without window", r.n, r.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if !s.windowInd(40) {
+		t.Fatalf("windowInd failed")
+	}
+
+	//
+	// the writer takes the window granted for a frame, which then fails to be
+	// sent since there is no peer, and the stream is reset.
+	//
+
+	select {
+	case r := <-done:
+		if r.err != ErrStrReset {
+			t.Fatalf("Write returned %d, %v, want ErrStrReset", r.n, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Write not woken up by windowInd")
+	}
+
+	if s.swin != 0 {
+		t.Fatalf("window to send %d, want 0", s.swin)
+	}
+
+	//
+	// and writers blocked are woken up by Close too
+	//
+
+	s = strTestStream(t)
+	s.swin = 0
+
+	go func() {
+		n, err := s.Write(make([]byte, 100))
+		done <- result{n, err}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	s.Close()
+
+	select {
+	case r := <-done:
+		if r.err != ErrStrClosed {
+			t.Fatalf("Write returned %d, %v, want ErrStrClosed", r.n, r.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Write not woken up by Close")
+	}
+}
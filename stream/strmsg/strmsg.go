@@ -0,0 +1,142 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package strmsg
+
+import (
+	pb		"github.com/yeeco/p2p/peer/pb"
+	yclog	"github.com/yeeco/p2p/logger"
+)
+
+//
+// Stream messages carried by packages with protocol identity PID_STREAM over
+// the peer connections, see tcpmsg.proto for the protobuf specification.
+//
+const (
+	MID_STREAM_OPEN		= pb.MessageId_MID_STREAM_OPEN
+	MID_STREAM_ACCEPT	= pb.MessageId_MID_STREAM_ACCEPT
+	MID_STREAM_DATA		= pb.MessageId_MID_STREAM_DATA
+	MID_STREAM_WINDOW	= pb.MessageId_MID_STREAM_WINDOW
+	MID_STREAM_CLOSE	= pb.MessageId_MID_STREAM_CLOSE
+	MID_STREAM_RESET	= pb.MessageId_MID_STREAM_RESET
+)
+
+//
+// errno
+//
+const (
+	StrMsgEnoNone	= iota
+	StrMsgEnoParameter
+	StrMsgEnoEncode
+	StrMsgEnoDecode
+)
+
+type StrMsgErrno int
+
+//
+// Stream message: all kinds of messages share the same fields, those not
+// needed by a kind are left zero.
+//
+type StrMessage struct {
+	Mid			pb.MessageId	// message identity
+	Id			uint32			// stream identity, by the opener
+	Pid			uint32			// protocol of stream, for OPEN
+	Window		uint32			// initial window for OPEN and ACCEPT, increment for WINDOW
+	Data		[]byte			// data for DATA
+}
+
+//
+// Encode stream message to payload of package
+//
+func (sm *StrMessage) Encode() ([]byte, StrMsgErrno) {
+
+	if sm.Mid < MID_STREAM_OPEN || sm.Mid > MID_STREAM_RESET {
+		yclog.LogCallerFileLine("Encode: invalid mid: %d", sm.Mid)
+		return nil, StrMsgEnoParameter
+	}
+
+	pbMsg := &pb.StreamMessage {
+		Mid:	new(pb.MessageId),
+		Id:		new(uint32),
+	}
+
+	*pbMsg.Mid = sm.Mid
+	*pbMsg.Id = sm.Id
+
+	switch sm.Mid {
+
+	case MID_STREAM_OPEN:
+		pbMsg.Pid = new(uint32)
+		*pbMsg.Pid = sm.Pid
+		pbMsg.Window = new(uint32)
+		*pbMsg.Window = sm.Window
+
+	case MID_STREAM_ACCEPT, MID_STREAM_WINDOW:
+		pbMsg.Window = new(uint32)
+		*pbMsg.Window = sm.Window
+
+	case MID_STREAM_DATA:
+		pbMsg.Data = sm.Data
+	}
+
+	buf, err := pbMsg.Marshal()
+	if err != nil {
+
+		yclog.LogCallerFileLine("Encode: " +
+			"Marshal failed, err: %s",
+			err.Error())
+
+		return nil, StrMsgEnoEncode
+	}
+
+	return buf, StrMsgEnoNone
+}
+
+//
+// Decode stream message from payload of package
+//
+func Decode(payload []byte) (*StrMessage, StrMsgErrno) {
+
+	pbMsg := new(pb.StreamMessage)
+
+	if err := pbMsg.Unmarshal(payload); err != nil {
+
+		yclog.LogCallerFileLine("Decode: " +
+			"Unmarshal failed, err: %s",
+			err.Error())
+
+		return nil, StrMsgEnoDecode
+	}
+
+	sm := &StrMessage {
+		Mid:	pbMsg.GetMid(),
+		Id:		pbMsg.GetId(),
+		Pid:	pbMsg.GetPid(),
+		Window:	pbMsg.GetWindow(),
+		Data:	pbMsg.Data,
+	}
+
+	if sm.Mid < MID_STREAM_OPEN || sm.Mid > MID_STREAM_RESET {
+		yclog.LogCallerFileLine("Decode: invalid mid: %d", sm.Mid)
+		return nil, StrMsgEnoDecode
+	}
+
+	return sm, StrMsgEnoNone
+}